@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	ceems_api "github.com/mahendrapaipuri/ceems/pkg/api/client"
+)
+
+// newCEEMSClient returns a new CEEMS API server client that makes requests on
+// behalf of user. When token is not empty, it is sent as bearer token.
+func newCEEMSClient(serverURL *url.URL, roundTripper http.RoundTripper, user, token string) (*ceems_api.Client, error) {
+	if serverURL.Scheme == "" {
+		serverURL.Scheme = "http"
+	}
+
+	return ceems_api.New(ceems_api.Config{
+		Address:    serverURL.String(),
+		User:       user,
+		Token:      token,
+		HTTPClient: &http.Client{Transport: roundTripper},
+	})
+}
+
+// CheckAPIServerStatus checks the CEEMS API server status by making a request to
+// health endpoint.
+func CheckAPIServerStatus(serverURL *url.URL, roundTripper http.RoundTripper) error {
+	client, err := newCEEMSClient(serverURL, roundTripper, "", "")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error creating API client:", err)
+
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := client.Health(ctx); err != nil {
+		return fmt.Errorf("check failed: URL=%s, %w", serverURL, err)
+	}
+
+	fmt.Fprintln(os.Stderr, "  SUCCESS: CEEMS API Server is healthy")
+
+	return nil
+}
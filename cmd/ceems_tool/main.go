@@ -53,6 +53,11 @@ func main() {
 		webConfigTLS         bool
 		webConfigTLSHosts    []string
 		webConfigTLSValidity time.Duration
+
+		unitsList  unitsListConfig
+		unitsShow  apiQueryConfig
+		usageQuery usageConfig
+		unitUUID   string
 	)
 
 	app := kingpin.New(filepath.Base(os.Args[0]), "Tooling for the CEEMS.").UsageWriter(os.Stdout)
@@ -164,6 +169,66 @@ func main() {
 		"end", "The time to end querying for metrics. Must be a RFC3339 formatted date or Unix timestamp. Default is current time.",
 	).StringVar(&end)
 
+	unitsCmd := app.Command("units", "Query compute units from CEEMS API server.")
+
+	unitsListCmd := unitsCmd.Command("list", "List compute units.")
+	apiQueryFlags(unitsListCmd, &unitsList.apiQueryConfig, &httpConfigFilePath)
+	unitsListCmd.Flag(
+		"from", "Start of the query window. Must be a RFC3339 formatted date, Unix timestamp or a duration relative to current time like 7d. Default is set by CEEMS API server.",
+	).StringVar(&unitsList.from)
+	unitsListCmd.Flag(
+		"to", "End of the query window. Must be a RFC3339 formatted date, Unix timestamp or a duration relative to current time like 1h. Default is current time.",
+	).StringVar(&unitsList.to)
+	unitsListCmd.Flag(
+		"running", "List only running units irrespective of the query window.",
+	).Default("false").BoolVar(&unitsList.running)
+	unitsListCmd.Flag(
+		"uuid", "UUID of the unit. Repeat the flag to query several units.",
+	).StringsVar(&unitsList.uuids)
+	unitsListCmd.Flag(
+		"project", "Name of the project. Repeat the flag to query several projects.",
+	).StringsVar(&unitsList.projects)
+	unitsListCmd.Flag(
+		"username", "Name of the user of units. Only admin users can query units of other users. Repeat the flag to query several users.",
+	).StringsVar(&unitsList.users)
+	unitsListCmd.Flag(
+		"annotation", "Annotation of units in key=value format. Repeat the flag to match several annotations.",
+	).StringsVar(&unitsList.annotations)
+	unitsListCmd.Flag(
+		"filter", "Filter units on a field, e.g., state=COMPLETED or total_cpu_energy_usage_kwh.total>1. Repeat the flag to add several filters.",
+	).StringsVar(&unitsList.filters)
+	unitsListCmd.Flag(
+		"sort", "Field to sort units. Prefix the field with - to sort in descending order.",
+	).StringVar(&unitsList.sort)
+	unitsListCmd.Flag(
+		"timezone", "Timezone to use for the timestamps of units.",
+	).StringVar(&unitsList.timezone)
+	unitsListCmd.Flag(
+		"limit", "Maximum number of units to list. When set to 0, all units are listed.",
+	).Default("0").IntVar(&unitsList.limit)
+
+	unitsShowCmd := unitsCmd.Command("show", "Show details of a compute unit.")
+	apiQueryFlags(unitsShowCmd, &unitsShow, &httpConfigFilePath)
+	unitsShowCmd.Arg("uuid", "UUID of the unit.").Required().StringVar(&unitUUID)
+
+	usageCmd := app.Command("usage", "Query usage of projects from CEEMS API server.")
+	apiQueryFlags(usageCmd, &usageQuery.apiQueryConfig, &httpConfigFilePath)
+	usageCmd.Flag(
+		"mode", "Usage mode. current returns usage of units within the query window and global returns usage of all units.",
+	).Default("current").EnumVar(&usageQuery.mode, "current", "global")
+	usageCmd.Flag(
+		"from", "Start of the query window for current usage. Must be a RFC3339 formatted date, Unix timestamp or a duration relative to current time like 7d. Default is set by CEEMS API server.",
+	).StringVar(&usageQuery.from)
+	usageCmd.Flag(
+		"to", "End of the query window for current usage. Must be a RFC3339 formatted date, Unix timestamp or a duration relative to current time like 1h. Default is current time.",
+	).StringVar(&usageQuery.to)
+	usageCmd.Flag(
+		"project", "Name of the project. Repeat the flag to query several projects.",
+	).StringsVar(&usageQuery.projects)
+	usageCmd.Flag(
+		"username", "Name of the user. Only admin users can query usage of other users. Repeat the flag to query several users.",
+	).StringsVar(&usageQuery.users)
+
 	parsedCmd := kingpin.MustParse(app.Parse(os.Args[1:]))
 
 	if httpConfigFilePath != "" {
@@ -186,7 +251,7 @@ func main() {
 
 	switch parsedCmd {
 	case checkAPIServerHealthCmd.FullCommand():
-		os.Exit(checkErr(CheckServerStatus(apiServerURL, checkHealth, httpRoundTripper)))
+		os.Exit(checkErr(CheckAPIServerStatus(apiServerURL, httpRoundTripper)))
 
 	case checkLBServerHealthCmd.FullCommand():
 		os.Exit(checkErr(CheckServerStatus(lbServerURL, checkHealth, httpRoundTripper)))
@@ -220,9 +285,52 @@ func main() {
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 		defer cancel()
 		os.Exit(checkErr(CreatePromRelabelConfig(ctx, promServerURL, start, end, httpRoundTripper)))
+
+	case unitsListCmd.FullCommand():
+		unitsList.roundTripper = httpRoundTripper
+		os.Exit(checkErr(ListUnits(os.Stdout, &unitsList)))
+
+	case unitsShowCmd.FullCommand():
+		unitsShow.roundTripper = httpRoundTripper
+		os.Exit(checkErr(ShowUnit(os.Stdout, &unitsShow, unitUUID)))
+
+	case usageCmd.FullCommand():
+		usageQuery.roundTripper = httpRoundTripper
+		os.Exit(checkErr(ShowUsage(os.Stdout, &usageQuery)))
 	}
 }
 
+// apiQueryFlags adds flags of commands that query CEEMS API server to cmd.
+func apiQueryFlags(cmd *kingpin.CmdClause, c *apiQueryConfig, httpConfigFilePath *string) {
+	cmd.Flag(
+		"http.config.file", "HTTP client configuration file for ceems_tool to connect to CEEMS API server.",
+	).PlaceHolder("<filename>").ExistingFileVar(httpConfigFilePath)
+	cmd.Flag(
+		"url", "The URL for the CEEMS API server.",
+	).Default("http://localhost:9020").Envar("CEEMS_API_URL").URLVar(&c.serverURL)
+	cmd.Flag(
+		"user", "Name of the user on whose behalf requests are made. It is sent in X-Grafana-User header.",
+	).Envar("CEEMS_USER").StringVar(&c.user)
+	cmd.Flag(
+		"token", "Personal API token or OIDC token to authenticate with CEEMS API server.",
+	).Envar("CEEMS_API_TOKEN").StringVar(&c.token)
+	cmd.Flag(
+		"admin", "Use admin endpoints to query resources of all users. Only admin users can use them.",
+	).Default("false").BoolVar(&c.admin)
+	cmd.Flag(
+		"cluster-id", "ID of the cluster. Repeat the flag to query several clusters. Default is all clusters.",
+	).StringsVar(&c.clusterIDs)
+	cmd.Flag(
+		"field", "Field to output. Nested fields can be selected with a dot, e.g., avg_cpu_usage.global. Repeat the flag to output several fields. Default is a set of common fields for table output and all fields for others.",
+	).StringsVar(&c.fields)
+	cmd.Flag(
+		"output", "Output format.",
+	).Short('o').Default(outputTable).EnumVar(&c.output, outputTable, outputJSON, outputCSV)
+	cmd.Flag(
+		"timeout", "Timeout for requests to CEEMS API server.",
+	).Default("2m").DurationVar(&c.timeout)
+}
+
 // CheckServerStatus checks the server status by making a request to check endpoint.
 func CheckServerStatus(serverURL *url.URL, checkEndpoint string, roundTripper http.RoundTripper) error {
 	if serverURL.Scheme == "" {
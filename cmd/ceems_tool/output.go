@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+)
+
+var errUnknownOutput = errors.New("unknown output format")
+
+// record is a resource flattened into columns. Nested objects are flattened
+// into columns with keys joined by a dot, _e.g.,_ `avg_cpu_usage.global`.
+type record struct {
+	keys   []string
+	values map[string]string
+}
+
+// flatten returns a record of resource by walking through its JSON representation.
+// Order of columns follows the order of fields in JSON.
+func flatten(resource any) (record, error) {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return record{}, err
+	}
+
+	rec := record{values: make(map[string]string)}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if err := rec.walk(dec, ""); err != nil {
+		return record{}, fmt.Errorf("failed to flatten resource: %w", err)
+	}
+
+	return rec, nil
+}
+
+// walk reads next JSON value from decoder and adds it to record under prefix.
+func (r *record) walk(dec *json.Decoder, prefix string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+
+				key, _ := keyTok.(string)
+				if prefix != "" {
+					key = prefix + "." + key
+				}
+
+				if err := r.walk(dec, key); err != nil {
+					return err
+				}
+			}
+		case '[':
+			// Arrays are kept as a single column
+			var items []string
+
+			for dec.More() {
+				var item json.RawMessage
+				if err := dec.Decode(&item); err != nil {
+					return err
+				}
+
+				items = append(items, strings.Trim(string(item), `"`))
+			}
+
+			r.add(prefix, strings.Join(items, ","))
+		}
+
+		// Consume closing delimiter
+		_, err = dec.Token()
+
+		return err
+	case nil:
+		r.add(prefix, "")
+	default:
+		r.add(prefix, fmt.Sprint(t))
+	}
+
+	return nil
+}
+
+// add adds column key with value to record.
+func (r *record) add(key, value string) {
+	if _, ok := r.values[key]; !ok {
+		r.keys = append(r.keys, key)
+	}
+
+	r.values[key] = value
+}
+
+// columns returns columns of records that match fields. A column matches a field
+// when they are equal or when column is nested under field. When fields is empty,
+// all columns are returned.
+func columns(records []record, fields []string) []string {
+	var all []string
+
+	for _, rec := range records {
+		for _, key := range rec.keys {
+			if !slices.Contains(all, key) {
+				all = append(all, key)
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return all
+	}
+
+	var cols []string
+
+	for _, field := range fields {
+		for _, key := range all {
+			if (key == field || strings.HasPrefix(key, field+".")) && !slices.Contains(cols, key) {
+				cols = append(cols, key)
+			}
+		}
+	}
+
+	return cols
+}
+
+// writeResources writes resources to w in output format. Only fields are written
+// when they are not empty.
+func writeResources[T any](w io.Writer, resources []T, output string, fields []string) error {
+	if output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+
+		if resources == nil {
+			resources = []T{}
+		}
+
+		return enc.Encode(resources)
+	}
+
+	records := make([]record, len(resources))
+
+	for i, resource := range resources {
+		rec, err := flatten(resource)
+		if err != nil {
+			return err
+		}
+
+		records[i] = rec
+	}
+
+	cols := columns(records, fields)
+
+	switch output {
+	case outputTable:
+		return writeTable(w, records, cols)
+	case outputCSV:
+		return writeCSV(w, records, cols)
+	default:
+		return fmt.Errorf("%w: %s", errUnknownOutput, output)
+	}
+}
+
+// writeTable writes records as a table with columns.
+func writeTable(w io.Writer, records []record, cols []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = strings.ToUpper(col)
+	}
+
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, rec := range records {
+		row := make([]string, len(cols))
+		for i, col := range cols {
+			row[i] = rec.values[col]
+		}
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// writeCSV writes records in CSV format with columns.
+func writeCSV(w io.Writer, records []record, cols []string) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		row := make([]string, len(cols))
+		for i, col := range cols {
+			row[i] = rec.values[col]
+		}
+
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// writeRecord writes columns of a single record as key value pairs.
+func writeRecord(w io.Writer, rec record) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	for _, key := range rec.keys {
+		fmt.Fprintf(tw, "%s:\t%s\n", key, rec.values[key])
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	ceems_api "github.com/mahendrapaipuri/ceems/pkg/api/client"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/model"
+)
+
+// Maximum number of units fetched in each request.
+const unitsPageSize = 1000
+
+var errUnitNotFound = errors.New("unit not found")
+
+// Default columns of units and usage in table output.
+var (
+	defaultUnitFields = []string{
+		"cluster_id", "uuid", "name", "project", "username", "state", "started_at", "elapsed",
+		"avg_cpu_usage.global", "avg_gpu_usage.global",
+		"total_cpu_energy_usage_kwh.total", "total_gpu_energy_usage_kwh.total",
+	}
+	defaultUsageFields = []string{
+		"cluster_id", "project", "username", "num_units",
+		"avg_cpu_usage.global", "avg_gpu_usage.global",
+		"total_cpu_energy_usage_kwh.total", "total_gpu_energy_usage_kwh.total",
+	}
+)
+
+// apiQueryConfig contains the common configuration of commands that query
+// CEEMS API server.
+type apiQueryConfig struct {
+	serverURL    *url.URL
+	roundTripper http.RoundTripper
+	user         string
+	token        string
+	admin        bool
+	clusterIDs   []string
+	output       string
+	fields       []string
+	timeout      time.Duration
+}
+
+// client returns a CEEMS API server client from config.
+func (c *apiQueryConfig) client() (*ceems_api.Client, error) {
+	return newCEEMSClient(c.serverURL, c.roundTripper, c.user, c.token)
+}
+
+// unitsListConfig contains the configuration of units list command.
+type unitsListConfig struct {
+	apiQueryConfig
+
+	from        string
+	to          string
+	running     bool
+	uuids       []string
+	projects    []string
+	users       []string
+	annotations []string
+	filters     []string
+	sort        string
+	timezone    string
+	limit       int
+}
+
+// usageConfig contains the configuration of usage command.
+type usageConfig struct {
+	apiQueryConfig
+
+	mode     string
+	from     string
+	to       string
+	projects []string
+	users    []string
+}
+
+// ListUnits lists units from CEEMS API server.
+func ListUnits(w io.Writer, c *unitsListConfig) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	q := ceems_api.NewUnitsQuery().
+		ClusterIDs(c.clusterIDs...).
+		UUIDs(c.uuids...).
+		Projects(c.projects...).
+		Users(c.users...).
+		Annotations(c.annotations...).
+		Filters(c.filters...).
+		Fields(queryFields(c.apiQueryConfig, defaultUnitFields)...)
+
+	if c.running {
+		q = q.Running()
+	}
+
+	if err := setTimeRange(q, c.from, c.to); err != nil {
+		return err
+	}
+
+	if c.sort != "" {
+		q = q.Sort(c.sort)
+	}
+
+	if c.timezone != "" {
+		q = q.Timezone(c.timezone)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	units, err := fetchUnits(ctx, client, c.admin, q, c.limit)
+	if err != nil {
+		return err
+	}
+
+	return writeResources(w, units, c.output, outputFields(c.apiQueryConfig, defaultUnitFields))
+}
+
+// ShowUnit prints details of unit with uuid from CEEMS API server.
+func ShowUnit(w io.Writer, c *apiQueryConfig, uuid string) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	q := ceems_api.NewUnitsQuery().ClusterIDs(c.clusterIDs...).UUIDs(uuid).Fields(queryFields(*c, nil)...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	units, err := fetchUnits(ctx, client, c.admin, q, 0)
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return fmt.Errorf("%w: %s", errUnitNotFound, uuid)
+	}
+
+	if c.output != outputTable {
+		return writeResources(w, units, c.output, c.fields)
+	}
+
+	// Same UUID can exist in different clusters
+	for i, unit := range units {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		rec, err := flatten(unit)
+		if err != nil {
+			return err
+		}
+
+		if err := writeRecord(w, rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ShowUsage prints usage of projects from CEEMS API server.
+func ShowUsage(w io.Writer, c *usageConfig) error {
+	client, err := c.client()
+	if err != nil {
+		return err
+	}
+
+	q := ceems_api.NewUsageQuery().
+		ClusterIDs(c.clusterIDs...).
+		Projects(c.projects...).
+		Users(c.users...).
+		Fields(queryFields(c.apiQueryConfig, defaultUsageFields)...)
+
+	// Time range is only meaningful for current usage
+	if c.mode == ceems_api.CurrentUsage {
+		if err := setTimeRange(q, c.from, c.to); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	var usage []models.Usage
+
+	if c.admin {
+		usage, err = client.UsageAdmin(ctx, c.mode, q)
+	} else {
+		usage, err = client.Usage(ctx, c.mode, q)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return writeResources(w, usage, c.output, outputFields(c.apiQueryConfig, defaultUsageFields))
+}
+
+// fetchUnits returns units by following the pages of query until limit units
+// are fetched. When limit is zero, all units are fetched.
+func fetchUnits(
+	ctx context.Context,
+	client *ceems_api.Client,
+	admin bool,
+	q *ceems_api.UnitsQuery,
+	limit int,
+) ([]models.Unit, error) {
+	var units []models.Unit
+
+	var cursor string
+
+	for {
+		pageSize := unitsPageSize
+		if limit > 0 {
+			pageSize = min(limit-len(units), unitsPageSize)
+		}
+
+		q = q.Limit(pageSize)
+		if cursor != "" {
+			q = q.Cursor(cursor)
+		}
+
+		var page *ceems_api.Page[models.Unit]
+
+		var err error
+
+		if admin {
+			page, err = client.UnitsAdmin(ctx, q)
+		} else {
+			page, err = client.Units(ctx, q)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		units = append(units, page.Data...)
+
+		if page.Next == "" || (limit > 0 && len(units) >= limit) {
+			return units, nil
+		}
+
+		cursor = page.Next
+	}
+}
+
+// timeRangeQuery is a query that accepts a time range.
+type timeRangeQuery[T any] interface {
+	From(t time.Time) T
+	To(t time.Time) T
+}
+
+// setTimeRange sets from and to on query when they are not empty.
+func setTimeRange[T any](q timeRangeQuery[T], from, to string) error {
+	now := time.Now()
+
+	if from != "" {
+		t, err := parseQueryTime(from, now)
+		if err != nil {
+			return fmt.Errorf("error parsing from time: %w", err)
+		}
+
+		q.From(t)
+	}
+
+	if to != "" {
+		t, err := parseQueryTime(to, now)
+		if err != nil {
+			return fmt.Errorf("error parsing to time: %w", err)
+		}
+
+		q.To(t)
+	}
+
+	return nil
+}
+
+// parseQueryTime parses s as a duration relative to now, like `7d`, or as an
+// absolute RFC3339 formatted date or Unix timestamp.
+func parseQueryTime(s string, now time.Time) (time.Time, error) {
+	if d, err := model.ParseDuration(s); err == nil {
+		return now.Add(-time.Duration(d)), nil
+	}
+
+	return parseTime(s)
+}
+
+// queryFields returns the fields requested from CEEMS API server. Table output
+// requests only default fields when no fields are set by user.
+func queryFields(c apiQueryConfig, defaults []string) []string {
+	fields := outputFields(c, defaults)
+
+	// Nested fields are requested using their parent
+	var queried []string
+
+	for _, field := range fields {
+		name, _, _ := strings.Cut(field, ".")
+		if !slices.Contains(queried, name) {
+			queried = append(queried, name)
+		}
+	}
+
+	return queried
+}
+
+// outputFields returns the fields to output.
+func outputFields(c apiQueryConfig, defaults []string) []string {
+	if len(c.fields) == 0 && c.output == outputTable {
+		return defaults
+	}
+
+	return c.fields
+}
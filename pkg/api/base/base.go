@@ -25,22 +25,26 @@ var CEEMSServerApp = *kingpin.New(
 
 // DB table names.
 var (
-	UnitsDBTableName      = models.Unit{}.TableName()
-	UsageDBTableName      = models.Usage{}.TableName()
-	DailyUsageDBTableName = models.DailyUsage{}.TableName()
-	ProjectsDBTableName   = models.Project{}.TableName()
-	UsersDBTableName      = models.User{}.TableName()
-	AdminUsersDBTableName = models.AdminUser{}.TableName()
+	UnitsDBTableName       = models.Unit{}.TableName()
+	UsageDBTableName       = models.Usage{}.TableName()
+	DailyUsageDBTableName  = models.DailyUsage{}.TableName()
+	ProjectsDBTableName    = models.Project{}.TableName()
+	UsersDBTableName       = models.User{}.TableName()
+	AdminUsersDBTableName  = models.AdminUser{}.TableName()
+	AnnotationsDBTableName = models.UnitAnnotation{}.TableName()
+	APITokensDBTableName   = models.APIToken{}.TableName()
 )
 
 // Slice of field names of all tables
 // This slice will not contain the DB columns that are ignored in the query.
 var (
-	UnitsDBTableColNames      = models.Unit{}.TagNames("json")
-	UsageDBTableColNames      = models.Usage{}.TagNames("json")
-	ProjectsDBTableColNames   = models.Project{}.TagNames("json")
-	UsersDBTableColNames      = models.User{}.TagNames("json")
-	AdminUsersDBTableColNames = models.User{}.TagNames("json")
+	UnitsDBTableColNames       = models.Unit{}.TagNames("json")
+	UsageDBTableColNames       = models.Usage{}.TagNames("json")
+	ProjectsDBTableColNames    = models.Project{}.TagNames("json")
+	UsersDBTableColNames       = models.User{}.TagNames("json")
+	AdminUsersDBTableColNames  = models.User{}.TagNames("json")
+	AnnotationsDBTableColNames = models.UnitAnnotation{}.TagNames("json")
+	APITokensDBTableColNames   = models.APIToken{}.TagNames("json")
 )
 
 // Map of struct field name to DB column name.
@@ -7,12 +7,14 @@ package cli
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"os/user"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"sync"
 	"syscall"
 	"time"
@@ -26,6 +28,7 @@ import (
 	ceems_http "github.com/mahendrapaipuri/ceems/pkg/api/http"
 	"github.com/mahendrapaipuri/ceems/pkg/api/resource"
 	"github.com/mahendrapaipuri/ceems/pkg/api/updater"
+	"github.com/mahendrapaipuri/ceems/pkg/api/webhooks"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/promslog"
 	"github.com/prometheus/common/promslog/flag"
@@ -42,6 +45,9 @@ type CEEMSAPIAppConfig struct {
 // SetDirectory joins any relative file paths with dir.
 func (c *CEEMSAPIAppConfig) SetDirectory(dir string) {
 	c.Server.Admin.SetDirectory(dir)
+	c.Server.Web.OIDC.SetDirectory(dir)
+	c.Server.Webhooks.SetDirectory(dir)
+	c.Server.Tenants.SetDirectory(dir)
 }
 
 // Validate validates the config.
@@ -56,14 +62,147 @@ func (c *CEEMSAPIAppConfig) Validate() error {
 		return err
 	}
 
+	// Validate OIDC config
+	if err := c.Server.Web.OIDC.Validate(); err != nil {
+		return err
+	}
+
+	// Validate webhooks config
+	if err := c.Server.Webhooks.Validate(); err != nil {
+		return err
+	}
+
+	// Validate reports config
+	if err := c.Server.Reports.Validate(); err != nil {
+		return err
+	}
+
+	// Validate usage metrics config
+	if err := c.Server.UsageMetrics.Validate(); err != nil {
+		return err
+	}
+
+	// Validate tenants config
+	if err := c.Server.Tenants.Validate(); err != nil {
+		return err
+	}
+
+	// Validate budgets config
+	if err := ceems_http.ValidateBudgets(c.Server.Budgets); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // CEEMSAPIServerConfig contains the configuration of CEEMS API server.
 type CEEMSAPIServerConfig struct {
-	Data  ceems_db.DataConfig  `yaml:"data"`
-	Admin ceems_db.AdminConfig `yaml:"admin"`
-	Web   ceems_http.WebConfig `yaml:"web"`
+	Data         ceems_db.DataConfig           `yaml:"data"`
+	Admin        ceems_db.AdminConfig          `yaml:"admin"`
+	Web          ceems_http.WebConfig          `yaml:"web"`
+	Budgets      []ceems_http.BudgetConfig     `yaml:"budgets"`
+	Reports      ceems_http.ReportsConfig      `yaml:"reports"`
+	UsageMetrics ceems_http.UsageMetricsConfig `yaml:"usage_metrics"`
+	Webhooks     webhooks.Config               `yaml:"webhooks"`
+	Tenants      ceems_http.TenantsConfig      `yaml:"tenants"`
+}
+
+// dbCollector is the interface of CEEMS DB that collects compute units.
+type dbCollector interface {
+	Collect(ctx context.Context) error
+	Backup(ctx context.Context) error
+	DeliverWebhooks(ctx context.Context) error
+	Stop() error
+}
+
+// apiServer is the interface of CEEMS API server.
+type apiServer interface {
+	Start(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// instance contains the DB and API server of a tenant.
+type instance struct {
+	id        string
+	logger    *slog.Logger
+	data      ceems_db.DataConfig
+	collector dbCollector
+	server    *ceems_http.CEEMSServer
+	cleanup   func()
+}
+
+// newInstances returns the DB and API server of each tenant. When tenants are
+// not configured, a single instance is returned.
+func newInstances(
+	config *CEEMSAPIAppConfig,
+	dbConfig *ceems_db.Config,
+	serverConfig *ceems_http.Config,
+) ([]instance, error) {
+	var instances []instance
+
+	// Without tenants, use the configs as they are
+	if !config.Server.Tenants.Enabled() {
+		server, cleanup, err := ceems_http.New(serverConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		collector, err := ceems_db.New(dbConfig)
+		if err != nil {
+			return []instance{{cleanup: cleanup}}, err
+		}
+
+		return []instance{{
+			logger:    dbConfig.Logger,
+			data:      dbConfig.Data,
+			collector: collector,
+			server:    server,
+			cleanup:   cleanup,
+		}}, nil
+	}
+
+	for _, tenant := range config.Server.Tenants.Instances {
+		logger := dbConfig.Logger.With("tenant", tenant.ID)
+
+		// Each tenant has its own DB in its data directory and fetches units
+		// only from its clusters
+		tenantDBConfig := *dbConfig
+		tenantDBConfig.Logger = logger
+		tenantDBConfig.Data.Path = tenant.Path
+		tenantDBConfig.Admin = tenant.Admin
+		tenantDBConfig.ResourceManager = resource.NewForClusters(tenant.Clusters)
+
+		if dbConfig.Data.BackupPath != "" {
+			tenantDBConfig.Data.BackupPath = filepath.Join(dbConfig.Data.BackupPath, tenant.ID)
+		}
+
+		tenantServerConfig := *serverConfig
+		tenantServerConfig.Logger = logger
+		tenantServerConfig.DB = tenantDBConfig
+		tenantServerConfig.Budgets = tenant.Budgets(serverConfig.Budgets)
+		tenantServerConfig.Tenant = tenant.ID
+
+		server, cleanup, err := ceems_http.New(&tenantServerConfig)
+		if err != nil {
+			return append(instances, instance{cleanup: cleanup}), fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+
+		collector, err := ceems_db.New(&tenantDBConfig)
+		if err != nil {
+			return append(instances, instance{cleanup: cleanup}), fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+
+		instances = append(instances, instance{
+			id:        tenant.ID,
+			logger:    logger,
+			data:      tenantDBConfig.Data,
+			collector: collector,
+			server:    server,
+			cleanup:   cleanup,
+		})
+	}
+
+	return instances, nil
 }
 
 // CEEMSServer represents the `ceems_server` cli.
@@ -268,6 +407,11 @@ func (b *CEEMSServer) Main() error {
 			ReadWritePaths: []string{config.Server.Data.Path, config.Server.Data.BackupPath},
 		}
 
+		// Data of tenants can be outside of data path
+		for _, tenant := range config.Server.Tenants.Instances {
+			securityCfg.ReadWritePaths = append(securityCfg.ReadWritePaths, tenant.Path)
+		}
+
 		// Drop all unnecessary privileges
 		if err := security.DropPrivileges(securityCfg); err != nil {
 			return err
@@ -283,10 +427,19 @@ func (b *CEEMSServer) Main() error {
 		Logger:          logger,
 		Data:            config.Server.Data,
 		Admin:           config.Server.Admin,
+		Webhooks:        config.Server.Webhooks,
 		ResourceManager: resource.New,
 		Updater:         updater.New,
 	}
 
+	// TSDB clients of updaters are used to add time series to reports of units
+	tsdbs, err := updater.TSDBClients(logger)
+	if err != nil {
+		logger.Error("Failed to setup TSDB clients of reports", "err", err)
+
+		return err
+	}
+
 	// Make server config.
 	serverConfig := &ceems_http.Config{
 		Logger: logger,
@@ -296,6 +449,7 @@ func (b *CEEMSServer) Main() error {
 			WebConfigFile:     webConfigFilePath,
 			EnableDebugServer: *enableDebugServer,
 			UserHeaderNames:   *userHeaders,
+			OIDC:              config.Server.Web.OIDC,
 			ExternalURL:       *externalURL,
 			RoutePrefix:       *routePrefix,
 			CORSOrigin:        corsRegex,
@@ -315,67 +469,59 @@ func (b *CEEMSServer) Main() error {
 						Address: "health",
 						Text:    "Health Status",
 					},
+					{
+						Address: "metrics",
+						Text:    "Metrics",
+					},
 				},
 			},
 		},
-		DB: *dbConfig,
+		DB:           *dbConfig,
+		Budgets:      config.Server.Budgets,
+		Reports:      config.Server.Reports,
+		TSDBs:        tsdbs,
+		UsageMetrics: config.Server.UsageMetrics,
 	}
 
-	// Create server instance.
-	apiServer, cleanup, err := ceems_http.New(serverConfig)
-	defer cleanup()
+	// Create an instance of DB and API server for each tenant. Without tenants,
+	// a single instance manages all the clusters.
+	instances, err := newInstances(config, dbConfig, serverConfig)
+	defer func() {
+		for _, inst := range instances {
+			inst.cleanup()
+		}
+	}()
 
 	if err != nil {
-		logger.Error("Failed to create ceems_server server", "err", err)
+		logger.Error("Failed to create ceems_server", "err", err)
 
 		return err
 	}
 
-	// Create DB instance.
-	collector, err := ceems_db.New(dbConfig)
-	if err != nil {
-		logger.Error("Failed to create ceems_server DB", "err", err)
+	var apiServer apiServer = instances[0].server
 
-		return err
+	if config.Server.Tenants.Enabled() {
+		servers := make(map[string]*ceems_http.CEEMSServer)
+		for _, inst := range instances {
+			servers[inst.id] = inst.server
+		}
+
+		if apiServer, err = ceems_http.NewTenantsServer(serverConfig, config.Server.Tenants, servers); err != nil {
+			logger.Error("Failed to create ceems_server server of tenants", "err", err)
+
+			return err
+		}
 	}
 
 	// Declare wait group and tickers.
 	var wg sync.WaitGroup
 
-	var dbUpdateTicker, dbBackupTicker *time.Ticker
+	var tickers []*time.Ticker
 
-	// Initialize tickers. We will stop the ticker immediately after signal has received.
-	dbUpdateTicker = time.NewTicker(time.Duration(config.Server.Data.UpdateInterval))
-
-	wg.Add(1)
-
-	go func() {
-		defer wg.Done()
-
-		for {
-			// This will ensure that we will run the method as soon as go routine
-			// starts instead of waiting for ticker to tick.
-			logger.Info("Updating CEEMS DB", "interval", config.Server.Data.UpdateInterval)
-
-			if err := collector.Collect(ctx); err != nil {
-				logger.Error("Failed to fetch data", "err", err)
-			}
-
-			select {
-			case <-dbUpdateTicker.C:
-				continue
-			case <-ctx.Done():
-				logger.Info("Received Interrupt. Stopping DB update")
-
-				return
-			}
-		}
-	}()
-
-	// Start backup go routine only backup path is provided in CLI.
-	if config.Server.Data.BackupPath != "" {
-		// Initialise ticker and increase waitgroup counter.
-		dbBackupTicker = time.NewTicker(time.Duration(config.Server.Data.BackupInterval))
+	for _, inst := range instances {
+		// Initialize tickers. We will stop the ticker immediately after signal has received.
+		dbUpdateTicker := time.NewTicker(time.Duration(inst.data.UpdateInterval))
+		tickers = append(tickers, dbUpdateTicker)
 
 		wg.Add(1)
 
@@ -383,23 +529,83 @@ func (b *CEEMSServer) Main() error {
 			defer wg.Done()
 
 			for {
+				// This will ensure that we will run the method as soon as go routine
+				// starts instead of waiting for ticker to tick.
+				inst.logger.Info("Updating CEEMS DB", "interval", inst.data.UpdateInterval)
+
+				if err := inst.collector.Collect(ctx); err != nil {
+					inst.logger.Error("Failed to fetch data", "err", err)
+				}
+
+				// Notify units streams about new updates
+				inst.server.NotifyUnitsUpdate()
+
 				select {
-				case <-dbBackupTicker.C:
-					// Dont run backup as soon as go routine is spawned. In prod, it
-					// can take very long depending on the size of DB and so wait until
-					// first tick to run it.
-					logger.Info("Backing up CEEMS DB", "interval", config.Server.Data.BackupInterval)
-
-					if err := collector.Backup(ctx); err != nil {
-						logger.Error("Failed to backup DB", "err", err)
-					}
+				case <-dbUpdateTicker.C:
+					continue
 				case <-ctx.Done():
-					logger.Info("Received Interrupt. Stopping DB backup")
+					inst.logger.Info("Received Interrupt. Stopping DB update")
 
 					return
 				}
 			}
 		}()
+
+		// Start backup go routine only backup path is provided in CLI.
+		if inst.data.BackupPath != "" {
+			// Initialise ticker and increase waitgroup counter.
+			dbBackupTicker := time.NewTicker(time.Duration(inst.data.BackupInterval))
+			tickers = append(tickers, dbBackupTicker)
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for {
+					select {
+					case <-dbBackupTicker.C:
+						// Dont run backup as soon as go routine is spawned. In prod, it
+						// can take very long depending on the size of DB and so wait until
+						// first tick to run it.
+						inst.logger.Info("Backing up CEEMS DB", "interval", inst.data.BackupInterval)
+
+						if err := inst.collector.Backup(ctx); err != nil {
+							inst.logger.Error("Failed to backup DB", "err", err)
+						}
+					case <-ctx.Done():
+						inst.logger.Info("Received Interrupt. Stopping DB backup")
+
+						return
+					}
+				}
+			}()
+		}
+
+		// Start webhooks delivery go routine only when webhooks are configured.
+		if config.Server.Webhooks.Enabled() {
+			webhooksTicker := time.NewTicker(time.Duration(config.Server.Webhooks.DeliveryInterval))
+			tickers = append(tickers, webhooksTicker)
+
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for {
+					select {
+					case <-webhooksTicker.C:
+						if err := inst.collector.DeliverWebhooks(ctx); err != nil {
+							inst.logger.Error("Failed to deliver webhooks", "err", err)
+						}
+					case <-ctx.Done():
+						inst.logger.Info("Received Interrupt. Stopping webhooks delivery")
+
+						return
+					}
+				}
+			}()
+		}
 	}
 
 	// Initializing the server in a goroutine so that
@@ -414,18 +620,18 @@ func (b *CEEMSServer) Main() error {
 	<-ctx.Done()
 
 	// Stop tickers.
-	dbUpdateTicker.Stop()
-
-	if config.Server.Data.BackupPath != "" {
-		dbBackupTicker.Stop()
+	for _, ticker := range tickers {
+		ticker.Stop()
 	}
 
 	// Wait for all DB go routines to finish.
 	wg.Wait()
 
 	// Close DB only after all DB go routines are done.
-	if err := collector.Stop(); err != nil {
-		logger.Error("Failed to close DB connection", "err", err)
+	for _, inst := range instances {
+		if err := inst.collector.Stop(); err != nil {
+			inst.logger.Error("Failed to close DB connection", "err", err)
+		}
 	}
 
 	// Restore default behavior on the interrupt signal and notify user of shutdown.
@@ -481,5 +687,38 @@ func createDirs(config *CEEMSAPIAppConfig) (*CEEMSAPIAppConfig, error) {
 		}
 	}
 
+	// Data of each tenant is in its own directory. By default, it is
+	// <data.path>/tenants/<id>. Backups of tenants are always in
+	// <data.backup_path>/<id>
+	var tenantPaths []string
+
+	for i, tenant := range config.Server.Tenants.Instances {
+		if tenant.Path == "" {
+			tenant.Path = filepath.Join(config.Server.Data.Path, "tenants", tenant.ID)
+		}
+
+		if config.Server.Tenants.Instances[i].Path, err = filepath.Abs(tenant.Path); err != nil {
+			return nil, fmt.Errorf("failed to get absolute path for data path of tenant %s: %w", tenant.ID, err)
+		}
+
+		// Default path of a tenant can still collide with the path of another one
+		if slices.Contains(tenantPaths, config.Server.Tenants.Instances[i].Path) {
+			return nil, fmt.Errorf("data path of tenant %s is used by another tenant", tenant.ID)
+		}
+
+		tenantPaths = append(tenantPaths, config.Server.Tenants.Instances[i].Path)
+
+		paths := []string{config.Server.Tenants.Instances[i].Path}
+		if config.Server.Data.BackupPath != "" {
+			paths = append(paths, filepath.Join(config.Server.Data.BackupPath, tenant.ID))
+		}
+
+		for _, path := range paths {
+			if err := os.MkdirAll(path, 0o750); err != nil {
+				return nil, fmt.Errorf("failed to create data directory of tenant %s: %w", tenant.ID, err)
+			}
+		}
+	}
+
 	return config, nil
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/mahendrapaipuri/ceems/internal/common"
 	"github.com/mahendrapaipuri/ceems/pkg/api/base"
 	"github.com/mahendrapaipuri/ceems/pkg/api/db"
+	ceems_http "github.com/mahendrapaipuri/ceems/pkg/api/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -85,6 +86,74 @@ func TestCEEMSConfigNestedDataDirs(t *testing.T) {
 	assert.True(t, filepath.IsAbs(config.Server.Data.BackupPath), "backup path is not absolute")
 }
 
+func TestCEEMSConfigTenantsDataDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "data")
+	backupDataDir := filepath.Join(tmpDir, "backup")
+	customDataDir := filepath.Join(tmpDir, "custom")
+
+	config := &CEEMSAPIAppConfig{
+		CEEMSAPIServerConfig{
+			Data: db.DataConfig{
+				Path:       dataDir,
+				BackupPath: backupDataDir,
+			},
+			Tenants: ceems_http.TenantsConfig{
+				Instances: []ceems_http.TenantConfig{
+					{ID: "inst-a", Clusters: []string{"slurm-0"}},
+					{ID: "inst-b", Clusters: []string{"slurm-1"}, Path: customDataDir},
+				},
+			},
+		},
+	}
+
+	// Setup data directories
+	var err error
+	config, err = createDirs(config)
+	require.NoError(t, err, "failed to create data directories")
+
+	// Check data dirs of tenants exists
+	assert.Equal(t, filepath.Join(dataDir, "tenants", "inst-a"), config.Server.Tenants.Instances[0].Path)
+	assert.Equal(t, customDataDir, config.Server.Tenants.Instances[1].Path)
+	assert.DirExists(t, filepath.Join(dataDir, "tenants", "inst-a"))
+	assert.DirExists(t, customDataDir)
+	assert.DirExists(t, filepath.Join(backupDataDir, "inst-a"))
+	assert.DirExists(t, filepath.Join(backupDataDir, "inst-b"))
+}
+
+func TestCEEMSConfigTenants(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_api_server:
+  data:
+    path: data
+  tenants:
+    instances:
+      - id: inst-a
+        clusters: [slurm-0]
+        admin:
+          users: [adm1]
+      - id: inst-b
+        clusters: [slurm-1]`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+
+	config, err := common.MakeConfig[CEEMSAPIAppConfig](configFilePath)
+	require.NoError(t, err)
+	require.NoError(t, config.Validate())
+
+	assert.Equal(t, "X-Ceems-Tenant", config.Server.Tenants.Header)
+	assert.Equal(t, []string{"adm1", base.CEEMSServiceAccount}, config.Server.Tenants.Instances[0].Admin.Users)
+	assert.Equal(t, []string{base.CEEMSServiceAccount}, config.Server.Tenants.Instances[1].Admin.Users)
+
+	// Duplicate tenant IDs must be rejected
+	config.Server.Tenants.Instances[1].ID = "inst-a"
+	assert.Error(t, config.Validate())
+}
+
 func TestCEEMSConfigMalformedData(t *testing.T) {
 	tmpDir := t.TempDir()
 	dataDir := filepath.Join(tmpDir, "data")
@@ -0,0 +1,278 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+)
+
+// Maximum size of an event in units stream.
+const maxEventSize = 64 * 1024 * 1024
+
+// Units returns a page of units of current user.
+func (c *Client) Units(ctx context.Context, q *UnitsQuery) (*Page[models.Unit], error) {
+	return request[models.Unit](ctx, c, http.MethodGet, "units", q.Values(), nil)
+}
+
+// UnitsAdmin returns a page of units of all users. Only admin users can access it.
+func (c *Client) UnitsAdmin(ctx context.Context, q *UnitsQuery) (*Page[models.Unit], error) {
+	return request[models.Unit](ctx, c, http.MethodGet, "units/admin", q.Values(), nil)
+}
+
+// VerifyUnits returns nil when all the units in query belong to current user.
+func (c *Client) VerifyUnits(ctx context.Context, q *VerifyQuery) error {
+	return c.check(ctx, c.URL("units/verify", q.Values()))
+}
+
+// StreamUnits subscribes to updates of units and calls fn with ID of the event and
+// updated units for each update. When lastEventID is not empty, units updated
+// after that event are sent first. It returns when ctx is cancelled, fn returns an
+// error or server closes the stream.
+func (c *Client) StreamUnits(
+	ctx context.Context,
+	q *UnitsQuery,
+	lastEventID string,
+	fn func(id string, units []models.Unit) error,
+) error {
+	req, err := c.newRequest(ctx, http.MethodGet, c.URL("units/stream", q.Values()), nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, maxEventSize)
+
+	var id, event string
+
+	var data bytes.Buffer
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Empty line dispatches the event
+		if line == "" {
+			if event == "units" && data.Len() > 0 {
+				var units []models.Unit
+				if err := json.Unmarshal(data.Bytes(), &units); err != nil {
+					return fmt.Errorf("failed to decode units event %s: %w", id, err)
+				}
+
+				if err := fn(id, units); err != nil {
+					return err
+				}
+			}
+
+			event = ""
+
+			data.Reset()
+
+			continue
+		}
+
+		// Lines starting with colon are comments
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "id":
+			id = value
+		case "event":
+			event = value
+		case "data":
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+
+			data.WriteString(value)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	return scanner.Err()
+}
+
+// Annotations returns annotations of unit.
+func (c *Client) Annotations(ctx context.Context, clusterID string, uuid string) ([]models.UnitAnnotation, error) {
+	return get[models.UnitAnnotation](ctx, c, annotationsResource(uuid), url.Values{"cluster_id": []string{clusterID}})
+}
+
+// CreateAnnotations adds annotations to unit. Existing keys are not overwritten.
+func (c *Client) CreateAnnotations(
+	ctx context.Context,
+	clusterID string,
+	uuid string,
+	annotations map[string]string,
+) ([]models.UnitAnnotation, error) {
+	page, err := request[models.UnitAnnotation](
+		ctx, c, http.MethodPost, annotationsResource(uuid), url.Values{"cluster_id": []string{clusterID}}, annotations,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Data, nil
+}
+
+// UpdateAnnotations adds or overwrites annotations of unit.
+func (c *Client) UpdateAnnotations(
+	ctx context.Context,
+	clusterID string,
+	uuid string,
+	annotations map[string]string,
+) ([]models.UnitAnnotation, error) {
+	page, err := request[models.UnitAnnotation](
+		ctx, c, http.MethodPatch, annotationsResource(uuid), url.Values{"cluster_id": []string{clusterID}}, annotations,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Data, nil
+}
+
+// DeleteAnnotations deletes annotations with keys of unit. When no keys are
+// provided, all annotations are deleted.
+func (c *Client) DeleteAnnotations(
+	ctx context.Context,
+	clusterID string,
+	uuid string,
+	keys ...string,
+) ([]models.UnitAnnotation, error) {
+	values := url.Values{"cluster_id": []string{clusterID}}
+	if len(keys) > 0 {
+		values["key"] = keys
+	}
+
+	page, err := request[models.UnitAnnotation](ctx, c, http.MethodDelete, annotationsResource(uuid), values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Data, nil
+}
+
+// Usage returns usage of current user in mode, which is one of CurrentUsage and
+// GlobalUsage.
+func (c *Client) Usage(ctx context.Context, mode string, q *UsageQuery) ([]models.Usage, error) {
+	return get[models.Usage](ctx, c, "usage/"+mode, q.Values())
+}
+
+// UsageAdmin returns usage of all users in mode, which is one of CurrentUsage and
+// GlobalUsage. Only admin users can access it.
+func (c *Client) UsageAdmin(ctx context.Context, mode string, q *UsageQuery) ([]models.Usage, error) {
+	return get[models.Usage](ctx, c, "usage/"+mode+"/admin", q.Values())
+}
+
+// Stats returns quick stats of clusters in mode, which is one of CurrentUsage and
+// GlobalUsage. Only admin users can access it.
+func (c *Client) Stats(ctx context.Context, mode string, q *StatsQuery) ([]models.Stat, error) {
+	return get[models.Stat](ctx, c, "stats/"+mode+"/admin", q.Values())
+}
+
+// Users returns a page of current user details.
+func (c *Client) Users(ctx context.Context, q *UsersQuery) (*Page[models.User], error) {
+	return request[models.User](ctx, c, http.MethodGet, "users", q.Values(), nil)
+}
+
+// UsersAdmin returns a page of all users. Only admin users can access it.
+func (c *Client) UsersAdmin(ctx context.Context, q *UsersQuery) (*Page[models.User], error) {
+	return request[models.User](ctx, c, http.MethodGet, "users/admin", q.Values(), nil)
+}
+
+// Projects returns a page of projects of current user.
+func (c *Client) Projects(ctx context.Context, q *ProjectsQuery) (*Page[models.Project], error) {
+	return request[models.Project](ctx, c, http.MethodGet, "projects", q.Values(), nil)
+}
+
+// ProjectsAdmin returns a page of all projects. Only admin users can access it.
+func (c *Client) ProjectsAdmin(ctx context.Context, q *ProjectsQuery) (*Page[models.Project], error) {
+	return request[models.Project](ctx, c, http.MethodGet, "projects/admin", q.Values(), nil)
+}
+
+// Clusters returns all clusters. Only admin users can access it.
+func (c *Client) Clusters(ctx context.Context) ([]models.Cluster, error) {
+	return get[models.Cluster](ctx, c, "clusters/admin", nil)
+}
+
+// Budgets returns budgets of projects of current user.
+func (c *Client) Budgets(ctx context.Context, q *BudgetsQuery) ([]models.Budget, error) {
+	return get[models.Budget](ctx, c, "budgets", q.Values())
+}
+
+// BudgetsAdmin returns budgets of all projects. Only admin users can access it.
+func (c *Client) BudgetsAdmin(ctx context.Context, q *BudgetsQuery) ([]models.Budget, error) {
+	return get[models.Budget](ctx, c, "budgets/admin", q.Values())
+}
+
+// Tokens returns personal API tokens of current user.
+func (c *Client) Tokens(ctx context.Context) ([]models.APIToken, error) {
+	return get[models.APIToken](ctx, c, "tokens", nil)
+}
+
+// CreateToken creates a new personal API token for current user.
+func (c *Client) CreateToken(ctx context.Context, token models.APITokenRequest) (*models.CreatedAPIToken, error) {
+	page, err := request[models.CreatedAPIToken](ctx, c, http.MethodPost, "tokens", nil, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Data) == 0 {
+		return nil, ErrNoData
+	}
+
+	return &page.Data[0], nil
+}
+
+// RevokeToken revokes personal API token with ID of current user.
+func (c *Client) RevokeToken(ctx context.Context, id string) error {
+	_, err := request[models.APIToken](ctx, c, http.MethodDelete, "tokens/"+url.PathEscape(id), nil, nil)
+
+	return err
+}
+
+// UnitReport returns report of unit in the format of query.
+func (c *Client) UnitReport(ctx context.Context, uuid string, q *ReportQuery) ([]byte, error) {
+	return c.raw(ctx, "reports/units/"+url.PathEscape(uuid), q.Values())
+}
+
+// ProjectReport returns monthly report of project in the format of query.
+func (c *Client) ProjectReport(ctx context.Context, name string, q *ReportQuery) ([]byte, error) {
+	return c.raw(ctx, "reports/projects/"+url.PathEscape(name), q.Values())
+}
+
+// DemoUnits returns mock units of demo resource.
+func (c *Client) DemoUnits(ctx context.Context) ([]models.Unit, error) {
+	return get[models.Unit](ctx, c, "demo/units", nil)
+}
+
+// DemoUsage returns mock usage of demo resource.
+func (c *Client) DemoUsage(ctx context.Context) ([]models.Usage, error) {
+	return get[models.Usage](ctx, c, "demo/usage", nil)
+}
+
+// annotationsResource returns the annotations resource of unit.
+func annotationsResource(uuid string) string {
+	return "units/" + url.PathEscape(uuid) + "/annotations"
+}
@@ -0,0 +1,318 @@
+// Package client implements a Go client for the REST API of CEEMS API server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+)
+
+// Status of API responses.
+const (
+	statusSuccess = "success"
+	statusError   = "error"
+)
+
+// Custom errors.
+var (
+	ErrNoAddress = errors.New("address of CEEMS API server is not configured")
+	ErrNoData    = errors.New("CEEMS API server response returned no data")
+)
+
+// Config contains the configuration of client.
+type Config struct {
+	// Address is the URL of CEEMS API server including the route prefix, if any,
+	// _e.g.,_ `https://ceems.example.com:9020`.
+	Address string
+
+	// User is the name of the user on whose behalf requests are made. It is sent
+	// in UserHeader.
+	User string
+
+	// UserHeader is the header used to send user name. Defaults to `X-Grafana-User`.
+	UserHeader string
+
+	// Token is a personal API token or an OIDC token sent in `Authorization` header.
+	Token string
+
+	// HTTPClient is used to make requests. Defaults to http.DefaultClient. Basic auth,
+	// TLS, _etc._ must be configured on the HTTP client.
+	HTTPClient *http.Client
+}
+
+// Client is a client of CEEMS API server.
+type Client struct {
+	url        *url.URL
+	client     *http.Client
+	user       string
+	userHeader string
+	token      string
+}
+
+// Error is the error returned by CEEMS API server.
+type Error struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("error response code %d from CEEMS API server: %s: %s", e.StatusCode, e.Type, e.Message)
+	}
+
+	return fmt.Sprintf("error response code %d from CEEMS API server: %s", e.StatusCode, e.Message)
+}
+
+// Page is a page of resources. When Next is not empty, it is the cursor of the
+// next page.
+type Page[T any] struct {
+	Data     []T
+	Warnings []string
+	Next     string
+}
+
+// response is the response model of CEEMS API server.
+type response[T any] struct {
+	Status    string   `json:"status"`
+	Data      []T      `json:"data"`
+	ErrorType string   `json:"errorType,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Next      string   `json:"next,omitempty"`
+}
+
+// New returns a new client of CEEMS API server.
+func New(c Config) (*Client, error) {
+	if c.Address == "" {
+		return nil, ErrNoAddress
+	}
+
+	// Unwrap original error to avoid leaking sensitive passwords in output
+	u, err := url.Parse(c.Address)
+	if err != nil {
+		return nil, errors.Unwrap(err)
+	}
+
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("missing scheme in address of CEEMS API server: %s", u.Redacted())
+	}
+
+	client := &Client{
+		url:        u,
+		client:     c.HTTPClient,
+		user:       c.User,
+		userHeader: c.UserHeader,
+		token:      c.Token,
+	}
+
+	if client.client == nil {
+		client.client = http.DefaultClient
+	}
+
+	if client.userHeader == "" {
+		client.userHeader = base.GrafanaUserHeader
+	}
+
+	return client, nil
+}
+
+// WithUser returns a copy of client that makes requests on behalf of user.
+func (c *Client) WithUser(user string) *Client {
+	client := *c
+	client.user = user
+
+	return &client
+}
+
+// URL returns the URL of API resource with query parameters.
+func (c *Client) URL(resource string, values url.Values) *url.URL {
+	u := c.url.JoinPath("api", base.APIVersion, resource)
+	u.RawQuery = values.Encode()
+
+	return u
+}
+
+// newRequest returns a new request to API resource.
+func (c *Client) newRequest(
+	ctx context.Context,
+	method string,
+	u *url.URL,
+	body any,
+) (*http.Request, error) {
+	var reader io.Reader
+
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.user != "" {
+		req.Header.Set(c.userHeader, c.user)
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	return req, nil
+}
+
+// do makes request and returns response when status code is successful.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+
+	return nil, responseError(resp)
+}
+
+// responseError returns the error of an unsuccessful response.
+func responseError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		apiErr.Message = err.Error()
+
+		return apiErr
+	}
+
+	// Errors from server are JSON responses. Other errors, like basic auth
+	// failures, are plain text
+	var data response[any]
+	if err := json.Unmarshal(body, &data); err == nil && data.Status == statusError {
+		apiErr.Type = data.ErrorType
+		apiErr.Message = data.Error
+	} else {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+// request makes a request to API resource and returns decoded response.
+func request[T any](
+	ctx context.Context,
+	c *Client,
+	method string,
+	resource string,
+	values url.Values,
+	body any,
+) (*Page[T], error) {
+	req, err := c.newRequest(ctx, method, c.URL(resource, values), body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var data response[T]
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode CEEMS API server response: %w", err)
+	}
+
+	if data.Status != statusSuccess {
+		return nil, &Error{StatusCode: resp.StatusCode, Type: data.ErrorType, Message: data.Error}
+	}
+
+	return &Page[T]{Data: data.Data, Warnings: data.Warnings, Next: nextCursor(data.Next)}, nil
+}
+
+// nextCursor returns cursor of next page from the link to next page.
+func nextCursor(next string) string {
+	if next == "" {
+		return ""
+	}
+
+	u, err := url.Parse(next)
+	if err != nil {
+		return ""
+	}
+
+	return u.Query().Get("cursor")
+}
+
+// get makes a GET request to API resource and returns data.
+func get[T any](ctx context.Context, c *Client, resource string, values url.Values) ([]T, error) {
+	page, err := request[T](ctx, c, http.MethodGet, resource, values, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return page.Data, nil
+}
+
+// raw makes a GET request to API resource and returns body of response.
+func (c *Client) raw(ctx context.Context, resource string, values url.Values) ([]byte, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.URL(resource, values), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// check makes a GET request and returns an error when response is not successful.
+// Body of successful responses is ignored.
+func (c *Client) check(ctx context.Context, u *url.URL) error {
+	req, err := c.newRequest(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// Health returns an error when CEEMS API server is not healthy.
+func (c *Client) Health(ctx context.Context) error {
+	return c.check(ctx, c.url.JoinPath("health"))
+}
@@ -0,0 +1,352 @@
+//go:build cgo
+// +build cgo
+
+package client
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/db"
+	"github.com/mahendrapaipuri/ceems/pkg/api/db/migrator"
+	ceems_http "github.com/mahendrapaipuri/ceems/pkg/api/http"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errStop = errors.New("stop")
+
+// setupServer starts a CEEMS API server backed by a DB with a few units and
+// returns its URL.
+func setupServer(t *testing.T) (*ceems_http.CEEMSServer, string) {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Create DB and apply migrations
+	dbConn, err := sql.Open("sqlite3", filepath.Join(tmpDir, base.CEEMSDBName))
+	require.NoError(t, err)
+
+	m, err := migrator.New(db.MigrationsFS, "migrations", logger)
+	require.NoError(t, err)
+	require.NoError(t, m.ApplyMigrations(dbConn))
+
+	for _, stmt := range []string{
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1000','job','foo','grp','foousr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1001','job','foo','grp','barusr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1002','job','baz','grp','bazusr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO usage (resource_manager,cluster_id,num_units,project,groupname,username,total_time_seconds,num_updates,last_updated_at) VALUES ('slurm','slurm-0',1,'foo','grp','foousr','{"walltime":3600}',1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO users (uid,cluster_id,resource_manager,name,projects,last_updated_at) VALUES ('','slurm-0','slurm','foousr','["foo"]','2024-10-10T10:00:00')`,
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-0','slurm','foo','["foousr","barusr"]','2024-10-10T10:00:00')`,
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-0','slurm','baz','["bazusr"]','2024-10-10T10:00:00')`,
+		`INSERT INTO admin_users (cluster_id,name,tags,last_updated_at) VALUES ('all','adm1','["ceems"]','2024-10-10T10:00:00')`,
+	} {
+		_, err = dbConn.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, dbConn.Close())
+
+	server, _, err := ceems_http.New(&ceems_http.Config{
+		Logger: logger,
+		DB: db.Config{
+			Data: db.DataConfig{
+				Path:     tmpDir,
+				Timezone: db.Timezone{Location: time.UTC},
+			},
+		},
+		Web: ceems_http.WebConfig{
+			Addresses:       []string{"localhost:9020"}, // dummy address
+			LandingConfig:   &web.LandingConfig{},
+			UserHeaderNames: []string{base.GrafanaUserHeader},
+		},
+	})
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler())
+
+	t.Cleanup(func() {
+		ts.Close()
+		server.Shutdown(context.Background())
+	})
+
+	return server, ts.URL
+}
+
+// newClient returns a new client for user.
+func newClient(t *testing.T, address string, user string) *Client {
+	t.Helper()
+
+	c, err := New(Config{Address: address, User: user})
+	require.NoError(t, err)
+
+	return c
+}
+
+// requireAPIError checks that err is an API error with status code.
+func requireAPIError(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, code, apiErr.StatusCode)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{})
+	require.ErrorIs(t, err, ErrNoAddress)
+
+	_, err = New(Config{Address: "localhost/ceems"})
+	require.Error(t, err)
+
+	c, err := New(Config{Address: "http://localhost:9020/ceems", User: "usr1"})
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9020/ceems/api/v1/units?uuid=1", c.URL("units", url.Values{"uuid": []string{"1"}}).String())
+
+	// Copy with another user must not modify client
+	assert.Equal(t, "usr2", c.WithUser("usr2").user)
+	assert.Equal(t, "usr1", c.user)
+}
+
+func TestQueries(t *testing.T) {
+	from := time.Unix(1728550800, 0)
+
+	assert.Equal(t,
+		url.Values{
+			"cluster_id": []string{"slurm-0", "slurm-1"},
+			"project":    []string{"foo"},
+			"running":    []string{"true"},
+			"from":       []string{"1728550800"},
+			"field":      []string{"uuid", "state"},
+			"sort":       []string{"-started_at_ts"},
+			"limit":      []string{"10"},
+		},
+		NewUnitsQuery().ClusterIDs("slurm-0").ClusterIDs("slurm-1").Projects("foo").Running().
+			From(from).Fields("uuid", "state").Sort("-started_at_ts").Limit(10).Values(),
+	)
+	assert.Equal(t, url.Values{"uuid": []string{"1"}, "time": []string{"1", "2"}}, NewVerifyQuery().UUIDs("1").Times(1, 2).Values())
+	assert.Equal(t, url.Values{"period": []string{"2024-02"}}, NewReportQuery().Period(2024, time.February).Values())
+
+	// Nil queries must be safe to use
+	var q *UsageQuery
+	assert.Empty(t, q.Values())
+
+	// Values must be a copy
+	uq := NewUsersQuery().Names("foo")
+	uq.Values().Add("user", "bar")
+	assert.Equal(t, url.Values{"user": []string{"foo"}}, uq.Values())
+}
+
+func TestClient(t *testing.T) {
+	_, address := setupServer(t)
+	ctx := context.Background()
+
+	foo := newClient(t, address, "foousr")
+	adm := newClient(t, address, "adm1")
+
+	// Health
+	require.NoError(t, foo.Health(ctx))
+
+	// Units of user
+	q := NewUnitsQuery().From(time.Unix(1728500000, 0)).To(time.Unix(1728600000, 0))
+
+	units, err := foo.Units(ctx, q)
+	require.NoError(t, err)
+	require.Len(t, units.Data, 1)
+	assert.Equal(t, "1000", units.Data[0].UUID)
+
+	// Admin resources are forbidden for normal users
+	_, err = foo.UnitsAdmin(ctx, q)
+	requireAPIError(t, err, http.StatusForbidden)
+
+	// Pages of units of all users
+	var uuids []string
+
+	aq := NewUnitsQuery().From(time.Unix(1728500000, 0)).To(time.Unix(1728600000, 0)).Sort("uuid").Limit(2)
+
+	for {
+		page, err := adm.UnitsAdmin(ctx, aq)
+		require.NoError(t, err)
+
+		for _, unit := range page.Data {
+			uuids = append(uuids, unit.UUID)
+		}
+
+		if page.Next == "" {
+			break
+		}
+
+		aq.Cursor(page.Next)
+	}
+
+	assert.Equal(t, []string{"1000", "1001", "1002"}, uuids)
+
+	// Verify ownership
+	require.NoError(t, foo.VerifyUnits(ctx, NewVerifyQuery().ClusterIDs("slurm-0").UUIDs("1000")))
+	requireAPIError(t, foo.VerifyUnits(ctx, NewVerifyQuery().ClusterIDs("slurm-0").UUIDs("1002")), http.StatusForbidden)
+
+	// Users and projects
+	users, err := foo.Users(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, users.Data, 1)
+	assert.Equal(t, "foousr", users.Data[0].Name)
+
+	projects, err := foo.Projects(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, projects.Data, 1)
+	assert.Equal(t, "foo", projects.Data[0].Name)
+
+	projects, err = adm.ProjectsAdmin(ctx, NewProjectsQuery().Names("baz"))
+	require.NoError(t, err)
+	require.Len(t, projects.Data, 1)
+	assert.Equal(t, "baz", projects.Data[0].Name)
+
+	admins, err := adm.UsersAdmin(ctx, NewUsersQuery().Role("admin"))
+	require.NoError(t, err)
+	require.Len(t, admins.Data, 1)
+	assert.Equal(t, "adm1", admins.Data[0].Name)
+
+	// Clusters
+	clusters, err := adm.Clusters(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []models.Cluster{{ID: "slurm-0", Manager: "slurm"}}, clusters)
+
+	// Usage and stats
+	usage, err := foo.Usage(ctx, GlobalUsage, NewUsageQuery().Fields("project", "username", "num_units"))
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "foo", usage[0].Project)
+	assert.Equal(t, int64(1), usage[0].NumUnits)
+
+	stats, err := adm.Stats(ctx, GlobalUsage, nil)
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(3), stats[0].NumUnits)
+
+	// Budgets
+	budgets, err := foo.Budgets(ctx, nil)
+	require.NoError(t, err)
+	assert.Empty(t, budgets)
+
+	// Reports
+	report, err := foo.UnitReport(ctx, "1000", NewReportQuery().Format(ReportHTML))
+	require.NoError(t, err)
+	assert.Contains(t, string(report), "<title>Report of compute unit 1000</title>")
+
+	report, err = foo.ProjectReport(ctx, "foo", NewReportQuery().Period(2024, time.October).Format(ReportPDF))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(report), "%PDF-"))
+
+	_, err = foo.UnitReport(ctx, "1002", nil)
+	requireAPIError(t, err, http.StatusNotFound)
+}
+
+func TestClientAnnotations(t *testing.T) {
+	_, address := setupServer(t)
+	ctx := context.Background()
+
+	foo := newClient(t, address, "foousr")
+
+	annotations, err := foo.CreateAnnotations(ctx, "slurm-0", "1000", map[string]string{"run": "benchmark", "step": "1"})
+	require.NoError(t, err)
+	assert.Len(t, annotations, 2)
+
+	// Existing annotations cannot be created again
+	_, err = foo.CreateAnnotations(ctx, "slurm-0", "1000", map[string]string{"run": "test"})
+	requireAPIError(t, err, http.StatusConflict)
+
+	_, err = foo.UpdateAnnotations(ctx, "slurm-0", "1000", map[string]string{"run": "test"})
+	require.NoError(t, err)
+
+	_, err = foo.DeleteAnnotations(ctx, "slurm-0", "1000", "step")
+	require.NoError(t, err)
+
+	annotations, err = foo.Annotations(ctx, "slurm-0", "1000")
+	require.NoError(t, err)
+	require.Len(t, annotations, 1)
+	assert.Equal(t, "run", annotations[0].Key)
+	assert.Equal(t, "test", annotations[0].Value)
+
+	// Annotations of units of other users are forbidden
+	_, err = foo.Annotations(ctx, "slurm-0", "1002")
+	requireAPIError(t, err, http.StatusForbidden)
+}
+
+func TestClientTokens(t *testing.T) {
+	_, address := setupServer(t)
+	ctx := context.Background()
+
+	foo := newClient(t, address, "foousr")
+
+	token, err := foo.CreateToken(ctx, models.APITokenRequest{Name: "ci", Scopes: []string{"units:read"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token.Token, "ceems_"))
+
+	tokens, err := foo.Tokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 1)
+	assert.Equal(t, "ci", tokens[0].Name)
+
+	// Make requests with token
+	tc, err := New(Config{Address: address, Token: token.Token})
+	require.NoError(t, err)
+
+	units, err := tc.Units(ctx, NewUnitsQuery().From(time.Unix(1728500000, 0)).To(time.Unix(1728600000, 0)))
+	require.NoError(t, err)
+	require.Len(t, units.Data, 1)
+
+	// Token does not have usage scope
+	_, err = tc.Usage(ctx, GlobalUsage, nil)
+	requireAPIError(t, err, http.StatusForbidden)
+
+	// Revoked tokens cannot be used
+	require.NoError(t, foo.RevokeToken(ctx, token.TokenID))
+
+	_, err = tc.Units(ctx, nil)
+	requireAPIError(t, err, http.StatusUnauthorized)
+}
+
+func TestClientStreamUnits(t *testing.T) {
+	_, address := setupServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	foo := newClient(t, address, "foousr")
+
+	// Units updated after last event are sent immediately
+	var gotID string
+
+	var gotUnits []models.Unit
+
+	err := foo.StreamUnits(ctx, NewUnitsQuery().Fields("uuid"), "2024-10-10T09:00:00", func(id string, units []models.Unit) error {
+		gotID = id
+		gotUnits = units
+
+		return errStop
+	})
+	require.ErrorIs(t, err, errStop)
+	assert.Equal(t, "2024-10-10T10:00:00+0000", gotID)
+	assert.Equal(t, []models.Unit{{UUID: "1000"}}, gotUnits)
+
+	// Stream ends when context is cancelled
+	sctx, scancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer scancel()
+
+	err = foo.StreamUnits(sctx, nil, "", func(string, []models.Unit) error { return nil })
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,514 @@
+package client
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Usage modes of usage and stats resources.
+const (
+	// CurrentUsage returns usage of compute units within the query window.
+	CurrentUsage = "current"
+	// GlobalUsage returns usage of all compute units.
+	GlobalUsage = "global"
+)
+
+// Report formats.
+const (
+	ReportHTML = "html"
+	ReportPDF  = "pdf"
+)
+
+// values is a set of query parameters. Methods of query builders are nil safe
+// so that a nil query can be passed to client methods.
+type values url.Values
+
+// add adds query parameters.
+func (v values) add(key string, vals ...string) values {
+	if v == nil {
+		v = make(values)
+	}
+
+	v[key] = append(v[key], vals...)
+
+	return v
+}
+
+// set sets a query parameter.
+func (v values) set(key string, val string) values {
+	if v == nil {
+		v = make(values)
+	}
+
+	v[key] = []string{val}
+
+	return v
+}
+
+// clone returns a copy of query parameters.
+func (v values) clone() url.Values {
+	c := make(url.Values, len(v))
+
+	for key, vals := range v {
+		c[key] = append([]string(nil), vals...)
+	}
+
+	return c
+}
+
+// UnitsQuery is a builder of query parameters of units resources.
+type UnitsQuery struct {
+	v values
+}
+
+// NewUnitsQuery returns a new units query.
+func NewUnitsQuery() *UnitsQuery {
+	return &UnitsQuery{}
+}
+
+// ClusterIDs filters units by cluster IDs.
+func (q *UnitsQuery) ClusterIDs(ids ...string) *UnitsQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// UUIDs filters units by UUIDs.
+func (q *UnitsQuery) UUIDs(uuids ...string) *UnitsQuery {
+	q.v = q.v.add("uuid", uuids...)
+
+	return q
+}
+
+// Projects filters units by projects.
+func (q *UnitsQuery) Projects(projects ...string) *UnitsQuery {
+	q.v = q.v.add("project", projects...)
+
+	return q
+}
+
+// Users filters units by users. Only admin users can filter by users.
+func (q *UnitsQuery) Users(users ...string) *UnitsQuery {
+	q.v = q.v.add("user", users...)
+
+	return q
+}
+
+// Running includes the running units in response.
+func (q *UnitsQuery) Running() *UnitsQuery {
+	q.v = q.v.set("running", "true")
+
+	return q
+}
+
+// From sets the start of query window.
+func (q *UnitsQuery) From(t time.Time) *UnitsQuery {
+	q.v = q.v.set("from", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// To sets the end of query window.
+func (q *UnitsQuery) To(t time.Time) *UnitsQuery {
+	q.v = q.v.set("to", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// Timezone sets the time zone of timestamps in response.
+func (q *UnitsQuery) Timezone(tz string) *UnitsQuery {
+	q.v = q.v.set("timezone", tz)
+
+	return q
+}
+
+// Annotations filters units by annotations, _e.g.,_ `run=benchmark`.
+func (q *UnitsQuery) Annotations(annotations ...string) *UnitsQuery {
+	q.v = q.v.add("annotation", annotations...)
+
+	return q
+}
+
+// Filters filters units by filter expressions, _e.g.,_ `avg_cpu_usage.global<10`.
+func (q *UnitsQuery) Filters(filters ...string) *UnitsQuery {
+	q.v = q.v.add("filter", filters...)
+
+	return q
+}
+
+// Fields sets the fields of units in response.
+func (q *UnitsQuery) Fields(fields ...string) *UnitsQuery {
+	q.v = q.v.add("field", fields...)
+
+	return q
+}
+
+// Sort sets the sort key. Prefix key with `-` to sort in descending order.
+func (q *UnitsQuery) Sort(key string) *UnitsQuery {
+	q.v = q.v.set("sort", key)
+
+	return q
+}
+
+// Limit sets the maximum number of units in response.
+func (q *UnitsQuery) Limit(limit int) *UnitsQuery {
+	q.v = q.v.set("limit", strconv.Itoa(limit))
+
+	return q
+}
+
+// Cursor sets the cursor of page.
+func (q *UnitsQuery) Cursor(cursor string) *UnitsQuery {
+	q.v = q.v.set("cursor", cursor)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *UnitsQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// UsageQuery is a builder of query parameters of usage resources.
+type UsageQuery struct {
+	v values
+}
+
+// NewUsageQuery returns a new usage query.
+func NewUsageQuery() *UsageQuery {
+	return &UsageQuery{}
+}
+
+// ClusterIDs filters usage by cluster IDs.
+func (q *UsageQuery) ClusterIDs(ids ...string) *UsageQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// Projects filters usage by projects.
+func (q *UsageQuery) Projects(projects ...string) *UsageQuery {
+	q.v = q.v.add("project", projects...)
+
+	return q
+}
+
+// Users filters usage by users. Only admin users can filter by users.
+func (q *UsageQuery) Users(users ...string) *UsageQuery {
+	q.v = q.v.add("user", users...)
+
+	return q
+}
+
+// From sets the start of query window of current usage.
+func (q *UsageQuery) From(t time.Time) *UsageQuery {
+	q.v = q.v.set("from", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// To sets the end of query window of current usage.
+func (q *UsageQuery) To(t time.Time) *UsageQuery {
+	q.v = q.v.set("to", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// Fields sets the fields of usage in response.
+func (q *UsageQuery) Fields(fields ...string) *UsageQuery {
+	q.v = q.v.add("field", fields...)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *UsageQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// StatsQuery is a builder of query parameters of stats resource.
+type StatsQuery struct {
+	v values
+}
+
+// NewStatsQuery returns a new stats query.
+func NewStatsQuery() *StatsQuery {
+	return &StatsQuery{}
+}
+
+// ClusterIDs filters stats by cluster IDs.
+func (q *StatsQuery) ClusterIDs(ids ...string) *StatsQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// From sets the start of query window of current stats.
+func (q *StatsQuery) From(t time.Time) *StatsQuery {
+	q.v = q.v.set("from", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// To sets the end of query window of current stats.
+func (q *StatsQuery) To(t time.Time) *StatsQuery {
+	q.v = q.v.set("to", strconv.FormatInt(t.Unix(), 10))
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *StatsQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// UsersQuery is a builder of query parameters of users resources.
+type UsersQuery struct {
+	v values
+}
+
+// NewUsersQuery returns a new users query.
+func NewUsersQuery() *UsersQuery {
+	return &UsersQuery{}
+}
+
+// ClusterIDs filters users by cluster IDs.
+func (q *UsersQuery) ClusterIDs(ids ...string) *UsersQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// Names filters users by names. Only admin users can filter by names.
+func (q *UsersQuery) Names(names ...string) *UsersQuery {
+	q.v = q.v.add("user", names...)
+
+	return q
+}
+
+// Role filters users by role. Only `admin` role is supported and only admin
+// users can filter by role.
+func (q *UsersQuery) Role(role string) *UsersQuery {
+	q.v = q.v.set("role", role)
+
+	return q
+}
+
+// Sort sets the sort key. Prefix key with `-` to sort in descending order.
+func (q *UsersQuery) Sort(key string) *UsersQuery {
+	q.v = q.v.set("sort", key)
+
+	return q
+}
+
+// Limit sets the maximum number of users in response.
+func (q *UsersQuery) Limit(limit int) *UsersQuery {
+	q.v = q.v.set("limit", strconv.Itoa(limit))
+
+	return q
+}
+
+// Cursor sets the cursor of page.
+func (q *UsersQuery) Cursor(cursor string) *UsersQuery {
+	q.v = q.v.set("cursor", cursor)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *UsersQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// ProjectsQuery is a builder of query parameters of projects resources.
+type ProjectsQuery struct {
+	v values
+}
+
+// NewProjectsQuery returns a new projects query.
+func NewProjectsQuery() *ProjectsQuery {
+	return &ProjectsQuery{}
+}
+
+// ClusterIDs filters projects by cluster IDs.
+func (q *ProjectsQuery) ClusterIDs(ids ...string) *ProjectsQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// Names filters projects by names.
+func (q *ProjectsQuery) Names(names ...string) *ProjectsQuery {
+	q.v = q.v.add("project", names...)
+
+	return q
+}
+
+// Sort sets the sort key. Prefix key with `-` to sort in descending order.
+func (q *ProjectsQuery) Sort(key string) *ProjectsQuery {
+	q.v = q.v.set("sort", key)
+
+	return q
+}
+
+// Limit sets the maximum number of projects in response.
+func (q *ProjectsQuery) Limit(limit int) *ProjectsQuery {
+	q.v = q.v.set("limit", strconv.Itoa(limit))
+
+	return q
+}
+
+// Cursor sets the cursor of page.
+func (q *ProjectsQuery) Cursor(cursor string) *ProjectsQuery {
+	q.v = q.v.set("cursor", cursor)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *ProjectsQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// VerifyQuery is a builder of query parameters of units verification resource.
+type VerifyQuery struct {
+	v values
+}
+
+// NewVerifyQuery returns a new verify query.
+func NewVerifyQuery() *VerifyQuery {
+	return &VerifyQuery{}
+}
+
+// ClusterIDs sets the cluster IDs of units.
+func (q *VerifyQuery) ClusterIDs(ids ...string) *VerifyQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// UUIDs sets the UUIDs of units.
+func (q *VerifyQuery) UUIDs(uuids ...string) *VerifyQuery {
+	q.v = q.v.add("uuid", uuids...)
+
+	return q
+}
+
+// Times sets the timestamps in milliseconds at which units must be running.
+func (q *VerifyQuery) Times(times ...int64) *VerifyQuery {
+	for _, t := range times {
+		q.v = q.v.add("time", strconv.FormatInt(t, 10))
+	}
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *VerifyQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// BudgetsQuery is a builder of query parameters of budgets resources.
+type BudgetsQuery struct {
+	v values
+}
+
+// NewBudgetsQuery returns a new budgets query.
+func NewBudgetsQuery() *BudgetsQuery {
+	return &BudgetsQuery{}
+}
+
+// ClusterIDs filters budgets by cluster IDs.
+func (q *BudgetsQuery) ClusterIDs(ids ...string) *BudgetsQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// Projects filters budgets by projects.
+func (q *BudgetsQuery) Projects(projects ...string) *BudgetsQuery {
+	q.v = q.v.add("project", projects...)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *BudgetsQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
+
+// ReportQuery is a builder of query parameters of reports resources.
+type ReportQuery struct {
+	v values
+}
+
+// NewReportQuery returns a new report query.
+func NewReportQuery() *ReportQuery {
+	return &ReportQuery{}
+}
+
+// ClusterIDs sets the cluster IDs of unit or project.
+func (q *ReportQuery) ClusterIDs(ids ...string) *ReportQuery {
+	q.v = q.v.add("cluster_id", ids...)
+
+	return q
+}
+
+// Period sets the month of project reports.
+func (q *ReportQuery) Period(year int, month time.Month) *ReportQuery {
+	q.v = q.v.set("period", time.Date(year, month, 1, 0, 0, 0, 0, time.UTC).Format("2006-01"))
+
+	return q
+}
+
+// Timezone sets the time zone of timestamps in unit reports.
+func (q *ReportQuery) Timezone(tz string) *ReportQuery {
+	q.v = q.v.set("timezone", tz)
+
+	return q
+}
+
+// Format sets the format of report. One of ReportHTML and ReportPDF.
+func (q *ReportQuery) Format(format string) *ReportQuery {
+	q.v = q.v.set("format", format)
+
+	return q
+}
+
+// Values returns query parameters.
+func (q *ReportQuery) Values() url.Values {
+	if q == nil {
+		return url.Values{}
+	}
+
+	return q.v.clone()
+}
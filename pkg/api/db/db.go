@@ -23,6 +23,7 @@ import (
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/mahendrapaipuri/ceems/pkg/api/resource"
 	"github.com/mahendrapaipuri/ceems/pkg/api/updater"
+	"github.com/mahendrapaipuri/ceems/pkg/api/webhooks"
 	"github.com/mahendrapaipuri/ceems/pkg/grafana"
 	ceems_sqlite3 "github.com/mahendrapaipuri/ceems/pkg/sqlite3"
 	"github.com/mattn/go-sqlite3"
@@ -204,6 +205,7 @@ type Config struct {
 	Logger          *slog.Logger
 	Data            DataConfig
 	Admin           AdminConfig
+	Webhooks        webhooks.Config
 	ResourceManager func(*slog.Logger) (*resource.Manager, error)
 	Updater         func(*slog.Logger) (*updater.UnitUpdater, error)
 }
@@ -235,14 +237,15 @@ type adminConfig struct {
 
 // stats struct implements fetching compute units, users and project data.
 type stats struct {
-	logger  *slog.Logger
-	db      *sql.DB
-	dbConn  *ceems_sqlite3.Conn
-	emptyDB bool
-	manager *resource.Manager
-	updater *updater.UnitUpdater
-	storage *storageConfig
-	admin   *adminConfig
+	logger   *slog.Logger
+	db       *sql.DB
+	dbConn   *ceems_sqlite3.Conn
+	emptyDB  bool
+	manager  *resource.Manager
+	updater  *updater.UnitUpdater
+	storage  *storageConfig
+	admin    *adminConfig
+	webhooks *webhooks.Webhooks
 }
 
 // SQLite DB related constant vars.
@@ -383,18 +386,30 @@ func New(c *Config) (*stats, error) {
 		return nil, err
 	}
 
+	// Setup webhooks that deliver lifecycle events of units
+	var unitWebhooks *webhooks.Webhooks
+
+	if c.Webhooks.Enabled() {
+		if unitWebhooks, err = webhooks.New(c.Webhooks, c.Logger); err != nil {
+			c.Logger.Error("Webhooks setup failed", "err", err)
+
+			return nil, err
+		}
+	}
+
 	// Emit debug logs
 	c.Logger.Debug("Storage config", "cfg", storageConfig)
 
 	return &stats{
-		logger:  c.Logger,
-		db:      db,
-		dbConn:  dbConn,
-		emptyDB: emptyDB,
-		manager: manager,
-		updater: updater,
-		storage: storageConfig,
-		admin:   adminConfig,
+		logger:   c.Logger,
+		db:       db,
+		dbConn:   dbConn,
+		emptyDB:  emptyDB,
+		manager:  manager,
+		updater:  updater,
+		storage:  storageConfig,
+		admin:    adminConfig,
+		webhooks: unitWebhooks,
 	}, nil
 }
 
@@ -442,6 +457,15 @@ func (s *stats) Backup(ctx context.Context) error {
 	return s.createBackup(ctx)
 }
 
+// DeliverWebhooks delivers pending lifecycle events of units to webhook endpoints.
+func (s *stats) DeliverWebhooks(ctx context.Context) error {
+	if s.webhooks == nil {
+		return nil
+	}
+
+	return s.webhooks.Deliver(ctx, s.db)
+}
+
 // Close DB connection.
 func (s *stats) Stop() error {
 	return s.db.Close()
@@ -577,6 +601,38 @@ func (s *stats) purgeExpiredUnits(ctx context.Context, tx *sql.Tx) error {
 		s.logger.Debug("DB update", "usage_deleted", usageDeleted)
 	}
 
+	// Purge annotations of units that do not exist anymore
+	deleteAnnotationsQuery := fmt.Sprintf(
+		"DELETE FROM %[1]s WHERE NOT EXISTS (SELECT 1 FROM %[2]s WHERE %[2]s.cluster_id = %[1]s.cluster_id AND %[2]s.uuid = %[1]s.uuid)",
+		base.AnnotationsDBTableName,
+		base.UnitsDBTableName,
+	) // #nosec
+	if _, err := tx.ExecContext(ctx, deleteAnnotationsQuery); err != nil {
+		return err
+	}
+
+	// Get changes
+	var annotationsDeleted int
+	if err := tx.QueryRowContext(ctx, "SELECT changes()").Scan(&annotationsDeleted); err == nil {
+		s.logger.Debug("DB update", "annotations_deleted", annotationsDeleted)
+	}
+
+	// Purge expired API tokens. Keep a margin of one day to avoid timezone issues
+	// as expiry times are stored in DB timezone
+	deleteTokensQuery := fmt.Sprintf(
+		"DELETE FROM %s WHERE expires_at <= date('now', '-1 day')",
+		base.APITokensDBTableName,
+	) // #nosec
+	if _, err := tx.ExecContext(ctx, deleteTokensQuery); err != nil {
+		return err
+	}
+
+	// Get changes
+	var tokensDeleted int
+	if err := tx.QueryRowContext(ctx, "SELECT changes()").Scan(&tokensDeleted); err == nil {
+		s.logger.Debug("DB update", "tokens_deleted", tokensDeleted)
+	}
+
 	return nil
 }
 
@@ -610,6 +666,24 @@ func (s *stats) execStatements(
 	// Get current day midnight
 	todayMidnight := currentTime.Truncate(24 * time.Hour).Format(base.DatetimeLayout)
 
+	// When webhooks are enabled, prepare statement to get the state of unit before
+	// updating it to detect lifecycle events. On a brand new DB, all the units will
+	// be seen for the first time and we do not want to flood endpoints with events
+	// of historical units.
+	var unitStateStmt *sql.Stmt
+
+	if s.webhooks != nil && !s.emptyDB {
+		unitStateStmt, err = tx.PrepareContext(
+			ctx,
+			fmt.Sprintf("SELECT started_at_ts, ended_at_ts FROM %s WHERE cluster_id = ? AND uuid = ? ORDER BY id DESC LIMIT 1", base.UnitsDBTableName),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to prepare statement for unit state: %w", err)
+		}
+
+		defer unitStateStmt.Close()
+	}
+
 	var unitIncr int
 
 	for _, cluster := range clusterUnits {
@@ -619,6 +693,20 @@ func (s *stats) execStatements(
 				continue
 			}
 
+			// Get state of unit before update
+			var prevState *webhooks.UnitState
+
+			if unitStateStmt != nil {
+				state := webhooks.UnitState{}
+
+				switch err = unitStateStmt.QueryRowContext(ctx, cluster.Cluster.ID, unit.UUID).Scan(&state.StartedAtTS, &state.EndedAtTS); {
+				case err == nil:
+					prevState = &state
+				case !errors.Is(err, sql.ErrNoRows):
+					s.logger.Error("Failed to fetch state of unit from DB", "cluster_id", cluster.Cluster.ID, "uuid", unit.UUID, "err", err)
+				}
+			}
+
 			// s.logger.Debug("Inserting unit", "id", unit.Jobid)
 			// Use named parameters to not to repeat the values
 			if _, err = stmts[base.UnitsDBTableName].ExecContext(
@@ -658,6 +746,13 @@ func (s *stats) execStatements(
 				sql.Named(base.UnitsDBTableStructFieldColNameMap["LastUpdatedAt"], currentTime.Format(base.DatetimeLayout)),
 			); err != nil {
 				s.logger.Error("Failed to insert unit in DB", "cluster_id", cluster.Cluster.ID, "uuid", unit.UUID, "err", err)
+			} else if unitStateStmt != nil {
+				// Add lifecycle events of unit to webhooks outbox
+				if events := s.webhooks.Events(prevState, unit); len(events) > 0 {
+					if err = s.webhooks.Enqueue(ctx, tx, cluster.Cluster.ID, unit, events); err != nil {
+						s.logger.Error("Failed to enqueue webhook events", "cluster_id", cluster.Cluster.ID, "uuid", unit.UUID, "err", err)
+					}
+				}
 			}
 
 			// If the unit has started in this update period, increment num units
@@ -748,6 +843,13 @@ func (s *stats) execStatements(
 	// Update projects
 	for _, cluster := range clusterProjects {
 		for _, project := range cluster.Projects {
+			// Managers are nil when resource manager failed to fetch them. Use NULL
+			// so that the managers in DB are kept until next successful fetch
+			var managers any
+			if project.Managers != nil {
+				managers = project.Managers
+			}
+
 			if _, err = stmts[base.ProjectsDBTableName].ExecContext(
 				ctx,
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["ClusterID"], cluster.Cluster.ID),
@@ -755,6 +857,7 @@ func (s *stats) execStatements(
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["UID"], project.UID),
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["Name"], project.Name),
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["Users"], project.Users),
+				sql.Named(base.ProjectsDBTableStructFieldColNameMap["Managers"], managers),
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["Tags"], project.Tags),
 				sql.Named(base.ProjectsDBTableStructFieldColNameMap["LastUpdatedAt"], project.LastUpdatedAt),
 			); err != nil {
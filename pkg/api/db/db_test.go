@@ -21,6 +21,7 @@ import (
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/mahendrapaipuri/ceems/pkg/api/resource"
 	"github.com/mahendrapaipuri/ceems/pkg/api/updater"
+	"github.com/mahendrapaipuri/ceems/pkg/api/webhooks"
 	"github.com/mahendrapaipuri/ceems/pkg/grafana"
 	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
@@ -1033,6 +1034,14 @@ func TestUnitStatsDeleteOldUnits(t *testing.T) {
 	err = s.execStatements(ctx, tx, time.Now().Add(-time.Minute), time.Now(), units, nil, nil)
 	require.NoError(t, err)
 
+	// Add an annotation to the unit which should be deleted along with the unit
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf("INSERT INTO %s (cluster_id,uuid,username,key,value) VALUES ('default',?,'usr1','foo','bar')", base.AnnotationsDBTableName),
+		unitID,
+	)
+	require.NoError(t, err)
+
 	// Now clean up DB for old units
 	err = s.purgeExpiredUnits(ctx, tx)
 	require.NoError(t, err, "failed to delete old entries in DB")
@@ -1049,4 +1058,129 @@ func TestUnitStatsDeleteOldUnits(t *testing.T) {
 	err = result.QueryRow(unitID).Scan(&numRows)
 	require.NoError(t, err, "failed to query DB")
 	assert.Equal(t, 0, numRows, "expected 0 rows after deletion")
+
+	// Query for deleted annotations
+	err = s.db.QueryRow(
+		fmt.Sprintf("SELECT COUNT(uuid) FROM %s WHERE uuid = ?;", base.AnnotationsDBTableName), unitID,
+	).Scan(&numRows)
+	require.NoError(t, err, "failed to query DB")
+	assert.Equal(t, 0, numRows, "expected 0 annotations after deletion")
+}
+
+func TestUnitStatsWebhookEvents(t *testing.T) {
+	tmpDir := t.TempDir()
+	c, err := prepareMockConfig(tmpDir)
+	require.NoError(t, err, "failed to create mock config")
+
+	c.Webhooks = webhooks.Config{
+		Endpoints: []webhooks.EndpointConfig{
+			{Name: "all", URL: "http://localhost:9999", HTTPClientConfig: config_util.DefaultHTTPClientConfig},
+		},
+		FailedStates: []string{"FAILED"},
+		MaxRetries:   1,
+		MinBackoff:   model.Duration(time.Minute),
+		MaxBackoff:   model.Duration(time.Hour),
+	}
+
+	// Make new stats DB
+	s, err := New(c)
+	defer s.Stop()
+	require.NoError(t, err, "failed to create new stats")
+
+	// Events must be emitted only for updates of a DB with existing data
+	s.emptyDB = false
+
+	ctx := context.Background()
+
+	update := func(units ...models.Unit) {
+		for i := range units {
+			units[i].TotalTime = models.MetricMap{
+				"walltime": 0, "alloc_cputime": 0, "alloc_cpumemtime": 0, "alloc_gputime": 0, "alloc_gpumemtime": 0,
+			}
+		}
+
+		tx, err := s.db.Begin()
+		require.NoError(t, err)
+		require.NoError(t, s.execStatements(
+			ctx, tx, time.Now().Add(-time.Minute), time.Now(),
+			[]models.ClusterUnits{{Cluster: models.Cluster{ID: "slurm-0"}, Units: units}}, nil, nil,
+		))
+		require.NoError(t, tx.Commit())
+	}
+
+	// Pending, running and finished units
+	update(
+		models.Unit{UUID: "1", State: "PENDING"},
+		models.Unit{UUID: "2", State: "RUNNING", StartedAtTS: 1000},
+	)
+
+	// Unit 1 starts and unit 2 fails
+	update(
+		models.Unit{UUID: "1", State: "RUNNING", StartedAtTS: 2000},
+		models.Unit{UUID: "2", State: "FAILED", StartedAtTS: 1000, EndedAtTS: 3000},
+	)
+
+	// No new events on updates of ended units
+	update(models.Unit{UUID: "2", State: "FAILED", StartedAtTS: 1000, EndedAtTS: 3000})
+
+	rows, err := s.db.Query("SELECT event FROM webhook_outbox ORDER BY id")
+	require.NoError(t, err)
+
+	defer rows.Close()
+
+	var events []string
+
+	for rows.Next() {
+		var event string
+		require.NoError(t, rows.Scan(&event))
+
+		events = append(events, event)
+	}
+
+	assert.Equal(t, []string{"unit.started", "unit.started", "unit.ended", "unit.failed"}, events)
+}
+
+func TestProjectManagersUpdate(t *testing.T) {
+	tmpDir := t.TempDir()
+	c, err := prepareMockConfig(tmpDir)
+	require.NoError(t, err, "failed to create mock config")
+
+	// Make new stats DB
+	s, err := New(c)
+	defer s.Stop()
+	require.NoError(t, err, "failed to create new stats")
+
+	ctx := context.Background()
+
+	update := func(managers models.List) models.List {
+		tx, err := s.db.Begin()
+		require.NoError(t, err)
+		require.NoError(t, s.execStatements(
+			ctx, tx, time.Now().Add(-time.Minute), time.Now(), nil, nil,
+			[]models.ClusterProjects{
+				{
+					Cluster:  models.Cluster{ID: "slurm-0"},
+					Projects: []models.Project{{Name: "acc1", Users: models.List{"usr1"}, Managers: managers}},
+				},
+			},
+		))
+		require.NoError(t, tx.Commit())
+
+		var got models.List
+		require.NoError(t, s.db.QueryRow("SELECT managers FROM projects WHERE cluster_id = 'slurm-0' AND name = 'acc1'").Scan(&got))
+
+		return got
+	}
+
+	// Managers failed to fetch on a new project
+	assert.Empty(t, update(nil))
+
+	// Managers fetched
+	assert.Equal(t, models.List{"usr2"}, update(models.List{"usr2"}))
+
+	// Managers failed to fetch must not remove existing managers
+	assert.Equal(t, models.List{"usr2"}, update(nil))
+
+	// Managers removed
+	assert.Empty(t, update(models.List{}))
 }
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_key_value;
+DROP INDEX IF EXISTS uq_cluster_id_uuid_key;
+DROP TABLE IF EXISTS annotations;
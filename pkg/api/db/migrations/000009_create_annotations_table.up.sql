@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS annotations (
+ "id" integer not null primary key,
+ "cluster_id" text,
+ "uuid" text,
+ "username" text,
+ "key" text,
+ "value" text,
+ "created_at" text,
+ "last_updated_at" text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_cluster_id_uuid_key ON annotations (cluster_id,uuid,key);
+CREATE INDEX IF NOT EXISTS idx_key_value ON annotations (key,value);
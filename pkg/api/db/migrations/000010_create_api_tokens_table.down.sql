@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_api_tokens_username;
+DROP INDEX IF EXISTS uq_api_tokens_hash;
+DROP INDEX IF EXISTS uq_token_id;
+DROP TABLE IF EXISTS api_tokens;
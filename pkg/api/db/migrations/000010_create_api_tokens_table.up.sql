@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_tokens (
+ "id" integer not null primary key,
+ "token_id" text not null,
+ "username" text not null,
+ "name" text not null default '',
+ "hash" text not null,
+ "scopes" text not null default '[]',
+ "created_at" text not null,
+ "expires_at" text not null,
+ "last_used_at" text not null default ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_token_id ON api_tokens (token_id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_api_tokens_hash ON api_tokens (hash);
+CREATE INDEX IF NOT EXISTS idx_api_tokens_username ON api_tokens (username);
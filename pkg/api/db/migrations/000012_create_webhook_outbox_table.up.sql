@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS webhook_outbox (
+ "id" integer not null primary key,
+ "event_id" text not null,
+ "endpoint" text not null,
+ "event" text not null,
+ "payload" text not null,
+ "attempts" integer not null default 0,
+ "next_attempt_at" integer not null default 0,
+ "created_at" text not null,
+ "last_error" text not null default ''
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_next_attempt_at ON webhook_outbox (next_attempt_at);
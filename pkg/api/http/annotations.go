@@ -0,0 +1,694 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+)
+
+// Limits on annotations.
+const (
+	maxAnnotationsBodySize = 64 * 1024 // 64 KiB
+	maxAnnotationValueLen  = 1024
+	maxAnnotationsPerReq   = 64
+)
+
+var (
+	// Annotation keys must start with a letter or underscore and can contain
+	// only alphanumeric characters, underscores, dots and hyphens.
+	annotationKeyRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.-]{0,62}$`)
+
+	// annotationsJoinQuery joins the annotations of each unit as a JSON object
+	// to units table.
+	annotationsJoinQuery = fmt.Sprintf(
+		"LEFT JOIN (SELECT cluster_id AS ann_cluster_id,uuid AS ann_uuid,json_group_object(key,value) AS annotations FROM %s GROUP BY cluster_id,uuid) ON ann_cluster_id = %s.cluster_id AND ann_uuid = %s.uuid",
+		base.AnnotationsDBTableName, base.UnitsDBTableName, base.UnitsDBTableName,
+	)
+)
+
+// parseAnnotationFilter parses the annotation filter of form `key` or `key:value`
+// and returns key, value and a bool indicating if value is present in the filter.
+func parseAnnotationFilter(filter string) (string, string, bool, error) {
+	key, value, found := strings.Cut(strings.TrimSpace(filter), ":")
+	if !annotationKeyRegex.MatchString(key) {
+		return "", "", false, fmt.Errorf("%w: %s", errInvalidAnnotation, filter)
+	}
+
+	return key, value, found, nil
+}
+
+// annotationsFilterQuery returns a sub query that filters units based on annotations.
+func annotationsFilterQuery(key string, value string, hasValue bool) Query {
+	q := Query{}
+	q.query(
+		fmt.Sprintf(
+			"SELECT 1 FROM %[1]s WHERE %[1]s.cluster_id = %[2]s.cluster_id AND %[1]s.uuid = %[2]s.uuid AND %[1]s.key = ",
+			base.AnnotationsDBTableName, base.UnitsDBTableName,
+		),
+	)
+	q.param([]string{key})
+
+	if hasValue {
+		q.query(fmt.Sprintf(" AND %s.value = ", base.AnnotationsDBTableName))
+		q.param([]string{value})
+	}
+
+	return q
+}
+
+// validateAnnotations validates the key/value pairs of annotations.
+func validateAnnotations(annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return fmt.Errorf("%w: no annotations found in request", errInvalidAnnotation)
+	}
+
+	if len(annotations) > maxAnnotationsPerReq {
+		return fmt.Errorf("%w: maximum of %d annotations allowed per request", errInvalidAnnotation, maxAnnotationsPerReq)
+	}
+
+	for key, value := range annotations {
+		if !annotationKeyRegex.MatchString(key) {
+			return fmt.Errorf("%w: invalid key %s", errInvalidAnnotation, key)
+		}
+
+		if len(value) > maxAnnotationValueLen {
+			return fmt.Errorf("%w: value of key %s exceeds %d characters", errInvalidAnnotation, key, maxAnnotationValueLen)
+		}
+	}
+
+	return nil
+}
+
+// annotationsTarget returns the cluster ID and UUID of the unit that is being
+// annotated after checking that the current user has access to the unit.
+func (s *CEEMSServer) annotationsTarget(w http.ResponseWriter, r *http.Request) (string, string, bool) {
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Get UUID from path parameters
+	uuid, exists := mux.Vars(r)["uuid"]
+	if !exists || uuid == "" {
+		errorResponse[any](w, &apiError{errorBadData, errMissingUUIDs}, s.logger, nil)
+
+		return "", "", false
+	}
+
+	// Cluster ID is mandatory as UUIDs are not unique across clusters
+	clusterID := r.URL.Query().Get("cluster_id")
+	if clusterID == "" {
+		errorResponse[any](w, &apiError{errorBadData, errMissingClusterID}, s.logger, nil)
+
+		return "", "", false
+	}
+
+	// Admin users can annotate any unit. Only check if unit exists
+	if r.Header.Get(base.AdminUserHeader) != "" {
+		q := Query{}
+		q.query("SELECT uuid,cluster_id FROM " + base.UnitsDBTableName)
+		q.query(" WHERE cluster_id IN ")
+		q.param([]string{clusterID})
+		q.query(" AND uuid IN ")
+		q.param([]string{uuid})
+
+		units, err := s.queriers.unit(r.Context(), s.db, q, s.logger)
+		if err != nil {
+			s.logger.Error("Failed to fetch unit", "logged_user", loggedUser, "cluster_id", clusterID, "uuid", uuid, "err", err)
+			errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+			return "", "", false
+		}
+
+		if len(units) == 0 {
+			errorResponse[any](w, &apiError{errorNotFound, errUnitNotFound}, s.logger, nil)
+
+			return "", "", false
+		}
+
+		return clusterID, uuid, true
+	}
+
+	// Check if user has access to the unit
+	if !VerifyOwnership(r.Context(), loggedUser, []string{clusterID}, []string{uuid}, nil, s.db, s.logger) {
+		errorResponse[any](w, &apiError{errorForbidden, errNoAuth}, s.logger, nil)
+
+		return "", "", false
+	}
+
+	return clusterID, uuid, true
+}
+
+// unitAnnotations returns all the annotations of a given unit.
+func (s *CEEMSServer) unitAnnotations(ctx context.Context, clusterID string, uuid string) ([]models.UnitAnnotation, error) {
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(base.AnnotationsDBTableColNames, ","), base.AnnotationsDBTableName))
+	q.query(" WHERE cluster_id IN ")
+	q.param([]string{clusterID})
+	q.query(" AND uuid IN ")
+	q.param([]string{uuid})
+	q.query(" ORDER BY key ASC ")
+
+	return s.queriers.annotation(ctx, s.db, q, s.logger)
+}
+
+// writeAnnotationsResponse writes current annotations of the unit in response.
+func (s *CEEMSServer) writeAnnotationsResponse(w http.ResponseWriter, r *http.Request, code int, clusterID string, uuid string) {
+	annotations, err := s.unitAnnotations(r.Context(), clusterID, uuid)
+	if annotations == nil && err != nil {
+		s.logger.Error("Failed to fetch annotations", "cluster_id", clusterID, "uuid", uuid, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Write response
+	w.WriteHeader(code)
+
+	response := Response[models.UnitAnnotation]{
+		Status: "success",
+		Data:   annotations,
+	}
+	if err != nil {
+		response.Warnings = append(response.Warnings, err.Error())
+	}
+
+	if err = json.NewEncoder(w).Encode(&response); err != nil {
+		s.logger.Error("Failed to encode response", "err", err)
+		w.Write([]byte("KO"))
+	}
+}
+
+// decodeAnnotations decodes and validates annotations in request body.
+func (s *CEEMSServer) decodeAnnotations(w http.ResponseWriter, r *http.Request) (map[string]string, error) {
+	var annotations map[string]string
+
+	// Limit request body size
+	r.Body = http.MaxBytesReader(w, r.Body, maxAnnotationsBodySize)
+
+	if err := json.NewDecoder(r.Body).Decode(&annotations); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidAnnotation, err)
+	}
+
+	if err := validateAnnotations(annotations); err != nil {
+		return nil, err
+	}
+
+	return annotations, nil
+}
+
+// listAnnotations godoc
+//
+//	@Summary		List annotations of a compute unit
+//	@Description	This endpoint will return all the annotations of a given compute unit. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	The current user must either be the owner of the compute unit, belong
+//	@Description	to the same project as the compute unit or be an admin user.
+//	@Description
+//	@Description	The query parameter `cluster_id` is mandatory as compute unit UUIDs are
+//	@Description	not unique across different clusters.
+//	@Security		BasicAuth
+//	@Tags			annotations
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string	true	"Current user name"
+//	@Param			uuid			path		string	true	"Unit UUID"
+//	@Param			cluster_id		query		string	true	"Cluster ID"
+//	@Success		200				{object}	Response[models.UnitAnnotation]
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/units/{uuid}/annotations [get]
+//
+// GET /units/{uuid}/annotations
+// List annotations of a unit.
+func (s *CEEMSServer) listAnnotations(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "list annotations endpoint", s.logger)
+
+	// Set headers
+	s.setHeaders(w)
+
+	// Get target unit
+	clusterID, uuid, ok := s.annotationsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeAnnotationsResponse(w, r, http.StatusOK, clusterID, uuid)
+}
+
+// createAnnotations godoc
+//
+//	@Summary		Create annotations on a compute unit
+//	@Description	This endpoint will add new annotations to a given compute unit. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	The request body must be a JSON object of key/value pairs where both keys
+//	@Description	and values are strings. Keys must start with a letter or underscore and can
+//	@Description	only contain alphanumeric characters, underscores, dots and hyphens. Values
+//	@Description	cannot be longer than 1024 characters.
+//	@Description
+//	@Description	The current user must either be the owner of the compute unit, belong
+//	@Description	to the same project as the compute unit or be an admin user. Created
+//	@Description	annotations are owned by the current user.
+//	@Description
+//	@Description	If an annotation with the same key already exists on the compute unit,
+//	@Description	a response 409 will be returned and none of the annotations in the
+//	@Description	request will be created. Use `PATCH` method to update existing annotations.
+//	@Description
+//	@Description	The response contains all the annotations of the compute unit.
+//	@Security		BasicAuth
+//	@Tags			annotations
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string				true	"Current user name"
+//	@Param			uuid			path		string				true	"Unit UUID"
+//	@Param			cluster_id		query		string				true	"Cluster ID"
+//	@Param			annotations		body		map[string]string	true	"Annotations"
+//	@Success		201				{object}	Response[models.UnitAnnotation]
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		409				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/units/{uuid}/annotations [post]
+//
+// POST /units/{uuid}/annotations
+// Create annotations on a unit.
+func (s *CEEMSServer) createAnnotations(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "create annotations endpoint", s.logger)
+
+	// Set headers
+	s.setHeaders(w)
+
+	// Get target unit
+	clusterID, uuid, ok := s.annotationsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Get annotations from body
+	annotations, err := s.decodeAnnotations(w, r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
+	// Insert annotations
+	if err := s.writeAnnotations(r.Context(), func(tx *sql.Tx) error {
+		return insertAnnotations(r.Context(), tx, clusterID, uuid, s.getUser(r), s.currentTime(), annotations)
+	}); err != nil {
+		s.annotationsErrorResponse(w, err)
+
+		return
+	}
+
+	s.writeAnnotationsResponse(w, r, http.StatusCreated, clusterID, uuid)
+}
+
+// updateAnnotations godoc
+//
+//	@Summary		Update annotations of a compute unit
+//	@Description	This endpoint will update existing annotations of a given compute unit. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	The request body must be a JSON object of key/value pairs where both keys
+//	@Description	and values are strings.
+//	@Description
+//	@Description	Users can only update the annotations that they own. Admin users can update
+//	@Description	any annotation. If an annotation in the request does not exist, a response
+//	@Description	404 will be returned and none of the annotations will be updated.
+//	@Description
+//	@Description	The response contains all the annotations of the compute unit.
+//	@Security		BasicAuth
+//	@Tags			annotations
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string				true	"Current user name"
+//	@Param			uuid			path		string				true	"Unit UUID"
+//	@Param			cluster_id		query		string				true	"Cluster ID"
+//	@Param			annotations		body		map[string]string	true	"Annotations"
+//	@Success		200				{object}	Response[models.UnitAnnotation]
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/units/{uuid}/annotations [patch]
+//
+// PATCH /units/{uuid}/annotations
+// Update annotations of a unit.
+func (s *CEEMSServer) updateAnnotations(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "update annotations endpoint", s.logger)
+
+	// Set headers
+	s.setHeaders(w)
+
+	// Get target unit
+	clusterID, uuid, ok := s.annotationsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Get annotations from body
+	annotations, err := s.decodeAnnotations(w, r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
+	// Owner of annotations. Admin users can update any annotation
+	var owner string
+	if r.Header.Get(base.AdminUserHeader) == "" {
+		owner = s.getUser(r)
+	}
+
+	// Update annotations
+	if err := s.writeAnnotations(r.Context(), func(tx *sql.Tx) error {
+		return updateAnnotations(r.Context(), tx, clusterID, uuid, owner, s.currentTime(), annotations)
+	}); err != nil {
+		s.annotationsErrorResponse(w, err)
+
+		return
+	}
+
+	s.writeAnnotationsResponse(w, r, http.StatusOK, clusterID, uuid)
+}
+
+// deleteAnnotations godoc
+//
+//	@Summary		Delete annotations of a compute unit
+//	@Description	This endpoint will delete annotations of a given compute unit. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	Annotations to delete are identified by `key` query parameters. If no
+//	@Description	`key` query parameter is provided, all the annotations owned by the
+//	@Description	current user on the compute unit will be deleted.
+//	@Description
+//	@Description	Users can only delete the annotations that they own. Admin users can delete
+//	@Description	any annotation.
+//	@Description
+//	@Description	The response contains all the remaining annotations of the compute unit.
+//	@Security		BasicAuth
+//	@Tags			annotations
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string		true	"Current user name"
+//	@Param			uuid			path		string		true	"Unit UUID"
+//	@Param			cluster_id		query		string		true	"Cluster ID"
+//	@Param			key				query		[]string	false	"Annotation keys"	collectionFormat(multi)
+//	@Success		200				{object}	Response[models.UnitAnnotation]
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/units/{uuid}/annotations [delete]
+//
+// DELETE /units/{uuid}/annotations
+// Delete annotations of a unit.
+func (s *CEEMSServer) deleteAnnotations(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "delete annotations endpoint", s.logger)
+
+	// Set headers
+	s.setHeaders(w)
+
+	// Get target unit
+	clusterID, uuid, ok := s.annotationsTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Get keys from query parameters
+	keys := r.URL.Query()["key"]
+	for _, key := range keys {
+		if !annotationKeyRegex.MatchString(key) {
+			errorResponse[any](w, &apiError{errorBadData, fmt.Errorf("%w: invalid key %s", errInvalidAnnotation, key)}, s.logger, nil)
+
+			return
+		}
+	}
+
+	// Owner of annotations. Admin users can delete any annotation
+	var owner string
+	if r.Header.Get(base.AdminUserHeader) == "" {
+		owner = s.getUser(r)
+	}
+
+	// Delete annotations
+	if err := s.writeAnnotations(r.Context(), func(tx *sql.Tx) error {
+		return deleteAnnotations(r.Context(), tx, clusterID, uuid, owner, keys)
+	}); err != nil {
+		s.annotationsErrorResponse(w, err)
+
+		return
+	}
+
+	s.writeAnnotationsResponse(w, r, http.StatusOK, clusterID, uuid)
+}
+
+// currentTime returns current time string in DB time zone.
+func (s *CEEMSServer) currentTime() string {
+	return time.Now().In(s.dbConfig.Data.Timezone.Location).Format(base.DatetimezoneLayout)
+}
+
+// writeAnnotations executes the annotations write function in a transaction.
+func (s *CEEMSServer) writeAnnotations(ctx context.Context, f func(*sql.Tx) error) error {
+	tx, err := s.writeDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin SQL transcation: %w", err)
+	}
+
+	if err := f(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			s.logger.Error("Failed to rollback SQL transaction", "err", rbErr)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit SQL transcation: %w", err)
+	}
+
+	return nil
+}
+
+// annotationsErrorResponse writes error response based on type of error.
+func (s *CEEMSServer) annotationsErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errAnnotationExists):
+		errorResponse[any](w, &apiError{errorConflict, err}, s.logger, nil)
+	case errors.Is(err, errAnnotationNotFound):
+		errorResponse[any](w, &apiError{errorNotFound, err}, s.logger, nil)
+	case errors.Is(err, errNoAuth):
+		errorResponse[any](w, &apiError{errorForbidden, err}, s.logger, nil)
+	default:
+		s.logger.Error("Failed to write annotations", "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+	}
+}
+
+// annotationOwners returns a map of existing annotation keys to their owners.
+func annotationOwners(ctx context.Context, tx *sql.Tx, clusterID string, uuid string) (map[string]string, error) {
+	rows, err := tx.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT key,username FROM %s WHERE cluster_id = ? AND uuid = ?", base.AnnotationsDBTableName),
+		clusterID, uuid,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owners := make(map[string]string)
+
+	for rows.Next() {
+		var key, user string
+		if err := rows.Scan(&key, &user); err != nil {
+			return nil, err
+		}
+
+		owners[key] = user
+	}
+
+	return owners, rows.Err()
+}
+
+// insertAnnotations inserts new annotations of the unit.
+func insertAnnotations(
+	ctx context.Context,
+	tx *sql.Tx,
+	clusterID string,
+	uuid string,
+	user string,
+	now string,
+	annotations map[string]string,
+) error {
+	owners, err := annotationOwners(ctx, tx, clusterID, uuid)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		fmt.Sprintf(
+			"INSERT INTO %s (cluster_id,uuid,username,key,value,created_at,last_updated_at) VALUES (?,?,?,?,?,?,?)",
+			base.AnnotationsDBTableName,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, key := range sortedKeys(annotations) {
+		if _, ok := owners[key]; ok {
+			return fmt.Errorf("%w: %s", errAnnotationExists, key)
+		}
+
+		if _, err := stmt.ExecContext(ctx, clusterID, uuid, user, key, annotations[key], now, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// updateAnnotations updates existing annotations of the unit. If owner is not empty,
+// only annotations owned by owner can be updated.
+func updateAnnotations(
+	ctx context.Context,
+	tx *sql.Tx,
+	clusterID string,
+	uuid string,
+	owner string,
+	now string,
+	annotations map[string]string,
+) error {
+	owners, err := annotationOwners(ctx, tx, clusterID, uuid)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		fmt.Sprintf(
+			"UPDATE %s SET value = ?, last_updated_at = ? WHERE cluster_id = ? AND uuid = ? AND key = ?",
+			base.AnnotationsDBTableName,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, key := range sortedKeys(annotations) {
+		user, ok := owners[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", errAnnotationNotFound, key)
+		}
+
+		if owner != "" && user != owner {
+			return fmt.Errorf("%w: annotation %s", errNoAuth, key)
+		}
+
+		if _, err := stmt.ExecContext(ctx, annotations[key], now, clusterID, uuid, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteAnnotations deletes annotations of the unit. If owner is not empty,
+// only annotations owned by owner can be deleted. If keys are empty, all
+// annotations (of owner) will be deleted.
+func deleteAnnotations(
+	ctx context.Context,
+	tx *sql.Tx,
+	clusterID string,
+	uuid string,
+	owner string,
+	keys []string,
+) error {
+	owners, err := annotationOwners(ctx, tx, clusterID, uuid)
+	if err != nil {
+		return err
+	}
+
+	// If no keys are provided, delete all annotations of the owner
+	if len(keys) == 0 {
+		for key, user := range owners {
+			if owner == "" || user == owner {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	stmt, err := tx.PrepareContext(
+		ctx,
+		fmt.Sprintf(
+			"DELETE FROM %s WHERE cluster_id = ? AND uuid = ? AND key = ?",
+			base.AnnotationsDBTableName,
+		),
+	)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, key := range keys {
+		user, ok := owners[key]
+		if !ok {
+			return fmt.Errorf("%w: %s", errAnnotationNotFound, key)
+		}
+
+		if owner != "" && user != owner {
+			return fmt.Errorf("%w: annotation %s", errNoAuth, key)
+		}
+
+		if _, err := stmt.ExecContext(ctx, clusterID, uuid, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns sorted keys of map.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
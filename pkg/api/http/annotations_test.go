@@ -0,0 +1,332 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotationsHandlers(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		uuid        string
+		query       string
+		body        string
+		user        string
+		admin       bool
+		code        int
+		annotations map[string]string
+	}{
+		{
+			name:        "create annotations by owner",
+			method:      http.MethodPost,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			body:        `{"doi":"10.1000/xyz123","run":"benchmark"}`,
+			user:        "foousr",
+			code:        201,
+			annotations: map[string]string{"doi": "10.1000/xyz123", "run": "benchmark"},
+		},
+		{
+			name:        "create annotations by project member",
+			method:      http.MethodPost,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			body:        `{"reviewed":"yes"}`,
+			user:        "barusr",
+			code:        201,
+			annotations: map[string]string{"doi": "10.1000/xyz123", "run": "benchmark", "reviewed": "yes"},
+		},
+		{
+			name:   "create existing annotation",
+			method: http.MethodPost,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"run":"other"}`,
+			user:   "foousr",
+			code:   409,
+		},
+		{
+			name:   "create annotations by non owner",
+			method: http.MethodPost,
+			uuid:   "1002",
+			query:  "cluster_id=slurm-0",
+			body:   `{"run":"benchmark"}`,
+			user:   "foousr",
+			code:   403,
+		},
+		{
+			name:   "create annotations with invalid key",
+			method: http.MethodPost,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"1run":"benchmark"}`,
+			user:   "foousr",
+			code:   400,
+		},
+		{
+			name:   "create annotations with malformed body",
+			method: http.MethodPost,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"run":1}`,
+			user:   "foousr",
+			code:   400,
+		},
+		{
+			name:   "create annotations without cluster_id",
+			method: http.MethodPost,
+			uuid:   "1000",
+			body:   `{"run":"benchmark"}`,
+			user:   "foousr",
+			code:   400,
+		},
+		{
+			name:   "create annotations by admin on non existing unit",
+			method: http.MethodPost,
+			uuid:   "2000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"run":"benchmark"}`,
+			user:   "adm1",
+			admin:  true,
+			code:   404,
+		},
+		{
+			name:        "update annotations by owner",
+			method:      http.MethodPatch,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			body:        `{"run":"production"}`,
+			user:        "foousr",
+			code:        200,
+			annotations: map[string]string{"doi": "10.1000/xyz123", "run": "production", "reviewed": "yes"},
+		},
+		{
+			name:   "update annotations owned by other user",
+			method: http.MethodPatch,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"reviewed":"no"}`,
+			user:   "foousr",
+			code:   403,
+		},
+		{
+			name:   "update non existing annotation",
+			method: http.MethodPatch,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0",
+			body:   `{"foo":"bar"}`,
+			user:   "foousr",
+			code:   404,
+		},
+		{
+			name:        "update annotations by admin",
+			method:      http.MethodPatch,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			body:        `{"reviewed":"no"}`,
+			user:        "adm1",
+			admin:       true,
+			code:        200,
+			annotations: map[string]string{"doi": "10.1000/xyz123", "run": "production", "reviewed": "no"},
+		},
+		{
+			name:        "list annotations",
+			method:      http.MethodGet,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			user:        "barusr",
+			code:        200,
+			annotations: map[string]string{"doi": "10.1000/xyz123", "run": "production", "reviewed": "no"},
+		},
+		{
+			name:   "delete annotations owned by other user",
+			method: http.MethodDelete,
+			uuid:   "1000",
+			query:  "cluster_id=slurm-0&key=reviewed",
+			user:   "foousr",
+			code:   403,
+		},
+		{
+			name:        "delete annotations by key",
+			method:      http.MethodDelete,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0&key=doi",
+			user:        "foousr",
+			code:        200,
+			annotations: map[string]string{"run": "production", "reviewed": "no"},
+		},
+		{
+			name:        "delete all annotations of user",
+			method:      http.MethodDelete,
+			uuid:        "1000",
+			query:       "cluster_id=slurm-0",
+			user:        "foousr",
+			code:        200,
+			annotations: map[string]string{"reviewed": "no"},
+		},
+	}
+
+	for _, test := range tests {
+		var body io.Reader
+		if test.body != "" {
+			body = strings.NewReader(test.body)
+		}
+
+		request := httptest.NewRequest(test.method, "/api/"+base.APIVersion+"/units/"+test.uuid+"/annotations?"+test.query, body)
+		request = mux.SetURLVars(request, map[string]string{"uuid": test.uuid})
+		request.Header.Set(base.LoggedUserHeader, test.user)
+
+		if test.admin {
+			request.Header.Set(base.AdminUserHeader, test.user)
+		}
+
+		// Start recorder
+		w := httptest.NewRecorder()
+
+		switch test.method {
+		case http.MethodPost:
+			server.createAnnotations(w, request)
+		case http.MethodPatch:
+			server.updateAnnotations(w, request)
+		case http.MethodDelete:
+			server.deleteAnnotations(w, request)
+		default:
+			server.listAnnotations(w, request)
+		}
+
+		res := w.Result()
+		defer res.Body.Close()
+
+		// Get body
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+
+		if test.annotations == nil {
+			continue
+		}
+
+		// Unmarshal byte into structs.
+		var response Response[models.UnitAnnotation]
+		require.NoError(t, json.Unmarshal(data, &response), test.name)
+
+		got := make(map[string]string)
+		for _, a := range response.Data {
+			got[a.Key] = a.Value
+		}
+
+		assert.Equal(t, test.annotations, got, test.name)
+	}
+}
+
+func TestUnitsHandlerWithAnnotations(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add annotations
+	for _, a := range []struct {
+		uuid string
+		user string
+		body string
+	}{
+		{uuid: "1000", user: "foousr", body: `{"run":"benchmark","doi":"10.1000/xyz123"}`},
+		{uuid: "1001", user: "barusr", body: `{"run":"production"}`},
+	} {
+		request := httptest.NewRequest(http.MethodPost, "/api/"+base.APIVersion+"/units/"+a.uuid+"/annotations?cluster_id=slurm-0", strings.NewReader(a.body))
+		request = mux.SetURLVars(request, map[string]string{"uuid": a.uuid})
+		request.Header.Set(base.LoggedUserHeader, a.user)
+
+		w := httptest.NewRecorder()
+		server.createAnnotations(w, request)
+		require.Equal(t, 201, w.Code)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		uuids []string
+	}{
+		{
+			name:  "filter by key",
+			query: "annotation=run",
+			code:  200,
+			uuids: []string{"1000", "1001"},
+		},
+		{
+			name:  "filter by key and value",
+			query: "annotation=run:benchmark",
+			code:  200,
+			uuids: []string{"1000"},
+		},
+		{
+			name:  "filter by multiple annotations",
+			query: "annotation=run&annotation=doi:10.1000/xyz123",
+			code:  200,
+			uuids: []string{"1000"},
+		},
+		{
+			name:  "filter with no matches",
+			query: "annotation=run:test",
+			code:  200,
+		},
+		{
+			name:  "filter with invalid key",
+			query: "annotation=-run",
+			code:  400,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(
+			http.MethodGet,
+			"/api/"+base.APIVersion+"/units/admin?from=1728500000&to=1728600000&"+test.query,
+			nil,
+		)
+
+		w := httptest.NewRecorder()
+		server.unitsAdmin(w, request)
+
+		res := w.Result()
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+
+		if test.code != 200 {
+			continue
+		}
+
+		var response Response[models.Unit]
+		require.NoError(t, json.Unmarshal(data, &response), test.name)
+
+		var uuids []string
+
+		for _, unit := range response.Data {
+			uuids = append(uuids, unit.UUID)
+
+			// Annotations must be included in response
+			assert.Contains(t, unit.Annotations, "run", test.name)
+		}
+
+		assert.Equal(t, test.uuids, uuids, test.name)
+	}
+}
@@ -0,0 +1,518 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Supported budget periods.
+const (
+	monthlyBudget   = "monthly"
+	quarterlyBudget = "quarterly"
+	yearlyBudget    = "yearly"
+)
+
+// budgetResources maps budgeted resources to keys of total_time_seconds
+// metric map in daily_usage table.
+var budgetResources = map[string]string{
+	"cpu_hours": "alloc_cputime",
+	"gpu_hours": "alloc_gputime",
+}
+
+// BudgetConfig contains the configuration of budget of a project.
+type BudgetConfig struct {
+	Name           string             `yaml:"name"`
+	ClusterID      string             `yaml:"cluster_id"`
+	Project        string             `yaml:"project"`
+	Period         string             `yaml:"period"`
+	Start          string             `yaml:"start"`
+	End            string             `yaml:"end"`
+	BurnRateWindow model.Duration     `yaml:"burn_rate_window"`
+	Limits         map[string]float64 `yaml:"limits"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *BudgetConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = BudgetConfig{
+		Period:         quarterlyBudget,
+		BurnRateWindow: model.Duration(7 * 24 * time.Hour),
+	}
+
+	type plain BudgetConfig
+
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	// Use project as name when not provided
+	if c.Name == "" {
+		c.Name = c.Project
+	}
+
+	return nil
+}
+
+// Validate validates the config.
+func (c *BudgetConfig) Validate() error {
+	if c.ClusterID == "" || c.Project == "" {
+		return errors.New("cluster_id and project are required in budget config")
+	}
+
+	if len(c.Limits) == 0 {
+		return fmt.Errorf("no limits found in budget %s", c.Name)
+	}
+
+	for resource, limit := range c.Limits {
+		if _, ok := budgetResources[resource]; !ok {
+			return fmt.Errorf("unknown resource %s in budget %s", resource, c.Name)
+		}
+
+		if limit <= 0 {
+			return fmt.Errorf("limit of resource %s in budget %s must be positive", resource, c.Name)
+		}
+	}
+
+	if c.BurnRateWindow <= 0 {
+		return fmt.Errorf("burn_rate_window of budget %s must be positive", c.Name)
+	}
+
+	// When start and end are provided, budget is for a fixed period
+	if c.Start != "" || c.End != "" {
+		start, err := time.Parse(time.DateOnly, c.Start)
+		if err != nil {
+			return fmt.Errorf("invalid start of budget %s: %w", c.Name, err)
+		}
+
+		end, err := time.Parse(time.DateOnly, c.End)
+		if err != nil {
+			return fmt.Errorf("invalid end of budget %s: %w", c.Name, err)
+		}
+
+		if !end.After(start) {
+			return fmt.Errorf("end of budget %s must be after start", c.Name)
+		}
+
+		return nil
+	}
+
+	if !slices.Contains([]string{monthlyBudget, quarterlyBudget, yearlyBudget}, c.Period) {
+		return fmt.Errorf("invalid period %s of budget %s. Supported periods are monthly, quarterly and yearly", c.Period, c.Name)
+	}
+
+	return nil
+}
+
+// ValidateBudgets validates the budgets config. Budgets must be unique by name,
+// cluster and project as they are exported as metrics with these labels.
+func ValidateBudgets(budgets []BudgetConfig) error {
+	type key struct{ name, clusterID, project string }
+
+	seen := make(map[key]bool)
+
+	for _, budget := range budgets {
+		if err := budget.Validate(); err != nil {
+			return err
+		}
+
+		k := key{budget.Name, budget.ClusterID, budget.Project}
+		if seen[k] {
+			return fmt.Errorf("duplicate budget %s of project %s in cluster %s", budget.Name, budget.Project, budget.ClusterID)
+		}
+
+		seen[k] = true
+	}
+
+	return nil
+}
+
+// bounds returns the start and end of the budget period that contains now.
+// Recurring periods are aligned to calendar months in the location of now.
+func (c *BudgetConfig) bounds(now time.Time) (time.Time, time.Time) {
+	loc := now.Location()
+
+	if c.Start != "" && c.End != "" {
+		start, _ := time.ParseInLocation(time.DateOnly, c.Start, loc)
+		end, _ := time.ParseInLocation(time.DateOnly, c.End, loc)
+
+		return start, end
+	}
+
+	switch c.Period {
+	case monthlyBudget:
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+		return start, start.AddDate(0, 1, 0)
+	case yearlyBudget:
+		start := time.Date(now.Year(), time.January, 1, 0, 0, 0, 0, loc)
+
+		return start, start.AddDate(1, 0, 0)
+	default:
+		month := time.Month(3*((int(now.Month())-1)/3) + 1)
+		start := time.Date(now.Year(), month, 1, 0, 0, 0, 0, loc)
+
+		return start, start.AddDate(0, 3, 0)
+	}
+}
+
+// budgetStatus returns the status of all resources of the budget at now.
+func (s *CEEMSServer) budgetStatus(ctx context.Context, budget BudgetConfig, now time.Time) ([]models.Budget, error) {
+	start, end := budget.bounds(now)
+
+	// Usage beyond the end of a fixed period must not be accounted
+	until := now
+	if until.After(end) {
+		until = end
+	}
+
+	// Burn rate is estimated using the usage of the last burn_rate_window. As
+	// daily_usage is aggregated per day, align the window to the midnight
+	windowStart := until.Add(-time.Duration(budget.BurnRateWindow))
+	windowStart = time.Date(windowStart.Year(), windowStart.Month(), windowStart.Day(), 0, 0, 0, 0, windowStart.Location())
+
+	if windowStart.Before(start) {
+		windowStart = start
+	}
+
+	windowDays := until.Sub(windowStart).Hours() / 24
+
+	resources := make([]string, 0, len(budget.Limits))
+	for resource := range budget.Limits {
+		resources = append(resources, resource)
+	}
+
+	slices.Sort(resources)
+
+	budgets := make([]models.Budget, 0, len(resources))
+
+	for _, resource := range resources {
+		metric := fmt.Sprintf("CAST(json_extract(total_time_seconds, '$.%s') AS REAL)", budgetResources[resource])
+
+		var consumed, windowConsumed float64
+
+		// Consumption in period and in burn rate window
+		if err := s.db.QueryRowContext(
+			ctx,
+			fmt.Sprintf(
+				"SELECT COALESCE(SUM(%[1]s), 0), COALESCE(SUM(CASE WHEN last_updated_at >= ? THEN %[1]s END), 0) FROM %[2]s WHERE cluster_id = ? AND project = ? AND last_updated_at >= ? AND last_updated_at < ?",
+				metric, base.DailyUsageDBTableName,
+			),
+			windowStart.Format(base.DatetimeLayout),
+			budget.ClusterID,
+			budget.Project,
+			start.Format(base.DatetimeLayout),
+			end.Format(base.DatetimeLayout),
+		).Scan(&consumed, &windowConsumed); err != nil {
+			return nil, fmt.Errorf("failed to fetch consumption of budget %s: %w", budget.Name, err)
+		}
+
+		// Convert seconds to hours
+		consumed /= 3600
+		windowConsumed /= 3600
+
+		limit := budget.Limits[resource]
+
+		status := models.Budget{
+			Name:        budget.Name,
+			ClusterID:   budget.ClusterID,
+			Project:     budget.Project,
+			Resource:    resource,
+			PeriodStart: start.Format(base.DatetimezoneLayout),
+			PeriodEnd:   end.Format(base.DatetimezoneLayout),
+			Limit:       limit,
+			Consumed:    consumed,
+			Remaining:   math.Max(limit-consumed, 0),
+			UsedRatio:   consumed / limit,
+		}
+
+		if windowDays > 0 {
+			status.BurnRate = windowConsumed / windowDays
+		}
+
+		// Project exhaustion time only when budget is not exhausted yet and
+		// it is still being consumed
+		if status.Remaining > 0 && status.BurnRate > 0 {
+			exhaustionAt := until.Add(time.Duration(status.Remaining / status.BurnRate * float64(24*time.Hour)))
+			status.ProjectedExhaustionAt = exhaustionAt.Format(base.DatetimezoneLayout)
+			status.ProjectedExhaustionAtTS = exhaustionAt.UnixMilli()
+		}
+
+		budgets = append(budgets, status)
+	}
+
+	return budgets, nil
+}
+
+// budgetsStatus returns the status of budgets of projects for which keep returns true.
+// A nil keep returns all the budgets.
+func (s *CEEMSServer) budgetsStatus(ctx context.Context, keep func(BudgetConfig) bool) ([]models.Budget, error) {
+	now := time.Now().In(s.dbConfig.Data.Timezone.Location)
+
+	var budgets []models.Budget
+
+	var errs error
+
+	for _, budget := range s.budgets {
+		if keep != nil && !keep(budget) {
+			continue
+		}
+
+		status, err := s.budgetStatus(ctx, budget, now)
+		if err != nil {
+			errs = errors.Join(errs, err)
+
+			continue
+		}
+
+		budgets = append(budgets, status...)
+	}
+
+	return budgets, errs
+}
+
+// budgetsQuerier returns the budgets of projects and writes response.
+func (s *CEEMSServer) budgetsQuerier(users []string, w http.ResponseWriter, r *http.Request) {
+	// Set headers
+	s.setHeaders(w)
+
+	projects := r.URL.Query()["project"]
+	clusterIDs := r.URL.Query()["cluster_id"]
+
+	// When users are provided, get projects of users including the
+	// ones managed by them
+	var userProjects []models.Project
+
+	if len(users) > 0 {
+		q := Query{}
+		q.query("SELECT * FROM " + base.ProjectsDBTableName)
+		q.query(" WHERE ")
+		q.subQuery(projectsCondition("name", users))
+
+		var err error
+		if userProjects, err = s.queriers.project(r.Context(), s.db, q, s.logger); err != nil {
+			s.logger.Error("Failed to fetch projects", "users", strings.Join(users, ","), "err", err)
+			errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+			return
+		}
+	}
+
+	keep := func(b BudgetConfig) bool {
+		if len(projects) > 0 && !slices.Contains(projects, b.Project) {
+			return false
+		}
+
+		if len(clusterIDs) > 0 && !slices.Contains(clusterIDs, b.ClusterID) {
+			return false
+		}
+
+		if len(users) > 0 {
+			return slices.ContainsFunc(userProjects, func(p models.Project) bool {
+				return p.Name == b.Project && p.ClusterID == b.ClusterID
+			})
+		}
+
+		return true
+	}
+
+	budgets, err := s.budgetsStatus(r.Context(), keep)
+	if budgets == nil && err != nil {
+		s.logger.Error("Failed to fetch budgets", "users", strings.Join(users, ","), "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Write response
+	w.WriteHeader(http.StatusOK)
+
+	response := Response[models.Budget]{
+		Status: "success",
+		Data:   budgets,
+	}
+	if err != nil {
+		response.Warnings = append(response.Warnings, err.Error())
+	}
+
+	if err = json.NewEncoder(w).Encode(&response); err != nil {
+		s.logger.Error("Failed to encode response", "err", err)
+		w.Write([]byte("KO"))
+	}
+}
+
+// budgetsUser godoc
+//
+//	@Summary		Budgets of projects
+//	@Description	This endpoint will return the status of budgets of projects of the current
+//	@Description	user. The current user is always identified by the header `X-Grafana-User`
+//	@Description	in the request.
+//	@Description
+//	@Description	Budgets are configured per project and per resource, _i.e.,_ `cpu_hours`
+//	@Description	and `gpu_hours`, for monthly, quarterly, yearly or fixed periods. The
+//	@Description	consumption in the current period is estimated from daily usage statistics.
+//	@Description
+//	@Description	The burn rate is the average consumption per day during the burn rate window
+//	@Description	of the budget and projected exhaustion time is estimated from the remaining
+//	@Description	budget and burn rate. The projected exhaustion time is omitted when the budget
+//	@Description	is already exhausted or it is not being consumed.
+//	@Description
+//	@Description	Budgets of projects that the current user is part of or manages are returned.
+//	@Description	The budgets can be further filtered by `project` and `cluster_id` query
+//	@Description	parameters.
+//	@Security		BasicAuth
+//	@Tags			budgets
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string		true	"Current user name"
+//	@Param			cluster_id		query		[]string	false	"cluster ID"	collectionFormat(multi)
+//	@Param			project			query		[]string	false	"Project"		collectionFormat(multi)
+//	@Success		200				{object}	Response[models.Budget]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/budgets [get]
+//
+// GET /budgets
+// Get budgets of projects of current user.
+func (s *CEEMSServer) budgetsUser(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "budgets endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Query for budgets and write response
+	s.budgetsQuerier([]string{loggedUser}, w, r)
+}
+
+// budgetsAdmin godoc
+//
+//	@Summary		Admin endpoint for fetching budgets of projects
+//	@Description	This admin endpoint will return the status of budgets of _any_ project. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	The user who is making the request must be in the list of admin users
+//	@Description	configured for the server.
+//	@Description
+//	@Description	The budgets can be filtered by `project` and `cluster_id` query parameters.
+//	@Security		BasicAuth
+//	@Tags			budgets
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string		true	"Current user name"
+//	@Param			cluster_id		query		[]string	false	"cluster ID"	collectionFormat(multi)
+//	@Param			project			query		[]string	false	"Project"		collectionFormat(multi)
+//	@Success		200				{object}	Response[models.Budget]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/budgets/admin [get]
+//
+// GET /budgets/admin
+// Get budgets of any project.
+func (s *CEEMSServer) budgetsAdmin(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "budgets admin endpoint", s.logger)
+
+	// Query for budgets and write response
+	s.budgetsQuerier(nil, w, r)
+}
+
+// budgetsCollector exports status of budgets as Prometheus metrics.
+type budgetsCollector struct {
+	server                *CEEMSServer
+	limit                 *prometheus.Desc
+	consumed              *prometheus.Desc
+	usedRatio             *prometheus.Desc
+	burnRate              *prometheus.Desc
+	projectedExhaustionAt *prometheus.Desc
+	scrapeError           *prometheus.Desc
+}
+
+// newBudgetsCollector returns a new instance of budgetsCollector.
+func newBudgetsCollector(server *CEEMSServer) *budgetsCollector {
+	labels := []string{"name", "cluster_id", "project", "resource"}
+
+	return &budgetsCollector{
+		server: server,
+		limit: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "limit"),
+			"Budget of project in current period",
+			labels, nil,
+		),
+		consumed: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "consumed"),
+			"Consumed budget of project in current period",
+			labels, nil,
+		),
+		usedRatio: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "used_ratio"),
+			"Ratio of consumed budget to budget of project in current period",
+			labels, nil,
+		),
+		burnRate: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "burn_rate_per_day"),
+			"Average consumption of budget of project per day during burn rate window",
+			labels, nil,
+		),
+		projectedExhaustionAt: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "projected_exhaustion_timestamp_seconds"),
+			"Time at which budget of project is projected to be exhausted at current burn rate",
+			labels, nil,
+		),
+		scrapeError: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "budget", "scrape_error"),
+			"1 if there was an error fetching status of budgets, 0 otherwise",
+			nil, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *budgetsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.limit
+	ch <- c.consumed
+	ch <- c.usedRatio
+	ch <- c.burnRate
+	ch <- c.projectedExhaustionAt
+	ch <- c.scrapeError
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *budgetsCollector) Collect(ch chan<- prometheus.Metric) {
+	budgets, err := c.server.budgetsStatus(context.Background(), nil)
+	if err != nil {
+		c.server.logger.Error("Failed to fetch budgets", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 1)
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, 0)
+	}
+
+	for _, b := range budgets {
+		labels := []string{b.Name, b.ClusterID, b.Project, b.Resource}
+
+		ch <- prometheus.MustNewConstMetric(c.limit, prometheus.GaugeValue, b.Limit, labels...)
+		ch <- prometheus.MustNewConstMetric(c.consumed, prometheus.GaugeValue, b.Consumed, labels...)
+		ch <- prometheus.MustNewConstMetric(c.usedRatio, prometheus.GaugeValue, b.UsedRatio, labels...)
+		ch <- prometheus.MustNewConstMetric(c.burnRate, prometheus.GaugeValue, b.BurnRate, labels...)
+
+		if b.ProjectedExhaustionAtTS > 0 {
+			ch <- prometheus.MustNewConstMetric(c.projectedExhaustionAt, prometheus.GaugeValue, float64(b.ProjectedExhaustionAtTS)/1000, labels...)
+		}
+	}
+}
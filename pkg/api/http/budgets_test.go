@@ -0,0 +1,322 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupServerWithBudgets returns a server with daily usage of projects and budgets.
+func setupServerWithBudgets(t *testing.T, budgets []BudgetConfig) *CEEMSServer {
+	t.Helper()
+
+	server := setupServerWithDB(t)
+	server.budgets = budgets
+
+	for _, stmt := range []string{
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','foousr','{"alloc_cputime":3600000}','2024-09-30T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','foousr','{"alloc_cputime":144000,"alloc_gputime":14400}','2024-10-02T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','foousr','{"alloc_cputime":180000,"alloc_gputime":21600}','2024-10-10T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','foousr','{"alloc_cputime":36000}','2024-10-14T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','barusr','{"alloc_cputime":36000}','2024-10-14T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,last_updated_at) VALUES ('slurm-0','slurm',1,'baz','bazusr','{"alloc_cputime":1800000}','2024-10-10T00:00:00')`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	return server
+}
+
+func TestBudgetConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config BudgetConfig
+		valid  bool
+	}{
+		{
+			name:   "valid recurring budget",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}},
+			valid:  true,
+		},
+		{
+			name:   "valid fixed budget",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Start: "2024-01-01", End: "2024-07-01", BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"gpu_hours": 100}},
+			valid:  true,
+		},
+		{
+			name:   "missing project",
+			config: BudgetConfig{ClusterID: "slurm-0", Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}},
+		},
+		{
+			name:   "missing limits",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour)},
+		},
+		{
+			name:   "unknown resource",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"tpu_hours": 100}},
+		},
+		{
+			name:   "negative limit",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": -1}},
+		},
+		{
+			name:   "unknown period",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Period: "weekly", BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}},
+		},
+		{
+			name:   "fixed budget without end",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Start: "2024-01-01", BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}},
+		},
+		{
+			name:   "fixed budget with end before start",
+			config: BudgetConfig{ClusterID: "slurm-0", Project: "foo", Start: "2024-07-01", End: "2024-01-01", BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.config.Validate()
+		if test.valid {
+			require.NoError(t, err, test.name)
+		} else {
+			require.Error(t, err, test.name)
+		}
+	}
+}
+
+func TestValidateBudgets(t *testing.T) {
+	budget := func(name, clusterID, project string) BudgetConfig {
+		return BudgetConfig{Name: name, ClusterID: clusterID, Project: project, Period: monthlyBudget, BurnRateWindow: model.Duration(time.Hour), Limits: map[string]float64{"cpu_hours": 100}}
+	}
+
+	tests := []struct {
+		name    string
+		budgets []BudgetConfig
+		valid   bool
+	}{
+		{
+			name:    "same project in different clusters",
+			budgets: []BudgetConfig{budget("foo", "slurm-0", "foo"), budget("foo", "slurm-1", "foo")},
+			valid:   true,
+		},
+		{
+			name:    "different budgets of same project",
+			budgets: []BudgetConfig{budget("foo", "slurm-0", "foo"), budget("foo-gpu", "slurm-0", "foo")},
+			valid:   true,
+		},
+		{
+			name:    "duplicate budgets",
+			budgets: []BudgetConfig{budget("foo", "slurm-0", "foo"), budget("foo", "slurm-0", "foo")},
+		},
+		{
+			name:    "invalid budget",
+			budgets: []BudgetConfig{budget("foo", "slurm-0", "")},
+		},
+	}
+
+	for _, test := range tests {
+		err := ValidateBudgets(test.budgets)
+		if test.valid {
+			require.NoError(t, err, test.name)
+		} else {
+			require.Error(t, err, test.name)
+		}
+	}
+}
+
+func TestBudgetBounds(t *testing.T) {
+	now := time.Date(2024, time.November, 15, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		config BudgetConfig
+		start  time.Time
+		end    time.Time
+	}{
+		{
+			config: BudgetConfig{Period: monthlyBudget},
+			start:  time.Date(2024, time.November, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2024, time.December, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			config: BudgetConfig{Period: quarterlyBudget},
+			start:  time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			config: BudgetConfig{Period: yearlyBudget},
+			start:  time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			config: BudgetConfig{Start: "2024-09-15", End: "2025-03-15"},
+			start:  time.Date(2024, time.September, 15, 0, 0, 0, 0, time.UTC),
+			end:    time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, test := range tests {
+		start, end := test.config.bounds(now)
+		assert.Equal(t, test.start, start, test.config.Period)
+		assert.Equal(t, test.end, end, test.config.Period)
+	}
+}
+
+func TestBudgetStatus(t *testing.T) {
+	budget := BudgetConfig{
+		Name:           "foo-compute",
+		ClusterID:      "slurm-0",
+		Project:        "foo",
+		Period:         quarterlyBudget,
+		BurnRateWindow: model.Duration(7 * 24 * time.Hour),
+		Limits:         map[string]float64{"cpu_hours": 200, "gpu_hours": 10},
+	}
+	server := setupServerWithBudgets(t, []BudgetConfig{budget})
+
+	now := time.Date(2024, time.October, 15, 12, 0, 0, 0, time.UTC)
+
+	budgets, err := server.budgetStatus(context.Background(), budget, now)
+	require.NoError(t, err)
+	require.Len(t, budgets, 2)
+
+	// CPU hours: 40 + 50 + 20 hours are consumed in the current quarter out of which
+	// 70 hours are consumed during the burn rate window of 7.5 days starting from
+	// midnight of 2024-10-08
+	cpu := budgets[0]
+	assert.Equal(t, "cpu_hours", cpu.Resource)
+	assert.Equal(t, "2024-10-01T00:00:00+0000", cpu.PeriodStart)
+	assert.Equal(t, "2025-01-01T00:00:00+0000", cpu.PeriodEnd)
+	assert.InDelta(t, 110, cpu.Consumed, 1e-9)
+	assert.InDelta(t, 90, cpu.Remaining, 1e-9)
+	assert.InDelta(t, 0.55, cpu.UsedRatio, 1e-9)
+	assert.InDelta(t, 70/7.5, cpu.BurnRate, 1e-9)
+
+	remaining, burnRate := 90.0, 70/7.5
+	exhaustionAt := now.Add(time.Duration(remaining / burnRate * float64(24*time.Hour)))
+	assert.Equal(t, exhaustionAt.UnixMilli(), cpu.ProjectedExhaustionAtTS)
+	assert.Equal(t, exhaustionAt.Format(base.DatetimezoneLayout), cpu.ProjectedExhaustionAt)
+
+	// GPU hours: budget is exhausted and hence, no projection
+	gpu := budgets[1]
+	assert.Equal(t, "gpu_hours", gpu.Resource)
+	assert.InDelta(t, 10, gpu.Consumed, 1e-9)
+	assert.InDelta(t, 0, gpu.Remaining, 1e-9)
+	assert.InDelta(t, 1, gpu.UsedRatio, 1e-9)
+	assert.InDelta(t, 6/7.5, gpu.BurnRate, 1e-9)
+	assert.Empty(t, gpu.ProjectedExhaustionAt)
+	assert.Zero(t, gpu.ProjectedExhaustionAtTS)
+}
+
+func TestBudgetsHandlers(t *testing.T) {
+	// Use fixed periods that contain all usage
+	server := setupServerWithBudgets(t, []BudgetConfig{
+		{Name: "foo", ClusterID: "slurm-0", Project: "foo", Start: "2024-10-01", End: "2100-01-01", BurnRateWindow: model.Duration(24 * time.Hour), Limits: map[string]float64{"cpu_hours": 200}},
+		{Name: "baz", ClusterID: "slurm-0", Project: "baz", Start: "2024-10-01", End: "2100-01-01", BurnRateWindow: model.Duration(24 * time.Hour), Limits: map[string]float64{"cpu_hours": 1000}},
+		{Name: "baz-other", ClusterID: "slurm-1", Project: "baz", Start: "2024-10-01", End: "2100-01-01", BurnRateWindow: model.Duration(24 * time.Hour), Limits: map[string]float64{"cpu_hours": 1000}},
+	})
+
+	tests := []struct {
+		name    string
+		user    string
+		query   string
+		admin   bool
+		budgets []string
+	}{
+		{
+			name:    "budgets of member",
+			user:    "foousr",
+			budgets: []string{"foo"},
+		},
+		{
+			name:    "budgets of other member",
+			user:    "bazusr",
+			budgets: []string{"baz"},
+		},
+		{
+			name: "budgets of user without projects",
+			user: "unknown",
+		},
+		{
+			name:    "all budgets of admin",
+			user:    "adm1",
+			admin:   true,
+			budgets: []string{"foo", "baz", "baz-other"},
+		},
+		{
+			name:    "budgets of admin filtered by cluster",
+			user:    "adm1",
+			query:   "?cluster_id=slurm-1",
+			admin:   true,
+			budgets: []string{"baz-other"},
+		},
+		{
+			name:    "budgets of admin filtered by project",
+			user:    "adm1",
+			query:   "?project=foo",
+			admin:   true,
+			budgets: []string{"foo"},
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/budgets"+test.query, nil)
+		request.Header.Set(base.LoggedUserHeader, test.user)
+
+		w := httptest.NewRecorder()
+		if test.admin {
+			server.budgetsAdmin(w, request)
+		} else {
+			server.budgetsUser(w, request)
+		}
+
+		require.Equal(t, 200, w.Code, test.name)
+
+		var response Response[models.Budget]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), test.name)
+
+		var names []string
+		for _, b := range response.Data {
+			names = append(names, b.Name)
+		}
+
+		assert.Equal(t, test.budgets, names, test.name)
+	}
+}
+
+func TestBudgetsCollector(t *testing.T) {
+	server := setupServerWithBudgets(t, []BudgetConfig{
+		{Name: "foo", ClusterID: "slurm-0", Project: "foo", Start: "2024-10-01", End: "2024-11-01", BurnRateWindow: model.Duration(24 * time.Hour), Limits: map[string]float64{"cpu_hours": 200}},
+	})
+
+	expected := `# HELP ceems_api_server_budget_burn_rate_per_day Average consumption of budget of project per day during burn rate window
+# TYPE ceems_api_server_budget_burn_rate_per_day gauge
+ceems_api_server_budget_burn_rate_per_day{cluster_id="slurm-0",name="foo",project="foo",resource="cpu_hours"} 0
+# HELP ceems_api_server_budget_consumed Consumed budget of project in current period
+# TYPE ceems_api_server_budget_consumed gauge
+ceems_api_server_budget_consumed{cluster_id="slurm-0",name="foo",project="foo",resource="cpu_hours"} 110
+# HELP ceems_api_server_budget_limit Budget of project in current period
+# TYPE ceems_api_server_budget_limit gauge
+ceems_api_server_budget_limit{cluster_id="slurm-0",name="foo",project="foo",resource="cpu_hours"} 200
+# HELP ceems_api_server_budget_scrape_error 1 if there was an error fetching status of budgets, 0 otherwise
+# TYPE ceems_api_server_budget_scrape_error gauge
+ceems_api_server_budget_scrape_error 0
+# HELP ceems_api_server_budget_used_ratio Ratio of consumed budget to budget of project in current period
+# TYPE ceems_api_server_budget_used_ratio gauge
+ceems_api_server_budget_used_ratio{cluster_id="slurm-0",name="foo",project="foo",resource="cpu_hours"} 0.55
+`
+
+	require.NoError(t, testutil.CollectAndCompare(newBudgetsCollector(server), strings.NewReader(expected)))
+}
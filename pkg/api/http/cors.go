@@ -2,6 +2,7 @@ package http
 
 import (
 	"fmt"
+	"maps"
 	"net/http"
 	"regexp"
 	"strings"
@@ -13,7 +14,7 @@ import (
 // server struct.
 var baseCorsHeaders = map[string]string{
 	"Access-Control-Allow-Headers":  "Accept, Authorization, Content-Type, Origin",
-	"Access-Control-Allow-Methods":  "GET",
+	"Access-Control-Allow-Methods":  "GET, POST, PATCH, DELETE",
 	"Access-Control-Expose-Headers": "Date",
 	"Vary":                          "Origin",
 }
@@ -26,7 +27,7 @@ type cors struct {
 // newCORS returns a new instance of CORS struct.
 func newCORS(origin *regexp.Regexp, userHeaders []string) *cors {
 	// Setup CORS headers based on user headers names
-	corsHeaders := baseCorsHeaders
+	corsHeaders := maps.Clone(baseCorsHeaders)
 	if len(userHeaders) > 0 {
 		corsHeaders["Access-Control-Allow-Headers"] = fmt.Sprintf(
 			"%s, %s", corsHeaders["Access-Control-Allow-Headers"], strings.Join(userHeaders, ", "),
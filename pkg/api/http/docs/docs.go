@@ -27,6 +27,150 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/budgets": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will return the status of budgets of projects of the current\nuser. The current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + `\nin the request.\n\nBudgets are configured per project and per resource, _i.e.,_ ` + "`" + `cpu_hours` + "`" + `\nand ` + "`" + `gpu_hours` + "`" + `, for monthly, quarterly, yearly or fixed periods. The\nconsumption in the current period is estimated from daily usage statistics.\n\nThe burn rate is the average consumption per day during the burn rate window\nof the budget and projected exhaustion time is estimated from the remaining\nbudget and burn rate. The projected exhaustion time is omitted when the budget\nis already exhausted or it is not being consumed.\n\nBudgets of projects that the current user is part of or manages are returned.\nThe budgets can be further filtered by ` + "`" + `project` + "`" + ` and ` + "`" + `cluster_id` + "`" + ` query\nparameters.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Budgets of projects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Project",
+                        "name": "project",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_Budget"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/admin": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This admin endpoint will return the status of budgets of _any_ project. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nThe budgets can be filtered by ` + "`" + `project` + "`" + ` and ` + "`" + `cluster_id` + "`" + ` query parameters.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Admin endpoint for fetching budgets of projects",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Project",
+                        "name": "project",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_Budget"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
         "/clusters/admin": {
             "get": {
                 "security": [
@@ -119,7 +263,7 @@ const docTemplate = `{
                         "BasicAuth": []
                     }
                 ],
-                "description": "This endpoint will show details of the queried project of current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe details include list of users in that project. If current user\nattempts to query a project that they are not part of, empty response\nwill be returned\n",
+                "description": "This endpoint will show details of the queried project of current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe details include list of users and managers of that project. Projects\nmanaged by the current user are returned as well. If current user\nattempts to query a project that they are not part of, empty response\nwill be returned\n\nThe projects can be sorted by ` + "`" + `cluster_id` + "`" + ` or ` + "`" + `name` + "`" + ` using ` + "`" + `sort` + "`" + ` query parameter.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order. Use ` + "`" + `limit` + "`" + ` query\nparameter to paginate the response and follow the ` + "`" + `next` + "`" + ` link in the response\nto fetch the next page.\n",
                 "produces": [
                     "application/json"
                 ],
@@ -154,6 +298,24 @@ const docTemplate = `{
                         "description": "Cluster ID",
                         "name": "cluster_id",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of projects in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -163,6 +325,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-models_Project"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -185,7 +353,7 @@ const docTemplate = `{
                         "BasicAuth": []
                     }
                 ],
-                "description": "This endpoint will show details of the queried project. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nThe details include list of users in that project. If current user\nattempts to query a project that they are not part of, empty response\nwill be returned\n",
+                "description": "This endpoint will show details of the queried project. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nThe details include list of users in that project. If current user\nattempts to query a project that they are not part of, empty response\nwill be returned\n\nThe projects can be sorted by ` + "`" + `cluster_id` + "`" + ` or ` + "`" + `name` + "`" + ` using ` + "`" + `sort` + "`" + ` query parameter.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order. Use ` + "`" + `limit` + "`" + ` query\nparameter to paginate the response and follow the ` + "`" + `next` + "`" + ` link in the response\nto fetch the next page.\n",
                 "produces": [
                     "application/json"
                 ],
@@ -220,6 +388,24 @@ const docTemplate = `{
                         "description": "Cluster ID",
                         "name": "cluster_id",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of projects in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -229,6 +415,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-models_Project"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -244,21 +436,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/stats/{mode}/admin": {
+        "/reports/projects/{name}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "This admin endpoint will return the quick stats of _queried_ cluster. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nA path parameter ` + "`" + `mode` + "`" + ` is required to return the kind of usage statistics.\nCurrently, two modes of statistics are supported:\n- ` + "`" + `current` + "`" + `: In this mode the usage between two time periods is returned\nbased on ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` query parameters.\n- ` + "`" + `global` + "`" + `: In this mode the _total_ usage statistics are returned. For\ninstance, if the retention period of the DB is set to 2 years, usage\nstatistics of last 2 years will be returned.\n\nThe statistics include current number of active users, projects, jobs, _etc_.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs.\n",
+                "description": "This endpoint returns a report of usage of a project during a month that\nsummarises number of compute units, efficiency, energy usage, emissions by\nprovider and cost along with the usage of each user and daily energy usage.\nThe current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in the request.\n\nRegular users can get reports of the projects they are part of or they manage\nwhereas admin users can get report of any project.\n\nThe month must be provided using ` + "`" + `period` + "`" + ` query parameter of format ` + "`" + `YYYY-MM` + "`" + `.\nBy default, report of the previous month is returned. Months are aligned to\nthe time zone of the DB.\n\nThe report is rendered as HTML by default. Use ` + "`" + `format=pdf` + "`" + ` query parameter or\n` + "`" + `Accept: application/pdf` + "`" + ` header to get a PDF document.",
                 "produces": [
-                    "application/json"
+                    "text/html",
+                    "application/pdf"
                 ],
                 "tags": [
-                    "stats"
+                    "reports"
                 ],
-                "summary": "Admin Stats",
+                "summary": "Monthly report of a project",
                 "parameters": [
                     {
                         "type": "string",
@@ -268,13 +461,9 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "enum": [
-                            "current",
-                            "global"
-                        ],
                         "type": "string",
-                        "description": "Whether to get quick stats within a period or global",
-                        "name": "mode",
+                        "description": "Project name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
@@ -284,20 +473,24 @@ const docTemplate = `{
                             "type": "string"
                         },
                         "collectionFormat": "multi",
-                        "description": "cluster ID",
+                        "description": "Cluster ID",
                         "name": "cluster_id",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "From timestamp",
-                        "name": "from",
+                        "description": "Month of format YYYY-MM",
+                        "name": "period",
                         "in": "query"
                     },
                     {
+                        "enum": [
+                            "html",
+                            "pdf"
+                        ],
                         "type": "string",
-                        "description": "To timestamp",
-                        "name": "to",
+                        "description": "Report format",
+                        "name": "format",
                         "in": "query"
                     }
                 ],
@@ -305,7 +498,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/http.Response-models_Stat"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
                         }
                     },
                     "401": {
@@ -314,8 +513,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
-                    "403": {
-                        "description": "Forbidden",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/http.Response-any"
                         }
@@ -329,21 +528,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/units": {
+        "/reports/units/{uuid}": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "This user endpoint will fetch compute units of the current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nIf multiple query parameters are passed, for instance, ` + "`" + `?uuid=\u003cuuid\u003e\u0026project=\u003cproject\u003e` + "`" + `,\nthe intersection of query parameters are used to fetch compute units rather than\nthe union. That means if the compute unit's ` + "`" + `uuid` + "`" + ` does not belong to the queried\nproject, null response will be returned.\n\nIn order to return the running compute units as well, use the query parameter ` + "`" + `running` + "`" + `.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs. If query\nparameter ` + "`" + `timezone` + "`" + ` is provided, the unit's created, start and end time strings\nwill be presented in that time zone.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.",
+                "description": "This endpoint returns a report of a finished compute unit that summarises\nits runtime, efficiency, energy usage, emissions by provider and cost. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in the request.\n\nRegular users can get reports of their own compute units and of compute units\nof the projects they manage whereas admin users can get report of any compute unit.\nWhen the same ` + "`" + `uuid` + "`" + ` exists in multiple clusters, ` + "`" + `cluster_id` + "`" + ` query parameter\nmust be used.\n\nThe report is rendered as HTML by default. Use ` + "`" + `format=pdf` + "`" + ` query parameter or\n` + "`" + `Accept: application/pdf` + "`" + ` header to get a PDF document. If query parameter\n` + "`" + `timezone` + "`" + ` is provided, times will be presented in that time zone.",
                 "produces": [
-                    "application/json"
+                    "text/html",
+                    "application/pdf"
                 ],
                 "tags": [
-                    "units"
+                    "reports"
                 ],
-                "summary": "User endpoint for fetching compute units",
+                "summary": "Report of a compute unit",
                 "parameters": [
                     {
                         "type": "string",
@@ -353,51 +553,16 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "Cluster ID",
-                        "name": "cluster_id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
+                        "type": "string",
                         "description": "Unit UUID",
                         "name": "uuid",
-                        "in": "query"
-                    },
-                    {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "Project",
-                        "name": "project",
-                        "in": "query"
-                    },
-                    {
-                        "type": "boolean",
-                        "description": "Whether to fetch running units",
-                        "name": "running",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "From timestamp",
-                        "name": "from",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "To timestamp",
-                        "name": "to",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
                         "in": "query"
                     },
                     {
@@ -407,13 +572,13 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "Fields to return in response",
-                        "name": "field",
+                        "enum": [
+                            "html",
+                            "pdf"
+                        ],
+                        "type": "string",
+                        "description": "Report format",
+                        "name": "format",
                         "in": "query"
                     }
                 ],
@@ -421,7 +586,111 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/http.Response-models_Unit"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/stats/{mode}/admin": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This admin endpoint will return the quick stats of _queried_ cluster. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nA path parameter ` + "`" + `mode` + "`" + ` is required to return the kind of usage statistics.\nCurrently, two modes of statistics are supported:\n- ` + "`" + `current` + "`" + `: In this mode the usage between two time periods is returned\nbased on ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` query parameters.\n- ` + "`" + `global` + "`" + `: In this mode the _total_ usage statistics are returned. For\ninstance, if the retention period of the DB is set to 2 years, usage\nstatistics of last 2 years will be returned.\n\nThe statistics include current number of active users, projects, jobs, _etc_.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs.\n",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "stats"
+                ],
+                "summary": "Admin Stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "current",
+                            "global"
+                        ],
+                        "type": "string",
+                        "description": "Whether to get quick stats within a period or global",
+                        "name": "mode",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "From timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "To timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_Stat"
                         }
                     },
                     "401": {
@@ -436,6 +705,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -445,21 +720,214 @@ const docTemplate = `{
                 }
             }
         },
-        "/units/admin": {
+        "/tokens": {
             "get": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "This admin endpoint will fetch compute units of _any_ user, compute unit and/or project. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nIf multiple query parameters are passed, for instance, ` + "`" + `?uuid=\u003cuuid\u003e\u0026user=\u003cuser\u003e` + "`" + `,\nthe intersection of query parameters are used to fetch compute units rather than\nthe union. That means if the compute unit's ` + "`" + `uuid` + "`" + ` does not belong to the queried\nuser, null response will be returned.\n\nIn order to return the running compute units as well, use the query parameter ` + "`" + `running` + "`" + `.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs. If query\nparameter ` + "`" + `timezone` + "`" + ` is provided, the unit's created, start and end time strings\nwill be presented in that time zone.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.",
+                "description": "This endpoint will return all the personal API tokens of the current user.\nThe current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nTokens themselves are never returned by this endpoint.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "List personal API tokens",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_APIToken"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will create a new personal API token for the current user.\nThe current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nPersonal API tokens can be used in ` + "`" + `Authorization: Bearer \u003ctoken\u003e` + "`" + ` header to\nmake requests on behalf of the user, for instance from CI pipelines. The\nscopes of the token restrict the endpoints that can be accessed:\n- ` + "`" + `units:read` + "`" + `: Read own compute units and their annotations\n- ` + "`" + `usage:read` + "`" + `: Read usage statistics and budgets of own projects\n\nTokens never grant admin privileges and they cannot be used to manage tokens.\nIf ` + "`" + `expires_in` + "`" + ` is not provided, tokens expire in 90 days. Maximum validity\nof a token is 1 year.\n\nThe token is returned only once in the response and it cannot be retrieved\nlater.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Create a personal API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.APITokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_CreatedAPIToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/tokens/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will revoke a personal API token of the current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe response contains the remaining tokens of the current user.",
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "tokens"
+                ],
+                "summary": "Revoke a personal API token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_APIToken"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/units": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This user endpoint will fetch compute units of the current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nIf the current user is a manager of a project, compute units of all the\nusers of that project will be returned as well.\n\nIf multiple query parameters are passed, for instance, ` + "`" + `?uuid=\u003cuuid\u003e\u0026project=\u003cproject\u003e` + "`" + `,\nthe intersection of query parameters are used to fetch compute units rather than\nthe union. That means if the compute unit's ` + "`" + `uuid` + "`" + ` does not belong to the queried\nproject, null response will be returned.\n\nIn order to return the running compute units as well, use the query parameter ` + "`" + `running` + "`" + `.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs. If query\nparameter ` + "`" + `timezone` + "`" + ` is provided, the unit's created, start and end time strings\nwill be presented in that time zone.\n\nTo filter compute units by user defined annotations, use ` + "`" + `annotation` + "`" + ` query parameter\nof form ` + "`" + `key` + "`" + ` or ` + "`" + `key:value` + "`" + `. When multiple ` + "`" + `annotation` + "`" + ` query parameters are passed,\ncompute units that match all of them will be returned.\n\nCompute units can be filtered further using ` + "`" + `filter` + "`" + ` query parameter that takes\nan expression like ` + "`" + `state=\"FAILED\" and allocation.gpus\u003e=4 and avg_gpu_usage.global\u003c10` + "`" + `.\nKeys of map fields like ` + "`" + `allocation` + "`" + `, ` + "`" + `tags` + "`" + ` and metrics are accessed with a ` + "`" + `.` + "`" + `.\nSupported operators are ` + "`" + `=` + "`" + `, ` + "`" + `!=` + "`" + `, ` + "`" + `\u003c` + "`" + `, ` + "`" + `\u003c=` + "`" + `, ` + "`" + `\u003e` + "`" + `, ` + "`" + `\u003e=` + "`" + `, ` + "`" + `in` + "`" + ` and ` + "`" + `not in` + "`" + ` and\nexpressions can be combined with ` + "`" + `and` + "`" + `, ` + "`" + `or` + "`" + `, ` + "`" + `not` + "`" + ` and parentheses.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.\n\nCompute units can be sorted using ` + "`" + `sort` + "`" + ` query parameter. Supported sort keys are\n` + "`" + `uuid` + "`" + `, ` + "`" + `name` + "`" + `, ` + "`" + `project` + "`" + `, ` + "`" + `username` + "`" + `, ` + "`" + `state` + "`" + `, ` + "`" + `created_at_ts` + "`" + `, ` + "`" + `started_at_ts` + "`" + `,\n` + "`" + `ended_at_ts` + "`" + `, ` + "`" + `elapsed` + "`" + `, ` + "`" + `avg_cpu_usage` + "`" + `, ` + "`" + `avg_cpu_mem_usage` + "`" + `, ` + "`" + `avg_gpu_usage` + "`" + `,\n` + "`" + `avg_gpu_mem_usage` + "`" + `, ` + "`" + `total_cpu_energy_usage_kwh` + "`" + ` and ` + "`" + `total_gpu_energy_usage_kwh` + "`" + `.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order.\n\nUse ` + "`" + `limit` + "`" + ` query parameter to paginate the response. When there are more compute\nunits, the response contains a ` + "`" + `next` + "`" + ` link that has a ` + "`" + `cursor` + "`" + ` query parameter\nwhich must be used to fetch the next page.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
                 "tags": [
                     "units"
                 ],
-                "summary": "Admin endpoint for fetching compute units.",
+                "summary": "User endpoint for fetching compute units",
                 "parameters": [
                     {
                         "type": "string",
@@ -499,9 +967,188 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
+                        "type": "boolean",
+                        "description": "Whether to fetch running units",
+                        "name": "running",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "From timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "To timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time zone in IANA format",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Annotation filter",
+                        "name": "annotation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filter expression",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Fields to return in response",
+                        "name": "field",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of units in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_Unit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/units/admin": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This admin endpoint will fetch compute units of _any_ user, compute unit and/or project. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nIf multiple query parameters are passed, for instance, ` + "`" + `?uuid=\u003cuuid\u003e\u0026user=\u003cuser\u003e` + "`" + `,\nthe intersection of query parameters are used to fetch compute units rather than\nthe union. That means if the compute unit's ` + "`" + `uuid` + "`" + ` does not belong to the queried\nuser, null response will be returned.\n\nIn order to return the running compute units as well, use the query parameter ` + "`" + `running` + "`" + `.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs. If query\nparameter ` + "`" + `timezone` + "`" + ` is provided, the unit's created, start and end time strings\nwill be presented in that time zone.\n\nTo filter compute units by user defined annotations, use ` + "`" + `annotation` + "`" + ` query parameter\nof form ` + "`" + `key` + "`" + ` or ` + "`" + `key:value` + "`" + `. When multiple ` + "`" + `annotation` + "`" + ` query parameters are passed,\ncompute units that match all of them will be returned.\n\nCompute units can be filtered further using ` + "`" + `filter` + "`" + ` query parameter that takes\nan expression like ` + "`" + `state=\"FAILED\" and allocation.gpus\u003e=4 and avg_gpu_usage.global\u003c10` + "`" + `.\nKeys of map fields like ` + "`" + `allocation` + "`" + `, ` + "`" + `tags` + "`" + ` and metrics are accessed with a ` + "`" + `.` + "`" + `.\nSupported operators are ` + "`" + `=` + "`" + `, ` + "`" + `!=` + "`" + `, ` + "`" + `\u003c` + "`" + `, ` + "`" + `\u003c=` + "`" + `, ` + "`" + `\u003e` + "`" + `, ` + "`" + `\u003e=` + "`" + `, ` + "`" + `in` + "`" + ` and ` + "`" + `not in` + "`" + ` and\nexpressions can be combined with ` + "`" + `and` + "`" + `, ` + "`" + `or` + "`" + `, ` + "`" + `not` + "`" + ` and parentheses.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.\n\nCompute units can be sorted using ` + "`" + `sort` + "`" + ` query parameter. Supported sort keys are\n` + "`" + `uuid` + "`" + `, ` + "`" + `name` + "`" + `, ` + "`" + `project` + "`" + `, ` + "`" + `username` + "`" + `, ` + "`" + `state` + "`" + `, ` + "`" + `created_at_ts` + "`" + `, ` + "`" + `started_at_ts` + "`" + `,\n` + "`" + `ended_at_ts` + "`" + `, ` + "`" + `elapsed` + "`" + `, ` + "`" + `avg_cpu_usage` + "`" + `, ` + "`" + `avg_cpu_mem_usage` + "`" + `, ` + "`" + `avg_gpu_usage` + "`" + `,\n` + "`" + `avg_gpu_mem_usage` + "`" + `, ` + "`" + `total_cpu_energy_usage_kwh` + "`" + ` and ` + "`" + `total_gpu_energy_usage_kwh` + "`" + `.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order.\n\nUse ` + "`" + `limit` + "`" + ` query parameter to paginate the response. When there are more compute\nunits, the response contains a ` + "`" + `next` + "`" + ` link that has a ` + "`" + `cursor` + "`" + ` query parameter\nwhich must be used to fetch the next page.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "Admin endpoint for fetching compute units.",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
                         },
                         "collectionFormat": "multi",
                         "description": "User name",
@@ -509,28 +1156,543 @@ const docTemplate = `{
                         "in": "query"
                     },
                     {
-                        "type": "boolean",
-                        "description": "Whether to fetch running units",
-                        "name": "running",
-                        "in": "query"
-                    },
+                        "type": "boolean",
+                        "description": "Whether to fetch running units",
+                        "name": "running",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "From timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "To timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time zone in IANA format",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Annotation filter",
+                        "name": "annotation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Filter expression",
+                        "name": "filter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Fields to return in response",
+                        "name": "field",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of units in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_Unit"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/units/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint streams compute units that are inserted or updated in the DB as\nserver-sent events. The current user is always identified by the header\n` + "`" + `X-Grafana-User` + "`" + ` in the request.\n\nFor regular users, compute units of the current user and of the projects managed\nby the current user are streamed. For admin users, compute units of all users are\nstreamed and they can be filtered using ` + "`" + `user` + "`" + ` query parameter.\n\nAfter each update of the DB, an event named ` + "`" + `units` + "`" + ` is sent with the list of\nupdated compute units as data. The ID of the event is the timestamp of the update\nin the time zone of the DB. When ` + "`" + `Last-Event-ID` + "`" + ` header or ` + "`" + `last_event_id` + "`" + ` query\nparameter is present, compute units updated after that timestamp are sent\nimmediately which allows clients to resume the stream without missing any updates.\n\nTo limit the number of fields in the events, use ` + "`" + `field` + "`" + ` query parameter. By default,\nall fields will be included in the events if they are _non-empty_.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "Stream of compute unit updates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of last received event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "User name. Only for admin users",
+                        "name": "user",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of last received event",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Time zone in IANA format",
+                        "name": "timezone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Fields to return in events",
+                        "name": "field",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Unit"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/units/verify": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will check if the current user is the owner of the\nqueried UUIDs. The current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nA response of 200 means that the current user is the owner of the queried UUIDs.\nAny other response code should be treated as the current user not being the owner\nof the queried units.\n\nThe ownership check passes if any of the following conditions are ` + "`" + `true` + "`" + `:\n- If the current user is the _direct_ owner of the compute unit.\n- If the current user belongs to the same account/project/namespace as\nthe compute unit. This means the users belonging to the same project can\naccess each others compute units.\n- If the current user has admin role\n\nThe above checks must pass for **all** the queried units.\nIf the check does not pass for at least one queried unit, a response 403 will be\nreturned.\n\nAny 500 response codes should be treated as failed check as well.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "units"
+                ],
+                "summary": "Verify unit ownership",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Timestamps",
+                        "name": "time",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        },
+        "/units/{uuid}/annotations": {
+            "get": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will return all the annotations of a given compute unit. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe current user must either be the owner of the compute unit, belong\nto the same project as the compute unit or be an admin user.\n\nThe query parameter ` + "`" + `cluster_id` + "`" + ` is mandatory as compute unit UUIDs are\nnot unique across different clusters.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "List annotations of a compute unit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_UnitAnnotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will add new annotations to a given compute unit. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe request body must be a JSON object of key/value pairs where both keys\nand values are strings. Keys must start with a letter or underscore and can\nonly contain alphanumeric characters, underscores, dots and hyphens. Values\ncannot be longer than 1024 characters.\n\nThe current user must either be the owner of the compute unit, belong\nto the same project as the compute unit or be an admin user. Created\nannotations are owned by the current user.\n\nIf an annotation with the same key already exists on the compute unit,\na response 409 will be returned and none of the annotations in the\nrequest will be created. Use ` + "`" + `PATCH` + "`" + ` method to update existing annotations.\n\nThe response contains all the annotations of the compute unit.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Create annotations on a compute unit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "description": "Annotations",
+                        "name": "annotations",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_UnitAnnotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BasicAuth": []
+                    }
+                ],
+                "description": "This endpoint will delete annotations of a given compute unit. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nAnnotations to delete are identified by ` + "`" + `key` + "`" + ` query parameters. If no\n` + "`" + `key` + "`" + ` query parameter is provided, all the annotations owned by the\ncurrent user on the compute unit will be deleted.\n\nUsers can only delete the annotations that they own. Admin users can delete\nany annotation.\n\nThe response contains all the remaining annotations of the compute unit.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "annotations"
+                ],
+                "summary": "Delete annotations of a compute unit",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "From timestamp",
-                        "name": "from",
-                        "in": "query"
+                        "description": "Current user name",
+                        "name": "X-Grafana-User",
+                        "in": "header",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "To timestamp",
-                        "name": "to",
-                        "in": "query"
+                        "description": "Unit UUID",
+                        "name": "uuid",
+                        "in": "path",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Time zone in IANA format",
-                        "name": "timezone",
-                        "in": "query"
+                        "description": "Cluster ID",
+                        "name": "cluster_id",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "array",
@@ -538,8 +1700,8 @@ const docTemplate = `{
                             "type": "string"
                         },
                         "collectionFormat": "multi",
-                        "description": "Fields to return in response",
-                        "name": "field",
+                        "description": "Annotation keys",
+                        "name": "key",
                         "in": "query"
                     }
                 ],
@@ -547,7 +1709,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/http.Response-models_Unit"
+                            "$ref": "#/definitions/http.Response-models_UnitAnnotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
                         }
                     },
                     "401": {
@@ -562,6 +1730,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -569,23 +1743,24 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/units/verify": {
-            "get": {
+            },
+            "patch": {
                 "security": [
                     {
                         "BasicAuth": []
                     }
                 ],
-                "description": "This endpoint will check if the current user is the owner of the\nqueried UUIDs. The current user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nA response of 200 means that the current user is the owner of the queried UUIDs.\nAny other response code should be treated as the current user not being the owner\nof the queried units.\n\nThe ownership check passes if any of the following conditions are ` + "`" + `true` + "`" + `:\n- If the current user is the _direct_ owner of the compute unit.\n- If the current user belongs to the same account/project/namespace as\nthe compute unit. This means the users belonging to the same project can\naccess each others compute units.\n- If the current user has admin role\n\nThe above checks must pass for **all** the queried units.\nIf the check does not pass for at least one queried unit, a response 403 will be\nreturned.\n\nAny 500 response codes should be treated as failed check as well.",
+                "description": "This endpoint will update existing annotations of a given compute unit. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe request body must be a JSON object of key/value pairs where both keys\nand values are strings.\n\nUsers can only update the annotations that they own. Admin users can update\nany annotation. If an annotation in the request does not exist, a response\n404 will be returned and none of the annotations will be updated.\n\nThe response contains all the annotations of the compute unit.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "units"
+                    "annotations"
                 ],
-                "summary": "Verify unit ownership",
+                "summary": "Update annotations of a compute unit",
                 "parameters": [
                     {
                         "type": "string",
@@ -595,39 +1770,41 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
+                        "type": "string",
                         "description": "Unit UUID",
                         "name": "uuid",
-                        "in": "query"
+                        "in": "path",
+                        "required": true
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
+                        "type": "string",
                         "description": "Cluster ID",
                         "name": "cluster_id",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "type": "array",
-                        "items": {
-                            "type": "string"
-                        },
-                        "collectionFormat": "multi",
-                        "description": "Timestamps",
-                        "name": "time",
-                        "in": "query"
+                        "description": "Annotations",
+                        "name": "annotations",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-models_UnitAnnotation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/http.Response-any"
                         }
@@ -644,6 +1821,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -662,7 +1845,9 @@ const docTemplate = `{
                 ],
                 "description": "This endpoint will return the usage statistics current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nA path parameter ` + "`" + `mode` + "`" + ` is required to return the kind of usage statistics.\nCurrently, two modes of statistics are supported:\n- ` + "`" + `current` + "`" + `: In this mode the usage between two time periods is returned\nbased on ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` query parameters.\n- ` + "`" + `global` + "`" + `: In this mode the _total_ usage statistics are returned. For\ninstance, if the retention period of the DB is set to 2 years, usage\nstatistics of last 2 years will be returned.\n\nThe statistics can be limited to certain projects by passing ` + "`" + `project` + "`" + ` query,\nparameter.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.\n\nThe ` + "`" + `current` + "`" + ` usage mode can be slow query depending the requested\nwindow interval. This is mostly due to the fact that the CEEMS DB\nuses custom JSON types to store metric data and usage statistics\nneeds to aggregate metrics over these JSON types using custom aggregate\nfunctions which can be slow.\n\nTherefore the query results are cached for 15 min to avoid load on server.\nURL string is used as the cache key. Thus, the query parameters\n` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` are rounded to the nearest timestamp that are\nmultiple of 900 sec (15 min). The first query will make a DB query and\ncache results and subsequent queries, for a given user and same URL\nquery parameters, will return the same cached result until the cache\nis invalidated after 15 min.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "usage"
@@ -728,6 +1913,17 @@ const docTemplate = `{
                         "description": "Fields to return in response",
                         "name": "field",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -743,6 +1939,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -761,7 +1963,9 @@ const docTemplate = `{
                 ],
                 "description": "This admin endpoint will return the usage statistics of _queried_ user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nA path parameter ` + "`" + `mode` + "`" + ` is required to return the kind of usage statistics.\nCurrently, two modes of statistics are supported:\n- ` + "`" + `current` + "`" + `: In this mode the usage between two time periods is returned\nbased on ` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` query parameters.\n- ` + "`" + `global` + "`" + `: In this mode the _total_ usage statistics are returned. For\ninstance, if the retention period of the DB is set to 2 years, usage\nstatistics of last 2 years will be returned.\n\nThe statistics can be limited to certain projects by passing ` + "`" + `project` + "`" + ` query,\nparameter.\n\nIf ` + "`" + `to` + "`" + ` query parameter is not provided, current time will be used. If ` + "`" + `from` + "`" + `\nquery parameter is not used, a default query window of 24 hours will be used.\nIt means if ` + "`" + `to` + "`" + ` is provided, ` + "`" + `from` + "`" + ` will be calculated as ` + "`" + `to` + "`" + ` - 24hrs.\n\nTo limit the number of fields in the response, use ` + "`" + `field` + "`" + ` query parameter. By default, all\nfields will be included in the response if they are _non-empty_.\n\nThe ` + "`" + `current` + "`" + ` usage mode can be slow query depending the requested\nwindow interval. This is mostly due to the fact that the CEEMS DB\nuses custom JSON types to store metric data and usage statistics\nneeds to aggregate metrics over these JSON types using custom aggregate\nfunctions which can be slow.\n\nTherefore the query results are cached for 15 min to avoid load on server.\nURL string is used as the cache key. Thus, the query parameters\n` + "`" + `from` + "`" + ` and ` + "`" + `to` + "`" + ` are rounded to the nearest timestamp that are\nmultiple of 900 sec (15 min). The first query will make a DB query and\ncache results and subsequent queries, for a given user and same URL\nquery parameters, will return the same cached result until the cache\nis invalidated after 15 min.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "usage"
@@ -837,6 +2041,17 @@ const docTemplate = `{
                         "description": "Fields to return in response",
                         "name": "field",
                         "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -858,6 +2073,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
+                    "406": {
+                        "description": "Not Acceptable",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -874,7 +2095,7 @@ const docTemplate = `{
                         "BasicAuth": []
                     }
                 ],
-                "description": "This endpoint will show details of the current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe details include list of projects that user is currently a part of.\n",
+                "description": "This endpoint will show details of the current user. The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe details include list of projects that user is currently a part of.\n\nThe users can be sorted by ` + "`" + `cluster_id` + "`" + ` or ` + "`" + `name` + "`" + ` using ` + "`" + `sort` + "`" + ` query parameter.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order. Use ` + "`" + `limit` + "`" + ` query\nparameter to paginate the response and follow the ` + "`" + `next` + "`" + ` link in the response\nto fetch the next page.\n",
                 "produces": [
                     "application/json"
                 ],
@@ -899,6 +2120,24 @@ const docTemplate = `{
                         "description": "Cluster ID",
                         "name": "cluster_id",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of users in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -908,6 +2147,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-models_User"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -930,7 +2175,7 @@ const docTemplate = `{
                         "BasicAuth": []
                     }
                 ],
-                "description": "This endpoint will show details of the queried user(s). The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nWhen the query parameter ` + "`" + `user` + "`" + ` is empty, all users will be returned\nin the response.\n\nThe details include list of projects that user is currently a part of.\n\nWhen query parameter ` + "`" + `role` + "`" + ` is set to ` + "`" + `admin` + "`" + `, only admin users will\nwill be returned. The ` + "`" + `tags` + "`" + ` values indicates the source of admin user.\n",
+                "description": "This endpoint will show details of the queried user(s). The\ncurrent user is always identified by the header ` + "`" + `X-Grafana-User` + "`" + ` in\nthe request.\n\nThe user who is making the request must be in the list of admin users\nconfigured for the server.\n\nWhen the query parameter ` + "`" + `user` + "`" + ` is empty, all users will be returned\nin the response.\n\nThe details include list of projects that user is currently a part of.\n\nWhen query parameter ` + "`" + `role` + "`" + ` is set to ` + "`" + `admin` + "`" + `, only admin users will\nwill be returned. The ` + "`" + `tags` + "`" + ` values indicates the source of admin user.\n\nThe users can be sorted by ` + "`" + `cluster_id` + "`" + ` or ` + "`" + `name` + "`" + ` using ` + "`" + `sort` + "`" + ` query parameter.\nPrefix the sort key with ` + "`" + `-` + "`" + ` to sort in descending order. Use ` + "`" + `limit` + "`" + ` query\nparameter to paginate the response and follow the ` + "`" + `next` + "`" + ` link in the response\nto fetch the next page.\n",
                 "produces": [
                     "application/json"
                 ],
@@ -966,6 +2211,24 @@ const docTemplate = `{
                         "name": "cluster_id",
                         "in": "query"
                     },
+                    {
+                        "type": "string",
+                        "description": "Sort key. Prefix with - for descending order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of users in response",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor of next page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
                         "description": "User role",
@@ -980,29 +2243,164 @@ const docTemplate = `{
                             "$ref": "#/definitions/http.Response-models_User"
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/http.Response-any"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/http.Response-any"
-                        }
-                    }
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/http.Response-any"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "http.Response-any": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {},
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "error": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "errorType": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/http.errorType"
+                        }
+                    ],
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                }
+            }
+        },
+        "http.Response-models_APIToken": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.APIToken"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "error": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "errorType": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/http.errorType"
+                        }
+                    ],
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                }
+            }
+        },
+        "http.Response-models_Budget": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Budget"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "error": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "errorType": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/http.errorType"
+                        }
+                    ],
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
                 }
             }
-        }
-    },
-    "definitions": {
-        "http.Response-any": {
+        },
+        "http.Response-models_Cluster": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
-                    "items": {},
+                    "items": {
+                        "$ref": "#/definitions/models.Cluster"
+                    },
                     "x-nullable": true,
                     "x-omitempty": true
                 },
@@ -1020,6 +2418,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1033,13 +2436,13 @@ const docTemplate = `{
                 }
             }
         },
-        "http.Response-models_Cluster": {
+        "http.Response-models_CreatedAPIToken": {
             "type": "object",
             "properties": {
                 "data": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/models.Cluster"
+                        "$ref": "#/definitions/models.CreatedAPIToken"
                     },
                     "x-nullable": true,
                     "x-omitempty": true
@@ -1058,6 +2461,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1096,6 +2504,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1134,6 +2547,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1172,6 +2590,54 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "status": {
+                    "type": "string"
+                },
+                "warnings": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                }
+            }
+        },
+        "http.Response-models_UnitAnnotation": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UnitAnnotation"
+                    },
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "error": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "errorType": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/http.errorType"
+                        }
+                    ],
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1210,6 +2676,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1248,6 +2719,11 @@ const docTemplate = `{
                     "x-nullable": true,
                     "x-omitempty": true
                 },
+                "next": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "x-omitempty": true
+                },
                 "status": {
                     "type": "string"
                 },
@@ -1274,7 +2750,8 @@ const docTemplate = `{
                 "internal",
                 "unavailable",
                 "not_found",
-                "not_acceptable"
+                "not_acceptable",
+                "conflict"
             ],
             "x-enum-varnames": [
                 "errorNone",
@@ -1287,9 +2764,151 @@ const docTemplate = `{
                 "errorInternal",
                 "errorUnavailable",
                 "errorNotFound",
-                "errorNotAcceptable"
+                "errorNotAcceptable",
+                "errorConflict"
             ]
         },
+        "models.APIToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Creation time",
+                    "type": "string",
+                    "example": "2023-02-21T15:48:20+0100"
+                },
+                "expires_at": {
+                    "description": "Expiry time",
+                    "type": "string",
+                    "example": "2023-05-22T15:48:20+0100"
+                },
+                "last_used_at": {
+                    "description": "Last time the token was used",
+                    "type": "string",
+                    "example": "2023-02-22T10:12:05+0100"
+                },
+                "name": {
+                    "description": "Name of the token",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "scopes": {
+                    "description": "Scopes of the token",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "units:read",
+                        "usage:read"
+                    ]
+                },
+                "token_id": {
+                    "description": "Public identifier of the token",
+                    "type": "string",
+                    "example": "4f9a1c2e7b3d8a60"
+                },
+                "username": {
+                    "description": "Name of the user who owns the token",
+                    "type": "string",
+                    "example": "usr1"
+                }
+            }
+        },
+        "models.APITokenRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Validity of the token. Maximum is 1 year",
+                    "type": "string",
+                    "example": "90d"
+                },
+                "name": {
+                    "description": "Name of the token",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "scopes": {
+                    "description": "Scopes of the token",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "units:read"
+                    ]
+                }
+            }
+        },
+        "models.Budget": {
+            "type": "object",
+            "properties": {
+                "burn_rate_per_day": {
+                    "description": "Average consumption per day during burn rate window",
+                    "type": "number",
+                    "example": 750
+                },
+                "cluster_id": {
+                    "description": "Identifier of the resource manager that owns project",
+                    "type": "string",
+                    "example": "slurm-0"
+                },
+                "consumed": {
+                    "description": "Consumed budget in current period",
+                    "type": "number",
+                    "example": 45000
+                },
+                "limit": {
+                    "description": "Budget in current period",
+                    "type": "number",
+                    "example": 100000
+                },
+                "name": {
+                    "description": "Name of the budget",
+                    "type": "string",
+                    "example": "prj1"
+                },
+                "period_end": {
+                    "description": "End of current budget period",
+                    "type": "string",
+                    "example": "2024-04-01T00:00:00+0200"
+                },
+                "period_start": {
+                    "description": "Start of current budget period",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+0100"
+                },
+                "project": {
+                    "description": "Account in batch systems, Tenant in Openstack, Namespace in k8s",
+                    "type": "string",
+                    "example": "prj1"
+                },
+                "projected_exhaustion_at": {
+                    "description": "Time at which budget is projected to be exhausted at current burn rate",
+                    "type": "string",
+                    "example": "2024-03-15T10:00:00+0100"
+                },
+                "projected_exhaustion_at_ts": {
+                    "description": "Timestamp at which budget is projected to be exhausted at current burn rate",
+                    "type": "integer",
+                    "example": 1710493200000
+                },
+                "remaining": {
+                    "description": "Remaining budget in current period",
+                    "type": "number",
+                    "example": 55000
+                },
+                "resource": {
+                    "description": "Budgeted resource. Currently ` + "`" + `cpu_hours` + "`" + ` and ` + "`" + `gpu_hours` + "`" + ` are supported",
+                    "type": "string",
+                    "example": "cpu_hours"
+                },
+                "used_ratio": {
+                    "description": "Ratio of consumed budget to budget",
+                    "type": "number",
+                    "example": 0.45
+                }
+            }
+        },
         "models.Cluster": {
             "type": "object",
             "properties": {
@@ -1301,6 +2920,57 @@ const docTemplate = `{
                 }
             }
         },
+        "models.CreatedAPIToken": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Creation time",
+                    "type": "string",
+                    "example": "2023-02-21T15:48:20+0100"
+                },
+                "expires_at": {
+                    "description": "Expiry time",
+                    "type": "string",
+                    "example": "2023-05-22T15:48:20+0100"
+                },
+                "last_used_at": {
+                    "description": "Last time the token was used",
+                    "type": "string",
+                    "example": "2023-02-22T10:12:05+0100"
+                },
+                "name": {
+                    "description": "Name of the token",
+                    "type": "string",
+                    "example": "ci-pipeline"
+                },
+                "scopes": {
+                    "description": "Scopes of the token",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "units:read",
+                        "usage:read"
+                    ]
+                },
+                "token": {
+                    "description": "Token. It is returned only once",
+                    "type": "string",
+                    "example": "ceems_Zm9vYmFyYmF6"
+                },
+                "token_id": {
+                    "description": "Public identifier of the token",
+                    "type": "string",
+                    "example": "4f9a1c2e7b3d8a60"
+                },
+                "username": {
+                    "description": "Name of the user who owns the token",
+                    "type": "string",
+                    "example": "usr1"
+                }
+            }
+        },
         "models.Project": {
             "type": "object",
             "properties": {
@@ -1309,6 +2979,16 @@ const docTemplate = `{
                     "type": "string",
                     "example": "slurm-0"
                 },
+                "managers": {
+                    "description": "List of managers of the project. Managers can access all compute units and usage of the project",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "usr1"
+                    ]
+                },
                 "name": {
                     "description": "Name of the project",
                     "type": "string",
@@ -1403,6 +3083,17 @@ const docTemplate = `{
                         "mem": 10
                     }
                 },
+                "annotations": {
+                    "description": "User defined key/value annotations of unit. They are stored separately and are never overwritten by the resource manager",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "doi": "10.1000/xyz123",
+                        "run": "benchmark"
+                    }
+                },
                 "avg_cpu_mem_usage": {
                     "description": "Average CPU memory usage(s) during lifetime of unit",
                     "type": "object",
@@ -1628,6 +3319,46 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UnitAnnotation": {
+            "type": "object",
+            "properties": {
+                "cluster_id": {
+                    "description": "Identifier of the resource manager that owns compute unit",
+                    "type": "string",
+                    "example": "slurm-0"
+                },
+                "created_at": {
+                    "description": "Creation time",
+                    "type": "string",
+                    "example": "2023-02-21T15:48:20+0100"
+                },
+                "key": {
+                    "description": "Key of the annotation",
+                    "type": "string",
+                    "example": "doi"
+                },
+                "last_updated_at": {
+                    "description": "Last updated time",
+                    "type": "string",
+                    "example": "2023-02-21T15:48:20+0100"
+                },
+                "username": {
+                    "description": "Name of the user who owns the annotation",
+                    "type": "string",
+                    "example": "usr1"
+                },
+                "uuid": {
+                    "description": "UUID of the compute unit",
+                    "type": "string",
+                    "example": "1479763"
+                },
+                "value": {
+                    "description": "Value of the annotation",
+                    "type": "string",
+                    "example": "10.1000/xyz123"
+                }
+            }
+        },
         "models.Usage": {
             "type": "object",
             "properties": {
@@ -1864,7 +3595,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{"https"},
 	Title:            "CEEMS API",
-	Description:      "OpenAPI specification (OAS) for the CEEMS REST API.\n\nSee the Interactive Docs to try CEEMS API methods without writing code, and get\nthe complete schema of resources exposed by the API.\n\nIf basic auth is enabled, all the endpoints require authentication.\n\nAll the endpoints, except `health`, `swagger`, `debug` and `demo`,\nmust send a user-agent header.\n\nA demo instance of CEEMS API server is provided for the users to test. This\ninstance is running at `https://ceems-demo.myaddr.tools:7443` and it is the\ndefault server that will serve the requests originating from current OAS client.\n\nSome of the valid users for this demo instance are:\n- arnold\n- betty\n- edna\n- gazoo\n- wilma\n\nEvery request must contain a `X-Grafana-User` header with one of the usernames\nabove as the value to the header. This is how CEEMS API server recognise the user.\n\nSome of the valid projects for this demo instance are:\n- bedrock\n- cornerstone\n\nDemo instance have CORS enabled to allow cross-domain communication from the browser.\nAll responses have a wildcard same-origin which makes them completely public and\naccessible to everyone, including any code on any site.\n\nTo test admin resources, users can use `admin` as `X-Grafana-User`.\n\nTimestamps must be specified in milliseconds, unless otherwise specified.",
+	Description:      "OpenAPI specification (OAS) for the CEEMS REST API.\n\nSee the Interactive Docs to try CEEMS API methods without writing code, and get\nthe complete schema of resources exposed by the API.\n\nIf basic auth is enabled, all the endpoints require authentication.\n\nAll the endpoints, except `health`, `metrics`, `swagger`, `debug` and `demo`,\nmust send a user-agent header.\n\nA demo instance of CEEMS API server is provided for the users to test. This\ninstance is running at `https://ceems-demo.myaddr.tools:7443` and it is the\ndefault server that will serve the requests originating from current OAS client.\n\nSome of the valid users for this demo instance are:\n- arnold\n- betty\n- edna\n- gazoo\n- wilma\n\nEvery request must contain a `X-Grafana-User` header with one of the usernames\nabove as the value to the header. This is how CEEMS API server recognise the user.\n\nSome of the valid projects for this demo instance are:\n- bedrock\n- cornerstone\n\nDemo instance have CORS enabled to allow cross-domain communication from the browser.\nAll responses have a wildcard same-origin which makes them completely public and\naccessible to everyone, including any code on any site.\n\nTo test admin resources, users can use `admin` as `X-Grafana-User`.\n\nTimestamps must be specified in milliseconds, unless otherwise specified.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -42,6 +42,7 @@ const (
 	errorUnavailable   errorType = "unavailable"
 	errorNotFound      errorType = "not_found"
 	errorNotAcceptable errorType = "not_acceptable"
+	errorConflict      errorType = "conflict"
 )
 
 // Custom error codes.
@@ -53,12 +54,31 @@ const (
 
 // Custom errors.
 var (
-	errNoUser            = errors.New("no user identified")
-	errNoPrivs           = errors.New("current user does not have admin privileges")
-	errInvalidRequest    = errors.New("invalid request")
-	errInvalidQueryField = errors.New("invalid query fields")
-	errMissingUUIDs      = errors.New("uuids missing in the request")
-	errNoAuth            = errors.New("user do not have permissions on uuids")
+	errNoUser             = errors.New("no user identified")
+	errNoPrivs            = errors.New("current user does not have admin privileges")
+	errInvalidRequest     = errors.New("invalid request")
+	errInvalidQueryField  = errors.New("invalid query fields")
+	errMissingUUIDs       = errors.New("uuids missing in the request")
+	errNoAuth             = errors.New("user do not have permissions on uuids")
+	errMissingClusterID   = errors.New("cluster_id missing in the request")
+	errUnitNotFound       = errors.New("unit not found")
+	errInvalidAnnotation  = errors.New("invalid annotation")
+	errAnnotationExists   = errors.New("annotation already exists")
+	errAnnotationNotFound = errors.New("annotation not found")
+	errInvalidSortKey     = errors.New("invalid sort key")
+	errInvalidLimit       = errors.New("invalid limit")
+	errInvalidCursor      = errors.New("invalid cursor")
+	errUnsupportedFormat  = errors.New("unsupported response format")
+	errInvalidFilter      = errors.New("invalid filter")
+	errInvalidToken       = errors.New("invalid token")
+	errUnknownKey         = errors.New("unknown signing key")
+	errTokenScope         = errors.New("token does not have scope to access resource")
+	errTokenNotFound      = errors.New("token not found")
+	errTooManyTokens      = errors.New("maximum number of tokens reached")
+	errUnitNotFinished    = errors.New("unit has not finished yet")
+	errAmbiguousUnit      = errors.New("multiple units found. Use cluster_id query parameter to select one")
+	errInvalidPeriod      = errors.New("invalid period. Period must be of format YYYY-MM")
+	errProjectNotFound    = errors.New("project not found")
 )
 
 // Return error response for by setting errorString and errorType in response.
@@ -84,6 +104,8 @@ func errorResponse[T any](w http.ResponseWriter, apiErr *apiError, logger *slog.
 		code = http.StatusNotFound
 	case errorNotAcceptable:
 		code = http.StatusNotAcceptable
+	case errorConflict:
+		code = http.StatusConflict
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -0,0 +1,458 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+)
+
+// Maximum length of filter expression.
+const maxFilterLength = 4096
+
+// Regex of keys of map fields in filter expressions.
+var filterKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// filterFieldType is the type of a field that can be used in filter expressions.
+type filterFieldType int
+
+const (
+	filterText filterFieldType = iota
+	filterNumber
+	filterMap
+)
+
+// Fields of units that can be used in filter expressions. Annotations are
+// stored in a separate table and they can be filtered using `annotation`
+// query parameter.
+var unitsFilterFields = filterFields[models.Unit]("annotations")
+
+// filterFields returns the fields of T that can be used in filter expressions.
+func filterFields[T any](exclude ...string) map[string]filterFieldType {
+	fields := make(map[string]filterFieldType)
+
+	t := reflect.TypeFor[T]()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if name == "" || slices.Contains(exclude, name) {
+			continue
+		}
+
+		switch t.Field(i).Type.Kind() { //nolint:exhaustive
+		case reflect.Map:
+			fields[name] = filterMap
+		case reflect.Int, reflect.Int64:
+			fields[name] = filterNumber
+		default:
+			fields[name] = filterText
+		}
+	}
+
+	return fields
+}
+
+// Token kinds of filter expressions.
+type tokenKind int
+
+const (
+	tokenInvalid tokenKind = iota
+	tokenEOF
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenIn
+)
+
+// token is a lexical token of filter expression.
+type token struct {
+	kind  tokenKind
+	text  string // Text of token as found in expression
+	value string // Unquoted value of string tokens
+	pos   int    // Position of token in expression starting from 1
+}
+
+// String returns the text of token to be used in error messages.
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of filter"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+// filterError returns an error that points to the offending token.
+func filterError(t token, format string, args ...any) error {
+	return fmt.Errorf("%w: %s at position %d near %s", errInvalidFilter, fmt.Sprintf(format, args...), t.pos, t)
+}
+
+// lexFilter splits filter expression into tokens.
+func lexFilter(expr string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		start := i
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: start + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: start + 1})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: start + 1})
+			i++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			i++
+			if i < len(expr) && expr[i] == '=' {
+				i++
+			}
+
+			// A single `!` is not a valid operator
+			if expr[start:i] == "!" {
+				return nil, filterError(token{text: "!", pos: start + 1}, "unknown operator")
+			}
+
+			tokens = append(tokens, token{kind: tokenOperator, text: expr[start:i], pos: start + 1})
+		case c == '"':
+			// Find closing quote skipping escaped characters
+			for i++; i < len(expr) && expr[i] != '"'; i++ {
+				if expr[i] == '\\' {
+					i++
+				}
+			}
+
+			if i >= len(expr) {
+				return nil, filterError(token{text: expr[start:], pos: start + 1}, "unterminated string")
+			}
+
+			i++
+
+			value, err := strconv.Unquote(expr[start:i])
+			if err != nil {
+				return nil, filterError(token{text: expr[start:i], pos: start + 1}, "malformed string")
+			}
+
+			tokens = append(tokens, token{kind: tokenString, text: expr[start:i], value: value, pos: start + 1})
+		case c == '-' || c == '.' || isDigit(c):
+			for i++; i < len(expr) && strings.ContainsRune("0123456789.eE+-", rune(expr[i])); i++ {
+				// Sign is only valid after exponent
+				if (expr[i] == '+' || expr[i] == '-') && expr[i-1] != 'e' && expr[i-1] != 'E' {
+					break
+				}
+			}
+
+			if _, err := strconv.ParseFloat(expr[start:i], 64); err != nil {
+				return nil, filterError(token{text: expr[start:i], pos: start + 1}, "malformed number")
+			}
+
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], value: expr[start:i], pos: start + 1})
+		case isIdentChar(c) && !isDigit(c):
+			i++
+			for i < len(expr) && (isIdentChar(expr[i]) || expr[i] == '.') {
+				i++
+			}
+
+			t := token{kind: tokenIdent, text: expr[start:i], pos: start + 1}
+
+			switch strings.ToLower(t.text) {
+			case "and":
+				t.kind = tokenAnd
+			case "or":
+				t.kind = tokenOr
+			case "not":
+				t.kind = tokenNot
+			case "in":
+				t.kind = tokenIn
+			}
+
+			tokens = append(tokens, t)
+		default:
+			return nil, filterError(token{text: string(c), pos: start + 1}, "unexpected character")
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(expr) + 1}), nil
+}
+
+// isDigit returns true if c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentChar returns true if c can be part of an identifier.
+func isIdentChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// filterParser is a recursive descent parser of filter expressions that
+// compiles them into parameterised SQL. The grammar is:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" expr ")" | comparison
+//	comparison = field operator value | field [ "not" ] "in" "(" value { "," value } ")"
+//	field      = column | column "." key
+//	operator   = "=" | "!=" | "<" | "<=" | ">" | ">="
+//	value      = string | number
+type filterParser struct {
+	tokens []token
+	pos    int
+	fields map[string]filterFieldType
+	q      Query
+}
+
+// parseFilter parses filter expression and returns the SQL condition as query.
+func parseFilter(expr string, fields map[string]filterFieldType) (Query, error) {
+	if len(expr) > maxFilterLength {
+		return Query{}, fmt.Errorf("%w: filter must not be longer than %d characters", errInvalidFilter, maxFilterLength)
+	}
+
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return Query{}, err
+	}
+
+	p := &filterParser{tokens: tokens, fields: fields}
+
+	if p.peek().kind == tokenEOF {
+		return Query{}, filterError(p.peek(), "empty filter")
+	}
+
+	if err := p.parseExpr(); err != nil {
+		return Query{}, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return Query{}, filterError(t, "unexpected token")
+	}
+
+	return p.q, nil
+}
+
+// peek returns current token.
+func (p *filterParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+// next returns current token and advances to next one.
+func (p *filterParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+
+	return t
+}
+
+// expect consumes a token of given kind or returns an error.
+func (p *filterParser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, filterError(t, "expected %s", what)
+	}
+
+	return t, nil
+}
+
+// parseExpr parses expressions joined by `or`.
+func (p *filterParser) parseExpr() error {
+	if err := p.parseAnd(); err != nil {
+		return err
+	}
+
+	for p.peek().kind == tokenOr {
+		p.next()
+		p.q.query(" OR ")
+
+		if err := p.parseAnd(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseAnd parses expressions joined by `and`.
+func (p *filterParser) parseAnd() error {
+	if err := p.parseUnary(); err != nil {
+		return err
+	}
+
+	for p.peek().kind == tokenAnd {
+		p.next()
+		p.q.query(" AND ")
+
+		if err := p.parseUnary(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseUnary parses negations, parenthesised expressions and comparisons.
+func (p *filterParser) parseUnary() error {
+	switch p.peek().kind { //nolint:exhaustive
+	case tokenNot:
+		p.next()
+		p.q.query("NOT ")
+
+		return p.parseUnary()
+	case tokenLParen:
+		p.next()
+		p.q.query("(")
+
+		if err := p.parseExpr(); err != nil {
+			return err
+		}
+
+		if _, err := p.expect(tokenRParen, "closing parenthesis"); err != nil {
+			return err
+		}
+
+		p.q.query(")")
+
+		return nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+// parseComparison parses comparison of a field with values.
+func (p *filterParser) parseComparison() error {
+	field, err := p.expect(tokenIdent, "field name")
+	if err != nil {
+		return err
+	}
+
+	column, key, _ := strings.Cut(field.text, ".")
+
+	typ, ok := p.fields[column]
+	if !ok {
+		return filterError(field, "unknown field")
+	}
+
+	// Map fields must be compared by their keys and other fields
+	// cannot have keys
+	var expr string
+
+	switch {
+	case typ == filterMap && key == "":
+		return filterError(field, "field %s must be used with a key like %s.<key>", column, column)
+	case typ != filterMap && key != "":
+		return filterError(field, "field %s does not have keys", column)
+	case typ == filterMap:
+		if !filterKeyRegex.MatchString(key) {
+			return filterError(field, "invalid key")
+		}
+
+		// JSON path is passed as parameter
+		expr = fmt.Sprintf("json_extract(%s,?)", column)
+	default:
+		expr = column
+	}
+
+	// Get operator
+	op := p.next()
+
+	var negate bool
+	if op.kind == tokenNot {
+		negate = true
+		op = p.next()
+	}
+
+	switch {
+	case op.kind == tokenIn:
+		p.q.query(expr)
+
+		if typ == filterMap {
+			p.q.params = append(p.q.params, "$."+key)
+		}
+
+		if negate {
+			p.q.query(" NOT")
+		}
+
+		p.q.query(" IN (")
+
+		if _, err := p.expect(tokenLParen, "opening parenthesis"); err != nil {
+			return err
+		}
+
+		for i := 0; ; i++ {
+			if i > 0 {
+				p.q.query(",")
+			}
+
+			if err := p.parseValue(typ); err != nil {
+				return err
+			}
+
+			if t := p.next(); t.kind == tokenRParen {
+				break
+			} else if t.kind != tokenComma {
+				return filterError(t, "expected comma or closing parenthesis")
+			}
+		}
+
+		p.q.query(")")
+
+		return nil
+	case negate:
+		return filterError(op, "expected in")
+	case op.kind == tokenOperator:
+		p.q.query(fmt.Sprintf("%s %s ", expr, op.text))
+
+		if typ == filterMap {
+			p.q.params = append(p.q.params, "$."+key)
+		}
+
+		return p.parseValue(typ)
+	default:
+		return filterError(op, "expected operator")
+	}
+}
+
+// parseValue parses a value and adds it as query parameter.
+func (p *filterParser) parseValue(typ filterFieldType) error {
+	t := p.next()
+
+	switch {
+	case t.kind == tokenString && typ != filterNumber:
+		p.q.query("?")
+	case t.kind == tokenNumber && typ != filterText:
+		// Parameters are bound as text and they must be cast to number
+		// to be compared with numeric columns and JSON values
+		p.q.query("CAST(? AS REAL)")
+	case t.kind == tokenString:
+		return filterError(t, "expected number")
+	case t.kind == tokenNumber:
+		return filterError(t, "expected string")
+	default:
+		return filterError(t, "expected value")
+	}
+
+	p.q.params = append(p.q.params, t.value)
+
+	return nil
+}
@@ -0,0 +1,229 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		query  string
+		params []any
+		err    string
+	}{
+		{
+			name:   "text field",
+			filter: `state="FAILED"`,
+			query:  "state = ?",
+			params: []any{"FAILED"},
+		},
+		{
+			name:   "map fields with and",
+			filter: `state="FAILED" and allocation.gpus>=4 and avg_gpu_usage.global<10`,
+			query:  "state = ? AND json_extract(allocation,?) >= CAST(? AS REAL) AND json_extract(avg_gpu_usage,?) < CAST(? AS REAL)",
+			params: []any{"FAILED", "$.gpus", "4", "$.global", "10"},
+		},
+		{
+			name:   "or, not and parentheses",
+			filter: `not (tags.partition = "gpu" OR ended_at_ts > 1.5e3)`,
+			query:  "NOT (json_extract(tags,?) = ? OR ended_at_ts > CAST(? AS REAL))",
+			params: []any{"$.partition", "gpu", "1.5e3"},
+		},
+		{
+			name:   "in and not in",
+			filter: `state in ("FAILED", "TIMEOUT") and allocation.nodes not in (1,-2)`,
+			query:  "state IN (?,?) AND json_extract(allocation,?) NOT IN (CAST(? AS REAL),CAST(? AS REAL))",
+			params: []any{"FAILED", "TIMEOUT", "$.nodes", "1", "-2"},
+		},
+		{
+			name:   "escaped string",
+			filter: `name = "my \"job\""`,
+			query:  "name = ?",
+			params: []any{`my "job"`},
+		},
+		{
+			name:   "unknown field",
+			filter: `state="FAILED" and foo=1`,
+			err:    `unknown field at position 20 near "foo"`,
+		},
+		{
+			name:   "map field without key",
+			filter: `allocation>1`,
+			err:    `field allocation must be used with a key like allocation.<key> at position 1 near "allocation"`,
+		},
+		{
+			name:   "scalar field with key",
+			filter: `state.foo="a"`,
+			err:    `field state does not have keys at position 1 near "state.foo"`,
+		},
+		{
+			name:   "number for text field",
+			filter: `state=1`,
+			err:    `expected string at position 7 near "1"`,
+		},
+		{
+			name:   "string for number field",
+			filter: `ended_at_ts>"a"`,
+			err:    `expected number at position 13 near "\"a\""`,
+		},
+		{
+			name:   "missing operator",
+			filter: `state "FAILED"`,
+			err:    `expected operator at position 7 near "\"FAILED\""`,
+		},
+		{
+			name:   "unterminated string",
+			filter: `state="FAILED`,
+			err:    `unterminated string at position 7`,
+		},
+		{
+			name:   "unbalanced parentheses",
+			filter: `(state="FAILED"`,
+			err:    `expected closing parenthesis at position 16 near end of filter`,
+		},
+		{
+			name:   "trailing tokens",
+			filter: `state="FAILED")`,
+			err:    `unexpected token at position 15 near ")"`,
+		},
+		{
+			name:   "unexpected character",
+			filter: `state;="FAILED"`,
+			err:    `unexpected character at position 6 near ";"`,
+		},
+		{
+			name:   "annotations are not supported",
+			filter: `annotations.run="a"`,
+			err:    `unknown field at position 1`,
+		},
+		{
+			name:   "empty filter",
+			filter: ` `,
+			err:    `empty filter`,
+		},
+	}
+
+	for _, test := range tests {
+		q, err := parseFilter(test.filter, unitsFilterFields)
+		if test.err != "" {
+			require.ErrorIs(t, err, errInvalidFilter, test.name)
+			assert.Contains(t, err.Error(), test.err, test.name)
+
+			continue
+		}
+
+		require.NoError(t, err, test.name)
+
+		query, params := q.get()
+		assert.Equal(t, test.query, query, test.name)
+		assert.Equal(t, test.params, params, test.name)
+	}
+}
+
+func TestUnitsHandlerWithFilter(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add states, allocations and metrics to units
+	for _, stmt := range []string{
+		`UPDATE units SET state = 'FAILED', allocation = '{"gpus":4,"cpus":8}', tags = '{"partition":"gpu"}', avg_gpu_usage = '{"global":5}' WHERE uuid = '1000'`,
+		`UPDATE units SET state = 'FAILED', allocation = '{"gpus":8,"cpus":16}', tags = '{"partition":"gpu"}', avg_gpu_usage = '{"global":50}' WHERE uuid = '1001'`,
+		`UPDATE units SET allocation = '{"cpus":32}', tags = '{"partition":"cpu"}' WHERE uuid = '1002'`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		code    int
+		uuids   []string
+	}{
+		{
+			name:    "state and metric thresholds",
+			filters: []string{`state="FAILED" and allocation.gpus>=4 and avg_gpu_usage.global<10`},
+			code:    200,
+			uuids:   []string{"1000"},
+		},
+		{
+			name:    "partition",
+			filters: []string{`tags.partition = "gpu"`},
+			code:    200,
+			uuids:   []string{"1000", "1001"},
+		},
+		{
+			name:    "or with parentheses",
+			filters: []string{`(allocation.cpus > 10 and tags.partition="cpu") or allocation.gpus = 4`},
+			code:    200,
+			uuids:   []string{"1000", "1002"},
+		},
+		{
+			name:    "multiple filters",
+			filters: []string{`state in ("FAILED")`, `allocation.cpus > 10`},
+			code:    200,
+			uuids:   []string{"1001"},
+		},
+		{
+			name:    "not",
+			filters: []string{`not state = "FAILED"`},
+			code:    200,
+			uuids:   []string{"1002"},
+		},
+		{
+			name:    "invalid filter",
+			filters: []string{`state = FAILED`},
+			code:    400,
+		},
+	}
+
+	for _, test := range tests {
+		values := url.Values{
+			"from":   []string{"1728500000"},
+			"to":     []string{"1728600000"},
+			"filter": test.filters,
+		}
+
+		request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/units/admin?"+values.Encode(), nil)
+
+		w := httptest.NewRecorder()
+		server.unitsAdmin(w, request)
+
+		res := w.Result()
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err, test.name)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+
+		if test.code != 200 {
+			assert.Contains(t, string(data), "at position 9", test.name)
+
+			continue
+		}
+
+		var response Response[models.Unit]
+		require.NoError(t, json.Unmarshal(data, &response), test.name)
+
+		var uuids []string
+		for _, unit := range response.Data {
+			uuids = append(uuids, unit.UUID)
+		}
+
+		assert.Equal(t, test.uuids, uuids, test.name)
+	}
+}
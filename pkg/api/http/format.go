@@ -0,0 +1,368 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+)
+
+// Supported response formats.
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// Content types of response formats.
+var formatContentTypes = map[string]string{
+	formatJSON:   "application/json",
+	formatCSV:    "text/csv",
+	formatNDJSON: "application/x-ndjson",
+}
+
+// responseFormat returns the format of response based on `format` query parameter
+// and `Accept` header. Query parameter takes precedence over header.
+func responseFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", fmt.Errorf("%w: %s", errUnsupportedFormat, format)
+		}
+
+		return format, nil
+	}
+
+	// Return first supported media type in Accept header. Quality values
+	// are ignored
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "text/csv":
+			return formatCSV, nil
+		case "application/x-ndjson", "application/ndjson", "application/jsonl":
+			return formatNDJSON, nil
+		case "application/json", "*/*":
+			return formatJSON, nil
+		}
+	}
+
+	return formatJSON, nil
+}
+
+// column is a column in CSV output.
+type column struct {
+	name  string // Name of column in header
+	index int    // Index of struct field
+	key   string // Key of map for map fields
+}
+
+// rowEncoder encodes rows in CSV or NDJSON formats. HTTP headers and
+// CSV header are only written when first row is encoded or when encoder
+// is closed so that errors before the first row can still be returned
+// as error responses.
+type rowEncoder[T any] struct {
+	w        http.ResponseWriter
+	format   string
+	resource string
+	next     string
+	columns  []column
+	buf      *bufio.Writer
+	csv      *csv.Writer
+	json     *json.Encoder
+	record   []string
+	started  bool
+}
+
+// newRowEncoder returns a new instance of rowEncoder. The fields are the queried
+// fields and mapKeys are the keys of the map fields that are flattened into
+// separate columns in CSV output.
+func newRowEncoder[T any](
+	w http.ResponseWriter,
+	format string,
+	resource string,
+	fields []string,
+	mapKeys map[string][]string,
+) *rowEncoder[T] {
+	e := &rowEncoder[T]{
+		w:        w,
+		format:   format,
+		resource: resource,
+		buf:      bufio.NewWriter(w),
+	}
+
+	switch format {
+	case formatCSV:
+		e.csv = csv.NewWriter(e.buf)
+		e.columns = csvColumns[T](fields, mapKeys)
+		e.record = make([]string, len(e.columns))
+	default:
+		e.json = json.NewEncoder(e.buf)
+	}
+
+	return e
+}
+
+// setNext sets the link to the next page of results.
+func (e *rowEncoder[T]) setNext(next string) {
+	e.next = next
+}
+
+// start writes HTTP headers and CSV header.
+func (e *rowEncoder[T]) start() error {
+	e.started = true
+
+	e.w.Header().Set("Content-Type", formatContentTypes[e.format]+"; charset=utf-8")
+
+	if e.next != "" {
+		e.w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", e.next))
+	}
+
+	if e.format == formatCSV {
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.resource+".csv"))
+	}
+
+	e.w.WriteHeader(http.StatusOK)
+
+	if e.format != formatCSV {
+		return nil
+	}
+
+	header := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		header[i] = c.name
+	}
+
+	return e.csv.Write(header)
+}
+
+// encode encodes a single row.
+func (e *rowEncoder[T]) encode(row T) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	if e.format != formatCSV {
+		return e.json.Encode(row)
+	}
+
+	v := reflect.ValueOf(row)
+	for i, c := range e.columns {
+		e.record[i] = csvValue(v.Field(c.index), c.key)
+	}
+
+	return e.csv.Write(e.record)
+}
+
+// close flushes all the buffered rows.
+func (e *rowEncoder[T]) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+
+	if e.csv != nil {
+		e.csv.Flush()
+
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+
+	return e.buf.Flush()
+}
+
+// mapFields returns the queried fields of T that are maps.
+func mapFields[T any](fields []string) []string {
+	var names []string
+
+	t := reflect.TypeFor[T]()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if t.Field(i).Type.Kind() == reflect.Map && slices.Contains(fields, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// csvColumns returns columns of CSV output of T in the order of struct
+// fields. Map fields are flattened into one column per key.
+func csvColumns[T any](fields []string, mapKeys map[string][]string) []column {
+	var columns []column
+
+	t := reflect.TypeFor[T]()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if name == "" || !slices.Contains(fields, name) {
+			continue
+		}
+
+		if t.Field(i).Type.Kind() != reflect.Map {
+			columns = append(columns, column{name: name, index: i})
+
+			continue
+		}
+
+		keys := slices.Clone(mapKeys[name])
+		slices.Sort(keys)
+
+		for _, key := range slices.Compact(keys) {
+			columns = append(columns, column{name: name + "." + key, index: i, key: key})
+		}
+	}
+
+	return columns
+}
+
+// sliceMapKeys returns the keys of map fields in rows.
+func sliceMapKeys[T any](rows []T, fields []string) map[string][]string {
+	keys := make(map[string][]string)
+
+	t := reflect.TypeFor[T]()
+	for i := range t.NumField() {
+		name := jsonName(t.Field(i))
+		if t.Field(i).Type.Kind() != reflect.Map || !slices.Contains(fields, name) {
+			continue
+		}
+
+		for _, row := range rows {
+			for _, k := range reflect.ValueOf(row).Field(i).MapKeys() {
+				keys[name] = append(keys[name], k.String())
+			}
+		}
+	}
+
+	return keys
+}
+
+// jsonName returns the name of JSON tag of field.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+
+	return name
+}
+
+// csvValue returns the string representation of value. For maps, value of key
+// is returned.
+func csvValue(v reflect.Value, key string) string {
+	if v.Kind() == reflect.Map {
+		if v.IsNil() {
+			return ""
+		}
+
+		mv := v.MapIndex(reflect.ValueOf(key))
+		if !mv.IsValid() {
+			return ""
+		}
+
+		v = mv
+	}
+
+	switch val := v.Interface().(type) {
+	case string:
+		return val
+	case models.JSONFloat:
+		return strconv.FormatFloat(float64(val), 'f', -1, 64)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case int:
+		return strconv.Itoa(val)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// writeRows writes rows yielded by rows function in CSV or NDJSON format.
+func writeRows[T any](
+	w http.ResponseWriter,
+	format string,
+	resource string,
+	fields []string,
+	mapKeys map[string][]string,
+	next string,
+	rows func(func(T) error) error,
+	logger *slog.Logger,
+) {
+	enc := newRowEncoder[T](w, format, resource, fields, mapKeys)
+	enc.setNext(next)
+
+	if err := rows(enc.encode); err != nil {
+		// If nothing has been written yet, we can still return an error response
+		if !enc.started {
+			logger.Error("Failed to fetch rows", "resource", resource, "err", err)
+			errorResponse[any](w, &apiError{errorInternal, err}, logger, nil)
+
+			return
+		}
+
+		logger.Error("Failed to stream rows", "resource", resource, "err", err)
+	}
+
+	if err := enc.close(); err != nil {
+		logger.Error("Failed to write rows", "resource", resource, "err", err)
+	}
+}
+
+// sliceRows returns a function that yields rows of slice.
+func sliceRows[T any](rows []T) func(func(T) error) error {
+	return func(yield func(T) error) error {
+		for _, row := range rows {
+			if err := yield(row); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// mapKeys returns the keys of map fields in the rows returned by query.
+func (s *CEEMSServer) mapKeys(ctx context.Context, q Query, fields []string) (map[string][]string, error) {
+	keys := make(map[string][]string)
+
+	for _, field := range fields {
+		kq := Query{}
+		kq.query("SELECT DISTINCT json_each.key AS name FROM ")
+		kq.subQuery(q)
+		kq.query(fmt.Sprintf(", json_each(%s)", field))
+
+		fieldKeys, err := s.queriers.key(ctx, s.db, kq, s.logger)
+		if fieldKeys == nil && err != nil {
+			return nil, err
+		}
+
+		for _, key := range fieldKeys {
+			keys[field] = append(keys[field], key.Name)
+		}
+	}
+
+	return keys, nil
+}
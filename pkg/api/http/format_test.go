@@ -0,0 +1,251 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		format string
+		err    bool
+	}{
+		{
+			name:   "default format",
+			format: formatJSON,
+		},
+		{
+			name:   "format query parameter",
+			query:  "format=csv",
+			accept: "application/json",
+			format: formatCSV,
+		},
+		{
+			name:   "csv accept header",
+			accept: "text/csv",
+			format: formatCSV,
+		},
+		{
+			name:   "ndjson accept header with parameters",
+			accept: "text/html;q=0.9, application/x-ndjson;q=0.8",
+			format: formatNDJSON,
+		},
+		{
+			name:   "unsupported accept header",
+			accept: "text/html",
+			format: formatJSON,
+		},
+		{
+			name:  "unsupported format query parameter",
+			query: "format=xml",
+			err:   true,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/units?"+test.query, nil)
+		request.Header.Set("Accept", test.accept)
+
+		format, err := responseFormat(request)
+		if test.err {
+			require.Error(t, err, test.name)
+
+			continue
+		}
+
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.format, format, test.name)
+	}
+}
+
+func TestUnitsHandlerFormats(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add metrics to units
+	for _, stmt := range []string{
+		`UPDATE units SET avg_cpu_usage = '{"global":10.5}', total_cpu_emissions_gms = '{"owid_total":2}' WHERE uuid = '1000'`,
+		`UPDATE units SET avg_cpu_usage = '{"global":20}', total_cpu_emissions_gms = '{"emaps_total":3}' WHERE uuid = '1001'`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	baseURL := "/api/" + base.APIVersion + "/units/admin?from=1728500000&to=1728600000&"
+
+	tests := []struct {
+		name        string
+		query       string
+		accept      string
+		code        int
+		contentType string
+		expected    string
+		link        bool
+	}{
+		{
+			name:        "csv with flattened metric maps",
+			query:       "format=csv&field=uuid&field=avg_cpu_usage&field=total_cpu_emissions_gms&field=username",
+			code:        200,
+			contentType: "text/csv; charset=utf-8",
+			expected: `uuid,username,avg_cpu_usage.global,total_cpu_emissions_gms.emaps_total,total_cpu_emissions_gms.owid_total
+1000,foousr,10.5,,2
+1001,barusr,20,3,
+1002,bazusr,,,
+`,
+		},
+		{
+			name:        "csv using accept header",
+			query:       "field=uuid&field=state",
+			accept:      "text/csv",
+			code:        200,
+			contentType: "text/csv; charset=utf-8",
+			expected: `uuid,state
+1000,COMPLETED
+1001,COMPLETED
+1002,COMPLETED
+`,
+		},
+		{
+			name:        "paginated csv",
+			query:       "format=csv&field=uuid&field=avg_cpu_usage&limit=2",
+			code:        200,
+			contentType: "text/csv; charset=utf-8",
+			expected: `uuid,avg_cpu_usage.global
+1000,10.5
+1001,20
+`,
+			link: true,
+		},
+		{
+			name:        "ndjson",
+			query:       "field=uuid&field=avg_cpu_usage",
+			accept:      "application/x-ndjson",
+			code:        200,
+			contentType: "application/x-ndjson; charset=utf-8",
+			expected: `{"uuid":"1000","avg_cpu_usage":{"global":10.50000000}}
+{"uuid":"1001","avg_cpu_usage":{"global":20}}
+{"uuid":"1002"}
+`,
+		},
+		{
+			name:  "unsupported format",
+			query: "format=xml",
+			code:  406,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, baseURL+test.query, nil)
+		if test.accept != "" {
+			request.Header.Set("Accept", test.accept)
+		}
+
+		w := httptest.NewRecorder()
+		server.unitsAdmin(w, request)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+
+		if test.code != 200 {
+			continue
+		}
+
+		assert.Equal(t, test.contentType, w.Header().Get("Content-Type"), test.name)
+		assert.Equal(t, test.expected, w.Body.String(), test.name)
+
+		if test.link {
+			assert.Contains(t, w.Header().Get("Link"), "cursor=", test.name)
+		} else {
+			assert.Empty(t, w.Header().Get("Link"), test.name)
+		}
+	}
+}
+
+func TestUsageHandlerFormats(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add usage
+	for _, stmt := range []string{
+		`INSERT INTO usage (resource_manager,cluster_id,num_units,project,groupname,username,total_cpu_energy_usage_kwh,last_updated_at) VALUES ('slurm','slurm-0',10,'foo','grp','foousr','{"total":1.5}','2024-10-10T10:00:00')`,
+		`INSERT INTO usage (resource_manager,cluster_id,num_units,project,groupname,username,total_cpu_energy_usage_kwh,last_updated_at) VALUES ('slurm','slurm-0',5,'foo','grp','barusr','{"total":2.5}','2024-10-10T10:00:00')`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	// CSV
+	request := httptest.NewRequest(
+		http.MethodGet,
+		"/api/"+base.APIVersion+"/usage/global/admin?format=csv&field=username&field=num_units&field=total_cpu_energy_usage_kwh",
+		nil,
+	)
+	request = mux.SetURLVars(request, map[string]string{"mode": "global"})
+
+	w := httptest.NewRecorder()
+	server.usageAdmin(w, request)
+
+	require.Equal(t, 200, w.Code)
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"num_units", "username", "total_cpu_energy_usage_kwh.total"},
+		{"5", "barusr", "2.5"},
+		{"10", "foousr", "1.5"},
+	}, records)
+
+	// NDJSON
+	request = httptest.NewRequest(
+		http.MethodGet,
+		"/api/"+base.APIVersion+"/usage/global/admin?format=ndjson&field=username&field=num_units",
+		nil,
+	)
+	request = mux.SetURLVars(request, map[string]string{"mode": "global"})
+
+	w = httptest.NewRecorder()
+	server.usageAdmin(w, request)
+
+	require.Equal(t, 200, w.Code)
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var usage models.Usage
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &usage))
+	assert.Equal(t, "barusr", usage.User)
+	assert.Equal(t, int64(5), usage.NumUnits)
+}
+
+func TestStatsHandlerFormats(t *testing.T) {
+	server := setupServer(t.TempDir())
+	defer server.Shutdown(context.Background())
+
+	request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/stats/global/admin?format=csv", nil)
+	request = mux.SetURLVars(request, map[string]string{"mode": "global"})
+
+	w := httptest.NewRecorder()
+	server.statsAdmin(w, request)
+
+	require.Equal(t, 200, w.Code)
+
+	records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, statsFields, records[0])
+	assert.Equal(t, "slurm-0", records[1][0])
+}
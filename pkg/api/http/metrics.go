@@ -0,0 +1,252 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Timeout of DB queries made during a scrape.
+const usageMetricsQueryTimeout = 30 * time.Second
+
+// UsageMetricsConfig contains the configuration of usage metrics exported on
+// `/metrics` endpoint.
+type UsageMetricsConfig struct {
+	Enabled  bool           `yaml:"enabled"`
+	CacheTTL model.Duration `yaml:"cache_ttl"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *UsageMetricsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = UsageMetricsConfig{
+		CacheTTL: model.Duration(5 * time.Minute),
+	}
+
+	type plain UsageMetricsConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *UsageMetricsConfig) Validate() error {
+	if c.CacheTTL < 0 {
+		return errors.New("cache_ttl of usage metrics must not be negative")
+	}
+
+	return nil
+}
+
+// usageMetricsCollector exports cumulative usage of projects and users and
+// stats of clusters as Prometheus metrics. Results of DB queries are cached
+// for cacheTTL as usage in DB only changes on every update interval.
+type usageMetricsCollector struct {
+	server          *CEEMSServer
+	cacheTTL        time.Duration
+	mu              sync.Mutex
+	lastFetched     time.Time
+	usage           []models.Usage
+	stats           []models.Stat
+	numUnits        *prometheus.Desc
+	totalTime       *prometheus.Desc
+	cpuEnergy       *prometheus.Desc
+	gpuEnergy       *prometheus.Desc
+	cpuEmissions    *prometheus.Desc
+	gpuEmissions    *prometheus.Desc
+	statsUnits      *prometheus.Desc
+	statsActive     *prometheus.Desc
+	statsInactive   *prometheus.Desc
+	statsProjects   *prometheus.Desc
+	statsUsers      *prometheus.Desc
+	lastFetchedTime *prometheus.Desc
+	scrapeError     *prometheus.Desc
+}
+
+// newUsageMetricsCollector returns a new instance of usageMetricsCollector.
+func newUsageMetricsCollector(server *CEEMSServer, cacheTTL time.Duration) *usageMetricsCollector {
+	usageLabels := []string{"cluster_id", "resource_manager", "project", "username"}
+	metricLabels := []string{"cluster_id", "resource_manager", "project", "username", "type"}
+	statsLabels := []string{"cluster_id", "resource_manager"}
+
+	return &usageMetricsCollector{
+		server:   server,
+		cacheTTL: cacheTTL,
+		numUnits: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "units"),
+			"Number of compute units of user in project",
+			usageLabels, nil,
+		),
+		totalTime: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "time_seconds"),
+			"Cumulative time consumed by compute units of user in project by type of time",
+			metricLabels, nil,
+		),
+		cpuEnergy: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "cpu_energy_kwh"),
+			"Cumulative CPU energy consumed by compute units of user in project in kWh",
+			metricLabels, nil,
+		),
+		gpuEnergy: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "gpu_energy_kwh"),
+			"Cumulative GPU energy consumed by compute units of user in project in kWh",
+			metricLabels, nil,
+		),
+		cpuEmissions: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "cpu_emissions_grams"),
+			"Cumulative equivalent CO2 emissions of CPU energy consumed by compute units of user in project in grams",
+			metricLabels, nil,
+		),
+		gpuEmissions: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "gpu_emissions_grams"),
+			"Cumulative equivalent CO2 emissions of GPU energy consumed by compute units of user in project in grams",
+			metricLabels, nil,
+		),
+		statsUnits: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "stats", "units"),
+			"Number of compute units in cluster",
+			statsLabels, nil,
+		),
+		statsActive: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "stats", "active_units"),
+			"Number of active compute units in cluster",
+			statsLabels, nil,
+		),
+		statsInactive: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "stats", "inactive_units"),
+			"Number of inactive compute units in cluster",
+			statsLabels, nil,
+		),
+		statsProjects: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "stats", "projects"),
+			"Number of projects in cluster",
+			statsLabels, nil,
+		),
+		statsUsers: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "stats", "users"),
+			"Number of users in cluster",
+			statsLabels, nil,
+		),
+		lastFetchedTime: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "last_fetched_timestamp_seconds"),
+			"Time at which usage and stats were last fetched from DB",
+			nil, nil,
+		),
+		scrapeError: prometheus.NewDesc(
+			prometheus.BuildFQName(base.CEEMSServerAppName, "usage", "scrape_error"),
+			"1 if there was an error fetching usage and stats, 0 otherwise",
+			nil, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *usageMetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.numUnits
+	ch <- c.totalTime
+	ch <- c.cpuEnergy
+	ch <- c.gpuEnergy
+	ch <- c.cpuEmissions
+	ch <- c.gpuEmissions
+	ch <- c.statsUnits
+	ch <- c.statsActive
+	ch <- c.statsInactive
+	ch <- c.statsProjects
+	ch <- c.statsUsers
+	ch <- c.lastFetchedTime
+	ch <- c.scrapeError
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *usageMetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Refresh cache when it is expired. On error, metrics from the last
+	// successful fetch are exported
+	scrapeError := 0.0
+
+	if time.Since(c.lastFetched) >= c.cacheTTL {
+		if err := c.fetch(); err != nil {
+			c.server.logger.Error("Failed to fetch usage metrics", "err", err)
+
+			scrapeError = 1
+		}
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+
+	if !c.lastFetched.IsZero() {
+		ch <- prometheus.MustNewConstMetric(c.lastFetchedTime, prometheus.GaugeValue, float64(c.lastFetched.Unix()))
+	}
+
+	for _, u := range c.usage {
+		labels := []string{u.ClusterID, u.ResourceManager, u.Project, u.User}
+
+		ch <- prometheus.MustNewConstMetric(c.numUnits, prometheus.GaugeValue, float64(u.NumUnits), labels...)
+
+		collectMetricMap(ch, c.totalTime, u.TotalTime, labels)
+		collectMetricMap(ch, c.cpuEnergy, u.TotalCPUEnergyUsage, labels)
+		collectMetricMap(ch, c.gpuEnergy, u.TotalGPUEnergyUsage, labels)
+		collectMetricMap(ch, c.cpuEmissions, u.TotalCPUEmissions, labels)
+		collectMetricMap(ch, c.gpuEmissions, u.TotalGPUEmissions, labels)
+	}
+
+	for _, s := range c.stats {
+		labels := []string{s.ClusterID, s.ResourceManager}
+
+		ch <- prometheus.MustNewConstMetric(c.statsUnits, prometheus.GaugeValue, float64(s.NumUnits), labels...)
+		ch <- prometheus.MustNewConstMetric(c.statsActive, prometheus.GaugeValue, float64(s.NumActiveUnits), labels...)
+		ch <- prometheus.MustNewConstMetric(c.statsInactive, prometheus.GaugeValue, float64(s.NumInActiveUnits), labels...)
+		ch <- prometheus.MustNewConstMetric(c.statsProjects, prometheus.GaugeValue, float64(s.NumProjects), labels...)
+		ch <- prometheus.MustNewConstMetric(c.statsUsers, prometheus.GaugeValue, float64(s.NumUsers), labels...)
+	}
+}
+
+// fetch fetches usage and stats from DB and updates cache.
+func (c *usageMetricsCollector) fetch() error {
+	ctx, cancel := context.WithTimeout(context.Background(), usageMetricsQueryTimeout)
+	defer cancel()
+
+	// Global usage of all projects and users
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(base.UsageDBTableColNames, ","), base.UsageDBTableName))
+
+	usage, err := c.server.queriers.usage(ctx, c.server.db, q, c.server.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch usage: %w", err)
+	}
+
+	// Quick stats of all clusters
+	q = Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s GROUP BY cluster_id", statsQuery, base.UnitsDBTableName))
+
+	stats, err := c.server.queriers.stat(ctx, c.server.db, q, c.server.logger)
+	if err != nil {
+		return fmt.Errorf("failed to fetch stats: %w", err)
+	}
+
+	c.usage = usage
+	c.stats = stats
+	c.lastFetched = time.Now()
+
+	return nil
+}
+
+// collectMetricMap sends a metric for each key of metric map m with key as
+// `type` label.
+func collectMetricMap(ch chan<- prometheus.Metric, desc *prometheus.Desc, m models.MetricMap, labels []string) {
+	for key, value := range m {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(value), append(labels, key)...)
+	}
+}
@@ -0,0 +1,89 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageMetricsConfigValidate(t *testing.T) {
+	c := UsageMetricsConfig{CacheTTL: model.Duration(time.Minute)}
+	require.NoError(t, c.Validate())
+
+	c = UsageMetricsConfig{CacheTTL: model.Duration(-time.Minute)}
+	assert.Error(t, c.Validate())
+}
+
+func TestUsageMetricsCollector(t *testing.T) {
+	server := setupServerWithDB(t)
+	server.queriers.usage = Querier[models.Usage]
+	server.queriers.stat = Querier[models.Stat]
+
+	_, err := server.writeDB.Exec(
+		`INSERT INTO usage (cluster_id,resource_manager,num_units,project,groupname,username,total_time_seconds,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-0','slurm',2,'foo','','foousr','{"alloc_cputime":7200}','{"total":1.5}','{"emaps_total":30}','2024-10-10T10:00:00')`,
+	)
+	require.NoError(t, err)
+
+	expected := `# HELP ceems_api_server_stats_active_units Number of active compute units in cluster
+# TYPE ceems_api_server_stats_active_units gauge
+ceems_api_server_stats_active_units{cluster_id="slurm-0",resource_manager="slurm"} 0
+# HELP ceems_api_server_stats_inactive_units Number of inactive compute units in cluster
+# TYPE ceems_api_server_stats_inactive_units gauge
+ceems_api_server_stats_inactive_units{cluster_id="slurm-0",resource_manager="slurm"} 3
+# HELP ceems_api_server_stats_projects Number of projects in cluster
+# TYPE ceems_api_server_stats_projects gauge
+ceems_api_server_stats_projects{cluster_id="slurm-0",resource_manager="slurm"} 2
+# HELP ceems_api_server_stats_units Number of compute units in cluster
+# TYPE ceems_api_server_stats_units gauge
+ceems_api_server_stats_units{cluster_id="slurm-0",resource_manager="slurm"} 3
+# HELP ceems_api_server_stats_users Number of users in cluster
+# TYPE ceems_api_server_stats_users gauge
+ceems_api_server_stats_users{cluster_id="slurm-0",resource_manager="slurm"} 3
+# HELP ceems_api_server_usage_cpu_emissions_grams Cumulative equivalent CO2 emissions of CPU energy consumed by compute units of user in project in grams
+# TYPE ceems_api_server_usage_cpu_emissions_grams gauge
+ceems_api_server_usage_cpu_emissions_grams{cluster_id="slurm-0",project="foo",resource_manager="slurm",type="emaps_total",username="foousr"} 30
+# HELP ceems_api_server_usage_cpu_energy_kwh Cumulative CPU energy consumed by compute units of user in project in kWh
+# TYPE ceems_api_server_usage_cpu_energy_kwh gauge
+ceems_api_server_usage_cpu_energy_kwh{cluster_id="slurm-0",project="foo",resource_manager="slurm",type="total",username="foousr"} 1.5
+# HELP ceems_api_server_usage_scrape_error 1 if there was an error fetching usage and stats, 0 otherwise
+# TYPE ceems_api_server_usage_scrape_error gauge
+ceems_api_server_usage_scrape_error 0
+# HELP ceems_api_server_usage_time_seconds Cumulative time consumed by compute units of user in project by type of time
+# TYPE ceems_api_server_usage_time_seconds gauge
+ceems_api_server_usage_time_seconds{cluster_id="slurm-0",project="foo",resource_manager="slurm",type="alloc_cputime",username="foousr"} 7200
+# HELP ceems_api_server_usage_units Number of compute units of user in project
+# TYPE ceems_api_server_usage_units gauge
+ceems_api_server_usage_units{cluster_id="slurm-0",project="foo",resource_manager="slurm",username="foousr"} 2
+`
+
+	collector := newUsageMetricsCollector(server, time.Minute)
+
+	require.NoError(t, testutil.CollectAndCompare(
+		collector, strings.NewReader(expected),
+		"ceems_api_server_usage_units", "ceems_api_server_usage_time_seconds",
+		"ceems_api_server_usage_cpu_energy_kwh", "ceems_api_server_usage_cpu_emissions_grams",
+		"ceems_api_server_usage_scrape_error", "ceems_api_server_stats_units",
+		"ceems_api_server_stats_active_units", "ceems_api_server_stats_inactive_units",
+		"ceems_api_server_stats_projects", "ceems_api_server_stats_users",
+	))
+
+	// Cached values must be served until TTL expires
+	lastFetched := collector.lastFetched
+
+	_, err = server.writeDB.Exec(`UPDATE usage SET num_units = 5`)
+	require.NoError(t, err)
+
+	require.NoError(t, testutil.CollectAndCompare(
+		collector, strings.NewReader(expected), "ceems_api_server_usage_units",
+	))
+	assert.Equal(t, lastFetched, collector.lastFetched)
+}
@@ -21,9 +21,12 @@ import (
 // Define our struct.
 type authenticationMiddleware struct {
 	logger          *slog.Logger
+	routePrefix     string
 	whitelistedURLs *regexp.Regexp
 	db              *sql.DB
 	headers         []string
+	oidc            *oidcVerifier
+	apiTokens       func(context.Context, string) (string, []string, error)
 	adminUsers      func(context.Context, *sql.DB) ([]string, error)
 }
 
@@ -31,7 +34,7 @@ type authenticationMiddleware struct {
 func newAuthenticationMiddleware(routePrefix string, headers []string, db *sql.DB, logger *slog.Logger) (*authenticationMiddleware, error) {
 	// Playground: https://regex101.com/r/lkmsWz/3
 	urlsRegex, err := regexp.Compile(
-		fmt.Sprintf("^(?:(%s)?)/(swagger|debug|health|demo)(.*)", strings.TrimSuffix(routePrefix, "/")),
+		fmt.Sprintf("^(?:(%s)?)/(swagger|debug|health|metrics|demo)(.*)", strings.TrimSuffix(routePrefix, "/")),
 	)
 	if err != nil {
 		return nil, err
@@ -44,6 +47,7 @@ func newAuthenticationMiddleware(routePrefix string, headers []string, db *sql.D
 
 	return &authenticationMiddleware{
 		logger:          logger,
+		routePrefix:     strings.TrimSuffix(routePrefix, "/") + "/",
 		whitelistedURLs: urlsRegex,
 		db:              db,
 		headers:         headers,
@@ -54,7 +58,11 @@ func newAuthenticationMiddleware(routePrefix string, headers []string, db *sql.D
 // Middleware function, which will be called for each request.
 func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var loggedUser string
+		var loggedUser, token string
+
+		var tokenAdmin, personalToken bool
+
+		var scopes []string
 
 		var admUsers []string
 
@@ -70,6 +78,7 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		// If requested URI is one of the following, skip checking for user header
 		//  - /
 		//  - /health endpoint
+		//  - /metrics endpoint
 		//  - /demo/* endpoint
 		//  - /swagger/* endpoints
 		//  - /debug/* endpoints
@@ -85,8 +94,40 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		r.Header.Del(base.AdminUserHeader)
 		r.Header.Del(base.LoggedUserHeader)
 
-		// Check if username header is available
-		if loggedUser = amw.getAuthenticatedUserFromHeader(r); loggedUser == "" {
+		// When a personal API token is present, get user from token and check
+		// if the token has the scope for requested resource. When OIDC is configured
+		// and a bearer token is present, get user from token claims. Else check
+		// if username header is available
+		if token = bearerToken(r); strings.HasPrefix(token, apiTokenPrefix) && amw.apiTokens != nil {
+			if loggedUser, scopes, err = amw.apiTokens(r.Context(), token); err != nil {
+				amw.logger.Error("Invalid personal API token. Denying authentication", "err", err)
+
+				// Write an error and stop the handler chain
+				errorResponse[any](w, &apiError{errorUnauthorized, err}, amw.logger, nil)
+
+				return
+			}
+
+			if scope := apiTokenScope(r.Method, strings.TrimPrefix(r.URL.Path, amw.routePrefix)); scope == "" || !slices.Contains(scopes, scope) {
+				amw.logger.Error("Personal API token does not have scope for resource", "logged_user", loggedUser, "url", r.URL)
+
+				// Write an error and stop the handler chain
+				errorResponse[any](w, &apiError{errorForbidden, errTokenScope}, amw.logger, nil)
+
+				return
+			}
+
+			personalToken = true
+		} else if token != "" && amw.oidc != nil {
+			if loggedUser, tokenAdmin, err = amw.oidc.authenticate(r.Context(), token); err != nil {
+				amw.logger.Error("Invalid bearer token. Denying authentication", "err", err)
+
+				// Write an error and stop the handler chain
+				errorResponse[any](w, &apiError{errorUnauthorized, err}, amw.logger, nil)
+
+				return
+			}
+		} else if loggedUser = amw.getAuthenticatedUserFromHeader(r); loggedUser == "" {
 			amw.logger.Error("User Header not found. Denying authentication")
 
 			// Write an error and stop the handler chain
@@ -105,14 +146,17 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		q.Add("logged_user", loggedUser)
 		r.URL.RawQuery = q.Encode()
 
-		// Fetch admin users from DB
-		admUsers, err = amw.adminUsers(r.Context(), amw.db)
-		if err != nil {
-			amw.logger.Error("Failed to fetch admin users", "logged_user", loggedUser, "url", r.URL, "err", err)
+		// Fetch admin users from DB. Personal API tokens never grant admin privileges
+		if !personalToken {
+			admUsers, err = amw.adminUsers(r.Context(), amw.db)
+			if err != nil {
+				amw.logger.Error("Failed to fetch admin users", "logged_user", loggedUser, "url", r.URL, "err", err)
+			}
 		}
 
-		// If current user is not in the list of admin users, do access control of resource.
-		if slices.Contains(admUsers, loggedUser) {
+		// If current user is not in the list of admin users or does not have admin claim
+		// in the token, do access control of resource.
+		if tokenAdmin || slices.Contains(admUsers, loggedUser) {
 			// Set X-Ceems-Admin-User header
 			r.Header.Set(base.AdminUserHeader, loggedUser)
 		} else {
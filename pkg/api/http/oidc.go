@@ -0,0 +1,554 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// Minimum interval between JWKS fetches when a token is signed by an unknown key.
+const minJWKSRefreshInterval = time.Minute
+
+// OIDCConfig contains the configuration of OIDC/JWT bearer authentication.
+type OIDCConfig struct {
+	IssuerURL           string                  `yaml:"issuer_url"`
+	JWKSURL             string                  `yaml:"jwks_url"`
+	Audiences           []string                `yaml:"audiences"`
+	UsernameClaim       string                  `yaml:"username_claim"`
+	AdminClaim          string                  `yaml:"admin_claim"`
+	AdminClaimValues    []string                `yaml:"admin_claim_values"`
+	JWKSRefreshInterval model.Duration          `yaml:"jwks_refresh_interval"`
+	ClockSkew           model.Duration          `yaml:"clock_skew"`
+	HTTPClientConfig    config.HTTPClientConfig `yaml:"http_client_config"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OIDCConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = OIDCConfig{
+		UsernameClaim:       "preferred_username",
+		JWKSRefreshInterval: model.Duration(time.Hour),
+		ClockSkew:           model.Duration(time.Minute),
+		HTTPClientConfig:    config.DefaultHTTPClientConfig,
+	}
+
+	type plain OIDCConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Enabled returns true when OIDC authentication is configured.
+func (c *OIDCConfig) Enabled() bool {
+	return c.IssuerURL != ""
+}
+
+// Validate validates the config.
+func (c *OIDCConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if _, err := url.Parse(c.IssuerURL); err != nil {
+		return fmt.Errorf("invalid oidc issuer_url: %w", err)
+	}
+
+	// Without audience check, tokens issued to any client of the IdP
+	// will be accepted
+	if len(c.Audiences) == 0 {
+		return errors.New("oidc audiences must be configured")
+	}
+
+	if c.UsernameClaim == "" {
+		return errors.New("oidc username_claim must not be empty")
+	}
+
+	return c.HTTPClientConfig.Validate()
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *OIDCConfig) SetDirectory(dir string) {
+	c.HTTPClientConfig.SetDirectory(dir)
+}
+
+// jwtHeader is the JOSE header of JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the claims of JWT. Registered claims are validated
+// and the rest are used to get username and admin status.
+type jwtClaims map[string]any
+
+// audiences returns `aud` claim which can be either a string or a list of strings.
+func (c jwtClaims) audiences() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []any:
+		var auds []string
+
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+
+		return auds
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim as time.
+func (c jwtClaims) time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(v), 0), true
+}
+
+// strings returns a claim as slice of strings. Claims can be either strings
+// or lists of strings.
+func (c jwtClaims) strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var values []string
+
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+
+		return values
+	default:
+		return nil
+	}
+}
+
+// jwk is a JSON Web Key.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey returns the public key of JWK.
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus: %w", err)
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent: %w", err)
+		}
+
+		exp := new(big.Int).SetBytes(e)
+		if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid exponent")
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x coordinate: %w", err)
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y coordinate: %w", err)
+		}
+
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) { //nolint:staticcheck
+			return nil, errors.New("point is not on curve")
+		}
+
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// oidcVerifier verifies JWTs issued by an OIDC provider. The signing keys are
+// fetched from JWKS endpoint of the provider and cached. Keys are refreshed
+// periodically and whenever a token signed by an unknown key is presented.
+type oidcVerifier struct {
+	logger    *slog.Logger
+	config    OIDCConfig
+	client    *http.Client
+	mu        sync.RWMutex
+	jwksURL   string
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	now       func() time.Time
+}
+
+// newOIDCVerifier returns a new instance of oidcVerifier.
+func newOIDCVerifier(c OIDCConfig, logger *slog.Logger) (*oidcVerifier, error) {
+	client, err := config.NewClientFromConfig(c.HTTPClientConfig, "ceems_api_server_oidc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create oidc http client: %w", err)
+	}
+
+	return &oidcVerifier{
+		logger:  logger,
+		config:  c,
+		client:  client,
+		jwksURL: c.JWKSURL,
+		now:     time.Now,
+	}, nil
+}
+
+// discover fetches JWKS URL from the OpenID provider configuration.
+func (v *oidcVerifier) discover(ctx context.Context) (string, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+
+	u := strings.TrimSuffix(v.config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := v.getJSON(ctx, u, &discovery); err != nil {
+		return "", fmt.Errorf("failed to fetch openid configuration: %w", err)
+	}
+
+	if discovery.Issuer != v.config.IssuerURL {
+		return "", fmt.Errorf("issuer %s in openid configuration does not match with configured issuer", discovery.Issuer)
+	}
+
+	if discovery.JWKSURI == "" {
+		return "", errors.New("jwks_uri not found in openid configuration")
+	}
+
+	return discovery.JWKSURI, nil
+}
+
+// getJSON makes a GET request and decodes JSON response.
+func (v *oidcVerifier) getJSON(ctx context.Context, u string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(data)
+}
+
+// refresh fetches signing keys from JWKS endpoint. Must be called with lock held.
+func (v *oidcVerifier) refresh(ctx context.Context) error {
+	v.fetchedAt = v.now()
+
+	if v.jwksURL == "" {
+		u, err := v.discover(ctx)
+		if err != nil {
+			return err
+		}
+
+		v.jwksURL = u
+	}
+
+	var jwks struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := v.getJSON(ctx, v.jwksURL, &jwks); err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+
+	for _, k := range jwks.Keys {
+		// Ignore encryption keys
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			v.logger.Debug("Ignoring invalid key in JWKS", "kid", k.Kid, "err", err)
+
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	v.keys = keys
+
+	v.logger.Debug("Fetched JWKS", "url", v.jwksURL, "num_keys", len(keys))
+
+	return nil
+}
+
+// key returns the signing key with kid. When kid is not found, JWKS is refreshed
+// at most once every minJWKSRefreshInterval to pick up rotated keys.
+func (v *oidcVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	stale := v.now().Sub(v.fetchedAt) > time.Duration(v.config.JWKSRefreshInterval)
+	v.mu.RUnlock()
+
+	if ok && !stale {
+		return key, nil
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// Check again as keys might have been refreshed by another request
+	if key, ok = v.keys[kid]; ok && v.now().Sub(v.fetchedAt) <= time.Duration(v.config.JWKSRefreshInterval) {
+		return key, nil
+	}
+
+	if v.fetchedAt.IsZero() || v.now().Sub(v.fetchedAt) > minJWKSRefreshInterval {
+		if err := v.refresh(ctx); err != nil {
+			// Keep using the cached keys when provider is unreachable
+			v.logger.Error("Failed to refresh OIDC signing keys", "err", err)
+		}
+	}
+
+	if key, ok = v.keys[kid]; ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s", errUnknownKey, kid)
+}
+
+// verifySignature verifies the signature of signed content using the key.
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, sig []byte) error {
+	var h hash.Hash
+
+	var hashFunc crypto.Hash
+
+	switch alg[2:] {
+	case "256":
+		h, hashFunc = sha256.New(), crypto.SHA256
+	case "384":
+		h, hashFunc = sha512.New384(), crypto.SHA384
+	case "512":
+		h, hashFunc = sha512.New(), crypto.SHA512
+	}
+
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match with algorithm")
+		}
+
+		if alg[:2] == "PS" {
+			return rsa.VerifyPSS(pub, hashFunc, digest, sig, nil)
+		}
+
+		return rsa.VerifyPKCS1v15(pub, hashFunc, digest, sig)
+	default:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match with algorithm")
+		}
+
+		// Curve of key must be the one of algorithm as per RFC 7518
+		if curve := map[string]elliptic.Curve{
+			"256": elliptic.P256(), "384": elliptic.P384(), "512": elliptic.P521(),
+		}[alg[2:]]; pub.Curve != curve {
+			return errors.New("key curve does not match with algorithm")
+		}
+
+		// Signature is concatenation of r and s of same size
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("invalid signature length")
+		}
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("signature verification failed")
+		}
+
+		return nil
+	}
+}
+
+// verify verifies the token and returns its claims.
+func (v *oidcVerifier) verify(ctx context.Context, token string) (jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", errInvalidToken)
+	}
+
+	// Decode header
+	var header jwtHeader
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", errInvalidToken)
+	}
+
+	if err := json.Unmarshal(b, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", errInvalidToken)
+	}
+
+	// Only asymmetric algorithms are supported. Notably `none` and HMAC
+	// algorithms must never be accepted
+	if !slices.Contains([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}, header.Alg) {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", errInvalidToken, header.Alg)
+	}
+
+	// Verify signature
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", errInvalidToken)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %w", errInvalidToken, err)
+	}
+
+	// Decode claims
+	var claims jwtClaims
+
+	if b, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", errInvalidToken)
+	}
+
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", errInvalidToken)
+	}
+
+	// Validate registered claims
+	if iss, _ := claims["iss"].(string); iss != v.config.IssuerURL {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", errInvalidToken, iss)
+	}
+
+	if !slices.ContainsFunc(claims.audiences(), func(aud string) bool { return slices.Contains(v.config.Audiences, aud) }) {
+		return nil, fmt.Errorf("%w: unexpected audience", errInvalidToken)
+	}
+
+	now := v.now()
+	skew := time.Duration(v.config.ClockSkew)
+
+	exp, ok := claims.time("exp")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp claim", errInvalidToken)
+	}
+
+	if now.After(exp.Add(skew)) {
+		return nil, fmt.Errorf("%w: token expired", errInvalidToken)
+	}
+
+	if nbf, ok := claims.time("nbf"); ok && now.Add(skew).Before(nbf) {
+		return nil, fmt.Errorf("%w: token not valid yet", errInvalidToken)
+	}
+
+	return claims, nil
+}
+
+// authenticate verifies the token and returns the username and whether the
+// user has admin privileges.
+func (v *oidcVerifier) authenticate(ctx context.Context, token string) (string, bool, error) {
+	claims, err := v.verify(ctx, token)
+	if err != nil {
+		return "", false, err
+	}
+
+	username, _ := claims[v.config.UsernameClaim].(string)
+	if username == "" {
+		return "", false, fmt.Errorf("%w: missing %s claim", errInvalidToken, v.config.UsernameClaim)
+	}
+
+	// Admin claim can be either a boolean or a string/list of strings that
+	// must contain one of the configured values
+	var admin bool
+
+	if v.config.AdminClaim != "" {
+		if b, ok := claims[v.config.AdminClaim].(bool); ok {
+			admin = b
+		} else {
+			admin = slices.ContainsFunc(claims.strings(v.config.AdminClaim), func(s string) bool {
+				return slices.Contains(v.config.AdminClaimValues, s)
+			})
+		}
+	}
+
+	return username, admin, nil
+}
+
+// bearerToken returns the bearer token in Authorization header, if any.
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
@@ -0,0 +1,530 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockIdP is a mock OIDC provider.
+type mockIdP struct {
+	server     *httptest.Server
+	rsaKey     *rsa.PrivateKey
+	ecKey      *ecdsa.PrivateKey
+	jwksCalls  atomic.Int64
+	rotatedKey *rsa.PrivateKey
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	t.Helper()
+
+	idp := &mockIdP{}
+
+	var err error
+
+	idp.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   idp.server.URL,
+			"jwks_uri": idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		idp.jwksCalls.Add(1)
+
+		keys := []map[string]string{
+			{
+				"kty": "RSA",
+				"kid": "rsa",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.rsaKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.rsaKey.E)).Bytes()),
+			},
+			{
+				"kty": "EC",
+				"kid": "ec",
+				"crv": "P-256",
+				"x":   base64.RawURLEncoding.EncodeToString(idp.ecKey.X.FillBytes(make([]byte, 32))),
+				"y":   base64.RawURLEncoding.EncodeToString(idp.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		}
+
+		if idp.rotatedKey != nil {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": "rotated",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.rotatedKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.rotatedKey.E)).Bytes()),
+			})
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// sign returns a signed JWT with given claims.
+func (idp *mockIdP) sign(t *testing.T, alg string, kid string, claims map[string]any) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+
+	switch alg {
+	case "RS256":
+		key := idp.rsaKey
+		if kid == "rotated" {
+			key = idp.rotatedKey
+		}
+
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, idp.ecKey, digest[:])
+		require.NoError(t, err)
+
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	default:
+		sig = []byte("signature")
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// claims returns valid claims for the user.
+func (idp *mockIdP) claims(user string) map[string]any {
+	return map[string]any{
+		"iss":                idp.server.URL,
+		"aud":                []string{"ceems", "other"},
+		"sub":                "1234",
+		"preferred_username": user,
+		"groups":             []string{"users"},
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+	}
+}
+
+func newTestOIDCVerifier(t *testing.T, idp *mockIdP) *oidcVerifier {
+	t.Helper()
+
+	v, err := newOIDCVerifier(OIDCConfig{
+		IssuerURL:           idp.server.URL,
+		Audiences:           []string{"ceems"},
+		UsernameClaim:       "preferred_username",
+		AdminClaim:          "groups",
+		AdminClaimValues:    []string{"admins"},
+		JWKSRefreshInterval: model.Duration(time.Hour),
+		ClockSkew:           model.Duration(time.Minute),
+		HTTPClientConfig:    config.DefaultHTTPClientConfig,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	idp := newMockIdP(t)
+	v := newTestOIDCVerifier(t, idp)
+
+	tests := []struct {
+		name   string
+		token  func() string
+		user   string
+		admin  bool
+		errMsg string
+	}{
+		{
+			name:  "valid RSA token",
+			token: func() string { return idp.sign(t, "RS256", "rsa", idp.claims("foousr")) },
+			user:  "foousr",
+		},
+		{
+			name:  "valid EC token",
+			token: func() string { return idp.sign(t, "ES256", "ec", idp.claims("barusr")) },
+			user:  "barusr",
+		},
+		{
+			name: "admin claim",
+			token: func() string {
+				c := idp.claims("adm")
+				c["groups"] = []string{"users", "admins"}
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			user:  "adm",
+			admin: true,
+		},
+		{
+			name: "string audience",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["aud"] = "ceems"
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			user: "foousr",
+		},
+		{
+			name: "expired within clock skew",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["exp"] = time.Now().Add(-30 * time.Second).Unix()
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			user: "foousr",
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["exp"] = time.Now().Add(-time.Hour).Unix()
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "token expired",
+		},
+		{
+			name: "token without exp",
+			token: func() string {
+				c := idp.claims("foousr")
+				delete(c, "exp")
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "missing exp claim",
+		},
+		{
+			name: "token not valid yet",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["nbf"] = time.Now().Add(time.Hour).Unix()
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "token not valid yet",
+		},
+		{
+			name: "wrong issuer",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["iss"] = "https://evil.example.com"
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func() string {
+				c := idp.claims("foousr")
+				c["aud"] = "other"
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "unexpected audience",
+		},
+		{
+			name: "missing username claim",
+			token: func() string {
+				c := idp.claims("foousr")
+				delete(c, "preferred_username")
+
+				return idp.sign(t, "RS256", "rsa", c)
+			},
+			errMsg: "missing preferred_username claim",
+		},
+		{
+			name:   "none algorithm",
+			token:  func() string { return idp.sign(t, "none", "rsa", idp.claims("foousr")) },
+			errMsg: "unsupported algorithm",
+		},
+		{
+			name:   "HMAC algorithm",
+			token:  func() string { return idp.sign(t, "HS256", "rsa", idp.claims("foousr")) },
+			errMsg: "unsupported algorithm",
+		},
+		{
+			name:   "key type does not match algorithm",
+			token:  func() string { return idp.sign(t, "RS256", "ec", idp.claims("foousr")) },
+			errMsg: "key type does not match",
+		},
+		{
+			name: "tampered payload",
+			token: func() string {
+				token := idp.sign(t, "RS256", "rsa", idp.claims("foousr"))
+				other := idp.sign(t, "RS256", "rsa", idp.claims("adm"))
+
+				return token[:len(token)-10] + other[len(other)-10:]
+			},
+			errMsg: "invalid token",
+		},
+		{
+			name:   "unknown key",
+			token:  func() string { return idp.sign(t, "RS256", "foo", idp.claims("foousr")) },
+			errMsg: "unknown signing key",
+		},
+		{
+			name:   "malformed token",
+			token:  func() string { return "foo.bar" },
+			errMsg: "malformed token",
+		},
+	}
+
+	for _, test := range tests {
+		user, admin, err := v.authenticate(context.Background(), test.token())
+		if test.errMsg != "" {
+			require.ErrorIs(t, err, errInvalidToken, test.name)
+			assert.Contains(t, err.Error(), test.errMsg, test.name)
+
+			continue
+		}
+
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.user, user, test.name)
+		assert.Equal(t, test.admin, admin, test.name)
+	}
+}
+
+func TestVerifySignatureCurve(t *testing.T) {
+	keys := map[string]*ecdsa.PrivateKey{}
+
+	for name, curve := range map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()} {
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		require.NoError(t, err)
+
+		keys[name] = key
+	}
+
+	hashes := map[string]crypto.Hash{"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512}
+
+	tests := []struct {
+		alg     string
+		curve   string
+		wantErr bool
+	}{
+		{alg: "ES256", curve: "P-256"},
+		{alg: "ES384", curve: "P-384"},
+		{alg: "ES512", curve: "P-521"},
+		{alg: "ES256", curve: "P-384", wantErr: true},
+		{alg: "ES256", curve: "P-521", wantErr: true},
+		{alg: "ES384", curve: "P-256", wantErr: true},
+		{alg: "ES384", curve: "P-521", wantErr: true},
+		{alg: "ES512", curve: "P-256", wantErr: true},
+		{alg: "ES512", curve: "P-384", wantErr: true},
+	}
+
+	signed := []byte("header.payload")
+
+	for _, test := range tests {
+		key := keys[test.curve]
+
+		// Sign with hash of algorithm and curve of key so that only the curve
+		// mismatch can fail the verification
+		h := hashes[test.alg].New()
+		h.Write(signed)
+
+		r, s, err := ecdsa.Sign(rand.Reader, key, h.Sum(nil))
+		require.NoError(t, err)
+
+		size := (key.Curve.Params().BitSize + 7) / 8
+		sig := append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+
+		err = verifySignature(test.alg, &key.PublicKey, signed, sig)
+		if test.wantErr {
+			assert.Error(t, err, test.alg+" "+test.curve)
+		} else {
+			assert.NoError(t, err, test.alg+" "+test.curve)
+		}
+	}
+}
+
+func TestOIDCVerifierKeyRotation(t *testing.T) {
+	idp := newMockIdP(t)
+	v := newTestOIDCVerifier(t, idp)
+
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	// First request fetches keys
+	_, _, err := v.authenticate(context.Background(), idp.sign(t, "RS256", "rsa", idp.claims("foousr")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), idp.jwksCalls.Load())
+
+	// Keys are served from cache
+	_, _, err = v.authenticate(context.Background(), idp.sign(t, "ES256", "ec", idp.claims("foousr")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), idp.jwksCalls.Load())
+
+	// Rotate keys at IdP
+	idp.rotatedKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	// Unknown keys do not trigger a fetch within minimum refresh interval
+	_, _, err = v.authenticate(context.Background(), idp.sign(t, "RS256", "rotated", idp.claims("foousr")))
+	require.ErrorIs(t, err, errUnknownKey)
+	assert.Equal(t, int64(1), idp.jwksCalls.Load())
+
+	// After minimum refresh interval, unknown keys trigger a fetch
+	now = now.Add(2 * minJWKSRefreshInterval)
+
+	_, _, err = v.authenticate(context.Background(), idp.sign(t, "RS256", "rotated", idp.claims("foousr")))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), idp.jwksCalls.Load())
+
+	// Stale keys are still used when IdP is unreachable
+	idp.server.Close()
+
+	now = now.Add(2 * time.Hour)
+
+	claims := idp.claims("foousr")
+	claims["exp"] = now.Add(time.Hour).Unix()
+
+	_, _, err = v.authenticate(context.Background(), idp.sign(t, "RS256", "rsa", claims))
+	require.NoError(t, err)
+}
+
+func TestMiddlewareWithOIDC(t *testing.T) {
+	idp := newMockIdP(t)
+
+	amw, err := newAuthenticationMiddleware("/api/v1", []string{base.GrafanaUserHeader}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	amw.adminUsers = mockAdminUsers
+	amw.oidc = newTestOIDCVerifier(t, idp)
+
+	var loggedUser, adminUser string
+
+	handler := amw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loggedUser = r.Header.Get(base.LoggedUserHeader)
+		adminUser = r.Header.Get(base.AdminUserHeader)
+	}))
+
+	adminClaims := idp.claims("oidcadm")
+	adminClaims["groups"] = []string{"admins"}
+
+	expiredClaims := idp.claims("foousr")
+	expiredClaims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		token    string
+		header   string
+		code     int
+		user     string
+		admin    string
+	}{
+		{
+			name:     "user with bearer token",
+			endpoint: "/api/v1/units",
+			token:    idp.sign(t, "RS256", "rsa", idp.claims("foousr")),
+			code:     200,
+			user:     "foousr",
+		},
+		{
+			name:     "user with bearer token ignores user header",
+			endpoint: "/api/v1/units",
+			token:    idp.sign(t, "RS256", "rsa", idp.claims("foousr")),
+			header:   "adm1",
+			code:     200,
+			user:     "foousr",
+		},
+		{
+			name:     "user with bearer token accessing admin resource",
+			endpoint: "/api/v1/units/admin",
+			token:    idp.sign(t, "RS256", "rsa", idp.claims("foousr")),
+			code:     403,
+		},
+		{
+			name:     "admin claim accessing admin resource",
+			endpoint: "/api/v1/units/admin",
+			token:    idp.sign(t, "RS256", "rsa", adminClaims),
+			code:     200,
+			user:     "oidcadm",
+			admin:    "oidcadm",
+		},
+		{
+			name:     "admin user from DB with bearer token",
+			endpoint: "/api/v1/units/admin",
+			token:    idp.sign(t, "ES256", "ec", idp.claims("adm1")),
+			code:     200,
+			user:     "adm1",
+			admin:    "adm1",
+		},
+		{
+			name:     "expired bearer token",
+			endpoint: "/api/v1/units",
+			token:    idp.sign(t, "RS256", "rsa", expiredClaims),
+			code:     401,
+		},
+		{
+			name:     "user header without bearer token",
+			endpoint: "/api/v1/units",
+			header:   "barusr",
+			code:     200,
+			user:     "barusr",
+		},
+	}
+
+	for _, test := range tests {
+		loggedUser, adminUser = "", ""
+
+		request := httptest.NewRequest(http.MethodGet, test.endpoint, nil)
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		if test.header != "" {
+			request.Header.Set(base.GrafanaUserHeader, test.header)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+		assert.Equal(t, test.user, loggedUser, test.name)
+		assert.Equal(t, test.admin, adminUser, test.name)
+	}
+}
@@ -0,0 +1,264 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Limits on page size.
+const (
+	defaultPageLimit = 1000
+	maxPageLimit     = 10000
+)
+
+// sortKey is a column or SQL expression that query results can be sorted by.
+type sortKey struct {
+	expr    string // SQL expression
+	numeric bool   // Whether expression evaluates to a number
+}
+
+// Sort keys for different resources. Metric maps are sorted by their
+// aggregate keys.
+var (
+	unitsSortKeys = map[string]sortKey{
+		"uuid":                       {expr: "uuid"},
+		"name":                       {expr: "name"},
+		"project":                    {expr: "project"},
+		"username":                   {expr: "username"},
+		"state":                      {expr: "state"},
+		"created_at_ts":              {expr: "created_at_ts", numeric: true},
+		"started_at_ts":              {expr: "started_at_ts", numeric: true},
+		"ended_at_ts":                {expr: "ended_at_ts", numeric: true},
+		"elapsed":                    {expr: "json_extract(total_time_seconds,'$.walltime')", numeric: true},
+		"avg_cpu_usage":              {expr: "json_extract(avg_cpu_usage,'$.global')", numeric: true},
+		"avg_cpu_mem_usage":          {expr: "json_extract(avg_cpu_mem_usage,'$.global')", numeric: true},
+		"avg_gpu_usage":              {expr: "json_extract(avg_gpu_usage,'$.global')", numeric: true},
+		"avg_gpu_mem_usage":          {expr: "json_extract(avg_gpu_mem_usage,'$.global')", numeric: true},
+		"total_cpu_energy_usage_kwh": {expr: "json_extract(total_cpu_energy_usage_kwh,'$.total')", numeric: true},
+		"total_gpu_energy_usage_kwh": {expr: "json_extract(total_gpu_energy_usage_kwh,'$.total')", numeric: true},
+	}
+	usersSortKeys = map[string]sortKey{
+		"cluster_id": {expr: "cluster_id"},
+		"name":       {expr: "name"},
+	}
+	projectsSortKeys = map[string]sortKey{
+		"cluster_id": {expr: "cluster_id"},
+		"name":       {expr: "name"},
+	}
+)
+
+// cursor points to the last row of the previous page. It is encoded in
+// URL safe base64 and must be treated as opaque by clients. Value of numeric
+// sort keys is kept in its shortest exact representation so that it can be
+// compared against the same value in DB.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int64  `json:"i"`
+}
+
+// encode returns cursor as URL safe string.
+func (c cursor) encode() string {
+	b, _ := json.Marshal(c) //nolint:errchkjson
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes the cursor string.
+func decodeCursor(s string) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// value returns the typed value of sort key in cursor.
+func (c cursor) value(numeric bool) (any, error) {
+	if !numeric {
+		return c.Value, nil
+	}
+
+	if v, err := strconv.ParseInt(c.Value, 10, 64); err == nil {
+		return v, nil
+	}
+
+	v, err := strconv.ParseFloat(c.Value, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+
+	return v, nil
+}
+
+// page defines sorting and pagination of query results.
+type page struct {
+	table  string  // Table name
+	sort   string  // Sort parameter as passed in the request
+	key    sortKey // Resolved sort key
+	desc   bool    // Sort in descending order
+	limit  int     // Number of rows in the page
+	cursor *cursor // Cursor from previous page
+}
+
+// enabled returns true if either sorting or pagination is requested.
+func (p page) enabled() bool {
+	return p.sort != "" || p.limit > 0
+}
+
+// expr returns the SQL expression of the sort key.
+func (p page) expr() string {
+	if p.key.numeric {
+		return fmt.Sprintf("COALESCE(%s,0)", p.key.expr)
+	}
+
+	return fmt.Sprintf("COALESCE(%s,'')", p.key.expr)
+}
+
+// getPage returns sorting and pagination parameters from query parameters.
+func (s *CEEMSServer) getPage(urlValues url.Values, table string, keys map[string]sortKey) (page, error) {
+	p := page{table: table}
+
+	// Get sort key. A `-` prefix means descending order
+	if p.sort = strings.TrimSpace(urlValues.Get("sort")); p.sort != "" {
+		name := strings.TrimPrefix(p.sort, "-")
+
+		key, ok := keys[name]
+		if !ok {
+			return page{}, fmt.Errorf("%w: %s", errInvalidSortKey, name)
+		}
+
+		p.key = key
+		p.desc = strings.HasPrefix(p.sort, "-")
+	} else {
+		// When no sort key is provided, rows are returned in the order that
+		// they are inserted into DB which is stable across pages.
+		p.key = sortKey{expr: table + ".id", numeric: true}
+	}
+
+	// Get limit
+	if l := urlValues.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return page{}, fmt.Errorf("%w: must be an integer between 1 and %d", errInvalidLimit, maxPageLimit)
+		}
+
+		p.limit = limit
+	}
+
+	// Get cursor
+	if c := urlValues.Get("cursor"); c != "" {
+		var err error
+		if p.cursor, err = decodeCursor(c); err != nil {
+			return page{}, err
+		}
+
+		// Cursor must be used with the same sort key
+		if p.cursor.Sort != p.sort {
+			return page{}, fmt.Errorf("%w: sort parameter does not match with cursor", errInvalidCursor)
+		}
+
+		if _, err := p.cursor.value(p.key.numeric); err != nil {
+			return page{}, err
+		}
+
+		if p.limit == 0 {
+			p.limit = defaultPageLimit
+		}
+	}
+
+	return p, nil
+}
+
+// paginate adds keyset pagination and ORDER BY clauses to the query. It must be
+// called after adding all the WHERE conditions. One more row than the limit is
+// requested to check if there is a next page.
+func (q *Query) paginate(p page) {
+	order, cmp := "ASC", ">"
+	if p.desc {
+		order, cmp = "DESC", "<"
+	}
+
+	// Use row ID as tie breaker to make the order stable. Values are bound with
+	// their types to compare them exactly
+	if p.cursor != nil {
+		value, _ := p.cursor.value(p.key.numeric)
+
+		q.query(fmt.Sprintf(" AND (%s,%s.id) %s (?,?) ", p.expr(), p.table, cmp))
+		q.params = append(q.params, value, p.cursor.ID)
+	}
+
+	q.query(fmt.Sprintf(" ORDER BY %s %s, %s.id %s ", p.expr(), order, p.table, order))
+
+	if p.limit > 0 {
+		q.query(" LIMIT ")
+		q.param([]string{strconv.Itoa(p.limit + 1)})
+		q.limit = p.limit + 1
+	}
+}
+
+// nextPage trims the extra row fetched by the query and returns the link to
+// the next page, if there is one.
+func nextPage[T any](
+	ctx context.Context,
+	db *sql.DB,
+	r *http.Request,
+	p page,
+	rows []T,
+	rowID func(T) int64,
+) ([]T, string, error) {
+	if p.limit == 0 || len(rows) <= p.limit {
+		return rows, "", nil
+	}
+
+	rows = rows[:p.limit]
+	id := rowID(rows[p.limit-1])
+
+	// Get value of sort key of last row
+	var v any
+	if err := db.QueryRowContext(
+		ctx,
+		fmt.Sprintf("SELECT %s FROM %s WHERE id = ?", p.expr(), p.table), // #nosec
+		id,
+	).Scan(&v); err != nil {
+		return nil, "", fmt.Errorf("failed to fetch cursor: %w", err)
+	}
+
+	// Format floats with the minimum number of digits that represent them
+	// exactly
+	var value string
+
+	switch v := v.(type) {
+	case float64:
+		value = strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		value = string(v)
+	default:
+		value = fmt.Sprint(v)
+	}
+
+	// Make link to next page
+	values := r.URL.Query()
+	values.Set("limit", strconv.Itoa(p.limit))
+	values.Set("cursor", cursor{Sort: p.sort, Value: value, ID: id}.encode())
+
+	next := url.URL{Path: r.URL.Path, RawQuery: values.Encode()}
+
+	return rows, next.String(), nil
+}
@@ -0,0 +1,260 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fetchPages follows next links and returns all the pages.
+func fetchPages[T any](t *testing.T, handler func(http.ResponseWriter, *http.Request), req string) [][]T {
+	t.Helper()
+
+	var pages [][]T
+
+	for req != "" {
+		request := httptest.NewRequest(http.MethodGet, req, nil)
+
+		w := httptest.NewRecorder()
+		handler(w, request)
+
+		res := w.Result()
+		defer res.Body.Close()
+
+		data, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		require.Equal(t, 200, w.Code, string(data))
+
+		var response Response[T]
+		require.NoError(t, json.Unmarshal(data, &response))
+
+		pages = append(pages, response.Data)
+		req = response.Next
+
+		require.Less(t, len(pages), 10, "too many pages")
+	}
+
+	return pages
+}
+
+func TestUnitsPagination(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add energy usage to units
+	for _, stmt := range []string{
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) SELECT resource_manager,cluster_id,'1003',name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at FROM units WHERE uuid = '1000'`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) SELECT resource_manager,cluster_id,'1004',name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at FROM units WHERE uuid = '1000'`,
+		`UPDATE units SET total_cpu_energy_usage_kwh = '{"total":3}' WHERE uuid = '1000'`,
+		`UPDATE units SET total_cpu_energy_usage_kwh = '{"total":1}' WHERE uuid = '1001'`,
+		`UPDATE units SET total_cpu_energy_usage_kwh = '{"total":2}' WHERE uuid = '1002'`,
+		`UPDATE units SET total_cpu_energy_usage_kwh = '{"total":2}' WHERE uuid = '1003'`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		pages [][]string
+	}{
+		{
+			name:  "limit without sort",
+			query: "limit=2",
+			pages: [][]string{{"1000", "1001"}, {"1002", "1003"}, {"1004"}},
+		},
+		{
+			name:  "limit with ascending sort",
+			query: "limit=2&sort=total_cpu_energy_usage_kwh",
+			pages: [][]string{{"1004", "1001"}, {"1002", "1003"}, {"1000"}},
+		},
+		{
+			name:  "limit with descending sort",
+			query: "limit=2&sort=-total_cpu_energy_usage_kwh",
+			pages: [][]string{{"1000", "1003"}, {"1002", "1001"}, {"1004"}},
+		},
+		{
+			name:  "sort without limit",
+			query: "sort=-total_cpu_energy_usage_kwh",
+			pages: [][]string{{"1000", "1003", "1002", "1001", "1004"}},
+		},
+		{
+			name:  "limit with sort and fields",
+			query: "limit=3&sort=-uuid&field=uuid",
+			pages: [][]string{{"1004", "1003", "1002"}, {"1001", "1000"}},
+		},
+		{
+			name:  "limit equal to number of units",
+			query: "limit=5",
+			pages: [][]string{{"1000", "1001", "1002", "1003", "1004"}},
+		},
+	}
+
+	for _, test := range tests {
+		pages := fetchPages[models.Unit](
+			t, server.unitsAdmin, "/api/"+base.APIVersion+"/units/admin?from=1728500000&to=1728600000&"+test.query,
+		)
+
+		var got [][]string
+
+		for _, page := range pages {
+			var uuids []string
+			for _, unit := range page {
+				uuids = append(uuids, unit.UUID)
+			}
+
+			got = append(got, uuids)
+		}
+
+		assert.Equal(t, test.pages, got, test.name)
+	}
+}
+
+func TestUnitsPaginationRealValues(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Values that are not exactly representable and differ only after 15
+	// significant digits
+	for _, stmt := range []string{
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) SELECT resource_manager,cluster_id,'1003',name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at FROM units WHERE uuid = '1000'`,
+		`UPDATE units SET avg_cpu_usage = '{"global":0.30000000000000004}' WHERE uuid = '1000'`,
+		`UPDATE units SET avg_cpu_usage = '{"global":0.3}' WHERE uuid = '1001'`,
+		`UPDATE units SET avg_cpu_usage = '{"global":0.3333333333333333}' WHERE uuid = '1002'`,
+		`UPDATE units SET avg_cpu_usage = '{"global":0.33333333333333337}' WHERE uuid = '1003'`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	for _, test := range []struct {
+		query string
+		uuids []string
+	}{
+		{query: "limit=1&sort=avg_cpu_usage", uuids: []string{"1001", "1000", "1002", "1003"}},
+		{query: "limit=1&sort=-avg_cpu_usage", uuids: []string{"1003", "1002", "1000", "1001"}},
+	} {
+		pages := fetchPages[models.Unit](
+			t, server.unitsAdmin, "/api/"+base.APIVersion+"/units/admin?from=1728500000&to=1728600000&"+test.query,
+		)
+
+		var uuids []string
+
+		for _, page := range pages {
+			for _, unit := range page {
+				uuids = append(uuids, unit.UUID)
+			}
+		}
+
+		assert.Equal(t, test.uuids, uuids, test.query)
+	}
+}
+
+func TestUsersProjectsPagination(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Add users
+	for _, stmt := range []string{
+		`INSERT INTO users (uid,cluster_id,resource_manager,name,projects,last_updated_at) VALUES ('','slurm-0','slurm','foousr','["foo"]','2024-10-10T10:00:00')`,
+		`INSERT INTO users (uid,cluster_id,resource_manager,name,projects,last_updated_at) VALUES ('','slurm-0','slurm','barusr','["foo"]','2024-10-10T10:00:00')`,
+		`INSERT INTO users (uid,cluster_id,resource_manager,name,projects,last_updated_at) VALUES ('','slurm-0','slurm','bazusr','["baz"]','2024-10-10T10:00:00')`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	// Users
+	userPages := fetchPages[models.User](t, server.usersAdmin, "/api/"+base.APIVersion+"/users/admin?limit=2&sort=name")
+
+	var users [][]string
+
+	for _, page := range userPages {
+		var names []string
+		for _, user := range page {
+			names = append(names, user.Name)
+		}
+
+		users = append(users, names)
+	}
+
+	assert.Equal(t, [][]string{{"barusr", "bazusr"}, {"foousr"}}, users)
+
+	// Projects
+	projectPages := fetchPages[models.Project](t, server.projectsAdmin, "/api/"+base.APIVersion+"/projects/admin?limit=1&sort=-name")
+
+	var projects [][]string
+
+	for _, page := range projectPages {
+		var names []string
+		for _, project := range page {
+			names = append(names, project.Name)
+		}
+
+		projects = append(projects, names)
+	}
+
+	assert.Equal(t, [][]string{{"foo"}, {"baz"}}, projects)
+}
+
+func TestPaginationErrors(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Make a valid cursor
+	c := cursor{Sort: "uuid", Value: "1000", ID: 1}.encode()
+
+	tests := []struct {
+		name    string
+		query   string
+		handler func(http.ResponseWriter, *http.Request)
+	}{
+		{
+			name:    "invalid units sort key",
+			query:   "sort=foo",
+			handler: server.unitsAdmin,
+		},
+		{
+			name:    "invalid users sort key",
+			query:   "sort=uuid",
+			handler: server.usersAdmin,
+		},
+		{
+			name:    "negative limit",
+			query:   "limit=-1",
+			handler: server.unitsAdmin,
+		},
+		{
+			name:    "limit exceeding maximum",
+			query:   "limit=100000",
+			handler: server.projectsAdmin,
+		},
+		{
+			name:    "malformed cursor",
+			query:   "cursor=foo",
+			handler: server.unitsAdmin,
+		},
+		{
+			name:    "cursor with different sort key",
+			query:   "sort=-uuid&cursor=" + url.QueryEscape(c),
+			handler: server.unitsAdmin,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/resource?"+test.query, nil)
+
+		w := httptest.NewRecorder()
+		test.handler(w, request)
+
+		assert.Equal(t, 400, w.Code, test.name)
+	}
+}
@@ -20,7 +20,8 @@ var queryRegexp = regexp.MustCompile("SELECT (.*?) FROM (.*)")
 // Query builder struct.
 type Query struct {
 	builder strings.Builder
-	params  []string
+	params  []any
+	limit   int // Maximum number of rows returned by query. Zero means no limit
 }
 
 // Add query to builder.
@@ -31,7 +32,10 @@ func (q *Query) query(s string) {
 // Add parameter and its placeholder.
 func (q *Query) param(val []string) {
 	q.builder.WriteString(fmt.Sprintf("(%s)", strings.Join(strings.Split(strings.Repeat("?", len(val)), ""), ",")))
-	q.params = append(q.params, val...)
+
+	for _, v := range val {
+		q.params = append(q.params, v)
+	}
 }
 
 // Add sub query to builder.
@@ -42,7 +46,7 @@ func (q *Query) subQuery(sq Query) {
 }
 
 // Get current query string and its parameters.
-func (q *Query) get() (string, []string) {
+func (q *Query) get() (string, []any) {
 	return q.builder.String(), q.params
 }
 
@@ -71,6 +75,55 @@ func projectsSubQuery(users []string) Query {
 	return qSub
 }
 
+// projectsCondition returns a condition that matches rows where column is one of
+// the projects of users. Projects where users have manager role are matched as well
+// as managers are not necessarily members of the project.
+// (project IN (SELECT name FROM projects WHERE ...) OR (cluster_id, project) IN (SELECT cluster_id, name FROM projects WHERE ...)).
+func projectsCondition(column string, users []string) Query {
+	q := Query{}
+	q.query(column + " IN ")
+	q.subQuery(projectsSubQuery(users))
+
+	// Add projects managed by users
+	if len(users) > 0 {
+		q.query(fmt.Sprintf(" OR (cluster_id, %s) IN ", column))
+		q.subQuery(managedProjectsSubQuery(users))
+	}
+
+	return q
+}
+
+// managedProjectsSubQuery returns a sub query that returns cluster IDs and names
+// of projects where users have manager role. Same project name can exist in
+// different clusters and hence, it must be matched against (cluster_id, project) pairs.
+// SELECT cluster_id, name FROM projects WHERE EXISTS (SELECT 1 FROM json_each(managers) WHERE value = 'usr1').
+func managedProjectsSubQuery(users []string) Query {
+	qSub := Query{}
+	qSub.query("SELECT cluster_id, name FROM " + base.ProjectsDBTableName)
+	qSub.query(" WHERE EXISTS ")
+	qSub.subQuery(managersSubQuery(users))
+
+	return qSub
+}
+
+// membersSubQuery returns a sub query that checks if users are members of project.
+func membersSubQuery(users []string) Query {
+	q := Query{}
+	q.query("SELECT 1 FROM json_each(users) WHERE value IN ")
+	q.param(users)
+
+	return q
+}
+
+// managersSubQuery returns a sub query that checks if users are managers of project.
+func managersSubQuery(users []string) Query {
+	q := Query{}
+	q.query("SELECT 1 FROM json_each(managers) WHERE value IN ")
+	q.param(users)
+
+	return q
+}
+
 // Scan rows
 // We use numRows only for units query as returned number of units can be very big
 // and preallocating can have positive impact on performance
@@ -143,14 +196,8 @@ func countRows(ctx context.Context, dbConn *sql.DB, query Query) (int, error) {
 
 	defer countStmt.Close()
 
-	// queryParams has to be an inteface. Do casting here
-	qParams := make([]interface{}, len(queryParams))
-	for i, v := range queryParams {
-		qParams[i] = v
-	}
-
 	// First make a query to get number of rows that will be returned by query
-	countRows, err := countStmt.QueryContext(ctx, qParams...)
+	countRows, err := countStmt.QueryContext(ctx, queryParams...)
 	if err != nil || countRows.Err() != nil {
 		return 0, err
 	}
@@ -188,6 +235,11 @@ func Querier[T any](ctx context.Context, dbConn *sql.DB, query Query, logger *sl
 
 			return nil, err
 		}
+
+		// Number of rows are capped by LIMIT clause, if present
+		if query.limit > 0 && numRows > query.limit {
+			numRows = query.limit
+		}
 	default:
 		numRows = 0
 	}
@@ -198,23 +250,17 @@ func Querier[T any](ctx context.Context, dbConn *sql.DB, query Query, logger *sl
 	queryStmt, err := dbConn.Prepare(queryString)
 	if err != nil {
 		logger.Error("Failed prepare query statement",
-			"query", queryString, "queryParams", strings.Join(queryParams, ","), "err", err,
+			"query", queryString, "queryParams", fmt.Sprint(queryParams), "err", err,
 		)
 
 		return nil, err
 	}
 	defer queryStmt.Close()
 
-	// queryParams has to be an inteface. Do casting here
-	qParams := make([]interface{}, len(queryParams))
-	for i, v := range queryParams {
-		qParams[i] = v
-	}
-
-	rows, err := queryStmt.QueryContext(ctx, qParams...)
+	rows, err := queryStmt.QueryContext(ctx, queryParams...)
 	if err != nil {
 		logger.Error("Failed to get rows",
-			"query", queryString, "queryParams", strings.Join(queryParams, ","), "err", err,
+			"query", queryString, "queryParams", fmt.Sprint(queryParams), "err", err,
 		)
 
 		return nil, err
@@ -223,8 +269,69 @@ func Querier[T any](ctx context.Context, dbConn *sql.DB, query Query, logger *sl
 
 	// Loop through rows, using Scan to assign column data to struct fields.
 	logger.Debug(
-		"DB query", "query", queryString, "queryParams", strings.Join(queryParams, ","),
+		"DB query", "query", queryString, "queryParams", fmt.Sprint(queryParams),
 	)
 
 	return scanRows[T](rows, numRows)
 }
+
+// Streamer queries the DB and calls handler for each returned row. Unlike Querier,
+// rows are never held in memory and hence, it must be used for queries that
+// can return a large number of rows.
+func Streamer[T any](ctx context.Context, dbConn *sql.DB, query Query, logger *slog.Logger, handler func(T) error) error {
+	// Get query string and params
+	queryString, queryParams := query.get()
+
+	rows, err := dbConn.QueryContext(ctx, queryString, queryParams...)
+	if err != nil {
+		logger.Error("Failed to get rows",
+			"query", queryString, "queryParams", fmt.Sprint(queryParams), "err", err,
+		)
+
+		return err
+	}
+	defer rows.Close()
+
+	logger.Debug(
+		"DB stream query", "query", queryString, "queryParams", fmt.Sprint(queryParams),
+	)
+
+	var value T
+
+	// Get indexes
+	indexes := structset.CachedFieldIndexes(reflect.TypeOf(&value).Elem())
+
+	// Get columns
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("cannot fetch columns: %w", err)
+	}
+
+	scanErrs := 0
+
+	// Scan each row and pass it to handler
+	for rows.Next() {
+		// Reset value as scanning NULLs do not overwrite previous values
+		value = *new(T)
+
+		if err := structset.ScanRow(rows, columns, indexes, &value); err != nil {
+			scanErrs++
+
+			continue
+		}
+
+		if err := handler(value); err != nil {
+			return err
+		}
+	}
+
+	if scanErrs > 0 {
+		err = fmt.Errorf("failed to scan %d rows", scanErrs)
+	}
+
+	if errRows := rows.Err(); errRows != nil {
+		err = errors.Join(err, errRows)
+	}
+
+	return err
+}
@@ -250,6 +250,7 @@ func TestProjectQuerier(t *testing.T) {
 			ResourceManager: "slurm",
 			ClusterID:       "slurm-1",
 			Users:           models.List{"usr1", "usr15", "usr8"},
+			Managers:        models.List{},
 			LastUpdatedAt:   "2024-07-02T14:49:39",
 		},
 	}
@@ -374,7 +375,7 @@ func TestKeysQuerier(t *testing.T) {
 
 func TestQueryBuilder(t *testing.T) {
 	expectedQueryString := "SELECT * FROM table WHERE a IN (?,?) AND b IN (?,?) AND c BETWEEN (?) AND (?)"
-	expectedQueryParams := []string{"a1", "a2", "10", "20", "2023-01-01", "2023-02-01"}
+	expectedQueryParams := []any{"a1", "a2", "10", "20", "2023-01-01", "2023-02-01"}
 
 	// StartedAt query
 	q := Query{}
@@ -398,7 +399,7 @@ func TestQueryBuilder(t *testing.T) {
 
 func TestSubQueryBuilder(t *testing.T) {
 	expectedQueryString := "SELECT * FROM table WHERE a IN (SELECT a FROM table1 WHERE d IN (?,?)) AND b IN (?,?)"
-	expectedQueryParams := []string{"d1", "d2", "10", "20"}
+	expectedQueryParams := []any{"d1", "d2", "10", "20"}
 
 	// Sub query
 	qSub := Query{}
@@ -0,0 +1,792 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/db"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/api/report"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// Supported report formats.
+const (
+	reportHTML = "html"
+	reportPDF  = "pdf"
+)
+
+// Layout of period query parameter of project reports.
+const reportPeriodLayout = "2006-01"
+
+// Maximum number of bars of time series charts in reports of units.
+const reportSeriesBars = 24
+
+// Layout of timestamps of time series charts in reports of units.
+const reportSeriesLayout = "01-02 15:04"
+
+// Content types of report formats.
+var reportContentTypes = map[string]string{
+	reportHTML: "text/html; charset=utf-8",
+	reportPDF:  "application/pdf",
+}
+
+// Notes that are added to reports.
+const (
+	reportNote     = "Energy and emissions are estimated from the metrics collected by CEEMS exporters."
+	reportCostNote = "Costs are estimated from the rates configured on CEEMS API server."
+)
+
+// ReportsConfig contains the configuration of usage reports.
+type ReportsConfig struct {
+	Cost   CostConfig           `yaml:"cost"`
+	Series []ReportSeriesConfig `yaml:"series"`
+}
+
+// ReportSeriesConfig contains the query of a time series of compute units that
+// is presented in reports of units. Query is a template where `{{.UUID}}` is
+// replaced by UUID of the unit.
+type ReportSeriesConfig struct {
+	Title string `yaml:"title"`
+	Unit  string `yaml:"unit"`
+	Query string `yaml:"query"`
+}
+
+// DefaultReportSeries are the time series presented in reports of units when
+// none are configured. They use the recording rules generated by ceems_tool.
+var DefaultReportSeries = []ReportSeriesConfig{
+	{Title: "CPU usage", Unit: "%", Query: `avg(uuid:ceems_cpu_usage:ratio_irate{uuid="{{.UUID}}"})`},
+	{Title: "CPU memory usage", Unit: "%", Query: `avg(uuid:ceems_cpu_memory_usage:ratio{uuid="{{.UUID}}"})`},
+	{Title: "GPU usage", Unit: "%", Query: `avg(uuid:ceems_gpu_usage:ratio{uuid="{{.UUID}}"})`},
+	{Title: "Power usage", Unit: "W", Query: `sum(uuid:ceems_host_power_watts:pue{uuid="{{.UUID}}"}) + (sum(uuid:ceems_gpu_power_watts:pue{uuid="{{.UUID}}"}) or vector(0))`},
+}
+
+// CostConfig contains the rates used to estimate cost of usage in reports.
+type CostConfig struct {
+	Currency  string  `yaml:"currency"`
+	CPUHour   float64 `yaml:"cpu_hour"`
+	GPUHour   float64 `yaml:"gpu_hour"`
+	EnergyKWh float64 `yaml:"energy_kwh"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CostConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = CostConfig{
+		Currency: "EUR",
+	}
+
+	type plain CostConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *ReportsConfig) Validate() error {
+	if c.Cost.CPUHour < 0 || c.Cost.GPUHour < 0 || c.Cost.EnergyKWh < 0 {
+		return errors.New("cost rates of reports must not be negative")
+	}
+
+	for _, series := range c.Series {
+		if series.Title == "" || series.Query == "" {
+			return errors.New("title and query of series of reports must not be empty")
+		}
+
+		if _, err := template.New(series.Title).Parse(series.Query); err != nil {
+			return fmt.Errorf("invalid query of series %s of reports: %w", series.Title, err)
+		}
+	}
+
+	return nil
+}
+
+// series returns the time series of reports of units. Default series are used
+// when none are configured and an empty list disables them.
+func (c *ReportsConfig) series() []ReportSeriesConfig {
+	if c.Series == nil {
+		return DefaultReportSeries
+	}
+
+	return c.Series
+}
+
+// enabled returns true when atleast one rate is configured.
+func (c *CostConfig) enabled() bool {
+	return c.CPUHour > 0 || c.GPUHour > 0 || c.EnergyKWh > 0
+}
+
+// reportMetrics are the metrics of units or usage that are presented in reports.
+type reportMetrics struct {
+	TotalTime           models.MetricMap
+	AveCPUUsage         models.MetricMap
+	AveCPUMemUsage      models.MetricMap
+	AveGPUUsage         models.MetricMap
+	AveGPUMemUsage      models.MetricMap
+	TotalCPUEnergyUsage models.MetricMap
+	TotalGPUEnergyUsage models.MetricMap
+	TotalCPUEmissions   models.MetricMap
+	TotalGPUEmissions   models.MetricMap
+}
+
+// unitReportMetrics returns report metrics of unit.
+func unitReportMetrics(u models.Unit) reportMetrics {
+	return reportMetrics{
+		TotalTime:           u.TotalTime,
+		AveCPUUsage:         u.AveCPUUsage,
+		AveCPUMemUsage:      u.AveCPUMemUsage,
+		AveGPUUsage:         u.AveGPUUsage,
+		AveGPUMemUsage:      u.AveGPUMemUsage,
+		TotalCPUEnergyUsage: u.TotalCPUEnergyUsage,
+		TotalGPUEnergyUsage: u.TotalGPUEnergyUsage,
+		TotalCPUEmissions:   u.TotalCPUEmissions,
+		TotalGPUEmissions:   u.TotalGPUEmissions,
+	}
+}
+
+// aggregateUsage returns report metrics by summing totals and by averaging usage
+// weighted by allocated times of usage rows.
+func aggregateUsage(usage []models.Usage) reportMetrics {
+	m := reportMetrics{
+		TotalTime:           models.MetricMap{},
+		AveCPUUsage:         models.MetricMap{},
+		AveCPUMemUsage:      models.MetricMap{},
+		AveGPUUsage:         models.MetricMap{},
+		AveGPUMemUsage:      models.MetricMap{},
+		TotalCPUEnergyUsage: models.MetricMap{},
+		TotalGPUEnergyUsage: models.MetricMap{},
+		TotalCPUEmissions:   models.MetricMap{},
+		TotalGPUEmissions:   models.MetricMap{},
+	}
+
+	sum := func(total models.MetricMap, value models.MetricMap) {
+		for k, v := range value {
+			total[k] += v
+		}
+	}
+
+	// Sum of weights of each key of averages
+	weights := make(map[string]models.MetricMap)
+
+	average := func(name string, avg models.MetricMap, value models.MetricMap, weight models.JSONFloat) {
+		if weight <= 0 {
+			return
+		}
+
+		if _, ok := weights[name]; !ok {
+			weights[name] = models.MetricMap{}
+		}
+
+		for k, v := range value {
+			avg[k] += v * weight
+			weights[name][k] += weight
+		}
+	}
+
+	for _, u := range usage {
+		sum(m.TotalTime, u.TotalTime)
+		sum(m.TotalCPUEnergyUsage, u.TotalCPUEnergyUsage)
+		sum(m.TotalGPUEnergyUsage, u.TotalGPUEnergyUsage)
+		sum(m.TotalCPUEmissions, u.TotalCPUEmissions)
+		sum(m.TotalGPUEmissions, u.TotalGPUEmissions)
+
+		average("avg_cpu_usage", m.AveCPUUsage, u.AveCPUUsage, u.TotalTime[db.Weights["avg_cpu_usage"]])
+		average("avg_cpu_mem_usage", m.AveCPUMemUsage, u.AveCPUMemUsage, u.TotalTime[db.Weights["avg_cpu_mem_usage"]])
+		average("avg_gpu_usage", m.AveGPUUsage, u.AveGPUUsage, u.TotalTime[db.Weights["avg_gpu_usage"]])
+		average("avg_gpu_mem_usage", m.AveGPUMemUsage, u.AveGPUMemUsage, u.TotalTime[db.Weights["avg_gpu_mem_usage"]])
+	}
+
+	for name, avg := range map[string]models.MetricMap{
+		"avg_cpu_usage":     m.AveCPUUsage,
+		"avg_cpu_mem_usage": m.AveCPUMemUsage,
+		"avg_gpu_usage":     m.AveGPUUsage,
+		"avg_gpu_mem_usage": m.AveGPUMemUsage,
+	} {
+		for k := range avg {
+			avg[k] /= weights[name][k]
+		}
+	}
+
+	return m
+}
+
+// hours returns the time of key in hours.
+func (m reportMetrics) hours(key string) float64 {
+	return float64(m.TotalTime[key]) / 3600
+}
+
+// energy returns total CPU and GPU energy in kWh.
+func (m reportMetrics) energy() (float64, float64) {
+	return float64(m.TotalCPUEnergyUsage["total"]), float64(m.TotalGPUEnergyUsage["total"])
+}
+
+// cost returns estimated cost using rates of c.
+func (m reportMetrics) cost(c CostConfig) float64 {
+	cpuEnergy, gpuEnergy := m.energy()
+
+	return m.hours("alloc_cputime")*c.CPUHour + m.hours("alloc_gputime")*c.GPUHour + (cpuEnergy+gpuEnergy)*c.EnergyKWh
+}
+
+// sections returns report sections of efficiency, energy, emissions and cost.
+func (m reportMetrics) sections(c CostConfig) []report.Section {
+	var sections []report.Section
+
+	// Efficiency
+	efficiency := report.Section{Title: "Efficiency", Chart: &report.Chart{Title: "Average usage", Unit: "%"}}
+
+	for _, metric := range []struct {
+		label string
+		value models.MetricMap
+	}{
+		{"CPU usage", m.AveCPUUsage},
+		{"CPU memory usage", m.AveCPUMemUsage},
+		{"GPU usage", m.AveGPUUsage},
+		{"GPU memory usage", m.AveGPUMemUsage},
+	} {
+		if v, ok := metric.value["global"]; ok {
+			efficiency.Fields = append(efficiency.Fields, report.Field{Label: "Average " + metric.label, Value: report.FormatValue(float64(v)) + " %"})
+			efficiency.Chart.Bars = append(efficiency.Chart.Bars, report.Bar{Label: metric.label, Value: float64(v)})
+		}
+	}
+
+	if len(efficiency.Fields) > 0 {
+		sections = append(sections, efficiency)
+	}
+
+	// Energy
+	cpuEnergy, gpuEnergy := m.energy()
+	sections = append(sections, report.Section{
+		Title: "Energy",
+		Fields: []report.Field{
+			{Label: "CPU energy", Value: report.FormatValue(cpuEnergy) + " kWh"},
+			{Label: "GPU energy", Value: report.FormatValue(gpuEnergy) + " kWh"},
+			{Label: "Total energy", Value: report.FormatValue(cpuEnergy+gpuEnergy) + " kWh"},
+		},
+		Chart: &report.Chart{
+			Title: "Energy usage",
+			Unit:  "kWh",
+			Bars:  []report.Bar{{Label: "CPU", Value: cpuEnergy}, {Label: "GPU", Value: gpuEnergy}},
+		},
+	})
+
+	// Emissions by provider
+	providers := slices.Sorted(maps.Keys(m.TotalCPUEmissions))
+	for _, provider := range slices.Sorted(maps.Keys(m.TotalGPUEmissions)) {
+		if !slices.Contains(providers, provider) {
+			providers = append(providers, provider)
+		}
+	}
+
+	if len(providers) > 0 {
+		emissions := report.Section{
+			Title: "Emissions",
+			Table: &report.Table{Header: []string{"Provider", "CPU (gCO2e)", "GPU (gCO2e)", "Total (gCO2e)"}},
+			Chart: &report.Chart{Title: "Emissions by provider", Unit: "gCO2e"},
+		}
+
+		for _, provider := range providers {
+			cpu, gpu := float64(m.TotalCPUEmissions[provider]), float64(m.TotalGPUEmissions[provider])
+			name := strings.TrimSuffix(provider, "_total")
+
+			emissions.Table.Rows = append(emissions.Table.Rows, []string{
+				name, report.FormatValue(cpu), report.FormatValue(gpu), report.FormatValue(cpu + gpu),
+			})
+			emissions.Chart.Bars = append(emissions.Chart.Bars, report.Bar{Label: name, Value: cpu + gpu})
+		}
+
+		sections = append(sections, emissions)
+	}
+
+	// Cost
+	if c.enabled() {
+		cpuHours, gpuHours := m.hours("alloc_cputime"), m.hours("alloc_gputime")
+		sections = append(sections, report.Section{
+			Title: "Cost",
+			Table: &report.Table{
+				Header: []string{"Item", "Quantity", "Rate (" + c.Currency + ")", "Cost (" + c.Currency + ")"},
+				Rows: [][]string{
+					{"CPU hours", report.FormatValue(cpuHours), report.FormatValue(c.CPUHour), report.FormatValue(cpuHours * c.CPUHour)},
+					{"GPU hours", report.FormatValue(gpuHours), report.FormatValue(c.GPUHour), report.FormatValue(gpuHours * c.GPUHour)},
+					{"Energy (kWh)", report.FormatValue(cpuEnergy + gpuEnergy), report.FormatValue(c.EnergyKWh), report.FormatValue((cpuEnergy + gpuEnergy) * c.EnergyKWh)},
+				},
+			},
+			Fields: []report.Field{{Label: "Total cost", Value: report.FormatValue(m.cost(c)) + " " + c.Currency}},
+		})
+	}
+
+	return sections
+}
+
+// unitSeriesSections returns report sections of time series of unit fetched
+// from TSDB of its cluster. Series that cannot be fetched are skipped.
+func (s *CEEMSServer) unitSeriesSections(ctx context.Context, unit models.Unit, loc *time.Location) []report.Section {
+	client, ok := s.tsdbs[unit.ClusterID]
+	if !ok || unit.StartedAtTS <= 0 || unit.EndedAtTS <= unit.StartedAtTS {
+		return nil
+	}
+
+	start, end := time.UnixMilli(unit.StartedAtTS), time.UnixMilli(unit.EndedAtTS)
+
+	// Step is rounded up to seconds so that there are at most reportSeriesBars
+	// bars in each chart
+	step := (end.Sub(start)/reportSeriesBars + time.Second).Truncate(time.Second)
+
+	// Escape UUID as it is used in a label matcher
+	data := map[string]string{"UUID": strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(unit.UUID)}
+
+	var sections []report.Section
+
+	for _, series := range s.reports.series() {
+		query := &strings.Builder{}
+		if err := template.Must(template.New(series.Title).Parse(series.Query)).Execute(query, data); err != nil {
+			s.logger.Error("Failed to build query of report series", "series", series.Title, "err", err)
+
+			continue
+		}
+
+		result, _, err := client.API.QueryRange(ctx, query.String(), v1.Range{Start: start, End: end, Step: step})
+		if err != nil {
+			s.logger.Error("Failed to fetch report series", "series", series.Title, "uuid", unit.UUID, "err", err)
+
+			continue
+		}
+
+		// Units without the series, like units without GPUs, do not have them
+		// in the report
+		matrix, ok := result.(model.Matrix)
+		if !ok || len(matrix) == 0 || len(matrix[0].Values) == 0 {
+			continue
+		}
+
+		chart := &report.Chart{Title: series.Title + " over time", Unit: series.Unit}
+		for _, sample := range matrix[0].Values {
+			chart.Bars = append(chart.Bars, report.Bar{
+				Label: sample.Timestamp.Time().In(loc).Format(reportSeriesLayout),
+				Value: float64(sample.Value),
+			})
+		}
+
+		sections = append(sections, report.Section{Title: series.Title, Chart: chart})
+	}
+
+	return sections
+}
+
+// reportNotes returns notes of reports.
+func (s *CEEMSServer) reportNotes() []string {
+	if s.reports.Cost.enabled() {
+		return []string{reportNote, reportCostNote}
+	}
+
+	return []string{reportNote}
+}
+
+// reportFormat returns the format of report based on `format` query parameter
+// and `Accept` header. Query parameter takes precedence over header.
+func reportFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := reportContentTypes[format]; !ok {
+			return "", fmt.Errorf("%w: %s", errUnsupportedFormat, format)
+		}
+
+		return format, nil
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept)); err == nil && mediaType == "application/pdf" {
+			return reportPDF, nil
+		}
+	}
+
+	return reportHTML, nil
+}
+
+// writeReport renders report in requested format and writes response.
+func (s *CEEMSServer) writeReport(w http.ResponseWriter, format string, filename string, rep *report.Report) {
+	// Render into a buffer so that rendering errors can still be returned
+	// as error responses
+	buf := &bytes.Buffer{}
+
+	var err error
+
+	if format == reportPDF {
+		err = report.PDF(buf, rep)
+	} else {
+		err = report.HTML(buf, rep)
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to render report", "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", reportContentTypes[format])
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if format == reportPDF {
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, filename))
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := buf.WriteTo(w); err != nil {
+		s.logger.Error("Failed to write report", "err", err)
+	}
+}
+
+// unitReport         godoc
+//
+//	@Summary		Report of a compute unit
+//	@Description	This endpoint returns a report of a finished compute unit that summarises
+//	@Description	its runtime, efficiency, energy usage, emissions by provider and cost. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in the request.
+//	@Description
+//	@Description	Regular users can get reports of their own compute units and of compute units
+//	@Description	of the projects they manage whereas admin users can get report of any compute unit.
+//	@Description	When the same `uuid` exists in multiple clusters, `cluster_id` query parameter
+//	@Description	must be used.
+//	@Description
+//	@Description	The report is rendered as HTML by default. Use `format=pdf` query parameter or
+//	@Description	`Accept: application/pdf` header to get a PDF document. If query parameter
+//	@Description	`timezone` is provided, times will be presented in that time zone.
+//	@Security		BasicAuth
+//	@Tags			reports
+//	@Produce		html,application/pdf
+//	@Param			X-Grafana-User	header		string	true	"Current user name"
+//	@Param			uuid			path		string	true	"Unit UUID"
+//	@Param			cluster_id		query		string	false	"Cluster ID"
+//	@Param			timezone		query		string	false	"Time zone in IANA format"
+//	@Param			format			query		string	false	"Report format"	Enums(html, pdf)
+//	@Success		200				{string}	string
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/reports/units/{uuid} [get]
+//
+// GET /reports/units/{uuid}
+// Get report of a compute unit.
+func (s *CEEMSServer) unitReport(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "unit report endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	format, err := reportFormat(r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
+	uuid := mux.Vars(r)["uuid"]
+
+	// Get unit. Regular users can only get their own units and units of the
+	// projects they manage. Annotations are not included in reports
+	fields := slices.DeleteFunc(slices.Clone(base.UnitsDBTableColNames), func(f string) bool { return f == "annotations" })
+
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s WHERE uuid IN ", strings.Join(fields, ","), base.UnitsDBTableName))
+	q.param([]string{uuid})
+
+	if r.Header.Get(base.AdminUserHeader) == "" {
+		q.query(" AND (username IN ")
+		q.param([]string{loggedUser})
+		q.query(" OR (cluster_id, project) IN ")
+		q.subQuery(managedProjectsSubQuery([]string{loggedUser}))
+		q.query(")")
+	}
+
+	if clusterIDs := r.URL.Query()["cluster_id"]; len(clusterIDs) > 0 {
+		q.query(" AND cluster_id IN ")
+		q.param(clusterIDs)
+	}
+
+	units, err := s.queriers.unit(r.Context(), s.db, q, s.logger)
+	if err != nil {
+		s.logger.Error("Failed to fetch unit", "logged_user", loggedUser, "uuid", uuid, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	switch {
+	case len(units) == 0:
+		errorResponse[any](w, &apiError{errorNotFound, errUnitNotFound}, s.logger, nil)
+
+		return
+	case len(units) > 1:
+		errorResponse[any](w, &apiError{errorBadData, errAmbiguousUnit}, s.logger, nil)
+
+		return
+	case units[0].EndedAtTS == 0:
+		errorResponse[any](w, &apiError{errorBadData, errUnitNotFinished}, s.logger, nil)
+
+		return
+	}
+
+	tz := r.URL.Query().Get("timezone")
+	unit := s.inTargetTimeLocation(tz, units)[0]
+
+	// Allocation in a stable order
+	allocation := make([]string, 0, len(unit.Allocation))
+	for _, k := range slices.Sorted(maps.Keys(unit.Allocation)) {
+		allocation = append(allocation, fmt.Sprintf("%s: %v", k, unit.Allocation[k]))
+	}
+
+	metrics := unitReportMetrics(unit)
+	rep := &report.Report{
+		Title:       "Report of compute unit " + unit.UUID,
+		Subtitle:    fmt.Sprintf("%s on cluster %s", unit.Name, unit.ClusterID),
+		GeneratedAt: time.Now().In(s.timeLocation(tz)).Format(base.DatetimezoneLayout),
+		Summary: []report.Field{
+			{Label: "UUID", Value: unit.UUID},
+			{Label: "Name", Value: unit.Name},
+			{Label: "Cluster", Value: unit.ClusterID},
+			{Label: "Project", Value: unit.Project},
+			{Label: "User", Value: unit.User},
+			{Label: "State", Value: unit.State},
+			{Label: "Started at", Value: unit.StartedAt},
+			{Label: "Ended at", Value: unit.EndedAt},
+			{Label: "Elapsed", Value: unit.Elapsed},
+			{Label: "Allocation", Value: strings.Join(allocation, ", ")},
+			{Label: "CPU hours", Value: report.FormatValue(metrics.hours("alloc_cputime"))},
+			{Label: "GPU hours", Value: report.FormatValue(metrics.hours("alloc_gputime"))},
+		},
+		Sections: append(metrics.sections(s.reports.Cost), s.unitSeriesSections(r.Context(), unit, s.timeLocation(tz))...),
+		Notes:    s.reportNotes(),
+	}
+
+	s.writeReport(w, format, fmt.Sprintf("%s-%s", unit.ClusterID, unit.UUID), rep)
+}
+
+// projectReport         godoc
+//
+//	@Summary		Monthly report of a project
+//	@Description	This endpoint returns a report of usage of a project during a month that
+//	@Description	summarises number of compute units, efficiency, energy usage, emissions by
+//	@Description	provider and cost along with the usage of each user and daily energy usage.
+//	@Description	The current user is always identified by the header `X-Grafana-User` in the request.
+//	@Description
+//	@Description	Regular users can get reports of the projects they are part of or they manage
+//	@Description	whereas admin users can get report of any project.
+//	@Description
+//	@Description	The month must be provided using `period` query parameter of format `YYYY-MM`.
+//	@Description	By default, report of the previous month is returned. Months are aligned to
+//	@Description	the time zone of the DB.
+//	@Description
+//	@Description	The report is rendered as HTML by default. Use `format=pdf` query parameter or
+//	@Description	`Accept: application/pdf` header to get a PDF document.
+//	@Security		BasicAuth
+//	@Tags			reports
+//	@Produce		html,application/pdf
+//	@Param			X-Grafana-User	header		string		true	"Current user name"
+//	@Param			name			path		string		true	"Project name"
+//	@Param			cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param			period			query		string		false	"Month of format YYYY-MM"
+//	@Param			format			query		string		false	"Report format"	Enums(html, pdf)
+//	@Success		200				{string}	string
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/reports/projects/{name} [get]
+//
+// GET /reports/projects/{name}
+// Get monthly report of a project.
+func (s *CEEMSServer) projectReport(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "project report endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	format, err := reportFormat(r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	loc := s.dbConfig.Data.Timezone.Location
+	now := time.Now().In(loc)
+
+	// Get period of report. Use previous month by default
+	start := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, loc)
+	if period := r.URL.Query().Get("period"); period != "" {
+		if start, err = time.ParseInLocation(reportPeriodLayout, period, loc); err != nil {
+			errorResponse[any](w, &apiError{errorBadData, errInvalidPeriod}, s.logger, nil)
+
+			return
+		}
+	}
+
+	end := start.AddDate(0, 1, 0)
+
+	// Get project. Regular users can only get their own projects and the
+	// projects they manage. Membership is checked on the same row so that
+	// project with the same name on other clusters is not included
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s WHERE name IN ", strings.Join(base.ProjectsDBTableColNames, ","), base.ProjectsDBTableName))
+	q.param([]string{name})
+
+	if r.Header.Get(base.AdminUserHeader) == "" {
+		q.query(" AND (EXISTS ")
+		q.subQuery(membersSubQuery([]string{loggedUser}))
+		q.query(" OR EXISTS ")
+		q.subQuery(managersSubQuery([]string{loggedUser}))
+		q.query(")")
+	}
+
+	if clusterIDs := r.URL.Query()["cluster_id"]; len(clusterIDs) > 0 {
+		q.query(" AND cluster_id IN ")
+		q.param(clusterIDs)
+	}
+
+	projects, err := s.queriers.project(r.Context(), s.db, q, s.logger)
+	if err != nil {
+		s.logger.Error("Failed to fetch project", "logged_user", loggedUser, "project", name, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	if len(projects) == 0 {
+		errorResponse[any](w, &apiError{errorNotFound, errProjectNotFound}, s.logger, nil)
+
+		return
+	}
+
+	clusterIDs := make([]string, len(projects))
+	for i, project := range projects {
+		clusterIDs[i] = project.ClusterID
+	}
+
+	// Get daily usage of project in the period
+	q = Query{}
+	q.query(fmt.Sprintf(
+		"SELECT cluster_id,username,num_units,last_updated_at,total_time_seconds,avg_cpu_usage,avg_cpu_mem_usage,avg_gpu_usage,avg_gpu_mem_usage,total_cpu_energy_usage_kwh,total_gpu_energy_usage_kwh,total_cpu_emissions_gms,total_gpu_emissions_gms FROM %s",
+		base.DailyUsageDBTableName,
+	))
+	q.query(" WHERE project IN ")
+	q.param([]string{name})
+	q.query(" AND cluster_id IN ")
+	q.param(clusterIDs)
+	q.query(" AND last_updated_at >= ")
+	q.param([]string{start.Format(base.DatetimeLayout)})
+	q.query(" AND last_updated_at < ")
+	q.param([]string{end.Format(base.DatetimeLayout)})
+
+	usage, err := s.queriers.usage(r.Context(), s.db, q, s.logger)
+	if usage == nil && err != nil {
+		s.logger.Error("Failed to fetch daily usage of project", "logged_user", loggedUser, "project", name, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Group usage by user and by day
+	var numUnits int64
+
+	byUser := make(map[string][]models.Usage)
+	byDay := make(map[string][]models.Usage)
+
+	for _, u := range usage {
+		numUnits += u.NumUnits
+		byUser[u.User] = append(byUser[u.User], u)
+
+		// Daily usage rows are keyed by midnight of each day
+		day, _, _ := strings.Cut(u.LastUpdatedAt, "T")
+		byDay[day] = append(byDay[day], u)
+	}
+
+	metrics := aggregateUsage(usage)
+	cost := s.reports.Cost
+
+	// Usage of each user
+	users := report.Section{
+		Title: "Users",
+		Table: &report.Table{Header: []string{"User", "Units", "CPU hours", "GPU hours", "Energy (kWh)"}},
+	}
+
+	if cost.enabled() {
+		users.Table.Header = append(users.Table.Header, "Cost ("+cost.Currency+")")
+	}
+
+	for _, user := range slices.Sorted(maps.Keys(byUser)) {
+		var units int64
+		for _, u := range byUser[user] {
+			units += u.NumUnits
+		}
+
+		m := aggregateUsage(byUser[user])
+		cpuEnergy, gpuEnergy := m.energy()
+		row := []string{
+			user,
+			fmt.Sprintf("%d", units),
+			report.FormatValue(m.hours("alloc_cputime")),
+			report.FormatValue(m.hours("alloc_gputime")),
+			report.FormatValue(cpuEnergy + gpuEnergy),
+		}
+
+		if cost.enabled() {
+			row = append(row, report.FormatValue(m.cost(cost)))
+		}
+
+		users.Table.Rows = append(users.Table.Rows, row)
+	}
+
+	// Daily energy usage until end of period or today
+	daily := report.Section{
+		Title: "Daily usage",
+		Chart: &report.Chart{Title: "Daily energy usage", Unit: "kWh"},
+	}
+
+	for day := start; day.Before(end) && !day.After(now); day = day.AddDate(0, 0, 1) {
+		cpuEnergy, gpuEnergy := aggregateUsage(byDay[day.Format(time.DateOnly)]).energy()
+		daily.Chart.Bars = append(daily.Chart.Bars, report.Bar{Label: day.Format(time.DateOnly), Value: cpuEnergy + gpuEnergy})
+	}
+
+	rep := &report.Report{
+		Title:       "Report of project " + name,
+		Subtitle:    fmt.Sprintf("Usage from %s to %s", start.Format(time.DateOnly), end.AddDate(0, 0, -1).Format(time.DateOnly)),
+		GeneratedAt: now.Format(base.DatetimezoneLayout),
+		Summary: []report.Field{
+			{Label: "Project", Value: name},
+			{Label: "Clusters", Value: strings.Join(clusterIDs, ", ")},
+			{Label: "Period", Value: start.Format(reportPeriodLayout)},
+			{Label: "Compute units", Value: fmt.Sprintf("%d", numUnits)},
+			{Label: "Users", Value: fmt.Sprintf("%d", len(byUser))},
+			{Label: "Walltime hours", Value: report.FormatValue(metrics.hours("walltime"))},
+			{Label: "CPU hours", Value: report.FormatValue(metrics.hours("alloc_cputime"))},
+			{Label: "GPU hours", Value: report.FormatValue(metrics.hours("alloc_gputime"))},
+		},
+		Sections: append(metrics.sections(cost), users, daily),
+		Notes:    s.reportNotes(),
+	}
+
+	if err != nil {
+		rep.Notes = append(rep.Notes, "Usage of some days could not be fetched: "+err.Error())
+	}
+
+	s.writeReport(w, format, fmt.Sprintf("%s-%s", name, start.Format(reportPeriodLayout)), rep)
+}
@@ -0,0 +1,310 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportFormat(t *testing.T) {
+	tests := []struct {
+		url    string
+		accept string
+		format string
+		err    bool
+	}{
+		{url: "/", format: reportHTML},
+		{url: "/", accept: "text/html,application/xhtml+xml", format: reportHTML},
+		{url: "/", accept: "application/pdf", format: reportPDF},
+		{url: "/?format=pdf", accept: "text/html", format: reportPDF},
+		{url: "/?format=html", format: reportHTML},
+		{url: "/?format=csv", err: true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.url, nil)
+		req.Header.Set("Accept", test.accept)
+
+		format, err := reportFormat(req)
+		if test.err {
+			require.Error(t, err, test.url)
+		} else {
+			require.NoError(t, err, test.url)
+			assert.Equal(t, test.format, format, test.url)
+		}
+	}
+}
+
+func TestReportsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ReportsConfig
+		wantErr bool
+	}{
+		{name: "default", config: ReportsConfig{}},
+		{name: "default series", config: ReportsConfig{Series: DefaultReportSeries}},
+		{name: "negative rate", config: ReportsConfig{Cost: CostConfig{CPUHour: -1}}, wantErr: true},
+		{name: "series without query", config: ReportsConfig{Series: []ReportSeriesConfig{{Title: "foo"}}}, wantErr: true},
+		{name: "series with invalid query", config: ReportsConfig{Series: []ReportSeriesConfig{{Title: "foo", Query: "foo{{.UUID"}}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		if test.wantErr {
+			assert.Error(t, test.config.Validate(), test.name)
+		} else {
+			assert.NoError(t, test.config.Validate(), test.name)
+		}
+	}
+}
+
+func TestAggregateUsage(t *testing.T) {
+	m := aggregateUsage([]models.Usage{
+		{
+			TotalTime:           models.MetricMap{"alloc_cputime": 3600, "alloc_gputime": 0},
+			AveCPUUsage:         models.MetricMap{"global": 20},
+			TotalCPUEnergyUsage: models.MetricMap{"total": 1},
+			TotalCPUEmissions:   models.MetricMap{"owid_total": 10},
+		},
+		{
+			TotalTime:           models.MetricMap{"alloc_cputime": 10800, "alloc_gputime": 7200},
+			AveCPUUsage:         models.MetricMap{"global": 60},
+			AveGPUUsage:         models.MetricMap{"global": 50},
+			TotalCPUEnergyUsage: models.MetricMap{"total": 2},
+			TotalGPUEnergyUsage: models.MetricMap{"total": 3},
+			TotalCPUEmissions:   models.MetricMap{"owid_total": 20},
+			TotalGPUEmissions:   models.MetricMap{"owid_total": 30, "emaps_total": 25},
+		},
+	})
+
+	assert.InDelta(t, 4.0, m.hours("alloc_cputime"), 1e-9)
+	assert.InDelta(t, 2.0, m.hours("alloc_gputime"), 1e-9)
+	assert.InDelta(t, 50.0, float64(m.AveCPUUsage["global"]), 1e-9)
+	assert.InDelta(t, 50.0, float64(m.AveGPUUsage["global"]), 1e-9)
+	assert.Equal(t, models.MetricMap{"owid_total": 30}, m.TotalCPUEmissions)
+	assert.Equal(t, models.MetricMap{"owid_total": 30, "emaps_total": 25}, m.TotalGPUEmissions)
+
+	cpuEnergy, gpuEnergy := m.energy()
+	assert.InDelta(t, 3.0, cpuEnergy, 1e-9)
+	assert.InDelta(t, 3.0, gpuEnergy, 1e-9)
+
+	// 4 CPU hours, 2 GPU hours and 6 kWh
+	assert.InDelta(t, 4*0.1+2*2+6*0.5, m.cost(CostConfig{CPUHour: 0.1, GPUHour: 2, EnergyKWh: 0.5}), 1e-9)
+}
+
+// reportRequest makes a report request and returns response.
+func reportRequest(handler http.HandlerFunc, url string, vars map[string]string, user string, admin bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set(base.LoggedUserHeader, user)
+
+	if admin {
+		req.Header.Set(base.AdminUserHeader, user)
+	}
+
+	req = mux.SetURLVars(req, vars)
+
+	w := httptest.NewRecorder()
+	handler(w, req)
+
+	return w
+}
+
+func TestUnitReport(t *testing.T) {
+	server := setupServerWithDB(t)
+	server.reports = ReportsConfig{Cost: CostConfig{Currency: "EUR", CPUHour: 0.01, GPUHour: 1}}
+
+	// Add metrics to unit 1000 and mark unit 1002 as running
+	for _, stmt := range []string{
+		`UPDATE units SET total_time_seconds = '{"walltime":3600,"alloc_cputime":14400,"alloc_gputime":3600}', allocation = '{"cpus":4,"gpus":1}', avg_cpu_usage = '{"global":45.5}', avg_gpu_usage = '{"global":12}', total_cpu_energy_usage_kwh = '{"total":0.4}', total_gpu_energy_usage_kwh = '{"total":0.3}', total_cpu_emissions_gms = '{"owid_total":20,"emaps_total":15}', total_gpu_emissions_gms = '{"owid_total":12,"emaps_total":9}' WHERE uuid = '1000'`,
+		`UPDATE units SET ended_at_ts = 0, state = 'RUNNING' WHERE uuid = '1002'`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	// HTML report of own unit
+	w := reportRequest(server.unitReport, "/api/v1/reports/units/1000", map[string]string{"uuid": "1000"}, "foousr", false)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>Report of compute unit 1000</title>")
+	assert.Contains(t, body, "<dt>Allocation</dt><dd>cpus: 4, gpus: 1</dd>")
+	assert.Contains(t, body, "<dt>Average CPU usage</dt><dd>45.50 %</dd>")
+	assert.Contains(t, body, "<dt>Total energy</dt><dd>0.7 kWh</dd>")
+	assert.Contains(t, body, "<tr><td>emaps</td><td>15.00</td><td>9.00</td><td>24.00</td></tr>")
+	assert.Contains(t, body, "<tr><td>owid</td><td>20.00</td><td>12.00</td><td>32.00</td></tr>")
+	assert.Contains(t, body, "<dt>Total cost</dt><dd>1.04 EUR</dd>")
+
+	// PDF report
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000?format=pdf", map[string]string{"uuid": "1000"}, "foousr", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="slurm-0-1000.pdf"`, w.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+
+	// Units of other users are not found
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000", map[string]string{"uuid": "1000"}, "bazusr", false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Admins can get report of any unit
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000?cluster_id=slurm-0", map[string]string{"uuid": "1000"}, "adm1", true)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Running units do not have reports
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1002", map[string]string{"uuid": "1002"}, "bazusr", false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Unsupported format
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000?format=csv", map[string]string{"uuid": "1000"}, "foousr", false)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestUnitReportSeries(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	var queries atomic.Int64
+
+	tsdbServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		queries.Add(1)
+
+		// Unit 1000 ran for an hour and hence, step must give at most 24 bars
+		assert.Equal(t, "151", r.FormValue("step"))
+		assert.Contains(t, r.FormValue("query"), `uuid="1000"`)
+
+		// Only CPU usage series exists
+		if !strings.Contains(r.FormValue("query"), "ceems_cpu_usage") {
+			w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[]}}`))
+
+			return
+		}
+
+		w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1728550800,"25"],[1728550951,"75"]]}]}}`))
+	}))
+	defer tsdbServer.Close()
+
+	client, err := tsdb.New(tsdbServer.URL, config.DefaultHTTPClientConfig, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	server.tsdbs = map[string]*tsdb.Client{"slurm-0": client}
+
+	w := reportRequest(server.unitReport, "/api/v1/reports/units/1000?timezone=UTC", map[string]string{"uuid": "1000"}, "foousr", false)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, int64(len(DefaultReportSeries)), queries.Load())
+
+	body := w.Body.String()
+	assert.Contains(t, body, "CPU usage over time")
+	assert.Contains(t, body, ">10-10 09:00</text>")
+	assert.Contains(t, body, ">10-10 09:02</text>")
+	assert.NotContains(t, body, "GPU usage over time")
+
+	// Series are not fetched when they are disabled
+	server.reports.Series = []ReportSeriesConfig{}
+
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000", map[string]string{"uuid": "1000"}, "foousr", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(len(DefaultReportSeries)), queries.Load())
+	assert.NotContains(t, w.Body.String(), "over time")
+
+	// Report is served even when TSDB fails
+	tsdbServer.Close()
+
+	server.reports.Series = nil
+
+	w = reportRequest(server.unitReport, "/api/v1/reports/units/1000", map[string]string{"uuid": "1000"}, "foousr", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "over time")
+}
+
+func TestProjectReport(t *testing.T) {
+	server := setupServerWithDB(t)
+	server.queriers.usage = Querier[models.Usage]
+
+	for _, stmt := range []string{
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,avg_cpu_usage,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-0','slurm',2,'foo','foousr','{"walltime":7200,"alloc_cputime":7200}','{"global":30}','{"total":1.5}','{"owid_total":100}','2024-10-02T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,avg_cpu_usage,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','barusr','{"walltime":3600,"alloc_cputime":3600}','{"global":60}','{"total":0.5}','{"owid_total":40}','2024-10-02T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,avg_cpu_usage,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-0','slurm',1,'foo','foousr','{"walltime":3600,"alloc_cputime":3600}','{"global":60}','{"total":1}','{"owid_total":60}','2024-10-31T00:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,avg_cpu_usage,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-0','slurm',5,'foo','foousr','{"walltime":3600,"alloc_cputime":3600}','{"global":60}','{"total":9}','{"owid_total":60}','2024-11-01T00:00:00')`,
+		// Project with same name on other cluster
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-1','slurm','foo','["quxusr"]','2024-10-10T10:00:00')`,
+		`INSERT INTO daily_usage (cluster_id,resource_manager,num_units,project,username,total_time_seconds,avg_cpu_usage,total_cpu_energy_usage_kwh,total_cpu_emissions_gms,last_updated_at) VALUES ('slurm-1','slurm',7,'foo','quxusr','{"walltime":3600,"alloc_cputime":3600}','{"global":60}','{"total":2}','{"owid_total":60}','2024-10-02T00:00:00')`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	// HTML report of own project
+	w := reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-10", map[string]string{"name": "foo"}, "barusr", false)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	body := w.Body.String()
+	assert.Contains(t, body, "<title>Report of project foo</title>")
+	assert.Contains(t, body, "Usage from 2024-10-01 to 2024-10-31")
+	assert.Contains(t, body, "<dt>Clusters</dt><dd>slurm-0</dd>")
+	assert.Contains(t, body, "<dt>Compute units</dt><dd>4</dd>")
+	assert.Contains(t, body, "<dt>CPU hours</dt><dd>4.00</dd>")
+	assert.Contains(t, body, "<dt>Average CPU usage</dt><dd>45.00 %</dd>")
+	assert.Contains(t, body, "<dt>Total energy</dt><dd>3.00 kWh</dd>")
+	assert.Contains(t, body, "<tr><td>owid</td><td>200</td><td>0</td><td>200</td></tr>")
+	assert.Contains(t, body, "<tr><td>barusr</td><td>1</td><td>1.00</td><td>0</td><td>0.5</td></tr>")
+	assert.Contains(t, body, "<tr><td>foousr</td><td>3</td><td>3.00</td><td>0</td><td>2.50</td></tr>")
+	assert.Contains(t, body, ">2024-10-01</text>")
+	assert.Contains(t, body, ">2024-10-31</text>")
+	assert.NotContains(t, body, "Cost")
+
+	// PDF report
+	w = reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-10", map[string]string{"name": "foo"}, "barusr", false)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/projects/foo?period=2024-10", nil)
+	req.Header.Set(base.LoggedUserHeader, "foousr")
+	req.Header.Set("Accept", "application/pdf")
+	req = mux.SetURLVars(req, map[string]string{"name": "foo"})
+
+	w = httptest.NewRecorder()
+	server.projectReport(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="foo-2024-10.pdf"`, w.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+
+	// Projects of other users are not found
+	w = reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-10", map[string]string{"name": "foo"}, "bazusr", false)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Members of project with same name on other cluster get usage of only that cluster
+	w = reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-10", map[string]string{"name": "foo"}, "quxusr", false)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<dt>Clusters</dt><dd>slurm-1</dd>")
+	assert.Contains(t, w.Body.String(), "<dt>Compute units</dt><dd>7</dd>")
+
+	// Admins can get report of any project
+	w = reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-10", map[string]string{"name": "foo"}, "adm1", true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<dt>Clusters</dt><dd>slurm-0, slurm-1</dd>")
+	assert.Contains(t, w.Body.String(), "<dt>Compute units</dt><dd>11</dd>")
+
+	// Invalid period
+	w = reportRequest(server.projectReport, "/api/v1/reports/projects/foo?period=2024-13", map[string]string{"name": "foo"}, "foousr", false)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -34,6 +34,11 @@ import (
 	"github.com/mahendrapaipuri/ceems/pkg/api/http/docs"
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/mahendrapaipuri/ceems/pkg/sqlite3"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+	promcollectors "github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/collectors/version"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/exporter-toolkit/web"
@@ -42,13 +47,17 @@ import (
 
 // API Resources names.
 const (
-	unitsResourceName      = "units"
-	usageResourceName      = "usage"
-	adminUsersResourceName = "admin_users"
-	usersResourceName      = "users"
-	projectsResourceName   = "projects"
-	clustersResourceName   = "clusters"
-	statsResourceName      = "stats"
+	unitsResourceName       = "units"
+	usageResourceName       = "usage"
+	adminUsersResourceName  = "admin_users"
+	usersResourceName       = "users"
+	projectsResourceName    = "projects"
+	clustersResourceName    = "clusters"
+	statsResourceName       = "stats"
+	annotationsResourceName = "annotations"
+	tokensResourceName      = "tokens"
+	budgetsResourceName     = "budgets"
+	reportsResourceName     = "reports"
 )
 
 // Usage modes.
@@ -65,6 +74,7 @@ type WebConfig struct {
 	LandingConfig     *web.LandingConfig
 	EnableDebugServer bool
 	UserHeaderNames   []string
+	OIDC              OIDCConfig `yaml:"oidc"`
 	ExternalURL       *url.URL
 	RoutePrefix       string
 	MaxQueryPeriod    model.Duration
@@ -98,20 +108,29 @@ func (c *WebConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 
 // Config makes a server config.
 type Config struct {
-	Logger *slog.Logger
-	Web    WebConfig
-	DB     db.Config
+	Logger       *slog.Logger
+	Web          WebConfig
+	DB           db.Config
+	Budgets      []BudgetConfig
+	Reports      ReportsConfig
+	TSDBs        map[string]*tsdb.Client // TSDB clients of clusters used for time series in reports
+	UsageMetrics UsageMetricsConfig
+	Tenant       string // ID of tenant served by server, if any
 }
 
 type queriers struct {
-	unit      func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Unit, error)
-	usage     func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Usage, error)
-	user      func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.User, error)
-	project   func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Project, error)
-	cluster   func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Cluster, error)
-	stat      func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Stat, error)
-	key       func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Key, error)
-	adminUser func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.User, error)
+	unit        func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Unit, error)
+	usage       func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Usage, error)
+	user        func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.User, error)
+	project     func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Project, error)
+	cluster     func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Cluster, error)
+	stat        func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Stat, error)
+	key         func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.Key, error)
+	adminUser   func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.User, error)
+	annotation  func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.UnitAnnotation, error)
+	apiToken    func(context.Context, *sql.DB, Query, *slog.Logger) ([]models.APIToken, error)
+	unitStream  func(context.Context, *sql.DB, Query, *slog.Logger, func(models.Unit) error) error
+	usageStream func(context.Context, *sql.DB, Query, *slog.Logger, func(models.Usage) error) error
 }
 
 // CEEMSServer struct implements HTTP server for stats.
@@ -121,10 +140,18 @@ type CEEMSServer struct {
 	webConfig      *web.FlagConfig
 	externalURL    *url.URL
 	db             *sql.DB
+	writeDB        *sql.DB // Read-write DB connection used only for user annotations and API tokens
 	dbConfig       db.Config
 	maxQueryPeriod time.Duration
 	queriers       queriers
 	usageCache     *ttlcache.Cache[uint64, []models.Usage] // Cache that stores usage query results
+	budgets        []BudgetConfig
+	reports        ReportsConfig
+	tsdbs          map[string]*tsdb.Client
+	registry       *prometheus.Registry
+	collectors     []prometheus.Collector // Collectors of usage and budgets exported on metrics endpoint
+	tenant         string
+	unitUpdates    *broadcaster // Notifies units streams about DB updates
 	healthCheck    func(*sql.DB, *slog.Logger) bool
 }
 
@@ -135,12 +162,14 @@ type Response[T any] struct {
 	ErrorType errorType `extensions:"x-nullable,x-omitempty" json:"errorType,omitempty"`
 	Error     string    `extensions:"x-nullable,x-omitempty" json:"error,omitempty"`
 	Warnings  []string  `extensions:"x-nullable,x-omitempty" json:"warnings,omitempty"`
+	Next      string    `extensions:"x-nullable,x-omitempty" json:"next,omitempty"`
 }
 
 var (
 	aggUsageQueries    = make(map[string]string, len(base.UsageDBTableColNames))
 	cacheTTL           = 15 * time.Minute
 	defaultQueryWindow = 24 * time.Hour // One day
+	statsFields        = models.Stat{}.TagNames("json")
 )
 
 const (
@@ -204,15 +233,25 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 		},
 		dbConfig:       c.DB,
 		maxQueryPeriod: time.Duration(c.Web.MaxQueryPeriod),
+		budgets:        c.Budgets,
+		reports:        c.Reports,
+		tsdbs:          c.TSDBs,
+		registry:       prometheus.NewRegistry(),
+		tenant:         c.Tenant,
+		unitUpdates:    newBroadcaster(),
 		queriers: queriers{
-			unit:      Querier[models.Unit],
-			usage:     Querier[models.Usage],
-			user:      Querier[models.User],
-			project:   Querier[models.Project],
-			cluster:   Querier[models.Cluster],
-			stat:      Querier[models.Stat],
-			key:       Querier[models.Key],
-			adminUser: Querier[models.User],
+			unit:        Querier[models.Unit],
+			usage:       Querier[models.Usage],
+			user:        Querier[models.User],
+			project:     Querier[models.Project],
+			cluster:     Querier[models.Cluster],
+			stat:        Querier[models.Stat],
+			key:         Querier[models.Key],
+			adminUser:   Querier[models.User],
+			annotation:  Querier[models.UnitAnnotation],
+			apiToken:    Querier[models.APIToken],
+			unitStream:  Streamer[models.Unit],
+			usageStream: Streamer[models.Usage],
 		},
 		healthCheck: getDBStatus,
 	}
@@ -255,6 +294,31 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 	// Health endpoint
 	router.HandleFunc("/health", cors.wrap(server.health))
 
+	// Metrics endpoint
+	server.registry.MustRegister(
+		version.NewCollector(base.CEEMSServerAppName),
+		promcollectors.NewProcessCollector(promcollectors.ProcessCollectorOpts{}),
+		promcollectors.NewGoCollector(),
+	)
+
+	if len(server.budgets) > 0 {
+		server.collectors = append(server.collectors, newBudgetsCollector(server))
+	}
+
+	if c.UsageMetrics.Enabled {
+		server.collectors = append(server.collectors, newUsageMetricsCollector(server, time.Duration(c.UsageMetrics.CacheTTL)))
+	}
+
+	server.registry.MustRegister(server.collectors...)
+
+	router.Handle("/metrics", promhttp.HandlerFor(
+		server.registry,
+		promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(c.Logger.Handler(), slog.LevelError),
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	)).Methods(http.MethodGet)
+
 	// Create a sub router with apiVersion as PathPrefix
 	// Allow only GET and OPTIONS methods
 	subRouter := router.Methods(http.MethodGet, http.MethodOptions).PathPrefix(routePrefix).Subrouter()
@@ -265,6 +329,12 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 	subRouter.HandleFunc("/"+unitsResourceName, cors.wrap(server.units))
 	subRouter.HandleFunc(fmt.Sprintf("/%s/{mode:(?:current|global)}", usageResourceName), cors.wrap(server.usage))
 	subRouter.HandleFunc(fmt.Sprintf("/%s/verify", unitsResourceName), cors.wrap(server.verifyUnitsOwnership))
+	subRouter.HandleFunc(fmt.Sprintf("/%s/stream", unitsResourceName), cors.wrap(server.unitsStream))
+	subRouter.HandleFunc(fmt.Sprintf("/%s/{uuid}/%s", unitsResourceName, annotationsResourceName), cors.wrap(server.listAnnotations))
+	subRouter.HandleFunc("/"+tokensResourceName, cors.wrap(server.listTokens))
+	subRouter.HandleFunc("/"+budgetsResourceName, cors.wrap(server.budgetsUser))
+	subRouter.HandleFunc(fmt.Sprintf("/%s/%s/{uuid}", reportsResourceName, unitsResourceName), cors.wrap(server.unitReport))
+	subRouter.HandleFunc(fmt.Sprintf("/%s/%s/{name}", reportsResourceName, projectsResourceName), cors.wrap(server.projectReport))
 
 	// Admin end points
 	subRouter.HandleFunc(fmt.Sprintf("/%s/admin", usersResourceName), cors.wrap(server.usersAdmin))
@@ -273,10 +343,25 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 	subRouter.HandleFunc(fmt.Sprintf("/%s/admin", unitsResourceName), cors.wrap(server.unitsAdmin))
 	subRouter.HandleFunc(fmt.Sprintf("/%s/{mode:(?:current|global)}/admin", usageResourceName), cors.wrap(server.usageAdmin))
 	subRouter.HandleFunc(fmt.Sprintf("/%s/{mode:(?:current|global)}/admin", statsResourceName), cors.wrap(server.statsAdmin))
+	subRouter.HandleFunc(fmt.Sprintf("/%s/admin", budgetsResourceName), cors.wrap(server.budgetsAdmin))
 
 	// A demo end point that returns mocked data for units and/or usage tables
 	subRouter.HandleFunc("/demo/{resource:(?:units|usage)}", cors.wrap(server.demo))
 
+	// Create a sub router for write end points. Only user annotations and personal
+	// API tokens can be written by API server
+	writeRouter := router.PathPrefix(routePrefix).Subrouter()
+
+	// Annotations end points
+	annotationsPath := fmt.Sprintf("/%s/{uuid}/%s", unitsResourceName, annotationsResourceName)
+	writeRouter.HandleFunc(annotationsPath, cors.wrap(server.createAnnotations)).Methods(http.MethodPost)
+	writeRouter.HandleFunc(annotationsPath, cors.wrap(server.updateAnnotations)).Methods(http.MethodPatch)
+	writeRouter.HandleFunc(annotationsPath, cors.wrap(server.deleteAnnotations)).Methods(http.MethodDelete)
+
+	// Personal API tokens end points
+	writeRouter.HandleFunc("/"+tokensResourceName, cors.wrap(server.createToken)).Methods(http.MethodPost)
+	writeRouter.HandleFunc(fmt.Sprintf("/%s/{id}", tokensResourceName), cors.wrap(server.revokeToken)).Methods(http.MethodDelete)
+
 	// Open DB connection
 	dsn := fmt.Sprintf(
 		"file:%s?%s",
@@ -287,6 +372,16 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 		return nil, func() {}, fmt.Errorf("failed to open DB: %w", err)
 	}
 
+	// Open a read-write DB connection for annotations
+	writeDSN := fmt.Sprintf(
+		"file:%s?%s",
+		filepath.Join(c.DB.Data.Path, base.CEEMSDBName),
+		"_mutex=no&_busy_timeout=5000",
+	)
+	if server.writeDB, err = sql.Open(sqlite3.DriverName, writeDSN); err != nil {
+		return nil, func() {}, fmt.Errorf("failed to open DB: %w", err)
+	}
+
 	// Rate limit requests by RealIP
 	if c.Web.RequestsLimit > 0 {
 		c.Logger.Debug("Rate limiting settings", "reqs_per_minute", c.Web.RequestsLimit)
@@ -299,6 +394,16 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 		return nil, func() {}, fmt.Errorf("failed to create middleware: %w", err)
 	}
 
+	// Resolve personal API tokens
+	amw.apiTokens = server.authenticateAPIToken
+
+	// Validate bearer tokens when OIDC is configured
+	if c.Web.OIDC.Enabled() {
+		if amw.oidc, err = newOIDCVerifier(c.Web.OIDC, c.Logger); err != nil {
+			return nil, func() {}, err
+		}
+	}
+
 	router.Use(amw.Middleware)
 
 	// Instantiate new cache for storing current usage query results with TTL of 15 min
@@ -322,7 +427,7 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 //	@description
 //	@description	If basic auth is enabled, all the endpoints require authentication.
 //	@description
-//	@description	All the endpoints, except `health`, `swagger`, `debug` and `demo`,
+//	@description	All the endpoints, except `health`, `metrics`, `swagger`, `debug` and `demo`,
 //	@description	must send a user-agent header.
 //	@description
 //	@description	A demo instance of CEEMS API server is provided for the users to test. This
@@ -369,35 +474,47 @@ func New(c *Config) (*CEEMSServer, func(), error) {
 //
 //	@x-logo						{"url": "https://raw.githubusercontent.com/mahendrapaipuri/ceems/refs/heads/main/website/static/img/logo.png", "altText": "CEEMS logo"}
 func (s *CEEMSServer) Start(_ context.Context) error {
-	// Set swagger info
+	setSwaggerInfo(s.server.Addr, s.externalURL)
+
+	s.logger.Info("Starting " + base.CEEMSServerAppName)
+
+	if err := web.ListenAndServe(s.server, s.webConfig, s.logger); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("Failed to Listen and Serve HTTP server", "err", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// setSwaggerInfo sets host and schemes of swagger docs.
+func setSwaggerInfo(addr string, externalURL *url.URL) {
 	docs.SwaggerInfo.BasePath = "/api/" + base.APIVersion
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
-	docs.SwaggerInfo.Host = s.server.Addr
+	docs.SwaggerInfo.Host = addr
 
 	// If externalURL is set, use it for Swagger Host
-	if s.externalURL.Host != "" {
-		docs.SwaggerInfo.Host = s.externalURL.Host
-		docs.SwaggerInfo.Schemes = []string{s.externalURL.Scheme}
+	if externalURL.Host != "" {
+		docs.SwaggerInfo.Host = externalURL.Host
+		docs.SwaggerInfo.Schemes = []string{externalURL.Scheme}
 	}
 
 	// If externalURL is not set, ensure the server address is of good format.
 	if host, port, err := net.SplitHostPort(docs.SwaggerInfo.Host); err == nil && host == "" {
 		docs.SwaggerInfo.Host = "localhost:" + port
 	}
+}
 
-	s.logger.Info("Starting " + base.CEEMSServerAppName)
-
-	if err := web.ListenAndServe(s.server, s.webConfig, s.logger); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		s.logger.Error("Failed to Listen and Serve HTTP server", "err", err)
-
-		return err
-	}
-
-	return nil
+// Handler returns the HTTP handler of server with all the routes and middlewares.
+func (s *CEEMSServer) Handler() http.Handler {
+	return s.server.Handler
 }
 
 // Shutdown server.
 func (s *CEEMSServer) Shutdown(ctx context.Context) error {
+	// Stop long lived units streams so that they do not block server shutdown
+	s.unitUpdates.close()
+
 	// Close DB connection
 	if err := s.db.Close(); err != nil {
 		s.logger.Error("Failed to close DB connection", "err", err)
@@ -405,6 +522,12 @@ func (s *CEEMSServer) Shutdown(ctx context.Context) error {
 		return err
 	}
 
+	if err := s.writeDB.Close(); err != nil {
+		s.logger.Error("Failed to close read-write DB connection", "err", err)
+
+		return err
+	}
+
 	// Shutdown the server
 	if err := s.server.Shutdown(ctx); err != nil {
 		s.logger.Error("Failed to shutdown HTTP server", "err", err)
@@ -655,9 +778,11 @@ func (s *CEEMSServer) inTargetTimeLocation(tz string, units []models.Unit) []mod
 	return units
 }
 
-// unitsQuerier queries for compute units and write response.
+// unitsQuerier queries for compute units and write response. Units of projects
+// managed by managers are returned along with the units of queriedUsers.
 func (s *CEEMSServer) unitsQuerier(
 	queriedUsers []string,
+	managers []string,
 	w http.ResponseWriter,
 	r *http.Request,
 ) {
@@ -688,17 +813,52 @@ func (s *CEEMSServer) unitsQuerier(
 		return
 	}
 
+	// Get sorting and pagination parameters
+	p, err := s.getPage(r.URL.Query(), base.UnitsDBTableName, unitsSortKeys)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
+	// Get response format
+	format, err := responseFormat(r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
+	// Row ID is needed to make cursor for next page
+	selectFields := queriedFields
+	if p.limit > 0 {
+		selectFields = append([]string{base.UnitsDBTableName + ".id"}, queriedFields...)
+	}
+
 	// Initialise query builder
 	q := Query{}
-	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(queriedFields, ","), base.UnitsDBTableName))
+	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectFields, ","), base.UnitsDBTableName))
+
+	// Annotations are stored in a separate table. Join them when requested
+	if slices.Contains(queriedFields, "annotations") {
+		q.query(" " + annotationsJoinQuery)
+	}
 
 	// Query for only unignored units
 	q.query(" WHERE ignore = 0 ")
 
-	// Add condition to query only for current dashboardUser
+	// Add condition to query only for current dashboardUser and projects
+	// managed by the user
 	if len(queriedUsers) > 0 {
-		q.query(" AND username IN ")
+		q.query(" AND (username IN ")
 		q.param(queriedUsers)
+
+		if len(managers) > 0 {
+			q.query(" OR (cluster_id, project) IN ")
+			q.subQuery(managedProjectsSubQuery(managers))
+		}
+
+		q.query(")")
 	}
 
 	// Add common query parameters
@@ -714,6 +874,32 @@ func (s *CEEMSServer) unitsQuerier(
 		checkQueryWindow = false
 	}
 
+	// Check if annotation filters are present in query params and add them
+	for _, filter := range r.URL.Query()["annotation"] {
+		key, value, hasValue, err := parseAnnotationFilter(filter)
+		if err != nil {
+			errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+			return
+		}
+
+		q.query(" AND EXISTS ")
+		q.subQuery(annotationsFilterQuery(key, value, hasValue))
+	}
+
+	// Check if filter expressions are present in query params and add them
+	for _, filter := range r.URL.Query()["filter"] {
+		filterQuery, err := parseFilter(filter, unitsFilterFields)
+		if err != nil {
+			errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+			return
+		}
+
+		q.query(" AND ")
+		q.subQuery(filterQuery)
+	}
+
 	// If we dont have to specific query window skip next section of code as it becomes
 	// irrelevant
 	if !checkQueryWindow {
@@ -736,8 +922,39 @@ func (s *CEEMSServer) unitsQuerier(
 	q.subQuery(timeQuery)
 
 queryUnits:
-	// Sort by uuid
-	q.query(" ORDER BY cluster_id ASC, uuid ASC ")
+	// When streaming units in CSV format, keys of map fields are needed
+	// to write CSV header. Fetch them before adding ORDER BY clause
+	var mapKeys map[string][]string
+	if format == formatCSV && p.limit == 0 {
+		if mapKeys, err = s.mapKeys(r.Context(), q, mapFields[models.Unit](queriedFields)); err != nil {
+			s.logger.Error("Failed to fetch keys of metric maps", "logged_user", loggedUser, "err", err)
+			errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+			return
+		}
+	}
+
+	// Sort by requested key or by uuid
+	if p.enabled() {
+		q.paginate(p)
+	} else {
+		q.query(" ORDER BY cluster_id ASC, uuid ASC ")
+	}
+
+	// Stream units directly from DB in CSV and NDJSON formats. If pagination
+	// is requested, number of units are bounded by limit and the page is
+	// fetched into memory to make link to next page
+	if format != formatJSON && p.limit == 0 {
+		tz := r.URL.Query().Get("timezone")
+
+		writeRows(w, format, unitsResourceName, queriedFields, mapKeys, "", func(yield func(models.Unit) error) error {
+			return s.queriers.unitStream(r.Context(), s.db, q, s.logger, func(unit models.Unit) error {
+				return yield(s.inTargetTimeLocation(tz, []models.Unit{unit})[0])
+			})
+		}, s.logger)
+
+		return
+	}
 
 	// Get all user units in the given time window
 	units, err := s.queriers.unit(r.Context(), s.db, q, s.logger)
@@ -748,15 +965,36 @@ queryUnits:
 		return
 	}
 
+	// Get link to next page
+	units, next, pageErr := nextPage(r.Context(), s.db, r, p, units, func(u models.Unit) int64 { return u.ID })
+	if pageErr != nil {
+		s.logger.Error("Failed to paginate units", "logged_user", loggedUser, "err", pageErr)
+		errorResponse[any](w, &apiError{errorInternal, pageErr}, s.logger, nil)
+
+		return
+	}
+
 	// Convert times to time zone provided in the query
 	units = s.inTargetTimeLocation(r.URL.Query().Get("timezone"), units)
 
+	// Write page in CSV and NDJSON formats
+	if format != formatJSON {
+		if err != nil {
+			s.logger.Warn("Failed to fetch some units", "logged_user", loggedUser, "err", err)
+		}
+
+		writeRows(w, format, unitsResourceName, queriedFields, sliceMapKeys(units, queriedFields), next, sliceRows(units), s.logger)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
 	response := Response[models.Unit]{
 		Status: "success",
 		Data:   units,
+		Next:   next,
 	}
 	if err != nil {
 		response.Warnings = append(response.Warnings, err.Error())
@@ -791,11 +1029,31 @@ queryUnits:
 //	@Description	parameter `timezone` is provided, the unit's created, start and end time strings
 //	@Description	will be presented in that time zone.
 //	@Description
+//	@Description	To filter compute units by user defined annotations, use `annotation` query parameter
+//	@Description	of form `key` or `key:value`. When multiple `annotation` query parameters are passed,
+//	@Description	compute units that match all of them will be returned.
+//	@Description
+//	@Description	Compute units can be filtered further using `filter` query parameter that takes
+//	@Description	an expression like `state="FAILED" and allocation.gpus>=4 and avg_gpu_usage.global<10`.
+//	@Description	Keys of map fields like `allocation`, `tags` and metrics are accessed with a `.`.
+//	@Description	Supported operators are `=`, `!=`, `<`, `<=`, `>`, `>=`, `in` and `not in` and
+//	@Description	expressions can be combined with `and`, `or`, `not` and parentheses.
+//	@Description
 //	@Description	To limit the number of fields in the response, use `field` query parameter. By default, all
 //	@Description	fields will be included in the response if they are _non-empty_.
+//	@Description
+//	@Description	Compute units can be sorted using `sort` query parameter. Supported sort keys are
+//	@Description	`uuid`, `name`, `project`, `username`, `state`, `created_at_ts`, `started_at_ts`,
+//	@Description	`ended_at_ts`, `elapsed`, `avg_cpu_usage`, `avg_cpu_mem_usage`, `avg_gpu_usage`,
+//	@Description	`avg_gpu_mem_usage`, `total_cpu_energy_usage_kwh` and `total_gpu_energy_usage_kwh`.
+//	@Description	Prefix the sort key with `-` to sort in descending order.
+//	@Description
+//	@Description	Use `limit` query parameter to paginate the response. When there are more compute
+//	@Description	units, the response contains a `next` link that has a `cursor` query parameter
+//	@Description	which must be used to fetch the next page.
 //	@Security		BasicAuth
 //	@Tags			units
-//	@Produce		json
+//	@Produce		json,text/csv,application/x-ndjson
 //	@Param			X-Grafana-User	header		string		true	"Current user name"
 //	@Param			cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
 //	@Param			uuid			query		[]string	false	"Unit UUID"		collectionFormat(multi)
@@ -805,10 +1063,18 @@ queryUnits:
 //	@Param			from			query		string		false	"From timestamp"
 //	@Param			to				query		string		false	"To timestamp"
 //	@Param			timezone		query		string		false	"Time zone in IANA format"
+//	@Param			annotation		query		[]string	false	"Annotation filter"				collectionFormat(multi)
+//	@Param			filter			query		[]string	false	"Filter expression"				collectionFormat(multi)
 //	@Param			field			query		[]string	false	"Fields to return in response"	collectionFormat(multi)
+//	@Param			sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param			limit			query		int			false	"Maximum number of units in response"
+//	@Param			cursor			query		string		false	"Cursor of next page"
+//	@Param			format			query		string		false	"Response format"	Enums(json, csv, ndjson)
 //	@Success		200				{object}	Response[models.Unit]
+//	@Failure		400				{object}	Response[any]
 //	@Failure		401				{object}	Response[any]
 //	@Failure		403				{object}	Response[any]
+//	@Failure		406				{object}	Response[any]
 //	@Failure		500				{object}	Response[any]
 //	@Router			/units/admin [get]
 //
@@ -819,7 +1085,7 @@ func (s *CEEMSServer) unitsAdmin(w http.ResponseWriter, r *http.Request) {
 	defer common.TimeTrack(time.Now(), "units admin endpoint", s.logger)
 
 	// Query for units and write response
-	s.unitsQuerier(r.URL.Query()["user"], w, r)
+	s.unitsQuerier(r.URL.Query()["user"], nil, w, r)
 }
 
 // units         godoc
@@ -829,6 +1095,9 @@ func (s *CEEMSServer) unitsAdmin(w http.ResponseWriter, r *http.Request) {
 //	@Description	current user is always identified by the header `X-Grafana-User` in
 //	@Description	the request.
 //	@Description
+//	@Description	If the current user is a manager of a project, compute units of all the
+//	@Description	users of that project will be returned as well.
+//	@Description
 //	@Description	If multiple query parameters are passed, for instance, `?uuid=<uuid>&project=<project>`,
 //	@Description	the intersection of query parameters are used to fetch compute units rather than
 //	@Description	the union. That means if the compute unit's `uuid` does not belong to the queried
@@ -842,11 +1111,31 @@ func (s *CEEMSServer) unitsAdmin(w http.ResponseWriter, r *http.Request) {
 //	@Description	parameter `timezone` is provided, the unit's created, start and end time strings
 //	@Description	will be presented in that time zone.
 //	@Description
+//	@Description	To filter compute units by user defined annotations, use `annotation` query parameter
+//	@Description	of form `key` or `key:value`. When multiple `annotation` query parameters are passed,
+//	@Description	compute units that match all of them will be returned.
+//	@Description
+//	@Description	Compute units can be filtered further using `filter` query parameter that takes
+//	@Description	an expression like `state="FAILED" and allocation.gpus>=4 and avg_gpu_usage.global<10`.
+//	@Description	Keys of map fields like `allocation`, `tags` and metrics are accessed with a `.`.
+//	@Description	Supported operators are `=`, `!=`, `<`, `<=`, `>`, `>=`, `in` and `not in` and
+//	@Description	expressions can be combined with `and`, `or`, `not` and parentheses.
+//	@Description
 //	@Description	To limit the number of fields in the response, use `field` query parameter. By default, all
 //	@Description	fields will be included in the response if they are _non-empty_.
+//	@Description
+//	@Description	Compute units can be sorted using `sort` query parameter. Supported sort keys are
+//	@Description	`uuid`, `name`, `project`, `username`, `state`, `created_at_ts`, `started_at_ts`,
+//	@Description	`ended_at_ts`, `elapsed`, `avg_cpu_usage`, `avg_cpu_mem_usage`, `avg_gpu_usage`,
+//	@Description	`avg_gpu_mem_usage`, `total_cpu_energy_usage_kwh` and `total_gpu_energy_usage_kwh`.
+//	@Description	Prefix the sort key with `-` to sort in descending order.
+//	@Description
+//	@Description	Use `limit` query parameter to paginate the response. When there are more compute
+//	@Description	units, the response contains a `next` link that has a `cursor` query parameter
+//	@Description	which must be used to fetch the next page.
 //	@Security		BasicAuth
 //	@Tags			units
-//	@Produce		json
+//	@Produce		json,text/csv,application/x-ndjson
 //	@Param			X-Grafana-User	header		string		true	"Current user name"
 //	@Param			cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
 //	@Param			uuid			query		[]string	false	"Unit UUID"		collectionFormat(multi)
@@ -855,10 +1144,18 @@ func (s *CEEMSServer) unitsAdmin(w http.ResponseWriter, r *http.Request) {
 //	@Param			from			query		string		false	"From timestamp"
 //	@Param			to				query		string		false	"To timestamp"
 //	@Param			timezone		query		string		false	"Time zone in IANA format"
+//	@Param			annotation		query		[]string	false	"Annotation filter"				collectionFormat(multi)
+//	@Param			filter			query		[]string	false	"Filter expression"				collectionFormat(multi)
 //	@Param			field			query		[]string	false	"Fields to return in response"	collectionFormat(multi)
+//	@Param			sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param			limit			query		int			false	"Maximum number of units in response"
+//	@Param			cursor			query		string		false	"Cursor of next page"
+//	@Param			format			query		string		false	"Response format"	Enums(json, csv, ndjson)
 //	@Success		200				{object}	Response[models.Unit]
+//	@Failure		400				{object}	Response[any]
 //	@Failure		401				{object}	Response[any]
 //	@Failure		403				{object}	Response[any]
+//	@Failure		406				{object}	Response[any]
 //	@Failure		500				{object}	Response[any]
 //	@Router			/units [get]
 //
@@ -872,7 +1169,7 @@ func (s *CEEMSServer) units(w http.ResponseWriter, r *http.Request) {
 	loggedUser := s.getUser(r)
 
 	// Query for units and write response
-	s.unitsQuerier([]string{loggedUser}, w, r)
+	s.unitsQuerier([]string{loggedUser}, []string{loggedUser}, w, r)
 }
 
 // verifyUnitsOwnership         godoc
@@ -1066,6 +1363,14 @@ func (s *CEEMSServer) usersQuerier(users []string, w http.ResponseWriter, r *htt
 	// Set headers
 	s.setHeaders(w)
 
+	// Get sorting and pagination parameters
+	p, err := s.getPage(r.URL.Query(), base.UsersDBTableName, usersSortKeys)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
 	// Make query
 	q := Query{}
 	q.query("SELECT * FROM " + base.UsersDBTableName)
@@ -1084,8 +1389,12 @@ func (s *CEEMSServer) usersQuerier(users []string, w http.ResponseWriter, r *htt
 		q.param(clusterIDs)
 	}
 
-	// Sort by cluster_id and name
-	q.query(" ORDER BY cluster_id ASC, name ASC ")
+	// Sort by requested key or by cluster_id and name
+	if p.enabled() {
+		q.paginate(p)
+	} else {
+		q.query(" ORDER BY cluster_id ASC, name ASC ")
+	}
 
 	// Make query and check for users returned in usage
 	userModels, err := s.queriers.user(r.Context(), s.db, q, s.logger)
@@ -1096,12 +1405,22 @@ func (s *CEEMSServer) usersQuerier(users []string, w http.ResponseWriter, r *htt
 		return
 	}
 
+	// Get link to next page
+	userModels, next, pageErr := nextPage(r.Context(), s.db, r, p, userModels, func(u models.User) int64 { return u.ID })
+	if pageErr != nil {
+		s.logger.Error("Failed to paginate users", "users", strings.Join(users, ","), "err", pageErr)
+		errorResponse[any](w, &apiError{errorInternal, pageErr}, s.logger, nil)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
 	usersResponse := Response[models.User]{
 		Status: "success",
 		Data:   userModels,
+		Next:   next,
 	}
 	if err != nil {
 		usersResponse.Warnings = append(usersResponse.Warnings, err.Error())
@@ -1122,12 +1441,21 @@ func (s *CEEMSServer) usersQuerier(users []string, w http.ResponseWriter, r *htt
 //	@Description
 //	@Description	The details include list of projects that user is currently a part of.
 //	@Description
+//	@Description	The users can be sorted by `cluster_id` or `name` using `sort` query parameter.
+//	@Description	Prefix the sort key with `-` to sort in descending order. Use `limit` query
+//	@Description	parameter to paginate the response and follow the `next` link in the response
+//	@Description	to fetch the next page.
+//	@Description
 //	@Security	BasicAuth
 //	@Tags		users
 //	@Produce	json
 //	@Param		X-Grafana-User	header		string		true	"Current user name"
 //	@Param		cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param		sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param		limit			query		int			false	"Maximum number of users in response"
+//	@Param		cursor			query		string		false	"Cursor of next page"
 //	@Success	200				{object}	Response[models.User]
+//	@Failure	400				{object}	Response[any]
 //	@Failure	401				{object}	Response[any]
 //	@Failure	500				{object}	Response[any]
 //	@Router		/users [get]
@@ -1163,14 +1491,23 @@ func (s *CEEMSServer) users(w http.ResponseWriter, r *http.Request) {
 //	@Description	When query parameter `role` is set to `admin`, only admin users will
 //	@Description	will be returned. The `tags` values indicates the source of admin user.
 //	@Description
+//	@Description	The users can be sorted by `cluster_id` or `name` using `sort` query parameter.
+//	@Description	Prefix the sort key with `-` to sort in descending order. Use `limit` query
+//	@Description	parameter to paginate the response and follow the `next` link in the response
+//	@Description	to fetch the next page.
+//	@Description
 //	@Security	BasicAuth
 //	@Tags		users
 //	@Produce	json
 //	@Param		X-Grafana-User	header		string		true	"Current user name"
 //	@Param		user			query		[]string	false	"User name"		collectionFormat(multi)
 //	@Param		cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param		sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param		limit			query		int			false	"Maximum number of users in response"
+//	@Param		cursor			query		string		false	"Cursor of next page"
 //	@Param		role			query		string		false	"User role"
 //	@Success	200				{object}	Response[models.User]
+//	@Failure	400				{object}	Response[any]
 //	@Failure	401				{object}	Response[any]
 //	@Failure	500				{object}	Response[any]
 //	@Router		/users/admin [get]
@@ -1198,15 +1535,23 @@ func (s *CEEMSServer) projectsQuerier(users []string, w http.ResponseWriter, r *
 	// Set headers
 	s.setHeaders(w)
 
+	// Get sorting and pagination parameters
+	p, err := s.getPage(r.URL.Query(), base.ProjectsDBTableName, projectsSortKeys)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
 	// Get sub query for projects
-	qSub := projectsSubQuery(users)
+	qSub := projectsCondition("name", users)
 
 	// Make query
 	q := Query{}
 	q.query("SELECT * FROM " + base.ProjectsDBTableName)
 
 	// First select all projects that user is part of using subquery
-	q.query(" WHERE name IN ")
+	q.query(" WHERE ")
 	q.subQuery(qSub)
 
 	// Get project query parameters if any
@@ -1221,8 +1566,12 @@ func (s *CEEMSServer) projectsQuerier(users []string, w http.ResponseWriter, r *
 		q.param(clusterIDs)
 	}
 
-	// Sort by cluster_id and name
-	q.query(" ORDER BY cluster_id ASC, name ASC ")
+	// Sort by requested key or by cluster_id and name
+	if p.enabled() {
+		q.paginate(p)
+	} else {
+		q.query(" ORDER BY cluster_id ASC, name ASC ")
+	}
 
 	// Make query
 	projectModels, err := s.queriers.project(r.Context(), s.db, q, s.logger)
@@ -1236,12 +1585,22 @@ func (s *CEEMSServer) projectsQuerier(users []string, w http.ResponseWriter, r *
 		return
 	}
 
+	// Get link to next page
+	projectModels, next, pageErr := nextPage(r.Context(), s.db, r, p, projectModels, func(prj models.Project) int64 { return prj.ID })
+	if pageErr != nil {
+		s.logger.Error("Failed to paginate projects", "users", strings.Join(users, ","), "err", pageErr)
+		errorResponse[any](w, &apiError{errorInternal, pageErr}, s.logger, nil)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
 	projectsResponse := Response[models.Project]{
 		Status: "success",
 		Data:   projectModels,
+		Next:   next,
 	}
 	if err != nil {
 		projectsResponse.Warnings = append(projectsResponse.Warnings, err.Error())
@@ -1260,17 +1619,27 @@ func (s *CEEMSServer) projectsQuerier(users []string, w http.ResponseWriter, r *
 //	@Description	current user is always identified by the header `X-Grafana-User` in
 //	@Description	the request.
 //	@Description
-//	@Description	The details include list of users in that project. If current user
+//	@Description	The details include list of users and managers of that project. Projects
+//	@Description	managed by the current user are returned as well. If current user
 //	@Description	attempts to query a project that they are not part of, empty response
 //	@Description	will be returned
 //	@Description
+//	@Description	The projects can be sorted by `cluster_id` or `name` using `sort` query parameter.
+//	@Description	Prefix the sort key with `-` to sort in descending order. Use `limit` query
+//	@Description	parameter to paginate the response and follow the `next` link in the response
+//	@Description	to fetch the next page.
+//	@Description
 //	@Security	BasicAuth
 //	@Tags		projects
 //	@Produce	json
 //	@Param		X-Grafana-User	header		string		true	"Current user name"
 //	@Param		project			query		[]string	false	"Project"		collectionFormat(multi)
 //	@Param		cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param		sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param		limit			query		int			false	"Maximum number of projects in response"
+//	@Param		cursor			query		string		false	"Cursor of next page"
 //	@Success	200				{object}	Response[models.Project]
+//	@Failure	400				{object}	Response[any]
 //	@Failure	401				{object}	Response[any]
 //	@Failure	500				{object}	Response[any]
 //	@Router		/projects [get]
@@ -1302,13 +1671,22 @@ func (s *CEEMSServer) projects(w http.ResponseWriter, r *http.Request) {
 //	@Description	attempts to query a project that they are not part of, empty response
 //	@Description	will be returned
 //	@Description
+//	@Description	The projects can be sorted by `cluster_id` or `name` using `sort` query parameter.
+//	@Description	Prefix the sort key with `-` to sort in descending order. Use `limit` query
+//	@Description	parameter to paginate the response and follow the `next` link in the response
+//	@Description	to fetch the next page.
+//	@Description
 //	@Security	BasicAuth
 //	@Tags		projects
 //	@Produce	json
 //	@Param		X-Grafana-User	header		string		true	"Current user name"
 //	@Param		project			query		[]string	false	"Project"		collectionFormat(multi)
 //	@Param		cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param		sort			query		string		false	"Sort key. Prefix with - for descending order"
+//	@Param		limit			query		int			false	"Maximum number of projects in response"
+//	@Param		cursor			query		string		false	"Cursor of next page"
 //	@Success	200				{object}	Response[models.Project]
+//	@Failure	400				{object}	Response[any]
 //	@Failure	401				{object}	Response[any]
 //	@Failure	500				{object}	Response[any]
 //	@Router		/projects/admin [get]
@@ -1393,6 +1771,15 @@ func (s *CEEMSServer) currentUsage(users []string, fields []string, w http.Respo
 
 	var err, qErrs error
 
+	var format string
+
+	// Get response format
+	if format, err = responseFormat(r); err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
 	// Round `to` and `from` query parameters to cacheTTL
 	if err := s.roundQueryWindow(r); err != nil {
 		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
@@ -1510,8 +1897,8 @@ func (s *CEEMSServer) currentUsage(users []string, fields []string, w http.Respo
 	)
 
 	// First select all projects that user is part of using subquery
-	q.query(" WHERE project IN ")
-	q.subQuery(projectsSubQuery(users)) // Get sub query for projects
+	q.query(" WHERE ")
+	q.subQuery(projectsCondition("project", users)) // Get sub query for projects
 
 	// Add common query parameters
 	q = s.getCommonQueryParams(&q, r.URL.Query())
@@ -1551,6 +1938,18 @@ func (s *CEEMSServer) currentUsage(users []string, fields []string, w http.Respo
 	}
 
 writer:
+	// Write response in CSV and NDJSON formats. Usage statistics are already
+	// aggregated and hence, they are always fetched into memory
+	if format != formatJSON {
+		if err := errors.Join(qErrs, err); err != nil {
+			s.logger.Warn("Failed to fetch some usage statistics", "users", strings.Join(users, ","), "err", err)
+		}
+
+		writeRows(w, format, usageResourceName, fields, sliceMapKeys(usage, fields), "", sliceRows(usage), s.logger)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
@@ -1575,23 +1974,52 @@ writer:
 // GET /usage/global
 // Get global usage statistics.
 func (s *CEEMSServer) globalUsage(users []string, queriedFields []string, w http.ResponseWriter, r *http.Request) {
+	// Get response format
+	format, err := responseFormat(r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
 	// Get sub query for projects
-	qSub := projectsSubQuery(users)
+	qSub := projectsCondition("project", users)
 
 	// Make query
 	q := Query{}
 	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(queriedFields, ","), base.UsageDBTableName))
 
 	// First select all projects that user is part of using subquery
-	q.query(" WHERE project IN ")
+	q.query(" WHERE ")
 	q.subQuery(qSub)
 
 	// Add common query parameters
 	q = s.getCommonQueryParams(&q, r.URL.Query())
 
+	// When streaming usage in CSV format, keys of map fields are needed
+	// to write CSV header. Fetch them before adding ORDER BY clause
+	var mapKeys map[string][]string
+	if format == formatCSV {
+		if mapKeys, err = s.mapKeys(r.Context(), q, mapFields[models.Usage](queriedFields)); err != nil {
+			s.logger.Error("Failed to fetch keys of metric maps", "users", strings.Join(users, ","), "err", err)
+			errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+			return
+		}
+	}
+
 	// Sort by cluster_id, username and project
 	q.query(" ORDER BY cluster_id ASC, username ASC, project ASC ")
 
+	// Stream usage directly from DB in CSV and NDJSON formats
+	if format != formatJSON {
+		writeRows(w, format, usageResourceName, queriedFields, mapKeys, "", func(yield func(models.Usage) error) error {
+			return s.queriers.usageStream(r.Context(), s.db, q, s.logger, yield)
+		}, s.logger)
+
+		return
+	}
+
 	// Make query and check for returned number of rows
 	usage, err := s.queriers.usage(r.Context(), s.db, q, s.logger)
 	if usage == nil && err != nil {
@@ -1658,7 +2086,7 @@ func (s *CEEMSServer) globalUsage(users []string, queriedFields []string, w http
 //	@Description	is invalidated after 15 min.
 //	@Security		BasicAuth
 //	@Tags			usage
-//	@Produce		json
+//	@Produce		json,text/csv,application/x-ndjson
 //	@Param			X-Grafana-User	header		string		true	"Current user name"
 //	@Param			mode			path		string		true	"Whether to get usage stats within a period or global"	Enums(current, global)
 //	@Param			cluster_id		query		[]string	false	"cluster ID"											collectionFormat(multi)
@@ -1666,8 +2094,10 @@ func (s *CEEMSServer) globalUsage(users []string, queriedFields []string, w http
 //	@Param			from			query		string		false	"From timestamp"
 //	@Param			to				query		string		false	"To timestamp"
 //	@Param			field			query		[]string	false	"Fields to return in response"	collectionFormat(multi)
+//	@Param			format			query		string		false	"Response format"	Enums(json, csv, ndjson)
 //	@Success		200				{object}	Response[models.Usage]
 //	@Failure		401				{object}	Response[any]
+//	@Failure		406				{object}	Response[any]
 //	@Failure		500				{object}	Response[any]
 //	@Router			/usage/{mode} [get]
 //
@@ -1756,7 +2186,7 @@ func (s *CEEMSServer) usage(w http.ResponseWriter, r *http.Request) {
 //	@Description	is invalidated after 15 min.
 //	@Security		BasicAuth
 //	@Tags			usage
-//	@Produce		json
+//	@Produce		json,text/csv,application/x-ndjson
 //	@Param			X-Grafana-User	header		string		true	"Current user name"
 //	@Param			mode			path		string		true	"Whether to get usage stats within a period or global"	Enums(current, global)
 //	@Param			cluster_id		query		[]string	false	"cluster ID"											collectionFormat(multi)
@@ -1765,9 +2195,11 @@ func (s *CEEMSServer) usage(w http.ResponseWriter, r *http.Request) {
 //	@Param			from			query		string		false	"From timestamp"
 //	@Param			to				query		string		false	"To timestamp"
 //	@Param			field			query		[]string	false	"Fields to return in response"	collectionFormat(multi)
+//	@Param			format			query		string		false	"Response format"	Enums(json, csv, ndjson)
 //	@Success		200				{object}	Response[models.Usage]
 //	@Failure		401				{object}	Response[any]
 //	@Failure		403				{object}	Response[any]
+//	@Failure		406				{object}	Response[any]
 //	@Failure		500				{object}	Response[any]
 //	@Router			/usage/{mode}/admin [get]
 //
@@ -1824,6 +2256,15 @@ func (s *CEEMSServer) currentStats(users []string, w http.ResponseWriter, r *htt
 
 	var err error
 
+	var format string
+
+	// Get response format
+	if format, err = responseFormat(r); err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
 	// Set write deadline
 	s.setWriteDeadline(1*time.Minute, w)
 
@@ -1864,6 +2305,17 @@ func (s *CEEMSServer) currentStats(users []string, w http.ResponseWriter, r *htt
 		return
 	}
 
+	// Write response in CSV and NDJSON formats
+	if format != formatJSON {
+		if err != nil {
+			s.logger.Warn("Failed to fetch some stats", "err", err)
+		}
+
+		writeRows(w, format, statsResourceName, statsFields, nil, "", sliceRows(stats), s.logger)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
@@ -1890,6 +2342,15 @@ func (s *CEEMSServer) globalStats(users []string, w http.ResponseWriter, r *http
 
 	var err error
 
+	var format string
+
+	// Get response format
+	if format, err = responseFormat(r); err != nil {
+		errorResponse[any](w, &apiError{errorNotAcceptable, err}, s.logger, nil)
+
+		return
+	}
+
 	// Set write deadline
 	s.setWriteDeadline(1*time.Minute, w)
 
@@ -1918,6 +2379,17 @@ func (s *CEEMSServer) globalStats(users []string, w http.ResponseWriter, r *http
 		return
 	}
 
+	// Write response in CSV and NDJSON formats
+	if format != formatJSON {
+		if err != nil {
+			s.logger.Warn("Failed to fetch some stats", "err", err)
+		}
+
+		writeRows(w, format, statsResourceName, statsFields, nil, "", sliceRows(stats), s.logger)
+
+		return
+	}
+
 	// Write response
 	w.WriteHeader(http.StatusOK)
 
@@ -1961,15 +2433,17 @@ func (s *CEEMSServer) globalStats(users []string, w http.ResponseWriter, r *http
 //	@Description
 //	@Security	BasicAuth
 //	@Tags		stats
-//	@Produce	json
+//	@Produce	json,text/csv,application/x-ndjson
 //	@Param		X-Grafana-User	header		string		true	"Current user name"
 //	@Param		mode			path		string		true	"Whether to get quick stats within a period or global"	Enums(current, global)
 //	@Param		cluster_id		query		[]string	false	"cluster ID"											collectionFormat(multi)
 //	@Param		from			query		string		false	"From timestamp"
 //	@Param		to				query		string		false	"To timestamp"
+//	@Param		format			query		string		false	"Response format"	Enums(json, csv, ndjson)
 //	@Success	200				{object}	Response[models.Stat]
 //	@Failure	401				{object}	Response[any]
 //	@Failure	403				{object}	Response[any]
+//	@Failure	406				{object}	Response[any]
 //	@Failure	500				{object}	Response[any]
 //	@Router		/stats/{mode}/admin [get]
 //
@@ -23,6 +23,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/mahendrapaipuri/ceems/pkg/api/base"
 	"github.com/mahendrapaipuri/ceems/pkg/api/db"
+	"github.com/mahendrapaipuri/ceems/pkg/api/db/migrator"
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/prometheus/exporter-toolkit/web"
 	"github.com/stretchr/testify/assert"
@@ -68,6 +69,9 @@ var (
 		{ClusterID: "slurm-0", ResourceManager: "slurm", NumUnits: 10, NumInActiveUnits: 2, NumActiveUnits: 8},
 		{ClusterID: "os-0", ResourceManager: "openstack", NumUnits: 10, NumInActiveUnits: 8, NumActiveUnits: 2},
 	}
+	mockAnnotations = []models.UnitAnnotation{
+		{ClusterID: "slurm-0", UUID: "1000", User: "foousr", Key: "run", Value: "benchmark"},
+	}
 	mockKeys = []models.Key{
 		{Name: "global"},
 	}
@@ -95,16 +99,65 @@ func setupServer(d string) *CEEMSServer {
 	)
 	server.maxQueryPeriod = time.Hour * 168
 	server.queriers = queriers{
-		unit:      unitQuerier,
-		usage:     usageQuerier,
-		project:   projectQuerier,
-		user:      userQuerier,
-		adminUser: adminUserQuerier,
-		cluster:   clusterQuerier,
-		stat:      statQuerier,
-		key:       keyQuerier,
+		unit:        unitQuerier,
+		usage:       usageQuerier,
+		project:     projectQuerier,
+		user:        userQuerier,
+		adminUser:   adminUserQuerier,
+		cluster:     clusterQuerier,
+		stat:        statQuerier,
+		key:         keyQuerier,
+		annotation:  annotationQuerier,
+		unitStream:  unitStreamer,
+		usageStream: usageStreamer,
+	}
+
+	return server
+}
+
+// setupServerWithDB returns a server backed by a real DB with a few units.
+func setupServerWithDB(t *testing.T) *CEEMSServer {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Create DB and apply migrations
+	dbConn, err := sql.Open("sqlite3", filepath.Join(tmpDir, base.CEEMSDBName))
+	require.NoError(t, err)
+
+	m, err := migrator.New(db.MigrationsFS, "migrations", logger)
+	require.NoError(t, err)
+	require.NoError(t, m.ApplyMigrations(dbConn))
+
+	// Insert test data
+	for _, stmt := range []string{
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1000','job','foo','grp','foousr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1001','job','foo','grp','barusr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1002','job','baz','grp','bazusr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-0','slurm','foo','["foousr","barusr"]','2024-10-10T10:00:00')`,
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-0','slurm','baz','["bazusr"]','2024-10-10T10:00:00')`,
+	} {
+		_, err = dbConn.Exec(stmt)
+		require.NoError(t, err)
 	}
 
+	require.NoError(t, dbConn.Close())
+
+	server := setupServer(tmpDir)
+	server.queriers.unit = Querier[models.Unit]
+	server.queriers.user = Querier[models.User]
+	server.queriers.project = Querier[models.Project]
+	server.queriers.annotation = Querier[models.UnitAnnotation]
+	server.queriers.apiToken = Querier[models.APIToken]
+	server.queriers.key = Querier[models.Key]
+	server.queriers.unitStream = Streamer[models.Unit]
+	server.queriers.usageStream = Streamer[models.Usage]
+
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+	})
+
 	return server
 }
 
@@ -136,6 +189,18 @@ func statQuerier(ctx context.Context, db *sql.DB, q Query, logger *slog.Logger)
 	return mockStats, nil
 }
 
+func unitStreamer(ctx context.Context, db *sql.DB, q Query, logger *slog.Logger, handler func(models.Unit) error) error {
+	return sliceRows(mockServerUnits)(handler)
+}
+
+func usageStreamer(ctx context.Context, db *sql.DB, q Query, logger *slog.Logger, handler func(models.Usage) error) error {
+	return sliceRows(mockServerUsage)(handler)
+}
+
+func annotationQuerier(ctx context.Context, db *sql.DB, q Query, logger *slog.Logger) ([]models.UnitAnnotation, error) {
+	return mockAnnotations, nil
+}
+
 func keyQuerier(ctx context.Context, db *sql.DB, q Query, logger *slog.Logger) ([]models.Key, error) {
 	return mockKeys, nil
 }
@@ -963,6 +1028,82 @@ func TestUnitsHandlerWithUnituuidsQueryParams(t *testing.T) {
 	assert.Equal(t, expectedUnits, response.Data)
 }
 
+func TestHandlersWithProjectManagers(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Make foousr manager of project baz on slurm-0 only. Project with same name
+	// on slurm-1 must not be accessible to foousr
+	for _, stmt := range []string{
+		`UPDATE projects SET managers = '["foousr"]' WHERE name = 'baz' AND cluster_id = 'slurm-0'`,
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-1','1003','job','baz','grp','quxusr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+		`INSERT INTO projects (uid,cluster_id,resource_manager,name,users,last_updated_at) VALUES ('','slurm-1','slurm','baz','["quxusr"]','2024-10-10T10:00:00')`,
+	} {
+		_, err := server.writeDB.Exec(stmt)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name  string
+		user  string
+		uuids []string
+	}{
+		{
+			name:  "manager gets own units and units of managed projects",
+			user:  "foousr",
+			uuids: []string{"1000", "1002"},
+		},
+		{
+			name:  "member gets only own units",
+			user:  "barusr",
+			uuids: []string{"1001"},
+		},
+		{
+			name:  "member of project with same name on other cluster gets only own units",
+			user:  "quxusr",
+			uuids: []string{"1003"},
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/units?from=1728500000&to=1728600000", nil)
+		request.Header.Set(base.LoggedUserHeader, test.user)
+
+		w := httptest.NewRecorder()
+		server.units(w, request)
+		require.Equal(t, 200, w.Code, test.name)
+
+		var response Response[models.Unit]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), test.name)
+
+		var uuids []string
+		for _, unit := range response.Data {
+			uuids = append(uuids, unit.UUID)
+		}
+
+		assert.ElementsMatch(t, test.uuids, uuids, test.name)
+	}
+
+	// Managed projects must be returned in projects of manager
+	request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/projects", nil)
+	request.Header.Set(base.LoggedUserHeader, "foousr")
+
+	w := httptest.NewRecorder()
+	server.projects(w, request)
+	require.Equal(t, 200, w.Code)
+
+	var response Response[models.Project]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	assert.Equal(t, "baz", response.Data[0].Name)
+	assert.Equal(t, models.List{"foousr"}, response.Data[0].Managers)
+	assert.Equal(t, "foo", response.Data[1].Name)
+
+	// Manager must be able to verify ownership of units of managed projects
+	assert.True(t, VerifyOwnership(context.Background(), "foousr", []string{"slurm-0"}, []string{"1001", "1002"}, nil, server.db, server.logger))
+	assert.False(t, VerifyOwnership(context.Background(), "bazusr", []string{"slurm-0"}, []string{"1000"}, nil, server.db, server.logger))
+	assert.False(t, VerifyOwnership(context.Background(), "foousr", []string{"slurm-1"}, []string{"1003"}, nil, server.db, server.logger))
+}
+
 // // Test /usage
 // func TestUsageHandler(t *testing.T) {
 // 	server := setupServer()
@@ -0,0 +1,372 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+)
+
+const (
+	// Name of the server-sent event that carries updated units.
+	unitsEventName = "units"
+)
+
+// Interval at which keep alive comments are sent on streams to prevent
+// proxies from closing idle connections.
+var streamKeepAliveInterval = 30 * time.Second
+
+// broadcaster notifies subscribers when new data is available in the DB.
+type broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+	done        chan struct{}
+	closed      bool
+}
+
+// newBroadcaster returns a new instance of broadcaster.
+func newBroadcaster() *broadcaster {
+	return &broadcaster{
+		subscribers: make(map[chan struct{}]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// subscribe returns a channel that receives notifications and a function to
+// unsubscribe.
+func (b *broadcaster) subscribe() (<-chan struct{}, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Buffer of one is enough as a pending notification already means that
+	// subscriber will check for new data
+	ch := make(chan struct{}, 1)
+	b.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers, ch)
+	}
+}
+
+// notify notifies all subscribers without blocking.
+func (b *broadcaster) notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// close signals subscribers to stop.
+func (b *broadcaster) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.closed {
+		close(b.done)
+		b.closed = true
+	}
+}
+
+// NotifyUnitsUpdate notifies units streams that units have been updated in DB.
+func (s *CEEMSServer) NotifyUnitsUpdate() {
+	s.unitUpdates.notify()
+}
+
+// parseLastEventID validates the event ID and returns it in DB time location. Event
+// IDs are last_updated_at timestamps of units.
+func (s *CEEMSServer) parseLastEventID(id string) (string, error) {
+	t, err := time.ParseInLocation(base.DatetimeLayout, id, s.dbConfig.Data.Timezone.Location)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid last event ID %s", errInvalidRequest, id)
+	}
+
+	return t.Format(base.DatetimeLayout), nil
+}
+
+// latestUnitsUpdate returns the last_updated_at of most recently updated unit.
+func (s *CEEMSServer) latestUnitsUpdate(ctx context.Context) (string, error) {
+	var latest sql.NullString
+
+	if err := s.db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(last_updated_at) FROM %s", base.UnitsDBTableName)).Scan(&latest); err != nil {
+		return "", err
+	}
+
+	return latest.String, nil
+}
+
+// updatedUnits returns units that are updated after cursor grouped by their
+// last_updated_at timestamps in ascending order.
+func (s *CEEMSServer) updatedUnits(
+	ctx context.Context,
+	queriedUsers []string,
+	managers []string,
+	queriedFields []string,
+	cursor string,
+	r *http.Request,
+) ([][]models.Unit, error) {
+	// last_updated_at is needed to group units
+	selectFields := queriedFields
+	if !slices.Contains(selectFields, "last_updated_at") {
+		selectFields = append([]string{"last_updated_at"}, queriedFields...)
+	}
+
+	// Initialise query builder
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(selectFields, ","), base.UnitsDBTableName))
+
+	// Annotations are stored in a separate table. Join them when requested
+	if slices.Contains(queriedFields, "annotations") {
+		q.query(" " + annotationsJoinQuery)
+	}
+
+	// Query for only unignored units that are updated after cursor
+	q.query(" WHERE ignore = 0 AND last_updated_at > ")
+	q.param([]string{cursor})
+
+	// Add condition to query only for current user and projects managed by the user
+	if len(queriedUsers) > 0 {
+		q.query(" AND (username IN ")
+		q.param(queriedUsers)
+
+		if len(managers) > 0 {
+			q.query(" OR (cluster_id, project) IN ")
+			q.subQuery(managedProjectsSubQuery(managers))
+		}
+
+		q.query(")")
+	}
+
+	// Add common query parameters
+	q = s.getCommonQueryParams(&q, r.URL.Query())
+
+	if uuids := r.URL.Query()["uuid"]; len(uuids) > 0 {
+		q.query(" AND uuid IN ")
+		q.param(uuids)
+	}
+
+	q.query(" ORDER BY last_updated_at ASC, cluster_id ASC, uuid ASC ")
+
+	units, err := s.queriers.unit(ctx, s.db, q, s.logger)
+	if units == nil && err != nil {
+		return nil, err
+	}
+
+	// Convert times to time zone provided in the query
+	units = s.inTargetTimeLocation(r.URL.Query().Get("timezone"), units)
+
+	// Group units by update
+	var updates [][]models.Unit
+
+	for i, unit := range units {
+		if i == 0 || unit.LastUpdatedAt != units[i-1].LastUpdatedAt {
+			updates = append(updates, nil)
+		}
+
+		updates[len(updates)-1] = append(updates[len(updates)-1], unit)
+	}
+
+	return updates, err
+}
+
+// unitsStreamer streams units that are updated in DB as server-sent events.
+func (s *CEEMSServer) unitsStreamer(
+	queriedUsers []string,
+	managers []string,
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Get fields query parameters if any
+	queriedFields := s.getQueriedFields(r.URL.Query(), base.UnitsDBTableColNames)
+	if len(queriedFields) == 0 {
+		s.logger.Error("Invalid query fields", "logged_user", loggedUser, "err", errInvalidQueryField)
+		errorResponse[any](w, &apiError{errorBadData, errInvalidQueryField}, s.logger, nil)
+
+		return
+	}
+
+	// Resume from Last-Event-ID when present. Browsers set the header when
+	// reconnecting and query parameter can be used for initial connection
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("last_event_id")
+	}
+
+	var err error
+
+	if cursor != "" {
+		if cursor, err = s.parseLastEventID(cursor); err != nil {
+			errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+			return
+		}
+	} else if cursor, err = s.latestUnitsUpdate(r.Context()); err != nil {
+		s.logger.Error("Failed to fetch last update of units", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Response controller to flush events and to disable write deadline
+	// of server for this long lived request
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.Error("Failed to disable write deadline", "err", err)
+	}
+
+	// Subscribe to updates before sending backlog so that no update is missed
+	updates, unsubscribe := s.unitUpdates.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if err := rc.Flush(); err != nil {
+		s.logger.Error("Failed to flush units stream", "logged_user", loggedUser, "err", err)
+
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	// Send units updated after cursor. Units of each update are sent as a single
+	// event so that clients can resume from any event ID
+	send := func() error {
+		batches, err := s.updatedUnits(r.Context(), queriedUsers, managers, queriedFields, cursor, r)
+		if err != nil {
+			s.logger.Error("Failed to fetch updated units", "logged_user", loggedUser, "err", err)
+
+			if batches == nil {
+				// Do not close the stream on transient DB errors
+				return nil
+			}
+		}
+
+		for _, units := range batches {
+			data, err := json.Marshal(units)
+			if err != nil {
+				return err
+			}
+
+			cursor = units[0].LastUpdatedAt
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", cursor, unitsEventName, data); err != nil {
+				return err
+			}
+		}
+
+		return rc.Flush()
+	}
+
+	if err := send(); err != nil {
+		s.logger.Debug("Units stream closed", "logged_user", loggedUser, "err", err)
+
+		return
+	}
+
+	for {
+		select {
+		case <-updates:
+			if err := send(); err != nil {
+				s.logger.Debug("Units stream closed", "logged_user", loggedUser, "err", err)
+
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case <-s.unitUpdates.done:
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// unitsStream   godoc
+//
+//	@Summary		Stream of compute unit updates
+//	@Description	This endpoint streams compute units that are inserted or updated in the DB as
+//	@Description	server-sent events. The current user is always identified by the header
+//	@Description	`X-Grafana-User` in the request.
+//	@Description
+//	@Description	For regular users, compute units of the current user and of the projects managed
+//	@Description	by the current user are streamed. For admin users, compute units of all users are
+//	@Description	streamed and they can be filtered using `user` query parameter.
+//	@Description
+//	@Description	After each update of the DB, an event named `units` is sent with the list of
+//	@Description	updated compute units as data. The ID of the event is the timestamp of the update
+//	@Description	in the time zone of the DB. When `Last-Event-ID` header or `last_event_id` query
+//	@Description	parameter is present, compute units updated after that timestamp are sent
+//	@Description	immediately which allows clients to resume the stream without missing any updates.
+//	@Description
+//	@Description	To limit the number of fields in the events, use `field` query parameter. By default,
+//	@Description	all fields will be included in the events if they are _non-empty_.
+//	@Security		BasicAuth
+//	@Tags			units
+//	@Produce		text/event-stream
+//	@Param			X-Grafana-User	header		string		true	"Current user name"
+//	@Param			Last-Event-ID	header		string		false	"ID of last received event"
+//	@Param			cluster_id		query		[]string	false	"Cluster ID"	collectionFormat(multi)
+//	@Param			uuid			query		[]string	false	"Unit UUID"		collectionFormat(multi)
+//	@Param			project			query		[]string	false	"Project"		collectionFormat(multi)
+//	@Param			user			query		[]string	false	"User name. Only for admin users"	collectionFormat(multi)
+//	@Param			last_event_id	query		string		false	"ID of last received event"
+//	@Param			timezone		query		string		false	"Time zone in IANA format"
+//	@Param			field			query		[]string	false	"Fields to return in events"	collectionFormat(multi)
+//	@Success		200				{object}	[]models.Unit
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/units/stream [get]
+//
+// GET /units/stream
+// Stream updated units of dashboard user or of all users for admins.
+func (s *CEEMSServer) unitsStream(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "units stream endpoint", s.logger)
+
+	// Admin users can stream units of any user
+	if r.Header.Get(base.AdminUserHeader) != "" {
+		s.unitsStreamer(r.URL.Query()["user"], nil, w, r)
+
+		return
+	}
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Stream units and write response
+	s.unitsStreamer([]string{loggedUser}, []string{loggedUser}, w, r)
+}
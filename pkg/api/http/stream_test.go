@@ -0,0 +1,210 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sseEvent is a server-sent event.
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent reads next event from stream skipping comments.
+func readEvent(t *testing.T, reader *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+
+		switch {
+		case line == "" && event.event != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// openStream opens units stream as user.
+func openStream(t *testing.T, url string, user string, admin bool, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	req.Header.Set(base.LoggedUserHeader, user)
+
+	if admin {
+		req.Header.Set(base.AdminUserHeader, user)
+	}
+
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// eventUnits returns UUIDs of units in event.
+func eventUnits(t *testing.T, event sseEvent) []string {
+	t.Helper()
+
+	var units []models.Unit
+	require.NoError(t, json.Unmarshal([]byte(event.data), &units))
+
+	uuids := make([]string, len(units))
+	for i, unit := range units {
+		uuids[i] = unit.UUID
+	}
+
+	return uuids
+}
+
+func TestUnitsStream(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	ts := httptest.NewServer(http.HandlerFunc(server.unitsStream))
+	defer ts.Close()
+
+	// Mark units of foo project as updated earlier than units of baz project
+	_, err := server.writeDB.Exec("UPDATE units SET last_updated_at = '2024-10-10T10:00:00' WHERE project = 'foo'")
+	require.NoError(t, err)
+	_, err = server.writeDB.Exec("UPDATE units SET last_updated_at = '2024-10-10T10:30:00' WHERE project = 'baz'")
+	require.NoError(t, err)
+
+	// Resume stream of user and admin from an old event
+	resp, userStream := openStream(t, ts.URL, "foousr", false, "2024-10-10T09:00:00")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	_, adminStream := openStream(t, ts.URL+"?field=uuid", "adm1", true, "2024-10-10T09:00:00")
+
+	event := readEvent(t, userStream)
+	assert.Equal(t, sseEvent{id: "2024-10-10T10:00:00", event: unitsEventName, data: event.data}, event)
+	assert.Equal(t, []string{"1000"}, eventUnits(t, event))
+
+	// Admin must get units of each update in a separate event
+	event = readEvent(t, adminStream)
+	assert.Equal(t, "2024-10-10T10:00:00", event.id)
+	assert.Equal(t, []string{"1000", "1001"}, eventUnits(t, event))
+	assert.Equal(t, `[{"uuid":"1000"},{"uuid":"1001"}]`, event.data)
+
+	event = readEvent(t, adminStream)
+	assert.Equal(t, "2024-10-10T10:30:00", event.id)
+	assert.Equal(t, []string{"1002"}, eventUnits(t, event))
+
+	// Update units and notify streams
+	_, err = server.writeDB.Exec("UPDATE units SET last_updated_at = '2024-10-10T11:00:00' WHERE uuid IN ('1000', '1002')")
+	require.NoError(t, err)
+	server.NotifyUnitsUpdate()
+
+	event = readEvent(t, userStream)
+	assert.Equal(t, "2024-10-10T11:00:00", event.id)
+	assert.Equal(t, []string{"1000"}, eventUnits(t, event))
+
+	event = readEvent(t, adminStream)
+	assert.Equal(t, "2024-10-10T11:00:00", event.id)
+	assert.Equal(t, []string{"1000", "1002"}, eventUnits(t, event))
+
+	// New stream without last event ID must only get future updates
+	_, newStream := openStream(t, ts.URL, "bazusr", false, "")
+
+	_, err = server.writeDB.Exec("UPDATE units SET last_updated_at = '2024-10-10T12:00:00' WHERE uuid = '1002'")
+	require.NoError(t, err)
+	server.NotifyUnitsUpdate()
+
+	event = readEvent(t, newStream)
+	assert.Equal(t, "2024-10-10T12:00:00", event.id)
+	assert.Equal(t, []string{"1002"}, eventUnits(t, event))
+
+	// Streams must be closed on shutdown
+	server.unitUpdates.close()
+
+	_, err = userStream.ReadString('\n')
+	require.Error(t, err)
+}
+
+func TestUnitsStreamInvalidLastEventID(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/units/stream?last_event_id=yesterday", nil)
+	req.Header.Set(base.LoggedUserHeader, "foousr")
+
+	w := httptest.NewRecorder()
+	server.unitsStream(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := newBroadcaster()
+
+	ch1, unsubscribe1 := b.subscribe()
+	ch2, unsubscribe2 := b.subscribe()
+
+	defer unsubscribe2()
+
+	// Multiple notifications must not block
+	b.notify()
+	b.notify()
+
+	for _, ch := range []<-chan struct{}{ch1, ch2} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("notification not received")
+		}
+	}
+
+	// Unsubscribed channels must not receive notifications
+	unsubscribe1()
+	b.notify()
+
+	select {
+	case <-ch1:
+		t.Fatal("notification received after unsubscribe")
+	case <-ch2:
+	case <-time.After(time.Second):
+		t.Fatal("notification not received")
+	}
+
+	// Closing multiple times must not panic
+	b.close()
+	b.close()
+
+	select {
+	case <-b.done:
+	case <-time.After(time.Second):
+		t.Fatal("broadcaster not closed")
+	}
+}
@@ -0,0 +1,448 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/httprate"
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/db"
+	"github.com/prometheus/client_golang/prometheus"
+	promcollectors "github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/collectors/version"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/exporter-toolkit/web"
+	httpSwagger "github.com/swaggo/http-swagger/v2"
+)
+
+// Path prefix used to select tenant in the URL path.
+const tenantsPathPrefix = "/tenants/"
+
+// Custom errors.
+var (
+	errNoTenant       = errors.New("no tenant identified")
+	errUnknownTenant  = errors.New("unknown tenant")
+	errTenantMismatch = errors.New("token does not belong to tenant")
+)
+
+// TenantConfig contains the configuration of a tenant.
+type TenantConfig struct {
+	ID       string         `yaml:"id"`
+	Clusters []string       `yaml:"clusters"`
+	Path     string         `yaml:"path"`
+	Admin    db.AdminConfig `yaml:"admin"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TenantConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = TenantConfig{}
+
+	type plain TenantConfig
+
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	// When admin section is not provided, UnmarshalYAML of admin config is
+	// not called. Ensure CEEMS service account is always an admin of tenant
+	if !slices.Contains(c.Admin.Users, base.CEEMSServiceAccount) {
+		c.Admin.Users = append(c.Admin.Users, base.CEEMSServiceAccount)
+	}
+
+	return nil
+}
+
+// Budgets returns the budgets of clusters of tenant.
+func (c *TenantConfig) Budgets(budgets []BudgetConfig) []BudgetConfig {
+	var tenantBudgets []BudgetConfig
+
+	for _, budget := range budgets {
+		if slices.Contains(c.Clusters, budget.ClusterID) {
+			tenantBudgets = append(tenantBudgets, budget)
+		}
+	}
+
+	return tenantBudgets
+}
+
+// TenantsConfig contains the configuration of tenants of API server.
+type TenantsConfig struct {
+	Header    string         `yaml:"header"`
+	Claim     string         `yaml:"claim"`
+	Instances []TenantConfig `yaml:"instances"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *TenantsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = TenantsConfig{
+		Header: "X-Ceems-Tenant",
+		Claim:  "tenant",
+	}
+
+	type plain TenantsConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Enabled returns true when tenants are configured.
+func (c *TenantsConfig) Enabled() bool {
+	return len(c.Instances) > 0
+}
+
+// Validate validates the config.
+func (c *TenantsConfig) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	if c.Header == "" {
+		return errors.New("tenants header must not be empty")
+	}
+
+	// Tenants share the OIDC config of API server and hence, the claim is
+	// needed to bind tokens to tenants
+	if c.Claim == "" {
+		return errors.New("tenants claim must not be empty")
+	}
+
+	var ids, paths []string
+
+	// Tenant of each cluster
+	clusters := make(map[string]string)
+
+	for _, tenant := range c.Instances {
+		if tenant.ID == "" || base.InvalidIDRegex.MatchString(tenant.ID) {
+			return fmt.Errorf("invalid tenant ID %q. It must contain only [a-zA-Z0-9-_]", tenant.ID)
+		}
+
+		if slices.Contains(ids, tenant.ID) {
+			return fmt.Errorf("duplicate tenant ID %s", tenant.ID)
+		}
+
+		if len(tenant.Clusters) == 0 {
+			return fmt.Errorf("no clusters found for tenant %s", tenant.ID)
+		}
+
+		// Tenants with same data path or cluster would share the DB
+		for _, cluster := range tenant.Clusters {
+			if id, ok := clusters[cluster]; ok {
+				return fmt.Errorf("cluster %s is claimed by tenants %s and %s", cluster, id, tenant.ID)
+			}
+
+			clusters[cluster] = tenant.ID
+		}
+
+		if tenant.Path != "" {
+			path := filepath.Clean(tenant.Path)
+			if slices.Contains(paths, path) {
+				return fmt.Errorf("duplicate path %s of tenant %s", tenant.Path, tenant.ID)
+			}
+
+			paths = append(paths, path)
+		}
+
+		if err := tenant.Admin.Validate(); err != nil {
+			return fmt.Errorf("invalid admin config of tenant %s: %w", tenant.ID, err)
+		}
+
+		ids = append(ids, tenant.ID)
+	}
+
+	return nil
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *TenantsConfig) SetDirectory(dir string) {
+	for i := range c.Instances {
+		c.Instances[i].Admin.SetDirectory(dir)
+	}
+}
+
+// TenantsServer implements HTTP server that routes requests to the CEEMS API
+// servers of tenants.
+type TenantsServer struct {
+	logger      *slog.Logger
+	server      *http.Server
+	webConfig   *web.FlagConfig
+	externalURL *url.URL
+	header      string
+	claim       string
+	tenants     map[string]*CEEMSServer
+	registry    *prometheus.Registry
+}
+
+// NewTenantsServer creates new TenantsServer struct instance. Each tenant is
+// served by its own CEEMS API server with its own DB.
+func NewTenantsServer(c *Config, tenants TenantsConfig, servers map[string]*CEEMSServer) (*TenantsServer, error) {
+	router := mux.NewRouter()
+	server := &TenantsServer{
+		logger: c.Logger,
+		server: &http.Server{
+			Addr:              c.Web.Addresses[0],
+			Handler:           router,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			ReadHeaderTimeout: 2 * time.Second,
+		},
+		externalURL: c.Web.ExternalURL,
+		webConfig: &web.FlagConfig{
+			WebListenAddresses: &c.Web.Addresses,
+			WebSystemdSocket:   &c.Web.WebSystemdSocket,
+			WebConfigFile:      &c.Web.WebConfigFile,
+		},
+		header:   tenants.Header,
+		claim:    tenants.Claim,
+		tenants:  servers,
+		registry: prometheus.NewRegistry(),
+	}
+
+	// Make a landing page from config
+	landingPage, err := web.NewLandingPage(*c.Web.LandingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create landing page: %w", err)
+	}
+
+	// Landing page
+	router.Handle("/", landingPage)
+
+	// pprof debug end points. Expose them only on localhost
+	if c.Web.EnableDebugServer {
+		router.PathPrefix("/debug/").Handler(http.DefaultServeMux).Methods(http.MethodGet).Host("localhost")
+	}
+
+	// Swagger
+	router.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
+		httpSwagger.URL("doc.json"), // The url pointing to API definition
+		httpSwagger.DeepLinking(true),
+		httpSwagger.DocExpansion("list"),
+		httpSwagger.DomID("swagger-ui"),
+	))
+
+	// Setup CORS. Tenant header must be allowed in cross-origin requests
+	cors := newCORS(c.Web.CORSOrigin, append(slices.Clone(c.Web.UserHeaderNames), server.header))
+
+	// Health endpoint
+	router.HandleFunc("/health", cors.wrap(server.health))
+
+	// Metrics endpoint. Usage and budgets metrics of each tenant have a tenant label
+	server.registry.MustRegister(
+		version.NewCollector(base.CEEMSServerAppName),
+		promcollectors.NewProcessCollector(promcollectors.ProcessCollectorOpts{}),
+		promcollectors.NewGoCollector(),
+	)
+
+	for id, s := range server.tenants {
+		prometheus.WrapRegistererWith(prometheus.Labels{"tenant": id}, server.registry).MustRegister(s.collectors...)
+	}
+
+	router.Handle("/metrics", promhttp.HandlerFor(
+		server.registry,
+		promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(c.Logger.Handler(), slog.LevelError),
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	)).Methods(http.MethodGet)
+
+	// All other requests are routed to tenants
+	router.PathPrefix("/").HandlerFunc(cors.wrap(server.route))
+
+	// Rate limit requests by RealIP
+	if c.Web.RequestsLimit > 0 {
+		router.Use(httprate.LimitByRealIP(c.Web.RequestsLimit, time.Minute))
+	}
+
+	return server, nil
+}
+
+// Start launches CEEMS HTTP server of tenants.
+func (s *TenantsServer) Start(_ context.Context) error {
+	setSwaggerInfo(s.server.Addr, s.externalURL)
+
+	s.logger.Info("Starting "+base.CEEMSServerAppName, "tenants", strings.Join(slices.Sorted(maps.Keys(s.tenants)), ","))
+
+	if err := web.ListenAndServe(s.server, s.webConfig, s.logger); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		s.logger.Error("Failed to Listen and Serve HTTP server", "err", err)
+
+		return err
+	}
+
+	return nil
+}
+
+// Handler returns the HTTP handler of server with all the routes and middlewares.
+func (s *TenantsServer) Handler() http.Handler {
+	return s.server.Handler
+}
+
+// Shutdown server.
+func (s *TenantsServer) Shutdown(ctx context.Context) error {
+	var errs error
+
+	// Shutdown servers of tenants first to close their units streams and DB
+	// connections
+	for id, server := range s.tenants {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("tenant %s: %w", id, err))
+		}
+	}
+
+	// Shutdown the server
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.logger.Error("Failed to shutdown HTTP server", "err", err)
+
+		errs = errors.Join(errs, err)
+	}
+
+	return errs
+}
+
+// Check status of servers of all tenants.
+func (s *TenantsServer) health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	for id, server := range s.tenants {
+		if !server.healthCheck(server.db, server.logger) {
+			s.logger.Error("Tenant is unhealthy", "tenant", id)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("KO"))
+
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("CEEMS API Server is healthy"))
+}
+
+// route routes request to the server of tenant.
+func (s *TenantsServer) route(w http.ResponseWriter, r *http.Request) {
+	server, r, err := s.resolveTenant(r)
+	if err != nil {
+		s.logger.Error("Failed to resolve tenant", "url", r.URL, "err", err)
+
+		typ := errorBadData
+
+		switch {
+		case errors.Is(err, errUnknownTenant):
+			typ = errorNotFound
+		case errors.Is(err, errTenantMismatch):
+			typ = errorForbidden
+		}
+
+		errorResponse[any](w, &apiError{typ, err}, s.logger, nil)
+
+		return
+	}
+
+	server.Handler().ServeHTTP(w, r)
+}
+
+// resolveTenant returns the server of tenant of request. Tenant is resolved, in
+// order, from the URL path prefix, tenant header and bearer token. When the path
+// prefix is used, it is stripped from the returned request.
+func (s *TenantsServer) resolveTenant(r *http.Request) (*CEEMSServer, *http.Request, error) {
+	var id string
+
+	if rest, ok := strings.CutPrefix(r.URL.Path, tenantsPathPrefix); ok {
+		var path string
+
+		id, path, _ = strings.Cut(rest, "/")
+
+		// Strip tenant prefix from path
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + path
+		r2.URL.RawPath = ""
+		r = r2
+	} else {
+		id = r.Header.Get(s.header)
+	}
+
+	// When the token carries a tenant, it must be the requested one. Tokens are
+	// verified by the server of tenant
+	token := bearerToken(r)
+	tokenID := s.tokenTenant(token)
+
+	// All tenants share the OIDC config of API server. A JWT without tenant
+	// claim would be accepted by every tenant and hence, it is rejected
+	if tokenID == "" && isJWT(token) {
+		return nil, r, fmt.Errorf("%w: missing %s claim in token", errTenantMismatch, s.claim)
+	}
+
+	switch {
+	case id == "" && tokenID == "":
+		return nil, r, errNoTenant
+	case id == "":
+		id = tokenID
+	case tokenID != "" && tokenID != id:
+		return nil, r, fmt.Errorf("%w %s", errTenantMismatch, id)
+	}
+
+	server, ok := s.tenants[id]
+	if !ok {
+		return nil, r, fmt.Errorf("%w %s", errUnknownTenant, id)
+	}
+
+	return server, r, nil
+}
+
+// tokenTenant returns the tenant embedded in personal API token or in the claim
+// of the bearer token, if any. The token is NOT verified here and so it must only
+// be used to route the request.
+func (s *TenantsServer) tokenTenant(token string) string {
+	if token == "" {
+		return ""
+	}
+
+	// Personal API tokens of tenants have format ceems_<tenant>.<random>
+	if rest, ok := strings.CutPrefix(token, apiTokenPrefix); ok {
+		if id, _, ok := strings.Cut(rest, apiTokenTenantSep); ok {
+			return id
+		}
+
+		return ""
+	}
+
+	if !isJWT(token) || s.claim == "" {
+		return ""
+	}
+
+	parts := strings.Split(token, ".")
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+
+	var claims jwtClaims
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return ""
+	}
+
+	id, _ := claims[s.claim].(string)
+
+	return id
+}
+
+// isJWT returns true if token has the format of a JWT.
+func isJWT(token string) bool {
+	return !strings.HasPrefix(token, apiTokenPrefix) && strings.Count(token, ".") == 2
+}
@@ -0,0 +1,286 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/exporter-toolkit/web"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTenantsServer returns a server of two tenants where tenant inst-b has
+// one more unit than inst-a.
+func setupTenantsServer(t *testing.T) *TenantsServer {
+	t.Helper()
+
+	servers := map[string]*CEEMSServer{
+		"inst-a": setupServerWithDB(t),
+		"inst-b": setupServerWithDB(t),
+	}
+
+	_, err := servers["inst-b"].writeDB.Exec(
+		`INSERT INTO units (resource_manager,cluster_id,uuid,name,project,groupname,username,created_at,started_at,ended_at,created_at_ts,started_at_ts,ended_at_ts,elapsed,state,ignore,num_updates,last_updated_at) VALUES ('slurm','slurm-0','1003','job','foo','grp','foousr','2024-10-10T09:00:00+0000','2024-10-10T09:00:00+0000','2024-10-10T10:00:00+0000',1728550800000,1728550800000,1728554400000,'01:00:00','COMPLETED',0,1,'2024-10-10T10:00:00+0000')`,
+	)
+	require.NoError(t, err)
+
+	for id, server := range servers {
+		server.tenant = id
+	}
+
+	server, err := NewTenantsServer(
+		&Config{
+			Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			Web: WebConfig{
+				Addresses:       []string{"localhost:9020"}, // dummy address
+				LandingConfig:   &web.LandingConfig{},
+				UserHeaderNames: []string{base.GrafanaUserHeader},
+				CORSOrigin:      regexp.MustCompile("^(?:.*)$"),
+			},
+		},
+		TenantsConfig{Header: "X-Ceems-Tenant", Claim: "tenant"},
+		servers,
+	)
+	require.NoError(t, err)
+
+	return server
+}
+
+// unsignedJWT returns a JWT with given claims and without a valid signature.
+func unsignedJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+
+	b, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	return "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(b) + ".c2ln"
+}
+
+func TestTenantsConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  TenantsConfig
+		wantErr bool
+	}{
+		{
+			name:   "no tenants",
+			config: TenantsConfig{},
+		},
+		{
+			name: "valid tenants",
+			config: TenantsConfig{
+				Header: "X-Ceems-Tenant",
+				Claim:  "tenant",
+				Instances: []TenantConfig{
+					{ID: "inst-a", Clusters: []string{"slurm-0"}},
+					{ID: "inst-b", Clusters: []string{"slurm-1", "os-0"}},
+				},
+			},
+		},
+		{
+			name: "duplicate tenants",
+			config: TenantsConfig{
+				Header: "X-Ceems-Tenant",
+				Instances: []TenantConfig{
+					{ID: "inst-a", Clusters: []string{"slurm-0"}},
+					{ID: "inst-a", Clusters: []string{"slurm-1"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid tenant ID",
+			config: TenantsConfig{
+				Header:    "X-Ceems-Tenant",
+				Instances: []TenantConfig{{ID: "inst/a", Clusters: []string{"slurm-0"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "tenant without clusters",
+			config: TenantsConfig{
+				Header:    "X-Ceems-Tenant",
+				Instances: []TenantConfig{{ID: "inst-a"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cluster of multiple tenants",
+			config: TenantsConfig{
+				Header: "X-Ceems-Tenant",
+				Claim:  "tenant",
+				Instances: []TenantConfig{
+					{ID: "inst-a", Clusters: []string{"slurm-0"}},
+					{ID: "inst-b", Clusters: []string{"slurm-1", "slurm-0"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate paths",
+			config: TenantsConfig{
+				Header: "X-Ceems-Tenant",
+				Claim:  "tenant",
+				Instances: []TenantConfig{
+					{ID: "inst-a", Clusters: []string{"slurm-0"}, Path: "/var/lib/ceems/a"},
+					{ID: "inst-b", Clusters: []string{"slurm-1"}, Path: "/var/lib/ceems/a/"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty claim",
+			config: TenantsConfig{
+				Header:    "X-Ceems-Tenant",
+				Instances: []TenantConfig{{ID: "inst-a", Clusters: []string{"slurm-0"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty header",
+			config: TenantsConfig{
+				Instances: []TenantConfig{{ID: "inst-a", Clusters: []string{"slurm-0"}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		if test.wantErr {
+			assert.Error(t, test.config.Validate(), test.name)
+		} else {
+			assert.NoError(t, test.config.Validate(), test.name)
+		}
+	}
+}
+
+func TestTenantBudgets(t *testing.T) {
+	tenant := TenantConfig{ID: "inst-a", Clusters: []string{"slurm-0"}}
+	budgets := []BudgetConfig{
+		{Name: "foo", ClusterID: "slurm-0", Project: "foo"},
+		{Name: "bar", ClusterID: "slurm-1", Project: "bar"},
+	}
+
+	assert.Equal(t, budgets[:1], tenant.Budgets(budgets))
+}
+
+func TestTenantsServerRouting(t *testing.T) {
+	server := setupTenantsServer(t)
+
+	// Personal API token of inst-b
+	token, _, err := generateAPIToken("inst-b")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "ceems_inst-b."))
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		code    int
+		units   int
+	}{
+		{
+			name:    "tenant from path",
+			path:    "/tenants/inst-a/api/" + base.APIVersion + "/units",
+			headers: map[string]string{base.GrafanaUserHeader: "foousr"},
+			code:    http.StatusOK,
+			units:   1,
+		},
+		{
+			name:    "tenant from header",
+			path:    "/api/" + base.APIVersion + "/units",
+			headers: map[string]string{base.GrafanaUserHeader: "foousr", "X-Ceems-Tenant": "inst-b"},
+			code:    http.StatusOK,
+			units:   2,
+		},
+		{
+			name:    "path takes precedence over header",
+			path:    "/tenants/inst-b/api/" + base.APIVersion + "/units",
+			headers: map[string]string{base.GrafanaUserHeader: "foousr", "X-Ceems-Tenant": "inst-a"},
+			code:    http.StatusOK,
+			units:   2,
+		},
+		{
+			name:    "tenant from token claim",
+			path:    "/api/" + base.APIVersion + "/units",
+			headers: map[string]string{"Authorization": "Bearer " + unsignedJWT(t, map[string]any{"tenant": "inst-b"})},
+			code:    http.StatusUnauthorized, // Token is not verified as OIDC is not configured
+		},
+		{
+			name:    "token of another tenant",
+			path:    "/tenants/inst-a/api/" + base.APIVersion + "/units",
+			headers: map[string]string{"Authorization": "Bearer " + token},
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "claim of another tenant",
+			path:    "/api/" + base.APIVersion + "/units",
+			headers: map[string]string{"X-Ceems-Tenant": "inst-a", "Authorization": "Bearer " + unsignedJWT(t, map[string]any{"tenant": "inst-b"})},
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "token without tenant claim",
+			path:    "/api/" + base.APIVersion + "/units",
+			headers: map[string]string{"X-Ceems-Tenant": "inst-a", "Authorization": "Bearer " + unsignedJWT(t, map[string]any{"sub": "foousr"})},
+			code:    http.StatusForbidden,
+		},
+		{
+			name:    "unknown tenant",
+			path:    "/tenants/inst-c/api/" + base.APIVersion + "/units",
+			headers: map[string]string{base.GrafanaUserHeader: "foousr"},
+			code:    http.StatusNotFound,
+		},
+		{
+			name:    "no tenant",
+			path:    "/api/" + base.APIVersion + "/units",
+			headers: map[string]string{base.GrafanaUserHeader: "foousr"},
+			code:    http.StatusBadRequest,
+		},
+		{
+			name: "health of all tenants",
+			path: "/health",
+			code: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, test.path+"?from=1728500000&to=1728600000", nil)
+		for k, v := range test.headers {
+			request.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		server.Handler().ServeHTTP(w, request)
+
+		require.Equal(t, test.code, w.Code, test.name)
+
+		if test.units > 0 {
+			var response Response[models.Unit]
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&response), test.name)
+			assert.Len(t, response.Data, test.units, test.name)
+		}
+	}
+}
+
+func TestTokenTenant(t *testing.T) {
+	server := &TenantsServer{claim: "tenant"}
+
+	assert.Equal(t, "inst-a", server.tokenTenant("ceems_inst-a.abc-_def"))
+	assert.Empty(t, server.tokenTenant("ceems_abc-_def"))
+	assert.Equal(t, "inst-b", server.tokenTenant(unsignedJWT(t, map[string]any{"tenant": "inst-b"})))
+	assert.Empty(t, server.tokenTenant(unsignedJWT(t, map[string]any{"sub": "foo"})))
+	assert.Empty(t, server.tokenTenant("malformed"))
+	assert.Empty(t, server.tokenTenant(""))
+}
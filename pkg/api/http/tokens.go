@@ -0,0 +1,454 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/model"
+)
+
+// Scopes of personal API tokens.
+const (
+	scopeUnitsRead = "units:read" // Read own units and their annotations
+	scopeUsageRead = "usage:read" // Read usage of own projects
+)
+
+// Personal API token settings.
+const (
+	apiTokenPrefix           = "ceems_"
+	apiTokenTenantSep        = "." // Separates tenant ID from random part of token
+	defaultAPITokenExpiry    = 90 * 24 * time.Hour
+	maxAPITokenExpiry        = 365 * 24 * time.Hour
+	maxAPITokensPerUser      = 50
+	maxAPITokenNameLen       = 64
+	maxAPITokenBodySize      = 4 * 1024 // 4 KiB
+	apiTokenLastUsedInterval = time.Minute
+)
+
+// All supported scopes.
+var apiTokenScopes = []string{scopeUnitsRead, scopeUsageRead}
+
+// generateAPIToken returns a new random token with its hash. When tenant is not
+// empty, it is embedded in the token so that requests can be routed to the tenant
+// using the token alone.
+func generateAPIToken(tenant string) (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	if tenant != "" {
+		token = apiTokenPrefix + tenant + apiTokenTenantSep + base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	return token, hashAPIToken(token), nil
+}
+
+// hashAPIToken returns SHA256 hash of token. As tokens are random with
+// 256 bits of entropy, a salted slow hash is not needed.
+func hashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(h[:])
+}
+
+// apiTokenScope returns the scope needed to access the resource using a personal
+// API token. An empty scope means the resource cannot be accessed with tokens.
+func apiTokenScope(method string, resource string) string {
+	if method != http.MethodGet {
+		return ""
+	}
+
+	parts := strings.Split(strings.Trim(resource, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == unitsResourceName:
+		return scopeUnitsRead
+	case len(parts) == 2 && parts[0] == unitsResourceName && (parts[1] == "verify" || parts[1] == "stream"):
+		return scopeUnitsRead
+	case len(parts) == 3 && parts[0] == unitsResourceName && parts[2] == annotationsResourceName:
+		return scopeUnitsRead
+	case len(parts) == 2 && parts[0] == usageResourceName && (parts[1] == currentUsage || parts[1] == globalUsage):
+		return scopeUsageRead
+	case len(parts) == 1 && parts[0] == budgetsResourceName:
+		return scopeUsageRead
+	case len(parts) == 3 && parts[0] == reportsResourceName && parts[1] == unitsResourceName:
+		return scopeUnitsRead
+	case len(parts) == 3 && parts[0] == reportsResourceName && parts[1] == projectsResourceName:
+		return scopeUsageRead
+	default:
+		return ""
+	}
+}
+
+// userAPITokens returns all the tokens of user.
+func (s *CEEMSServer) userAPITokens(ctx context.Context, user string) ([]models.APIToken, error) {
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(base.APITokensDBTableColNames, ","), base.APITokensDBTableName))
+	q.query(" WHERE username IN ")
+	q.param([]string{user})
+	q.query(" ORDER BY id ASC ")
+
+	return s.queriers.apiToken(ctx, s.db, q, s.logger)
+}
+
+// apiTokenExpired returns true if the token is expired at now. Tokens with
+// invalid expiry time are considered as expired.
+func apiTokenExpired(t models.APIToken, now time.Time) bool {
+	expiresAt, err := time.Parse(base.DatetimezoneLayout, t.ExpiresAt)
+
+	return err != nil || now.After(expiresAt)
+}
+
+// authenticateAPIToken returns the owner and scopes of the token.
+func (s *CEEMSServer) authenticateAPIToken(ctx context.Context, token string) (string, []string, error) {
+	q := Query{}
+	q.query(fmt.Sprintf("SELECT id,%s FROM %s", strings.Join(base.APITokensDBTableColNames, ","), base.APITokensDBTableName))
+	q.query(" WHERE hash IN ")
+	q.param([]string{hashAPIToken(token)})
+
+	tokens, err := s.queriers.apiToken(ctx, s.db, q, s.logger)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch token: %w", err)
+	}
+
+	if len(tokens) == 0 {
+		return "", nil, fmt.Errorf("%w: unknown token", errInvalidToken)
+	}
+
+	t := tokens[0]
+	now := time.Now()
+
+	if apiTokenExpired(t, now) {
+		return "", nil, fmt.Errorf("%w: token expired", errInvalidToken)
+	}
+
+	// Update last used time. To avoid a DB write on every request, it is updated
+	// only once in apiTokenLastUsedInterval
+	if lastUsedAt, err := time.Parse(base.DatetimezoneLayout, t.LastUsedAt); err != nil || now.Sub(lastUsedAt) > apiTokenLastUsedInterval {
+		if _, err := s.writeDB.ExecContext(
+			ctx, fmt.Sprintf("UPDATE %s SET last_used_at = ? WHERE id = ?", base.APITokensDBTableName), s.currentTime(), t.ID,
+		); err != nil {
+			s.logger.Warn("Failed to update last used time of token", "token_id", t.TokenID, "err", err)
+		}
+	}
+
+	scopes := make([]string, 0, len(t.Scopes))
+	for _, scope := range t.Scopes {
+		if s, ok := scope.(string); ok {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return t.User, scopes, nil
+}
+
+// decodeTokenRequest decodes and validates the request to create token.
+func decodeTokenRequest(w http.ResponseWriter, r *http.Request) (models.APITokenRequest, time.Duration, error) {
+	var req models.APITokenRequest
+
+	// Limit request body size
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPITokenBodySize)
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return req, 0, fmt.Errorf("%w: %w", errInvalidRequest, err)
+	}
+
+	if req.Name = strings.TrimSpace(req.Name); req.Name == "" || len(req.Name) > maxAPITokenNameLen {
+		return req, 0, fmt.Errorf("%w: name must be between 1 and %d characters", errInvalidRequest, maxAPITokenNameLen)
+	}
+
+	if len(req.Scopes) == 0 {
+		return req, 0, fmt.Errorf("%w: at least one scope must be provided", errInvalidRequest)
+	}
+
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiTokenScopes, scope) {
+			return req, 0, fmt.Errorf("%w: unknown scope %s", errInvalidRequest, scope)
+		}
+	}
+
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	// Default expiry when not provided
+	if req.ExpiresIn == "" {
+		return req, defaultAPITokenExpiry, nil
+	}
+
+	expiresIn, err := model.ParseDuration(req.ExpiresIn)
+	if err != nil {
+		return req, 0, fmt.Errorf("%w: %w", errInvalidRequest, err)
+	}
+
+	if expiresIn <= 0 || time.Duration(expiresIn) > maxAPITokenExpiry {
+		return req, 0, fmt.Errorf("%w: expires_in must be positive and at most %s", errInvalidRequest, model.Duration(maxAPITokenExpiry))
+	}
+
+	return req, time.Duration(expiresIn), nil
+}
+
+// listTokens godoc
+//
+//	@Summary		List personal API tokens
+//	@Description	This endpoint will return all the personal API tokens of the current user.
+//	@Description	The current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	Tokens themselves are never returned by this endpoint.
+//	@Security		BasicAuth
+//	@Tags			tokens
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string	true	"Current user name"
+//	@Success		200				{object}	Response[models.APIToken]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/tokens [get]
+//
+// GET /tokens
+// List personal API tokens of current user.
+func (s *CEEMSServer) listTokens(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "list tokens endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Set headers
+	s.setHeaders(w)
+
+	tokens, err := s.userAPITokens(r.Context(), loggedUser)
+	if tokens == nil && err != nil {
+		s.logger.Error("Failed to fetch tokens", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Write response
+	w.WriteHeader(http.StatusOK)
+
+	response := Response[models.APIToken]{
+		Status: "success",
+		Data:   tokens,
+	}
+	if err != nil {
+		response.Warnings = append(response.Warnings, err.Error())
+	}
+
+	if err = json.NewEncoder(w).Encode(&response); err != nil {
+		s.logger.Error("Failed to encode response", "err", err)
+		w.Write([]byte("KO"))
+	}
+}
+
+// createToken godoc
+//
+//	@Summary		Create a personal API token
+//	@Description	This endpoint will create a new personal API token for the current user.
+//	@Description	The current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	Personal API tokens can be used in `Authorization: Bearer <token>` header to
+//	@Description	make requests on behalf of the user, for instance from CI pipelines. The
+//	@Description	scopes of the token restrict the endpoints that can be accessed:
+//	@Description	- `units:read`: Read own compute units and their annotations
+//	@Description	- `usage:read`: Read usage statistics and budgets of own projects
+//	@Description
+//	@Description	Tokens never grant admin privileges and they cannot be used to manage tokens.
+//	@Description	If `expires_in` is not provided, tokens expire in 90 days. Maximum validity
+//	@Description	of a token is 1 year.
+//	@Description
+//	@Description	The token is returned only once in the response and it cannot be retrieved
+//	@Description	later.
+//	@Security		BasicAuth
+//	@Tags			tokens
+//	@Accept			json
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string				true	"Current user name"
+//	@Param			token			body		models.APITokenRequest	true	"Token"
+//	@Success		201				{object}	Response[models.CreatedAPIToken]
+//	@Failure		400				{object}	Response[any]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		409				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/tokens [post]
+//
+// POST /tokens
+// Create a personal API token for current user.
+func (s *CEEMSServer) createToken(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "create token endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Set headers
+	s.setHeaders(w)
+
+	req, expiresIn, err := decodeTokenRequest(w, r)
+	if err != nil {
+		errorResponse[any](w, &apiError{errorBadData, err}, s.logger, nil)
+
+		return
+	}
+
+	// Check number of active tokens of user
+	tokens, err := s.userAPITokens(r.Context(), loggedUser)
+	if err != nil {
+		s.logger.Error("Failed to fetch tokens", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	// Expired tokens do not count towards the limit
+	now := time.Now()
+
+	active := slices.DeleteFunc(tokens, func(t models.APIToken) bool {
+		return apiTokenExpired(t, now)
+	})
+
+	if len(active) >= maxAPITokensPerUser {
+		errorResponse[any](w, &apiError{errorConflict, errTooManyTokens}, s.logger, nil)
+
+		return
+	}
+
+	// Generate token
+	token, hash, err := generateAPIToken(s.tenant)
+	if err != nil {
+		s.logger.Error("Failed to generate token", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	tokenID := make([]byte, 8)
+	if _, err := rand.Read(tokenID); err != nil {
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	now = now.In(s.dbConfig.Data.Timezone.Location)
+	created := models.CreatedAPIToken{
+		APIToken: models.APIToken{
+			TokenID:   hex.EncodeToString(tokenID),
+			User:      loggedUser,
+			Name:      req.Name,
+			Hash:      hash,
+			CreatedAt: now.Format(base.DatetimezoneLayout),
+			ExpiresAt: now.Add(expiresIn).Format(base.DatetimezoneLayout),
+		},
+		Token: token,
+	}
+
+	for _, scope := range req.Scopes {
+		created.Scopes = append(created.Scopes, scope)
+	}
+
+	if _, err := s.writeDB.ExecContext(
+		r.Context(),
+		fmt.Sprintf(
+			"INSERT INTO %s (token_id,username,name,hash,scopes,created_at,expires_at) VALUES (?,?,?,?,?,?,?)",
+			base.APITokensDBTableName,
+		),
+		created.TokenID, created.User, created.Name, created.Hash, created.Scopes, created.CreatedAt, created.ExpiresAt,
+	); err != nil {
+		s.logger.Error("Failed to insert token", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	s.logger.Info("Personal API token created", "logged_user", loggedUser, "token_id", created.TokenID, "scopes", req.Scopes)
+
+	// Write response
+	w.WriteHeader(http.StatusCreated)
+
+	response := Response[models.CreatedAPIToken]{
+		Status: "success",
+		Data:   []models.CreatedAPIToken{created},
+	}
+
+	if err = json.NewEncoder(w).Encode(&response); err != nil {
+		s.logger.Error("Failed to encode response", "err", err)
+		w.Write([]byte("KO"))
+	}
+}
+
+// revokeToken godoc
+//
+//	@Summary		Revoke a personal API token
+//	@Description	This endpoint will revoke a personal API token of the current user. The
+//	@Description	current user is always identified by the header `X-Grafana-User` in
+//	@Description	the request.
+//	@Description
+//	@Description	The response contains the remaining tokens of the current user.
+//	@Security		BasicAuth
+//	@Tags			tokens
+//	@Produce		json
+//	@Param			X-Grafana-User	header		string	true	"Current user name"
+//	@Param			id				path		string	true	"Token ID"
+//	@Success		200				{object}	Response[models.APIToken]
+//	@Failure		401				{object}	Response[any]
+//	@Failure		403				{object}	Response[any]
+//	@Failure		404				{object}	Response[any]
+//	@Failure		500				{object}	Response[any]
+//	@Router			/tokens/{id} [delete]
+//
+// DELETE /tokens/{id}
+// Revoke a personal API token of current user.
+func (s *CEEMSServer) revokeToken(w http.ResponseWriter, r *http.Request) {
+	// Measure elapsed time
+	defer common.TimeTrack(time.Now(), "revoke token endpoint", s.logger)
+
+	// Get current logged user from headers
+	loggedUser := s.getUser(r)
+
+	// Set headers
+	s.setHeaders(w)
+
+	// Users can only revoke their own tokens
+	res, err := s.writeDB.ExecContext(
+		r.Context(),
+		fmt.Sprintf("DELETE FROM %s WHERE token_id = ? AND username = ?", base.APITokensDBTableName),
+		mux.Vars(r)["id"], loggedUser,
+	)
+	if err != nil {
+		s.logger.Error("Failed to revoke token", "logged_user", loggedUser, "err", err)
+		errorResponse[any](w, &apiError{errorInternal, err}, s.logger, nil)
+
+		return
+	}
+
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		errorResponse[any](w, &apiError{errorNotFound, errTokenNotFound}, s.logger, nil)
+
+		return
+	}
+
+	s.logger.Info("Personal API token revoked", "logged_user", loggedUser, "token_id", mux.Vars(r)["id"])
+
+	s.listTokens(w, r)
+}
@@ -0,0 +1,363 @@
+//go:build cgo
+// +build cgo
+
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestToken creates a personal API token for user and returns it.
+func createTestToken(t *testing.T, server *CEEMSServer, user string, body string) (int, models.CreatedAPIToken) {
+	t.Helper()
+
+	request := httptest.NewRequest(http.MethodPost, "/api/"+base.APIVersion+"/tokens", strings.NewReader(body))
+	request.Header.Set(base.LoggedUserHeader, user)
+
+	w := httptest.NewRecorder()
+	server.createToken(w, request)
+
+	res := w.Result()
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var response Response[models.CreatedAPIToken]
+	require.NoError(t, json.Unmarshal(data, &response))
+
+	if len(response.Data) == 0 {
+		return w.Code, models.CreatedAPIToken{}
+	}
+
+	return w.Code, response.Data[0]
+}
+
+func TestAPITokenScope(t *testing.T) {
+	tests := []struct {
+		method   string
+		resource string
+		scope    string
+	}{
+		{http.MethodGet, "units", scopeUnitsRead},
+		{http.MethodGet, "units/verify", scopeUnitsRead},
+		{http.MethodGet, "units/stream", scopeUnitsRead},
+		{http.MethodGet, "units/1000/annotations", scopeUnitsRead},
+		{http.MethodPost, "units/1000/annotations", ""},
+		{http.MethodGet, "units/admin", ""},
+		{http.MethodGet, "usage/current", scopeUsageRead},
+		{http.MethodGet, "usage/global", scopeUsageRead},
+		{http.MethodGet, "usage/current/admin", ""},
+		{http.MethodGet, "reports/units/1000", scopeUnitsRead},
+		{http.MethodGet, "reports/projects/foo", scopeUsageRead},
+		{http.MethodGet, "projects", ""},
+		{http.MethodGet, "tokens", ""},
+		{http.MethodDelete, "tokens/abcd", ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.scope, apiTokenScope(test.method, test.resource), test.method+" "+test.resource)
+	}
+}
+
+func TestTokensHandlers(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	// Create tokens
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{
+			name: "valid token",
+			body: `{"name":"ci","scopes":["units:read","usage:read","units:read"],"expires_in":"30d"}`,
+			code: 201,
+		},
+		{
+			name: "token with default expiry",
+			body: `{"name":"notebook","scopes":["usage:read"]}`,
+			code: 201,
+		},
+		{
+			name: "token without name",
+			body: `{"scopes":["units:read"]}`,
+			code: 400,
+		},
+		{
+			name: "token without scopes",
+			body: `{"name":"ci"}`,
+			code: 400,
+		},
+		{
+			name: "token with unknown scope",
+			body: `{"name":"ci","scopes":["units:write"]}`,
+			code: 400,
+		},
+		{
+			name: "token with too long expiry",
+			body: `{"name":"ci","scopes":["units:read"],"expires_in":"2y"}`,
+			code: 400,
+		},
+		{
+			name: "malformed body",
+			body: `{"name":1}`,
+			code: 400,
+		},
+	}
+
+	var created []models.CreatedAPIToken
+
+	for _, test := range tests {
+		code, token := createTestToken(t, server, "foousr", test.body)
+		assert.Equal(t, test.code, code, test.name)
+
+		if code == 201 {
+			assert.True(t, strings.HasPrefix(token.Token, apiTokenPrefix), test.name)
+			assert.Equal(t, "foousr", token.User, test.name)
+			assert.Empty(t, token.Hash, test.name)
+
+			created = append(created, token)
+		}
+	}
+
+	require.Len(t, created, 2)
+	assert.Equal(t, models.List{"units:read", "usage:read"}, created[0].Scopes)
+
+	// Check expiry times
+	for i, d := range []time.Duration{30 * 24 * time.Hour, defaultAPITokenExpiry} {
+		createdAt, err := time.Parse(base.DatetimezoneLayout, created[i].CreatedAt)
+		require.NoError(t, err)
+
+		expiresAt, err := time.Parse(base.DatetimezoneLayout, created[i].ExpiresAt)
+		require.NoError(t, err)
+		assert.Equal(t, d, expiresAt.Sub(createdAt))
+	}
+
+	// Token of another user
+	code, _ := createTestToken(t, server, "barusr", `{"name":"ci","scopes":["units:read"]}`)
+	require.Equal(t, 201, code)
+
+	// List tokens
+	request := httptest.NewRequest(http.MethodGet, "/api/"+base.APIVersion+"/tokens", nil)
+	request.Header.Set(base.LoggedUserHeader, "foousr")
+
+	w := httptest.NewRecorder()
+	server.listTokens(w, request)
+	require.Equal(t, 200, w.Code)
+
+	var response Response[models.APIToken]
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data, 2)
+	assert.Equal(t, created[0].TokenID, response.Data[0].TokenID)
+	assert.Equal(t, "notebook", response.Data[1].Name)
+	assert.NotContains(t, w.Body.String(), created[0].Token)
+
+	// Revoke tokens
+	for _, test := range []struct {
+		name string
+		user string
+		id   string
+		code int
+	}{
+		{name: "revoke token of other user", user: "barusr", id: created[0].TokenID, code: 404},
+		{name: "revoke unknown token", user: "foousr", id: "unknown", code: 404},
+		{name: "revoke own token", user: "foousr", id: created[0].TokenID, code: 200},
+	} {
+		request := httptest.NewRequest(http.MethodDelete, "/api/"+base.APIVersion+"/tokens/"+test.id, nil)
+		request = mux.SetURLVars(request, map[string]string{"id": test.id})
+		request.Header.Set(base.LoggedUserHeader, test.user)
+
+		w := httptest.NewRecorder()
+		server.revokeToken(w, request)
+		assert.Equal(t, test.code, w.Code, test.name)
+	}
+
+	// Revoked token must not authenticate anymore
+	_, _, err := server.authenticateAPIToken(context.Background(), created[0].Token)
+	require.ErrorIs(t, err, errInvalidToken)
+
+	// Other token must still authenticate
+	user, scopes, err := server.authenticateAPIToken(context.Background(), created[1].Token)
+	require.NoError(t, err)
+	assert.Equal(t, "foousr", user)
+	assert.Equal(t, []string{"usage:read"}, scopes)
+}
+
+func TestTokensLimit(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	insertTokens := func(prefix string, expiresAt time.Time) {
+		for i := range maxAPITokensPerUser - 1 {
+			_, err := server.writeDB.Exec(
+				"INSERT INTO "+base.APITokensDBTableName+" (token_id,username,name,hash,scopes,created_at,expires_at) VALUES (?,?,?,?,?,?,?)",
+				fmt.Sprintf("%s-%d", prefix, i), "foousr", "ci", fmt.Sprintf("%s-%d", prefix, i), "[]",
+				expiresAt.Add(-time.Hour).Format(base.DatetimezoneLayout), expiresAt.Format(base.DatetimezoneLayout),
+			)
+			require.NoError(t, err)
+		}
+	}
+
+	// Expired tokens must not count towards the limit
+	insertTokens("expired", time.Now().Add(-time.Hour))
+
+	for range 2 {
+		code, _ := createTestToken(t, server, "foousr", `{"name":"ci","scopes":["units:read"]}`)
+		require.Equal(t, 201, code)
+	}
+
+	// Active tokens must count towards the limit
+	insertTokens("active", time.Now().Add(time.Hour))
+
+	code, _ := createTestToken(t, server, "foousr", `{"name":"ci","scopes":["units:read"]}`)
+	assert.Equal(t, 409, code)
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	server := setupServerWithDB(t)
+
+	code, token := createTestToken(t, server, "foousr", `{"name":"ci","scopes":["units:read"]}`)
+	require.Equal(t, 201, code)
+
+	// Unknown token
+	_, _, err := server.authenticateAPIToken(context.Background(), apiTokenPrefix+"unknown")
+	require.ErrorIs(t, err, errInvalidToken)
+
+	// Valid token must update last used time
+	user, _, err := server.authenticateAPIToken(context.Background(), token.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "foousr", user)
+
+	var lastUsedAt string
+	require.NoError(t, server.writeDB.QueryRow("SELECT last_used_at FROM api_tokens WHERE token_id = ?", token.TokenID).Scan(&lastUsedAt))
+	assert.NotEmpty(t, lastUsedAt)
+
+	// Expired token
+	_, err = server.writeDB.Exec(
+		"UPDATE api_tokens SET expires_at = ? WHERE token_id = ?",
+		time.Now().Add(-time.Minute).Format(base.DatetimezoneLayout), token.TokenID,
+	)
+	require.NoError(t, err)
+
+	_, _, err = server.authenticateAPIToken(context.Background(), token.Token)
+	require.ErrorIs(t, err, errInvalidToken)
+}
+
+func TestMiddlewareWithAPITokens(t *testing.T) {
+	amw, err := newAuthenticationMiddleware("/api/v1/", []string{base.GrafanaUserHeader}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	amw.adminUsers = mockAdminUsers
+	amw.apiTokens = func(_ context.Context, token string) (string, []string, error) {
+		switch token {
+		case apiTokenPrefix + "units":
+			return "foousr", []string{scopeUnitsRead}, nil
+		case apiTokenPrefix + "admin":
+			return "adm1", []string{scopeUnitsRead, scopeUsageRead}, nil
+		default:
+			return "", nil, errInvalidToken
+		}
+	}
+
+	var loggedUser, adminUser string
+
+	handler := amw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loggedUser = r.Header.Get(base.LoggedUserHeader)
+		adminUser = r.Header.Get(base.AdminUserHeader)
+	}))
+
+	tests := []struct {
+		name     string
+		method   string
+		endpoint string
+		token    string
+		header   string
+		code     int
+		user     string
+	}{
+		{
+			name:     "token with scope",
+			endpoint: "/api/v1/units",
+			token:    apiTokenPrefix + "units",
+			header:   "adm1",
+			code:     200,
+			user:     "foousr",
+		},
+		{
+			name:     "token without scope",
+			endpoint: "/api/v1/usage/current",
+			token:    apiTokenPrefix + "units",
+			code:     403,
+		},
+		{
+			name:     "token on write endpoint",
+			method:   http.MethodPost,
+			endpoint: "/api/v1/units/1000/annotations",
+			token:    apiTokenPrefix + "units",
+			code:     403,
+		},
+		{
+			name:     "token on tokens endpoint",
+			endpoint: "/api/v1/tokens",
+			token:    apiTokenPrefix + "units",
+			code:     403,
+		},
+		{
+			name:     "token of admin user does not grant admin privileges",
+			endpoint: "/api/v1/usage/current",
+			token:    apiTokenPrefix + "admin",
+			code:     200,
+			user:     "adm1",
+		},
+		{
+			name:     "token of admin user on admin endpoint",
+			endpoint: "/api/v1/units/admin",
+			token:    apiTokenPrefix + "admin",
+			code:     403,
+		},
+		{
+			name:     "invalid token",
+			endpoint: "/api/v1/units",
+			token:    apiTokenPrefix + "invalid",
+			code:     401,
+		},
+	}
+
+	for _, test := range tests {
+		loggedUser, adminUser = "", ""
+
+		method := http.MethodGet
+		if test.method != "" {
+			method = test.method
+		}
+
+		request := httptest.NewRequest(method, test.endpoint, nil)
+		request.Header.Set("Authorization", "Bearer "+test.token)
+
+		if test.header != "" {
+			request.Header.Set(base.GrafanaUserHeader, test.header)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+
+		assert.Equal(t, test.code, w.Code, test.name)
+		assert.Equal(t, test.user, loggedUser, test.name)
+		assert.Empty(t, adminUser, test.name)
+	}
+}
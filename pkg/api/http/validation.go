@@ -82,14 +82,14 @@ func VerifyOwnership(
 	logger.Debug("UUIDs in query", "user", user, "cluster_id", strings.Join(clusterIDs, ","), "queried_uuids", strings.Join(uuids, ","))
 
 	// Get sub query for projects
-	qSub := projectsSubQuery([]string{user})
+	qSub := projectsCondition("project", []string{user})
 
 	// Make query
 	q := Query{}
 	q.query("SELECT uuid,cluster_id FROM " + base.UnitsDBTableName)
 
 	// Add project sub query
-	q.query(" WHERE project IN ")
+	q.query(" WHERE ")
 	q.subQuery(qSub)
 
 	// Add cluster IDs conditional clause
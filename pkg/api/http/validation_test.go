@@ -60,14 +60,15 @@ CREATE TABLE projects (
 	"id" integer not null primary key,
 	"cluster_id" text,
 	"name" text,
-	"users" text
+	"users" text,
+	"managers" text default '[]'
 );
-INSERT INTO projects VALUES(1, 'rm-0', 'prj1', '["usr1","usr2"]');
-INSERT INTO projects VALUES(2, 'rm-0', 'prj2', '["usr2"]');
-INSERT INTO projects VALUES(3, 'rm-0', 'prj3', '["usr3"]');
-INSERT INTO projects VALUES(4, 'rm-1', 'prj1', '["usr1","usr2"]');
-INSERT INTO projects VALUES(5, 'rm-1', 'prj4', '["usr4"]');
-INSERT INTO projects VALUES(6, 'rm-1', 'prj5', '["usr5"]');
+INSERT INTO projects VALUES(1, 'rm-0', 'prj1', '["usr1","usr2"]', '[]');
+INSERT INTO projects VALUES(2, 'rm-0', 'prj2', '["usr2"]', '[]');
+INSERT INTO projects VALUES(3, 'rm-0', 'prj3', '["usr3"]', '["usr6"]');
+INSERT INTO projects VALUES(4, 'rm-1', 'prj1', '["usr1","usr2"]', '[]');
+INSERT INTO projects VALUES(5, 'rm-1', 'prj4', '["usr4"]', '[]');
+INSERT INTO projects VALUES(6, 'rm-1', 'prj5', '["usr5"]', '[]');
 CREATE TABLE users (
 	"id" integer not null primary key,
 	"cluster_id" text,
@@ -162,6 +163,20 @@ func TestVerifyOwnership(t *testing.T) {
 			user:   "usr1",
 			verify: true,
 		},
+		{
+			name:   "pass due to uuid from managed project",
+			uuids:  []string{"1481510"},
+			rmID:   "rm-0",
+			user:   "usr6",
+			verify: true,
+		},
+		{
+			name:   "forbid due to uuid from project managed by other user",
+			uuids:  []string{"1479765"},
+			rmID:   "rm-0",
+			user:   "usr6",
+			verify: false,
+		},
 		{
 			name:   "pass with correct uuid and start",
 			uuids:  []string{"1481508"},
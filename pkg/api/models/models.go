@@ -6,12 +6,14 @@ import (
 )
 
 const (
-	unitsTableName      = "units"
-	usageTableName      = "usage"
-	dailyUsageTableName = "daily_usage"
-	projectsTableName   = "projects"
-	usersTableName      = "users"
-	adminUsersTableName = "admin_users"
+	unitsTableName       = "units"
+	usageTableName       = "usage"
+	dailyUsageTableName  = "daily_usage"
+	projectsTableName    = "projects"
+	usersTableName       = "users"
+	adminUsersTableName  = "admin_users"
+	annotationsTableName = "annotations"
+	apiTokensTableName   = "api_tokens"
 )
 
 // Unit is an abstract compute unit that can mean Job (batchjobs), VM (cloud) or Pod (k8s).
@@ -47,6 +49,7 @@ type Unit struct {
 	TotalIngressStats   MetricMap  `example:"total:0.5"                                                                                   json:"total_ingress_stats,omitempty"        sql:"total_ingress_stats"                 sqlitetype:"text"    swaggertype:"object,number"` // Total Ingress statistics of unit
 	TotalOutgressStats  MetricMap  `example:"total:0.1"                                                                                   json:"total_outgress_stats,omitempty"       sql:"total_outgress_stats"                sqlitetype:"text"    swaggertype:"object,number"` // Total Outgress statistics of unit
 	Tags                Tag        `example:"uid:1000,gid:1000,workdir:/home/user"                                                        json:"tags,omitempty"                       sql:"tags"                                sqlitetype:"text"    swaggertype:"object,string"` // A map to store generic info. String and int64 are valid value types of map
+	Annotations         Annotation `example:"doi:10.1000/xyz123,run:benchmark"                                                            json:"annotations,omitempty"                sql:"annotations"                         sqlitetype:"text"    swaggertype:"object,string"` // User defined key/value annotations of unit. They are stored separately and are never overwritten by the resource manager
 	Ignore              int        `json:"-"                                                                                              sql:"ignore"                                sqlitetype:"integer"`                                                                       // Whether to ignore unit
 	NumUpdates          int64      `json:"-"                                                                                              sql:"num_updates"                           sqlitetype:"integer"`                                                                       // Number of updates. This is used internally to update aggregate metrics
 	LastUpdatedAt       string     `json:"-"                                                                                              sql:"last_updated_at"                       sqlitetype:"text"`                                                                          // Last updated time. It can be used to clean up DB
@@ -136,6 +139,23 @@ func (s Stat) TagNames(tag string) []string {
 	return structset.StructFieldTagValues(s, tag)
 }
 
+// Budget represents the status of budget of a project for a given resource in current period.
+type Budget struct {
+	Name                    string  `example:"prj1"                     json:"name"`                                 // Name of the budget
+	ClusterID               string  `example:"slurm-0"                  json:"cluster_id"`                           // Identifier of the resource manager that owns project
+	Project                 string  `example:"prj1"                     json:"project"`                              // Account in batch systems, Tenant in Openstack, Namespace in k8s
+	Resource                string  `example:"cpu_hours"                json:"resource"`                             // Budgeted resource. Currently `cpu_hours` and `gpu_hours` are supported
+	PeriodStart             string  `example:"2024-01-01T00:00:00+0100" json:"period_start"`                         // Start of current budget period
+	PeriodEnd               string  `example:"2024-04-01T00:00:00+0200" json:"period_end"`                           // End of current budget period
+	Limit                   float64 `example:"100000"                   json:"limit"`                                // Budget in current period
+	Consumed                float64 `example:"45000"                    json:"consumed"`                             // Consumed budget in current period
+	Remaining               float64 `example:"55000"                    json:"remaining"`                            // Remaining budget in current period
+	UsedRatio               float64 `example:"0.45"                     json:"used_ratio"`                           // Ratio of consumed budget to budget
+	BurnRate                float64 `example:"750"                      json:"burn_rate_per_day"`                    // Average consumption per day during burn rate window
+	ProjectedExhaustionAt   string  `example:"2024-03-15T10:00:00+0100" json:"projected_exhaustion_at,omitempty"`    // Time at which budget is projected to be exhausted at current burn rate
+	ProjectedExhaustionAtTS int64   `example:"1710493200000"            json:"projected_exhaustion_at_ts,omitempty"` // Timestamp at which budget is projected to be exhausted at current burn rate
+}
+
 // TagMap returns a map of tags based on keyTag and valueTag. If keyTag is empty,
 // field names are used as map keys.
 func (s Stat) TagMap(keyTag string, valueTag string) map[string]string {
@@ -144,14 +164,15 @@ func (s Stat) TagMap(keyTag string, valueTag string) map[string]string {
 
 // Project is the container for a given account/tenant/namespace of cluster.
 type Project struct {
-	ID              int64  `example:"1"         json:"-"                sql:"id"               sqlitetype:"integer not null primary key"`
-	UID             string `example:"1000"      json:"uid,omitempty"    sql:"uid"              sqlitetype:"text"`                                                    // Unique identifier of the project provided by cluster
-	ClusterID       string `example:"slurm-0"   json:"cluster_id"       sql:"cluster_id"       sqlitetype:"text"`                                                    // Identifier of the resource manager that owns project. It is used to differentiate multiple clusters of same resource manager.
-	ResourceManager string `example:"slurm"     json:"resource_manager" sql:"resource_manager" sqlitetype:"text"`                                                    // Name of the resource manager that owns project. Eg slurm, openstack, kubernetes, etc
-	Name            string `example:"prj1"      json:"name"             sql:"name"             sqlitetype:"text"`                                                    // Name of the project
-	Users           List   `example:"usr1,usr2" json:"users"            sql:"users"            sqlitetype:"text"                         swaggertype:"array,string"` // List of users of the project
-	Tags            List   `example:"tag1,tag2" json:"tags,omitempty"   sql:"tags"             sqlitetype:"text"                         swaggertype:"array,string"` // List of meta data tags of the project
-	LastUpdatedAt   string `json:"-"            sql:"last_updated_at"   sqlitetype:"text"`                                                                           // Last Updated time
+	ID              int64  `example:"1"         json:"-"                  sql:"id"               sqlitetype:"integer not null primary key"`
+	UID             string `example:"1000"      json:"uid,omitempty"      sql:"uid"              sqlitetype:"text"`                                                    // Unique identifier of the project provided by cluster
+	ClusterID       string `example:"slurm-0"   json:"cluster_id"         sql:"cluster_id"       sqlitetype:"text"`                                                    // Identifier of the resource manager that owns project. It is used to differentiate multiple clusters of same resource manager.
+	ResourceManager string `example:"slurm"     json:"resource_manager"   sql:"resource_manager" sqlitetype:"text"`                                                    // Name of the resource manager that owns project. Eg slurm, openstack, kubernetes, etc
+	Name            string `example:"prj1"      json:"name"               sql:"name"             sqlitetype:"text"`                                                    // Name of the project
+	Users           List   `example:"usr1,usr2" json:"users"              sql:"users"            sqlitetype:"text"                         swaggertype:"array,string"` // List of users of the project
+	Managers        List   `example:"usr1"      json:"managers,omitempty" sql:"managers"         sqlitetype:"text"                         swaggertype:"array,string"` // List of managers of the project. Managers can access all compute units and usage of the project
+	Tags            List   `example:"tag1,tag2" json:"tags,omitempty"     sql:"tags"             sqlitetype:"text"                         swaggertype:"array,string"` // List of meta data tags of the project
+	LastUpdatedAt   string `json:"-"            sql:"last_updated_at"     sqlitetype:"text"`                                                                           // Last Updated time
 }
 
 // TableName returns the table which admin users list is stored into.
@@ -211,6 +232,78 @@ func (AdminUser) TableName() string {
 	return adminUsersTableName
 }
 
+// UnitAnnotation is a user defined key/value pair attached to a compute unit. Annotations
+// are owned by the user who created them.
+type UnitAnnotation struct {
+	ID            int64  `json:"-"                           sql:"id"               sqlitetype:"integer not null primary key"`
+	ClusterID     string `example:"slurm-0"                  json:"cluster_id"      sql:"cluster_id"                          sqlitetype:"text"` // Identifier of the resource manager that owns compute unit
+	UUID          string `example:"1479763"                  json:"uuid"            sql:"uuid"                                sqlitetype:"text"` // UUID of the compute unit
+	User          string `example:"usr1"                     json:"username"        sql:"username"                            sqlitetype:"text"` // Name of the user who owns the annotation
+	Key           string `example:"doi"                      json:"key"             sql:"key"                                 sqlitetype:"text"` // Key of the annotation
+	Value         string `example:"10.1000/xyz123"           json:"value"           sql:"value"                               sqlitetype:"text"` // Value of the annotation
+	CreatedAt     string `example:"2023-02-21T15:48:20+0100" json:"created_at"      sql:"created_at"                          sqlitetype:"text"` // Creation time
+	LastUpdatedAt string `example:"2023-02-21T15:48:20+0100" json:"last_updated_at" sql:"last_updated_at"                     sqlitetype:"text"` // Last updated time
+}
+
+// TableName returns the table which unit annotations are stored into.
+func (UnitAnnotation) TableName() string {
+	return annotationsTableName
+}
+
+// TagNames returns a slice of all tag names.
+func (a UnitAnnotation) TagNames(tag string) []string {
+	return structset.StructFieldTagValues(a, tag)
+}
+
+// TagMap returns a map of tags based on keyTag and valueTag. If keyTag is empty,
+// field names are used as map keys.
+func (a UnitAnnotation) TagMap(keyTag string, valueTag string) map[string]string {
+	return structset.StructFieldTagMap(a, keyTag, valueTag)
+}
+
+// APIToken is a personal API token of a user. Only the hash of the token is stored
+// in the DB and the token itself is returned only once when it is created.
+type APIToken struct {
+	ID         int64  `json:"-"                           sql:"id"            sqlitetype:"integer not null primary key"`
+	TokenID    string `example:"4f9a1c2e7b3d8a60"         json:"token_id"     sql:"token_id"                            sqlitetype:"text"`                            // Public identifier of the token
+	User       string `example:"usr1"                     json:"username"     sql:"username"                            sqlitetype:"text"`                            // Name of the user who owns the token
+	Name       string `example:"ci-pipeline"              json:"name"         sql:"name"                                sqlitetype:"text"`                            // Name of the token
+	Hash       string `json:"-"                           sql:"hash"          sqlitetype:"text"`                                                                      // SHA256 hash of the token
+	Scopes     List   `example:"units:read,usage:read"    json:"scopes"       sql:"scopes"                              sqlitetype:"text" swaggertype:"array,string"` // Scopes of the token
+	CreatedAt  string `example:"2023-02-21T15:48:20+0100" json:"created_at"   sql:"created_at"                          sqlitetype:"text"`                            // Creation time
+	ExpiresAt  string `example:"2023-05-22T15:48:20+0100" json:"expires_at"   sql:"expires_at"                          sqlitetype:"text"`                            // Expiry time
+	LastUsedAt string `example:"2023-02-22T10:12:05+0100" json:"last_used_at" sql:"last_used_at"                        sqlitetype:"text"`                            // Last time the token was used
+}
+
+// TableName returns the table which API tokens are stored into.
+func (APIToken) TableName() string {
+	return apiTokensTableName
+}
+
+// TagNames returns a slice of all tag names.
+func (t APIToken) TagNames(tag string) []string {
+	return structset.StructFieldTagValues(t, tag)
+}
+
+// TagMap returns a map of tags based on keyTag and valueTag. If keyTag is empty,
+// field names are used as map keys.
+func (t APIToken) TagMap(keyTag string, valueTag string) map[string]string {
+	return structset.StructFieldTagMap(t, keyTag, valueTag)
+}
+
+// APITokenRequest is the request body to create a personal API token.
+type APITokenRequest struct {
+	Name      string   `example:"ci-pipeline" json:"name"`       // Name of the token
+	Scopes    []string `example:"units:read"  json:"scopes"`     // Scopes of the token
+	ExpiresIn string   `example:"90d"         json:"expires_in"` // Validity of the token. Maximum is 1 year
+}
+
+// CreatedAPIToken is the personal API token returned upon creation.
+type CreatedAPIToken struct {
+	APIToken
+	Token string `example:"ceems_Zm9vYmFyYmF6" json:"token"` // Token. It is returned only once
+}
+
 // Key represents arbritrary keys used in metric maps.
 type Key struct {
 	Name string `json:"name" sql:"name" sqlitetype:"text"` // Name of the metric key
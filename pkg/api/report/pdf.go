@@ -0,0 +1,278 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page layout of PDF reports in points. Pages are of A4 size.
+const (
+	pageWidth    = 595.0
+	pageHeight   = 842.0
+	pageMargin   = 50.0
+	contentWidth = pageWidth - 2*pageMargin
+	labelWidth   = 160.0
+	lineGap      = 4.0
+)
+
+// Approximate average width of glyphs of Helvetica relative to font size.
+// It is only used to truncate text that does not fit in table cells.
+const avgGlyphWidth = 0.52
+
+// pdfDocument is a minimal PDF writer that lays out text, lines and rectangles
+// from top to bottom of pages. Only standard Helvetica fonts are used so that
+// no font needs to be embedded.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+// newPDFDocument returns a new PDF document with an empty page.
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+
+	return d
+}
+
+// newPage starts a new page.
+func (d *pdfDocument) newPage() {
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pageHeight - pageMargin
+}
+
+// ensure starts a new page when height does not fit in current page.
+func (d *pdfDocument) ensure(height float64) {
+	if d.y-height < pageMargin {
+		d.newPage()
+	}
+}
+
+// text writes text with its baseline at y.
+func (d *pdfDocument) text(x float64, y float64, size float64, bold bool, gray float64, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(d.page, "BT %.3f g /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", gray, font, size, x, y, escapePDFString(s))
+}
+
+// rect fills a rectangle whose bottom left corner is at x, y.
+func (d *pdfDocument) rect(x float64, y float64, w float64, h float64, r float64, g float64, b float64) {
+	fmt.Fprintf(d.page, "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n", r, g, b, x, y, w, h)
+}
+
+// line draws a horizontal line at y.
+func (d *pdfDocument) line(x float64, y float64, w float64) {
+	fmt.Fprintf(d.page, "0.8 G 0.5 w %.2f %.2f m %.2f %.2f l S\n", x, y, x+w, y)
+}
+
+// title writes title of document.
+func (d *pdfDocument) title(title string, subtitle string, generatedAt string) {
+	d.y -= 18
+	d.text(pageMargin, d.y, 18, true, 0, title)
+
+	if subtitle != "" {
+		d.y -= 11 + 2*lineGap
+		d.text(pageMargin, d.y, 11, false, 0.4, subtitle)
+	}
+
+	d.y -= 9 + 2*lineGap
+	d.text(pageMargin, d.y, 9, false, 0.4, "Generated at "+generatedAt)
+	d.y -= 2 * lineGap
+}
+
+// section writes title of a section.
+func (d *pdfDocument) section(title string) {
+	d.ensure(13 + 6*lineGap + 40)
+	d.y -= 13 + 4*lineGap
+	d.text(pageMargin, d.y, 13, true, 0, title)
+	d.y -= lineGap
+	d.line(pageMargin, d.y, contentWidth)
+	d.y -= lineGap
+}
+
+// fields writes label and value pairs.
+func (d *pdfDocument) fields(fields []Field) {
+	for _, field := range fields {
+		d.ensure(10 + lineGap)
+		d.y -= 10 + lineGap
+		d.text(pageMargin, d.y, 10, true, 0, truncate(field.Label, labelWidth, 10))
+		d.text(pageMargin+labelWidth, d.y, 10, false, 0, truncate(field.Value, contentWidth-labelWidth, 10))
+	}
+}
+
+// table writes a table with equal column widths.
+func (d *pdfDocument) table(t *Table) {
+	if len(t.Header) == 0 {
+		return
+	}
+
+	colWidth := contentWidth / float64(len(t.Header))
+	rowHeight := 9 + 2*lineGap
+
+	row := func(cells []string, bold bool) {
+		for i, cell := range cells {
+			if i >= len(t.Header) {
+				break
+			}
+
+			d.text(pageMargin+float64(i)*colWidth+2, d.y+lineGap, 9, bold, 0, truncate(cell, colWidth-4, 9))
+		}
+	}
+
+	header := func() {
+		d.y -= rowHeight
+		d.rect(pageMargin, d.y, contentWidth, rowHeight, 0.95, 0.95, 0.95)
+		row(t.Header, true)
+	}
+
+	d.ensure(2*rowHeight + lineGap)
+	d.y -= lineGap
+	header()
+
+	for _, cells := range t.Rows {
+		// Repeat header on new pages
+		if d.y-rowHeight < pageMargin {
+			d.newPage()
+			header()
+		}
+
+		d.y -= rowHeight
+		row(cells, false)
+		d.line(pageMargin, d.y, contentWidth)
+	}
+}
+
+// chart writes a horizontal bar chart.
+func (d *pdfDocument) chart(c *Chart) {
+	const (
+		barHeight = 12.0
+		barGap    = 5.0
+		valueGap  = 60.0
+	)
+
+	maxValue := c.Max()
+	barsWidth := contentWidth - labelWidth - valueGap
+
+	d.ensure(11 + 2*lineGap + barHeight + barGap)
+	d.y -= 11 + 2*lineGap
+	d.text(pageMargin, d.y, 11, true, 0, c.Title)
+	d.y -= lineGap
+
+	for _, bar := range c.Bars {
+		d.ensure(barHeight + barGap)
+		d.y -= barHeight + barGap
+
+		length := barLength(bar.Value, maxValue, barsWidth)
+
+		d.text(pageMargin, d.y+2, 9, false, 0, truncate(bar.Label, labelWidth-4, 9))
+		d.rect(pageMargin+labelWidth, d.y, length, barHeight, 0.231, 0.471, 0.765)
+		d.text(pageMargin+labelWidth+length+4, d.y+2, 9, false, 0.2, strings.TrimSpace(FormatValue(bar.Value)+" "+c.Unit))
+	}
+}
+
+// note writes a note in small font.
+func (d *pdfDocument) note(note string) {
+	d.ensure(8 + 3*lineGap)
+	d.y -= 8 + 3*lineGap
+	d.text(pageMargin, d.y, 8, false, 0.4, truncate(note, contentWidth, 8))
+}
+
+// WriteTo writes PDF document to w.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	offsets := []int{}
+
+	// Objects are numbered from 1 and the first four objects are catalog,
+	// page tree and fonts. Each page has a page object and a content object.
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 6+2*i,
+		))
+
+		// Compress content stream
+		content := &bytes.Buffer{}
+		zw := zlib.NewWriter(content)
+
+		if _, err := zw.Write(page.Bytes()); err != nil {
+			return 0, err
+		}
+
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	// Cross reference table and trailer
+	xref := buf.Len()
+	fmt.Fprintf(buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+
+	for _, offset := range offsets {
+		fmt.Fprintf(buf, "%010d 00000 n \n", offset)
+	}
+
+	fmt.Fprintf(buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// escapePDFString encodes s in WinAnsi encoding and escapes special characters
+// of PDF literal strings. Characters that cannot be encoded are replaced by `?`.
+func escapePDFString(s string) string {
+	var b strings.Builder
+
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\t':
+			b.WriteByte(' ')
+		case r < 0x20 || (r >= 0x7f && r < 0xa0) || r > 0xff:
+			b.WriteByte('?')
+		default:
+			b.WriteByte(byte(r))
+		}
+	}
+
+	return b.String()
+}
+
+// truncate shortens s so that it approximately fits in width at font size.
+func truncate(s string, width float64, size float64) string {
+	maxChars := int(width / (avgGlyphWidth * size))
+
+	runes := []rune(s)
+	if len(runes) <= maxChars || maxChars < 4 {
+		return s
+	}
+
+	return string(runes[:maxChars-3]) + "..."
+}
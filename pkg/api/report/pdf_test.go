@@ -0,0 +1,214 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	startxrefRegex = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefEntryRegex = regexp.MustCompile(`^(\d{10}) (\d{5}) ([nf]) \n$`)
+	trailerRegex   = regexp.MustCompile(`^trailer\n<< /Size (\d+) /Root (\d+) 0 R >>\n`)
+	refRegex       = regexp.MustCompile(`(\d+) 0 R`)
+	lengthRegex    = regexp.MustCompile(`^<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+)
+
+// pdfObject is an indirect object of PDF document.
+type pdfObject struct {
+	dict   string
+	stream []byte
+}
+
+// parsePDF parses PDF document using only its cross reference table to locate
+// objects and returns objects by their number and the root object number.
+func parsePDF(t *testing.T, doc []byte) (map[int]pdfObject, int) {
+	t.Helper()
+
+	require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+
+	// Trailer must point to cross reference table
+	m := startxrefRegex.FindSubmatch(doc)
+	require.NotNil(t, m, "startxref not found")
+
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+
+	rest, ok := bytes.CutPrefix(doc[xref:], []byte("xref\n"))
+	require.True(t, ok, "startxref does not point to xref")
+
+	var first, count int
+
+	n, err := fmt.Sscanf(string(rest), "%d %d\n", &first, &count)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, 0, first)
+
+	rest = rest[bytes.IndexByte(rest, '\n')+1:]
+
+	// Each entry of cross reference table must be exactly 20 bytes
+	objects := make(map[int]pdfObject)
+
+	for i := range count {
+		require.GreaterOrEqual(t, len(rest), 20)
+
+		entry := xrefEntryRegex.FindSubmatch(rest[:20])
+		require.NotNil(t, entry, "invalid xref entry %q", rest[:20])
+
+		rest = rest[20:]
+
+		if i == 0 {
+			assert.Equal(t, "f", string(entry[3]))
+			assert.Equal(t, "65535", string(entry[2]))
+
+			continue
+		}
+
+		require.Equal(t, "n", string(entry[3]))
+
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+
+		// Offset must point to the start of object
+		body, ok := bytes.CutPrefix(doc[offset:], fmt.Appendf(nil, "%d 0 obj\n", i))
+		require.True(t, ok, "xref offset of object %d does not point to it", i)
+
+		obj := pdfObject{}
+
+		if lm := lengthRegex.FindSubmatch(body); lm != nil {
+			length, err := strconv.Atoi(string(lm[1]))
+			require.NoError(t, err)
+
+			// Length of stream must match its data
+			data := body[len(lm[0]):]
+			require.GreaterOrEqual(t, len(data), length)
+			require.True(t, bytes.HasPrefix(data[length:], []byte("\nendstream\nendobj\n")), "invalid length of stream %d", i)
+
+			obj.dict = string(body[:bytes.Index(body, []byte(">>"))+2])
+			obj.stream = data[:length]
+		} else {
+			end := bytes.Index(body, []byte("\nendobj\n"))
+			require.Positive(t, end, "object %d is not terminated", i)
+
+			obj.dict = string(body[:end])
+		}
+
+		objects[i] = obj
+	}
+
+	tm := trailerRegex.FindSubmatch(rest)
+	require.NotNil(t, tm, "trailer not found after xref")
+
+	size, err := strconv.Atoi(string(tm[1]))
+	require.NoError(t, err)
+	assert.Equal(t, count, size)
+
+	root, err := strconv.Atoi(string(tm[2]))
+	require.NoError(t, err)
+
+	return objects, root
+}
+
+// refs returns object numbers referenced by key in dict.
+func refs(t *testing.T, dict string, key string) []int {
+	t.Helper()
+
+	_, value, ok := strings.Cut(dict, key+" ")
+	require.True(t, ok, "%s not found in %s", key, dict)
+
+	switch {
+	case strings.HasPrefix(value, "["):
+		value = value[:strings.Index(value, "]")]
+	case strings.HasSuffix(key, "<<"):
+		value = value[:strings.Index(value, ">>")]
+	default:
+		value = refRegex.FindString(value)
+	}
+
+	var nums []int
+
+	for _, m := range refRegex.FindAllStringSubmatch(value, -1) {
+		num, err := strconv.Atoi(m[1])
+		require.NoError(t, err)
+
+		nums = append(nums, num)
+	}
+
+	return nums
+}
+
+func TestPDFStructure(t *testing.T) {
+	for _, test := range []struct {
+		rows  int
+		pages int
+	}{
+		{rows: 0, pages: 1},
+		{rows: 2, pages: 1},
+		{rows: 100, pages: 3},
+	} {
+		buf := &bytes.Buffer{}
+		require.NoError(t, PDF(buf, testReport(test.rows)))
+
+		objects, root := parsePDF(t, buf.Bytes())
+
+		// Walk through document tree from catalog to content streams
+		catalog, ok := objects[root]
+		require.True(t, ok)
+		assert.Contains(t, catalog.dict, "/Type /Catalog")
+
+		pagesRef := refs(t, catalog.dict, "/Pages")
+		require.Len(t, pagesRef, 1)
+
+		pages := objects[pagesRef[0]]
+		assert.Contains(t, pages.dict, "/Type /Pages")
+		assert.Contains(t, pages.dict, fmt.Sprintf("/Count %d", test.pages))
+
+		kids := refs(t, pages.dict, "/Kids")
+		require.Len(t, kids, test.pages, "rows %d", test.rows)
+
+		var text strings.Builder
+
+		for _, kid := range kids {
+			page, ok := objects[kid]
+			require.True(t, ok, "page %d not found", kid)
+			assert.Contains(t, page.dict, "/Type /Page ")
+			assert.Equal(t, pagesRef, refs(t, page.dict, "/Parent"))
+
+			// Fonts used by content must exist
+			fonts := refs(t, page.dict, "/Font <<")
+			require.Len(t, fonts, 2)
+
+			for _, font := range fonts {
+				assert.Contains(t, objects[font].dict, "/Type /Font")
+			}
+
+			contents := refs(t, page.dict, "/Contents")
+			require.Len(t, contents, 1)
+
+			zr, err := zlib.NewReader(bytes.NewReader(objects[contents[0]].stream))
+			require.NoError(t, err)
+
+			content, err := io.ReadAll(zr)
+			require.NoError(t, err)
+
+			// Text objects must be balanced
+			assert.Equal(t, bytes.Count(content, []byte("BT ")), bytes.Count(content, []byte(" ET\n")))
+
+			text.Write(content)
+		}
+
+		assert.Contains(t, text.String(), "(Report of compute unit 1234) Tj")
+
+		if test.rows > 0 {
+			assert.Contains(t, text.String(), fmt.Sprintf("(usr%d) Tj", test.rows-1))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package report renders usage reports of compute units and projects as HTML
+// and PDF documents.
+package report
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+)
+
+//go:embed templates/*.html
+var templatesFS embed.FS
+
+// Height of each bar row of charts in HTML reports.
+const chartRowHeight = 24
+
+var reportTemplate = template.Must(
+	template.New("report.html").Funcs(template.FuncMap{
+		"barLength": barLength,
+		"value":     FormatValue,
+		"rowY":      func(i int) int { return i * chartRowHeight },
+		"height":    func(n int) int { return n * chartRowHeight },
+	}).ParseFS(templatesFS, "templates/report.html"),
+)
+
+// Report is a document with a summary followed by sections.
+type Report struct {
+	Title       string
+	Subtitle    string
+	GeneratedAt string
+	Summary     []Field
+	Sections    []Section
+	Notes       []string
+}
+
+// Field is a labelled value.
+type Field struct {
+	Label string
+	Value string
+}
+
+// Section is a titled part of report with fields, a table and a chart.
+// All of them are optional.
+type Section struct {
+	Title  string
+	Fields []Field
+	Table  *Table
+	Chart  *Chart
+}
+
+// Table is a table with a header row.
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Chart is a horizontal bar chart.
+type Chart struct {
+	Title string
+	Unit  string
+	Bars  []Bar
+}
+
+// Bar is a bar of chart.
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// Max returns the largest value of bars.
+func (c *Chart) Max() float64 {
+	var maxValue float64
+
+	for _, bar := range c.Bars {
+		maxValue = math.Max(maxValue, bar.Value)
+	}
+
+	return maxValue
+}
+
+// HTML renders report as a standalone HTML document.
+func HTML(w io.Writer, r *Report) error {
+	if err := reportTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return nil
+}
+
+// PDF renders report as a PDF document.
+func PDF(w io.Writer, r *Report) error {
+	doc := newPDFDocument()
+
+	doc.title(r.Title, r.Subtitle, r.GeneratedAt)
+	doc.fields(r.Summary)
+
+	for _, section := range r.Sections {
+		doc.section(section.Title)
+		doc.fields(section.Fields)
+
+		if section.Table != nil {
+			doc.table(section.Table)
+		}
+
+		if section.Chart != nil {
+			doc.chart(section.Chart)
+		}
+	}
+
+	for _, note := range r.Notes {
+		doc.note(note)
+	}
+
+	if _, err := doc.WriteTo(w); err != nil {
+		return fmt.Errorf("failed to render report: %w", err)
+	}
+
+	return nil
+}
+
+// FormatValue formats a float value with precision that depends on its magnitude.
+func FormatValue(v float64) string {
+	switch a := math.Abs(v); {
+	case a == 0:
+		return "0"
+	case a >= 100:
+		return fmt.Sprintf("%.0f", v)
+	case a >= 1:
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.3g", v)
+	}
+}
+
+// barLength returns length of bar when maxValue spans fullLength.
+func barLength(value float64, maxValue float64, fullLength float64) float64 {
+	if maxValue <= 0 || value <= 0 {
+		return 0
+	}
+
+	return math.Round(10*fullLength*value/maxValue) / 10
+}
@@ -0,0 +1,157 @@
+package report
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testReport(rows int) *Report {
+	r := &Report{
+		Title:       "Report of compute unit 1234",
+		Subtitle:    "Cluster slurm-0",
+		GeneratedAt: "2025-01-01T10:00:00+0000",
+		Summary: []Field{
+			{Label: "Project", Value: "prj(1)"},
+			{Label: "User", Value: "usr1"},
+		},
+		Sections: []Section{
+			{
+				Title:  "Energy",
+				Fields: []Field{{Label: "CPU energy", Value: "1.50 kWh"}},
+				Chart: &Chart{
+					Title: "Emissions by provider",
+					Unit:  "g",
+					Bars: []Bar{
+						{Label: "owid", Value: 100},
+						{Label: "emaps", Value: 50},
+					},
+				},
+			},
+			{
+				Title: "Users",
+				Table: &Table{Header: []string{"User", "Energy (kWh)"}},
+			},
+		},
+		Notes: []string{"Emissions are estimated from energy usage"},
+	}
+
+	for i := range rows {
+		r.Sections[1].Table.Rows = append(r.Sections[1].Table.Rows, []string{fmt.Sprintf("usr%d", i), "1.00"})
+	}
+
+	return r
+}
+
+func TestHTML(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, HTML(buf, testReport(2)))
+
+	out := buf.String()
+	assert.Contains(t, out, "<title>Report of compute unit 1234</title>")
+	assert.Contains(t, out, "<dt>Project</dt><dd>prj(1)</dd>")
+	assert.Contains(t, out, "<th>Energy (kWh)</th>")
+	assert.Contains(t, out, "<tr><td>usr1</td><td>1.00</td></tr>")
+	assert.Contains(t, out, `<rect x="160" y="0" height="18" width="380"></rect>`)
+	assert.Contains(t, out, `<rect x="160" y="24" height="18" width="190"></rect>`)
+	assert.Contains(t, out, "Emissions are estimated from energy usage")
+
+	// Values must be escaped
+	buf.Reset()
+	require.NoError(t, HTML(buf, &Report{Title: "<script>"}))
+	assert.NotContains(t, buf.String(), "<script>")
+}
+
+// pdfContents returns decompressed content streams of PDF document.
+func pdfContents(t *testing.T, doc []byte) []string {
+	t.Helper()
+
+	var contents []string
+
+	for _, m := range regexp.MustCompile(`(?s)/Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindAllSubmatchIndex(doc, -1) {
+		length, err := strconv.Atoi(string(doc[m[2]:m[3]]))
+		require.NoError(t, err)
+
+		zr, err := zlib.NewReader(bytes.NewReader(doc[m[1] : m[1]+length]))
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(zr)
+		require.NoError(t, err)
+
+		contents = append(contents, string(content))
+	}
+
+	return contents
+}
+
+func TestPDF(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, PDF(buf, testReport(2)))
+
+	doc := buf.Bytes()
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+
+	// Check that cross reference table points to objects
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, m)
+
+	xref, err := strconv.Atoi(string(m[1]))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n0 7\n")))
+
+	for i, offset := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xref:], -1) {
+		o, err := strconv.Atoi(string(offset[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(doc[o:], fmt.Appendf(nil, "%d 0 obj\n", i+1)))
+	}
+
+	// Check text of content
+	contents := pdfContents(t, doc)
+	require.Len(t, contents, 1)
+	assert.Contains(t, contents[0], `(Report of compute unit 1234) Tj`)
+	assert.Contains(t, contents[0], `(prj\(1\)) Tj`)
+	assert.Contains(t, contents[0], `(100 g) Tj`)
+	assert.Contains(t, contents[0], `(usr1) Tj`)
+}
+
+func TestPDFPages(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, PDF(buf, testReport(100)))
+
+	// Long tables must be split into pages with header repeated
+	contents := pdfContents(t, buf.Bytes())
+	require.Len(t, contents, 3)
+	assert.Contains(t, buf.String(), "/Count 3")
+
+	for _, content := range contents[1:] {
+		assert.Contains(t, content, "(Energy \\(kWh\\)) Tj")
+	}
+
+	assert.Contains(t, strings.Join(contents, ""), "(usr99) Tj")
+}
+
+func TestEscapePDFString(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c ?`, escapePDFString("a(b)\\c\n€"))
+	assert.Equal(t, "caf\xe9", escapePDFString("café"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 100, 10))
+	assert.Equal(t, "a very lo...", truncate("a very long text that does not fit", 65, 10))
+}
+
+func TestFormatValue(t *testing.T) {
+	assert.Equal(t, "0", FormatValue(0))
+	assert.Equal(t, "1235", FormatValue(1234.56))
+	assert.Equal(t, "12.35", FormatValue(12.345))
+	assert.Equal(t, "0.0123", FormatValue(0.012345))
+}
@@ -24,6 +24,7 @@ var (
 	ErrDuplID         = errors.New("duplicate ID found in clusters config")
 	ErrUnknownManager = errors.New("unknown resource manager found in the config")
 	ErrInvalidID      = errors.New("invalid cluster ID. It must contain only [a-zA-Z0-9-_]")
+	ErrUnknownCluster = errors.New("unknown cluster ID")
 )
 
 // Config contains the configuration of resource manager cluster(s).
@@ -108,8 +109,39 @@ func managerConfig() (*Config[models.Cluster], error) {
 	return config, nil
 }
 
+// filterClusters returns the config with only clusters of given IDs.
+func filterClusters(config *Config[models.Cluster], ids []string) (*Config[models.Cluster], error) {
+	var clusters []models.Cluster
+
+	for _, id := range ids {
+		i := slices.IndexFunc(config.Clusters, func(c models.Cluster) bool { return c.ID == id })
+		if i < 0 {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownCluster, id)
+		}
+
+		clusters = append(clusters, config.Clusters[i])
+	}
+
+	return &Config[models.Cluster]{Clusters: clusters}, nil
+}
+
 // New creates a new Manager struct instance.
 func New(logger *slog.Logger) (*Manager, error) {
+	return newManager(nil, logger)
+}
+
+// NewForClusters returns a function that creates a new Manager struct instance
+// with only the clusters of given IDs. It is used to fetch compute units of a
+// tenant.
+func NewForClusters(ids []string) func(*slog.Logger) (*Manager, error) {
+	return func(logger *slog.Logger) (*Manager, error) {
+		return newManager(ids, logger)
+	}
+}
+
+// newManager creates a new Manager struct instance. When ids is not nil, only
+// clusters with those IDs are managed.
+func newManager(ids []string, logger *slog.Logger) (*Manager, error) {
 	var fetcher Fetcher
 
 	var registeredManagers []string
@@ -133,6 +165,15 @@ func New(logger *slog.Logger) (*Manager, error) {
 		return nil, err
 	}
 
+	// Keep only requested clusters
+	if ids != nil {
+		if config, err = filterClusters(config, ids); err != nil {
+			logger.Error("Invalid clusters of resource manager", "err", err)
+
+			return nil, err
+		}
+	}
+
 	// Preflight checks on config
 	configMap, err := checkConfig(registeredManagers, config)
 	if err != nil {
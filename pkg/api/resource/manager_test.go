@@ -283,6 +283,23 @@ func TestMixedClusterConfig(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestFilterClusters(t *testing.T) {
+	// Make mock config
+	base.ConfigFilePath = mockConfig(t.TempDir(), "mixed_instances")
+
+	cfg, err := managerConfig()
+	require.NoError(t, err)
+
+	filtered, err := filterClusters(cfg, []string{"openstack-0", "slurm-0"})
+	require.NoError(t, err)
+	require.Len(t, filtered.Clusters, 2)
+	assert.Equal(t, "openstack-0", filtered.Clusters[0].ID)
+	assert.Equal(t, "slurm-0", filtered.Clusters[1].ID)
+
+	_, err = filterClusters(cfg, []string{"slurm-2"})
+	assert.ErrorIs(t, err, ErrUnknownCluster)
+}
+
 func TestNewManager(t *testing.T) {
 	// Make mock config
 	base.ConfigFilePath = mockConfig(t.TempDir(), "mock_instance")
@@ -309,6 +326,23 @@ func TestNewManager(t *testing.T) {
 	assert.Len(t, projects[0].Projects, 1)
 }
 
+func TestNewManagerForClusters(t *testing.T) {
+	// Make mock config
+	base.ConfigFilePath = mockConfig(t.TempDir(), "mock_instance")
+
+	// Register mock manager
+	Register("mock", NewMockResourceManager)
+
+	// Create new manager with known cluster
+	manager, err := NewForClusters([]string{"default"})(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	assert.Len(t, manager.Fetchers, 1)
+
+	// Create new manager with unknown cluster
+	_, err = NewForClusters([]string{"unknown"})(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.ErrorIs(t, err, ErrUnknownCluster)
+}
+
 func TestNewManagerWithNoClusters(t *testing.T) {
 	// Make mock config
 	base.ConfigFilePath = mockConfig(t.TempDir(), "empty_instance")
@@ -114,6 +114,51 @@ func (o *openstackManager) fetchUserProjects(ctx context.Context, userID string)
 	return resp.Projects, nil
 }
 
+// fetchProjectManagers fetches managers of projects from role assignments and returns
+// a map of project ID to the names of managers.
+func (o *openstackManager) fetchProjectManagers(ctx context.Context) (map[string][]string, error) {
+	// If no manager roles are configured, there is nothing to fetch
+	if len(o.managerRoles) == 0 {
+		return nil, nil
+	}
+
+	// Create a new GET request
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		o.roleAssignments().String(),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request to fetch role assignments for openstack cluster: %w", err)
+	}
+
+	// Add token to request headers
+	req, err = o.addTokenHeader(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate api token for openstack cluster: %w", err)
+	}
+
+	// Get response
+	resp, err := apiRequest[RoleAssignmentsResponse](req, o.client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete request to fetch role assignments for openstack cluster: %w", err)
+	}
+
+	managers := make(map[string][]string)
+
+	for _, assignment := range resp.RoleAssignments {
+		// Ignore assignments that are not on projects or not manager roles
+		if assignment.Scope.Project.ID == "" || assignment.User.Name == "" || !slices.Contains(o.managerRoles, assignment.Role.Name) {
+			continue
+		}
+
+		managers[assignment.Scope.Project.ID] = append(managers[assignment.Scope.Project.ID], assignment.User.Name)
+	}
+
+	return managers, nil
+}
+
 // fetchUsers fetches a list of users or specific user from Openstack cluster.
 func (o *openstackManager) usersProjectsAssoc(ctx context.Context, current time.Time) (userProjectsCache, error) {
 	// Check if service is online
@@ -199,6 +244,14 @@ func (o *openstackManager) usersProjectsAssoc(ctx context.Context, current time.
 	slices.Sort(projectIDs)
 	projectIDs = slices.Compact(projectIDs)
 
+	// Get managers of projects. Failing to fetch them must not fail the update
+	// of users and projects. In that case managers are left nil so that existing
+	// managers in DB are kept
+	projectManagers, err := o.fetchProjectManagers(ctx)
+	if err != nil {
+		o.logger.Warn("Failed to fetch managers of projects", "id", o.cluster.ID, "err", err)
+	}
+
 	// Transform map into slice of projects
 	projectModels := make([]models.Project, len(projectIDs))
 
@@ -213,11 +266,25 @@ func (o *openstackManager) usersProjectsAssoc(ctx context.Context, current time.
 			usersList = append(usersList, u)
 		}
 
+		// Sort managers
+		managers := projectManagers[projectID]
+		slices.Sort(managers)
+
+		var managersList models.List
+		if err == nil {
+			managersList = models.List{}
+		}
+
+		for _, m := range slices.Compact(managers) {
+			managersList = append(managersList, m)
+		}
+
 		// Make Association
 		projectModels[iproject] = models.Project{
 			UID:           projectID,
 			Name:          projectIDNameMap[projectID],
 			Users:         usersList,
+			Managers:      managersList,
 			LastUpdatedAt: currentTime,
 		}
 	}
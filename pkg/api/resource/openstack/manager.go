@@ -52,6 +52,7 @@ type openstackManager struct {
 	userProjectsCache          userProjectsCache
 	userProjectsCacheTTL       time.Duration
 	userProjectsLastUpdateTime time.Time
+	managerRoles               []string
 }
 
 type openstackConfig struct {
@@ -59,7 +60,8 @@ type openstackConfig struct {
 		Compute  string `yaml:"compute"`
 		Identity string `yaml:"identity"`
 	} `yaml:"api_service_endpoints"`
-	AuthConfig interface{} `yaml:"auth"`
+	AuthConfig   interface{} `yaml:"auth"`
+	ManagerRoles []string    `yaml:"project_manager_roles"`
 }
 
 // addAuthKey embeds AuthConfig as value under `auth` key.
@@ -119,13 +121,16 @@ func New(cluster models.Cluster, logger *slog.Logger) (resource.Fetcher, error)
 	}
 
 	// Fetch compute and identity API URLs and auth config from extra_config
-	osConfig := &openstackConfig{}
+	// By default, users with manager role on a project are the managers of that project
+	osConfig := &openstackConfig{ManagerRoles: []string{"manager"}}
 	if err := cluster.Extra.Decode(osConfig); err != nil {
 		logger.Error("Failed to decode extra_config for Openstack cluster", "id", cluster.ID, "err", err)
 
 		return nil, err
 	}
 
+	openstackManager.managerRoles = osConfig.ManagerRoles
+
 	// Ensure we have valid compute and identity API URLs
 	// Unwrap original error to avoid leaking sensitive passwords in output
 	openstackManager.apiURLs["compute"], err = url.Parse(osConfig.APIEndpoints.Compute)
@@ -231,6 +236,15 @@ func (o *openstackManager) userProjects(id string) *url.URL {
 	return o.apiURLs["identity"].JoinPath(fmt.Sprintf("/v3/users/%s/projects", id))
 }
 
+// role assignments endpoint. Effective assignments include the ones inherited
+// from groups.
+func (o *openstackManager) roleAssignments() *url.URL {
+	u := o.apiURLs["identity"].JoinPath("/v3/role_assignments")
+	u.RawQuery = "effective&include_names"
+
+	return u
+}
+
 // addTokenHeader adds API token to request headers.
 func (o *openstackManager) addTokenHeader(ctx context.Context, req *http.Request) (*http.Request, error) {
 	// Check if token is still valid. If not rotate token
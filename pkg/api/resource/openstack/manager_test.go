@@ -153,12 +153,12 @@ var (
 		{UID: "dc87e591c0d247d5ac04e873bd8a1646", Name: "test-user-4", Projects: models.List{"test-project-4"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
 	}
 	expectedProjects = []models.Project{
-		{UID: "066a633fd999424faa3409ab60221fbf", Name: "admin", Users: models.List{"admin"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
-		{UID: "706f9e5f3e174feebcce4e7f08a7b7e3", Name: "test-project-2", Users: models.List{"test-user-1", "test-user-2"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
-		{UID: "9d87d46f8af54da2adc3e7b94c9d3c30", Name: "demo", Users: models.List{"admin"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
-		{UID: "b964a9e51c0046a4a84d3f83a135a97c", Name: "test-project-4", Users: models.List{"test-user-4"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
-		{UID: "bdb137e6ee6d427a899ac22de5d76b8c", Name: "test-project-3", Users: models.List{"test-user-1", "test-user-2", "test-user-3"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
-		{UID: "cca105ea0cff426e96f096887b7f4b82", Name: "test-project-1", Users: models.List{"test-user-1"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "066a633fd999424faa3409ab60221fbf", Name: "admin", Users: models.List{"admin"}, Managers: models.List{}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "706f9e5f3e174feebcce4e7f08a7b7e3", Name: "test-project-2", Users: models.List{"test-user-1", "test-user-2"}, Managers: models.List{"test-user-1"}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "9d87d46f8af54da2adc3e7b94c9d3c30", Name: "demo", Users: models.List{"admin"}, Managers: models.List{}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "b964a9e51c0046a4a84d3f83a135a97c", Name: "test-project-4", Users: models.List{"test-user-4"}, Managers: models.List{}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "bdb137e6ee6d427a899ac22de5d76b8c", Name: "test-project-3", Users: models.List{"test-user-1", "test-user-2", "test-user-3"}, Managers: models.List{}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
+		{UID: "cca105ea0cff426e96f096887b7f4b82", Name: "test-project-1", Users: models.List{"test-user-1"}, Managers: models.List{}, LastUpdatedAt: "2024-10-15T15:15:00+0200"},
 	}
 )
 
@@ -211,7 +211,19 @@ func mockOSComputeAPIServer() *httptest.Server {
 func mockOSIdentityAPIServer() *httptest.Server {
 	// Start test server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, "users") {
+		if strings.HasSuffix(r.URL.Path, "role_assignments") {
+			if tokens := r.Header[tokenHeaderName]; len(tokens) == 0 {
+				w.WriteHeader(http.StatusForbidden)
+
+				return
+			}
+
+			if data, err := os.ReadFile("../../testdata/openstack/identity/role_assignments.json"); err == nil {
+				w.Write(data)
+
+				return
+			}
+		} else if strings.HasSuffix(r.URL.Path, "users") {
 			if tokens := r.Header[tokenHeaderName]; len(tokens) == 0 {
 				w.WriteHeader(http.StatusForbidden)
 
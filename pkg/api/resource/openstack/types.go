@@ -358,3 +358,30 @@ type Project struct {
 type ProjectsResponse struct {
 	Projects []Project `json:"projects"`
 }
+
+// RoleAssignment represents a role assignment in the OpenStack Identity Service.
+type RoleAssignment struct {
+	// Role is the role that is assigned.
+	Role struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"role"`
+
+	// Scope is the scope of the assignment. Only project scopes are relevant.
+	Scope struct {
+		Project struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"project"`
+	} `json:"scope"`
+
+	// User is the user to whom the role is assigned.
+	User struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"user"`
+}
+
+type RoleAssignmentsResponse struct {
+	RoleAssignments []RoleAssignment `json:"role_assignments"`
+}
@@ -491,6 +491,33 @@ func parseSacctMgrCmdOutput(sacctMgrOutput string, currentTime string) ([]models
 	return userModels, projectModels
 }
 
+// Parse sacctmgr command output of accounts with coordinators and return
+// a map of account to its coordinators.
+func parseSacctMgrCoordOutput(sacctMgrOutput string) map[string]models.List {
+	coords := make(map[string]models.List)
+
+	for _, line := range strings.Split(sacctMgrOutput, "\n") {
+		components := strings.Split(line, "|")
+
+		// Ignore if we cannot get all components or there are no coordinators
+		if len(components) < 2 || components[0] == "" || components[0] == "root" || components[1] == "" {
+			continue
+		}
+
+		// Coordinators are comma separated
+		users := strings.Split(components[1], ",")
+		slices.Sort(users)
+
+		for _, u := range slices.Compact(users) {
+			if u = strings.TrimSpace(u); u != "" {
+				coords[components[0]] = append(coords[components[0]], u)
+			}
+		}
+	}
+
+	return coords
+}
+
 // runSacctCmd executes sacct command and return output.
 func (s *slurmScheduler) runSacctCmd(ctx context.Context, start, end time.Time) ([]byte, error) {
 	// sacct path
@@ -546,10 +573,8 @@ func (s *slurmScheduler) runSacctCmd(ctx context.Context, start, end time.Time)
 	return internal_osexec.ExecuteContext(ctx, sacctPath, args, env)
 }
 
-// Run sacctmgr command and return output.
-func (s *slurmScheduler) runSacctMgrCmd(ctx context.Context) ([]byte, error) {
-	// Use jobIDRaw that outputs the array jobs as regular job IDs instead of id_array format
-	args := []string{"--parsable2", "--noheader", "list", "associations", "format=Account,User"}
+// Run sacctmgr command with args and return output.
+func (s *slurmScheduler) runSacctMgrCmd(ctx context.Context, args []string) ([]byte, error) {
 
 	// sacct path
 	sacctMgrPath := filepath.Join(s.cluster.CLI.Path, "sacctmgr")
@@ -10,6 +10,7 @@ import (
 
 	"github.com/mahendrapaipuri/ceems/internal/security"
 	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -72,3 +73,8 @@ func TestParseSacctMgrCmdOutput(t *testing.T) {
 	require.ElementsMatch(t, expectedUsers, users)
 	require.ElementsMatch(t, expectedProjects, projects)
 }
+
+func TestParseSacctMgrCoordOutput(t *testing.T) {
+	coords := parseSacctMgrCoordOutput(sacctMgrCoordCmdOutput)
+	require.Equal(t, map[string]models.List{"prj3": {"usr2"}, "prj4": {"usr3", "usr4"}}, coords)
+}
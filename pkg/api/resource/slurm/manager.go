@@ -55,6 +55,10 @@ var (
 		"RUNNING",
 	}
 	sacctFieldMap = make(map[string]int, len(sacctFields))
+
+	// sacctmgr args to list associations and coordinators of accounts.
+	sacctMgrAssocArgs = []string{"--parsable2", "--noheader", "list", "associations", "format=Account,User"}
+	sacctMgrCoordArgs = []string{"--parsable2", "--noheader", "list", "accounts", "withcoord", "format=Account,Coordinators"}
 )
 
 func init() {
@@ -162,7 +166,7 @@ func (s *slurmScheduler) fetchFromSacctMgr(
 	currentTime := current.Format(base.DatetimeLayout)
 
 	// Execute sacctmgr command
-	sacctMgrOutput, err := s.runSacctMgrCmd(ctx)
+	sacctMgrOutput, err := s.runSacctMgrCmd(ctx, sacctMgrAssocArgs)
 	if err != nil {
 		s.logger.Error("Failed to run sacctmgr command", "cluster_id", s.cluster.ID, "err", err)
 
@@ -171,6 +175,20 @@ func (s *slurmScheduler) fetchFromSacctMgr(
 
 	// Parse sacctmgr output to get user project associations
 	users, projects := parseSacctMgrCmdOutput(string(sacctMgrOutput), currentTime)
+
+	// Account coordinators are managers of the project. Failing to fetch them
+	// must not fail the update of users and projects. In that case managers are
+	// left nil so that existing managers in DB are kept
+	if sacctMgrCoordOutput, err := s.runSacctMgrCmd(ctx, sacctMgrCoordArgs); err != nil {
+		s.logger.Warn("Failed to fetch SLURM account coordinators", "cluster_id", s.cluster.ID, "err", err)
+	} else {
+		coords := parseSacctMgrCoordOutput(string(sacctMgrCoordOutput))
+		for i := range projects {
+			if projects[i].Managers = coords[projects[i].Name]; projects[i].Managers == nil {
+				projects[i].Managers = models.List{}
+			}
+		}
+	}
 	s.logger.Info("SLURM user account data fetched", "cluster_id", s.cluster.ID, "num_users", len(users), "num_accounts", len(projects))
 
 	return users, projects, nil
@@ -31,6 +31,11 @@ prj3|usr2
 prj4|
 prj4|usr2
 prj4|usr3`
+	sacctMgrCoordCmdOutput = `root|
+prj1|
+prj2|
+prj3|usr2
+prj4|usr3,usr4,usr3`
 	expectedBatchJobs = []models.Unit{
 		{
 			ID:              0,
@@ -158,7 +163,11 @@ printf """%s"""`, sacctCmdOutput)
 
 	sacctMgrPath := filepath.Join(tmpDir, "sacctmgr")
 	sacctMgrScript := fmt.Sprintf(`#!/bin/bash
-printf """%s"""`, sacctMgrCmdOutput)
+if [[ "$*" == *"withcoord"* ]]; then
+  printf """%s"""
+  exit 0
+fi
+printf """%s"""`, sacctMgrCoordCmdOutput, sacctMgrCmdOutput)
 	os.WriteFile(sacctMgrPath, []byte(sacctMgrScript), 0o700) // #nosec
 
 	sacctMgrDir := filepath.Dir(sacctMgrPath)
@@ -189,7 +198,14 @@ printf """%s"""`, sacctMgrCmdOutput)
 		_, err = slurm.FetchUnits(ctx, start, end)
 		require.NoError(t, err)
 
-		_, _, err = slurm.FetchUsersProjects(ctx, current)
+		_, projects, err := slurm.FetchUsersProjects(ctx, current)
 		require.NoError(t, err)
+
+		// Account coordinators must be managers of projects
+		for _, project := range projects[0].Projects {
+			if project.Name == "prj4" {
+				require.Equal(t, models.List{"usr3", "usr4"}, project.Managers)
+			}
+		}
 	}
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/mahendrapaipuri/ceems/internal/common"
 	"github.com/mahendrapaipuri/ceems/pkg/api/base"
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
 	"gopkg.in/yaml.v3"
 )
 
@@ -114,6 +115,53 @@ func updaterConfig() (*Config[Instance], error) {
 	return config, nil
 }
 
+// TSDBClients returns TSDB clients of TSDB updaters keyed by IDs of the clusters
+// using them. When a cluster uses more than one TSDB updater, the first one is
+// used.
+func TSDBClients(logger *slog.Logger) (map[string]*tsdb.Client, error) {
+	config, err := updaterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clustersConfig, err := common.MakeConfig[struct {
+		Clusters []models.Cluster `yaml:"clusters"`
+	}](base.ConfigFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make(map[string]Instance)
+
+	for _, instance := range config.Instances {
+		if instance.Updater == "tsdb" && instance.Web.URL != "" {
+			instances[instance.ID] = instance
+		}
+	}
+
+	clients := make(map[string]*tsdb.Client)
+
+	for _, cluster := range clustersConfig.Clusters {
+		for _, id := range cluster.Updaters {
+			instance, ok := instances[id]
+			if !ok {
+				continue
+			}
+
+			client, err := tsdb.New(instance.Web.URL, instance.Web.HTTPClientConfig, logger.With("updater_id", id))
+			if err != nil {
+				return nil, err
+			}
+
+			clients[cluster.ID] = client
+
+			break
+		}
+	}
+
+	return clients, nil
+}
+
 // New creates a new UnitUpdater.
 func New(logger *slog.Logger) (*UnitUpdater, error) {
 	var updater Updater
@@ -96,6 +96,37 @@ updaters:
       queries:
         avg_cpu_usage: foo
         avg_cpu_mem_usage: foo`
+	case "clusters":
+		configFileTmpl = `
+---
+clusters:
+  - id: slurm-0
+    manager: slurm
+    updaters:
+      - default-0
+      - default-1
+  - id: slurm-1
+    manager: slurm
+    updaters:
+      - mock
+  - id: slurm-2
+    manager: slurm
+
+updaters:
+  - id: default-0
+    updater: tsdb
+    web:
+      url: %[1]s
+    extra_config:
+      cutoff_duration: %[2]s
+  - id: default-1
+    updater: tsdb
+    web:
+      url: http://localhost:9091
+  - id: mock
+    updater: mock
+    web:
+      url: http://localhost:9092`
 	case "malformed_1":
 		// Missing s in tsbd_instances
 		configFileTmpl = `
@@ -209,6 +240,19 @@ func TestOneInstanceConfig(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestTSDBClients(t *testing.T) {
+	// Make mock config
+	base.ConfigFilePath = mockConfig(t.TempDir(), "clusters", "http://localhost:9090")
+
+	clients, err := TSDBClients(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	// Only clusters with TSDB updaters must have clients and first TSDB updater
+	// must be used
+	require.Len(t, clients, 1)
+	assert.Equal(t, "http://localhost:9090", clients["slurm-0"].URL.String())
+}
+
 func TestNewUpdater(t *testing.T) {
 	// Make mock config
 	base.ConfigFilePath = mockConfig(t.TempDir(), "mock_instance", "http://localhost:9090")
@@ -0,0 +1,165 @@
+package webhooks
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// Config contains the configuration of webhooks.
+type Config struct {
+	Endpoints        []EndpointConfig    `yaml:"endpoints"`
+	LowEfficiency    LowEfficiencyConfig `yaml:"low_efficiency"`
+	FailedStates     []string            `yaml:"failed_states"`
+	DeliveryInterval model.Duration      `yaml:"delivery_interval"`
+	MaxRetries       int                 `yaml:"max_retries"`
+	MinBackoff       model.Duration      `yaml:"min_backoff"`
+	MaxBackoff       model.Duration      `yaml:"max_backoff"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = Config{
+		FailedStates:     []string{"FAILED", "TIMEOUT", "OUT_OF_MEMORY", "NODE_FAIL", "BOOT_FAIL", "ERROR"},
+		DeliveryInterval: model.Duration(30 * time.Second),
+		MaxRetries:       5,
+		MinBackoff:       model.Duration(30 * time.Second),
+		MaxBackoff:       model.Duration(time.Hour),
+	}
+
+	type plain Config
+
+	return unmarshal((*plain)(c))
+}
+
+// Enabled returns true when atleast one endpoint is configured.
+func (c *Config) Enabled() bool {
+	return len(c.Endpoints) > 0
+}
+
+// Validate validates the config.
+func (c *Config) Validate() error {
+	if !c.Enabled() {
+		return nil
+	}
+
+	var names []string
+
+	for _, endpoint := range c.Endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+
+		if slices.Contains(names, endpoint.Name) {
+			return fmt.Errorf("duplicate webhook endpoint name %s", endpoint.Name)
+		}
+
+		names = append(names, endpoint.Name)
+	}
+
+	if c.DeliveryInterval <= 0 {
+		return errors.New("webhooks delivery_interval must be positive")
+	}
+
+	if c.MaxRetries < 0 {
+		return errors.New("webhooks max_retries must not be negative")
+	}
+
+	if c.MinBackoff <= 0 || c.MaxBackoff < c.MinBackoff {
+		return errors.New("webhooks min_backoff must be positive and less than max_backoff")
+	}
+
+	return nil
+}
+
+// SetDirectory joins any relative file paths with dir.
+func (c *Config) SetDirectory(dir string) {
+	for i := range c.Endpoints {
+		c.Endpoints[i].SecretFile = config.JoinDir(dir, c.Endpoints[i].SecretFile)
+		c.Endpoints[i].HTTPClientConfig.SetDirectory(dir)
+	}
+}
+
+// LowEfficiencyConfig contains the thresholds below which a compute unit is
+// considered inefficient.
+type LowEfficiencyConfig struct {
+	AvgCPUUsage float64        `yaml:"avg_cpu_usage"`
+	AvgGPUUsage float64        `yaml:"avg_gpu_usage"`
+	MinWalltime model.Duration `yaml:"min_walltime"`
+}
+
+// EndpointConfig contains the configuration of a webhook endpoint.
+type EndpointConfig struct {
+	Name             string                  `yaml:"name"`
+	URL              string                  `yaml:"url"`
+	Secret           config.Secret           `yaml:"secret"`
+	SecretFile       string                  `yaml:"secret_file"`
+	Events           []string                `yaml:"events"`
+	Clusters         []string                `yaml:"clusters"`
+	Projects         []string                `yaml:"projects"`
+	Users            []string                `yaml:"users"`
+	HTTPClientConfig config.HTTPClientConfig `yaml:"http_client_config"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *EndpointConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = EndpointConfig{
+		HTTPClientConfig: config.DefaultHTTPClientConfig,
+	}
+
+	type plain EndpointConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *EndpointConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required for webhook endpoint")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url of webhook endpoint %s", c.Name)
+	}
+
+	if c.Secret != "" && c.SecretFile != "" {
+		return fmt.Errorf("at most one of secret and secret_file must be configured for webhook endpoint %s", c.Name)
+	}
+
+	for _, event := range c.Events {
+		if !slices.Contains(EventTypes, event) {
+			return fmt.Errorf("unknown event %s for webhook endpoint %s", event, c.Name)
+		}
+	}
+
+	return c.HTTPClientConfig.Validate()
+}
+
+// matches returns true when event of unit must be delivered to endpoint.
+func (c *EndpointConfig) matches(event string, clusterID string, project string, user string) bool {
+	if len(c.Events) > 0 && !slices.Contains(c.Events, event) {
+		return false
+	}
+
+	if len(c.Clusters) > 0 && !slices.Contains(c.Clusters, clusterID) {
+		return false
+	}
+
+	if len(c.Projects) > 0 && !slices.Contains(c.Projects, project) {
+		return false
+	}
+
+	if len(c.Users) > 0 && !slices.Contains(c.Users, user) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,437 @@
+// Package webhooks implements detection of compute unit lifecycle events and
+// their delivery to webhook endpoints using a persistent outbox.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/config"
+)
+
+// Event types.
+const (
+	UnitStarted       = "unit.started"
+	UnitEnded         = "unit.ended"
+	UnitFailed        = "unit.failed"
+	UnitLowEfficiency = "unit.low_efficiency"
+)
+
+// Headers of webhook requests.
+const (
+	EventHeader     = "X-Ceems-Event"
+	DeliveryHeader  = "X-Ceems-Delivery"
+	TimestampHeader = "X-Ceems-Timestamp"
+	SignatureHeader = "X-Ceems-Signature"
+)
+
+const (
+	outboxTableName = "webhook_outbox"
+	batchSize       = 100
+)
+
+// EventTypes are all the supported event types.
+var EventTypes = []string{UnitStarted, UnitEnded, UnitFailed, UnitLowEfficiency}
+
+// Event is the payload of a webhook.
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt string      `json:"created_at"`
+	Unit      models.Unit `json:"unit"`
+}
+
+// UnitState is the state of a compute unit in the DB before an update.
+type UnitState struct {
+	StartedAtTS int64
+	EndedAtTS   int64
+}
+
+// endpoint is a webhook endpoint with its HTTP client.
+type endpoint struct {
+	config EndpointConfig
+	secret []byte
+	client *http.Client
+}
+
+// Webhooks detects lifecycle events of compute units and delivers them to
+// endpoints.
+type Webhooks struct {
+	logger    *slog.Logger
+	config    Config
+	endpoints map[string]*endpoint
+	now       func() time.Time
+}
+
+// New returns a new instance of Webhooks.
+func New(c Config, logger *slog.Logger) (*Webhooks, error) {
+	endpoints := make(map[string]*endpoint, len(c.Endpoints))
+
+	for _, e := range c.Endpoints {
+		client, err := config.NewClientFromConfig(e.HTTPClientConfig, "ceems_api_server_webhooks")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create client for webhook endpoint %s: %w", e.Name, err)
+		}
+
+		secret := []byte(e.Secret)
+
+		if e.SecretFile != "" {
+			content, err := os.ReadFile(e.SecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read secret file of webhook endpoint %s: %w", e.Name, err)
+			}
+
+			secret = bytes.TrimSpace(content)
+		}
+
+		endpoints[e.Name] = &endpoint{config: e, secret: secret, client: client}
+	}
+
+	return &Webhooks{
+		logger:    logger,
+		config:    c,
+		endpoints: endpoints,
+		now:       time.Now,
+	}, nil
+}
+
+// Events returns the lifecycle events of unit based on its previous state in
+// the DB. A nil prev means the unit is seen for the first time.
+func (w *Webhooks) Events(prev *UnitState, unit models.Unit) []string {
+	var events []string
+
+	if unit.StartedAtTS > 0 && (prev == nil || prev.StartedAtTS == 0) {
+		events = append(events, UnitStarted)
+	}
+
+	// Rest of the events are only emitted when unit ends
+	if unit.EndedAtTS == 0 || (prev != nil && prev.EndedAtTS > 0) {
+		return events
+	}
+
+	events = append(events, UnitEnded)
+
+	// SLURM states can be like `CANCELLED by 1000`
+	if fields := strings.Fields(unit.State); len(fields) > 0 && slices.ContainsFunc(w.config.FailedStates, func(s string) bool {
+		return strings.EqualFold(s, fields[0])
+	}) {
+		events = append(events, UnitFailed)
+	}
+
+	if w.lowEfficiency(unit) {
+		events = append(events, UnitLowEfficiency)
+	}
+
+	return events
+}
+
+// lowEfficiency returns true if average CPU or GPU usage of unit is below
+// configured thresholds.
+func (w *Webhooks) lowEfficiency(unit models.Unit) bool {
+	c := w.config.LowEfficiency
+
+	// Ignore short units
+	if walltime, ok := unit.TotalTime["walltime"]; ok && float64(walltime) < time.Duration(c.MinWalltime).Seconds() {
+		return false
+	}
+
+	if usage, ok := unit.AveCPUUsage["global"]; ok && c.AvgCPUUsage > 0 && float64(usage) < c.AvgCPUUsage {
+		return true
+	}
+
+	if usage, ok := unit.AveGPUUsage["global"]; ok && c.AvgGPUUsage > 0 && float64(usage) < c.AvgGPUUsage {
+		return true
+	}
+
+	return false
+}
+
+// Enqueue adds events of unit to the outbox for each matching endpoint. It must be
+// called inside the same transaction that updates the unit so that events are not
+// lost when the update fails. Events of unit are added all-or-nothing: on error, no
+// event is added to the outbox and transaction can still be committed.
+func (w *Webhooks) Enqueue(ctx context.Context, tx *sql.Tx, clusterID string, unit models.Unit, events []string) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT webhooks_enqueue"); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := w.enqueue(ctx, tx, clusterID, unit, events); err != nil {
+		// Rolling back to savepoint does not remove it and hence, it must be
+		// released as well
+		for _, stmt := range []string{"ROLLBACK TO webhooks_enqueue", "RELEASE webhooks_enqueue"} {
+			if _, rerr := tx.ExecContext(ctx, stmt); rerr != nil {
+				return errors.Join(err, fmt.Errorf("failed to roll back to savepoint: %w", rerr))
+			}
+		}
+
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE webhooks_enqueue"); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+
+	return nil
+}
+
+// enqueue adds events of unit to the outbox for each matching endpoint.
+func (w *Webhooks) enqueue(ctx context.Context, tx *sql.Tx, clusterID string, unit models.Unit, events []string) error {
+	createdAt := w.now()
+
+	for _, eventType := range events {
+		event := Event{
+			ID:        newEventID(),
+			Type:      eventType,
+			CreatedAt: createdAt.Format(base.DatetimezoneLayout),
+			Unit:      unit,
+		}
+		event.Unit.ClusterID = clusterID
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal webhook event: %w", err)
+		}
+
+		for _, name := range w.endpointNames() {
+			if !w.endpoints[name].config.matches(eventType, clusterID, unit.Project, unit.User) {
+				continue
+			}
+
+			if _, err := tx.ExecContext(
+				ctx,
+				fmt.Sprintf("INSERT INTO %s (event_id,endpoint,event,payload,next_attempt_at,created_at) VALUES (?,?,?,?,?,?)", outboxTableName),
+				event.ID, name, eventType, string(payload), createdAt.UnixMilli(), event.CreatedAt,
+			); err != nil {
+				return fmt.Errorf("failed to add webhook event to outbox: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// delivery is a pending delivery in the outbox.
+type delivery struct {
+	id       int64
+	eventID  string
+	endpoint string
+	event    string
+	payload  []byte
+	attempts int
+}
+
+// Deliver sends all the due events in the outbox to their endpoints. Delivered
+// events and events that exhausted retries are removed from the outbox.
+func (w *Webhooks) Deliver(ctx context.Context, db *sql.DB) error {
+	for {
+		deliveries, err := w.pending(ctx, db)
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deliveries {
+			if err := w.deliver(ctx, db, d); err != nil {
+				return err
+			}
+		}
+
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+}
+
+// pending returns due deliveries from outbox.
+func (w *Webhooks) pending(ctx context.Context, db *sql.DB) ([]delivery, error) {
+	rows, err := db.QueryContext(
+		ctx,
+		fmt.Sprintf("SELECT id,event_id,endpoint,event,payload,attempts FROM %s WHERE next_attempt_at <= ? ORDER BY id ASC LIMIT %d", outboxTableName, batchSize),
+		w.now().UnixMilli(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook events from outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []delivery
+
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.eventID, &d.endpoint, &d.event, &d.payload, &d.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook event: %w", err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// deliver sends a single delivery and updates the outbox.
+func (w *Webhooks) deliver(ctx context.Context, db *sql.DB, d delivery) error {
+	e, ok := w.endpoints[d.endpoint]
+	if !ok {
+		w.logger.Warn("Dropping webhook event of unknown endpoint", "endpoint", d.endpoint, "event_id", d.eventID)
+
+		return w.remove(ctx, db, d.id)
+	}
+
+	retry, err := w.send(ctx, e, d)
+	if err == nil {
+		w.logger.Debug("Webhook event delivered", "endpoint", d.endpoint, "event", d.event, "event_id", d.eventID)
+
+		return w.remove(ctx, db, d.id)
+	}
+
+	d.attempts++
+
+	if !retry || d.attempts > w.config.MaxRetries {
+		w.logger.Error(
+			"Failed to deliver webhook event. Dropping event",
+			"endpoint", d.endpoint, "event", d.event, "event_id", d.eventID, "attempts", d.attempts, "err", err,
+		)
+
+		return w.remove(ctx, db, d.id)
+	}
+
+	w.logger.Warn(
+		"Failed to deliver webhook event. Retrying later",
+		"endpoint", d.endpoint, "event", d.event, "event_id", d.eventID, "attempts", d.attempts, "err", err,
+	)
+
+	if _, err := db.ExecContext(
+		ctx,
+		fmt.Sprintf("UPDATE %s SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?", outboxTableName),
+		d.attempts, w.now().Add(w.backoff(d.attempts)).UnixMilli(), err.Error(), d.id,
+	); err != nil {
+		return fmt.Errorf("failed to update webhook event in outbox: %w", err)
+	}
+
+	return nil
+}
+
+// send makes the webhook request. It returns an error when the delivery failed and
+// if the delivery can be retried.
+func (w *Webhooks) send(ctx context.Context, e *endpoint, d delivery) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.config.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", base.CEEMSServerAppName)
+	req.Header.Set(EventHeader, d.event)
+	req.Header.Set(DeliveryHeader, d.eventID)
+	req.Header.Set(TimestampHeader, timestamp)
+
+	if len(e.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(e.secret, timestamp, d.payload))
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	// Drain body to reuse connection
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+
+	// Client errors will not be fixed by retrying except for timeouts and rate limits
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return false, err
+	}
+
+	return true, err
+}
+
+// remove deletes a delivery from outbox.
+func (w *Webhooks) remove(ctx context.Context, db *sql.DB, id int64) error {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = ?", outboxTableName), id); err != nil {
+		return fmt.Errorf("failed to delete webhook event from outbox: %w", err)
+	}
+
+	return nil
+}
+
+// backoff returns the exponential backoff after attempts.
+func (w *Webhooks) backoff(attempts int) time.Duration {
+	backoff := time.Duration(w.config.MinBackoff)
+
+	for range attempts - 1 {
+		backoff *= 2
+		if backoff >= time.Duration(w.config.MaxBackoff) {
+			return time.Duration(w.config.MaxBackoff)
+		}
+	}
+
+	return backoff
+}
+
+// endpointNames returns sorted names of endpoints.
+func (w *Webhooks) endpointNames() []string {
+	names := make([]string, 0, len(w.endpoints))
+	for name := range w.endpoints {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// Sign returns the hex encoded HMAC-SHA256 signature of the payload. The signed
+// content is `<timestamp>.<payload>` so that receivers can reject replayed requests.
+func Sign(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify returns nil if signature is valid for payload.
+func Verify(secret []byte, timestamp string, payload []byte, signature string) error {
+	expected := "sha256=" + Sign(secret, timestamp, payload)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return errors.New("invalid webhook signature")
+	}
+
+	return nil
+}
+
+// newEventID returns a random event ID.
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
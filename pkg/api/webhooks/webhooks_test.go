@@ -0,0 +1,445 @@
+//go:build cgo
+// +build cgo
+
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedRequest is a webhook request received by test server.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook endpoint that returns configured status codes.
+type testReceiver struct {
+	mu       sync.Mutex
+	codes    []int
+	requests []receivedRequest
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+
+	code := http.StatusOK
+	if len(r.codes) > 0 {
+		code = r.codes[0]
+		r.codes = r.codes[1:]
+	}
+
+	w.WriteHeader(code)
+}
+
+func setupDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	migration, err := os.ReadFile("../db/migrations/000012_create_webhook_outbox_table.up.sql")
+	require.NoError(t, err)
+
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	return db
+}
+
+func setupWebhooks(t *testing.T, endpoints ...EndpointConfig) *Webhooks {
+	t.Helper()
+
+	for i := range endpoints {
+		endpoints[i].HTTPClientConfig = config.DefaultHTTPClientConfig
+	}
+
+	w, err := New(Config{
+		Endpoints:    endpoints,
+		FailedStates: []string{"FAILED", "TIMEOUT"},
+		LowEfficiency: LowEfficiencyConfig{
+			AvgCPUUsage: 10,
+			AvgGPUUsage: 20,
+			MinWalltime: model.Duration(10 * time.Minute),
+		},
+		MaxRetries: 2,
+		MinBackoff: model.Duration(time.Minute),
+		MaxBackoff: model.Duration(time.Hour),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	return w
+}
+
+// enqueue adds events of unit to outbox.
+func enqueue(t *testing.T, w *Webhooks, db *sql.DB, unit models.Unit, events []string) {
+	t.Helper()
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	require.NoError(t, w.Enqueue(context.Background(), tx, "slurm-0", unit, events))
+	require.NoError(t, tx.Commit())
+}
+
+// outbox returns events in outbox.
+func outbox(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT endpoint, event FROM webhook_outbox ORDER BY id")
+	require.NoError(t, err)
+
+	defer rows.Close()
+
+	var events []string
+
+	for rows.Next() {
+		var endpoint, event string
+		require.NoError(t, rows.Scan(&endpoint, &event))
+
+		events = append(events, endpoint+":"+event)
+	}
+
+	return events
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := Config{}
+	require.NoError(t, c.Validate())
+
+	tests := []struct {
+		name     string
+		endpoint EndpointConfig
+		valid    bool
+	}{
+		{
+			name:     "valid endpoint",
+			endpoint: EndpointConfig{Name: "chat", URL: "https://chat.example.com/hook", Events: []string{UnitEnded}},
+			valid:    true,
+		},
+		{
+			name:     "missing name",
+			endpoint: EndpointConfig{URL: "https://chat.example.com/hook"},
+		},
+		{
+			name:     "invalid url",
+			endpoint: EndpointConfig{Name: "chat", URL: "chat.example.com"},
+		},
+		{
+			name:     "unknown event",
+			endpoint: EndpointConfig{Name: "chat", URL: "https://chat.example.com/hook", Events: []string{"unit.deleted"}},
+		},
+		{
+			name:     "both secret and secret file",
+			endpoint: EndpointConfig{Name: "chat", URL: "https://chat.example.com/hook", Secret: "s3cret", SecretFile: "secret"},
+		},
+	}
+
+	for _, test := range tests {
+		c := Config{
+			Endpoints:        []EndpointConfig{test.endpoint},
+			DeliveryInterval: model.Duration(time.Second),
+			MinBackoff:       model.Duration(time.Second),
+			MaxBackoff:       model.Duration(time.Minute),
+		}
+
+		if test.valid {
+			require.NoError(t, c.Validate(), test.name)
+		} else {
+			require.Error(t, c.Validate(), test.name)
+		}
+	}
+
+	// Duplicate names
+	c = Config{
+		Endpoints: []EndpointConfig{
+			{Name: "chat", URL: "https://chat.example.com/hook"},
+			{Name: "chat", URL: "https://chat.example.com/other"},
+		},
+		DeliveryInterval: model.Duration(time.Second),
+		MinBackoff:       model.Duration(time.Second),
+		MaxBackoff:       model.Duration(time.Minute),
+	}
+	require.Error(t, c.Validate())
+}
+
+func TestEvents(t *testing.T) {
+	w := setupWebhooks(t)
+
+	efficient := models.Unit{
+		StartedAtTS: 1000,
+		EndedAtTS:   2000,
+		State:       "COMPLETED",
+		TotalTime:   models.MetricMap{"walltime": 3600},
+		AveCPUUsage: models.MetricMap{"global": 80},
+		AveGPUUsage: models.MetricMap{"global": 90},
+	}
+
+	tests := []struct {
+		name   string
+		prev   *UnitState
+		unit   func(models.Unit) models.Unit
+		events []string
+	}{
+		{
+			name:   "new pending unit",
+			unit:   func(u models.Unit) models.Unit { u.StartedAtTS, u.EndedAtTS = 0, 0; return u }, //nolint:nlreturn
+			events: nil,
+		},
+		{
+			name:   "new running unit",
+			unit:   func(u models.Unit) models.Unit { u.EndedAtTS = 0; return u }, //nolint:nlreturn
+			events: []string{UnitStarted},
+		},
+		{
+			name:   "pending unit started",
+			prev:   &UnitState{},
+			unit:   func(u models.Unit) models.Unit { u.EndedAtTS = 0; return u }, //nolint:nlreturn
+			events: []string{UnitStarted},
+		},
+		{
+			name:   "running unit still running",
+			prev:   &UnitState{StartedAtTS: 1000},
+			unit:   func(u models.Unit) models.Unit { u.EndedAtTS = 0; return u }, //nolint:nlreturn
+			events: nil,
+		},
+		{
+			name:   "running unit ended",
+			prev:   &UnitState{StartedAtTS: 1000},
+			unit:   func(u models.Unit) models.Unit { return u },
+			events: []string{UnitEnded},
+		},
+		{
+			name:   "new unit that already ended",
+			unit:   func(u models.Unit) models.Unit { return u },
+			events: []string{UnitStarted, UnitEnded},
+		},
+		{
+			name:   "ended unit is updated",
+			prev:   &UnitState{StartedAtTS: 1000, EndedAtTS: 2000},
+			unit:   func(u models.Unit) models.Unit { u.State = "FAILED"; return u }, //nolint:nlreturn
+			events: nil,
+		},
+		{
+			name:   "running unit failed",
+			prev:   &UnitState{StartedAtTS: 1000},
+			unit:   func(u models.Unit) models.Unit { u.State = "TIMEOUT"; return u }, //nolint:nlreturn
+			events: []string{UnitEnded, UnitFailed},
+		},
+		{
+			name:   "running unit cancelled",
+			prev:   &UnitState{StartedAtTS: 1000},
+			unit:   func(u models.Unit) models.Unit { u.State = "CANCELLED by 1000"; return u }, //nolint:nlreturn
+			events: []string{UnitEnded},
+		},
+		{
+			name: "running unit ended with low GPU usage",
+			prev: &UnitState{StartedAtTS: 1000},
+			unit: func(u models.Unit) models.Unit {
+				u.AveGPUUsage = models.MetricMap{"global": 5}
+
+				return u
+			},
+			events: []string{UnitEnded, UnitLowEfficiency},
+		},
+		{
+			name: "running unit failed with low CPU usage",
+			prev: &UnitState{StartedAtTS: 1000},
+			unit: func(u models.Unit) models.Unit {
+				u.State = "FAILED"
+				u.AveCPUUsage = models.MetricMap{"global": 5}
+
+				return u
+			},
+			events: []string{UnitEnded, UnitFailed, UnitLowEfficiency},
+		},
+		{
+			name: "short unit with low CPU usage",
+			prev: &UnitState{StartedAtTS: 1000},
+			unit: func(u models.Unit) models.Unit {
+				u.TotalTime = models.MetricMap{"walltime": 60}
+				u.AveCPUUsage = models.MetricMap{"global": 5}
+
+				return u
+			},
+			events: []string{UnitEnded},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.events, w.Events(test.prev, test.unit(efficient)), test.name)
+	}
+}
+
+func TestEnqueueAndDeliver(t *testing.T) {
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db := setupDB(t)
+	w := setupWebhooks(
+		t,
+		EndpointConfig{Name: "all", URL: server.URL, Secret: "s3cret"},
+		EndpointConfig{Name: "prj1-ended", URL: server.URL, Events: []string{UnitEnded}, Projects: []string{"prj1"}},
+	)
+
+	enqueue(t, w, db, models.Unit{UUID: "1", Project: "prj1", User: "usr1"}, []string{UnitStarted, UnitEnded})
+	enqueue(t, w, db, models.Unit{UUID: "2", Project: "prj2", User: "usr2"}, []string{UnitEnded})
+
+	assert.Equal(t, []string{"all:unit.started", "all:unit.ended", "prj1-ended:unit.ended", "all:unit.ended"}, outbox(t, db))
+
+	// Deliver events
+	require.NoError(t, w.Deliver(context.Background(), db))
+	assert.Empty(t, outbox(t, db))
+	require.Len(t, receiver.requests, 4)
+
+	// Check payload and signature
+	req := receiver.requests[0]
+	assert.Equal(t, UnitStarted, req.header.Get(EventHeader))
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	require.NoError(t, Verify([]byte("s3cret"), req.header.Get(TimestampHeader), req.body, req.header.Get(SignatureHeader)))
+	require.Error(t, Verify([]byte("other"), req.header.Get(TimestampHeader), req.body, req.header.Get(SignatureHeader)))
+
+	var event Event
+	require.NoError(t, json.Unmarshal(req.body, &event))
+	assert.Equal(t, UnitStarted, event.Type)
+	assert.Equal(t, req.header.Get(DeliveryHeader), event.ID)
+	assert.Equal(t, "1", event.Unit.UUID)
+	assert.Equal(t, "slurm-0", event.Unit.ClusterID)
+
+	// Endpoint without secret must not sign requests
+	assert.Empty(t, receiver.requests[2].header.Get(SignatureHeader))
+}
+
+func TestEnqueueAtomic(t *testing.T) {
+	db := setupDB(t)
+	w := setupWebhooks(
+		t,
+		EndpointConfig{Name: "a", URL: "http://localhost"},
+		EndpointConfig{Name: "b", URL: "http://localhost", Events: []string{UnitEnded}},
+	)
+
+	// Fail insertion of events of endpoint b
+	_, err := db.Exec(`CREATE TRIGGER fail_b BEFORE INSERT ON webhook_outbox WHEN NEW.endpoint = 'b'
+BEGIN SELECT RAISE(ABORT, 'failed'); END`)
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	require.NoError(t, w.Enqueue(context.Background(), tx, "slurm-0", models.Unit{UUID: "1"}, []string{UnitStarted}))
+	require.Error(t, w.Enqueue(context.Background(), tx, "slurm-0", models.Unit{UUID: "2"}, []string{UnitStarted, UnitEnded}))
+	require.NoError(t, tx.Commit())
+
+	// Events of unit 1 must be kept and none of unit 2 must be in outbox
+	assert.Equal(t, []string{"a:unit.started"}, outbox(t, db))
+}
+
+func TestDeliverRetries(t *testing.T) {
+	receiver := &testReceiver{
+		codes: []int{
+			http.StatusInternalServerError, // first attempt of unit 1
+			http.StatusBadRequest,          // first attempt of unit 2 which must not be retried
+			http.StatusTooManyRequests,     // second attempt of unit 1
+			http.StatusOK,                  // third attempt of unit 1
+		},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db := setupDB(t)
+	w := setupWebhooks(t, EndpointConfig{Name: "all", URL: server.URL})
+
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	enqueue(t, w, db, models.Unit{UUID: "1"}, []string{UnitEnded})
+	enqueue(t, w, db, models.Unit{UUID: "2"}, []string{UnitEnded})
+
+	// First attempt fails for both and only unit 1 is retained
+	require.NoError(t, w.Deliver(context.Background(), db))
+	assert.Equal(t, []string{"all:unit.ended"}, outbox(t, db))
+
+	var attempts int
+
+	var lastError string
+
+	require.NoError(t, db.QueryRow("SELECT attempts, last_error FROM webhook_outbox").Scan(&attempts, &lastError))
+	assert.Equal(t, 1, attempts)
+	assert.Contains(t, lastError, "500")
+
+	// Event is not due yet
+	require.NoError(t, w.Deliver(context.Background(), db))
+	assert.Len(t, receiver.requests, 2)
+
+	// Second attempt after backoff of 1 minute
+	now = now.Add(time.Minute)
+	require.NoError(t, w.Deliver(context.Background(), db))
+	assert.Len(t, receiver.requests, 3)
+	assert.Len(t, outbox(t, db), 1)
+
+	// Third attempt after backoff of 2 minutes succeeds
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, w.Deliver(context.Background(), db))
+	assert.Len(t, receiver.requests, 4)
+	assert.Empty(t, outbox(t, db))
+}
+
+func TestDeliverMaxRetries(t *testing.T) {
+	receiver := &testReceiver{
+		codes: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+	}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	db := setupDB(t)
+	w := setupWebhooks(t, EndpointConfig{Name: "all", URL: server.URL})
+
+	now := time.Now()
+	w.now = func() time.Time { return now }
+
+	enqueue(t, w, db, models.Unit{UUID: "1"}, []string{UnitEnded})
+
+	// Two retries are allowed after first attempt
+	for range 3 {
+		require.NoError(t, w.Deliver(context.Background(), db))
+
+		now = now.Add(time.Hour)
+	}
+
+	assert.Len(t, receiver.requests, 3)
+	assert.Empty(t, outbox(t, db))
+}
+
+func TestBackoff(t *testing.T) {
+	w := setupWebhooks(t)
+
+	assert.Equal(t, time.Minute, w.backoff(1))
+	assert.Equal(t, 2*time.Minute, w.backoff(2))
+	assert.Equal(t, 32*time.Minute, w.backoff(6))
+	assert.Equal(t, time.Hour, w.backoff(7))
+	assert.Equal(t, time.Hour, w.backoff(20))
+}
@@ -0,0 +1,239 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultRefreshInterval is the default refresh interval of service discovery.
+const DefaultRefreshInterval = model.Duration(30 * time.Second)
+
+// FileSDConfig contains the configuration of file based service discovery of
+// backend servers. Files contain a list of target groups in the same format as
+// Prometheus' file based service discovery.
+type FileSDConfig struct {
+	Files           []string       `yaml:"files"`
+	Scheme          string         `yaml:"scheme"`
+	RefreshInterval model.Duration `yaml:"refresh_interval"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *FileSDConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = FileSDConfig{
+		Scheme:          "http",
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	type plain FileSDConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *FileSDConfig) Validate() error {
+	if len(c.Files) == 0 {
+		return errors.New("files of file_sd must not be empty")
+	}
+
+	for _, f := range c.Files {
+		if _, err := filepath.Match(f, ""); err != nil {
+			return fmt.Errorf("invalid file pattern %s in file_sd: %w", f, err)
+		}
+	}
+
+	return validateSD(c.Scheme, c.RefreshInterval)
+}
+
+// DNSSDConfig contains the configuration of DNS SRV based service discovery of
+// backend servers.
+type DNSSDConfig struct {
+	Names           []string       `yaml:"names"`
+	Scheme          string         `yaml:"scheme"`
+	RefreshInterval model.Duration `yaml:"refresh_interval"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *DNSSDConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = DNSSDConfig{
+		Scheme:          "http",
+		RefreshInterval: DefaultRefreshInterval,
+	}
+
+	type plain DNSSDConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *DNSSDConfig) Validate() error {
+	if len(c.Names) == 0 {
+		return errors.New("names of dns_sd must not be empty")
+	}
+
+	return validateSD(c.Scheme, c.RefreshInterval)
+}
+
+// validateSD validates the common parameters of service discovery configs.
+func validateSD(scheme string, refreshInterval model.Duration) error {
+	if scheme != "http" && scheme != "https" {
+		return fmt.Errorf("invalid scheme %s of service discovery. Only http and https are supported", scheme)
+	}
+
+	if refreshInterval <= 0 {
+		return errors.New("refresh_interval of service discovery must be positive")
+	}
+
+	return nil
+}
+
+// targetGroup is a group of targets in file based service discovery.
+type targetGroup struct {
+	Targets []string `yaml:"targets"`
+}
+
+// RefreshInterval returns the refresh interval of service discovery configs of
+// server. It returns zero when service discovery is not configured.
+func (c *ServerConfig) RefreshInterval() time.Duration {
+	var intervals []time.Duration
+
+	if c.FileSD != nil {
+		intervals = append(intervals, time.Duration(c.FileSD.RefreshInterval))
+	}
+
+	if c.DNSSD != nil {
+		intervals = append(intervals, time.Duration(c.DNSSD.RefreshInterval))
+	}
+
+	if len(intervals) == 0 {
+		return 0
+	}
+
+	return slices.Min(intervals)
+}
+
+// Discover returns the configs of backend servers discovered using service
+// discovery configs. Discovered backend servers use the same HTTP client config
+// and filter labels as the current config. When service discovery is not
+// configured, current config is returned.
+func (c *ServerConfig) Discover(ctx context.Context) ([]*ServerConfig, error) {
+	if c.FileSD == nil && c.DNSSD == nil {
+		return []*ServerConfig{c}, nil
+	}
+
+	var urls []string
+
+	var errs error
+
+	if c.FileSD != nil {
+		targets, err := c.FileSD.targets()
+		errs = errors.Join(errs, err)
+
+		urls = append(urls, targetURLs(c.FileSD.Scheme, targets)...)
+	}
+
+	if c.DNSSD != nil {
+		targets, err := c.DNSSD.targets(ctx)
+		errs = errors.Join(errs, err)
+
+		urls = append(urls, targetURLs(c.DNSSD.Scheme, targets)...)
+	}
+
+	// If there was an error, do not return partial results so that backends
+	// are not removed due to transient errors
+	if errs != nil {
+		return nil, errs
+	}
+
+	configs := make([]*ServerConfig, 0, len(urls))
+
+	for _, u := range slices.Compact(slices.Sorted(slices.Values(urls))) {
+		web := &models.WebConfig{}
+		if c.Web != nil {
+			*web = *c.Web
+		}
+
+		web.URL = u
+
+		configs = append(configs, &ServerConfig{
+			Web:          web,
+			FilterLabels: c.FilterLabels,
+			HealthCheck:  c.HealthCheck,
+		})
+	}
+
+	return configs, nil
+}
+
+// targets returns targets in all the files.
+func (c *FileSDConfig) targets() ([]string, error) {
+	var targets []string
+
+	for _, pattern := range c.Files {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+
+			var groups []targetGroup
+			if err := yaml.Unmarshal(content, &groups); err != nil {
+				return nil, fmt.Errorf("failed to parse file %s: %w", file, err)
+			}
+
+			for _, group := range groups {
+				targets = append(targets, group.Targets...)
+			}
+		}
+	}
+
+	return targets, nil
+}
+
+// targets returns targets of all the SRV records.
+func (c *DNSSDConfig) targets(ctx context.Context) ([]string, error) {
+	var targets []string
+
+	for _, name := range c.Names {
+		_, records, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup SRV records of %s: %w", name, err)
+		}
+
+		for _, record := range records {
+			targets = append(targets, net.JoinHostPort(strings.TrimSuffix(record.Target, "."), strconv.Itoa(int(record.Port))))
+		}
+	}
+
+	return targets, nil
+}
+
+// targetURLs returns URLs of targets using scheme.
+func targetURLs(scheme string, targets []string) []string {
+	urls := make([]string, 0, len(targets))
+
+	for _, target := range targets {
+		urls = append(urls, (&url.URL{Scheme: scheme, Host: target}).String())
+	}
+
+	return urls
+}
@@ -0,0 +1,86 @@
+package backend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSDDiscover(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Write target files
+	targets := `
+- targets:
+    - localhost:9091
+    - localhost:9092
+- targets:
+    - localhost:9091`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "a.yml"), []byte(targets), 0o600))
+
+	targets = `
+- targets:
+    - localhost:9093`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "b.yml"), []byte(targets), 0o600))
+
+	c := &ServerConfig{
+		Web: &models.WebConfig{},
+		FileSD: &FileSDConfig{
+			Files:           []string{filepath.Join(tmpDir, "*.yml")},
+			Scheme:          "https",
+			RefreshInterval: model.Duration(time.Minute),
+		},
+		FilterLabels: []string{"job"},
+	}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, time.Minute, c.RefreshInterval())
+
+	configs, err := c.Discover(context.Background())
+	require.NoError(t, err)
+
+	var urls []string
+	for _, config := range configs {
+		urls = append(urls, config.Web.URL)
+		assert.Equal(t, []string{"job"}, config.FilterLabels)
+	}
+
+	assert.Equal(t, []string{"https://localhost:9091", "https://localhost:9092", "https://localhost:9093"}, urls)
+
+	// Original config must not be modified
+	assert.Empty(t, c.Web.URL)
+
+	// Invalid target file must not return partial results
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "c.yml"), []byte("invalid"), 0o600))
+
+	configs, err = c.Discover(context.Background())
+	require.Error(t, err)
+	assert.Empty(t, configs)
+}
+
+func TestStaticDiscover(t *testing.T) {
+	c := &ServerConfig{Web: &models.WebConfig{URL: "http://localhost:9090"}}
+	require.NoError(t, c.Validate())
+	assert.Equal(t, time.Duration(0), c.RefreshInterval())
+
+	configs, err := c.Discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []*ServerConfig{c}, configs)
+}
+
+func TestDiscoveryConfigFail(t *testing.T) {
+	for _, c := range []*ServerConfig{
+		{Web: &models.WebConfig{}},
+		{FileSD: &FileSDConfig{Scheme: "http", RefreshInterval: DefaultRefreshInterval}},
+		{FileSD: &FileSDConfig{Files: []string{"a.yml"}, Scheme: "ftp", RefreshInterval: DefaultRefreshInterval}},
+		{DNSSD: &DNSSDConfig{Names: []string{"_prom._tcp.example.com"}, Scheme: "http"}},
+	} {
+		assert.Error(t, c.Validate())
+	}
+}
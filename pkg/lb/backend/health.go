@@ -0,0 +1,375 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// Readiness endpoints of backend servers used by active health checks.
+const (
+	tsdbReadyPath = "/-/ready"
+	pyroReadyPath = "/ready"
+	lokiReadyPath = "/ready"
+)
+
+// HealthState is the health state of a backend server.
+type HealthState int
+
+// ErrTrialInFlight is returned for requests to a half open backend server that
+// is already serving its trial request.
+var ErrTrialInFlight = errors.New("backend server is half open and already serving a trial request")
+
+// Health states enum.
+const (
+	// Healthy backend servers receive requests.
+	Healthy HealthState = iota
+	// Unhealthy backend servers failed active health checks.
+	Unhealthy
+	// Ejected backend servers are ejected by outlier detection.
+	Ejected
+	// HalfOpen backend servers are the ejected ones whose ejection time
+	// elapsed. They receive a single trial request and its outcome decides
+	// whether they become healthy or ejected again.
+	HalfOpen
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case Healthy:
+		return "healthy"
+	case Unhealthy:
+		return "unhealthy"
+	case Ejected:
+		return "ejected"
+	case HalfOpen:
+		return "half_open"
+	}
+
+	return "undefined"
+}
+
+// HealthStates are all the health states of backend servers.
+var HealthStates = []HealthState{Healthy, Unhealthy, Ejected, HalfOpen}
+
+// HealthCheckConfig contains the configuration of active health checks and
+// outlier detection of backend servers.
+type HealthCheckConfig struct {
+	Interval           model.Duration         `yaml:"interval"`
+	Timeout            model.Duration         `yaml:"timeout"`
+	HealthyThreshold   int                    `yaml:"healthy_threshold"`
+	UnhealthyThreshold int                    `yaml:"unhealthy_threshold"`
+	OutlierDetection   OutlierDetectionConfig `yaml:"outlier_detection"`
+}
+
+// OutlierDetectionConfig contains the configuration of passive outlier detection
+// of backend servers.
+type OutlierDetectionConfig struct {
+	ConsecutiveFailures int            `yaml:"consecutive_failures"`
+	BaseEjectionTime    model.Duration `yaml:"base_ejection_time"`
+	MaxEjectionTime     model.Duration `yaml:"max_ejection_time"`
+}
+
+// DefaultHealthCheckConfig is the default health check config.
+var DefaultHealthCheckConfig = HealthCheckConfig{
+	Interval:           model.Duration(20 * time.Second),
+	Timeout:            model.Duration(5 * time.Second),
+	HealthyThreshold:   2,
+	UnhealthyThreshold: 3,
+	OutlierDetection: OutlierDetectionConfig{
+		ConsecutiveFailures: 5,
+		BaseEjectionTime:    model.Duration(30 * time.Second),
+		MaxEjectionTime:     model.Duration(5 * time.Minute),
+	},
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *HealthCheckConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = DefaultHealthCheckConfig
+
+	type plain HealthCheckConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *OutlierDetectionConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = DefaultHealthCheckConfig.OutlierDetection
+
+	type plain OutlierDetectionConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *HealthCheckConfig) Validate() error {
+	if c.Interval <= 0 || c.Timeout <= 0 {
+		return errors.New("interval and timeout of health check must be positive")
+	}
+
+	if c.Timeout > c.Interval {
+		return errors.New("timeout of health check must not be greater than interval")
+	}
+
+	if c.HealthyThreshold < 1 || c.UnhealthyThreshold < 1 {
+		return errors.New("healthy_threshold and unhealthy_threshold of health check must be at least 1")
+	}
+
+	// Zero consecutive failures disables outlier detection
+	if c.OutlierDetection.ConsecutiveFailures < 0 {
+		return errors.New("consecutive_failures of outlier detection must not be negative")
+	}
+
+	if c.OutlierDetection.BaseEjectionTime <= 0 || c.OutlierDetection.MaxEjectionTime < c.OutlierDetection.BaseEjectionTime {
+		return errors.New("base_ejection_time of outlier detection must be positive and not greater than max_ejection_time")
+	}
+
+	return nil
+}
+
+// health tracks the health of a backend server using results of active health
+// checks and outcomes of proxied requests. Outcomes of proxied requests are used
+// to eject the backend server after consecutive failures similar to a circuit
+// breaker.
+type health struct {
+	mu              sync.RWMutex
+	config          HealthCheckConfig
+	probeHealthy    bool
+	probeSuccesses  int
+	probeFailures   int
+	failures        int
+	ejected         bool
+	trial           bool
+	ejectedAt       time.Time
+	ejectionTime    time.Duration
+	ejections       int
+	totalEjections  int
+	readinessURL    *url.URL
+	readinessClient *http.Client
+}
+
+// newHealth returns a new instance of health for the backend server at u with
+// readiness endpoint at path. Zero config uses the default config.
+func newHealth(c *HealthCheckConfig, u *url.URL, path string, rt http.RoundTripper) *health {
+	config := DefaultHealthCheckConfig
+	if c != nil && c.Interval > 0 {
+		config = *c
+	}
+
+	return &health{
+		config:          config,
+		probeHealthy:    true,
+		readinessURL:    u.JoinPath(path),
+		readinessClient: &http.Client{Transport: rt},
+	}
+}
+
+// isAlive returns true if the backend server can receive requests. Half open
+// backend server can receive requests only when it is not serving its trial
+// request.
+func (h *health) isAlive() bool {
+	state := h.state()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return state == Healthy || (state == HalfOpen && !h.trial)
+}
+
+// admit returns false if the backend server is half open and already serving
+// its trial request. Otherwise, the request is admitted and the returned function
+// must be called once it has been served.
+func (h *health) admit() (func(), bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Backend server is not half open
+	if !h.ejected || time.Since(h.ejectedAt) < h.ejectionTime {
+		return func() {}, true
+	}
+
+	if h.trial {
+		return nil, false
+	}
+
+	h.trial = true
+
+	// Outcome of trial request is recorded by observe. When it is not, like for
+	// requests cancelled by client, the next request becomes the trial request
+	return func() {
+		h.mu.Lock()
+		h.trial = false
+		h.mu.Unlock()
+	}, true
+}
+
+// rejectRequest rejects the request that cannot be served by backend server
+// using the error handler of reverse proxy so that the request can be retried
+// on other backend servers.
+func rejectRequest(rp *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request) {
+	if rp.ErrorHandler != nil {
+		rp.ErrorHandler(w, r, ErrTrialInFlight)
+
+		return
+	}
+
+	http.Error(w, ErrTrialInFlight.Error(), http.StatusServiceUnavailable)
+}
+
+// setAlive forces the health of backend server.
+func (h *health) setAlive(alive bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.probeHealthy = alive
+	h.probeSuccesses = 0
+	h.probeFailures = 0
+
+	// Reset the circuit when backend server is marked as alive
+	if alive {
+		h.failures = 0
+		h.ejected = false
+		h.trial = false
+		h.ejections = 0
+	}
+}
+
+// state returns the current health state of backend server.
+func (h *health) state() HealthState {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	switch {
+	case !h.probeHealthy:
+		return Unhealthy
+	case h.ejected && time.Since(h.ejectedAt) < h.ejectionTime:
+		return Ejected
+	case h.ejected:
+		return HalfOpen
+	}
+
+	return Healthy
+}
+
+// numEjections returns the total number of ejections of backend server.
+func (h *health) numEjections() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.totalEjections
+}
+
+// probe makes a request to readiness endpoint of backend server and records
+// the result.
+func (h *health) probe(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(h.config.Timeout))
+	defer cancel()
+
+	err := h.ready(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.probeSuccesses = 0
+		h.probeFailures++
+
+		if h.probeFailures >= h.config.UnhealthyThreshold {
+			h.probeHealthy = false
+		}
+
+		return err
+	}
+
+	h.probeFailures = 0
+	h.probeSuccesses++
+
+	if h.probeSuccesses >= h.config.HealthyThreshold {
+		h.probeHealthy = true
+	}
+
+	return nil
+}
+
+// ready returns an error if the readiness endpoint of backend server does not
+// return a successful response.
+func (h *health) ready(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.readinessURL.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.readinessClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("readiness endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// observe records the outcome of a proxied request. Backend server is ejected
+// after consecutive failures and an ejected backend server whose ejection time
+// elapsed is either restored or ejected again based on the outcome.
+func (h *health) observe(failed bool) {
+	// Outlier detection is disabled
+	if h.config.OutlierDetection.ConsecutiveFailures == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	halfOpen := h.ejected && time.Since(h.ejectedAt) >= h.ejectionTime
+
+	// Outcome of a request in half open state ends the trial
+	if halfOpen {
+		h.trial = false
+	}
+
+	if !failed {
+		h.failures = 0
+
+		// Close the circuit after a successful request in half open state
+		if halfOpen {
+			h.ejected = false
+			h.ejections = 0
+		}
+
+		return
+	}
+
+	// Ignore failures of requests that were in flight when backend was ejected
+	if h.ejected && !halfOpen {
+		return
+	}
+
+	h.failures++
+
+	if !halfOpen && h.failures < h.config.OutlierDetection.ConsecutiveFailures {
+		return
+	}
+
+	// Eject backend server. Ejection time increases exponentially with number
+	// of consecutive ejections
+	h.ejected = true
+	h.ejectedAt = time.Now()
+	h.ejectionTime = time.Duration(h.config.OutlierDetection.BaseEjectionTime) << min(h.ejections, 16)
+	h.ejectionTime = min(h.ejectionTime, time.Duration(h.config.OutlierDetection.MaxEjectionTime))
+	h.ejections++
+	h.totalEjections++
+	h.failures = 0
+}
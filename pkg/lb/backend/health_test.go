@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthProbe(t *testing.T) {
+	var ready atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tsdbReadyPath || !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	h := newHealth(nil, u, tsdbReadyPath, http.DefaultTransport)
+	assert.Equal(t, Healthy, h.state())
+
+	// Backend must be unhealthy only after unhealthy_threshold failures
+	for i := range DefaultHealthCheckConfig.UnhealthyThreshold {
+		assert.Equal(t, Healthy, h.state(), "probe %d", i)
+		require.Error(t, h.probe(context.Background()))
+	}
+
+	assert.Equal(t, Unhealthy, h.state())
+	assert.False(t, h.isAlive())
+
+	// Backend must be healthy only after healthy_threshold successes
+	ready.Store(true)
+
+	for i := range DefaultHealthCheckConfig.HealthyThreshold {
+		assert.Equal(t, Unhealthy, h.state(), "probe %d", i)
+		require.NoError(t, h.probe(context.Background()))
+	}
+
+	assert.Equal(t, Healthy, h.state())
+	assert.True(t, h.isAlive())
+}
+
+func TestHealthOutlierDetection(t *testing.T) {
+	config := DefaultHealthCheckConfig
+	config.OutlierDetection = OutlierDetectionConfig{
+		ConsecutiveFailures: 2,
+		BaseEjectionTime:    model.Duration(50 * time.Millisecond),
+		MaxEjectionTime:     model.Duration(time.Second),
+	}
+
+	h := newHealth(&config, &url.URL{}, tsdbReadyPath, http.DefaultTransport)
+
+	// Successful requests reset consecutive failures
+	h.observe(true)
+	h.observe(false)
+	h.observe(true)
+	assert.Equal(t, Healthy, h.state())
+
+	// Backend is ejected after consecutive failures
+	h.observe(true)
+	assert.Equal(t, Ejected, h.state())
+	assert.False(t, h.isAlive())
+	assert.Equal(t, 1, h.numEjections())
+
+	// Backend is half open after ejection time and a failure ejects it again
+	// for a longer time
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, HalfOpen, h.state())
+	assert.True(t, h.isAlive())
+
+	h.observe(true)
+	assert.Equal(t, Ejected, h.state())
+	assert.Equal(t, 2, h.numEjections())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, Ejected, h.state())
+
+	// A success in half open state closes the circuit
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, HalfOpen, h.state())
+
+	h.observe(false)
+	assert.Equal(t, Healthy, h.state())
+	assert.Equal(t, 2, h.numEjections())
+}
+
+func TestHealthOutlierDetectionDisabled(t *testing.T) {
+	config := DefaultHealthCheckConfig
+	config.OutlierDetection.ConsecutiveFailures = 0
+
+	h := newHealth(&config, &url.URL{}, tsdbReadyPath, http.DefaultTransport)
+
+	for range 10 {
+		h.observe(true)
+	}
+
+	assert.Equal(t, Healthy, h.state())
+	assert.Equal(t, 0, h.numEjections())
+}
+
+func TestHealthHalfOpenSingleTrial(t *testing.T) {
+	config := DefaultHealthCheckConfig
+	config.OutlierDetection = OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    model.Duration(50 * time.Millisecond),
+		MaxEjectionTime:     model.Duration(time.Second),
+	}
+
+	// Backend blocks queries until released
+	var queries atomic.Int64
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			return
+		}
+
+		queries.Add(1)
+		<-release
+	}))
+	defer server.Close()
+
+	b, err := NewTSDB(
+		&ServerConfig{Web: &models.WebConfig{URL: server.URL}, HealthCheck: &config},
+		slog.New(slog.NewTextHandler(io.Discard, nil)),
+	)
+	require.NoError(t, err)
+
+	// Eject backend and wait until it is half open
+	b.Observe(true)
+	assert.Equal(t, Ejected, b.HealthState())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, HalfOpen, b.HealthState())
+	assert.True(t, b.IsAlive())
+
+	// Only one of the concurrent requests must reach the backend
+	codes := make([]int, 5)
+
+	var wg sync.WaitGroup
+
+	for i := range codes {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			b.Serve(w, httptest.NewRequest(http.MethodGet, "/api/v1/query?query=foo", nil))
+			codes[i] = w.Code
+		}(i)
+	}
+
+	require.Eventually(t, func() bool { return queries.Load() == 1 }, time.Second, time.Millisecond)
+
+	// Backend is not alive while trial request is in flight
+	assert.False(t, b.IsAlive())
+
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), queries.Load())
+
+	var rejected int
+
+	for _, code := range codes {
+		if code == http.StatusServiceUnavailable {
+			rejected++
+		}
+	}
+
+	assert.Equal(t, len(codes)-1, rejected)
+
+	// Successful trial request closes the circuit
+	assert.Equal(t, Healthy, b.HealthState())
+	assert.True(t, b.IsAlive())
+}
+
+func TestHealthHalfOpenTrialWithoutOutcome(t *testing.T) {
+	config := DefaultHealthCheckConfig
+	config.OutlierDetection = OutlierDetectionConfig{
+		ConsecutiveFailures: 1,
+		BaseEjectionTime:    model.Duration(10 * time.Millisecond),
+		MaxEjectionTime:     model.Duration(time.Second),
+	}
+
+	h := newHealth(&config, &url.URL{}, tsdbReadyPath, http.DefaultTransport)
+
+	h.observe(true)
+	time.Sleep(20 * time.Millisecond)
+
+	done, ok := h.admit()
+	require.True(t, ok)
+
+	_, ok = h.admit()
+	require.False(t, ok)
+
+	// Trial request ended without outcome, like when cancelled by client, lets
+	// the next request be the trial request
+	done()
+
+	assert.True(t, h.isAlive())
+
+	_, ok = h.admit()
+	require.True(t, ok)
+}
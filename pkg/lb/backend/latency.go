@@ -0,0 +1,63 @@
+package backend
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Decay time of peak EWMA of response latencies.
+const latencyDecayTime = 10 * time.Second
+
+// peakEWMA tracks the exponentially weighted moving average of response latencies
+// of a backend server. Latencies higher than the current average replace it so
+// that the average reacts quickly to slow downs and decays slowly afterwards.
+type peakEWMA struct {
+	mu       sync.Mutex
+	value    float64
+	lastSeen time.Time
+	decay    time.Duration
+}
+
+// newPeakEWMA returns a new instance of peakEWMA.
+func newPeakEWMA() *peakEWMA {
+	return &peakEWMA{decay: latencyDecayTime}
+}
+
+// observe records the latency of a response.
+func (e *peakEWMA) observe(latency time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	elapsed := now.Sub(e.lastSeen)
+	e.lastSeen = now
+
+	rtt := float64(latency)
+
+	if rtt > e.value {
+		e.value = rtt
+
+		return
+	}
+
+	w := math.Exp(-float64(elapsed) / float64(e.decay))
+	e.value = e.value*w + rtt*(1-w)
+}
+
+// get returns the current average latency. Average decays towards zero with
+// the time elapsed since the last response so that a backend server that was
+// slow once is not starved forever and gets requests to re-estimate its
+// latency.
+func (e *peakEWMA) get() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.value == 0 {
+		return 0
+	}
+
+	elapsed := time.Since(e.lastSeen)
+
+	return time.Duration(e.value * math.Exp(-float64(elapsed)/float64(e.decay)))
+}
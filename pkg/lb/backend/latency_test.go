@@ -0,0 +1,29 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeakEWMA(t *testing.T) {
+	e := &peakEWMA{decay: 100 * time.Millisecond}
+
+	// No latency before any response
+	assert.Zero(t, e.get())
+
+	// Peak latency replaces the average
+	e.observe(10 * time.Millisecond)
+	e.observe(time.Second)
+	assert.InDelta(t, float64(time.Second), float64(e.get()), float64(10*time.Millisecond))
+
+	// Average decays without any responses so that slow backend servers are
+	// not starved
+	time.Sleep(300 * time.Millisecond)
+	assert.Less(t, e.get(), 100*time.Millisecond)
+
+	// Lower latencies are averaged
+	e.observe(time.Millisecond)
+	assert.Less(t, e.get(), 100*time.Millisecond)
+}
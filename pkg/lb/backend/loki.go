@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/config"
+)
+
+// lokiServer implements a given backend Loki server.
+type lokiServer struct {
+	url          *url.URL
+	config       *ServerConfig
+	mux          sync.RWMutex
+	connections  int
+	reverseProxy *httputil.ReverseProxy
+	health       *health
+	latency      *peakEWMA
+	logger       *slog.Logger
+}
+
+// NewLoki returns an instance of backend Loki server.
+func NewLoki(c *ServerConfig, logger *slog.Logger) (Server, error) {
+	webURL, err := url.Parse(c.Web.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a HTTP roundtripper
+	httpRoundTripper, err := config.NewRoundTripperFromConfig(c.Web.HTTPClientConfig, "ceems_lb", config.WithUserAgent("ceems_lb/"+webURL.Host))
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup reverse proxy
+	rp := httputil.NewSingleHostReverseProxy(webURL)
+	rp.Transport = httpRoundTripper
+
+	server := &lokiServer{
+		url:          webURL,
+		config:       c,
+		reverseProxy: rp,
+		health:       newHealth(c.HealthCheck, webURL, lokiReadyPath, httpRoundTripper),
+		latency:      newPeakEWMA(),
+		logger:       logger,
+	}
+
+	// Record outcome of each proxied request before modifying response
+	modifier := LokiResponseModifier(c.FilterLabels)
+	rp.ModifyResponse = func(r *http.Response) error {
+		server.Observe(r.StatusCode >= http.StatusInternalServerError)
+
+		return modifier(r)
+	}
+
+	return server, nil
+}
+
+// RetentionPeriod is retention period of backend Loki server.
+func (b *lokiServer) RetentionPeriod() time.Duration {
+	// Return a very long duration so that query will always
+	// get proxied to one of the backends
+	return 10 * 365 * 24 * time.Hour
+}
+
+// String returns name/web URL backend Loki server.
+func (b *lokiServer) String() string {
+	if b.url != nil {
+		return "url: " + b.url.Redacted()
+	}
+
+	return "No backend found"
+}
+
+// ActiveConnections is current number of active connections.
+func (b *lokiServer) ActiveConnections() int {
+	b.mux.RLock()
+	connections := b.connections
+	b.mux.RUnlock()
+
+	return connections
+}
+
+// SetAlive sets the backend Loki server as alive.
+func (b *lokiServer) SetAlive(alive bool) {
+	b.health.setAlive(alive)
+}
+
+// IsAlive returns if backend Loki server is alive.
+func (b *lokiServer) IsAlive() bool {
+	return b.health.isAlive()
+}
+
+// HealthState returns the health state of backend Loki server.
+func (b *lokiServer) HealthState() HealthState {
+	return b.health.state()
+}
+
+// Ejections returns the number of times backend Loki server has been ejected.
+func (b *lokiServer) Ejections() int {
+	return b.health.numEjections()
+}
+
+// Probe checks the readiness of backend Loki server.
+func (b *lokiServer) Probe(ctx context.Context) error {
+	return b.health.probe(ctx)
+}
+
+// Observe records the outcome of a request proxied to backend Loki server.
+func (b *lokiServer) Observe(failed bool) {
+	b.health.observe(failed)
+}
+
+// Latency returns the peak EWMA of response latencies of backend Loki server.
+func (b *lokiServer) Latency() time.Duration {
+	return b.latency.get()
+}
+
+// URL of the backend Loki server.
+func (b *lokiServer) URL() *url.URL {
+	return b.url
+}
+
+// Config is the config of backend Loki server.
+func (b *lokiServer) Config() *ServerConfig {
+	return b.config
+}
+
+// ReverseProxy is reverse proxy of backend Loki server.
+func (b *lokiServer) ReverseProxy() *httputil.ReverseProxy {
+	return b.reverseProxy
+}
+
+// Serve the request by the backend Loki server.
+func (b *lokiServer) Serve(w http.ResponseWriter, r *http.Request) {
+	// Half open backend server serves only one trial request at a time
+	done, ok := b.health.admit()
+	if !ok {
+		rejectRequest(b.reverseProxy, w, r)
+
+		return
+	}
+	defer done()
+
+	defer func() {
+		b.mux.Lock()
+		b.connections--
+		b.mux.Unlock()
+	}()
+
+	// Always strip Authorization header from request so that
+	// roundtripper can add one that is configured in the config
+	r.Header.Del("Authorization")
+
+	b.mux.Lock()
+	b.connections++
+	b.mux.Unlock()
+
+	start := time.Now()
+	b.reverseProxy.ServeHTTP(w, r)
+	b.latency.observe(time.Since(start))
+}
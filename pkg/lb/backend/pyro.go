@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 	"net/http/httputil"
@@ -14,10 +15,12 @@ import (
 // pyroServer implements a given backend Pyroscope server.
 type pyroServer struct {
 	url          *url.URL
-	alive        bool
+	config       *ServerConfig
 	mux          sync.RWMutex
 	connections  int
 	reverseProxy *httputil.ReverseProxy
+	health       *health
+	latency      *peakEWMA
 	logger       *slog.Logger
 }
 
@@ -38,12 +41,23 @@ func NewPyroscope(c *ServerConfig, logger *slog.Logger) (Server, error) {
 	rp := httputil.NewSingleHostReverseProxy(webURL)
 	rp.Transport = httpRoundTripper
 
-	return &pyroServer{
+	server := &pyroServer{
 		url:          webURL,
-		alive:        true,
+		config:       c,
 		reverseProxy: rp,
+		health:       newHealth(c.HealthCheck, webURL, pyroReadyPath, httpRoundTripper),
+		latency:      newPeakEWMA(),
 		logger:       logger,
-	}, nil
+	}
+
+	// Record outcome of each proxied request
+	rp.ModifyResponse = func(r *http.Response) error {
+		server.Observe(r.StatusCode >= http.StatusInternalServerError)
+
+		return nil
+	}
+
+	return server, nil
 }
 
 // RetentionPeriod is retention period of backend Pyroscope server.
@@ -73,18 +87,37 @@ func (b *pyroServer) ActiveConnections() int {
 
 // SetAlive sets the backend Pyroscope server as alive.
 func (b *pyroServer) SetAlive(alive bool) {
-	b.mux.Lock()
-	b.alive = alive
-	b.mux.Unlock()
+	b.health.setAlive(alive)
 }
 
 // IsAlive returns if backend Pyroscope server is alive.
 func (b *pyroServer) IsAlive() bool {
-	b.mux.RLock()
-	alive := b.alive
-	defer b.mux.RUnlock()
+	return b.health.isAlive()
+}
 
-	return alive
+// HealthState returns the health state of backend Pyroscope server.
+func (b *pyroServer) HealthState() HealthState {
+	return b.health.state()
+}
+
+// Ejections returns the number of times backend Pyroscope server has been ejected.
+func (b *pyroServer) Ejections() int {
+	return b.health.numEjections()
+}
+
+// Probe checks the readiness of backend Pyroscope server.
+func (b *pyroServer) Probe(ctx context.Context) error {
+	return b.health.probe(ctx)
+}
+
+// Observe records the outcome of a request proxied to backend Pyroscope server.
+func (b *pyroServer) Observe(failed bool) {
+	b.health.observe(failed)
+}
+
+// Latency returns the peak EWMA of response latencies of backend Pyroscope server.
+func (b *pyroServer) Latency() time.Duration {
+	return b.latency.get()
 }
 
 // URL of the backend Pyroscope server.
@@ -92,6 +125,11 @@ func (b *pyroServer) URL() *url.URL {
 	return b.url
 }
 
+// Config is the config of backend Pyroscope server.
+func (b *pyroServer) Config() *ServerConfig {
+	return b.config
+}
+
 // ReverseProxy is reverse proxy of backend TSDB server.
 func (b *pyroServer) ReverseProxy() *httputil.ReverseProxy {
 	return b.reverseProxy
@@ -99,6 +137,15 @@ func (b *pyroServer) ReverseProxy() *httputil.ReverseProxy {
 
 // Serves the request by the backend Pyroscope server.
 func (b *pyroServer) Serve(w http.ResponseWriter, r *http.Request) {
+	// Half open backend server serves only one trial request at a time
+	done, ok := b.health.admit()
+	if !ok {
+		rejectRequest(b.reverseProxy, w, r)
+
+		return
+	}
+	defer done()
+
 	defer func() {
 		b.mux.Lock()
 		b.connections--
@@ -112,5 +159,8 @@ func (b *pyroServer) Serve(w http.ResponseWriter, r *http.Request) {
 	b.mux.Lock()
 	b.connections++
 	b.mux.Unlock()
+
+	start := time.Now()
 	b.reverseProxy.ServeHTTP(w, r)
+	b.latency.observe(time.Since(start))
 }
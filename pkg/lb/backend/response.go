@@ -157,3 +157,145 @@ func PromResponseModifier(labelsToFilter []string) func(r *http.Response) error
 		return nil
 	}
 }
+
+// LokiResponseModifier modifies the Loki response before sending to client.
+func LokiResponseModifier(labelsToFilter []string) func(r *http.Response) error {
+	return func(r *http.Response) error {
+		// If there are no labels to filter or if the response is an error, return.
+		// Loki returns errors as plain text
+		if len(labelsToFilter) == 0 || r.StatusCode != http.StatusOK {
+			return nil
+		}
+
+		// Read response body
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		defer r.Body.Close()
+
+		newBody := b
+
+		// Get correct struct to read body into
+		switch {
+		case strings.HasSuffix(r.Request.URL.Path, "query") || strings.HasSuffix(r.Request.URL.Path, "query_range"):
+			// Read response bytes into Loki response. Data is kept as raw messages
+			// so that only labels of streams and series are modified
+			var lokiResp tsdb.Response[map[string]json.RawMessage]
+			if err = json.Unmarshal(b, &lokiResp); err != nil {
+				return err
+			}
+
+			// Ensure that result exists
+			var results []map[string]json.RawMessage
+			if err = json.Unmarshal(lokiResp.Data["result"], &results); err != nil || results == nil {
+				break
+			}
+
+			// Remove labelsToFilter from labels of streams (log queries)
+			// and metrics (metric queries)
+			for iresult := range results {
+				for _, key := range []string{"stream", "metric"} {
+					if _, ok := results[iresult][key]; !ok {
+						continue
+					}
+
+					var lbls map[string]string
+					if err = json.Unmarshal(results[iresult][key], &lbls); err != nil {
+						return err
+					}
+
+					for _, label := range labelsToFilter {
+						delete(lbls, label)
+					}
+
+					if results[iresult][key], err = json.Marshal(lbls); err != nil {
+						return err
+					}
+				}
+			}
+
+			if lokiResp.Data["result"], err = json.Marshal(results); err != nil {
+				return err
+			}
+
+			// Marshal into newBody
+			if newBody, err = json.Marshal(lokiResp); err != nil {
+				return err
+			}
+		case strings.HasSuffix(r.Request.URL.Path, "series"):
+			// Read response bytes into Loki response
+			var lokiResp tsdb.Response[[]map[string]string]
+			if err = json.Unmarshal(b, &lokiResp); err != nil {
+				return err
+			}
+
+			// Ensure that Data exists
+			if lokiResp.Data == nil {
+				break
+			}
+
+			// Remove labelsToFilter from response
+			for _, label := range labelsToFilter {
+				for idata := range lokiResp.Data {
+					delete(lokiResp.Data[idata], label)
+				}
+			}
+
+			// Marshal into newBody
+			if newBody, err = json.Marshal(lokiResp); err != nil {
+				return err
+			}
+		case strings.HasSuffix(r.Request.URL.Path, "labels"):
+			// Read response bytes into Loki response
+			var lokiResp tsdb.Response[[]string]
+			if err = json.Unmarshal(b, &lokiResp); err != nil {
+				return err
+			}
+
+			// Ensure that Data exists
+			if lokiResp.Data == nil {
+				break
+			}
+
+			// Remove labelsToFilter from response
+			lokiResp.Data = slices.DeleteFunc(lokiResp.Data, func(l string) bool {
+				return slices.Contains(labelsToFilter, l)
+			})
+
+			// Marshal into newBody
+			if newBody, err = json.Marshal(lokiResp); err != nil {
+				return err
+			}
+		case strings.HasSuffix(r.Request.URL.Path, "values"):
+			// Get label name from URL path and check if it is in list of labelsToFilter
+			matches := regexLabelValues.FindStringSubmatch(r.Request.URL.Path)
+			if len(matches) < 2 || !slices.Contains(labelsToFilter, matches[1]) {
+				break
+			}
+
+			// Read response bytes into Loki response
+			var lokiResp tsdb.Response[[]string]
+			if err = json.Unmarshal(b, &lokiResp); err != nil {
+				return err
+			}
+
+			// Replace Data in the response with nil
+			lokiResp.Data = nil
+
+			// Marshal into newBody
+			if newBody, err = json.Marshal(lokiResp); err != nil {
+				return err
+			}
+		}
+
+		// Set it to response body
+		r.Body = io.NopCloser(bytes.NewReader(newBody))
+
+		// Set Content Length to newBody
+		r.ContentLength = int64(len(newBody))
+		r.Header.Set("Content-Length", strconv.Itoa(len(newBody)))
+
+		return nil
+	}
+}
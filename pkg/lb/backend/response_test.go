@@ -135,3 +135,92 @@ func TestPromReverseProxyModifyResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestLokiReverseProxyModifyResponse(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var resp string
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "query"):
+			resp = `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"job":"test","hostname":"example"},"value":[1735209000,"1"]}],"stats":{"summary":{"bytesProcessedPerSecond":10}}}}`
+		case strings.HasSuffix(r.URL.Path, "query_range"):
+			resp = `{"status":"success","data":{"resultType":"streams","result":[{"stream":{"job":"test","instance":"example.com:9010"},"values":[["1735209000000000000","log line"]]}],"stats":{"summary":{"bytesProcessedPerSecond":10}}}}`
+		case strings.HasSuffix(r.URL.Path, "series"):
+			resp = `{"status":"success","data":[{"job":"test","hostname":"example"}]}`
+		case strings.HasSuffix(r.URL.Path, "labels"):
+			resp = `{"status":"success","data":["job","instance","hostname"]}`
+		case strings.HasSuffix(r.URL.Path, "values"):
+			resp = `{"status":"success","data":["value1","value2"]}`
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("parse error: unexpected hostname"))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(resp))
+	}))
+	defer backendServer.Close()
+
+	rpURL, err := url.Parse(backendServer.URL)
+	require.NoError(t, err)
+
+	rproxy := httputil.NewSingleHostReverseProxy(rpURL)
+
+	// Labels to filter
+	labelsToFilter := []string{"instance", "hostname"}
+	rproxy.ModifyResponse = LokiResponseModifier(labelsToFilter) //nolint:bodyclose // Ref: https://github.com/timakin/bodyclose/issues/11
+
+	frontendProxy := httptest.NewServer(rproxy)
+	defer frontendProxy.Close()
+
+	for _, tt := range []string{
+		frontendProxy.URL + "/loki/api/v1/query",
+		frontendProxy.URL + "/loki/api/v1/query_range",
+		frontendProxy.URL + "/loki/api/v1/series",
+		frontendProxy.URL + "/loki/api/v1/labels",
+		frontendProxy.URL + "/loki/api/v1/label/instance/values",
+		frontendProxy.URL + "/loki/api/v1/label/job/values",
+	} {
+		resp, err := http.Get(tt) //nolint:gosec,noctx
+		require.NoError(t, err)
+
+		// Read response body
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		for _, label := range labelsToFilter {
+			if strings.Contains(string(b), label) {
+				assert.Fail(t, "response for %s contains filtered label %s", tt, label)
+			}
+		}
+
+		// Rest of the response must be intact
+		if strings.Contains(tt, "query") {
+			assert.Contains(t, string(b), "bytesProcessedPerSecond", tt)
+		}
+
+		if !strings.Contains(tt, "values") {
+			assert.Contains(t, string(b), "job", tt)
+		}
+
+		if strings.Contains(tt, "/instance/values") {
+			assert.NotContains(t, string(b), "value1", tt)
+		} else if strings.Contains(tt, "/job/values") {
+			assert.Contains(t, string(b), "value1", tt)
+		}
+	}
+
+	// Error responses must be proxied as such
+	resp, err := http.Get(frontendProxy.URL + "/loki/api/v1/unknown") //nolint:noctx
+	require.NoError(t, err)
+
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "parse error: unexpected hostname", string(b))
+}
@@ -14,7 +14,9 @@ func New(t base.LBType, c *ServerConfig, logger *slog.Logger) (Server, error) {
 		return NewTSDB(c, logger)
 	case base.PyroLB:
 		return NewPyroscope(c, logger)
+	case base.LokiLB:
+		return NewLoki(c, logger)
 	}
 
-	return nil, errors.New("unknown load balancer type. Only tsdb and pyroscope types supported")
+	return nil, errors.New("unknown load balancer type. Only tsdb, pyroscope and loki types supported")
 }
@@ -19,6 +19,10 @@ func TestNewServer(t *testing.T) {
 	_, err = New(base.PyroLB, &ServerConfig{Web: &models.WebConfig{URL: "http://localhost:9090"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
 	require.NoError(t, err)
 
+	// Loki
+	_, err = New(base.LokiLB, &ServerConfig{Web: &models.WebConfig{URL: "http://localhost:3100"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
 	// Unknown
 	_, err = New(base.LBType(4), &ServerConfig{Web: &models.WebConfig{URL: "http://localhost:9090"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
 	require.Error(t, err)
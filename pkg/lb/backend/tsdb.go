@@ -18,13 +18,15 @@ import (
 // tsdbServer implements a given backend TSDB server.
 type tsdbServer struct {
 	url             *url.URL
-	alive           bool
+	config          *ServerConfig
 	mux             sync.RWMutex
 	connections     int
 	retentionPeriod time.Duration
 	lastUpdate      time.Time
 	updateInterval  time.Duration
 	reverseProxy    *httputil.ReverseProxy
+	health          *health
+	latency         *peakEWMA
 	client          *tsdb.Client
 	logger          *slog.Logger
 }
@@ -44,7 +46,6 @@ func NewTSDB(c *ServerConfig, logger *slog.Logger) (Server, error) {
 
 	// Setup reverse proxy
 	rp := httputil.NewSingleHostReverseProxy(webURL)
-	rp.ModifyResponse = PromResponseModifier(c.FilterLabels) //nolint:bodyclose
 	rp.Transport = httpRoundTripper
 
 	// Create a new API client
@@ -56,13 +57,23 @@ func NewTSDB(c *ServerConfig, logger *slog.Logger) (Server, error) {
 	// Make server struct
 	server := &tsdbServer{
 		url:            webURL,
-		alive:          true,
+		config:         c,
 		reverseProxy:   rp,
+		health:         newHealth(c.HealthCheck, webURL, tsdbReadyPath, httpRoundTripper),
+		latency:        newPeakEWMA(),
 		updateInterval: 3 * time.Hour,
 		client:         client,
 		logger:         logger,
 	}
 
+	// Record outcome of each proxied request before modifying response
+	modifier := PromResponseModifier(c.FilterLabels)
+	rp.ModifyResponse = func(r *http.Response) error {
+		server.Observe(r.StatusCode >= http.StatusInternalServerError)
+
+		return modifier(r)
+	}
+
 	// Update retention period
 	server.RetentionPeriod()
 
@@ -120,18 +131,37 @@ func (b *tsdbServer) ActiveConnections() int {
 
 // SetAlive sets the backend TSDB server as alive.
 func (b *tsdbServer) SetAlive(alive bool) {
-	b.mux.Lock()
-	b.alive = alive
-	b.mux.Unlock()
+	b.health.setAlive(alive)
 }
 
 // IsAlive returns if backend TSDB server is alive.
 func (b *tsdbServer) IsAlive() bool {
-	b.mux.RLock()
-	alive := b.alive
-	defer b.mux.RUnlock()
+	return b.health.isAlive()
+}
+
+// HealthState returns the health state of backend TSDB server.
+func (b *tsdbServer) HealthState() HealthState {
+	return b.health.state()
+}
+
+// Ejections returns the number of times backend TSDB server has been ejected.
+func (b *tsdbServer) Ejections() int {
+	return b.health.numEjections()
+}
 
-	return alive
+// Probe checks the readiness of backend TSDB server.
+func (b *tsdbServer) Probe(ctx context.Context) error {
+	return b.health.probe(ctx)
+}
+
+// Observe records the outcome of a request proxied to backend TSDB server.
+func (b *tsdbServer) Observe(failed bool) {
+	b.health.observe(failed)
+}
+
+// Latency returns the peak EWMA of response latencies of backend TSDB server.
+func (b *tsdbServer) Latency() time.Duration {
+	return b.latency.get()
 }
 
 // URL of backend TSDB server.
@@ -139,6 +169,11 @@ func (b *tsdbServer) URL() *url.URL {
 	return b.url
 }
 
+// Config is the config of backend TSDB server.
+func (b *tsdbServer) Config() *ServerConfig {
+	return b.config
+}
+
 // ReverseProxy is reverse proxy of backend TSDB server.
 func (b *tsdbServer) ReverseProxy() *httputil.ReverseProxy {
 	return b.reverseProxy
@@ -146,6 +181,15 @@ func (b *tsdbServer) ReverseProxy() *httputil.ReverseProxy {
 
 // Serve the request by the backend TSDB server.
 func (b *tsdbServer) Serve(w http.ResponseWriter, r *http.Request) {
+	// Half open backend server serves only one trial request at a time
+	done, ok := b.health.admit()
+	if !ok {
+		rejectRequest(b.reverseProxy, w, r)
+
+		return
+	}
+	defer done()
+
 	defer func() {
 		b.mux.Lock()
 		b.connections--
@@ -159,7 +203,10 @@ func (b *tsdbServer) Serve(w http.ResponseWriter, r *http.Request) {
 	b.mux.Lock()
 	b.connections++
 	b.mux.Unlock()
+
+	start := time.Now()
 	b.reverseProxy.ServeHTTP(w, r)
+	b.latency.observe(time.Since(start))
 }
 
 // Fetches retention period from backend TSDB server.
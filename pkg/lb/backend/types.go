@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httputil"
@@ -8,6 +9,7 @@ import (
 	"time"
 
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/prometheus/common/config"
 )
 
 // Custom errors.
@@ -15,13 +17,19 @@ var (
 	ErrTypeAssertion = errors.New("failed type assertion")
 )
 
-// Server is the interface each backend server (TSDB/Pyroscope) needs to implement.
+// Server is the interface each backend server (TSDB/Pyroscope/Loki) needs to implement.
 type Server interface {
 	SetAlive(alive bool)
 	IsAlive() bool
+	HealthState() HealthState
+	Ejections() int
+	Probe(ctx context.Context) error
+	Observe(failed bool)
 	URL() *url.URL
+	Config() *ServerConfig
 	String() string
 	ActiveConnections() int
+	Latency() time.Duration
 	RetentionPeriod() time.Duration
 	ReverseProxy() *httputil.ReverseProxy
 	Serve(w http.ResponseWriter, r *http.Request)
@@ -29,8 +37,49 @@ type Server interface {
 
 // ServerConfig contains the configuration of backend server.
 type ServerConfig struct {
-	Web          *models.WebConfig `yaml:"web"`
-	FilterLabels []string          `yaml:"filter_labels"`
+	Web          *models.WebConfig  `yaml:"web"`
+	FilterLabels []string           `yaml:"filter_labels"`
+	FileSD       *FileSDConfig      `yaml:"file_sd"`
+	DNSSD        *DNSSDConfig       `yaml:"dns_sd"`
+	HealthCheck  *HealthCheckConfig `yaml:"-"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *ServerConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config. Web config is optional when service discovery
+	// is used
+	*c = ServerConfig{
+		Web: &models.WebConfig{
+			HTTPClientConfig: config.DefaultHTTPClientConfig,
+		},
+	}
+
+	type plain ServerConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *ServerConfig) Validate() error {
+	if c.FileSD == nil && c.DNSSD == nil {
+		if c.Web == nil || c.Web.URL == "" {
+			return errors.New("either web.url or one of file_sd and dns_sd must be configured for backend server")
+		}
+
+		return nil
+	}
+
+	if c.FileSD != nil {
+		if err := c.FileSD.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.DNSSD != nil {
+		return c.DNSSD.Validate()
+	}
+
+	return nil
 }
 
 // Backend defines backend server.
@@ -38,4 +87,5 @@ type Backend struct {
 	ID    string          `yaml:"id"`
 	TSDBs []*ServerConfig `yaml:"tsdb"`
 	Pyros []*ServerConfig `yaml:"pyroscope"`
+	Lokis []*ServerConfig `yaml:"loki"`
 }
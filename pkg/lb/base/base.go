@@ -11,7 +11,7 @@ const CEEMSLoadBalancerAppName = "ceems_lb"
 // CEEMSLoadBalancerApp is kingpin CLI app.
 var CEEMSLoadBalancerApp = *kingpin.New(
 	CEEMSLoadBalancerAppName,
-	"CEEMS load balancer for TSDB and Pyroscope servers with access control support.",
+	"CEEMS load balancer for TSDB, Pyroscope and Loki servers with access control support.",
 )
 
 // LBType is type of load balancer server.
@@ -21,6 +21,7 @@ type LBType int
 const (
 	PromLB LBType = iota
 	PyroLB
+	LokiLB
 )
 
 func (l LBType) String() string {
@@ -29,6 +30,8 @@ func (l LBType) String() string {
 		return "tsdb"
 	case PyroLB:
 		return "pyroscope"
+	case LokiLB:
+		return "loki"
 	}
 
 	return "undefined"
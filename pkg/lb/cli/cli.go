@@ -9,8 +9,6 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"net"
-	"net/url"
 	"os"
 	"os/signal"
 	"path/filepath"
@@ -41,7 +39,7 @@ import (
 // Custom errors.
 var (
 	ErrMissingIDs  = errors.New("missing ID for backend(s)")
-	ErrMissingURLs = errors.New("missing TSDB and Pyroscope URL(s) for backend(s)")
+	ErrMissingURLs = errors.New("missing TSDB, Pyroscope and Loki URL(s) for backend(s)")
 )
 
 // CEEMSLBAppConfig contains the configuration of CEEMS load balancer app.
@@ -73,7 +71,7 @@ func (c *CEEMSLBAppConfig) Validate() error {
 			return ErrMissingIDs
 		}
 
-		if len(backend.TSDBs) == 0 && len(backend.Pyros) == 0 {
+		if len(backend.TSDBs) == 0 && len(backend.Pyros) == 0 && len(backend.Lokis) == 0 {
 			return ErrMissingURLs
 		}
 
@@ -84,9 +82,51 @@ func (c *CEEMSLBAppConfig) Validate() error {
 				backend.ID,
 			)
 		}
+
+		// Validate backend servers and their service discovery configs
+		for _, lbType := range []base.LBType{base.PromLB, base.PyroLB, base.LokiLB} {
+			for _, serverCfg := range backendURLs(lbType, backend) {
+				if err := serverCfg.Validate(); err != nil {
+					return fmt.Errorf("invalid %s backend server of cluster %s: %w", lbType, backend.ID, err)
+				}
+			}
+		}
 	}
 
-	return nil
+	// Validate health check config
+	if err := c.LB.HealthCheck.Validate(); err != nil {
+		return err
+	}
+
+	// Validate audit log config
+	if err := c.LB.AuditLog.Validate(); err != nil {
+		return err
+	}
+
+	// Validate limits config
+	if err := c.LB.Limits.Validate(); err != nil {
+		return err
+	}
+
+	// Validate remote write config
+	if err := c.LB.RemoteWrite.Validate(); err != nil {
+		return err
+	}
+
+	// Agents can only push metrics of clusters that have TSDB backends
+	for _, agent := range c.LB.RemoteWrite.Agents {
+		if !slices.ContainsFunc(c.LB.Backends, func(b lb_backend.Backend) bool {
+			return b.ID == agent.ClusterID && len(b.TSDBs) > 0
+		}) {
+			return fmt.Errorf(
+				"unknown cluster ID %s of remote write agent %s. Cluster must have TSDB backends in ceems_lb.backends",
+				agent.ClusterID, agent.Name,
+			)
+		}
+	}
+
+	// Validate cache config
+	return c.LB.Cache.Validate()
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -94,7 +134,8 @@ func (c *CEEMSLBAppConfig) UnmarshalYAML(unmarshal func(interface{}) error) erro
 	// Set a default config
 	*c = CEEMSLBAppConfig{
 		CEEMSLBConfig{
-			Strategy: "round-robin",
+			Strategy:    "round-robin",
+			HealthCheck: lb_backend.DefaultHealthCheckConfig,
 		},
 		ceems_api.CEEMSAPIServerConfig{
 			Web: ceems_http.WebConfig{
@@ -127,8 +168,13 @@ func (c *CEEMSLBAppConfig) UnmarshalYAML(unmarshal func(interface{}) error) erro
 
 // CEEMSLBConfig contains the CEEMS load balancer config.
 type CEEMSLBConfig struct {
-	Backends []lb_backend.Backend `yaml:"backends"`
-	Strategy string               `yaml:"strategy"`
+	Backends    []lb_backend.Backend         `yaml:"backends"`
+	Strategy    string                       `yaml:"strategy"`
+	Cache       frontend.CacheConfig         `yaml:"cache"`
+	Limits      frontend.LimitsConfig        `yaml:"limits"`
+	AuditLog    frontend.AuditLogConfig      `yaml:"audit_log"`
+	RemoteWrite frontend.RemoteWriteConfig   `yaml:"remote_write"`
+	HealthCheck lb_backend.HealthCheckConfig `yaml:"health_check"`
 }
 
 // CEEMSLoadBalancer represents the `ceems_lb` cli.
@@ -150,10 +196,10 @@ func (lb *CEEMSLoadBalancer) Main() error {
 	var (
 		webListenAddresses = lb.App.Flag(
 			"web.listen-address",
-			"Addresses on which to expose load balancer(s). When both TSDB and Pyroscope LBs are configured, it must be "+
-				"repeated to provide two addresses: one for TSDB LB and one for Pyroscope LB. In that case TSDB LB will listen on "+
-				"first address and Pyroscope LB on second address",
-		).Default(":9030", ":9040").Strings()
+			"Addresses on which to expose load balancer(s). When more than one of TSDB, Pyroscope and Loki LBs are configured, it must be "+
+				"repeated to provide an address for each LB. In that case TSDB LB will listen on "+
+				"first address, Pyroscope LB on second address and Loki LB on third address",
+		).Default(":9030", ":9040", ":9050").Strings()
 		webConfigFile = lb.App.Flag(
 			"web.config.file",
 			"Path to configuration file that can enable TLS or authentication. See: https://github.com/prometheus/exporter-toolkit/blob/master/docs/web-configuration.md",
@@ -166,6 +212,11 @@ func (lb *CEEMSLoadBalancer) Main() error {
 			"runtime.gomaxprocs", "The target number of CPUs Go will run on (GOMAXPROCS)",
 		).Envar("GOMAXPROCS").Default("1").Int()
 
+		enableLifecycle = lb.App.Flag(
+			"web.enable-lifecycle",
+			"Enable reloading of configuration via HTTP request at /-/reload endpoint.",
+		).Default("false").Bool()
+
 		// Hidden test flags
 		dropPrivs = lb.App.Flag(
 			"security.drop-privileges",
@@ -227,14 +278,43 @@ func (lb *CEEMSLoadBalancer) Main() error {
 	runtime.GOMAXPROCS(*maxProcs)
 	logger.Debug("Go MAXPROCS", "procs", runtime.GOMAXPROCS(0))
 
+	// Setup audit log shared by all load balancers. Audit log file must be opened
+	// before dropping privileges
+	var auditLogger *frontend.AuditLogger
+
+	var auditLogPaths []string
+
+	if config.LB.AuditLog.Enabled {
+		if auditLogger, err = frontend.NewAuditLogger(config.LB.AuditLog); err != nil {
+			logger.Error("Failed to setup audit log", "err", err)
+
+			return err
+		}
+		defer auditLogger.Close()
+
+		// Rotation of audit log file needs write permissions on its directory
+		if config.LB.AuditLog.Sink == "file" {
+			auditLogPaths = append(auditLogPaths, filepath.Dir(config.LB.AuditLog.File.Path))
+		}
+	}
+
 	// We should STRONGLY advise in docs that CEEMS API server should not be started as root
 	// as that will end up dropping the privileges and running it as nobody user which can
 	// be strange as CEEMS API server writes data to DB.
 	if *dropPrivs {
+		// Service discovery files and token files of remote write agents are
+		// read after dropping privileges
+		readPaths := slices.Concat(
+			[]string{webConfigFilePath, configFilePath},
+			sdReadPaths(config),
+			config.LB.RemoteWrite.TokenFiles(),
+		)
+
 		securityCfg := &security.Config{
-			RunAsUser: "nobody",
-			Caps:      nil,
-			ReadPaths: []string{webConfigFilePath, configFilePath},
+			RunAsUser:      "nobody",
+			Caps:           nil,
+			ReadPaths:      readPaths,
+			ReadWritePaths: auditLogPaths,
 		}
 
 		// Drop all unnecessary privileges
@@ -267,8 +347,24 @@ func (lb *CEEMSLoadBalancer) Main() error {
 	defer stop()
 
 	// Make manager and LB maps
-	managers := make(map[base.LBType]serverpool.Manager, 2)
-	lbs := make(map[base.LBType]frontend.LoadBalancer, 2)
+	managers := make(map[base.LBType]serverpool.Manager, 3)
+	lbs := make(map[base.LBType]frontend.LoadBalancer, 3)
+
+	// Reloader of config and backend servers
+	reloader := &reloader{
+		configFilePath: configFilePath,
+		webConfigDir:   filepath.Dir(webConfigFilePath),
+		config:         config,
+		managers:       managers,
+		lbs:            lbs,
+		logger:         logger,
+	}
+
+	// Reload config using lifecycle API only when enabled
+	var reload func() error
+	if *enableLifecycle {
+		reload = reloader.reload
+	}
 
 	for i, lbType := range lbTypes {
 		// Create a pool of backend servers
@@ -279,12 +375,20 @@ func (lb *CEEMSLoadBalancer) Main() error {
 			return err
 		}
 
-		// Add backend servers to serverPool
-		for _, backend := range config.LB.Backends {
-			for _, serverCfg := range backendURLs(lbType, backend) {
-				// Set directory for reading files
-				serverCfg.Web.SetDirectory(filepath.Dir(webConfigFilePath))
+		// Discover backend servers. Backend servers that failed to be discovered
+		// will be added in the next refresh
+		discoverCtx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+		backends, _, err := discoverBackends(discoverCtx, config, lbType, filepath.Dir(webConfigFilePath))
 
+		cancel()
+
+		if err != nil {
+			logger.Error("Failed to discover backend servers", "backend_type", lbType, "err", err)
+		}
+
+		// Add backend servers to serverPool
+		for id, serverCfgs := range backends {
+			for _, serverCfg := range serverCfgs {
 				backendServer, err := lb_backend.New(lbType, serverCfg, logger.With("backend_type", lbType))
 				if err != nil {
 					logger.Error("Could not set up backend server", "backend_type", lbType, "err", errors.Unwrap(err))
@@ -292,7 +396,7 @@ func (lb *CEEMSLoadBalancer) Main() error {
 					continue
 				}
 
-				managers[lbType].Add(backend.ID, backendServer)
+				managers[lbType].Add(id, backendServer)
 			}
 		}
 
@@ -305,6 +409,11 @@ func (lb *CEEMSLoadBalancer) Main() error {
 			WebConfigFile:    webConfigFilePath,
 			APIServer:        config.Server,
 			Manager:          managers[lbType],
+			Cache:            config.LB.Cache,
+			Limits:           config.LB.Limits,
+			AuditLogger:      auditLogger,
+			RemoteWrite:      config.LB.RemoteWrite,
+			Reload:           reload,
 		}
 
 		// Create frontend instance for load balancer
@@ -316,6 +425,14 @@ func (lb *CEEMSLoadBalancer) Main() error {
 		}
 	}
 
+	// Reload config on SIGHUP and refresh discovered backend servers
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	defer signal.Stop(hup)
+
+	go reloader.run(ctx, hup)
+
 	// Declare wait group and tickers
 	var wg sync.WaitGroup
 
@@ -325,7 +442,7 @@ func (lb *CEEMSLoadBalancer) Main() error {
 
 		go func() {
 			defer wg.Done()
-			monitor(ctx, managers[lbType], logger.With("backend_type", lbType))
+			monitor(ctx, managers[lbType], time.Duration(config.LB.HealthCheck.Interval), logger.With("backend_type", lbType))
 		}()
 
 		// Initializing the server in a goroutine so that
@@ -375,6 +492,10 @@ func backendTypes(config *CEEMSLBAppConfig) []base.LBType {
 		if len(backend.Pyros) > 0 && !slices.Contains(types, base.PyroLB) {
 			types = append(types, base.PyroLB)
 		}
+
+		if len(backend.Lokis) > 0 && !slices.Contains(types, base.LokiLB) {
+			types = append(types, base.LokiLB)
+		}
 	}
 
 	return types
@@ -387,14 +508,17 @@ func backendURLs(t base.LBType, backend lb_backend.Backend) []*lb_backend.Server
 		return backend.TSDBs
 	case base.PyroLB:
 		return backend.Pyros
+	case base.LokiLB:
+		return backend.Lokis
 	}
 
 	return nil
 }
 
-// monitor checks the backend servers health.
-func monitor(ctx context.Context, manager serverpool.Manager, logger *slog.Logger) {
-	t := time.NewTicker(time.Second * 20)
+// monitor checks the backend servers health at every interval.
+func monitor(ctx context.Context, manager serverpool.Manager, interval time.Duration, logger *slog.Logger) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
 
 	logger.Info("Starting health checker")
 
@@ -414,48 +538,25 @@ func monitor(ctx context.Context, manager serverpool.Manager, logger *slog.Logge
 	}
 }
 
-// healthCheck monitors the status of all backend servers.
+// healthCheck probes the readiness endpoints of all backend servers concurrently.
 func healthCheck(ctx context.Context, manager serverpool.Manager, logger *slog.Logger) {
-	aliveChannel := make(chan bool, 1)
+	var wg sync.WaitGroup
 
 	for id, backends := range manager.Backends() {
 		for _, backend := range backends {
-			requestCtx, stop := context.WithTimeout(ctx, 10*time.Second)
-			defer stop()
-
-			status := "up"
+			wg.Add(1)
 
-			go isAlive(requestCtx, aliveChannel, backend.URL(), logger)
+			go func() {
+				defer wg.Done()
 
-			select {
-			case <-ctx.Done():
-				logger.Info("Gracefully shutting down health check")
-
-				return
-			case alive := <-aliveChannel:
-				backend.SetAlive(alive)
-
-				if !alive {
-					status = "down"
+				if err := backend.Probe(ctx); err != nil {
+					logger.Debug("Health check failed", "id", id, "backend", backend.String(), "err", err)
 				}
-			}
-			logger.Debug("Health check", "id", id, "backend", backend.String(), "status", status)
-		}
-	}
-}
 
-// isAlive returns the status of backend server with a channel.
-func isAlive(ctx context.Context, aliveChannel chan bool, u *url.URL, logger *slog.Logger) {
-	var d net.Dialer
-
-	conn, err := d.DialContext(ctx, "tcp", u.Host)
-	if err != nil {
-		logger.Debug("Backend unreachable", "backend", u.Redacted(), "err", err)
-		aliveChannel <- false
-
-		return
+				logger.Debug("Health check", "id", id, "backend", backend.String(), "state", backend.HealthState())
+			}()
+		}
 	}
 
-	_ = conn.Close()
-	aliveChannel <- true
+	wg.Wait()
 }
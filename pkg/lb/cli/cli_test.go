@@ -14,7 +14,9 @@ import (
 	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/alecthomas/units"
 	"github.com/mahendrapaipuri/ceems/internal/common"
+	"github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 )
 
@@ -84,6 +86,9 @@ ceems_lb:
         - web:
            url: %[1]s
       pyroscope:
+        - web:
+           url: %[1]s
+      loki:
         - web:
            url: %[1]s`
 
@@ -110,7 +115,7 @@ ceems_lb:
 	// Query LB
 	for i := range 10 {
 		if err := queryLB("localhost:9040", "default"); err == nil {
-			if err := queryLB("localhost:9040", "default"); err == nil {
+			if err := queryLB("localhost:9050", "default"); err == nil {
 				break
 			}
 		}
@@ -225,3 +230,151 @@ clusters:
 	_, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
 	require.Error(t, err)
 }
+
+func TestCEEMSLBCacheConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_lb:
+  strategy: "resource-based"
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+  cache:
+    enabled: true
+    max_size: 64MiB`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+	require.True(t, config.LB.Cache.Enabled)
+	require.Equal(t, 64*units.MiB, config.LB.Cache.MaxSize)
+	require.Equal(t, model.Duration(24*time.Hour), config.LB.Cache.TTL)
+
+	// Invalid TTL
+	configFile += `
+    ttl: 0s`
+
+	configFilePath = makeConfigFile(configFile, tmpDir)
+	_, err = common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.Error(t, err)
+}
+
+func TestCEEMSLBHealthCheckConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_lb:
+  strategy: "resource-based"
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+  health_check:
+    interval: 10s
+    outlier_detection:
+      consecutive_failures: 3`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+	require.Equal(t, model.Duration(10*time.Second), config.LB.HealthCheck.Interval)
+	require.Equal(t, model.Duration(5*time.Second), config.LB.HealthCheck.Timeout)
+	require.Equal(t, 2, config.LB.HealthCheck.HealthyThreshold)
+	require.Equal(t, 3, config.LB.HealthCheck.OutlierDetection.ConsecutiveFailures)
+	require.Equal(t, model.Duration(30*time.Second), config.LB.HealthCheck.OutlierDetection.BaseEjectionTime)
+
+	// Timeout greater than interval
+	configFile += `
+    timeout: 20s`
+
+	configFilePath = makeConfigFile(configFile, tmpDir)
+	_, err = common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.Error(t, err)
+}
+
+func TestCEEMSLBLimitsConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+  limits:
+    enabled: true
+    max_query_range: 30d
+    min_step: 1m
+    cost_budget_per_minute: 100000`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+	require.True(t, config.LB.Limits.Enabled)
+	require.Equal(t, 10, config.LB.Limits.MaxConcurrentQueries)
+	require.Equal(t, model.Duration(30*24*time.Hour), config.LB.Limits.MaxQueryRange)
+	require.Equal(t, model.Duration(time.Minute), config.LB.Limits.MinStep)
+	require.Equal(t, int64(100000), config.LB.Limits.CostBudgetPerMinute)
+	require.False(t, config.LB.Limits.SeriesPreCheck)
+
+	// Negative limits
+	configFile += `
+    max_query_cost: -1`
+
+	configFilePath = makeConfigFile(configFile, tmpDir)
+	_, err = common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.Error(t, err)
+}
+
+func TestCEEMSLBRemoteWriteConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+  remote_write:
+    agents:
+      - name: alloy
+        cluster_id: default
+        token: secret
+        max_series: 1000
+        write_relabel_configs:
+          - target_label: ceems_id
+            replacement: default`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+	require.Len(t, config.LB.RemoteWrite.Agents, 1)
+	require.Equal(t, "default", config.LB.RemoteWrite.Agents[0].ClusterID)
+	require.Equal(t, 1000, config.LB.RemoteWrite.Agents[0].MaxSeries)
+	require.Len(t, config.LB.RemoteWrite.Agents[0].WriteRelabelConfigs, 1)
+	require.Equal(t, 10*units.MiB, config.LB.RemoteWrite.MaxRequestSize)
+
+	// Agent of unknown cluster
+	configFile += `
+      - name: prom
+        cluster_id: unknown
+        token: other`
+
+	configFilePath = makeConfigFile(configFile, tmpDir)
+	_, err = common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.Error(t, err)
+}
@@ -0,0 +1,330 @@
+//go:build cgo
+// +build cgo
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	lb_backend "github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/frontend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+)
+
+// Timeout of service discovery of backend servers.
+const discoveryTimeout = 10 * time.Second
+
+// reloader reloads the config of LB and reconciles the backend servers of running
+// LBs with the backend servers in config and the ones discovered by service
+// discovery. Backend servers whose config is unchanged are never recreated so that
+// requests being served by them are not dropped.
+type reloader struct {
+	mu             sync.Mutex
+	configFilePath string
+	webConfigDir   string
+	config         *CEEMSLBAppConfig
+	managers       map[base.LBType]serverpool.Manager
+	lbs            map[base.LBType]frontend.LoadBalancer
+	logger         *slog.Logger
+}
+
+// reload reads the config file and reconciles backend servers of running LBs.
+func (r *reloader) reload() error {
+	config, err := common.MakeConfig[CEEMSLBAppConfig](r.configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
+	// New clusters must be validated against CEEMS DB or API server like the
+	// ones at the start
+	if err := r.validateClusterIDs(ctx, config); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+
+	// Only backend servers and tokens of remote write agents are reloaded
+	for _, section := range changedSections(r.config, config) {
+		r.logger.Warn("Restart is needed to apply changes of configuration section", "section", section)
+	}
+
+	r.config = config
+	r.mu.Unlock()
+
+	// Load balancers of new backend types cannot be started without a restart
+	for _, lbType := range backendTypes(config) {
+		if _, ok := r.lbs[lbType]; !ok {
+			r.logger.Warn("Restart is needed to start load balancer of new backend type", "backend_type", lbType)
+		}
+	}
+
+	// Token files of remote write agents might be rotated
+	for _, lb := range r.lbs {
+		lb.ReloadRemoteWriteTokens()
+	}
+
+	if err := r.reconcile(ctx); err != nil {
+		return err
+	}
+
+	r.logger.Info("Completed loading of configuration file", "file", r.configFilePath)
+
+	return nil
+}
+
+// validateClusterIDs returns an error if the clusters in config that are not
+// served by running LBs are unknown to CEEMS DB or API server.
+func (r *reloader) validateClusterIDs(ctx context.Context, config *CEEMSLBAppConfig) error {
+	for lbType, lb := range r.lbs {
+		current := r.managers[lbType].Backends()
+
+		var ids []string
+
+		for _, backend := range config.LB.Backends {
+			if _, ok := current[backend.ID]; !ok && len(backendURLs(lbType, backend)) > 0 {
+				ids = append(ids, backend.ID)
+			}
+		}
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		if err := lb.ValidateClusterIDs(ctx, ids); err != nil {
+			return fmt.Errorf("invalid clusters of backend type %s: %w", lbType, err)
+		}
+	}
+
+	return nil
+}
+
+// changedSections returns the sections of config, other than backends, that
+// changed and hence, need a restart of LB to be applied.
+func changedSections(prev, next *CEEMSLBAppConfig) []string {
+	var sections []string
+
+	for _, section := range []struct {
+		name       string
+		prev, next any
+	}{
+		{"ceems_lb.strategy", prev.LB.Strategy, next.LB.Strategy},
+		{"ceems_lb.cache", prev.LB.Cache, next.LB.Cache},
+		{"ceems_lb.limits", prev.LB.Limits, next.LB.Limits},
+		{"ceems_lb.audit_log", prev.LB.AuditLog, next.LB.AuditLog},
+		{"ceems_lb.remote_write", prev.LB.RemoteWrite, next.LB.RemoteWrite},
+		{"ceems_lb.health_check", prev.LB.HealthCheck, next.LB.HealthCheck},
+		{"ceems_api_server", prev.Server, next.Server},
+	} {
+		if !reflect.DeepEqual(section.prev, section.next) {
+			sections = append(sections, section.name)
+		}
+	}
+
+	return sections
+}
+
+// reconcile adds new backend servers to and removes stale backend servers from
+// running LBs. Backend servers whose config changed are replaced by adding the
+// ones with new config before removing the ones with old config.
+func (r *reloader) reconcile(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs error
+
+	for lbType, lb := range r.lbs {
+		desired, failed, err := discoverBackends(ctx, r.config, lbType, r.webConfigDir)
+		errs = errors.Join(errs, err)
+
+		current := r.managers[lbType].Backends()
+
+		// Add backend servers that are not in current ones
+		for id, configs := range desired {
+			for _, c := range configs {
+				if slices.ContainsFunc(current[id], func(s lb_backend.Server) bool {
+					return sameServer(c, s)
+				}) {
+					continue
+				}
+
+				server, err := lb_backend.New(lbType, c, r.logger.With("backend_type", lbType))
+				if err != nil {
+					errs = errors.Join(errs, fmt.Errorf("could not set up backend server of type %s: %w", lbType, err))
+
+					continue
+				}
+
+				r.logger.Info("Adding backend server", "backend_type", lbType, "cluster_id", id, "backend", server.String())
+				lb.AddBackend(id, server)
+			}
+		}
+
+		// Remove backend servers that are not in desired ones. Backends of clusters
+		// whose discovery failed are kept
+		for id, servers := range current {
+			if slices.Contains(failed, id) {
+				continue
+			}
+
+			for _, server := range servers {
+				if !slices.ContainsFunc(desired[id], func(c *lb_backend.ServerConfig) bool {
+					return sameServer(c, server)
+				}) {
+					r.logger.Info("Removing backend server", "backend_type", lbType, "cluster_id", id, "backend", server.String())
+					lb.RemoveBackend(id, server)
+				}
+			}
+		}
+	}
+
+	return errs
+}
+
+// refreshInterval returns the minimum refresh interval of all service discovery
+// configs. It returns zero when service discovery is not used.
+func (r *reloader) refreshInterval() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var interval time.Duration
+
+	for _, backend := range r.config.LB.Backends {
+		for _, lbType := range []base.LBType{base.PromLB, base.PyroLB, base.LokiLB} {
+			for _, c := range backendURLs(lbType, backend) {
+				if i := c.RefreshInterval(); i > 0 && (interval == 0 || i < interval) {
+					interval = i
+				}
+			}
+		}
+	}
+
+	return interval
+}
+
+// run reloads the config on SIGHUP and refreshes the backend servers discovered
+// by service discovery periodically until context is cancelled.
+func (r *reloader) run(ctx context.Context, hup <-chan os.Signal) {
+	for {
+		// Refresh backend servers only when service discovery is used
+		var refresh <-chan time.Time
+
+		if interval := r.refreshInterval(); interval > 0 {
+			refresh = time.After(interval)
+		}
+
+		select {
+		case <-hup:
+			if err := r.reload(); err != nil {
+				r.logger.Error("Failed to reload config", "err", err)
+			}
+		case <-refresh:
+			ctx, cancel := context.WithTimeout(ctx, discoveryTimeout)
+			if err := r.reconcile(ctx); err != nil {
+				r.logger.Error("Failed to refresh backend servers", "err", err)
+			}
+
+			cancel()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// discoverBackends returns the configs of backend servers of type `lbType` for each
+// cluster in config by resolving service discovery configs and the clusters whose
+// service discovery failed.
+func discoverBackends(
+	ctx context.Context,
+	config *CEEMSLBAppConfig,
+	lbType base.LBType,
+	webConfigDir string,
+) (map[string][]*lb_backend.ServerConfig, []string, error) {
+	backends := make(map[string][]*lb_backend.ServerConfig)
+
+	var failed []string
+
+	var errs error
+
+	for _, backend := range config.LB.Backends {
+		for _, serverCfg := range backendURLs(lbType, backend) {
+			// Set directory for reading files
+			serverCfg.Web.SetDirectory(webConfigDir)
+
+			// Set health check config
+			serverCfg.HealthCheck = &config.LB.HealthCheck
+
+			configs, err := serverCfg.Discover(ctx)
+			if err != nil {
+				errs = errors.Join(errs, fmt.Errorf("failed to discover backend servers of cluster %s: %w", backend.ID, err))
+				failed = append(failed, backend.ID)
+
+				continue
+			}
+
+			backends[backend.ID] = append(backends[backend.ID], configs...)
+		}
+	}
+
+	return backends, failed, errs
+}
+
+// sameURL returns true if raw URL `a` is same as URL `b`.
+func sameURL(a string, b *url.URL) bool {
+	u, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	return u.String() == b.String()
+}
+
+// sameServer returns true if backend server `s` is set up with config `c`. Health
+// check config is not compared as it needs a restart to be applied.
+func sameServer(c *lb_backend.ServerConfig, s lb_backend.Server) bool {
+	if !sameURL(c.Web.URL, s.URL()) {
+		return false
+	}
+
+	current := s.Config()
+
+	return reflect.DeepEqual(c.Web.HTTPClientConfig, current.Web.HTTPClientConfig) &&
+		slices.Equal(c.FilterLabels, current.FilterLabels)
+}
+
+// sdReadPaths returns the directories of files used in file based service discovery.
+func sdReadPaths(config *CEEMSLBAppConfig) []string {
+	var paths []string
+
+	for _, backend := range config.LB.Backends {
+		for _, lbType := range []base.LBType{base.PromLB, base.PyroLB, base.LokiLB} {
+			for _, c := range backendURLs(lbType, backend) {
+				if c.FileSD == nil {
+					continue
+				}
+
+				for _, f := range c.FileSD.Files {
+					if dir := filepath.Dir(f); !slices.Contains(paths, dir) {
+						paths = append(paths, dir)
+					}
+				}
+			}
+		}
+	}
+
+	return paths
+}
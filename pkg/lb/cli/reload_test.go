@@ -0,0 +1,299 @@
+//go:build cgo
+// +build cgo
+
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/internal/common"
+	ceems_api_base "github.com/mahendrapaipuri/ceems/pkg/api/base"
+	lb_backend "github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/frontend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func backendURLStrings(m serverpool.Manager, id string) []string {
+	var urls []string
+
+	for _, b := range m.Backends()[id] {
+		urls = append(urls, b.URL().String())
+	}
+
+	return urls
+}
+
+func TestReloaderReconcile(t *testing.T) {
+	tmpDir := t.TempDir()
+	targetsFile := filepath.Join(tmpDir, "targets.yml")
+
+	// Make targets file
+	writeTargets := func(targets ...string) {
+		content := "- targets:\n"
+		for _, target := range targets {
+			content += fmt.Sprintf("    - %s\n", target)
+		}
+
+		require.NoError(t, os.WriteFile(targetsFile, []byte(content), 0o600))
+	}
+
+	writeTargets("localhost:9091", "localhost:9092")
+
+	// Make config file
+	configFile := fmt.Sprintf(`
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - file_sd:
+            files:
+              - %s
+    - id: "static"
+      tsdb:
+        - web:
+            url: http://localhost:9090`, targetsFile)
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	lb, err := frontend.New(&frontend.Config{
+		Logger:  logger,
+		LBType:  base.PromLB,
+		Address: "localhost:9030",
+		Manager: manager,
+	})
+	require.NoError(t, err)
+
+	r := &reloader{
+		configFilePath: configFilePath,
+		webConfigDir:   tmpDir,
+		config:         config,
+		managers:       map[base.LBType]serverpool.Manager{base.PromLB: manager},
+		lbs:            map[base.LBType]frontend.LoadBalancer{base.PromLB: lb},
+		logger:         logger,
+	}
+
+	// Initial reconcile must add all backends
+	require.NoError(t, r.reconcile(context.Background()))
+	assert.ElementsMatch(t, []string{"http://localhost:9091", "http://localhost:9092"}, backendURLStrings(manager, "default"))
+	assert.ElementsMatch(t, []string{"http://localhost:9090"}, backendURLStrings(manager, "static"))
+
+	// Keep reference to existing backend to check it is not recreated
+	existing := manager.Backends()["default"][1]
+
+	// Update targets
+	writeTargets("localhost:9092", "localhost:9093")
+
+	require.NoError(t, r.reconcile(context.Background()))
+	assert.ElementsMatch(t, []string{"http://localhost:9092", "http://localhost:9093"}, backendURLStrings(manager, "default"))
+	assert.Contains(t, manager.Backends()["default"], existing)
+
+	// Invalid targets file must keep existing backends
+	require.NoError(t, os.WriteFile(targetsFile, []byte("invalid"), 0o600))
+
+	require.Error(t, r.reconcile(context.Background()))
+	assert.ElementsMatch(t, []string{"http://localhost:9092", "http://localhost:9093"}, backendURLStrings(manager, "default"))
+
+	// Remove a cluster from config and reload
+	configFile = `
+---
+ceems_lb:
+  backends:
+    - id: "static"
+      tsdb:
+        - web:
+            url: http://localhost:9094`
+
+	makeConfigFile(configFile, tmpDir)
+	require.NoError(t, r.reload())
+	assert.Empty(t, backendURLStrings(manager, "default"))
+	assert.ElementsMatch(t, []string{"http://localhost:9094"}, backendURLStrings(manager, "static"))
+	assert.Equal(t, time.Duration(0), r.refreshInterval())
+
+	// Reload with same config must not recreate backend
+	existing = manager.Backends()["static"][0]
+
+	require.NoError(t, r.reload())
+	assert.Equal(t, []lb_backend.Server{existing}, manager.Backends()["static"])
+
+	// Change HTTP client config and filter labels of backend and reload
+	configFile = `
+---
+ceems_lb:
+  backends:
+    - id: "static"
+      tsdb:
+        - web:
+            url: http://localhost:9094
+            basic_auth:
+              username: foo
+              password: bar
+          filter_labels:
+            - job`
+
+	makeConfigFile(configFile, tmpDir)
+	require.NoError(t, r.reload())
+	require.Len(t, manager.Backends()["static"], 1)
+
+	replaced := manager.Backends()["static"][0]
+	assert.NotSame(t, existing, replaced)
+	assert.Equal(t, "http://localhost:9094", replaced.URL().String())
+	assert.Equal(t, "foo", replaced.Config().Web.HTTPClientConfig.BasicAuth.Username)
+	assert.Equal(t, []string{"job"}, replaced.Config().FilterLabels)
+}
+
+func TestReloaderRefreshInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Make config file
+	configFile := `
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - file_sd:
+            files:
+              - /etc/ceems/targets/*.yml
+            refresh_interval: 1m
+      pyroscope:
+        - dns_sd:
+            names:
+              - _pyroscope._tcp.example.com
+            refresh_interval: 10s`
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+
+	r := &reloader{config: config}
+	assert.Equal(t, 10*time.Second, r.refreshInterval())
+	assert.Equal(t, []string{"/etc/ceems/targets"}, sdReadPaths(config))
+
+	// Missing URL and service discovery
+	configFile = `
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - filter_labels:
+            - job`
+
+	configFilePath = makeConfigFile(configFile, tmpDir)
+	_, err = common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.Error(t, err)
+}
+
+func TestReloaderValidateClusterIDs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Setup CEEMS DB with only default cluster
+	db, err := sql.Open("sqlite3", filepath.Join(tmpDir, ceems_api_base.CEEMSDBName))
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+CREATE TABLE units (
+	"id" integer not null primary key,
+	"cluster_id" text,
+	"resource_manager" text
+);
+INSERT INTO units VALUES(1, 'default', 'slurm');`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	// Make config file
+	configFile := fmt.Sprintf(`
+---
+ceems_lb:
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+ceems_api_server:
+  data:
+    path: %s`, tmpDir)
+
+	configFilePath := makeConfigFile(configFile, tmpDir)
+	config, err := common.MakeConfig[CEEMSLBAppConfig](configFilePath)
+	require.NoError(t, err)
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	lb, err := frontend.New(&frontend.Config{
+		Logger:    logger,
+		LBType:    base.PromLB,
+		Address:   "localhost:9030",
+		Manager:   manager,
+		APIServer: config.Server,
+	})
+	require.NoError(t, err)
+
+	r := &reloader{
+		configFilePath: configFilePath,
+		webConfigDir:   tmpDir,
+		config:         config,
+		managers:       map[base.LBType]serverpool.Manager{base.PromLB: manager},
+		lbs:            map[base.LBType]frontend.LoadBalancer{base.PromLB: lb},
+		logger:         logger,
+	}
+
+	require.NoError(t, r.reconcile(context.Background()))
+
+	// Cluster unknown to CEEMS DB must reject the reload and keep existing config
+	// and backends
+	configFile = fmt.Sprintf(`
+---
+ceems_lb:
+  strategy: least-connection
+  backends:
+    - id: "default"
+      tsdb:
+        - web:
+            url: http://localhost:9090
+    - id: "unknown"
+      tsdb:
+        - web:
+            url: http://localhost:9091
+ceems_api_server:
+  data:
+    path: %s`, tmpDir)
+
+	makeConfigFile(configFile, tmpDir)
+	require.Error(t, r.reload())
+	assert.Equal(t, config, r.config)
+	assert.NotContains(t, manager.Backends(), "unknown")
+}
+
+func TestChangedSections(t *testing.T) {
+	prev := &CEEMSLBAppConfig{}
+	next := &CEEMSLBAppConfig{}
+	assert.Empty(t, changedSections(prev, next))
+
+	next.LB.Strategy = "least-connection"
+	next.LB.Cache.Enabled = true
+	next.LB.Backends = []lb_backend.Backend{{ID: "default"}}
+	assert.Equal(t, []string{"ceems_lb.strategy", "ceems_lb.cache"}, changedSections(prev, next))
+}
@@ -0,0 +1,406 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	ceems_api_base "github.com/mahendrapaipuri/ceems/pkg/api/base"
+)
+
+// Value of redacted parameters in audit log.
+const redactedValue = "<redacted>"
+
+// Decisions of audit events.
+const (
+	decisionAllow = "allow"
+	decisionDeny  = "deny"
+)
+
+// Supported sinks of audit log.
+const (
+	auditSinkFile   = "file"
+	auditSinkSyslog = "syslog"
+)
+
+// Syslog facilities supported by audit log.
+var syslogFacilities = map[string]syslog.Priority{
+	"auth":     syslog.LOG_AUTH,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"daemon":   syslog.LOG_DAEMON,
+	"user":     syslog.LOG_USER,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// AuditLogConfig contains the configuration of audit log of proxied queries.
+type AuditLogConfig struct {
+	Enabled      bool              `yaml:"enabled"`
+	Sink         string            `yaml:"sink"`
+	File         AuditFileConfig   `yaml:"file"`
+	Syslog       AuditSyslogConfig `yaml:"syslog"`
+	RedactParams []string          `yaml:"redact_params"`
+}
+
+// AuditFileConfig contains the configuration of file sink of audit log.
+type AuditFileConfig struct {
+	Path       string           `yaml:"path"`
+	MaxSize    units.Base2Bytes `yaml:"max_size"`
+	MaxBackups int              `yaml:"max_backups"`
+}
+
+// AuditSyslogConfig contains the configuration of syslog sink of audit log.
+type AuditSyslogConfig struct {
+	Network  string `yaml:"network"`
+	Address  string `yaml:"address"`
+	Facility string `yaml:"facility"`
+	Tag      string `yaml:"tag"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *AuditLogConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = AuditLogConfig{
+		Sink: auditSinkFile,
+		File: AuditFileConfig{
+			MaxSize:    100 * units.MiB,
+			MaxBackups: 5,
+		},
+		Syslog: AuditSyslogConfig{
+			Facility: "auth",
+			Tag:      "ceems_lb",
+		},
+	}
+
+	type plain AuditLogConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *AuditLogConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	switch c.Sink {
+	case auditSinkFile:
+		if c.File.Path == "" {
+			return errors.New("path of file sink of audit log must not be empty")
+		}
+
+		if c.File.MaxSize <= 0 {
+			return errors.New("max_size of file sink of audit log must be positive")
+		}
+
+		if c.File.MaxBackups < 0 {
+			return errors.New("max_backups of file sink of audit log must not be negative")
+		}
+	case auditSinkSyslog:
+		if _, ok := syslogFacilities[c.Syslog.Facility]; !ok {
+			return fmt.Errorf("invalid facility %s of syslog sink of audit log", c.Syslog.Facility)
+		}
+	default:
+		return fmt.Errorf("invalid sink %s of audit log. Only file and syslog are supported", c.Sink)
+	}
+
+	return nil
+}
+
+// auditEvent is a record of the access control decision and outcome of a request.
+type auditEvent struct {
+	Time          time.Time           `json:"time"`
+	LBType        string              `json:"lb_type"`
+	User          string              `json:"user"`
+	Admin         bool                `json:"admin"`
+	ClusterID     string              `json:"cluster_id"`
+	UUIDs         []string            `json:"uuids"`
+	Decision      string              `json:"decision"`
+	Reason        string              `json:"reason,omitempty"`
+	Method        string              `json:"method"`
+	Path          string              `json:"path"`
+	Params        map[string][]string `json:"params,omitempty"`
+	RemoteAddr    string              `json:"remote_addr"`
+	Backend       string              `json:"backend,omitempty"`
+	Status        int                 `json:"status"`
+	Latency       float64             `json:"latency_seconds"`
+	ResponseBytes int64               `json:"response_bytes"`
+}
+
+// auditEventContextKey is the key used to set audit event in request's context.
+type auditEventContextKey struct{}
+
+// deny records a deny decision with reason. It is a no-op on nil event.
+func (e *auditEvent) deny(reason string) {
+	if e == nil {
+		return
+	}
+
+	e.Decision = decisionDeny
+	e.Reason = reason
+}
+
+// setBackend records the backend that served the request. It is a no-op on
+// nil event.
+func (e *auditEvent) setBackend(backend string) {
+	if e == nil {
+		return
+	}
+
+	e.Backend = backend
+}
+
+// auditEventFromContext returns the audit event of the request if audit log
+// is enabled.
+func auditEventFromContext(ctx context.Context) *auditEvent {
+	if e, ok := ctx.Value(auditEventContextKey{}).(*auditEvent); ok {
+		return e
+	}
+
+	return nil
+}
+
+// AuditLogger writes audit events of requests to a sink. It is shared by all
+// the load balancers.
+type AuditLogger struct {
+	mu           sync.Mutex
+	w            io.WriteCloser
+	newline      bool
+	redactParams []string
+}
+
+// NewAuditLogger returns a new instance of AuditLogger.
+func NewAuditLogger(c AuditLogConfig) (*AuditLogger, error) {
+	l := &AuditLogger{redactParams: c.RedactParams}
+
+	switch c.Sink {
+	case auditSinkSyslog:
+		w, err := syslog.Dial(c.Syslog.Network, c.Syslog.Address, syslogFacilities[c.Syslog.Facility]|syslog.LOG_INFO, c.Syslog.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+
+		l.w = w
+	default:
+		w, err := newRotatingFile(c.File.Path, int64(c.File.MaxSize), c.File.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+
+		l.w = w
+		l.newline = true
+	}
+
+	return l, nil
+}
+
+// Close closes the sink of audit log.
+func (l *AuditLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.w.Close()
+}
+
+// newEvent returns a new audit event of the request.
+func (l *AuditLogger) newEvent(r *http.Request) *auditEvent {
+	e := &auditEvent{
+		Time:       time.Now(),
+		User:       r.Header.Get(ceems_api_base.GrafanaUserHeader),
+		Decision:   decisionAllow,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		RemoteAddr: r.RemoteAddr,
+	}
+
+	// Form parameters contain queries. Requests without body have only URL
+	// query parameters
+	form := r.URL.Query()
+	if values, err := readForm(r); err == nil {
+		form = values
+	}
+
+	if len(form) > 0 {
+		e.Params = make(map[string][]string, len(form))
+
+		for name, values := range form {
+			if slices.Contains(l.redactParams, name) {
+				values = []string{redactedValue}
+			}
+
+			e.Params[name] = values
+		}
+	}
+
+	return e
+}
+
+// log writes the audit event to the sink.
+func (l *AuditLogger) log(e *auditEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if l.newline {
+		b = append(b, '\n')
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err = l.w.Write(b)
+
+	return err
+}
+
+// auditResponseWriter is a http.ResponseWriter that records the status code
+// and size of response.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	code int
+	size int64
+}
+
+// WriteHeader records the status code of response.
+func (w *auditResponseWriter) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the size of response.
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+
+	return n, err
+}
+
+// Flush flushes the response if underlying writer supports it.
+func (w *auditResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// rotatingFile is a file that is rotated when its size exceeds maximum size.
+// Rotated files are suffixed with their index and the oldest ones are removed
+// when number of rotated files exceeds maximum backups.
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// newRotatingFile returns a new instance of rotatingFile.
+func newRotatingFile(path string, maxSize int64, maxBackups int) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// open opens the file in append mode.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return fmt.Errorf("failed to stat audit log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// Write writes to the file and rotates it before if needed.
+func (f *rotatingFile) Write(b []byte) (int, error) {
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+
+	return n, err
+}
+
+// rotate shifts the rotated files, renames current file and opens a new one.
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		return f.open()
+	}
+
+	for i := f.maxBackups - 1; i > 0; i-- {
+		if err := os.Rename(f.backup(i), f.backup(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := os.Rename(f.path, f.backup(1)); err != nil {
+		return err
+	}
+
+	return f.open()
+}
+
+// backup returns the path of rotated file with index i.
+func (f *rotatingFile) backup(i int) string {
+	return fmt.Sprintf("%s.%d", f.path, i)
+}
+
+// Close closes the file.
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
+}
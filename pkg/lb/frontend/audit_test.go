@@ -0,0 +1,196 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/units"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAuditEvents(t *testing.T, path string) []auditEvent {
+	t.Helper()
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var events []auditEvent
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e auditEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+
+		events = append(events, e)
+	}
+
+	return events
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	f, err := newRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		_, err := f.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, f.Close())
+
+	// Oldest file must be removed
+	for file, content := range map[string]string{path: "dddddddd\n", path + ".1": "cccccccc\n", path + ".2": "bbbbbbbb\n"} {
+		b, err := os.ReadFile(file)
+		require.NoError(t, err)
+		assert.Equal(t, content, string(b))
+	}
+
+	assert.NoFileExists(t, path+".3")
+
+	// Existing file must be appended
+	f, err = newRotatingFile(path, 100, 2)
+	require.NoError(t, err)
+
+	_, err = f.Write([]byte("eeeeeeee\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "dddddddd\neeeeeeee\n", string(b))
+}
+
+func TestAuditLogConfig(t *testing.T) {
+	for _, c := range []AuditLogConfig{
+		{Enabled: true, Sink: "file", File: AuditFileConfig{MaxSize: units.MiB}},
+		{Enabled: true, Sink: "file", File: AuditFileConfig{Path: "audit.log"}},
+		{Enabled: true, Sink: "syslog", Syslog: AuditSyslogConfig{Facility: "unknown"}},
+		{Enabled: true, Sink: "unknown"},
+	} {
+		assert.Error(t, c.Validate())
+	}
+
+	c := AuditLogConfig{Sink: "unknown"}
+	require.NoError(t, c.Validate())
+}
+
+func TestAuditLogMiddleware(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "audit.log")
+
+	audit, err := NewAuditLogger(AuditLogConfig{
+		Sink:         "file",
+		File:         AuditFileConfig{Path: path, MaxSize: units.MiB},
+		RedactParams: []string{"token"},
+	})
+	require.NoError(t, err)
+
+	db, err := setupTestDB(tmpDir)
+	require.NoError(t, err)
+
+	amw := &authenticationMiddleware{
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+		clusterIDs:    []string{"rm-0", "rm-1"},
+		lbType:        base.PromLB,
+		audit:         audit,
+		ceems:         &ceems{db: db},
+		parseRequest:  parseTSDBRequest,
+		pathsACLRegex: regexpAllowedTSDBResources,
+	}
+
+	// Next handler mimics serving by a backend
+	handler := amw.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auditEventFromContext(r.Context()).setBackend("http://localhost:9090")
+		w.Write([]byte("response"))
+	}))
+
+	tests := []struct {
+		name     string
+		req      string
+		user     string
+		decision string
+		reason   string
+		code     int
+	}{
+		{
+			name:     "allow own units",
+			req:      "/api/v1/query_range?query=foo{uuid=\"1479763\"}&start=1735045414&token=secret",
+			user:     "usr1",
+			decision: "allow",
+			code:     200,
+		},
+		{
+			name:     "deny units of other users",
+			req:      "/api/v1/query?query=foo{uuid=~\"1479765|1481510\"}&time=1735045414",
+			user:     "usr1",
+			decision: "deny",
+			reason:   "unauthorized compute units",
+			code:     403,
+		},
+		{
+			name:     "allow admin",
+			req:      "/api/v1/query?query=foo{uuid=~\"1479765|1481510\"}&time=1735045414",
+			user:     "adm1",
+			decision: "allow",
+			code:     200,
+		},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, test.req, nil)
+		request.Header.Set("X-Grafana-User", test.user)
+		request.Header.Set("X-Ceems-Cluster-Id", "rm-0")
+
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		require.Equal(t, test.code, responseRecorder.Code, test.name)
+	}
+
+	require.NoError(t, audit.Close())
+
+	events := readAuditEvents(t, path)
+	require.Len(t, events, len(tests))
+
+	for i, test := range tests {
+		e := events[i]
+		assert.Equal(t, test.user, e.User, test.name)
+		assert.Equal(t, test.decision, e.Decision, test.name)
+		assert.Equal(t, test.reason, e.Reason, test.name)
+		assert.Equal(t, test.code, e.Status, test.name)
+		assert.Equal(t, "rm-0", e.ClusterID, test.name)
+		assert.Equal(t, "tsdb", e.LBType, test.name)
+		assert.NotEmpty(t, e.UUIDs, test.name)
+		assert.NotEmpty(t, e.Params["query"], test.name)
+		assert.Positive(t, e.ResponseBytes, test.name)
+
+		if test.decision == "allow" {
+			assert.Equal(t, "http://localhost:9090", e.Backend, test.name)
+		} else {
+			assert.Empty(t, e.Backend, test.name)
+		}
+	}
+
+	assert.True(t, events[2].Admin)
+	assert.Equal(t, []string{"<redacted>"}, events[0].Params["token"])
+
+	// Raw log must not contain redacted values
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(b), "secret"))
+}
@@ -0,0 +1,453 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Metrics of compute units are considered immutable only after this margin
+// has passed since the end of units. It accounts for scrape and ingestion
+// delays of TSDB.
+const cacheUnitEndMargin = 10 * time.Minute
+
+// Approximate sizes of samples used to estimate the size of cached results.
+const (
+	sampleSize          = 16
+	histogramSampleSize = 128
+)
+
+// CacheConfig contains the configuration of results cache of range queries.
+type CacheConfig struct {
+	Enabled bool             `yaml:"enabled"`
+	MaxSize units.Base2Bytes `yaml:"max_size"`
+	TTL     model.Duration   `yaml:"ttl"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *CacheConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = CacheConfig{
+		MaxSize: 256 * units.MiB,
+		TTL:     model.Duration(24 * time.Hour),
+	}
+
+	type plain CacheConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *CacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	if c.MaxSize <= 0 {
+		return errors.New("max_size of cache must be positive")
+	}
+
+	if c.TTL <= 0 {
+		return errors.New("ttl of cache must be positive")
+	}
+
+	return nil
+}
+
+// extent is the result of range query between evaluation timestamps start and end.
+type extent struct {
+	start  time.Time
+	end    time.Time
+	result model.Matrix
+}
+
+// cacheEntry contains the extents of a cache key.
+type cacheEntry struct {
+	key       string
+	extents   []extent
+	size      int
+	expiresAt time.Time
+}
+
+// resultsCache is a size bounded LRU cache of range query results. Each key
+// can have multiple extents that are partially reused by subsequent queries.
+type resultsCache struct {
+	mu       sync.Mutex
+	maxSize  int
+	ttl      time.Duration
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List
+	requests *prometheus.CounterVec
+	evicted  prometheus.Counter
+	entriesG prometheus.GaugeFunc
+	sizeG    prometheus.GaugeFunc
+}
+
+// newResultsCache returns a new instance of resultsCache.
+func newResultsCache(c CacheConfig) *resultsCache {
+	cache := &resultsCache{
+		maxSize: int(c.MaxSize),
+		ttl:     time.Duration(c.TTL),
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "cache",
+			Name:      "requests_total",
+			Help:      "Total number of range queries looked up in results cache by result (hit, partial_hit, miss).",
+		}, []string{"result"}),
+		evicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "cache",
+			Name:      "evictions_total",
+			Help:      "Total number of entries evicted from results cache.",
+		}),
+	}
+
+	cache.entriesG = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "ceems_lb",
+		Subsystem: "cache",
+		Name:      "entries",
+		Help:      "Current number of entries in results cache.",
+	}, func() float64 {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		return float64(len(cache.entries))
+	})
+
+	cache.sizeG = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "ceems_lb",
+		Subsystem: "cache",
+		Name:      "size_bytes",
+		Help:      "Current estimated size of results cache in bytes.",
+	}, func() float64 {
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+
+		return float64(cache.size)
+	})
+
+	// Initialise counters so that they are always exported
+	for _, result := range []string{"hit", "partial_hit", "miss"} {
+		cache.requests.WithLabelValues(result)
+	}
+
+	return cache
+}
+
+// collectors returns the metrics of cache.
+func (c *resultsCache) collectors() []prometheus.Collector {
+	return []prometheus.Collector{c.requests, c.evicted, c.entriesG, c.sizeG}
+}
+
+// get returns the extents of key that overlap with the range between start and end.
+func (c *resultsCache) get(key string, start, end time.Time) []extent {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert
+
+	// Remove expired entries
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+
+	var extents []extent
+
+	for _, e := range entry.extents {
+		if !e.end.Before(start) && !e.start.After(end) {
+			extents = append(extents, e)
+		}
+	}
+
+	return extents
+}
+
+// add adds the extents to the key. Overlapping and adjacent extents are merged.
+func (c *resultsCache) add(key string, step time.Duration, extents ...extent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert
+		if time.Now().Before(entry.expiresAt) {
+			extents = append(extents, entry.extents...)
+		}
+
+		c.remove(elem)
+	}
+
+	entry := &cacheEntry{
+		key:       key,
+		extents:   mergeExtents(extents, step),
+		expiresAt: time.Now().Add(c.ttl),
+	}
+
+	for _, e := range entry.extents {
+		entry.size += matrixSize(e.result)
+	}
+
+	// Do not cache results that are bigger than cache itself
+	if entry.size > c.maxSize {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += entry.size
+
+	// Evict least recently used entries
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+		c.evicted.Inc()
+	}
+}
+
+// remove removes the element from cache. Caller must hold the lock.
+func (c *resultsCache) remove(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry) //nolint:forcetypeassert
+
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// cacheKey returns the key of range query in the cluster. Query is normalised
+// and the offset of start from step is included in the key so that evaluation
+// timestamps of all the extents of a key are aligned.
+func cacheKey(id string, q *rangeQuery) (string, error) {
+	if q.step < time.Millisecond {
+		return "", errors.New("step must be at least a millisecond")
+	}
+
+	expr, err := parser.ParseExpr(q.query)
+	if err != nil {
+		return "", err
+	}
+
+	offset := q.start.UnixMilli() % q.step.Milliseconds()
+
+	return fmt.Sprintf("%s:%s:%d:%d", id, expr.String(), q.step.Milliseconds(), offset), nil
+}
+
+// missingRanges returns the ranges between start and end that are not covered
+// by extents.
+func missingRanges(extents []extent, start, end time.Time, step time.Duration) [][2]time.Time {
+	var ranges [][2]time.Time
+
+	current := start
+
+	for _, e := range extents {
+		if e.end.Before(current) {
+			continue
+		}
+
+		if e.start.After(end) {
+			break
+		}
+
+		if e.start.After(current) {
+			ranges = append(ranges, [2]time.Time{current, e.start.Add(-step)})
+		}
+
+		current = e.end.Add(step)
+	}
+
+	if !current.After(end) {
+		ranges = append(ranges, [2]time.Time{current, end})
+	}
+
+	return ranges
+}
+
+// mergeExtents merges overlapping and adjacent extents and returns them sorted.
+func mergeExtents(extents []extent, step time.Duration) []extent {
+	slices.SortFunc(extents, func(a, b extent) int {
+		return a.start.Compare(b.start)
+	})
+
+	var merged []extent
+
+	for _, e := range extents {
+		if n := len(merged); n > 0 && !e.start.After(merged[n-1].end.Add(step)) {
+			last := &merged[n-1]
+			last.result = mergeMatrices(last.result, e.result)
+
+			if e.end.After(last.end) {
+				last.end = e.end
+			}
+
+			continue
+		}
+
+		merged = append(merged, e)
+	}
+
+	return merged
+}
+
+// trimMatrix returns the samples of matrix between start and end.
+func trimMatrix(matrix model.Matrix, start, end time.Time) model.Matrix {
+	from, to := model.TimeFromUnixNano(start.UnixNano()), model.TimeFromUnixNano(end.UnixNano())
+
+	trimmed := make(model.Matrix, 0, len(matrix))
+
+	for _, s := range matrix {
+		stream := &model.SampleStream{Metric: s.Metric}
+
+		for _, v := range s.Values {
+			if !v.Timestamp.Before(from) && !v.Timestamp.After(to) {
+				stream.Values = append(stream.Values, v)
+			}
+		}
+
+		for _, h := range s.Histograms {
+			if !h.Timestamp.Before(from) && !h.Timestamp.After(to) {
+				stream.Histograms = append(stream.Histograms, h)
+			}
+		}
+
+		if len(stream.Values) > 0 || len(stream.Histograms) > 0 {
+			trimmed = append(trimmed, stream)
+		}
+	}
+
+	return trimmed
+}
+
+// matrixSize returns the estimated size of matrix in bytes.
+func matrixSize(matrix model.Matrix) int {
+	var size int
+
+	for _, s := range matrix {
+		for name, value := range s.Metric {
+			size += len(name) + len(value)
+		}
+
+		size += len(s.Values)*sampleSize + len(s.Histograms)*histogramSampleSize
+	}
+
+	return size
+}
+
+// serveCached serves the range query in the request using results cache. Only
+// queries on compute units that have ended are cached as their metrics will not
+// change anymore. It returns false when the query cannot be cached.
+func (lb *loadBalancer) serveCached(w http.ResponseWriter, r *http.Request, p *ReqParams) bool {
+	if lb.cache == nil || !lb.isRangeQuery(r) || len(p.uuids) == 0 {
+		return false
+	}
+
+	q, err := parseRangeQuery(r)
+	if err != nil {
+		return false
+	}
+
+	key, err := cacheKey(p.clusterID, q)
+	if err != nil {
+		return false
+	}
+
+	// Check if all the queried units have ended
+	ended, err := lb.amw.ceems.unitsEnded(r.Context(), p.clusterID, p.uuids, time.Now().Add(-cacheUnitEndMargin))
+	if err != nil {
+		lb.logger.Error("Failed to check if units have ended", "cluster_id", p.clusterID, "err", err)
+
+		return false
+	}
+
+	if !ended {
+		return false
+	}
+
+	auditEventFromContext(r.Context()).setBackend("cache")
+
+	// Get cached extents and fetch the missing ranges
+	extents := lb.cache.get(key, q.start, q.end)
+	missing := missingRanges(extents, q.start, q.end, q.step)
+
+	switch {
+	case len(missing) == 0:
+		lb.cache.requests.WithLabelValues("hit").Inc()
+	case len(extents) > 0:
+		lb.cache.requests.WithLabelValues("partial_hit").Inc()
+	default:
+		lb.cache.requests.WithLabelValues("miss").Inc()
+	}
+
+	response := tsdb.Response[matrixData]{
+		Status: "success",
+		Data:   matrixData{ResultType: model.ValMatrix.String()},
+	}
+
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	matrices := make([]model.Matrix, 0, len(extents)+len(missing))
+
+	var fetched []extent
+
+	for _, m := range missing {
+		resp := lb.serveSlices(r, q, lb.splits(p.clusterID, m[0], m[1], q.step))
+		if resp.code != http.StatusOK || resp.response.Status != "success" {
+			writeResponse(w, resp.code, resp.header, resp.body)
+
+			return true
+		}
+
+		header = resp.header
+		response.Warnings = append(response.Warnings, resp.response.Warnings...)
+
+		// End of extent is the last evaluation timestamp in the range
+		fetched = append(fetched, extent{
+			start:  m[0],
+			end:    m[0].Add(m[1].Sub(m[0]) / q.step * q.step),
+			result: resp.response.Data.Result,
+		})
+		matrices = append(matrices, resp.response.Data.Result)
+	}
+
+	if len(fetched) > 0 {
+		lb.cache.add(key, q.step, fetched...)
+	}
+
+	for _, e := range extents {
+		matrices = append(matrices, e.result)
+	}
+
+	response.Data.Result = trimMatrix(mergeMatrices(matrices...), q.start, q.end)
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		lb.logger.Error("Failed to marshal cached range query response", "err", err)
+		http.Error(w, "Failed to merge responses", http.StatusInternalServerError)
+
+		return true
+	}
+
+	writeResponse(w, http.StatusOK, header, body)
+
+	return true
+}
@@ -0,0 +1,252 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testMatrix(value float64, timestamps ...int64) model.Matrix {
+	stream := &model.SampleStream{Metric: model.Metric{"__name__": "foo"}}
+	for _, ts := range timestamps {
+		stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.Time(ts * 1000), Value: model.SampleValue(value)})
+	}
+
+	return model.Matrix{stream}
+}
+
+func TestMissingRanges(t *testing.T) {
+	step := 10 * time.Second
+	extents := []extent{
+		{start: time.Unix(100, 0), end: time.Unix(200, 0)},
+		{start: time.Unix(300, 0), end: time.Unix(400, 0)},
+	}
+
+	// Fully covered
+	assert.Empty(t, missingRanges(extents, time.Unix(110, 0), time.Unix(200, 0), step))
+
+	// Not covered at all
+	assert.Equal(t, [][2]time.Time{{time.Unix(500, 0), time.Unix(600, 0)}}, missingRanges(extents, time.Unix(500, 0), time.Unix(600, 0), step))
+
+	// Partially covered
+	assert.Equal(
+		t,
+		[][2]time.Time{
+			{time.Unix(50, 0), time.Unix(90, 0)},
+			{time.Unix(210, 0), time.Unix(290, 0)},
+			{time.Unix(410, 0), time.Unix(455, 0)},
+		},
+		missingRanges(extents, time.Unix(50, 0), time.Unix(455, 0), step),
+	)
+}
+
+func TestMergeExtents(t *testing.T) {
+	step := 10 * time.Second
+	extents := mergeExtents([]extent{
+		{start: time.Unix(300, 0), end: time.Unix(400, 0), result: testMatrix(2, 300, 400)},
+		{start: time.Unix(100, 0), end: time.Unix(200, 0), result: testMatrix(1, 100, 200)},
+		{start: time.Unix(210, 0), end: time.Unix(250, 0), result: testMatrix(1, 210, 250)},
+		{start: time.Unix(400, 0), end: time.Unix(500, 0), result: testMatrix(3, 400, 500)},
+	}, step)
+
+	require.Len(t, extents, 2)
+	assert.Equal(t, time.Unix(100, 0), extents[0].start)
+	assert.Equal(t, time.Unix(250, 0), extents[0].end)
+	assert.Len(t, extents[0].result[0].Values, 4)
+	assert.Equal(t, time.Unix(300, 0), extents[1].start)
+	assert.Equal(t, time.Unix(500, 0), extents[1].end)
+	assert.Equal(t, testMatrix(2, 300, 400)[0].Values[0], extents[1].result[0].Values[0])
+	assert.Len(t, extents[1].result[0].Values, 3)
+}
+
+func TestResultsCacheEviction(t *testing.T) {
+	size := matrixSize(testMatrix(1, 100, 200))
+	cache := newResultsCache(CacheConfig{Enabled: true, MaxSize: units.Base2Bytes(2 * size), TTL: model.Duration(time.Hour)})
+
+	cache.add("a", time.Second, extent{start: time.Unix(100, 0), end: time.Unix(200, 0), result: testMatrix(1, 100, 200)})
+	cache.add("b", time.Second, extent{start: time.Unix(100, 0), end: time.Unix(200, 0), result: testMatrix(1, 100, 200)})
+
+	// Access a so that b is least recently used
+	assert.Len(t, cache.get("a", time.Unix(0, 0), time.Unix(1000, 0)), 1)
+
+	cache.add("c", time.Second, extent{start: time.Unix(100, 0), end: time.Unix(200, 0), result: testMatrix(1, 100, 200)})
+
+	assert.Len(t, cache.get("a", time.Unix(0, 0), time.Unix(1000, 0)), 1)
+	assert.Empty(t, cache.get("b", time.Unix(0, 0), time.Unix(1000, 0)))
+	assert.Len(t, cache.get("c", time.Unix(0, 0), time.Unix(1000, 0)), 1)
+	assert.InDelta(t, 1, testutil.ToFloat64(cache.evicted), 0)
+	assert.Equal(t, 2*size, cache.size)
+
+	// Expired entries are not returned
+	cache.ttl = -time.Second
+	cache.add("d", time.Second, extent{start: time.Unix(100, 0), end: time.Unix(200, 0), result: testMatrix(1, 100, 200)})
+	assert.Empty(t, cache.get("d", time.Unix(0, 0), time.Unix(1000, 0)))
+}
+
+func TestCachedRangeQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Setup DB with one ended unit and one running unit
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "ceems.db"))
+	require.NoError(t, err)
+
+	_, err = db.Exec(`
+CREATE TABLE units (
+	"id" integer not null primary key,
+	"cluster_id" text,
+	"uuid" text,
+	"ended_at_ts" integer
+);
+INSERT INTO units VALUES(1, 'default', '1', ` + strconv.FormatInt(time.Now().Add(-time.Hour).UnixMilli(), 10) + `);
+INSERT INTO units VALUES(2, 'default', '2', 0);`)
+	require.NoError(t, err)
+
+	var requests atomic.Int64
+
+	server := dummyMatrixTSDBServer("30d", 1, http.StatusOK, &requests)
+	defer server.Close()
+
+	b, err := backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: server.URL}}, logger)
+	require.NoError(t, err)
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	manager.Add("default", b)
+
+	frontend, err := New(&Config{
+		Logger:  logger,
+		Manager: manager,
+		Address: "localhost:9030",
+		Cache:   CacheConfig{Enabled: true, MaxSize: units.MiB, TTL: model.Duration(time.Hour)},
+	})
+	require.NoError(t, err)
+
+	lb := frontend.(*loadBalancer) //nolint:forcetypeassert
+	lb.amw.ceems.db = db
+
+	// Cache metrics must be registered
+	count, err := testutil.GatherAndCount(lb.registry, "ceems_lb_cache_requests_total")
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	end := time.Now().Truncate(time.Hour)
+
+	tests := []struct {
+		name     string
+		uuid     string
+		start    time.Time
+		requests int64
+		result   string
+	}{
+		{
+			name:     "miss",
+			uuid:     "1",
+			start:    end.Add(-2 * time.Hour),
+			requests: 1,
+			result:   "miss",
+		},
+		{
+			name:     "hit",
+			uuid:     "1",
+			start:    end.Add(-time.Hour),
+			requests: 0,
+			result:   "hit",
+		},
+		{
+			name:     "partial hit",
+			uuid:     "1",
+			start:    end.Add(-4 * time.Hour),
+			requests: 1,
+			result:   "partial_hit",
+		},
+		{
+			name:     "hit after partial hit",
+			uuid:     "1",
+			start:    end.Add(-4 * time.Hour),
+			requests: 0,
+			result:   "hit",
+		},
+		{
+			name:     "running unit",
+			uuid:     "2",
+			start:    end.Add(-time.Hour),
+			requests: 1,
+		},
+	}
+
+	for _, test := range tests {
+		before := requests.Load()
+
+		var counter float64
+		if test.result != "" {
+			counter = testutil.ToFloat64(lb.cache.requests.WithLabelValues(test.result))
+		}
+
+		values := url.Values{}
+		values.Set("query", `foo{uuid="`+test.uuid+`"}`)
+		values.Set("start", strconv.FormatInt(test.start.Unix(), 10))
+		values.Set("end", strconv.FormatInt(end.Unix(), 10))
+		values.Set("step", "5m")
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?"+values.Encode(), nil)
+		request = request.WithContext(
+			context.WithValue(
+				request.Context(), ReqParamsContextKey{},
+				&ReqParams{queryPeriod: time.Since(test.start), clusterID: "default", uuids: []string{test.uuid}},
+			),
+		)
+
+		// Responses served from cache are always uncompressed even when the
+		// client accepts compressed ones
+		if test.result != "" {
+			request.Header.Set("Accept-Encoding", "gzip")
+		}
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(lb.Serve).ServeHTTP(responseRecorder, request)
+
+		require.Equal(t, http.StatusOK, responseRecorder.Code, test.name)
+		assert.Empty(t, responseRecorder.Header().Get("Content-Encoding"), test.name)
+		assert.Equal(t, test.requests, requests.Load()-before, test.name)
+
+		if test.result != "" {
+			assert.InDelta(t, counter+1, testutil.ToFloat64(lb.cache.requests.WithLabelValues(test.result)), 0, test.name)
+		}
+
+		// Response must have every evaluation timestamp exactly once
+		var resp tsdb.Response[matrixData]
+		require.NoError(t, json.NewDecoder(responseRecorder.Body).Decode(&resp), test.name)
+		require.Len(t, resp.Data.Result, 1, test.name)
+
+		samples := resp.Data.Result[0].Values
+		require.Len(t, samples, int(end.Sub(test.start)/(5*time.Minute))+1, test.name)
+
+		for i, s := range samples {
+			assert.Equal(t, test.start.Add(time.Duration(i)*5*time.Minute).UnixMilli(), int64(s.Timestamp), test.name)
+		}
+	}
+}
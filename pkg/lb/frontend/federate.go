@@ -0,0 +1,415 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/common/model"
+)
+
+// matrixData is the data of range query response of TSDB.
+type matrixData struct {
+	ResultType string       `json:"resultType"`
+	Result     model.Matrix `json:"result"`
+}
+
+// sliceResponse is the response of a slice of federated query.
+type sliceResponse struct {
+	code     int
+	header   http.Header
+	body     []byte
+	response tsdb.Response[matrixData]
+}
+
+// rangeQuery contains the parameters of a range query.
+type rangeQuery struct {
+	form  url.Values
+	query string
+	start time.Time
+	end   time.Time
+	step  time.Duration
+}
+
+// parseRangeQuery returns the parameters of range query in the request.
+func parseRangeQuery(r *http.Request) (*rangeQuery, error) {
+	form, err := readForm(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRangeForm(form)
+}
+
+// parseRangeForm returns the parameters of range query in the form values.
+func parseRangeForm(form url.Values) (*rangeQuery, error) {
+	var err error
+
+	q := &rangeQuery{form: form, query: form.Get("query")}
+
+	if q.start, err = parseTime(form.Get("start")); err != nil {
+		return nil, err
+	}
+
+	if q.end, err = parseTime(form.Get("end")); err != nil {
+		return nil, err
+	}
+
+	if q.end.Before(q.start) {
+		return nil, errors.New("end timestamp must not be before start time")
+	}
+
+	if q.step, err = parseDuration(form.Get("step")); err != nil {
+		return nil, err
+	}
+
+	if q.step <= 0 {
+		return nil, errors.New("zero or negative query resolution step widths are not accepted")
+	}
+
+	return q, nil
+}
+
+// isRangeQuery returns true if the request is a TSDB range query.
+func (lb *loadBalancer) isRangeQuery(r *http.Request) bool {
+	return lb.lbType == base.PromLB && strings.HasSuffix(r.URL.Path, "query_range")
+}
+
+// federate splits the range query in the request at retention boundaries of
+// backend TSDB servers, proxies each slice to the backend server that covers
+// it and merges the results. It returns false when the request must be served
+// by a single backend server.
+func (lb *loadBalancer) federate(w http.ResponseWriter, r *http.Request, id string) bool {
+	// Only range queries on TSDB can be federated
+	if !lb.isRangeQuery(r) {
+		return false
+	}
+
+	// Check if strategy supports splitting queries
+	if _, ok := lb.manager.(serverpool.Splitter); !ok {
+		return false
+	}
+
+	// Read query parameters. If any of them is invalid, let backend server
+	// respond with an appropriate error
+	q, err := parseRangeQuery(r)
+	if err != nil {
+		return false
+	}
+
+	// Split the query and check if we need more than one backend
+	splits := lb.splits(id, q.start, q.end, q.step)
+	if len(splits) < 2 {
+		return false
+	}
+
+	lb.logger.Debug("Federating range query", "cluster_id", id, "num_slices", len(splits))
+
+	backends := make([]string, len(splits))
+	for i, split := range splits {
+		backends[i] = split.Backend.String()
+	}
+
+	auditEventFromContext(r.Context()).setBackend(strings.Join(backends, ","))
+
+	resp := lb.serveSlices(r, q, splits)
+	writeResponse(w, resp.code, resp.header, resp.body)
+
+	return true
+}
+
+// splits returns the slices of range query between start and end and backend
+// servers that serve them. When strategy does not support splitting queries,
+// the entire range is served by the target backend server.
+func (lb *loadBalancer) splits(id string, start, end time.Time, step time.Duration) []serverpool.Slice {
+	if splitter, ok := lb.manager.(serverpool.Splitter); ok {
+		return alignSlices(splitter.Split(id, start, end), start, step)
+	}
+
+	if target := lb.manager.Target(id, time.Since(start)); target != nil {
+		return []serverpool.Slice{{Backend: target, Start: start, End: end}}
+	}
+
+	return nil
+}
+
+// serveSlices proxies each slice of range query concurrently and returns the
+// merged response. If any of the slices failed, its response is returned.
+func (lb *loadBalancer) serveSlices(r *http.Request, q *rangeQuery, splits []serverpool.Slice) *sliceResponse {
+	if len(splits) == 0 {
+		body := []byte("Service not available\n")
+
+		return &sliceResponse{
+			code:     http.StatusServiceUnavailable,
+			header:   http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			body:     body,
+			response: tsdb.Response[matrixData]{Status: "error", Error: "service not available"},
+		}
+	}
+
+	responses := make([]*sliceResponse, len(splits))
+
+	var wg sync.WaitGroup
+
+	for i, split := range splits {
+		wg.Add(1)
+
+		go func(i int, split serverpool.Slice) {
+			defer wg.Done()
+
+			responses[i] = serveSlice(r, q.form, split)
+		}(i, split)
+	}
+
+	wg.Wait()
+
+	// If any of the slices failed, return its response as it is
+	for _, resp := range responses {
+		if resp.code != http.StatusOK || resp.response.Status != "success" {
+			lb.logger.Error("Failed to serve range query", "code", resp.code, "err", resp.response.Error)
+
+			return resp
+		}
+	}
+
+	// Single slice needs no merging
+	if len(responses) == 1 {
+		return responses[0]
+	}
+
+	// Merge responses
+	merged := &sliceResponse{
+		code:   http.StatusOK,
+		header: responses[0].header,
+		response: tsdb.Response[matrixData]{
+			Status: "success",
+			Data:   matrixData{ResultType: model.ValMatrix.String()},
+		},
+	}
+
+	matrices := make([]model.Matrix, len(responses))
+
+	for i, resp := range responses {
+		matrices[i] = resp.response.Data.Result
+
+		for _, warning := range resp.response.Warnings {
+			if !slices.Contains(merged.response.Warnings, warning) {
+				merged.response.Warnings = append(merged.response.Warnings, warning)
+			}
+		}
+	}
+
+	merged.response.Data.Result = mergeMatrices(matrices...)
+
+	var err error
+	if merged.body, err = json.Marshal(merged.response); err != nil {
+		lb.logger.Error("Failed to marshal merged range query response", "err", err)
+
+		body := []byte("Failed to merge responses\n")
+
+		return &sliceResponse{
+			code:     http.StatusInternalServerError,
+			header:   http.Header{"Content-Type": []string{"text/plain; charset=utf-8"}},
+			body:     body,
+			response: tsdb.Response[matrixData]{Status: "error", Error: err.Error()},
+		}
+	}
+
+	return merged
+}
+
+// alignSlices aligns the boundaries of slices to the evaluation timestamps of
+// range query which are start + k*step. Adjacent slices share the boundary
+// timestamp so that no evaluation timestamp is left out. Slices that do not
+// contain any evaluation timestamp after alignment are merged into the older
+// slice.
+func alignSlices(splits []serverpool.Slice, start time.Time, step time.Duration) []serverpool.Slice {
+	var aligned []serverpool.Slice
+
+	for i, split := range splits {
+		// Oldest slice already starts at start of query
+		if i == len(splits)-1 {
+			aligned = append(aligned, split)
+
+			break
+		}
+
+		// Round boundary up to the next evaluation timestamp
+		offset := split.Start.Sub(start)
+		if rem := offset % step; rem != 0 {
+			offset += step - rem
+		}
+
+		split.Start = start.Add(offset)
+
+		// Slice has no evaluation timestamp. Older slice will take its range
+		if split.Start.After(split.End) {
+			splits[i+1].End = split.End
+
+			continue
+		}
+
+		// Older slice ends at the boundary
+		splits[i+1].End = split.Start
+
+		aligned = append(aligned, split)
+	}
+
+	return aligned
+}
+
+// serveSlice proxies the slice of range query to its backend server and returns
+// the response.
+func serveSlice(r *http.Request, form url.Values, split serverpool.Slice) *sliceResponse {
+	values := url.Values{}
+	for k, v := range form {
+		values[k] = slices.Clone(v)
+	}
+
+	values.Set("start", strconv.FormatFloat(float64(split.Start.UnixMilli())/1000, 'f', -1, 64))
+	values.Set("end", strconv.FormatFloat(float64(split.End.UnixMilli())/1000, 'f', -1, 64))
+
+	body := values.Encode()
+
+	// Always use POST method to send the parameters in the body. Do not retry
+	// failed slices on other backends as they might not cover the slice
+	req := r.Clone(context.WithValue(r.Context(), RetryContextKey{}, true))
+	req.Method = http.MethodPost
+	req.URL.RawQuery = ""
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Del("Content-Length")
+
+	// Responses of slices are decoded and merged. Let the transport negotiate
+	// and decompress the response instead of passing client's encodings
+	req.Header.Del("Accept-Encoding")
+
+	rw := newBufferedResponseWriter()
+	split.Backend.Serve(rw, req)
+
+	resp := &sliceResponse{
+		code:   rw.code,
+		header: rw.header,
+		body:   rw.body.Bytes(),
+	}
+
+	if err := json.Unmarshal(resp.body, &resp.response); err != nil {
+		resp.response.Status = "error"
+		resp.response.Error = err.Error()
+
+		if resp.code == http.StatusOK {
+			resp.code = http.StatusBadGateway
+		}
+	}
+
+	return resp
+}
+
+// mergeMatrices merges the matrices ordered by their precedence. When matrices
+// overlap, samples from the matrix with higher precedence are retained.
+func mergeMatrices(matrices ...model.Matrix) model.Matrix {
+	streams := make(map[model.Fingerprint]*model.SampleStream)
+	timestamps := make(map[model.Fingerprint]map[model.Time]struct{})
+
+	var fingerprints []model.Fingerprint
+
+	for _, matrix := range matrices {
+		for _, s := range matrix {
+			fp := s.Metric.Fingerprint()
+
+			stream, ok := streams[fp]
+			if !ok {
+				stream = &model.SampleStream{Metric: s.Metric}
+				streams[fp] = stream
+				timestamps[fp] = make(map[model.Time]struct{})
+				fingerprints = append(fingerprints, fp)
+			}
+
+			for _, v := range s.Values {
+				if _, ok := timestamps[fp][v.Timestamp]; !ok {
+					stream.Values = append(stream.Values, v)
+					timestamps[fp][v.Timestamp] = struct{}{}
+				}
+			}
+
+			for _, h := range s.Histograms {
+				if _, ok := timestamps[fp][h.Timestamp]; !ok {
+					stream.Histograms = append(stream.Histograms, h)
+					timestamps[fp][h.Timestamp] = struct{}{}
+				}
+			}
+		}
+	}
+
+	merged := make(model.Matrix, 0, len(fingerprints))
+
+	for _, fp := range fingerprints {
+		stream := streams[fp]
+
+		slices.SortFunc(stream.Values, func(a, b model.SamplePair) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		slices.SortFunc(stream.Histograms, func(a, b model.SampleHistogramPair) int {
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+
+		merged = append(merged, stream)
+	}
+
+	return merged
+}
+
+// readForm returns the form values of the request without consuming its body.
+func readForm(r *http.Request) (url.Values, error) {
+	if r.Body == nil {
+		return nil, errors.New("no body found in the request")
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	clonedReq := r.Clone(r.Context())
+	clonedReq.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := clonedReq.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	return clonedReq.Form, nil
+}
+
+// writeResponse writes the response with given code, headers and body.
+func writeResponse(w http.ResponseWriter, code int, header http.Header, body []byte) {
+	for k, v := range header {
+		if k == "Content-Length" {
+			continue
+		}
+
+		w.Header()[k] = v
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	w.Write(body)
+}
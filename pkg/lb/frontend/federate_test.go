@@ -0,0 +1,258 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dummyMatrixTSDBServer returns a TSDB server with given retention that responds
+// to range queries with a series that has value at every evaluation timestamp.
+// Number of range queries served are counted in requests when it is not nil.
+func dummyMatrixTSDBServer(retention string, value float64, code int, requests *atomic.Int64) *httptest.Server {
+	expectedConfig := tsdb.Response[any]{
+		Status: "success",
+		Data: map[string]string{
+			"yaml": "global:\n  scrape_interval: 15s\n  scrape_timeout: 10s",
+		},
+	}
+
+	expectedFlags := tsdb.Response[any]{
+		Status: "success",
+		Data: map[string]interface{}{
+			"query.lookback-delta": "5m",
+			"query.max-samples":    "50000000",
+			"query.timeout":        "2m",
+		},
+	}
+
+	expectedRuntimeInfo := tsdb.Response[any]{
+		Status: "success",
+		Data: map[string]string{
+			"storageRetention": retention,
+		},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "config"):
+			json.NewEncoder(w).Encode(&expectedConfig)
+
+			return
+		case strings.HasSuffix(r.URL.Path, "flags"):
+			json.NewEncoder(w).Encode(&expectedFlags)
+
+			return
+		case strings.HasSuffix(r.URL.Path, "runtimeinfo"):
+			json.NewEncoder(w).Encode(&expectedRuntimeInfo)
+
+			return
+		}
+
+		if !strings.HasSuffix(r.URL.Path, "query_range") || strings.HasPrefix(r.FormValue("query"), "up{") {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		if requests != nil {
+			requests.Add(1)
+		}
+
+		if code != http.StatusOK {
+			w.WriteHeader(code)
+			json.NewEncoder(w).Encode(&tsdb.Response[any]{Status: "error", ErrorType: "internal", Error: "failed"})
+
+			return
+		}
+
+		start, _ := parseTime(r.FormValue("start"))
+		end, _ := parseTime(r.FormValue("end"))
+		step, _ := parseDuration(r.FormValue("step"))
+
+		stream := &model.SampleStream{Metric: model.Metric{"__name__": "foo", "uuid": "1"}}
+		for ts := start; !ts.After(end); ts = ts.Add(step) {
+			stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.TimeFromUnixNano(ts.UnixNano()), Value: model.SampleValue(value)})
+		}
+
+		resp := tsdb.Response[matrixData]{
+			Status:   "success",
+			Data:     matrixData{ResultType: "matrix", Result: model.Matrix{stream}},
+			Warnings: []string{"dummy"},
+		}
+
+		// Compress responses like Prometheus does
+		var out io.Writer = w
+
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+
+			out = gz
+		}
+
+		json.NewEncoder(out).Encode(&resp)
+	}))
+}
+
+func TestAlignSlices(t *testing.T) {
+	start := time.Unix(0, 0)
+	end := time.Unix(1000, 0)
+
+	// Boundary is rounded up to next evaluation timestamp
+	splits := alignSlices([]serverpool.Slice{
+		{Start: time.Unix(555, 0), End: end},
+		{Start: start, End: time.Unix(555, 0)},
+	}, start, 100*time.Second)
+	require.Len(t, splits, 2)
+	assert.Equal(t, time.Unix(600, 0), splits[0].Start)
+	assert.Equal(t, time.Unix(600, 0), splits[1].End)
+
+	// Slice without any evaluation timestamp is merged into older slice
+	splits = alignSlices([]serverpool.Slice{
+		{Start: time.Unix(950, 0), End: time.Unix(990, 0)},
+		{Start: start, End: time.Unix(950, 0)},
+	}, start, 100*time.Second)
+	require.Len(t, splits, 1)
+	assert.Equal(t, start, splits[0].Start)
+	assert.Equal(t, time.Unix(990, 0), splits[0].End)
+}
+
+func TestFederatedRangeQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	tests := []struct {
+		name       string
+		coldCode   int
+		start      time.Time
+		split      bool
+		acceptGzip bool
+	}{
+		{
+			name:     "range query spanning hot and cold backends",
+			coldCode: http.StatusOK,
+			start:    time.Now().Add(-60 * 24 * time.Hour),
+			split:    true,
+		},
+		{
+			name:       "range query spanning hot and cold backends from client accepting gzip",
+			coldCode:   http.StatusOK,
+			start:      time.Now().Add(-60 * 24 * time.Hour),
+			split:      true,
+			acceptGzip: true,
+		},
+		{
+			name:     "range query within hot backend",
+			coldCode: http.StatusOK,
+			start:    time.Now().Add(-10 * 24 * time.Hour),
+		},
+		{
+			name:     "range query with failing cold backend",
+			coldCode: http.StatusInternalServerError,
+			start:    time.Now().Add(-60 * 24 * time.Hour),
+			split:    true,
+		},
+	}
+
+	for _, test := range tests {
+		hotServer := dummyMatrixTSDBServer("30d", 1, http.StatusOK, nil)
+		defer hotServer.Close()
+
+		coldServer := dummyMatrixTSDBServer("180d", 2, test.coldCode, nil)
+		defer coldServer.Close()
+
+		manager, err := serverpool.New("resource-based", logger)
+		require.NoError(t, err)
+
+		for _, s := range []*httptest.Server{hotServer, coldServer} {
+			b, err := backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: s.URL}}, logger)
+			require.NoError(t, err)
+
+			manager.Add("default", b)
+		}
+
+		lb, err := New(&Config{Logger: logger, Manager: manager, Address: "localhost:9030"})
+		require.NoError(t, err)
+
+		end := time.Now()
+		step := time.Hour
+
+		values := url.Values{}
+		values.Set("query", `foo{uuid="1"}`)
+		values.Set("start", strconv.FormatInt(test.start.Unix(), 10))
+		values.Set("end", strconv.FormatInt(end.Unix(), 10))
+		values.Set("step", "1h")
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?"+values.Encode(), nil)
+		request = request.WithContext(
+			context.WithValue(
+				request.Context(), ReqParamsContextKey{},
+				&ReqParams{queryPeriod: time.Since(test.start), clusterID: "default"},
+			),
+		)
+
+		// Browsers and Grafana always accept compressed responses
+		if test.acceptGzip {
+			request.Header.Set("Accept-Encoding", "gzip")
+		}
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(lb.Serve).ServeHTTP(responseRecorder, request)
+
+		if test.coldCode != http.StatusOK {
+			assert.Equal(t, test.coldCode, responseRecorder.Code, test.name)
+
+			continue
+		}
+
+		require.Equal(t, http.StatusOK, responseRecorder.Code, test.name)
+
+		var resp tsdb.Response[matrixData]
+		require.NoError(t, json.NewDecoder(responseRecorder.Body).Decode(&resp), test.name)
+		require.Len(t, resp.Data.Result, 1, test.name)
+
+		samples := resp.Data.Result[0].Values
+
+		// Every evaluation timestamp must be present exactly once and in order
+		expectedStart, _ := parseTime(strconv.FormatInt(test.start.Unix(), 10))
+		expectedEnd, _ := parseTime(strconv.FormatInt(end.Unix(), 10))
+		require.Len(t, samples, int(expectedEnd.Sub(expectedStart)/step)+1, test.name)
+
+		for i, s := range samples {
+			assert.Equal(t, expectedStart.Add(time.Duration(i)*step).UnixMilli(), int64(s.Timestamp), test.name)
+		}
+
+		// Recent samples must come from hot backend and older ones from cold backend
+		assert.InDelta(t, 1, float64(samples[len(samples)-1].Value), 0, test.name)
+
+		if test.split {
+			assert.InDelta(t, 2, float64(samples[0].Value), 0, test.name)
+			assert.Equal(t, []string{"dummy"}, resp.Warnings, test.name)
+		} else {
+			assert.InDelta(t, 1, float64(samples[0].Value), 0, test.name)
+		}
+	}
+}
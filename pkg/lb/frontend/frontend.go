@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"slices"
 	"strings"
@@ -17,9 +18,14 @@ import (
 	ceems_api_base "github.com/mahendrapaipuri/ceems/pkg/api/base"
 	ceems_api_cli "github.com/mahendrapaipuri/ceems/pkg/api/cli"
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
 	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
 	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	promcollectors "github.com/prometheus/client_golang/prometheus/collectors"
+	versioncollector "github.com/prometheus/client_golang/prometheus/collectors/version"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/exporter-toolkit/web"
 )
 
@@ -45,6 +51,10 @@ type ReqParams struct {
 // LoadBalancer is the interface to implement.
 type LoadBalancer interface {
 	Serve(w http.ResponseWriter, r *http.Request)
+	AddBackend(id string, b backend.Server)
+	RemoveBackend(id string, b backend.Server)
+	ValidateClusterIDs(ctx context.Context, ids []string) error
+	ReloadRemoteWriteTokens()
 	Start(ctx context.Context) error
 	Shutdown(ctx context.Context) error
 }
@@ -58,16 +68,28 @@ type Config struct {
 	WebConfigFile    string
 	APIServer        ceems_api_cli.CEEMSAPIServerConfig
 	Manager          serverpool.Manager
+	Cache            CacheConfig
+	Limits           LimitsConfig
+	AuditLogger      *AuditLogger
+	RemoteWrite      RemoteWriteConfig
+	Reload           func() error
 }
 
 // loadBalancer struct.
 type loadBalancer struct {
-	logger    *slog.Logger
-	lbType    base.LBType
-	manager   serverpool.Manager
-	server    *http.Server
-	webConfig *web.FlagConfig
-	amw       *authenticationMiddleware
+	logger       *slog.Logger
+	lbType       base.LBType
+	manager      serverpool.Manager
+	server       *http.Server
+	webConfig    *web.FlagConfig
+	amw          *authenticationMiddleware
+	cache        *resultsCache
+	limiter      *queryLimiter
+	registry     *prometheus.Registry
+	remoteWriter *remoteWriteGateway
+	rwServer     *http.Server
+	rwWebConfig  *web.FlagConfig
+	reload       func() error
 }
 
 // New returns a new instance of load balancer.
@@ -91,8 +113,50 @@ func New(c *Config) (LoadBalancer, error) {
 			WebSystemdSocket:   &c.WebSystemdSocket,
 			WebConfigFile:      &c.WebConfigFile,
 		},
-		manager: c.Manager,
-		amw:     amw,
+		manager:  c.Manager,
+		amw:      amw,
+		registry: prometheus.NewRegistry(),
+		reload:   c.Reload,
+	}
+
+	// Register metrics
+	lb.registry.MustRegister(
+		versioncollector.NewCollector(base.CEEMSLoadBalancerAppName),
+		promcollectors.NewProcessCollector(promcollectors.ProcessCollectorOpts{}),
+		promcollectors.NewGoCollector(),
+		newBackendCollector(lb.manager),
+	)
+
+	// Setup results cache of range queries
+	if c.Cache.Enabled {
+		lb.cache = newResultsCache(c.Cache)
+		lb.registry.MustRegister(lb.cache.collectors()...)
+	}
+
+	// Setup per user query limits
+	if c.Limits.Enabled {
+		lb.limiter = newQueryLimiter(c.Limits)
+		lb.registry.MustRegister(lb.limiter.collectors()...)
+	}
+
+	// Setup remote write gateway for push based clusters
+	if c.LBType == base.PromLB && len(c.RemoteWrite.Agents) > 0 {
+		lb.remoteWriter = newRemoteWriteGateway(c.RemoteWrite, c.Logger)
+		lb.registry.MustRegister(lb.remoteWriter.collectors()...)
+
+		// Remote write on a dedicated listener so that it is not behind the
+		// basic auth of web config of LB
+		if c.RemoteWrite.ListenAddress != "" {
+			lb.rwServer = &http.Server{
+				Addr:              c.RemoteWrite.ListenAddress,
+				ReadHeaderTimeout: 2 * time.Second,
+			}
+			lb.rwWebConfig = &web.FlagConfig{
+				WebListenAddresses: &[]string{c.RemoteWrite.ListenAddress},
+				WebSystemdSocket:   new(bool),
+				WebConfigFile:      &c.RemoteWrite.WebConfigFile,
+			}
+		}
 	}
 
 	// Setup a timeout context
@@ -105,41 +169,61 @@ func New(c *Config) (LoadBalancer, error) {
 	}
 
 	// Setup error handlers for backend servers
-	lb.errorHandlers()
+	for _, backends := range lb.manager.Backends() {
+		for _, backend := range backends {
+			lb.errorHandler(backend)
+		}
+	}
 
 	return lb, nil
 }
 
-// errorHandlers sets up error handlers for backend servers.
-func (lb *loadBalancer) errorHandlers() {
-	// Iterate over all backend servers
-	for _, backends := range lb.manager.Backends() {
-		for _, backend := range backends {
-			backend.ReverseProxy().ErrorHandler = func(writer http.ResponseWriter, request *http.Request, err error) {
-				lb.logger.Error("Failed to handle the request", "host", backend.URL().Host, "err", err)
-				backend.SetAlive(false)
-
-				// If already retried the request, return error
-				if !allowRetry(request) {
-					lb.logger.Info("Max retry attempts reached, terminating", "address", request.RemoteAddr, "path", request.URL.Path)
-					http.Error(writer, "Service not available", http.StatusServiceUnavailable)
-
-					return
-				}
-
-				// Retry request and set context value so that we dont retry for second time
-				lb.logger.Info("Attempting retry", "address", request.RemoteAddr, "path", request.URL.Path)
-				lb.Serve(
-					writer,
-					request.WithContext(
-						context.WithValue(request.Context(), RetryContextKey{}, true),
-					),
-				)
-			}
+// errorHandler sets up error handler for backend server.
+func (lb *loadBalancer) errorHandler(server backend.Server) {
+	server.ReverseProxy().ErrorHandler = func(writer http.ResponseWriter, request *http.Request, err error) {
+		lb.logger.Error("Failed to handle the request", "host", server.URL().Host, "err", err)
+
+		// Requests cancelled by the client and requests rejected by half open
+		// backend are not failures of backend
+		if !errors.Is(err, context.Canceled) && !errors.Is(err, backend.ErrTrialInFlight) {
+			server.Observe(true)
+		}
+
+		// If already retried the request, return error
+		if !allowRetry(request) {
+			lb.logger.Info("Max retry attempts reached, terminating", "address", request.RemoteAddr, "path", request.URL.Path)
+			http.Error(writer, "Service not available", http.StatusServiceUnavailable)
+
+			return
 		}
+
+		// Retry request and set context value so that we dont retry for second time
+		lb.logger.Info("Attempting retry", "address", request.RemoteAddr, "path", request.URL.Path)
+		lb.Serve(
+			writer,
+			request.WithContext(
+				context.WithValue(request.Context(), RetryContextKey{}, true),
+			),
+		)
 	}
 }
 
+// AddBackend adds backend server to the pool of cluster `id` while LB is serving
+// requests.
+func (lb *loadBalancer) AddBackend(id string, b backend.Server) {
+	lb.errorHandler(b)
+	lb.manager.Add(id, b)
+	lb.amw.setClusterIDs(slices.Collect(maps.Keys(lb.manager.Backends())))
+}
+
+// RemoveBackend removes backend server from the pool of cluster `id` while LB is
+// serving requests. Requests that are being served by the backend server are
+// not dropped.
+func (lb *loadBalancer) RemoveBackend(id string, b backend.Server) {
+	lb.manager.Remove(id, b)
+	lb.amw.setClusterIDs(slices.Collect(maps.Keys(lb.manager.Backends())))
+}
+
 // validate validates the cluster IDs by checking them against DB.
 func (lb *loadBalancer) validate(ctx context.Context) error {
 	// Fetch all cluster IDs set in config file
@@ -147,9 +231,15 @@ func (lb *loadBalancer) validate(ctx context.Context) error {
 		lb.amw.clusterIDs = append(lb.amw.clusterIDs, id)
 	}
 
+	return lb.ValidateClusterIDs(ctx, lb.amw.clusterIDs)
+}
+
+// ValidateClusterIDs returns an error if any of the cluster IDs is unknown to
+// CEEMS DB or API server.
+func (lb *loadBalancer) ValidateClusterIDs(ctx context.Context, ids []string) error {
 	// If neither CEEMD DB or API server is configured, return
 	// This means LB is used without any access control configured
-	if lb.amw.ceems.db == nil && lb.amw.ceems.clustersEndpoint() == nil {
+	if lb.amw.ceems.db == nil && lb.amw.ceems.client == nil {
 		return nil
 	}
 
@@ -184,20 +274,11 @@ func (lb *loadBalancer) validate(ctx context.Context) error {
 		goto validate
 	}
 
-	if lb.amw.ceems.clustersEndpoint() != nil {
-		// Create a new GET request
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, lb.amw.ceems.clustersEndpoint().String(), nil)
-		if err != nil {
-			return err
-		}
-
-		// Add necessary headers
-		// Use service account as user which will be in list of admin users.
-		req.Header.Add(ceems_api_base.GrafanaUserHeader, ceems_api_base.CEEMSServiceAccount)
-
-		// Make request
-		clusters, err = ceemsAPIRequest[models.Cluster](req, lb.amw.ceems.client)
-		if err != nil {
+	if lb.amw.ceems.client != nil {
+		// Make request. Client uses service account as user which will be in
+		// list of admin users.
+		var err error
+		if clusters, err = lb.amw.ceems.client.Clusters(ctx); err != nil {
 			return err
 		}
 	}
@@ -211,7 +292,7 @@ validate:
 	}
 
 	// Check if ID is in actualClusterIDs
-	for _, id := range lb.amw.clusterIDs {
+	for _, id := range ids {
 		if !slices.Contains(actualClusterIDs, id) {
 			return fmt.Errorf(
 				"%w: %s. Cluster IDs in CEEMS DB are %s",
@@ -225,8 +306,34 @@ validate:
 
 // Start server.
 func (lb *loadBalancer) Start(_ context.Context) error {
+	// Metrics endpoint of LB is not proxied to backends
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(
+		lb.registry,
+		promhttp.HandlerOpts{
+			ErrorLog:      slog.NewLogLogger(lb.logger.Handler(), slog.LevelError),
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	))
+
+	// Reload endpoint of LB when lifecycle API is enabled
+	if lb.reload != nil {
+		mux.HandleFunc("/-/reload", lb.reloadHandler)
+	}
+
+	// Remote write endpoint authenticates agents with their own tokens and
+	// hence, it is not behind auth middleware
+	if lb.remoteWriter != nil {
+		if lb.rwServer != nil {
+			go lb.startRemoteWrite()
+		} else {
+			mux.HandleFunc(remoteWritePath, lb.remoteWrite)
+		}
+	}
+
 	// Apply middleware
-	lb.server.Handler = lb.amw.Middleware(http.HandlerFunc(lb.Serve))
+	mux.Handle("/", lb.amw.Middleware(http.HandlerFunc(lb.Serve)))
+	lb.server.Handler = mux
 	lb.logger.Info("Starting "+base.CEEMSLoadBalancerAppName, "listening", lb.server.Addr)
 
 	// Listen for requests
@@ -240,6 +347,37 @@ func (lb *loadBalancer) Start(_ context.Context) error {
 	return nil
 }
 
+// startRemoteWrite starts the dedicated listener of remote write endpoint.
+func (lb *loadBalancer) startRemoteWrite() {
+	mux := http.NewServeMux()
+	mux.HandleFunc(remoteWritePath, lb.remoteWrite)
+	lb.rwServer.Handler = mux
+	lb.logger.Info("Starting remote write gateway", "listening", lb.rwServer.Addr)
+
+	if err := web.ListenAndServe(lb.rwServer, lb.rwWebConfig, lb.logger); err != nil &&
+		!errors.Is(err, http.ErrServerClosed) {
+		lb.logger.Error("Failed to Listen and Serve remote write HTTP server", "err", err)
+	}
+}
+
+// reloadHandler reloads the configuration of LB.
+func (lb *loadBalancer) reloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("Only POST or PUT requests allowed"))
+
+		return
+	}
+
+	if err := lb.reload(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to reload config: %s", err), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
 // Shutdown server.
 func (lb *loadBalancer) Shutdown(ctx context.Context) error {
 	// Close DB connection only if DB file is provided
@@ -258,9 +396,31 @@ func (lb *loadBalancer) Shutdown(ctx context.Context) error {
 		return err
 	}
 
+	// Shutdown the remote write server
+	if lb.rwServer != nil {
+		if err := lb.rwServer.Shutdown(ctx); err != nil {
+			lb.logger.Error("Failed to shutdown remote write HTTP server", "err", err)
+
+			return err
+		}
+	}
+
 	return nil
 }
 
+// target returns the backend server to serve the request. Strategies that support
+// hashing choose the backend server based on UUIDs in the query so that queries of
+// the same compute units are always served by the same backend server.
+func (lb *loadBalancer) target(p *ReqParams) backend.Server {
+	if hasher, ok := lb.manager.(serverpool.Hasher); ok && len(p.uuids) > 0 {
+		uuids := slices.Compact(slices.Sorted(slices.Values(p.uuids)))
+
+		return hasher.TargetByKey(p.clusterID, strings.Join(uuids, ","))
+	}
+
+	return lb.manager.Target(p.clusterID, p.queryPeriod)
+}
+
 // Serve serves the request using a backend TSDB server from the pool.
 func (lb *loadBalancer) Serve(w http.ResponseWriter, r *http.Request) {
 	// Health check
@@ -282,21 +442,34 @@ func (lb *loadBalancer) Serve(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Middleware ensures that query parameters are always set in request's context
-	var queryPeriod time.Duration
+	v, ok := queryParams.(*ReqParams)
+	if !ok {
+		http.Error(w, "Invalid query parameters", http.StatusBadRequest)
 
-	var id string
+		return
+	}
 
-	if v, ok := queryParams.(*ReqParams); ok {
-		queryPeriod = v.queryPeriod
-		id = v.clusterID
-	} else {
-		http.Error(w, "Invalid query parameters", http.StatusBadRequest)
+	// Enforce per user limits on the query
+	release, ok := lb.limit(w, r, v)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Serve range queries on units that have ended from cache
+	if lb.serveCached(w, r, v) {
+		return
+	}
 
+	// Split range queries across backends when a single backend cannot
+	// serve the entire range
+	if lb.federate(w, r, v.clusterID) {
 		return
 	}
 
-	// Choose target based on query Period
-	if target := lb.manager.Target(id, queryPeriod); target != nil {
+	// Choose target based on query Period or UUIDs in the query
+	if target := lb.target(v); target != nil {
+		auditEventFromContext(r.Context()).setBackend(target.String())
 		target.Serve(w, r)
 
 		return
@@ -0,0 +1,518 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	ceems_api_base "github.com/mahendrapaipuri/ceems/pkg/api/base"
+	ceems_api "github.com/mahendrapaipuri/ceems/pkg/api/http"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// Window of cost budget of users.
+const costBudgetWindow = time.Minute
+
+// Default range of Loki queries when start is not set.
+const lokiDefaultQueryRange = time.Hour
+
+// User used to account requests without user header.
+const anonymousUser = "anonymous"
+
+// Reasons of rejection of queries.
+const (
+	reasonMaxQueryRange        = "max_query_range"
+	reasonMinStep              = "min_step"
+	reasonMaxQueryCost         = "max_query_cost"
+	reasonMaxConcurrentQueries = "max_concurrent_queries"
+	reasonCostBudget           = "cost_budget"
+)
+
+// LimitsConfig contains the configuration of per user query limits.
+type LimitsConfig struct {
+	Enabled              bool           `yaml:"enabled"`
+	MaxConcurrentQueries int            `yaml:"max_concurrent_queries"`
+	MaxQueryRange        model.Duration `yaml:"max_query_range"`
+	MinStep              model.Duration `yaml:"min_step"`
+	MaxQueryCost         int64          `yaml:"max_query_cost"`
+	CostBudgetPerMinute  int64          `yaml:"cost_budget_per_minute"`
+	SeriesPreCheck       bool           `yaml:"series_pre_check"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *LimitsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = LimitsConfig{
+		MaxConcurrentQueries: 10,
+	}
+
+	type plain LimitsConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *LimitsConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	// Zero values disable the respective limits
+	if c.MaxConcurrentQueries < 0 || c.MaxQueryCost < 0 || c.CostBudgetPerMinute < 0 {
+		return errors.New("max_concurrent_queries, max_query_cost and cost_budget_per_minute of limits must not be negative")
+	}
+
+	if c.MaxQueryRange < 0 || c.MinStep < 0 {
+		return errors.New("max_query_range and min_step of limits must not be negative")
+	}
+
+	return nil
+}
+
+// queryCost is the estimated cost of a query. Cost is the number of evaluation
+// steps of the query multiplied by the number of series it selects.
+type queryCost struct {
+	rng    time.Duration
+	step   time.Duration
+	points int64
+	series int64
+}
+
+// cost returns the estimated cost of query.
+func (c *queryCost) cost() int64 {
+	return c.points * c.series
+}
+
+// limitError is returned when a query exceeds the limits of user.
+type limitError struct {
+	code       int
+	reason     string
+	retryAfter time.Duration
+	err        error
+}
+
+func (e *limitError) Error() string {
+	return e.err.Error()
+}
+
+// userUsage is the current usage of a user.
+type userUsage struct {
+	inflight    int
+	windowStart time.Time
+	cost        int64
+}
+
+// queryLimiter enforces per user limits on range, resolution and estimated
+// cost of queries, concurrent queries and cost budget per minute.
+type queryLimiter struct {
+	config   LimitsConfig
+	mu       sync.Mutex
+	users    map[string]*userUsage
+	inflight *prometheus.GaugeVec
+	queries  *prometheus.CounterVec
+	costs    *prometheus.CounterVec
+	rejected *prometheus.CounterVec
+}
+
+// newQueryLimiter returns a new instance of queryLimiter.
+func newQueryLimiter(c LimitsConfig) *queryLimiter {
+	return &queryLimiter{
+		config: c,
+		users:  make(map[string]*userUsage),
+		inflight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "user",
+			Name:      "inflight_queries",
+			Help:      "Current number of queries of user being served.",
+		}, []string{"user"}),
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "user",
+			Name:      "queries_total",
+			Help:      "Total number of queries of user accepted by limits.",
+		}, []string{"user"}),
+		costs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "user",
+			Name:      "query_cost_total",
+			Help:      "Total estimated cost of queries of user accepted by limits.",
+		}, []string{"user"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "user",
+			Name:      "rejected_queries_total",
+			Help:      "Total number of queries of user rejected by limits by reason.",
+		}, []string{"user", "reason"}),
+	}
+}
+
+// collectors returns the metrics of limiter.
+func (l *queryLimiter) collectors() []prometheus.Collector {
+	return []prometheus.Collector{l.inflight, l.queries, l.costs, l.rejected}
+}
+
+// check returns an error if the range, resolution or cost of query exceed limits.
+func (l *queryLimiter) check(c *queryCost) *limitError {
+	if l.config.MaxQueryRange > 0 && c.rng > time.Duration(l.config.MaxQueryRange) {
+		return &limitError{
+			code:   http.StatusBadRequest,
+			reason: reasonMaxQueryRange,
+			err: fmt.Errorf(
+				"query range %s exceeds the limit of %s. Reduce the time range of the query",
+				model.Duration(c.rng), l.config.MaxQueryRange,
+			),
+		}
+	}
+
+	if l.config.MinStep > 0 && c.step > 0 && c.step < time.Duration(l.config.MinStep) {
+		return &limitError{
+			code:   http.StatusBadRequest,
+			reason: reasonMinStep,
+			err: fmt.Errorf(
+				"query resolution step %s is smaller than the limit of %s. Increase the step or min interval of the query",
+				model.Duration(c.step), l.config.MinStep,
+			),
+		}
+	}
+
+	if l.config.MaxQueryCost > 0 && c.cost() > l.config.MaxQueryCost {
+		return &limitError{
+			code:   http.StatusBadRequest,
+			reason: reasonMaxQueryCost,
+			err: fmt.Errorf(
+				"estimated query cost %d (%d steps x %d series) exceeds the limit of %d. Reduce the time range, the number of compute units or increase the step of the query",
+				c.cost(), c.points, c.series, l.config.MaxQueryCost,
+			),
+		}
+	}
+
+	return nil
+}
+
+// acquire accounts the query of user and returns a function that must be
+// called once the query has been served. It returns an error if the query
+// exceeds the limits of user.
+func (l *queryLimiter) acquire(user string, c *queryCost) (func(), *limitError) {
+	if err := l.check(c); err != nil {
+		l.rejected.WithLabelValues(user, err.reason).Inc()
+
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	usage, ok := l.users[user]
+	if !ok {
+		usage = &userUsage{}
+		l.users[user] = usage
+	}
+
+	// Start a new window when current one has elapsed
+	now := time.Now()
+	if now.Sub(usage.windowStart) >= costBudgetWindow {
+		usage.windowStart = now
+		usage.cost = 0
+	}
+
+	if l.config.MaxConcurrentQueries > 0 && usage.inflight >= l.config.MaxConcurrentQueries {
+		l.rejected.WithLabelValues(user, reasonMaxConcurrentQueries).Inc()
+
+		return nil, &limitError{
+			code:       http.StatusTooManyRequests,
+			reason:     reasonMaxConcurrentQueries,
+			retryAfter: time.Second,
+			err: fmt.Errorf(
+				"number of concurrent queries exceeds the limit of %d. Retry after current queries have finished",
+				l.config.MaxConcurrentQueries,
+			),
+		}
+	}
+
+	// A single query is always allowed in a new window even if its cost is
+	// bigger than budget. Else such queries can never be served
+	if l.config.CostBudgetPerMinute > 0 && usage.cost > 0 && usage.cost+c.cost() > l.config.CostBudgetPerMinute {
+		l.rejected.WithLabelValues(user, reasonCostBudget).Inc()
+
+		retryAfter := costBudgetWindow - now.Sub(usage.windowStart)
+
+		return nil, &limitError{
+			code:       http.StatusTooManyRequests,
+			reason:     reasonCostBudget,
+			retryAfter: retryAfter,
+			err: fmt.Errorf(
+				"estimated query cost %d exceeds the remaining budget of %d of the limit of %d per minute. Retry after %s",
+				c.cost(), max(l.config.CostBudgetPerMinute-usage.cost, 0), l.config.CostBudgetPerMinute, retryAfter.Round(time.Second),
+			),
+		}
+	}
+
+	usage.inflight++
+	usage.cost += c.cost()
+
+	l.inflight.WithLabelValues(user).Inc()
+	l.queries.WithLabelValues(user).Inc()
+	l.costs.WithLabelValues(user).Add(float64(c.cost()))
+
+	return func() { l.release(user) }, nil
+}
+
+// release releases the query of user.
+func (l *queryLimiter) release(user string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight.WithLabelValues(user).Dec()
+
+	usage, ok := l.users[user]
+	if !ok {
+		return
+	}
+
+	usage.inflight--
+
+	// Remove idle users so that usage of users does not grow unbounded
+	if usage.inflight <= 0 && time.Since(usage.windowStart) >= costBudgetWindow {
+		delete(l.users, user)
+	}
+}
+
+// limit enforces the limits of user on the query in the request. It writes an
+// error response and returns false when the query exceeds the limits. Returned
+// function must be called once the query has been served.
+func (lb *loadBalancer) limit(w http.ResponseWriter, r *http.Request, p *ReqParams) (func(), bool) {
+	// Retried requests are served while the original request still holds the
+	// slot and it has already been charged
+	if lb.limiter == nil || !allowRetry(r) {
+		return func() {}, true
+	}
+
+	user := r.Header.Get(ceems_api_base.GrafanaUserHeader)
+	if user == "" {
+		user = anonymousUser
+	}
+
+	c := lb.estimateCost(r, p)
+
+	release, err := lb.limiter.acquire(user, c)
+	if err == nil {
+		return release, true
+	}
+
+	auditEventFromContext(r.Context()).deny("query limits: " + err.reason)
+
+	lb.logger.Error("Query rejected by limits", "user", user, "cluster_id", p.clusterID,
+		"reason", err.reason, "cost", c.cost(), "err", err)
+
+	response := ceems_api.Response[any]{
+		Status:    "error",
+		ErrorType: "bad_data",
+		Error:     err.Error(),
+	}
+
+	// Queries exceeding rate limits can be retried later
+	if err.code == http.StatusTooManyRequests {
+		response.ErrorType = "too_many_requests"
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(err.retryAfter.Seconds()))))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.code)
+
+	if err := json.NewEncoder(w).Encode(&response); err != nil {
+		lb.logger.Error("Failed to encode response", "err", err)
+	}
+
+	return nil, false
+}
+
+// estimateCost returns the estimated cost of query in the request. Number of
+// series is the number of compute units in the query unless series pre-check
+// is enabled in which case the number of series matched by the selectors of
+// query is used.
+func (lb *loadBalancer) estimateCost(r *http.Request, p *ReqParams) *queryCost {
+	c := &queryCost{
+		points: 1,
+		series: int64(max(len(slices.Compact(slices.Sorted(slices.Values(p.uuids)))), 1)),
+	}
+
+	// Only queries of TSDB and Loki have a range and resolution
+	if lb.lbType != base.PromLB && lb.lbType != base.LokiLB {
+		return c
+	}
+
+	isRange := strings.HasSuffix(r.URL.Path, "query_range")
+	if !isRange && !strings.HasSuffix(r.URL.Path, "query") {
+		return c
+	}
+
+	// If parameters are invalid, backend server will respond with an
+	// appropriate error
+	form, err := readForm(r)
+	if err != nil {
+		return c
+	}
+
+	var start, end time.Time
+
+	if isRange {
+		if start, end, c.step, err = queryRange(lb.lbType, form); err != nil {
+			return c
+		}
+
+		c.rng = end.Sub(start)
+		c.points = int64(c.rng/c.step) + 1
+	}
+
+	if !lb.limiter.config.SeriesPreCheck || lb.lbType != base.PromLB {
+		return c
+	}
+
+	// Instant queries are evaluated at a single timestamp
+	if !isRange {
+		end = time.Now()
+
+		if val := form.Get("time"); val != "" {
+			if end, err = parseTime(val); err != nil {
+				return c
+			}
+		}
+
+		start = end
+	}
+
+	if series, err := lb.countSeries(r, p, form.Get("query"), start, end); err != nil {
+		lb.logger.Debug("Failed to count series of query", "cluster_id", p.clusterID, "err", err)
+	} else {
+		c.series = max(series, 1)
+	}
+
+	return c
+}
+
+// queryRange returns start, end and step of range query of TSDB or Loki.
+func queryRange(lbType base.LBType, form url.Values) (time.Time, time.Time, time.Duration, error) {
+	if lbType == base.PromLB {
+		q, err := parseRangeForm(form)
+		if err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+
+		return q.start, q.end, q.step, nil
+	}
+
+	// Loki uses defaults for all the parameters of range query
+	end := time.Now()
+
+	var err error
+
+	if val := form.Get("end"); val != "" {
+		if end, err = parseLokiTime(val); err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+	}
+
+	start := end.Add(-lokiDefaultQueryRange)
+
+	if val := form.Get("start"); val != "" {
+		if start, err = parseLokiTime(val); err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+	}
+
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, 0, errors.New("end timestamp must not be before start time")
+	}
+
+	// Default step of Loki is range/250 rounded down to seconds with a minimum
+	// of a second
+	step := max(end.Sub(start)/250/time.Second*time.Second, time.Second)
+
+	if val := form.Get("step"); val != "" {
+		if step, err = parseDuration(val); err != nil {
+			return time.Time{}, time.Time{}, 0, err
+		}
+	}
+
+	if step <= 0 {
+		return time.Time{}, time.Time{}, 0, errors.New("zero or negative query resolution step widths are not accepted")
+	}
+
+	return start, end, step, nil
+}
+
+// countSeries returns the number of series matched by the selectors of query
+// between start and end using series API of TSDB.
+func (lb *loadBalancer) countSeries(r *http.Request, p *ReqParams, query string, start, end time.Time) (int64, error) {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return 0, err
+	}
+
+	values := url.Values{}
+	for _, matchers := range parser.ExtractSelectors(expr) {
+		values.Add("match[]", (&parser.VectorSelector{LabelMatchers: matchers}).String())
+	}
+
+	// Query without any selectors like `vector(1)`
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	values.Set("start", strconv.FormatFloat(float64(start.UnixMilli())/1000, 'f', -1, 64))
+	values.Set("end", strconv.FormatFloat(float64(end.UnixMilli())/1000, 'f', -1, 64))
+
+	target := lb.target(p)
+	if target == nil {
+		return 0, errors.New("no backend server available")
+	}
+
+	body := values.Encode()
+
+	// Do not retry pre-check on other backends
+	req := r.Clone(context.WithValue(r.Context(), RetryContextKey{}, true))
+	req.Method = http.MethodPost
+	req.URL.Path = path.Join(path.Dir(r.URL.Path), "series")
+	req.URL.RawQuery = ""
+	req.Body = io.NopCloser(strings.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Del("Content-Length")
+
+	// Response is decoded here. Let the transport negotiate and decompress the
+	// response instead of passing client's encodings
+	req.Header.Del("Accept-Encoding")
+
+	rw := newBufferedResponseWriter()
+	target.Serve(rw, req)
+
+	if rw.code != http.StatusOK {
+		return 0, fmt.Errorf("series request returned status %d", rw.code)
+	}
+
+	var resp tsdb.Response[[]model.LabelSet]
+	if err := json.Unmarshal(rw.body.Bytes(), &resp); err != nil {
+		return 0, err
+	}
+
+	return int64(len(resp.Data)), nil
+}
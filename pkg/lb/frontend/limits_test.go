@@ -0,0 +1,366 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/mahendrapaipuri/ceems/pkg/tsdb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func limitedLB(t *testing.T, lbType base.LBType, c LimitsConfig, handler http.HandlerFunc) *loadBalancer {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	b, err := backend.New(lbType, &backend.ServerConfig{Web: &models.WebConfig{URL: server.URL}}, logger)
+	require.NoError(t, err)
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	manager.Add("default", b)
+
+	frontend, err := New(&Config{
+		Logger:  logger,
+		LBType:  lbType,
+		Manager: manager,
+		Address: "localhost:9030",
+		Limits:  c,
+	})
+	require.NoError(t, err)
+
+	return frontend.(*loadBalancer) //nolint:forcetypeassert
+}
+
+func TestQueryLimiter(t *testing.T) {
+	limiter := newQueryLimiter(LimitsConfig{
+		Enabled:              true,
+		MaxConcurrentQueries: 2,
+		MaxQueryRange:        model.Duration(24 * time.Hour),
+		MinStep:              model.Duration(time.Minute),
+		MaxQueryCost:         1000,
+		CostBudgetPerMinute:  1500,
+	})
+
+	// Range, resolution and cost limits
+	_, err := limiter.acquire("usr1", &queryCost{rng: 48 * time.Hour, step: time.Hour, points: 49, series: 1})
+	require.Error(t, err)
+	assert.Equal(t, reasonMaxQueryRange, err.reason)
+	assert.Equal(t, http.StatusBadRequest, err.code)
+
+	_, err = limiter.acquire("usr1", &queryCost{rng: time.Hour, step: time.Second, points: 3601, series: 1})
+	require.Error(t, err)
+	assert.Equal(t, reasonMinStep, err.reason)
+
+	_, err = limiter.acquire("usr1", &queryCost{rng: time.Hour, step: time.Minute, points: 61, series: 20})
+	require.Error(t, err)
+	assert.Equal(t, reasonMaxQueryCost, err.reason)
+
+	// Concurrency limit
+	release1, err := limiter.acquire("usr1", &queryCost{points: 1, series: 1})
+	require.Nil(t, err)
+
+	release2, err := limiter.acquire("usr1", &queryCost{points: 1, series: 1})
+	require.Nil(t, err)
+
+	_, err = limiter.acquire("usr1", &queryCost{points: 1, series: 1})
+	require.Error(t, err)
+	assert.Equal(t, reasonMaxConcurrentQueries, err.reason)
+	assert.Equal(t, http.StatusTooManyRequests, err.code)
+
+	// Other users are not affected
+	release3, err := limiter.acquire("usr2", &queryCost{points: 1, series: 1})
+	require.Nil(t, err)
+	release3()
+
+	release1()
+	release2()
+
+	// Cost budget
+	release, err := limiter.acquire("usr1", &queryCost{points: 100, series: 9})
+	require.Nil(t, err)
+	release()
+
+	_, err = limiter.acquire("usr1", &queryCost{points: 100, series: 9})
+	require.Error(t, err)
+	assert.Equal(t, reasonCostBudget, err.reason)
+	assert.Positive(t, err.retryAfter)
+
+	// Budget starts over in a new window
+	limiter.users["usr1"].windowStart = time.Now().Add(-costBudgetWindow)
+
+	release, err = limiter.acquire("usr1", &queryCost{points: 100, series: 9})
+	require.Nil(t, err)
+	release()
+
+	// Metrics
+	assert.InDelta(t, 4, testutil.ToFloat64(limiter.queries.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 1802, testutil.ToFloat64(limiter.costs.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(limiter.inflight.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(limiter.rejected.WithLabelValues("usr1", reasonCostBudget)), 0)
+}
+
+func TestEstimateCost(t *testing.T) {
+	end := time.Now().Truncate(time.Minute)
+
+	tests := []struct {
+		name      string
+		lbType    base.LBType
+		path      string
+		values    url.Values
+		uuids     []string
+		preCheck  bool
+		rng       time.Duration
+		step      time.Duration
+		points    int64
+		series    int64
+		seriesReq bool
+	}{
+		{
+			name:   "tsdb range query",
+			lbType: base.PromLB,
+			path:   "/api/v1/query_range",
+			values: url.Values{
+				"query": []string{`foo{uuid=~"1|2"}`},
+				"start": []string{strconv.FormatInt(end.Add(-time.Hour).Unix(), 10)},
+				"end":   []string{strconv.FormatInt(end.Unix(), 10)},
+				"step":  []string{"1m"},
+			},
+			uuids:  []string{"1", "2", "2"},
+			rng:    time.Hour,
+			step:   time.Minute,
+			points: 61,
+			series: 2,
+		},
+		{
+			name:   "tsdb instant query",
+			lbType: base.PromLB,
+			path:   "/api/v1/query",
+			values: url.Values{"query": []string{`foo{uuid="1"}`}},
+			uuids:  []string{"1"},
+			points: 1,
+			series: 1,
+		},
+		{
+			name:   "tsdb labels",
+			lbType: base.PromLB,
+			path:   "/api/v1/labels",
+			points: 1,
+			series: 1,
+		},
+		{
+			name:   "tsdb range query with series pre-check",
+			lbType: base.PromLB,
+			path:   "/api/v1/query_range",
+			values: url.Values{
+				"query": []string{`sum(foo{uuid="1"}) / sum(bar{uuid="1"})`},
+				"start": []string{strconv.FormatInt(end.Add(-time.Hour).Unix(), 10)},
+				"end":   []string{strconv.FormatInt(end.Unix(), 10)},
+				"step":  []string{"60"},
+			},
+			uuids:     []string{"1"},
+			preCheck:  true,
+			rng:       time.Hour,
+			step:      time.Minute,
+			points:    61,
+			series:    6,
+			seriesReq: true,
+		},
+		{
+			name:   "loki range query with default step",
+			lbType: base.LokiLB,
+			path:   "/loki/api/v1/query_range",
+			values: url.Values{
+				"query": []string{`{uuid="1"} |= "error"`},
+				"start": []string{strconv.FormatInt(end.Add(-25*time.Minute).UnixNano(), 10)},
+				"end":   []string{strconv.FormatInt(end.UnixNano(), 10)},
+			},
+			uuids:  []string{"1"},
+			rng:    25 * time.Minute,
+			step:   6 * time.Second,
+			points: 251,
+			series: 1,
+		},
+	}
+
+	for _, test := range tests {
+		var seriesReq bool
+
+		lb := limitedLB(t, test.lbType, LimitsConfig{Enabled: true, SeriesPreCheck: test.preCheck}, func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasSuffix(r.URL.Path, "series") {
+				return
+			}
+
+			seriesReq = true
+
+			require.NoError(t, r.ParseForm())
+			assert.Len(t, r.Form["match[]"], 2)
+
+			// Return three series for each selector compressed like Prometheus does
+			series := make([]model.LabelSet, 3*len(r.Form["match[]"]))
+
+			var out io.Writer = w
+
+			if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+				w.Header().Set("Content-Encoding", "gzip")
+
+				gz := gzip.NewWriter(w)
+				defer gz.Close()
+
+				out = gz
+			}
+
+			json.NewEncoder(out).Encode(tsdb.Response[[]model.LabelSet]{Status: "success", Data: series})
+		})
+
+		request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("Accept-Encoding", "gzip")
+
+		c := lb.estimateCost(request, &ReqParams{clusterID: "default", uuids: test.uuids})
+		assert.Equal(t, test.rng, c.rng, test.name)
+		assert.Equal(t, test.step, c.step, test.name)
+		assert.Equal(t, test.points, c.points, test.name)
+		assert.Equal(t, test.series, c.series, test.name)
+		assert.Equal(t, test.seriesReq, seriesReq, test.name)
+
+		// Request body must be intact
+		require.NoError(t, request.ParseForm())
+		assert.Equal(t, test.values.Get("query"), request.Form.Get("query"), test.name)
+	}
+}
+
+func TestLimitedQuery(t *testing.T) {
+	lb := limitedLB(t, base.PromLB, LimitsConfig{Enabled: true, MaxConcurrentQueries: 1, CostBudgetPerMinute: 100}, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	// Limits metrics must be registered
+	_, err := testutil.GatherAndCount(lb.registry, "ceems_lb_user_queries_total")
+	require.NoError(t, err)
+
+	end := time.Now()
+
+	for i, code := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		values := url.Values{}
+		values.Set("query", `foo{uuid="1"}`)
+		values.Set("start", strconv.FormatInt(end.Add(-time.Hour).Unix(), 10))
+		values.Set("end", strconv.FormatInt(end.Unix(), 10))
+		values.Set("step", "1m")
+
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/query_range?"+values.Encode(), nil)
+		request.Header.Set("X-Grafana-User", "usr1")
+		request = request.WithContext(
+			context.WithValue(
+				request.Context(), ReqParamsContextKey{},
+				&ReqParams{queryPeriod: time.Hour, clusterID: "default", uuids: []string{"1"}},
+			),
+		)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(lb.Serve).ServeHTTP(responseRecorder, request)
+
+		require.Equal(t, code, responseRecorder.Code, i)
+
+		if code == http.StatusTooManyRequests {
+			assert.NotEmpty(t, responseRecorder.Header().Get("Retry-After"))
+			assert.Contains(t, responseRecorder.Body.String(), "too_many_requests")
+		}
+	}
+
+	// Inflight queries must be released
+	assert.InDelta(t, 0, testutil.ToFloat64(lb.limiter.inflight.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 61, testutil.ToFloat64(lb.limiter.costs.WithLabelValues("usr1")), 0)
+}
+
+func TestLimitedQueryRetry(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	// First backend drops connections so that queries are retried on second one
+	var dropped int
+
+	for _, handler := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/v1/query") {
+				return
+			}
+
+			dropped++
+
+			conn, _, err := http.NewResponseController(w).Hijack()
+			require.NoError(t, err)
+			conn.Close()
+		},
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		},
+	} {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		b, err := backend.New(base.PromLB, &backend.ServerConfig{Web: &models.WebConfig{URL: server.URL}}, logger)
+		require.NoError(t, err)
+
+		manager.Add("default", b)
+	}
+
+	frontend, err := New(&Config{
+		Logger:  logger,
+		LBType:  base.PromLB,
+		Manager: manager,
+		Address: "localhost:9030",
+		Limits:  LimitsConfig{Enabled: true, MaxConcurrentQueries: 1},
+	})
+	require.NoError(t, err)
+
+	lb := frontend.(*loadBalancer) //nolint:forcetypeassert
+
+	for i := range 2 {
+		request := httptest.NewRequest(http.MethodGet, `/api/v1/query?query=foo{uuid="1"}`, nil)
+		request.Header.Set("X-Grafana-User", "usr1")
+		request = request.WithContext(
+			context.WithValue(
+				request.Context(), ReqParamsContextKey{},
+				&ReqParams{clusterID: "default", uuids: []string{"1"}},
+			),
+		)
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(lb.Serve).ServeHTTP(responseRecorder, request)
+
+		require.Equal(t, http.StatusOK, responseRecorder.Code, i)
+	}
+
+	// Retried queries must neither be rejected nor charged twice
+	assert.Positive(t, dropped)
+	assert.InDelta(t, 2, testutil.ToFloat64(lb.limiter.queries.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 2, testutil.ToFloat64(lb.limiter.costs.WithLabelValues("usr1")), 0)
+	assert.InDelta(t, 0, testutil.ToFloat64(lb.limiter.inflight.WithLabelValues("usr1")), 0)
+}
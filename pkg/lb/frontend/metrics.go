@@ -0,0 +1,85 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backendCollector exports the health of backend servers.
+type backendCollector struct {
+	manager     serverpool.Manager
+	up          *prometheus.Desc
+	state       *prometheus.Desc
+	connections *prometheus.Desc
+	ejections   *prometheus.Desc
+}
+
+// newBackendCollector returns a new instance of backendCollector.
+func newBackendCollector(manager serverpool.Manager) *backendCollector {
+	labels := []string{"cluster_id", "backend"}
+
+	return &backendCollector{
+		manager: manager,
+		up: prometheus.NewDesc(
+			"ceems_lb_backend_up",
+			"Whether the backend server is receiving requests.",
+			labels, nil,
+		),
+		state: prometheus.NewDesc(
+			"ceems_lb_backend_health_state",
+			"Current health state of backend server.",
+			append(labels, "state"), nil,
+		),
+		connections: prometheus.NewDesc(
+			"ceems_lb_backend_active_connections",
+			"Current number of active connections to backend server.",
+			labels, nil,
+		),
+		ejections: prometheus.NewDesc(
+			"ceems_lb_backend_ejections_total",
+			"Total number of ejections of backend server by outlier detection.",
+			labels, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *backendCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	ch <- c.state
+	ch <- c.connections
+	ch <- c.ejections
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *backendCollector) Collect(ch chan<- prometheus.Metric) {
+	for id, backends := range c.manager.Backends() {
+		for _, b := range backends {
+			name := b.URL().Redacted()
+
+			var up float64
+			if b.IsAlive() {
+				up = 1
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up, id, name)
+
+			current := b.HealthState()
+			for _, state := range backend.HealthStates {
+				var value float64
+				if state == current {
+					value = 1
+				}
+
+				ch <- prometheus.MustNewConstMetric(c.state, prometheus.GaugeValue, value, id, name, state.String())
+			}
+
+			ch <- prometheus.MustNewConstMetric(c.connections, prometheus.GaugeValue, float64(b.ActiveConnections()), id, name)
+			ch <- prometheus.MustNewConstMetric(c.ejections, prometheus.CounterValue, float64(b.Ejections()), id, name)
+		}
+	}
+}
@@ -11,18 +11,17 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 	"slices"
-	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	ceems_api_base "github.com/mahendrapaipuri/ceems/pkg/api/base"
+	ceems_api_client "github.com/mahendrapaipuri/ceems/pkg/api/client"
 	ceems_api "github.com/mahendrapaipuri/ceems/pkg/api/http"
-	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
 	"github.com/prometheus/common/config"
 )
@@ -43,6 +42,13 @@ import (
 // - LabelNames
 // - LabelValues
 //
+// For Loki following resources are allowed
+// - query
+// - query_range
+// - labels
+// - values
+// - series
+//
 // Not sure if we need to allow more resources for Pyroscope
 // So of the resources that need to checked can be found here:
 // https://github.com/grafana/pyroscope/blob/b4125b77f44444eb413244d5e56d73a04b1c1def/tools/k6/tests/reads.js#L37-L57
@@ -60,6 +66,13 @@ var (
 		"LabelNames",
 		"LabelValues",
 	}
+	allowedLokiResources = []string{
+		"query",
+		"query_range",
+		"labels",
+		"series",
+		"values",
+	}
 )
 
 var (
@@ -67,46 +80,18 @@ var (
 	regexpURLPaths             = "/([/]*(%s)?/?)(?:$)"
 	regexpAllowedTSDBResources = regexp.MustCompile(fmt.Sprintf(regexpURLPaths, strings.Join(allowedTSDBResources, "|")))
 	regexpAllowedPyroResources = regexp.MustCompile(fmt.Sprintf(regexpURLPaths, strings.Join(allowedPyroResources, "|")))
+	regexpAllowedLokiResources = regexp.MustCompile(fmt.Sprintf(regexpURLPaths, strings.Join(allowedLokiResources, "|")))
 
-	// Regex that will match unit's UUIDs
-	// Dont use greedy matching to avoid capturing gpuuuid label
-	// Use strict UUID allowable character set. They can be only letters, digits and hypen (-)
-	// Playground: https://goplay.tools/snippet/kq_r_1SOgnG
-	regexpUUID = regexp.MustCompile("(?:.*?)[^gpu](?:uuid|service_name)=[~]{0,1}\"(?P<uuid>[a-zA-Z0-9-|]+)\"(?:.*)")
-
-	// Regex that will match cluster's ID.
-	regexID = regexp.MustCompile("(?:.+?)ceems_id=[~]{0,1}\"(?P<id>[a-zA-Z0-9-|_]+)\"(?:.*)")
+	// Regexes that match invalid characters in values of unit's UUIDs and cluster's
+	// IDs. UUIDs can be only letters, digits and hypen (-)
+	regexpInvalidUUID = regexp.MustCompile("[^a-zA-Z0-9-]")
+	regexpInvalidID   = regexp.MustCompile("[^a-zA-Z0-9-_]")
 )
 
 // ceems is the struct container for CEEMS API server.
 type ceems struct {
 	db     *sql.DB
-	webURL *url.URL
-	client *http.Client
-}
-
-func (c *ceems) verifyEndpoint() *url.URL {
-	if c.webURL != nil {
-		return c.webURL.JoinPath("/api/v1/units/verify")
-	}
-
-	return nil
-}
-
-func (c *ceems) clustersEndpoint() *url.URL {
-	if c.webURL != nil {
-		return c.webURL.JoinPath("/api/v1/clusters/admin")
-	}
-
-	return nil
-}
-
-func (c *ceems) usersEndpoint() *url.URL {
-	if c.webURL != nil {
-		return c.webURL.JoinPath("/api/v1/users/admin")
-	}
-
-	return nil
+	client *ceems_api_client.Client
 }
 
 // adminUsers returns the list of admin users either pulling
@@ -126,39 +111,78 @@ func (c *ceems) adminUsers(ctx context.Context) ([]string, error) {
 		if err != nil {
 			return nil, err
 		}
-	} else if c.webURL != nil {
-		// If CEEMS URL is available make a API request
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.usersEndpoint().String(), nil)
+	} else if c.client != nil {
+		// If CEEMS URL is available make a API request. Client uses CEEMS
+		// service account as user
+		admins, err := c.client.UsersAdmin(ctx, ceems_api_client.NewUsersQuery().Role("admin"))
 		if err != nil {
 			return nil, err
 		}
 
-		// Add role query parameter to request
-		urlVals := url.Values{"role": []string{"admin"}}
-		req.URL.RawQuery = urlVals.Encode()
+		for _, user := range admins.Data {
+			adminUsers = append(adminUsers, user.Name)
+		}
+	}
 
-		// Add necessary headers. Use CEEMS service account as user
-		req.Header.Add(ceems_api_base.GrafanaUserHeader, ceems_api_base.CEEMSServiceAccount)
+	return adminUsers, nil
+}
+
+// unitsEnded returns true if all the units in the cluster have ended before
+// the given time.
+func (c *ceems) unitsEnded(ctx context.Context, clusterID string, uuids []string, before time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+
+	var numEnded int
+
+	// Check if DB is available
+	if c.db != nil {
+		//nolint:gosec
+		query := fmt.Sprintf(
+			"SELECT COUNT(DISTINCT uuid) FROM %s WHERE cluster_id = ? AND ended_at_ts > 0 AND ended_at_ts < ? AND uuid IN (%s)",
+			ceems_api_base.UnitsDBTableName, strings.TrimSuffix(strings.Repeat("?,", len(uuids)), ","),
+		)
 
-		// Make request
-		admins, err := ceemsAPIRequest[models.User](req, c.client)
+		args := []any{clusterID, before.UnixMilli()}
+		for _, uuid := range uuids {
+			args = append(args, uuid)
+		}
+
+		if err := c.db.QueryRowContext(ctx, query, args...).Scan(&numEnded); err != nil {
+			return false, err
+		}
+	} else if c.client != nil {
+		// If CEEMS URL is available make a API request. Client uses CEEMS
+		// service account as user
+		units, err := c.client.UnitsAdmin(
+			ctx, ceems_api_client.NewUnitsQuery().ClusterIDs(clusterID).UUIDs(uuids...).Fields("uuid", "ended_at_ts"),
+		)
 		if err != nil {
-			return nil, err
+			return false, err
 		}
 
-		for _, user := range admins {
-			adminUsers = append(adminUsers, user.Name)
+		var ended []string
+
+		for _, unit := range units.Data {
+			if unit.EndedAtTS > 0 && unit.EndedAtTS < before.UnixMilli() && !slices.Contains(ended, unit.UUID) {
+				ended = append(ended, unit.UUID)
+			}
 		}
+
+		numEnded = len(ended)
 	}
 
-	return adminUsers, nil
+	return numEnded == len(uuids), nil
 }
 
 // authenticationMiddleware implements the auth middleware for LB.
 type authenticationMiddleware struct {
 	logger        *slog.Logger
 	ceems         *ceems
+	mu            sync.RWMutex
 	clusterIDs    []string
+	lbType        base.LBType
+	audit         *AuditLogger
 	pathsACLRegex *regexp.Regexp
 	parseRequest  func(*ReqParams, *http.Request) error
 }
@@ -167,11 +191,7 @@ type authenticationMiddleware struct {
 func newAuthMiddleware(c *Config) (*authenticationMiddleware, error) {
 	var db *sql.DB
 
-	var ceemsClient *http.Client
-
-	var ceemsWebURL *url.URL
-
-	var err error
+	var ceemsClient *ceems_api_client.Client
 
 	// Check if DB path exists and get pointer to DB connection
 	if c.APIServer.Data.Path != "" {
@@ -195,14 +215,18 @@ func newAuthMiddleware(c *Config) (*authenticationMiddleware, error) {
 
 	// Check if URL for CEEMS API exists
 	if c.APIServer.Web.URL != "" {
-		// Unwrap original error to avoid leaking sensitive passwords in output
-		ceemsWebURL, err = url.Parse(c.APIServer.Web.URL)
+		// Make a CEEMS API server client from client config
+		httpClient, err := config.NewClientFromConfig(c.APIServer.Web.HTTPClientConfig, "ceems_api_server")
 		if err != nil {
-			return nil, errors.Unwrap(err)
+			return nil, err
 		}
 
-		// Make a CEEMS API server client from client config
-		if ceemsClient, err = config.NewClientFromConfig(c.APIServer.Web.HTTPClientConfig, "ceems_api_server"); err != nil {
+		// Use CEEMS service account as user which will be in list of admin users
+		if ceemsClient, err = ceems_api_client.New(ceems_api_client.Config{
+			Address:    c.APIServer.Web.URL,
+			User:       ceems_api_base.CEEMSServiceAccount,
+			HTTPClient: httpClient,
+		}); err != nil {
 			return nil, err
 		}
 	}
@@ -210,9 +234,10 @@ func newAuthMiddleware(c *Config) (*authenticationMiddleware, error) {
 	// Setup middleware
 	amw := &authenticationMiddleware{
 		logger: c.Logger,
+		lbType: c.LBType,
+		audit:  c.AuditLogger,
 		ceems: &ceems{
 			db:     db,
-			webURL: ceemsWebURL,
 			client: ceemsClient,
 		},
 	}
@@ -225,11 +250,30 @@ func newAuthMiddleware(c *Config) (*authenticationMiddleware, error) {
 	case base.PyroLB:
 		amw.parseRequest = parsePyroRequest
 		amw.pathsACLRegex = regexpAllowedPyroResources
+	case base.LokiLB:
+		amw.parseRequest = parseLokiRequest
+		amw.pathsACLRegex = regexpAllowedLokiResources
 	}
 
 	return amw, nil
 }
 
+// isClusterID returns true if id is a valid cluster ID.
+func (amw *authenticationMiddleware) isClusterID(id string) bool {
+	amw.mu.RLock()
+	defer amw.mu.RUnlock()
+
+	return slices.Contains(amw.clusterIDs, id)
+}
+
+// setClusterIDs sets valid cluster IDs.
+func (amw *authenticationMiddleware) setClusterIDs(ids []string) {
+	amw.mu.Lock()
+	defer amw.mu.Unlock()
+
+	amw.clusterIDs = ids
+}
+
 // isAdminUser returns true if user is in admin users list.
 func (amw *authenticationMiddleware) isAdminUser(ctx context.Context, user string) bool {
 	// Get current admin users
@@ -261,37 +305,14 @@ func (amw *authenticationMiddleware) isUserUnit(
 	// Any errors in making HTTP request will fail the query. This can happen due
 	// to deployment issues and by failing queries we make operators to look into
 	// what is happening
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, amw.ceems.verifyEndpoint().String(), nil)
-	if err != nil {
-		amw.logger.Error("Failed to create new request for unit ownership verification",
-			"user", user, "queried_uuids", strings.Join(uuids, ","), "err", err)
-
-		return false
-	}
-
-	// Add uuids to request
-	urlVals := url.Values{"uuid": uuids, "cluster_id": clusterIDs}
-	for _, s := range starts {
-		urlVals.Add("time", strconv.FormatInt(s, 10))
-	}
-
-	req.URL.RawQuery = urlVals.Encode()
-
-	// Add necessary headers
-	req.Header.Add(ceems_api_base.GrafanaUserHeader, user)
-
-	// Make request
+	//
 	// If request failed, forbid the query. It can happen when CEEMS API server
 	// goes offline and we should wait for it to come back online
-	if resp, err := amw.ceems.client.Do(req); err != nil {
-		amw.logger.Error("Failed to make request for unit ownership verification",
-			"user", user, "queried_uuids", strings.Join(uuids, ","), "err", err)
-
-		return false
-	} else if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
+	if err := amw.ceems.client.WithUser(user).VerifyUnits(
+		ctx, ceems_api_client.NewVerifyQuery().UUIDs(uuids...).ClusterIDs(clusterIDs...).Times(starts...),
+	); err != nil {
 		amw.logger.Error("Unauthorised query", "user", user,
-			"queried_uuids", strings.Join(uuids, ","), "status_code", resp.StatusCode)
+			"queried_uuids", strings.Join(uuids, ","), "err", err)
 
 		return false
 	}
@@ -310,14 +331,29 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 
 		var err error
 
+		// Record the decision and outcome of request in audit log
+		var event *auditEvent
+
+		if amw.audit != nil {
+			event = amw.audit.newEvent(r)
+			aw := &auditResponseWriter{ResponseWriter: w}
+			w = aw
+			r = r.WithContext(context.WithValue(r.Context(), auditEventContextKey{}, event))
+
+			defer func() {
+				amw.logAudit(event, aw, reqParams, loggedUser, isAdmin)
+			}()
+		}
+
 		// Get cluster id from X-Ceems-Cluster-Id header
 		// This is most important and request parameter that we need
 		// to proxy request. Rest of them are optional
 		reqParams.clusterID = r.Header.Get(ceems_api_base.ClusterIDHeader)
 
 		// Verify clusterID is in list of valid cluster IDs
-		if !slices.Contains(amw.clusterIDs, reqParams.clusterID) {
+		if !amw.isClusterID(reqParams.clusterID) {
 			amw.logger.Error("ClusterID header not found. Bad request", "url", r.URL)
+			event.deny("invalid cluster ID")
 
 			// Write an error and stop the handler chain
 			w.WriteHeader(http.StatusBadRequest)
@@ -337,7 +373,7 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 
 		// If ceems url or db is not configured, pass through. There is nothing
 		// to check here
-		if amw.ceems.webURL == nil && amw.ceems.db == nil {
+		if amw.ceems.client == nil && amw.ceems.db == nil {
 			goto end
 		}
 
@@ -350,6 +386,7 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		loggedUser = r.Header.Get(ceems_api_base.GrafanaUserHeader)
 		if loggedUser == "" {
 			amw.logger.Error("Grafana user Header not found. Denying authentication", "url", r.URL)
+			event.deny("missing user header")
 
 			// Write an error and stop the handler chain
 			w.WriteHeader(http.StatusUnauthorized)
@@ -379,6 +416,7 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		// Skip this check for admin users
 		if !amw.pathsACLRegex.MatchString(r.URL.Path) && !isAdmin {
 			amw.logger.Error("Forbidden resource", "logged_user", loggedUser, "resource", r.URL.Path)
+			event.deny("forbidden resource")
 
 			// Write an error and stop the handler chain
 			w.WriteHeader(http.StatusForbidden)
@@ -408,6 +446,26 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 			goto end
 		}
 
+		// Queries that can select compute units of other users are forbidden
+		if errors.Is(err, errInvalidQuery) {
+			event.deny("invalid query")
+
+			// Write an error and stop the handler chain
+			w.WriteHeader(http.StatusBadRequest)
+
+			response := ceems_api.Response[any]{
+				Status:    "error",
+				ErrorType: "bad_data",
+				Error:     err.Error(),
+			}
+			if err := json.NewEncoder(w).Encode(&response); err != nil {
+				amw.logger.Error("Failed to encode response", "err", err)
+				w.Write([]byte("KO"))
+			}
+
+			return
+		}
+
 		// Check if user is querying for his/her own compute units by looking to DB
 		// If the current user is admin, allow query
 		if !amw.isUserUnit(
@@ -417,6 +475,8 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 			reqParams.uuids,
 			[]int64{reqParams.time},
 		) {
+			event.deny("unauthorized compute units")
+
 			// Write an error and stop the handler chain
 			w.WriteHeader(http.StatusForbidden)
 
@@ -441,3 +501,34 @@ func (amw *authenticationMiddleware) Middleware(next http.Handler) http.Handler
 		next.ServeHTTP(w, r)
 	})
 }
+
+// logAudit writes the audit event of request with its outcome.
+func (amw *authenticationMiddleware) logAudit(
+	e *auditEvent,
+	w *auditResponseWriter,
+	p *ReqParams,
+	user string,
+	isAdmin bool,
+) {
+	e.LBType = amw.lbType.String()
+	e.Admin = isAdmin
+	e.ClusterID = p.clusterID
+	e.UUIDs = slices.Compact(slices.Sorted(slices.Values(p.uuids)))
+	e.Status = w.code
+	e.ResponseBytes = w.size
+	e.Latency = time.Since(e.Time).Seconds()
+
+	// Logged user is known only when access control is enabled. Else user
+	// header of the request is used
+	if user != "" {
+		e.User = user
+	}
+
+	if e.UUIDs == nil {
+		e.UUIDs = []string{}
+	}
+
+	if err := amw.audit.log(e); err != nil {
+		amw.logger.Error("Failed to write audit log", "err", err)
+	}
+}
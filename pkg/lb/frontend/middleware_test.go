@@ -12,7 +12,6 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
 	"path/filepath"
 	"strconv"
 	"strings"
@@ -20,6 +19,7 @@ import (
 	"time"
 
 	"github.com/mahendrapaipuri/ceems/pkg/api/base"
+	ceems_api_client "github.com/mahendrapaipuri/ceems/pkg/api/client"
 	http_api "github.com/mahendrapaipuri/ceems/pkg/api/http"
 	"github.com/mahendrapaipuri/ceems/pkg/api/models"
 	"github.com/stretchr/testify/assert"
@@ -72,14 +72,15 @@ CREATE TABLE projects (
 	"id" integer not null primary key,
 	"cluster_id" text,
 	"name" text,
-	"users" text
+	"users" text,
+	"managers" text default '[]'
 );
-INSERT INTO projects VALUES(1, 'rm-0', 'prj1', '["usr1","usr2"]');
-INSERT INTO projects VALUES(2, 'rm-0', 'prj2', '["usr2"]');
-INSERT INTO projects VALUES(3, 'rm-0', 'prj3', '["usr3"]');
-INSERT INTO projects VALUES(4, 'rm-1', 'prj1', '["usr1","usr2"]');
-INSERT INTO projects VALUES(5, 'rm-1', 'prj4', '["usr4"]');
-INSERT INTO projects VALUES(6, 'rm-1', 'prj5', '["usr5"]');
+INSERT INTO projects VALUES(1, 'rm-0', 'prj1', '["usr1","usr2"]', '[]');
+INSERT INTO projects VALUES(2, 'rm-0', 'prj2', '["usr2"]', '[]');
+INSERT INTO projects VALUES(3, 'rm-0', 'prj3', '["usr3"]', '["usr6"]');
+INSERT INTO projects VALUES(4, 'rm-1', 'prj1', '["usr1","usr2"]', '[]');
+INSERT INTO projects VALUES(5, 'rm-1', 'prj4', '["usr4"]', '[]');
+INSERT INTO projects VALUES(6, 'rm-1', 'prj5', '["usr5"]', '[]');
 CREATE TABLE users (
 	"id" integer not null primary key,
 	"cluster_id" text,
@@ -147,13 +148,20 @@ func setupMiddlewareWithAPI(tmpDir string) (http.Handler, error) {
 
 	// Setup test CEEMS API server
 	ceemsServer := setupCEEMSAPI(db)
-	ceemsURL, _ := url.Parse(ceemsServer.URL)
+
+	ceemsClient, err := ceems_api_client.New(ceems_api_client.Config{
+		Address: ceemsServer.URL,
+		User:    base.CEEMSServiceAccount,
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Create an instance of middleware
 	amw := authenticationMiddleware{
 		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
 		clusterIDs:    []string{"rm-0", "rm-1"},
-		ceems:         &ceems{webURL: ceemsURL, client: http.DefaultClient},
+		ceems:         &ceems{client: ceemsClient},
 		parseRequest:  parseTSDBRequest,
 		pathsACLRegex: regexpAllowedTSDBResources,
 	}
@@ -312,20 +320,84 @@ func TestMiddleware(t *testing.T) {
 			code:   200,
 		},
 		{
-			name:   "forbid due to no uuid",
+			name:   "pass due to uuid from managed project",
+			req:    "/query?query=foo{uuid=\"1481510\"}&time=1735045414",
+			id:     "rm-0",
+			user:   "usr6",
+			header: true,
+			code:   200,
+		},
+		{
+			name:   "forbid due to uuid from project managed by other user",
+			req:    "/query?query=foo{uuid=\"1479765\"}&time=1735045414",
+			id:     "rm-0",
+			user:   "usr6",
+			header: true,
+			code:   403,
+		},
+		{
+			name:   "bad request due to empty uuid",
 			req:    "/query_range?query=foo{uuid=\"\"}",
 			id:     "rm-0",
 			header: true,
 			user:   "usr3",
-			code:   403,
+			code:   400,
 		},
 		{
-			name:   "forbid due to no uuid and non-empty gpuuuid",
+			name:   "bad request due to empty uuid and non-empty gpuuuid",
 			req:    "/query?query=foo{uuid=\"\",gpuuuid=\"GPU-01234\"}",
 			id:     "rm-0",
 			header: true,
 			user:   "usr2",
-			code:   403,
+			code:   400,
+		},
+		{
+			name:   "bad request due to ceems metric without uuid",
+			req:    "/query?query=sum(ceems_compute_unit_cpu_user_seconds_total)&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "usr1",
+			code:   400,
+		},
+		{
+			name:   "bad request due to ceems metric without uuid along with owned uuid",
+			req:    "/query?query=ceems_compute_unit_cpus{uuid=\"1479763\"}%2Bsum(ceems_compute_unit_cpus)&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "usr1",
+			code:   400,
+		},
+		{
+			name:   "bad request due to uuid regex",
+			req:    "/query?query=ceems_compute_unit_cpus{uuid=~\".%2B\"}&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "usr1",
+			code:   400,
+		},
+		{
+			name:   "bad request due to selector without metric name",
+			req:    "/query?query={job=\"ceems\"}&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "usr1",
+			code:   400,
+		},
+		{
+			name:   "pass due to ceems metrics with owned uuid",
+			req:    "/query?query=rate(ceems_compute_unit_cpu_user_seconds_total{uuid=\"1479763\"}[5m])/ceems_compute_unit_cpus{uuid=\"1479763\"}&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "usr1",
+			code:   200,
+		},
+		{
+			name:   "allow ceems metric without uuid for admins",
+			req:    "/query?query=sum(ceems_compute_unit_cpu_user_seconds_total)&time=1735045414",
+			id:     "rm-0",
+			header: true,
+			user:   "adm1",
+			code:   200,
 		},
 		{
 			name:   "forbid due blacklisted resource",
@@ -11,6 +11,7 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -18,9 +19,28 @@ import (
 
 	querierv1 "github.com/grafana/pyroscope/api/gen/proto/go/querier/v1"
 	typesv1 "github.com/grafana/pyroscope/api/gen/proto/go/types/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
 	"google.golang.org/protobuf/proto"
 )
 
+// Labels used to identify compute units and clusters in queries.
+const (
+	tsdbUUIDLabel = "uuid"
+	pyroUUIDLabel = "service_name"
+	lokiUUIDLabel = "uuid"
+	clusterLabel  = "ceems_id"
+)
+
+// errInvalidQuery is returned when a query does not comply with the access
+// control rules of LB.
+var errInvalidQuery = errors.New("invalid query")
+
+// Prefixes of metric names that are always scoped to compute units. Selectors
+// on these metrics must select compute units with uuid label.
+var enforcedMetricPrefixes = []string{"ceems_", "uuid:"}
+
 // These functions are nicked from https://github.com/prometheus/prometheus/blob/main/web/api/v1/api.go
 var (
 	// MinTime is the default timestamp used for the begin of optional time ranges.
@@ -83,10 +103,18 @@ func parseTSDBRequest(p *ReqParams, r *http.Request) error {
 		targetTimeParam = "start"
 	}
 
-	// Parse TSDB's query in request query params
+	// Parse TSDB's query in request query params. Queries of query and query_range
+	// endpoints are PromQL expressions and match[] of other endpoints are series
+	// selectors
+	var queryErr error
+
 	if vals, ok := clonedReq.Form[targetQueryParam]; ok {
 		for _, val := range vals {
-			parseReqParams(p, val)
+			if targetQueryParam == "query" {
+				queryErr = errors.Join(queryErr, parseTSDBQuery(p, val))
+			} else {
+				queryErr = errors.Join(queryErr, parseSelector(p, val, tsdbUUIDLabel))
+			}
 		}
 	}
 
@@ -99,7 +127,7 @@ func parseTSDBRequest(p *ReqParams, r *http.Request) error {
 		p.time = startTime.Local().UnixMilli()
 	}
 
-	return nil
+	return queryErr
 }
 
 // parsePyroRequest parses Pyroscope query in the request after cloning it and reads them into request params.
@@ -123,6 +151,8 @@ func parsePyroRequest(p *ReqParams, r *http.Request) error {
 
 	var start int64
 
+	var queryErr error
+
 	// Read body into request data based on resource
 	switch {
 	case strings.HasSuffix(r.URL.Path, "SelectMergeStacktraces"):
@@ -134,7 +164,7 @@ func parsePyroRequest(p *ReqParams, r *http.Request) error {
 
 		// Parse Pyroscope's LabelSelector in request data
 		if val := data.GetLabelSelector(); val != "" {
-			parseReqParams(p, val)
+			queryErr = parseSelector(p, val, pyroUUIDLabel)
 		}
 
 		// Get start time of query
@@ -149,7 +179,7 @@ func parsePyroRequest(p *ReqParams, r *http.Request) error {
 		// Parse Pyroscope's LabelSelector in request data
 		if vals := data.GetMatchers(); vals != nil {
 			for _, val := range vals {
-				parseReqParams(p, val)
+				queryErr = errors.Join(queryErr, parseSelector(p, val, pyroUUIDLabel))
 			}
 		}
 
@@ -165,7 +195,7 @@ func parsePyroRequest(p *ReqParams, r *http.Request) error {
 		// Parse Pyroscope's LabelSelector in request data
 		if vals := data.GetMatchers(); vals != nil {
 			for _, val := range vals {
-				parseReqParams(p, val)
+				queryErr = errors.Join(queryErr, parseSelector(p, val, pyroUUIDLabel))
 			}
 		}
 
@@ -185,36 +215,279 @@ func parsePyroRequest(p *ReqParams, r *http.Request) error {
 		p.time = startTime.UnixMilli()
 	}
 
-	return nil
+	return queryErr
 }
 
-// parseRequestParams parses request parameters from `req` and reads them into `p`.
-func parseReqParams(p *ReqParams, req string) {
-	// Extract UUIDs from query
-	for _, match := range regexpUUID.FindAllStringSubmatch(req, -1) {
-		if len(match) > 1 {
-			for _, uuid := range strings.Split(match[1], "|") {
-				// Ignore empty strings
-				if strings.TrimSpace(uuid) != "" && !slices.Contains(p.uuids, uuid) {
-					p.uuids = append(p.uuids, uuid)
-				}
+// parseLokiRequest parses Loki query in the request after cloning it and reads them into request params.
+func parseLokiRequest(p *ReqParams, r *http.Request) error {
+	var body []byte
+
+	var err error
+
+	// Make a new request and add newReader to that request body
+	clonedReq := r.Clone(r.Context())
+
+	// If request has no body go to proxy directly
+	if r.Body == nil {
+		return errors.New("no body found in the request")
+	}
+
+	// If failed to read body, skip verification and go to request proxy
+	if body, err = io.ReadAll(r.Body); err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	// clone body to existing request and new request
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	clonedReq.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Get form values
+	if err = clonedReq.ParseForm(); err != nil {
+		return fmt.Errorf("failed to parse request form data: %w", err)
+	}
+
+	// Except for query API, rest of the load balanced API endpoint have start query param.
+	// Series API uses match[] for stream selectors and rest of them use query
+	var targetTimeParam string
+
+	targetQueryParams := []string{"query"}
+
+	switch {
+	case strings.HasSuffix(clonedReq.URL.Path, "query"):
+		targetTimeParam = "time"
+	case strings.HasSuffix(clonedReq.URL.Path, "series"):
+		targetQueryParams = []string{"match[]", "match"}
+		targetTimeParam = "start"
+	default:
+		targetTimeParam = "start"
+	}
+
+	// Parse LogQL queries and stream selectors in request query params
+	var queryErr error
+
+	for _, param := range targetQueryParams {
+		for _, val := range clonedReq.Form[param] {
+			queryErr = errors.Join(queryErr, parseLokiQuery(p, val))
+		}
+	}
+
+	// Parse Loki's start query in request query params
+	if startTime, err := parseLokiTime(clonedReq.FormValue(targetTimeParam)); err != nil {
+		p.queryPeriod = 0 * time.Second
+		p.time = time.Now().Local().UnixMilli()
+	} else {
+		p.queryPeriod = time.Now().Local().Sub(startTime)
+		p.time = startTime.Local().UnixMilli()
+	}
+
+	return queryErr
+}
+
+// parseTSDBQuery parses PromQL query and reads UUIDs and cluster ID of every
+// vector selector into `p`. It returns an error when the query cannot be parsed
+// or when a selector on a metric of compute units does not select the units
+// using UUIDs.
+func parseTSDBQuery(p *ReqParams, query string) error {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+
+	var errs error
+
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if vs, ok := node.(*parser.VectorSelector); ok {
+			errs = errors.Join(errs, checkMatchers(p, vs.String(), vs.LabelMatchers, tsdbUUIDLabel, isEnforcedMetric(vs.LabelMatchers)))
+		}
+
+		return nil
+	})
+
+	return errs
+}
+
+// parseSelector parses series selector and reads UUIDs and cluster ID into `p`.
+// Pyroscope selectors must always select compute units using UUIDs whereas TSDB
+// selectors must do so only for metrics of compute units.
+func parseSelector(p *ReqParams, selector string, uuidLabel string) error {
+	matchers, err := parser.ParseMetricSelector(selector)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+
+	return checkMatchers(p, selector, matchers, uuidLabel, uuidLabel == pyroUUIDLabel || isEnforcedMetric(matchers))
+}
+
+// parseLokiQuery parses every stream selector in LogQL query and reads UUIDs and
+// cluster ID into `p`. Every stream selector must select compute units using UUIDs.
+func parseLokiQuery(p *ReqParams, query string) error {
+	selectors, err := streamSelectors(query)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errInvalidQuery, err)
+	}
+
+	if len(selectors) == 0 {
+		return fmt.Errorf("%w: no stream selector found in %s", errInvalidQuery, query)
+	}
+
+	var errs error
+
+	for _, selector := range selectors {
+		matchers, err := parser.ParseMetricSelector(selector)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%w: %w", errInvalidQuery, err))
+
+			continue
+		}
+
+		errs = errors.Join(errs, checkMatchers(p, selector, matchers, lokiUUIDLabel, true))
+	}
+
+	return errs
+}
+
+// streamSelectors returns all the stream selectors, i.e., `{...}` blocks, in LogQL
+// query. Braces inside string literals like templates of line_format stage are
+// ignored.
+func streamSelectors(query string) ([]string, error) {
+	var selectors []string
+
+	start := -1
+
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '"', '`':
+			// Skip string literals
+			end := stringLiteralEnd(query, i)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string literal in %s", query)
+			}
+
+			i = end
+		case '{':
+			if start >= 0 {
+				return nil, fmt.Errorf("unexpected { in %s", query)
+			}
+
+			start = i
+		case '}':
+			if start < 0 {
+				return nil, fmt.Errorf("unexpected } in %s", query)
 			}
+
+			selectors = append(selectors, query[start:i+1])
+			start = -1
+		}
+	}
+
+	if start >= 0 {
+		return nil, fmt.Errorf("unterminated stream selector in %s", query)
+	}
+
+	return selectors, nil
+}
+
+// stringLiteralEnd returns the index of the quote that terminates the string literal
+// starting at index i of query. It returns -1 when the literal is not terminated.
+func stringLiteralEnd(query string, i int) int {
+	quote := query[i]
+
+	for j := i + 1; j < len(query); j++ {
+		switch {
+		case query[j] == '\\' && quote == '"':
+			j++
+		case query[j] == quote:
+			return j
 		}
 	}
 
-	// Extract ceems_id from query. If multiple values are provided, always
-	// get the last and most recent one
-	idMatches := regexID.FindAllStringSubmatch(req, -1)
-	for _, match := range idMatches {
-		if len(match) > 1 {
-			for _, idMatch := range strings.Split(match[1], "|") {
-				// Ignore empty strings
-				if strings.TrimSpace(idMatch) != "" {
-					p.clusterID = strings.TrimSpace(idMatch)
+	return -1
+}
+
+// isEnforcedMetric returns true if matchers can select metrics of compute units.
+// Matchers without an exact metric name can select any metric and so they are
+// always enforced.
+func isEnforcedMetric(matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return slices.ContainsFunc(enforcedMetricPrefixes, func(prefix string) bool {
+				return strings.HasPrefix(m.Value, prefix)
+			})
+		}
+	}
+
+	return true
+}
+
+// checkMatchers reads UUIDs and cluster ID in matchers into `p`. UUID matcher, if
+// present, must be either an exact match or a regex that is an alternation of
+// UUIDs as a user can only query compute units that they own. When enforce is true,
+// UUID matcher must be present.
+func checkMatchers(p *ReqParams, selector string, matchers []*labels.Matcher, uuidLabel string, enforce bool) error {
+	var found bool
+
+	for _, m := range matchers {
+		switch m.Name {
+		case uuidLabel:
+			uuids, ok := matcherValues(m, regexpInvalidUUID)
+			if !ok {
+				return fmt.Errorf(
+					"%w: matcher %s in %s must be an exact match or an alternation of UUIDs like %s=~\"uuid1|uuid2\"",
+					errInvalidQuery, m, selector, uuidLabel,
+				)
+			}
+
+			for _, uuid := range uuids {
+				if !slices.Contains(p.uuids, uuid) {
+					p.uuids = append(p.uuids, uuid)
 				}
 			}
+
+			found = true
+		case clusterLabel:
+			// Cluster ID is only used to route the request. If multiple values are
+			// provided, always get the last and most recent one
+			if m.Type != labels.MatchEqual && m.Type != labels.MatchRegexp {
+				continue
+			}
+
+			for _, id := range strings.Split(m.Value, "|") {
+				if id = strings.TrimSpace(id); id != "" && !regexpInvalidID.MatchString(id) {
+					p.clusterID = id
+				}
+			}
+		}
+	}
+
+	if enforce && !found {
+		return fmt.Errorf("%w: %s must have a %s matcher selecting compute units", errInvalidQuery, selector, uuidLabel)
+	}
+
+	return nil
+}
+
+// matcherValues returns the values of equality matcher or regex matcher that is
+// an alternation of values. For any other matcher or when any of the values
+// matches invalid regex, it returns false.
+func matcherValues(m *labels.Matcher, invalid *regexp.Regexp) ([]string, bool) {
+	var values []string
+
+	switch m.Type { //nolint:exhaustive
+	case labels.MatchEqual:
+		values = []string{strings.TrimSpace(m.Value)}
+	case labels.MatchRegexp:
+		values = strings.Split(m.Value, "|")
+	default:
+		return nil, false
+	}
+
+	for _, value := range values {
+		if value == "" || invalid.MatchString(value) {
+			return nil, false
 		}
 	}
+
+	return values, true
 }
 
 // Parse time parameter in request.
@@ -232,6 +505,44 @@ func parseTimeParam(r *http.Request, paramName string, defaultValue time.Time) (
 	return result, nil
 }
 
+// Convert duration parameter string into time.Duration.
+func parseDuration(s string) (time.Duration, error) {
+	if d, err := strconv.ParseFloat(s, 64); err == nil {
+		ts := d * float64(time.Second)
+		if ts > float64(math.MaxInt64) || ts < float64(math.MinInt64) {
+			return 0, fmt.Errorf("cannot parse %q to a valid duration. It overflows int64", s)
+		}
+
+		return time.Duration(ts), nil
+	}
+
+	if d, err := model.ParseDuration(s); err == nil {
+		return time.Duration(d), nil
+	}
+
+	return 0, fmt.Errorf("cannot parse %q to a valid duration", s)
+}
+
+// Convert Loki's time parameter string into time.Time. Integers with more than
+// 10 digits are nanoseconds since epoch as in Loki. Empty value returns an error.
+func parseLokiTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("empty time value")
+	}
+
+	if !strings.Contains(s, ".") {
+		if t, err := strconv.ParseInt(s, 10, 64); err == nil {
+			if len(s) <= 10 {
+				return time.Unix(t, 0).UTC(), nil
+			}
+
+			return time.Unix(0, t).UTC(), nil
+		}
+	}
+
+	return parseTime(s)
+}
+
 // Convert time parameter string into time.Time.
 func parseTime(s string) (time.Time, error) {
 	if t, err := strconv.ParseFloat(s, 64); err == nil {
@@ -154,12 +154,13 @@ func TestParseTime(t *testing.T) {
 
 func TestParseTSDBQueryParams(t *testing.T) {
 	tests := []struct {
-		path   string
-		query  string
-		uuids  []string
-		rmID   string
-		rmIDs  []string
-		method string
+		path    string
+		query   string
+		uuids   []string
+		rmID    string
+		rmIDs   []string
+		method  string
+		invalid bool
 	}{
 		{
 			path:   "/api/v1/query",
@@ -194,9 +195,64 @@ func TestParseTSDBQueryParams(t *testing.T) {
 			method: "GET",
 		},
 		{
-			path:   "/api/v1/query_range",
-			query:  "foo{uuid=~\"abc_123|456\"}",
-			method: "POST",
+			path:    "/api/v1/query_range",
+			query:   "foo{uuid=~\"abc_123|456\"}",
+			method:  "POST",
+			invalid: true,
+		},
+		{
+			path:   "/api/v1/query",
+			query:  "sum by (uuid) (rate(ceems_compute_unit_cpu_user_seconds_total{uuid=\"123\"}[5m])) / on(uuid) uuid:ceems_cpu_usage:ratio_irate{uuid=~\"123|456\"}",
+			uuids:  []string{"123", "456"},
+			method: "GET",
+		},
+		{
+			path:   "/api/v1/query",
+			query:  "up{job=\"ceems\"}",
+			method: "GET",
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "sum(ceems_compute_unit_cpu_user_seconds_total)",
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "ceems_compute_unit_cpus{uuid=\"123\"} + sum(uuid:ceems_cpu_usage:ratio_irate)",
+			uuids:   []string{"123"},
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "ceems_compute_unit_cpus{uuid=~\".+\"}",
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "ceems_compute_unit_cpus{uuid!=\"123\"}",
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "{__name__=~\"ceems_.*\"}",
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/query",
+			query:   "sum(ceems_compute_unit_cpus{uuid=\"123\"}",
+			method:  "GET",
+			invalid: true,
+		},
+		{
+			path:    "/api/v1/series",
+			query:   "ceems_compute_unit_cpus{instance=\"host-0\"}",
+			method:  "GET",
+			invalid: true,
 		},
 	}
 
@@ -229,10 +285,15 @@ func TestParseTSDBQueryParams(t *testing.T) {
 
 		p := &ReqParams{}
 		err = parseTSDBRequest(p, req)
-		require.NoError(t, err)
 
-		assert.Equal(t, test.uuids, p.uuids)
-		assert.Equal(t, test.rmID, p.clusterID)
+		if test.invalid {
+			require.ErrorIs(t, err, errInvalidQuery, test.query)
+		} else {
+			require.NoError(t, err, test.query)
+		}
+
+		assert.Equal(t, test.uuids, p.uuids, test.query)
+		assert.Equal(t, test.rmID, p.clusterID, test.query)
 
 		// Set parameters to request's context
 		newReq := setQueryParams(req, p)
@@ -254,6 +315,7 @@ func TestParsePyroQueryParams(t *testing.T) {
 		uuids    []string
 		start    int64
 		rmIDs    string
+		invalid  bool
 	}{
 		{
 			resource: "SelectMergeStacktraces",
@@ -294,6 +356,25 @@ func TestParsePyroQueryParams(t *testing.T) {
 			rmIDs: "default",
 			start: 1735209000,
 		},
+		{
+			resource: "SelectMergeStacktraces",
+			message: &querierv1.SelectMergeStacktracesRequest{
+				LabelSelector: `{ceems_id="default", service_name=~".+"}`,
+				Start:         1735209190,
+			},
+			rmIDs:   "default",
+			start:   1735209000,
+			invalid: true,
+		},
+		{
+			resource: "LabelNames",
+			message: &typesv1.LabelNamesRequest{
+				Matchers: []string{`{__profile_type__="process_cpu:cpu:nanoseconds:cpu:nanoseconds"}`},
+				Start:    1735209000,
+			},
+			start:   1735209000,
+			invalid: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -317,10 +398,111 @@ func TestParsePyroQueryParams(t *testing.T) {
 
 		p := &ReqParams{}
 		err = parsePyroRequest(p, req)
-		require.NoError(t, err)
+
+		if test.invalid {
+			require.ErrorIs(t, err, errInvalidQuery, test.resource)
+		} else {
+			require.NoError(t, err, test.resource)
+		}
 
 		assert.Equal(t, test.uuids, p.uuids, test.resource)
 		assert.Equal(t, test.rmIDs, p.clusterID, test.resource)
 		assert.Equal(t, test.start, p.time, test.resource)
 	}
 }
+
+func TestParseLokiQueryParams(t *testing.T) {
+	tests := []struct {
+		path    string
+		query   string
+		start   string
+		uuids   []string
+		rmID    string
+		time    int64
+		invalid bool
+	}{
+		{
+			path:  "/loki/api/v1/query_range",
+			query: `{uuid="1234", ceems_id="default"} |= "error"`,
+			start: "1735209000000000000",
+			uuids: []string{"1234"},
+			rmID:  "default",
+			time:  1735209000000,
+		},
+		{
+			path:  "/loki/api/v1/query_range",
+			query: `sum by (level) (count_over_time({uuid=~"1234|5678"} | json | line_format "{{.msg}}" [5m]))`,
+			start: "1735209000",
+			uuids: []string{"1234", "5678"},
+			time:  1735209000000,
+		},
+		{
+			path:  "/loki/api/v1/query",
+			query: `{uuid="1234"} |~ "{not a selector}" or {uuid="5678"}`,
+			uuids: []string{"1234", "5678"},
+		},
+		{
+			path:  "/loki/api/v1/series",
+			query: `{uuid="1234", stream="stderr"}`,
+			uuids: []string{"1234"},
+		},
+		{
+			path:  "/loki/api/v1/label/stream/values",
+			query: `{uuid="1234"}`,
+			uuids: []string{"1234"},
+		},
+		{
+			path:    "/loki/api/v1/query_range",
+			query:   `{job="slurm"} |= "error"`,
+			invalid: true,
+		},
+		{
+			path:    "/loki/api/v1/query_range",
+			query:   `{uuid=~".+"}`,
+			invalid: true,
+		},
+		{
+			path:    "/loki/api/v1/query_range",
+			query:   `{uuid="1234"} | line_format "{{.msg}}`,
+			invalid: true,
+		},
+		{
+			path:    "/loki/api/v1/query",
+			query:   `vector(1)`,
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		// Query params
+		data := url.Values{}
+		if strings.HasSuffix(test.path, "series") {
+			data.Set("match[]", test.query)
+		} else {
+			data.Set("query", test.query)
+		}
+
+		if test.start != "" {
+			data.Set("start", test.start)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, "http://localhost:3100"+test.path+"?"+data.Encode(), strings.NewReader("")) //nolint:noctx
+		require.NoError(t, err)
+
+		p := &ReqParams{}
+		err = parseLokiRequest(p, req)
+
+		if test.invalid {
+			require.ErrorIs(t, err, errInvalidQuery, test.query)
+		} else {
+			require.NoError(t, err, test.query)
+		}
+
+		assert.Equal(t, test.uuids, p.uuids, test.query)
+		assert.Equal(t, test.rmID, p.clusterID, test.query)
+
+		if test.time > 0 {
+			assert.Equal(t, test.time, p.time, test.query)
+		}
+	}
+}
@@ -0,0 +1,562 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/klauspost/compress/snappy"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// Path of remote write endpoint.
+const remoteWritePath = "/api/v1/write"
+
+// Series of agents that have not received any sample for this duration are
+// not considered as active anymore.
+const activeSeriesIdleTimeout = 20 * time.Minute
+
+// Reasons of rejection of series.
+const (
+	reasonMissingClusterID  = "missing_cluster_id"
+	reasonMismatchClusterID = "mismatch_cluster_id"
+	reasonMaxSeries         = "max_series"
+)
+
+// RemoteWriteConfig contains the configuration of remote write gateway.
+type RemoteWriteConfig struct {
+	ListenAddress  string                   `yaml:"listen_address"`
+	WebConfigFile  string                   `yaml:"web_config_file"`
+	MaxRequestSize units.Base2Bytes         `yaml:"max_request_size"`
+	Agents         []RemoteWriteAgentConfig `yaml:"agents"`
+}
+
+// RemoteWriteAgentConfig contains the configuration of an agent that pushes
+// metrics of a cluster using remote write.
+type RemoteWriteAgentConfig struct {
+	Name                string            `yaml:"name"`
+	ClusterID           string            `yaml:"cluster_id"`
+	Token               config.Secret     `yaml:"token"`
+	TokenFile           string            `yaml:"token_file"`
+	MaxSeries           int               `yaml:"max_series"`
+	WriteRelabelConfigs []*relabel.Config `yaml:"write_relabel_configs"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (c *RemoteWriteConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	// Set a default config
+	*c = RemoteWriteConfig{
+		MaxRequestSize: 10 * units.MiB,
+	}
+
+	type plain RemoteWriteConfig
+
+	return unmarshal((*plain)(c))
+}
+
+// Validate validates the config.
+func (c *RemoteWriteConfig) Validate() error {
+	if len(c.Agents) == 0 {
+		return nil
+	}
+
+	if c.MaxRequestSize <= 0 {
+		return errors.New("max_request_size of remote write must be positive")
+	}
+
+	if c.WebConfigFile != "" && c.ListenAddress == "" {
+		return errors.New("web_config_file of remote write can only be set along with listen_address")
+	}
+
+	var names []string
+
+	for _, agent := range c.Agents {
+		if agent.Name == "" || agent.ClusterID == "" {
+			return errors.New("name and cluster_id of remote write agent must not be empty")
+		}
+
+		if slices.Contains(names, agent.Name) {
+			return fmt.Errorf("duplicate remote write agent %s", agent.Name)
+		}
+
+		names = append(names, agent.Name)
+
+		if (agent.Token == "") == (agent.TokenFile == "") {
+			return fmt.Errorf("exactly one of token and token_file must be set for remote write agent %s", agent.Name)
+		}
+
+		if agent.MaxSeries < 0 {
+			return fmt.Errorf("max_series of remote write agent %s must not be negative", agent.Name)
+		}
+
+		for _, rc := range agent.WriteRelabelConfigs {
+			if rc == nil {
+				return fmt.Errorf("empty write_relabel_configs of remote write agent %s", agent.Name)
+			}
+
+			if err := rc.Validate(); err != nil {
+				return fmt.Errorf("invalid write_relabel_configs of remote write agent %s: %w", agent.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// TokenFiles returns the token files of agents.
+func (c *RemoteWriteConfig) TokenFiles() []string {
+	var files []string
+
+	for _, agent := range c.Agents {
+		if agent.TokenFile != "" {
+			files = append(files, agent.TokenFile)
+		}
+	}
+
+	return files
+}
+
+// remoteWriteAgent is an agent that pushes metrics of a cluster.
+type remoteWriteAgent struct {
+	config RemoteWriteAgentConfig
+	mu     sync.Mutex
+	series map[uint64]time.Time
+	purged time.Time
+}
+
+// token returns the token of agent.
+func (a *remoteWriteAgent) token() (string, error) {
+	if a.config.TokenFile == "" {
+		return string(a.config.Token), nil
+	}
+
+	b, err := os.ReadFile(a.config.TokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file of remote write agent %s: %w", a.config.Name, err)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("empty token file of remote write agent %s", a.config.Name)
+	}
+
+	return token, nil
+}
+
+// admit returns the series that can be accepted within the series limit of
+// agent. Series that are already active are always accepted.
+func (a *remoteWriteAgent) admit(hashes []uint64) []bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+
+	// Purge idle series periodically
+	if now.Sub(a.purged) > time.Minute {
+		for hash, lastSeen := range a.series {
+			if now.Sub(lastSeen) > activeSeriesIdleTimeout {
+				delete(a.series, hash)
+			}
+		}
+
+		a.purged = now
+	}
+
+	admitted := make([]bool, len(hashes))
+
+	for i, hash := range hashes {
+		if _, ok := a.series[hash]; !ok && a.config.MaxSeries > 0 && len(a.series) >= a.config.MaxSeries {
+			continue
+		}
+
+		a.series[hash] = now
+		admitted[i] = true
+	}
+
+	return admitted
+}
+
+// activeSeries returns the number of active series of agent.
+func (a *remoteWriteAgent) activeSeries() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return len(a.series)
+}
+
+// remoteWriteGateway accepts remote write requests from authenticated agents,
+// enforces cluster ID label and series limits of agents on the series and
+// forwards them to TSDB backend servers of the cluster of agent.
+type remoteWriteGateway struct {
+	logger         *slog.Logger
+	maxRequestSize int64
+	agents         []*remoteWriteAgent
+	mu             sync.RWMutex
+	tokens         map[*remoteWriteAgent][sha256.Size]byte
+	requests       *prometheus.CounterVec
+	samples        *prometheus.CounterVec
+	rejected       *prometheus.CounterVec
+	active         *prometheus.GaugeVec
+}
+
+// newRemoteWriteGateway returns a new instance of remoteWriteGateway.
+func newRemoteWriteGateway(c RemoteWriteConfig, logger *slog.Logger) *remoteWriteGateway {
+	g := &remoteWriteGateway{
+		logger:         logger,
+		maxRequestSize: int64(c.MaxRequestSize),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "remote_write",
+			Name:      "requests_total",
+			Help:      "Total number of remote write requests of agent by status code.",
+		}, []string{"agent", "cluster_id", "code"}),
+		samples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "remote_write",
+			Name:      "samples_total",
+			Help:      "Total number of samples of agent forwarded to TSDB backend servers.",
+		}, []string{"agent", "cluster_id"}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "remote_write",
+			Name:      "rejected_series_total",
+			Help:      "Total number of series of agent rejected by reason.",
+		}, []string{"agent", "cluster_id", "reason"}),
+		active: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "ceems_lb",
+			Subsystem: "remote_write",
+			Name:      "active_series",
+			Help:      "Current number of active series of agent.",
+		}, []string{"agent", "cluster_id"}),
+	}
+
+	for _, agent := range c.Agents {
+		g.agents = append(g.agents, &remoteWriteAgent{config: agent, series: make(map[uint64]time.Time)})
+	}
+
+	g.loadTokens()
+
+	return g
+}
+
+// loadTokens loads the tokens of agents. Agents whose token cannot be loaded
+// are not authenticated until their token is loaded on a later reload.
+func (g *remoteWriteGateway) loadTokens() {
+	tokens := make(map[*remoteWriteAgent][sha256.Size]byte, len(g.agents))
+
+	for _, agent := range g.agents {
+		token, err := agent.token()
+		if err != nil {
+			g.logger.Error("Failed to load token of remote write agent. Skipping agent", "agent", agent.config.Name, "err", err)
+
+			continue
+		}
+
+		// Keep only the digests so that tokens of any length are compared in
+		// constant time
+		tokens[agent] = sha256.Sum256([]byte(token))
+	}
+
+	g.mu.Lock()
+	g.tokens = tokens
+	g.mu.Unlock()
+}
+
+// collectors returns the metrics of gateway.
+func (g *remoteWriteGateway) collectors() []prometheus.Collector {
+	return []prometheus.Collector{g.requests, g.samples, g.rejected, g.active}
+}
+
+// authenticate returns the agent of the request based on its bearer token.
+func (g *remoteWriteGateway) authenticate(r *http.Request) (*remoteWriteAgent, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errors.New("missing bearer token")
+	}
+
+	digest := sha256.Sum256([]byte(token))
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	// Compare with tokens of all agents so that the time taken does not
+	// depend on the matching agent
+	var matched *remoteWriteAgent
+
+	for _, agent := range g.agents {
+		agentDigest, ok := g.tokens[agent]
+		if !ok {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare(digest[:], agentDigest[:]) == 1 {
+			matched = agent
+		}
+	}
+
+	if matched == nil {
+		return nil, errors.New("invalid bearer token")
+	}
+
+	return matched, nil
+}
+
+// decode returns the write request in the body of request.
+func (g *remoteWriteGateway) decode(r *http.Request) (*prompb.WriteRequest, error) {
+	compressed, err := io.ReadAll(io.LimitReader(r.Body, g.maxRequestSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	if int64(len(compressed)) > g.maxRequestSize {
+		return nil, fmt.Errorf("request body exceeds the limit of %d bytes", g.maxRequestSize)
+	}
+
+	if n, err := snappy.DecodedLen(compressed); err != nil || int64(n) > 10*g.maxRequestSize {
+		return nil, errors.New("invalid or too large snappy compressed request body")
+	}
+
+	b, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress request body: %w", err)
+	}
+
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal write request: %w", err)
+	}
+
+	return &req, nil
+}
+
+// process relabels the series of write request and enforces cluster ID label
+// and series limit of agent on them. It returns the number of rejected series
+// by reason. A series with missing or mismatching cluster ID rejects the entire
+// request as agent is misconfigured.
+func (g *remoteWriteGateway) process(agent *remoteWriteAgent, req *prompb.WriteRequest) (map[string]int, error) {
+	rejected := make(map[string]int)
+
+	timeseries := make([]prompb.TimeSeries, 0, len(req.Timeseries))
+	hashes := make([]uint64, 0, len(req.Timeseries))
+
+	builder := labels.NewScratchBuilder(0)
+
+	for _, ts := range req.Timeseries {
+		lbls := ts.ToLabels(&builder, nil)
+
+		// Apply relabeling rules of agent
+		lbls, keep := relabel.Process(lbls, agent.config.WriteRelabelConfigs...)
+		if !keep {
+			continue
+		}
+
+		switch id := lbls.Get(clusterLabel); id {
+		case "":
+			rejected[reasonMissingClusterID]++
+
+			return rejected, fmt.Errorf("series %s has no %s label. Expected %s", lbls.String(), clusterLabel, agent.config.ClusterID)
+		case agent.config.ClusterID:
+		default:
+			rejected[reasonMismatchClusterID]++
+
+			return rejected, fmt.Errorf(
+				"series %s has %s label %s that does not match %s of agent",
+				lbls.String(), clusterLabel, id, agent.config.ClusterID,
+			)
+		}
+
+		ts.Labels = prompb.FromLabels(lbls, nil)
+		timeseries = append(timeseries, ts)
+		hashes = append(hashes, lbls.Hash())
+	}
+
+	// Drop series that exceed the series limit of agent
+	admitted := agent.admit(hashes)
+
+	req.Timeseries = req.Timeseries[:0]
+
+	for i, ts := range timeseries {
+		if !admitted[i] {
+			rejected[reasonMaxSeries]++
+
+			continue
+		}
+
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+
+	return rejected, nil
+}
+
+// forward sends the write request to all TSDB backend servers of the cluster
+// so that replicas have the same data. It returns the first failed response.
+func (lb *loadBalancer) forward(r *http.Request, id string, req *prompb.WriteRequest) (int, []byte) {
+	var backends []backend.Server
+
+	for _, b := range lb.manager.Backends()[id] {
+		if b.IsAlive() {
+			backends = append(backends, b)
+		}
+	}
+
+	if len(backends) == 0 {
+		return http.StatusServiceUnavailable, []byte("no TSDB backend server available for cluster " + id)
+	}
+
+	b, err := req.Marshal()
+	if err != nil {
+		return http.StatusInternalServerError, []byte(err.Error())
+	}
+
+	body := snappy.Encode(nil, b)
+
+	responses := make([]*bufferedResponseWriter, len(backends))
+
+	var wg sync.WaitGroup
+
+	for i, target := range backends {
+		wg.Add(1)
+
+		go func(i int, target backend.Server) {
+			defer wg.Done()
+
+			// Do not retry failed writes on other backends as every backend
+			// must receive the write request
+			fwd := r.Clone(context.WithValue(r.Context(), RetryContextKey{}, true))
+			fwd.URL.RawQuery = ""
+			fwd.Body = io.NopCloser(bytes.NewReader(body))
+			fwd.ContentLength = int64(len(body))
+			fwd.Header.Set("Content-Encoding", "snappy")
+			fwd.Header.Set("Content-Type", "application/x-protobuf")
+			fwd.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+			fwd.Header.Del("Content-Length")
+
+			responses[i] = newBufferedResponseWriter()
+			target.Serve(responses[i], fwd)
+		}(i, target)
+	}
+
+	wg.Wait()
+
+	for i, resp := range responses {
+		if resp.code/100 != 2 {
+			lb.logger.Error("Failed to forward remote write request", "cluster_id", id,
+				"backend", backends[i].String(), "code", resp.code)
+
+			return resp.code, resp.body.Bytes()
+		}
+	}
+
+	return http.StatusNoContent, nil
+}
+
+// ReloadRemoteWriteTokens reloads the tokens of remote write agents.
+func (lb *loadBalancer) ReloadRemoteWriteTokens() {
+	if lb.remoteWriter != nil {
+		lb.remoteWriter.loadTokens()
+	}
+}
+
+// remoteWrite handles remote write requests of agents.
+func (lb *loadBalancer) remoteWrite(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST requests allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	g := lb.remoteWriter
+
+	agent, err := g.authenticate(r)
+	if err != nil {
+		lb.logger.Error("Unauthorized remote write request", "remote_addr", r.RemoteAddr, "err", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+
+		return
+	}
+
+	name, id := agent.config.Name, agent.config.ClusterID
+
+	code, msg := func() (int, string) {
+		// Only remote write 1.0 protocol is supported
+		switch contentType := r.Header.Get("Content-Type"); contentType {
+		case "", "application/x-protobuf", "application/x-protobuf;proto=prometheus.WriteRequest":
+		default:
+			return http.StatusUnsupportedMediaType, fmt.Sprintf(
+				"unsupported content type %s. Only remote write 1.0 protocol is supported", contentType,
+			)
+		}
+
+		req, err := g.decode(r)
+		if err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+
+		rejected, err := g.process(agent, req)
+		for reason, n := range rejected {
+			g.rejected.WithLabelValues(name, id, reason).Add(float64(n))
+		}
+
+		g.active.WithLabelValues(name, id).Set(float64(agent.activeSeries()))
+
+		if err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+
+		// Series that exceed the limit are dropped and rest of them are forwarded.
+		// Agent is informed with a non retryable error
+		if len(req.Timeseries) > 0 {
+			if code, body := lb.forward(r, id, req); code/100 != 2 {
+				return code, string(body)
+			}
+		}
+
+		var samples int
+		for _, ts := range req.Timeseries {
+			samples += len(ts.Samples) + len(ts.Histograms)
+		}
+
+		g.samples.WithLabelValues(name, id).Add(float64(samples))
+
+		if n := rejected[reasonMaxSeries]; n > 0 {
+			return http.StatusBadRequest, fmt.Sprintf(
+				"%d series dropped as number of active series exceeds the limit of %d of agent %s",
+				n, agent.config.MaxSeries, name,
+			)
+		}
+
+		return http.StatusNoContent, ""
+	}()
+
+	g.requests.WithLabelValues(name, id, strconv.Itoa(code)).Inc()
+
+	if code != http.StatusNoContent {
+		lb.logger.Error("Failed remote write request", "agent", name, "cluster_id", id, "code", code, "err", msg)
+		http.Error(w, msg, code)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
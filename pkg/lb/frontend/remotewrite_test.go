@@ -0,0 +1,375 @@
+//go:build cgo
+// +build cgo
+
+package frontend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/base"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/serverpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/relabel"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRequest(t *testing.T, series ...map[string]string) *bytes.Reader {
+	t.Helper()
+
+	req := &prompb.WriteRequest{}
+
+	for i, lbls := range series {
+		ts := prompb.TimeSeries{Samples: []prompb.Sample{{Value: float64(i), Timestamp: 1735045414000}}}
+
+		for name, value := range lbls {
+			ts.Labels = append(ts.Labels, prompb.Label{Name: name, Value: value})
+		}
+
+		req.Timeseries = append(req.Timeseries, ts)
+	}
+
+	b, err := req.Marshal()
+	require.NoError(t, err)
+
+	return bytes.NewReader(snappy.Encode(nil, b))
+}
+
+func remoteWriteLB(t *testing.T, c RemoteWriteConfig, handlers ...http.HandlerFunc) *loadBalancer {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	for _, handler := range handlers {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+
+		b, err := backend.New(base.PromLB, &backend.ServerConfig{Web: &models.WebConfig{URL: server.URL}}, logger)
+		require.NoError(t, err)
+
+		manager.Add("rm-0", b)
+	}
+
+	frontend, err := New(&Config{
+		Logger:      logger,
+		LBType:      base.PromLB,
+		Manager:     manager,
+		Address:     "localhost:9030",
+		RemoteWrite: c,
+	})
+	require.NoError(t, err)
+
+	return frontend.(*loadBalancer) //nolint:forcetypeassert
+}
+
+func TestRemoteWriteConfig(t *testing.T) {
+	for _, c := range []RemoteWriteConfig{
+		{MaxRequestSize: 0, Agents: []RemoteWriteAgentConfig{{Name: "a", ClusterID: "rm-0", Token: "t"}}},
+		{MaxRequestSize: 1, Agents: []RemoteWriteAgentConfig{{ClusterID: "rm-0", Token: "t"}}},
+		{MaxRequestSize: 1, Agents: []RemoteWriteAgentConfig{{Name: "a", ClusterID: "rm-0"}}},
+		{MaxRequestSize: 1, Agents: []RemoteWriteAgentConfig{{Name: "a", ClusterID: "rm-0", Token: "t", TokenFile: "f"}}},
+		{MaxRequestSize: 1, Agents: []RemoteWriteAgentConfig{{Name: "a", ClusterID: "rm-0", Token: "t", MaxSeries: -1}}},
+		{MaxRequestSize: 1, Agents: []RemoteWriteAgentConfig{
+			{Name: "a", ClusterID: "rm-0", Token: "t"}, {Name: "a", ClusterID: "rm-1", Token: "u"},
+		}},
+		{MaxRequestSize: 1, WebConfigFile: "f", Agents: []RemoteWriteAgentConfig{{Name: "a", ClusterID: "rm-0", Token: "t"}}},
+	} {
+		assert.Error(t, c.Validate())
+	}
+
+	c := RemoteWriteConfig{}
+	require.NoError(t, c.Validate())
+}
+
+func TestRemoteWriteTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+	tokenFile := filepath.Join(tmpDir, "token")
+	missingFile := filepath.Join(tmpDir, "missing")
+
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret-1\n"), 0o600))
+
+	g := newRemoteWriteGateway(RemoteWriteConfig{
+		Agents: []RemoteWriteAgentConfig{
+			{Name: "alloy", ClusterID: "rm-0", TokenFile: tokenFile},
+			{Name: "missing", ClusterID: "rm-1", TokenFile: missingFile},
+			{Name: "prom", ClusterID: "rm-2", Token: "secret-2"},
+		},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	authenticate := func(token string) string {
+		request := httptest.NewRequest(http.MethodPost, remoteWritePath, nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+
+		agent, err := g.authenticate(request)
+		if err != nil {
+			return ""
+		}
+
+		return agent.config.Name
+	}
+
+	// Agent whose token cannot be loaded must not fail other agents
+	assert.Equal(t, "alloy", authenticate("secret-1"))
+	assert.Equal(t, "prom", authenticate("secret-2"))
+	assert.Empty(t, authenticate(""))
+	assert.Empty(t, authenticate("secret-3"))
+
+	// Tokens are read only at the start and on reload
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret-4"), 0o600))
+	require.NoError(t, os.WriteFile(missingFile, []byte("secret-3"), 0o600))
+	assert.Equal(t, "alloy", authenticate("secret-1"))
+	assert.Empty(t, authenticate("secret-3"))
+
+	g.loadTokens()
+	assert.Empty(t, authenticate("secret-1"))
+	assert.Equal(t, "alloy", authenticate("secret-4"))
+	assert.Equal(t, "missing", authenticate("secret-3"))
+	assert.Equal(t, "prom", authenticate("secret-2"))
+}
+
+func TestRemoteWriteListener(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	// Main listener of LB is protected by basic auth. Password is password
+	webConfigFile := filepath.Join(t.TempDir(), "web-config.yml")
+	require.NoError(t, os.WriteFile(
+		webConfigFile,
+		[]byte("basic_auth_users:\n  prometheus: $2y$10$GQUblMhmbhVOvwQ4wSL2C.MFA.wVlKrROdWT92a27GhfrjfHvSy96\n"),
+		0o600,
+	))
+
+	var received int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == remoteWritePath {
+			received++
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	manager, err := serverpool.New("round-robin", logger)
+	require.NoError(t, err)
+
+	b, err := backend.New(base.PromLB, &backend.ServerConfig{Web: &models.WebConfig{URL: server.URL}}, logger)
+	require.NoError(t, err)
+
+	manager.Add("rm-0", b)
+
+	// Get free addresses for listeners
+	var addresses []string
+
+	for range 2 {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		addresses = append(addresses, l.Addr().String())
+		require.NoError(t, l.Close())
+	}
+
+	lb, err := New(&Config{
+		Logger:        logger,
+		LBType:        base.PromLB,
+		Manager:       manager,
+		Address:       addresses[0],
+		WebConfigFile: webConfigFile,
+		RemoteWrite: RemoteWriteConfig{
+			ListenAddress:  addresses[1],
+			MaxRequestSize: 1024,
+			Agents:         []RemoteWriteAgentConfig{{Name: "alloy", ClusterID: "rm-0", Token: "secret-1"}},
+		},
+	})
+	require.NoError(t, err)
+
+	go lb.Start(context.Background())
+
+	defer lb.Shutdown(context.Background())
+
+	push := func(address string) int {
+		request, err := http.NewRequest(http.MethodPost, "http://"+address+remoteWritePath, writeRequest(t, map[string]string{"__name__": "up", "ceems_id": "rm-0"}))
+		require.NoError(t, err)
+		request.Header.Set("Authorization", "Bearer secret-1")
+
+		resp, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return 0
+		}
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	// Remote write must work on its own listener with bearer token of agent
+	require.Eventually(t, func() bool {
+		return push(addresses[1]) == http.StatusNoContent
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, 1, received)
+
+	// Main listener still requires basic auth
+	assert.Equal(t, http.StatusUnauthorized, push(addresses[0]))
+	assert.Equal(t, 1, received)
+}
+
+func TestRemoteWriteGateway(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("secret-1\n"), 0o600))
+
+	// Backends record the series they receive
+	var received [2][]prompb.TimeSeries
+
+	handler := func(i int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != remoteWritePath {
+				return
+			}
+
+			assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+			assert.Empty(t, r.Header.Get("Authorization"))
+
+			compressed, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			b, err := snappy.Decode(nil, compressed)
+			require.NoError(t, err)
+
+			var req prompb.WriteRequest
+			require.NoError(t, req.Unmarshal(b))
+
+			received[i] = append(received[i], req.Timeseries...)
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+
+	lb := remoteWriteLB(t, RemoteWriteConfig{
+		MaxRequestSize: 1024,
+		Agents: []RemoteWriteAgentConfig{
+			{Name: "alloy", ClusterID: "rm-0", TokenFile: tokenFile, MaxSeries: 2},
+			{
+				Name: "prom", ClusterID: "rm-1", Token: "secret-2",
+				WriteRelabelConfigs: []*relabel.Config{
+					{
+						SourceLabels: model.LabelNames{"__name__"},
+						Regex:        relabel.MustNewRegexp("up"),
+						Action:       relabel.Drop,
+					},
+				},
+			},
+		},
+	}, handler(0), handler(1))
+
+	tests := []struct {
+		name   string
+		token  string
+		series []map[string]string
+		code   int
+		fwd    int
+	}{
+		{
+			name:   "missing token",
+			series: []map[string]string{{"__name__": "foo", "ceems_id": "rm-0"}},
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:   "invalid token",
+			token:  "secret-3",
+			series: []map[string]string{{"__name__": "foo", "ceems_id": "rm-0"}},
+			code:   http.StatusUnauthorized,
+		},
+		{
+			name:  "valid series",
+			token: "secret-1",
+			series: []map[string]string{
+				{"__name__": "foo", "ceems_id": "rm-0"},
+				{"__name__": "bar", "ceems_id": "rm-0"},
+			},
+			code: http.StatusNoContent,
+			fwd:  2,
+		},
+		{
+			name:   "missing cluster ID",
+			token:  "secret-1",
+			series: []map[string]string{{"__name__": "foo"}},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:   "cluster ID of other agent",
+			token:  "secret-1",
+			series: []map[string]string{{"__name__": "foo", "ceems_id": "rm-1"}},
+			code:   http.StatusBadRequest,
+		},
+		{
+			name:  "series limit",
+			token: "secret-1",
+			series: []map[string]string{
+				{"__name__": "foo", "ceems_id": "rm-0"},
+				{"__name__": "baz", "ceems_id": "rm-0"},
+			},
+			code: http.StatusBadRequest,
+			fwd:  1,
+		},
+		{
+			name:   "series dropped by relabeling",
+			token:  "secret-2",
+			series: []map[string]string{{"__name__": "up"}},
+			code:   http.StatusNoContent,
+		},
+	}
+
+	for _, test := range tests {
+		before := len(received[0])
+
+		request := httptest.NewRequest(http.MethodPost, remoteWritePath, writeRequest(t, test.series...))
+		request.Header.Set("Content-Type", "application/x-protobuf")
+
+		if test.token != "" {
+			request.Header.Set("Authorization", "Bearer "+test.token)
+		}
+
+		responseRecorder := httptest.NewRecorder()
+		http.HandlerFunc(lb.remoteWrite).ServeHTTP(responseRecorder, request)
+
+		require.Equal(t, test.code, responseRecorder.Code, test.name)
+		assert.Len(t, received[0], before+test.fwd, test.name)
+	}
+
+	// All backends of cluster must receive the same series
+	assert.Equal(t, received[0], received[1])
+
+	// Remote write v2 is not supported
+	request := httptest.NewRequest(http.MethodPost, remoteWritePath, writeRequest(t))
+	request.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+	request.Header.Set("Authorization", "Bearer secret-1")
+
+	responseRecorder := httptest.NewRecorder()
+	http.HandlerFunc(lb.remoteWrite).ServeHTTP(responseRecorder, request)
+	require.Equal(t, http.StatusUnsupportedMediaType, responseRecorder.Code)
+
+	// Metrics
+	g := lb.remoteWriter
+	assert.InDelta(t, 3, testutil.ToFloat64(g.samples.WithLabelValues("alloy", "rm-0")), 0)
+	assert.InDelta(t, 2, testutil.ToFloat64(g.active.WithLabelValues("alloy", "rm-0")), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(g.rejected.WithLabelValues("alloy", "rm-0", reasonMaxSeries)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(g.rejected.WithLabelValues("alloy", "rm-0", reasonMissingClusterID)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(g.rejected.WithLabelValues("alloy", "rm-0", reasonMismatchClusterID)), 0)
+	assert.InDelta(t, 1, testutil.ToFloat64(g.requests.WithLabelValues("prom", "rm-1", "204")), 0)
+}
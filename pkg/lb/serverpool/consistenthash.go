@@ -0,0 +1,137 @@
+package serverpool
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+)
+
+// Number of virtual nodes of each backend server on the hash ring.
+const virtualNodes = 160
+
+// ringNode is a virtual node of a backend server on the hash ring.
+type ringNode struct {
+	hash    uint64
+	backend backend.Server
+}
+
+// consistentHash implements consistent hashing load balancer strategy. Requests
+// with same key are always sent to the same backend server as long as it is
+// alive so that backend server can benefit from its caches.
+type consistentHash struct {
+	*pool
+	rings map[string][]ringNode
+	mux   sync.RWMutex
+}
+
+// TargetByKey returns the backend server that owns the key on hash ring if it is alive.
+// When the owner is not alive, next alive backend server on the ring is returned.
+func (s *consistentHash) TargetByKey(id string, key string) backend.Server {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	// If the ID is unknown return
+	ring, ok := s.rings[id]
+	if !ok {
+		s.logger.Error("Consistent hash strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
+
+		return nil
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+
+	for i := range ring {
+		node := ring[(start+i)%len(ring)]
+		if node.backend.IsAlive() {
+			s.logger.Debug("Consistent hash strategy", "cluster_id", id, "key", key, "selected_backend", node.backend.String())
+
+			return node.backend
+		}
+	}
+
+	return nil
+}
+
+// Target returns the alive backend server with least active connections. It is
+// used for requests that do not have any key.
+func (s *consistentHash) Target(id string, _ time.Duration) backend.Server {
+	// If the ID is unknown return
+	servers, ok := s.servers(id)
+	if !ok {
+		s.logger.Error("Consistent hash strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
+
+		return nil
+	}
+
+	var targetBackend backend.Server
+
+	activeConnections := math.MaxInt32
+
+	for _, backend := range servers {
+		if !backend.IsAlive() {
+			continue
+		}
+
+		if backendActiveConnections := backend.ActiveConnections(); activeConnections > backendActiveConnections {
+			targetBackend = backend
+			activeConnections = backendActiveConnections
+		}
+	}
+
+	if targetBackend != nil {
+		s.logger.Debug("Consistent hash strategy", "cluster_id", id, "selected_backend", targetBackend.String())
+	}
+
+	return targetBackend
+}
+
+// Add a backend server to pool and rebuild the hash ring.
+func (s *consistentHash) Add(id string, b backend.Server) {
+	s.pool.Add(id, b)
+	s.build(id)
+}
+
+// Remove a backend server from pool and rebuild the hash ring.
+func (s *consistentHash) Remove(id string, b backend.Server) {
+	s.pool.Remove(id, b)
+	s.build(id)
+}
+
+// build rebuilds the hash ring of cluster `id` from backend servers in pool.
+func (s *consistentHash) build(id string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	servers, ok := s.servers(id)
+	if !ok {
+		delete(s.rings, id)
+
+		return
+	}
+
+	ring := make([]ringNode, 0, len(servers)*virtualNodes)
+
+	for _, backend := range servers {
+		for i := range virtualNodes {
+			ring = append(ring, ringNode{hash: hashKey(backend.URL().String() + "#" + strconv.Itoa(i)), backend: backend})
+		}
+	}
+
+	slices.SortFunc(ring, func(a, b ringNode) int { return cmp.Compare(a.hash, b.hash) })
+
+	s.rings[id] = ring
+}
+
+// hashKey returns 64 bit xxhash of key.
+func hashKey(key string) uint64 {
+	return xxhash.Sum64String(key)
+}
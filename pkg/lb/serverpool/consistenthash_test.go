@@ -0,0 +1,80 @@
+package serverpool
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsistentHashLB(t *testing.T) {
+	// Start manager
+	manager, err := New("consistent-hash", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	hasher, ok := manager.(Hasher)
+	require.True(t, ok)
+
+	backends := make([]backend.Server, 3)
+
+	for i := range backends {
+		backends[i], err = backend.NewTSDB(
+			&backend.ServerConfig{Web: &models.WebConfig{URL: fmt.Sprintf("http://localhost:%d", 3333+i)}},
+			slog.New(slog.NewTextHandler(io.Discard, nil)),
+		)
+		require.NoError(t, err)
+
+		manager.Add("default", backends[i])
+	}
+
+	// Same key must always be served by same backend and keys must be
+	// distributed across all backends
+	owners := make(map[string]backend.Server)
+	used := make(map[backend.Server]bool)
+
+	for i := range 100 {
+		key := fmt.Sprintf("uuid-%d", i)
+
+		owners[key] = hasher.TargetByKey("default", key)
+		require.NotNil(t, owners[key])
+		assert.Equal(t, owners[key], hasher.TargetByKey("default", key))
+
+		used[owners[key]] = true
+	}
+
+	assert.Len(t, used, 3)
+
+	// When a backend is dead, only its keys must move to other backends
+	backends[0].SetAlive(false)
+
+	for key, owner := range owners {
+		target := hasher.TargetByKey("default", key)
+		require.NotNil(t, target)
+
+		if owner == backends[0] {
+			assert.NotEqual(t, backends[0], target, key)
+		} else {
+			assert.Equal(t, owner, target, key)
+		}
+	}
+
+	// Requests without key must be served by an alive backend
+	assert.NotEqual(t, backends[0], manager.Target("default", time.Second))
+
+	// For unknown ID expect nil
+	assert.Nil(t, hasher.TargetByKey("unknown", "uuid-0"))
+	assert.Nil(t, manager.Target("unknown", time.Second))
+
+	// When all backends are dead expect nil
+	for _, b := range backends {
+		b.SetAlive(false)
+	}
+
+	assert.Nil(t, hasher.TargetByKey("default", "uuid-0"))
+}
@@ -2,7 +2,6 @@ package serverpool
 
 import (
 	"fmt"
-	"log/slog"
 	"math"
 	"time"
 
@@ -11,15 +10,15 @@ import (
 
 // leastConn implements the least connection load balancer strategy.
 type leastConn struct {
-	backends map[string][]backend.Server
-	logger   *slog.Logger
+	*pool
 }
 
 // Target returns the backend server to send the request if it is alive.
 func (s *leastConn) Target(id string, _ time.Duration) backend.Server {
 	// If the ID is unknown return
-	if _, ok := s.backends[id]; !ok {
-		s.logger.Error("Round Robin strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
+	servers, ok := s.servers(id)
+	if !ok {
+		s.logger.Error("Least connection strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
 
 		return nil
 	}
@@ -28,7 +27,7 @@ func (s *leastConn) Target(id string, _ time.Duration) backend.Server {
 
 	activeConnections := math.MaxInt32
 
-	for _, backend := range s.backends[id] {
+	for _, backend := range servers {
 		if !backend.IsAlive() {
 			continue
 		}
@@ -48,17 +47,3 @@ func (s *leastConn) Target(id string, _ time.Duration) backend.Server {
 
 	return nil
 }
-
-func (s *leastConn) Add(id string, b backend.Server) {
-	s.logger.Debug("Backend added", "strategy", "least-connection", "cluster_id", id, "backend", b.String())
-
-	s.backends[id] = append(s.backends[id], b)
-}
-
-func (s *leastConn) Size(id string) int {
-	return len(s.backends[id])
-}
-
-func (s *leastConn) Backends() map[string][]backend.Server {
-	return s.backends
-}
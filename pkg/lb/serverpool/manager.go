@@ -5,6 +5,8 @@ package serverpool
 import (
 	"errors"
 	"log/slog"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
@@ -20,29 +22,136 @@ type Manager interface {
 	Backends() map[string][]backend.Server
 	Target(id string, d time.Duration) backend.Server
 	Add(id string, b backend.Server)
+	Remove(id string, b backend.Server)
 	Size(id string) int
 }
 
+// Slice is a time range of a query and backend server that serves it.
+type Slice struct {
+	Backend backend.Server
+	Start   time.Time
+	End     time.Time
+}
+
+// Splitter is the interface implemented by strategies that can split a query
+// over its time range across multiple backend servers.
+type Splitter interface {
+	Split(id string, start, end time.Time) []Slice
+}
+
+// Hasher is the interface implemented by strategies that choose backend server
+// based on a key of the request, e.g., UUIDs of compute units.
+type Hasher interface {
+	TargetByKey(id string, key string) backend.Server
+}
+
 // New returns a new instance of server pool manager.
 func New(strategy string, logger *slog.Logger) (Manager, error) {
 	switch strategy {
 	case "round-robin":
 		return &roundRobin{
-			backends: make(map[string][]backend.Server, 0),
-			current:  0,
-			logger:   logger,
+			pool:    newPool(strategy, logger),
+			current: 0,
 		}, nil
 	case "least-connection":
 		return &leastConn{
-			backends: make(map[string][]backend.Server, 0),
-			logger:   logger,
+			pool: newPool(strategy, logger),
 		}, nil
 	case "resource-based":
 		return &resourceBased{
-			backends: make(map[string][]backend.Server, 0),
-			logger:   logger,
+			pool: newPool(strategy, logger),
+		}, nil
+	case "consistent-hash":
+		return &consistentHash{
+			pool:  newPool(strategy, logger),
+			rings: make(map[string][]ringNode, 0),
+		}, nil
+	case "peak-ewma":
+		return &peakEWMA{
+			pool: newPool(strategy, logger),
 		}, nil
 	default:
 		return nil, ErrInvalidStrategy
 	}
 }
+
+// pool is the pool of backend servers of each cluster shared by all strategies.
+// Backend servers can be added to and removed from the pool while it is serving
+// requests.
+type pool struct {
+	backends map[string][]backend.Server
+	mu       sync.RWMutex
+	strategy string
+	logger   *slog.Logger
+}
+
+// newPool returns a new pool of backend servers.
+func newPool(strategy string, logger *slog.Logger) *pool {
+	return &pool{
+		backends: make(map[string][]backend.Server, 0),
+		strategy: strategy,
+		logger:   logger,
+	}
+}
+
+// List all backend servers in pool.
+func (p *pool) Backends() map[string][]backend.Server {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	backends := make(map[string][]backend.Server, len(p.backends))
+	for id, servers := range p.backends {
+		backends[id] = slices.Clone(servers)
+	}
+
+	return backends
+}
+
+// servers returns the backend servers of cluster `id` and false if the cluster
+// is unknown.
+func (p *pool) servers(id string) ([]backend.Server, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	servers, ok := p.backends[id]
+
+	return servers, ok
+}
+
+// Add a backend server to pool.
+func (p *pool) Add(id string, b backend.Server) {
+	p.logger.Debug("Backend added", "strategy", p.strategy, "cluster_id", id, "backend", b.String())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.backends[id] = append(p.backends[id], b)
+}
+
+// Remove a backend server from pool. Requests that are being served by the
+// backend server are not affected.
+func (p *pool) Remove(id string, b backend.Server) {
+	p.logger.Debug("Backend removed", "strategy", p.strategy, "cluster_id", id, "backend", b.String())
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Always make a new slice as the current one might be in use
+	servers := slices.DeleteFunc(slices.Clone(p.backends[id]), func(s backend.Server) bool {
+		return s == b
+	})
+
+	if len(servers) == 0 {
+		delete(p.backends, id)
+	} else {
+		p.backends[id] = servers
+	}
+}
+
+// Total number of backend servers in pool.
+func (p *pool) Size(id string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.backends[id])
+}
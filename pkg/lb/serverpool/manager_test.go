@@ -23,7 +23,7 @@ var h = http.HandlerFunc(SleepHandler)
 // w   = httptest.NewRecorder()
 
 func TestNew(t *testing.T) {
-	for _, strategy := range []string{"round-robin", "least-connection", "resource-based"} {
+	for _, strategy := range []string{"round-robin", "least-connection", "resource-based", "consistent-hash", "peak-ewma"} {
 		m, _ := New(strategy, slog.New(slog.NewTextHandler(io.Discard, nil)))
 		b, err := backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: "http://localhost:3333"}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
 		require.NoError(t, err)
@@ -38,3 +38,29 @@ func TestNewUnknownStrategy(t *testing.T) {
 	_, err := New("unknown", slog.New(slog.NewTextHandler(io.Discard, nil)))
 	assert.Error(t, err)
 }
+
+func TestRemove(t *testing.T) {
+	for _, strategy := range []string{"round-robin", "least-connection", "resource-based", "consistent-hash", "peak-ewma"} {
+		m, _ := New(strategy, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+		var backends []backend.Server
+
+		for _, u := range []string{"http://localhost:3333", "http://localhost:3334"} {
+			b, err := backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: u}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			require.NoError(t, err)
+
+			m.Add("default", b)
+			backends = append(backends, b)
+		}
+
+		m.Remove("default", backends[0])
+		assert.Equal(t, 1, m.Size("default"), strategy)
+		assert.Equal(t, []backend.Server{backends[1]}, m.Backends()["default"], strategy)
+
+		// Removing last backend must remove cluster ID
+		m.Remove("default", backends[1])
+		assert.Equal(t, 0, m.Size("default"), strategy)
+		assert.NotContains(t, m.Backends(), "default", strategy)
+		assert.Nil(t, m.Target("default", 0), strategy)
+	}
+}
@@ -0,0 +1,66 @@
+package serverpool
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+)
+
+// Score of backend servers without any estimated latency that are serving
+// requests. It is larger than any realistic latency so that concurrent
+// requests are not all sent to a new backend server before its latency is
+// known. Same value is used by Finagle.
+const unestimatedLatencyPenalty = float64(math.MaxInt64 >> 16)
+
+// peakEWMA implements latency aware load balancer strategy. Each backend server
+// is scored by the peak EWMA of its response latencies weighted by its active
+// connections and the backend server with lowest score is chosen.
+type peakEWMA struct {
+	*pool
+}
+
+// Target returns the backend server to send the request if it is alive.
+func (s *peakEWMA) Target(id string, _ time.Duration) backend.Server {
+	// If the ID is unknown return
+	servers, ok := s.servers(id)
+	if !ok {
+		s.logger.Error("Peak EWMA strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
+
+		return nil
+	}
+
+	var targetBackend backend.Server
+
+	score := math.MaxFloat64
+
+	for _, backend := range servers {
+		if !backend.IsAlive() {
+			continue
+		}
+
+		// Backend servers without any observed latency have a score of zero so
+		// that they receive a request to get their latency estimated. While that
+		// request is being served, they are penalized
+		latency, connections := backend.Latency(), backend.ActiveConnections()
+
+		backendScore := float64(latency) * float64(connections+1)
+		if latency == 0 && connections > 0 {
+			backendScore = unestimatedLatencyPenalty + float64(connections)
+		}
+
+		if score > backendScore {
+			targetBackend = backend
+			score = backendScore
+		}
+	}
+
+	if targetBackend != nil {
+		s.logger.Debug("Peak EWMA strategy", "cluster_id", id, "selected_backend", targetBackend.String(), "latency", targetBackend.Latency())
+
+		return targetBackend
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package serverpool
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mahendrapaipuri/ceems/pkg/api/models"
+	"github.com/mahendrapaipuri/ceems/pkg/lb/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeakEWMALB(t *testing.T) {
+	d := 0 * time.Second
+
+	// Start manager
+	manager, err := New("peak-ewma", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	// Make a slow and a fast backend
+	backends := make([]backend.Server, 2)
+
+	for i, delay := range []time.Duration{200 * time.Millisecond, 10 * time.Millisecond} {
+		dummyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+		}))
+		defer dummyServer.Close()
+
+		backends[i], err = backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: dummyServer.URL}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+
+		manager.Add("default", backends[i])
+	}
+
+	// Serve few requests so that latencies of both backends are estimated
+	for range 4 {
+		target := manager.Target("default", d)
+		require.NotNil(t, target)
+
+		target.Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	}
+
+	assert.Greater(t, backends[0].Latency(), backends[1].Latency())
+
+	// Fast backend must be chosen
+	assert.Equal(t, backends[1], manager.Target("default", d))
+
+	// When fast backend is dead, slow backend must be chosen
+	backends[1].SetAlive(false)
+	assert.Equal(t, backends[0], manager.Target("default", d))
+
+	// For unknown ID expect nil
+	assert.Nil(t, manager.Target("unknown", d))
+}
+
+func TestPeakEWMALBNewBackend(t *testing.T) {
+	d := 0 * time.Second
+
+	manager, err := New("peak-ewma", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	// Make an estimated backend and a new backend that blocks requests
+	block := make(chan struct{})
+	backends := make([]backend.Server, 2)
+
+	for i, handler := range []http.HandlerFunc{
+		func(w http.ResponseWriter, r *http.Request) {},
+		func(w http.ResponseWriter, r *http.Request) { <-block },
+	} {
+		dummyServer := httptest.NewServer(handler)
+		defer dummyServer.Close()
+
+		backends[i], err = backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: dummyServer.URL}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+	}
+
+	backends[0].Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+	require.Positive(t, backends[0].Latency())
+
+	for _, b := range backends {
+		manager.Add("default", b)
+	}
+
+	// New backend without latency must be chosen to get its latency estimated
+	assert.Equal(t, backends[1], manager.Target("default", d))
+
+	// Once it is serving a request, it must be penalized
+	done := make(chan struct{})
+
+	go func() {
+		backends[1].Serve(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/test", nil))
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return backends[1].ActiveConnections() > 0 }, time.Second, time.Millisecond)
+	assert.Equal(t, backends[0], manager.Target("default", d))
+
+	close(block)
+	<-done
+}
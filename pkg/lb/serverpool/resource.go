@@ -2,7 +2,7 @@ package serverpool
 
 import (
 	"fmt"
-	"log/slog"
+	"maps"
 	"math"
 	"slices"
 	"time"
@@ -18,14 +18,14 @@ import (
 // can be served by multiple backend TSDB servers, the one with least retention period
 // will be chosen as it is assumed as "hot" TSDB with maximum performance.
 type resourceBased struct {
-	backends map[string][]backend.Server
-	logger   *slog.Logger
+	*pool
 }
 
 // Target returns the backend server to send the request if it is alive.
 func (s *resourceBased) Target(id string, d time.Duration) backend.Server {
 	// If the ID is unknown return
-	if _, ok := s.backends[id]; !ok {
+	servers, ok := s.servers(id)
+	if !ok {
 		s.logger.Error("Resource based strategy", "err", fmt.Errorf("unknown backend ID: %s", id))
 
 		return nil
@@ -39,16 +39,16 @@ func (s *resourceBased) Target(id string, d time.Duration) backend.Server {
 
 	var retentionPeriods []time.Duration
 
-	for i := range s.Size(id) {
-		if !s.backends[id][i].IsAlive() {
+	for _, server := range servers {
+		if !server.IsAlive() {
 			continue
 		}
 
 		// If query duration is less than backend TSDB's retention period, it is
 		// target backend as it can serve the query
-		if d < s.backends[id][i].RetentionPeriod() {
-			targetBackends = append(targetBackends, s.backends[id][i])
-			retentionPeriods = append(retentionPeriods, s.backends[id][i].RetentionPeriod())
+		if d < server.RetentionPeriod() {
+			targetBackends = append(targetBackends, server)
+			retentionPeriods = append(retentionPeriods, server.RetentionPeriod())
 		}
 	}
 
@@ -94,19 +94,61 @@ func (s *resourceBased) Target(id string, d time.Duration) backend.Server {
 	return nil
 }
 
-// List all backend servers in pool.
-func (s *resourceBased) Backends() map[string][]backend.Server {
-	return s.backends
-}
+// Split splits the time range between start and end at retention boundaries of
+// backend servers and returns slices ordered from the most recent to the oldest.
+//
+// Each slice is served by the backend server with the least retention period
+// that covers it. Oldest part of the range that is not covered by any backend
+// server will be served by the backend server with the longest retention period.
+func (s *resourceBased) Split(id string, start, end time.Time) []Slice {
+	// Backends with the same retention period are replicas and for each retention
+	// period, use the one with least connections
+	targets := make(map[time.Duration]backend.Server)
+
+	servers, _ := s.servers(id)
+
+	for _, b := range servers {
+		if !b.IsAlive() {
+			continue
+		}
+
+		retentionPeriod := b.RetentionPeriod()
+		if t, ok := targets[retentionPeriod]; !ok || b.ActiveConnections() < t.ActiveConnections() {
+			targets[retentionPeriod] = b
+		}
+	}
 
-// Add a backend server to pool.
-func (s *resourceBased) Add(id string, b backend.Server) {
-	s.logger.Debug("Backend added", "strategy", "resource", "cluster_id", id, "backend", b.String())
+	retentionPeriods := slices.Sorted(maps.Keys(targets))
 
-	s.backends[id] = append(s.backends[id], b)
-}
+	var splits []Slice
+
+	now := time.Now()
+	current := end
+
+	for i, retentionPeriod := range retentionPeriods {
+		boundary := now.Add(-retentionPeriod)
+
+		// Backend does not cover the current slice. Move to the one with longer
+		// retention period
+		if i < len(retentionPeriods)-1 && !boundary.Before(current) {
+			continue
+		}
+
+		// Backend covers rest of the range
+		if i == len(retentionPeriods)-1 || !boundary.After(start) {
+			splits = append(splits, Slice{Backend: targets[retentionPeriod], Start: start, End: current})
+
+			break
+		}
+
+		splits = append(splits, Slice{Backend: targets[retentionPeriod], Start: boundary, End: current})
+		current = boundary
+	}
+
+	s.logger.Debug(
+		"Resource based strategy. Split query", "cluster_id", id,
+		"start", start, "end", end, "num_slices", len(splits),
+	)
 
-// Total number of backend servers in pool.
-func (s *resourceBased) Size(id string) int {
-	return len(s.backends[id])
+	return splits
 }
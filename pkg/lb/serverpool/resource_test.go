@@ -171,3 +171,68 @@ func TestResourceBasedLB(t *testing.T) {
 		assert.Empty(t, manager.Target(id, 100*24*time.Hour))
 	}
 }
+
+func TestResourceBasedSplit(t *testing.T) {
+	// Create a manager
+	manager, err := New("resource-based", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+
+	// Retention periods
+	periods := []string{"30d", "180d", "180d"}
+	backends := make([]backend.Server, len(periods))
+
+	for i, p := range periods {
+		dummyServer := dummyServer(p)
+		defer dummyServer.Close()
+
+		backends[i], err = backend.NewTSDB(&backend.ServerConfig{Web: &models.WebConfig{URL: dummyServer.URL}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		require.NoError(t, err)
+
+		manager.Add("rb0", backends[i])
+	}
+
+	splitter, ok := manager.(Splitter)
+	require.True(t, ok)
+
+	now := time.Now()
+	hotBoundary := now.Add(-30 * 24 * time.Hour)
+
+	// Query within retention of hot backend must not be split
+	splits := splitter.Split("rb0", now.Add(-10*24*time.Hour), now)
+	require.Len(t, splits, 1)
+	assert.Equal(t, backends[0].URL(), splits[0].Backend.URL())
+
+	// Query older than retention of hot backend must not be split
+	splits = splitter.Split("rb0", now.Add(-100*24*time.Hour), now.Add(-50*24*time.Hour))
+	require.Len(t, splits, 1)
+	assert.Equal(t, backends[1].URL(), splits[0].Backend.URL())
+
+	// Query spanning both backends must be split at retention boundary
+	start := now.Add(-90 * 24 * time.Hour)
+	splits = splitter.Split("rb0", start, now)
+	require.Len(t, splits, 2)
+	assert.Equal(t, backends[0].URL(), splits[0].Backend.URL())
+	assert.Equal(t, now, splits[0].End)
+	assert.WithinDuration(t, hotBoundary, splits[0].Start, time.Second)
+	assert.Equal(t, backends[1].URL(), splits[1].Backend.URL())
+	assert.Equal(t, start, splits[1].Start)
+	assert.Equal(t, splits[0].Start, splits[1].End)
+
+	// Query older than all retention periods is served by longest retention backend
+	start = now.Add(-365 * 24 * time.Hour)
+	splits = splitter.Split("rb0", start, now)
+	require.Len(t, splits, 2)
+	assert.Equal(t, start, splits[1].Start)
+
+	// When cold backends are offline, hot backend serves the entire range
+	backends[1].SetAlive(false)
+	backends[2].SetAlive(false)
+
+	splits = splitter.Split("rb0", start, now)
+	require.Len(t, splits, 1)
+	assert.Equal(t, backends[0].URL(), splits[0].Backend.URL())
+
+	// No backends are alive
+	backends[0].SetAlive(false)
+	assert.Empty(t, splitter.Split("rb0", start, now))
+}
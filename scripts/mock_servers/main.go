@@ -411,6 +411,18 @@ func UsersHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("KO"))
 }
 
+// RoleAssignmentsHandler handles OS role assignments.
+func RoleAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	if data, err := os.ReadFile("pkg/api/testdata/openstack/identity/role_assignments.json"); err == nil {
+		w.Write(data)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte("KO"))
+}
+
 // ProjectsHandler handles OS projects.
 func ProjectsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("id")
@@ -593,6 +605,7 @@ func osKSServer(ctx context.Context) {
 	osKSMux.HandleFunc("/v3/auth/tokens", TokensHandler)
 	osKSMux.HandleFunc("/v3/users", UsersHandler)
 	osKSMux.HandleFunc("/v3/users/{id}/projects", ProjectsHandler)
+	osKSMux.HandleFunc("/v3/role_assignments", RoleAssignmentsHandler)
 
 	log.Println("Started Openstack identity API server on port", osKSPortNum)
 	log.Println("To close connection CTRL+C :-)")